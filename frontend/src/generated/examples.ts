@@ -61,15 +61,54 @@ export const EXAMPLES: Record<string, unknown> = {
     "subURI": "",
     "subUpdates": 0,
     "tgBotAPIServer": "",
+    "tgBotAdminMenu": "",
     "tgBotBackup": false,
+    "tgBotChannelCategories": "",
+    "tgBotChannelId": "",
     "tgBotChatId": "",
+    "tgBotClientMenu": "",
     "tgBotEnable": false,
+    "tgBotExtraBots": "",
+    "tgBotFallbackWebhook": "",
+    "tgBotJsonLog": false,
     "tgBotLoginNotify": false,
     "tgBotProxy": "",
+    "tgBotStartupNotify": false,
     "tgBotToken": "",
+    "tgButtonTTL": 0,
+    "tgCertExpiryDays": 0,
+    "tgClientLimitInterval": 0,
+    "tgClientUsageDays": 1,
+    "tgClientUsageInterval": 0,
+    "tgCommandAliases": "",
     "tgCpu": 0,
+    "tgCpuWindow": 10,
+    "tgDebugStats": false,
+    "tgGeoMaxAge": 0,
     "tgLang": "",
+    "tgNumberFormat": "plain",
+    "tgOnlineHistoryDays": 1,
+    "tgPinCritical": false,
+    "tgQuietEnd": "",
+    "tgQuietStart": "",
+    "tgReportDisabledInbounds": false,
+    "tgReportFileThreshold": 0,
+    "tgReportNoInboundsNote": false,
+    "tgReportSections": "",
+    "tgReportSparklines": 0,
+    "tgReportSummaryThreshold": 0,
+    "tgReportSummaryTop": 1,
     "tgRunTime": "",
+    "tgServerAddress": "",
+    "tgSeverityEmoji": false,
+    "tgSilentCategories": "",
+    "tgStatusClientCounts": false,
+    "tgTrafficDecimals": 0,
+    "tgTrafficHistoryDays": 1,
+    "tgTrafficResetNotify": false,
+    "tgTrafficUnits": "binary",
+    "tgXrayStartRetries": 0,
+    "tgXrayStartTimeout": 2,
     "timeLocation": "",
     "trafficDiff": 0,
     "trustedProxyCIDRs": "",
@@ -91,6 +130,7 @@ export const EXAMPLES: Record<string, unknown> = {
     "hasApiToken": false,
     "hasLdapPassword": false,
     "hasNordSecret": false,
+    "hasTgBotExtraBots": false,
     "hasTgBotToken": false,
     "hasTwoFactorToken": false,
     "hasWarpSecret": false,
@@ -150,15 +190,54 @@ export const EXAMPLES: Record<string, unknown> = {
     "subURI": "",
     "subUpdates": 0,
     "tgBotAPIServer": "",
+    "tgBotAdminMenu": "",
     "tgBotBackup": false,
+    "tgBotChannelCategories": "",
+    "tgBotChannelId": "",
     "tgBotChatId": "",
+    "tgBotClientMenu": "",
     "tgBotEnable": false,
+    "tgBotExtraBots": "",
+    "tgBotFallbackWebhook": "",
+    "tgBotJsonLog": false,
     "tgBotLoginNotify": false,
     "tgBotProxy": "",
+    "tgBotStartupNotify": false,
     "tgBotToken": "",
+    "tgButtonTTL": 0,
+    "tgCertExpiryDays": 0,
+    "tgClientLimitInterval": 0,
+    "tgClientUsageDays": 1,
+    "tgClientUsageInterval": 0,
+    "tgCommandAliases": "",
     "tgCpu": 0,
+    "tgCpuWindow": 10,
+    "tgDebugStats": false,
+    "tgGeoMaxAge": 0,
     "tgLang": "",
+    "tgNumberFormat": "plain",
+    "tgOnlineHistoryDays": 1,
+    "tgPinCritical": false,
+    "tgQuietEnd": "",
+    "tgQuietStart": "",
+    "tgReportDisabledInbounds": false,
+    "tgReportFileThreshold": 0,
+    "tgReportNoInboundsNote": false,
+    "tgReportSections": "",
+    "tgReportSparklines": 0,
+    "tgReportSummaryThreshold": 0,
+    "tgReportSummaryTop": 1,
     "tgRunTime": "",
+    "tgServerAddress": "",
+    "tgSeverityEmoji": false,
+    "tgSilentCategories": "",
+    "tgStatusClientCounts": false,
+    "tgTrafficDecimals": 0,
+    "tgTrafficHistoryDays": 1,
+    "tgTrafficResetNotify": false,
+    "tgTrafficUnits": "binary",
+    "tgXrayStartRetries": 0,
+    "tgXrayStartTimeout": 2,
     "timeLocation": "",
     "trafficDiff": 0,
     "trustedProxyCIDRs": "",
@@ -259,6 +338,8 @@ export const EXAMPLES: Record<string, unknown> = {
     "seederName": ""
   },
   "Inbound": {
+    "alertExpiryDays": null,
+    "alertTrafficPercent": null,
     "clientStats": [
       {
         "down": 2097152,
@@ -283,6 +364,7 @@ export const EXAMPLES: Record<string, unknown> = {
     "lastTrafficResetTime": 0,
     "listen": "",
     "nodeId": null,
+    "note": "",
     "originNodeGuid": "",
     "port": 443,
     "protocol": "vless",
@@ -239,18 +239,46 @@ export const SCHEMAS: Record<string, unknown> = {
         "description": "Custom API server for Telegram bot",
         "type": "string"
       },
+      "tgBotAdminMenu": {
+        "description": "Admin menu layout; empty uses the default",
+        "type": "string"
+      },
       "tgBotBackup": {
         "description": "Enable database backup via Telegram",
         "type": "boolean"
       },
+      "tgBotChannelCategories": {
+        "description": "Notification categories posted to the channel",
+        "type": "string"
+      },
+      "tgBotChannelId": {
+        "description": "Channel reports and selected notifications are posted to",
+        "type": "string"
+      },
       "tgBotChatId": {
         "description": "Telegram chat ID for notifications",
         "type": "string"
       },
+      "tgBotClientMenu": {
+        "description": "Client menu layout; empty uses the default",
+        "type": "string"
+      },
       "tgBotEnable": {
         "description": "Telegram bot settings\nEnable Telegram bot notifications",
         "type": "boolean"
       },
+      "tgBotExtraBots": {
+        "description": "JSON list of additional notification-only bots",
+        "type": "string"
+      },
+      "tgBotFallbackWebhook": {
+        "description": "URL notified when Telegram is unreachable",
+        "type": "string"
+      },
+      "tgBotJsonLog": {
+        "description": "Log bot events as structured JSON lines",
+        "type": "boolean"
+      },
       "tgBotLoginNotify": {
         "description": "Send login notifications",
         "type": "boolean"
@@ -259,24 +287,194 @@ export const SCHEMAS: Record<string, unknown> = {
         "description": "Proxy URL for Telegram bot",
         "type": "string"
       },
+      "tgBotStartupNotify": {
+        "description": "Notify admins when the panel starts",
+        "type": "boolean"
+      },
       "tgBotToken": {
         "description": "Telegram bot token",
         "type": "string"
       },
+      "tgButtonTTL": {
+        "description": "Minutes a bot button that changes something stays valid; 0 disables the check",
+        "maximum": 10080,
+        "minimum": 0,
+        "type": "integer"
+      },
+      "tgCertExpiryDays": {
+        "description": "Days before a TLS certificate expires when the bot warns about it; 0 disables the daily check",
+        "maximum": 365,
+        "minimum": 0,
+        "type": "integer"
+      },
+      "tgClientLimitInterval": {
+        "description": "Seconds between client limit scan batches; 0 disables the alerts",
+        "maximum": 3600,
+        "minimum": 0,
+        "type": "integer"
+      },
+      "tgClientUsageDays": {
+        "description": "Days the per-client traffic samples are kept",
+        "maximum": 90,
+        "minimum": 1,
+        "type": "integer"
+      },
+      "tgClientUsageInterval": {
+        "description": "Minutes between per-client traffic samples; 0 disables",
+        "maximum": 60,
+        "minimum": 0,
+        "type": "integer"
+      },
+      "tgCommandAliases": {
+        "description": "Comma-separated alias=command shortcuts of the bot commands",
+        "type": "string"
+      },
       "tgCpu": {
         "description": "CPU usage threshold for alerts (percent)",
         "maximum": 100,
         "minimum": 0,
         "type": "integer"
       },
+      "tgCpuWindow": {
+        "description": "Window in seconds the CPU usage is averaged over for alerts",
+        "maximum": 3600,
+        "minimum": 10,
+        "type": "integer"
+      },
+      "tgDebugStats": {
+        "description": "Allow /debugstats to show the panel's runtime internals",
+        "type": "boolean"
+      },
+      "tgGeoMaxAge": {
+        "description": "Days after which /geoinfo warns that the GeoIP file is stale (0 to never warn)",
+        "maximum": 3650,
+        "minimum": 0,
+        "type": "integer"
+      },
       "tgLang": {
         "description": "Telegram bot language",
         "type": "string"
       },
+      "tgNumberFormat": {
+        "description": "Digit grouping and decimal separator in bot messages",
+        "enum": [
+          "plain",
+          "en",
+          "eu",
+          "fr",
+          "ch"
+        ],
+        "type": "string"
+      },
+      "tgOnlineHistoryDays": {
+        "description": "Days the online client samples are kept",
+        "maximum": 365,
+        "minimum": 1,
+        "type": "integer"
+      },
+      "tgPinCritical": {
+        "description": "Pin critical alerts in the admin chats until they are resolved",
+        "type": "boolean"
+      },
+      "tgQuietEnd": {
+        "description": "End of quiet hours (HH:MM)",
+        "type": "string"
+      },
+      "tgQuietStart": {
+        "description": "Start of quiet hours (HH:MM); empty disables",
+        "type": "string"
+      },
+      "tgReportDisabledInbounds": {
+        "description": "Include disabled inbounds, marked, in bot reports and status",
+        "type": "boolean"
+      },
+      "tgReportFileThreshold": {
+        "description": "Report size in bytes above which it is sent as a file; 0 disables",
+        "minimum": 0,
+        "type": "integer"
+      },
+      "tgReportNoInboundsNote": {
+        "description": "Send one note instead of the report while the panel has no inbounds",
+        "type": "boolean"
+      },
+      "tgReportSections": {
+        "description": "Comma-separated report sections in the order they are shown",
+        "type": "string"
+      },
+      "tgReportSparklines": {
+        "description": "Top inbounds whose daily traffic is drawn as a sparkline in reports; 0 disables it",
+        "maximum": 10,
+        "minimum": 0,
+        "type": "integer"
+      },
+      "tgReportSummaryThreshold": {
+        "description": "Inbound count above which the report is summarized (0 to always list every inbound)",
+        "minimum": 0,
+        "type": "integer"
+      },
+      "tgReportSummaryTop": {
+        "description": "Number of busiest inbounds the report summary lists",
+        "maximum": 50,
+        "minimum": 1,
+        "type": "integer"
+      },
       "tgRunTime": {
         "description": "Cron schedule for Telegram notifications",
         "type": "string"
       },
+      "tgServerAddress": {
+        "description": "Public address /server shows instead of the detected IP, for servers behind NAT",
+        "type": "string"
+      },
+      "tgSeverityEmoji": {
+        "description": "Prefix bot messages with a severity emoji",
+        "type": "boolean"
+      },
+      "tgSilentCategories": {
+        "description": "Notification categories sent without a sound; critical ones never are",
+        "type": "string"
+      },
+      "tgStatusClientCounts": {
+        "description": "Show total and enabled client counts for each inbound in status lists",
+        "type": "boolean"
+      },
+      "tgTrafficDecimals": {
+        "description": "Decimal places for traffic in bot messages",
+        "maximum": 4,
+        "minimum": 0,
+        "type": "integer"
+      },
+      "tgTrafficHistoryDays": {
+        "description": "Days the inbound traffic snapshots are kept",
+        "maximum": 365,
+        "minimum": 1,
+        "type": "integer"
+      },
+      "tgTrafficResetNotify": {
+        "description": "Notify the admins of automatic traffic resets",
+        "type": "boolean"
+      },
+      "tgTrafficUnits": {
+        "description": "Unit system for traffic in bot messages",
+        "enum": [
+          "binary",
+          "iec",
+          "si"
+        ],
+        "type": "string"
+      },
+      "tgXrayStartRetries": {
+        "description": "Extra attempts of a bot-triggered Xray restart whose core fails to start",
+        "maximum": 5,
+        "minimum": 0,
+        "type": "integer"
+      },
+      "tgXrayStartTimeout": {
+        "description": "Seconds a bot-triggered Xray restart waits for the core to become ready",
+        "maximum": 300,
+        "minimum": 2,
+        "type": "integer"
+      },
       "timeLocation": {
         "description": "Security settings\nTime zone location",
         "type": "string"
@@ -392,15 +590,54 @@ export const SCHEMAS: Record<string, unknown> = {
       "subURI",
       "subUpdates",
       "tgBotAPIServer",
+      "tgBotAdminMenu",
       "tgBotBackup",
+      "tgBotChannelCategories",
+      "tgBotChannelId",
       "tgBotChatId",
+      "tgBotClientMenu",
       "tgBotEnable",
+      "tgBotExtraBots",
+      "tgBotFallbackWebhook",
+      "tgBotJsonLog",
       "tgBotLoginNotify",
       "tgBotProxy",
+      "tgBotStartupNotify",
       "tgBotToken",
+      "tgButtonTTL",
+      "tgCertExpiryDays",
+      "tgClientLimitInterval",
+      "tgClientUsageDays",
+      "tgClientUsageInterval",
+      "tgCommandAliases",
       "tgCpu",
+      "tgCpuWindow",
+      "tgDebugStats",
+      "tgGeoMaxAge",
       "tgLang",
+      "tgNumberFormat",
+      "tgOnlineHistoryDays",
+      "tgPinCritical",
+      "tgQuietEnd",
+      "tgQuietStart",
+      "tgReportDisabledInbounds",
+      "tgReportFileThreshold",
+      "tgReportNoInboundsNote",
+      "tgReportSections",
+      "tgReportSparklines",
+      "tgReportSummaryThreshold",
+      "tgReportSummaryTop",
       "tgRunTime",
+      "tgServerAddress",
+      "tgSeverityEmoji",
+      "tgSilentCategories",
+      "tgStatusClientCounts",
+      "tgTrafficDecimals",
+      "tgTrafficHistoryDays",
+      "tgTrafficResetNotify",
+      "tgTrafficUnits",
+      "tgXrayStartRetries",
+      "tgXrayStartTimeout",
       "timeLocation",
       "trafficDiff",
       "trustedProxyCIDRs",
@@ -445,6 +682,9 @@ export const SCHEMAS: Record<string, unknown> = {
       "hasNordSecret": {
         "type": "boolean"
       },
+      "hasTgBotExtraBots": {
+        "type": "boolean"
+      },
       "hasTgBotToken": {
         "type": "boolean"
       },
@@ -673,18 +913,46 @@ export const SCHEMAS: Record<string, unknown> = {
         "description": "Custom API server for Telegram bot",
         "type": "string"
       },
+      "tgBotAdminMenu": {
+        "description": "Admin menu layout; empty uses the default",
+        "type": "string"
+      },
       "tgBotBackup": {
         "description": "Enable database backup via Telegram",
         "type": "boolean"
       },
+      "tgBotChannelCategories": {
+        "description": "Notification categories posted to the channel",
+        "type": "string"
+      },
+      "tgBotChannelId": {
+        "description": "Channel reports and selected notifications are posted to",
+        "type": "string"
+      },
       "tgBotChatId": {
         "description": "Telegram chat ID for notifications",
         "type": "string"
       },
+      "tgBotClientMenu": {
+        "description": "Client menu layout; empty uses the default",
+        "type": "string"
+      },
       "tgBotEnable": {
         "description": "Telegram bot settings\nEnable Telegram bot notifications",
         "type": "boolean"
       },
+      "tgBotExtraBots": {
+        "description": "JSON list of additional notification-only bots",
+        "type": "string"
+      },
+      "tgBotFallbackWebhook": {
+        "description": "URL notified when Telegram is unreachable",
+        "type": "string"
+      },
+      "tgBotJsonLog": {
+        "description": "Log bot events as structured JSON lines",
+        "type": "boolean"
+      },
       "tgBotLoginNotify": {
         "description": "Send login notifications",
         "type": "boolean"
@@ -693,24 +961,194 @@ export const SCHEMAS: Record<string, unknown> = {
         "description": "Proxy URL for Telegram bot",
         "type": "string"
       },
+      "tgBotStartupNotify": {
+        "description": "Notify admins when the panel starts",
+        "type": "boolean"
+      },
       "tgBotToken": {
         "description": "Telegram bot token",
         "type": "string"
       },
+      "tgButtonTTL": {
+        "description": "Minutes a bot button that changes something stays valid; 0 disables the check",
+        "maximum": 10080,
+        "minimum": 0,
+        "type": "integer"
+      },
+      "tgCertExpiryDays": {
+        "description": "Days before a TLS certificate expires when the bot warns about it; 0 disables the daily check",
+        "maximum": 365,
+        "minimum": 0,
+        "type": "integer"
+      },
+      "tgClientLimitInterval": {
+        "description": "Seconds between client limit scan batches; 0 disables the alerts",
+        "maximum": 3600,
+        "minimum": 0,
+        "type": "integer"
+      },
+      "tgClientUsageDays": {
+        "description": "Days the per-client traffic samples are kept",
+        "maximum": 90,
+        "minimum": 1,
+        "type": "integer"
+      },
+      "tgClientUsageInterval": {
+        "description": "Minutes between per-client traffic samples; 0 disables",
+        "maximum": 60,
+        "minimum": 0,
+        "type": "integer"
+      },
+      "tgCommandAliases": {
+        "description": "Comma-separated alias=command shortcuts of the bot commands",
+        "type": "string"
+      },
       "tgCpu": {
         "description": "CPU usage threshold for alerts (percent)",
         "maximum": 100,
         "minimum": 0,
         "type": "integer"
       },
+      "tgCpuWindow": {
+        "description": "Window in seconds the CPU usage is averaged over for alerts",
+        "maximum": 3600,
+        "minimum": 10,
+        "type": "integer"
+      },
+      "tgDebugStats": {
+        "description": "Allow /debugstats to show the panel's runtime internals",
+        "type": "boolean"
+      },
+      "tgGeoMaxAge": {
+        "description": "Days after which /geoinfo warns that the GeoIP file is stale (0 to never warn)",
+        "maximum": 3650,
+        "minimum": 0,
+        "type": "integer"
+      },
       "tgLang": {
         "description": "Telegram bot language",
         "type": "string"
       },
+      "tgNumberFormat": {
+        "description": "Digit grouping and decimal separator in bot messages",
+        "enum": [
+          "plain",
+          "en",
+          "eu",
+          "fr",
+          "ch"
+        ],
+        "type": "string"
+      },
+      "tgOnlineHistoryDays": {
+        "description": "Days the online client samples are kept",
+        "maximum": 365,
+        "minimum": 1,
+        "type": "integer"
+      },
+      "tgPinCritical": {
+        "description": "Pin critical alerts in the admin chats until they are resolved",
+        "type": "boolean"
+      },
+      "tgQuietEnd": {
+        "description": "End of quiet hours (HH:MM)",
+        "type": "string"
+      },
+      "tgQuietStart": {
+        "description": "Start of quiet hours (HH:MM); empty disables",
+        "type": "string"
+      },
+      "tgReportDisabledInbounds": {
+        "description": "Include disabled inbounds, marked, in bot reports and status",
+        "type": "boolean"
+      },
+      "tgReportFileThreshold": {
+        "description": "Report size in bytes above which it is sent as a file; 0 disables",
+        "minimum": 0,
+        "type": "integer"
+      },
+      "tgReportNoInboundsNote": {
+        "description": "Send one note instead of the report while the panel has no inbounds",
+        "type": "boolean"
+      },
+      "tgReportSections": {
+        "description": "Comma-separated report sections in the order they are shown",
+        "type": "string"
+      },
+      "tgReportSparklines": {
+        "description": "Top inbounds whose daily traffic is drawn as a sparkline in reports; 0 disables it",
+        "maximum": 10,
+        "minimum": 0,
+        "type": "integer"
+      },
+      "tgReportSummaryThreshold": {
+        "description": "Inbound count above which the report is summarized (0 to always list every inbound)",
+        "minimum": 0,
+        "type": "integer"
+      },
+      "tgReportSummaryTop": {
+        "description": "Number of busiest inbounds the report summary lists",
+        "maximum": 50,
+        "minimum": 1,
+        "type": "integer"
+      },
       "tgRunTime": {
         "description": "Cron schedule for Telegram notifications",
         "type": "string"
       },
+      "tgServerAddress": {
+        "description": "Public address /server shows instead of the detected IP, for servers behind NAT",
+        "type": "string"
+      },
+      "tgSeverityEmoji": {
+        "description": "Prefix bot messages with a severity emoji",
+        "type": "boolean"
+      },
+      "tgSilentCategories": {
+        "description": "Notification categories sent without a sound; critical ones never are",
+        "type": "string"
+      },
+      "tgStatusClientCounts": {
+        "description": "Show total and enabled client counts for each inbound in status lists",
+        "type": "boolean"
+      },
+      "tgTrafficDecimals": {
+        "description": "Decimal places for traffic in bot messages",
+        "maximum": 4,
+        "minimum": 0,
+        "type": "integer"
+      },
+      "tgTrafficHistoryDays": {
+        "description": "Days the inbound traffic snapshots are kept",
+        "maximum": 365,
+        "minimum": 1,
+        "type": "integer"
+      },
+      "tgTrafficResetNotify": {
+        "description": "Notify the admins of automatic traffic resets",
+        "type": "boolean"
+      },
+      "tgTrafficUnits": {
+        "description": "Unit system for traffic in bot messages",
+        "enum": [
+          "binary",
+          "iec",
+          "si"
+        ],
+        "type": "string"
+      },
+      "tgXrayStartRetries": {
+        "description": "Extra attempts of a bot-triggered Xray restart whose core fails to start",
+        "maximum": 5,
+        "minimum": 0,
+        "type": "integer"
+      },
+      "tgXrayStartTimeout": {
+        "description": "Seconds a bot-triggered Xray restart waits for the core to become ready",
+        "maximum": 300,
+        "minimum": 2,
+        "type": "integer"
+      },
       "timeLocation": {
         "description": "Security settings\nTime zone location",
         "type": "string"
@@ -773,6 +1211,7 @@ export const SCHEMAS: Record<string, unknown> = {
       "hasApiToken",
       "hasLdapPassword",
       "hasNordSecret",
+      "hasTgBotExtraBots",
       "hasTgBotToken",
       "hasTwoFactorToken",
       "hasWarpSecret",
@@ -832,15 +1271,54 @@ export const SCHEMAS: Record<string, unknown> = {
       "subURI",
       "subUpdates",
       "tgBotAPIServer",
+      "tgBotAdminMenu",
       "tgBotBackup",
+      "tgBotChannelCategories",
+      "tgBotChannelId",
       "tgBotChatId",
+      "tgBotClientMenu",
       "tgBotEnable",
+      "tgBotExtraBots",
+      "tgBotFallbackWebhook",
+      "tgBotJsonLog",
       "tgBotLoginNotify",
       "tgBotProxy",
+      "tgBotStartupNotify",
       "tgBotToken",
+      "tgButtonTTL",
+      "tgCertExpiryDays",
+      "tgClientLimitInterval",
+      "tgClientUsageDays",
+      "tgClientUsageInterval",
+      "tgCommandAliases",
       "tgCpu",
+      "tgCpuWindow",
+      "tgDebugStats",
+      "tgGeoMaxAge",
       "tgLang",
+      "tgNumberFormat",
+      "tgOnlineHistoryDays",
+      "tgPinCritical",
+      "tgQuietEnd",
+      "tgQuietStart",
+      "tgReportDisabledInbounds",
+      "tgReportFileThreshold",
+      "tgReportNoInboundsNote",
+      "tgReportSections",
+      "tgReportSparklines",
+      "tgReportSummaryThreshold",
+      "tgReportSummaryTop",
       "tgRunTime",
+      "tgServerAddress",
+      "tgSeverityEmoji",
+      "tgSilentCategories",
+      "tgStatusClientCounts",
+      "tgTrafficDecimals",
+      "tgTrafficHistoryDays",
+      "tgTrafficResetNotify",
+      "tgTrafficUnits",
+      "tgXrayStartRetries",
+      "tgXrayStartTimeout",
       "timeLocation",
       "trafficDiff",
       "trustedProxyCIDRs",
@@ -1227,6 +1705,16 @@ export const SCHEMAS: Record<string, unknown> = {
   "Inbound": {
     "description": "Inbound represents an Xray inbound configuration with traffic statistics and settings.",
     "properties": {
+      "alertExpiryDays": {
+        "description": "Near-expiry alert this many days ahead, 0 for none; unset uses the panel's expireDiff",
+        "nullable": true,
+        "type": "integer"
+      },
+      "alertTrafficPercent": {
+        "description": "Near-limit alert once this % of the traffic limit is used; unset uses the panel's trafficDiff",
+        "nullable": true,
+        "type": "integer"
+      },
       "clientStats": {
         "description": "Client traffic statistics",
         "items": {
@@ -1273,6 +1761,10 @@ export const SCHEMAS: Record<string, unknown> = {
         "nullable": true,
         "type": "integer"
       },
+      "note": {
+        "description": "Free-text operator note (e.g. customer name or contact)",
+        "type": "string"
+      },
       "originNodeGuid": {
         "description": "OriginNodeGuid is the panelGuid of the node that physically hosts this\ninbound, propagated up across hops (#4983). Empty for an inbound that\nlives on this panel's own xray; set to the originating node's GUID when\nthe inbound was synced from a node (kept as-is across further hops). Lets\nthe master attribute a deeply nested inbound to the real node instead of\nthe intermediate one it was fetched through.",
         "type": "string"
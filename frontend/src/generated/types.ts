@@ -1,8 +1,12 @@
 // Code generated by tools/openapigen. DO NOT EDIT.
+export type ClientLimitState = number;
+export type Notifier = unknown;
 export type OnlineAPISupport = number;
 export type ProcessState = string;
 export type Protocol = string;
+export type ReportWaiter = unknown;
 export type SubLinkProvider = unknown;
+export type TrafficResetNotifier = unknown;
 export type transportBits = number;
 
 export interface AllSetting {
@@ -66,15 +70,54 @@ export interface AllSetting {
   subURI: string;
   subUpdates: number;
   tgBotAPIServer: string;
+  tgBotAdminMenu: string;
   tgBotBackup: boolean;
+  tgBotChannelCategories: string;
+  tgBotChannelId: string;
   tgBotChatId: string;
+  tgBotClientMenu: string;
   tgBotEnable: boolean;
+  tgBotExtraBots: string;
+  tgBotFallbackWebhook: string;
+  tgBotJsonLog: boolean;
   tgBotLoginNotify: boolean;
   tgBotProxy: string;
+  tgBotStartupNotify: boolean;
   tgBotToken: string;
+  tgButtonTTL: number;
+  tgCertExpiryDays: number;
+  tgClientLimitInterval: number;
+  tgClientUsageDays: number;
+  tgClientUsageInterval: number;
+  tgCommandAliases: string;
   tgCpu: number;
+  tgCpuWindow: number;
+  tgDebugStats: boolean;
+  tgGeoMaxAge: number;
   tgLang: string;
+  tgNumberFormat: string;
+  tgOnlineHistoryDays: number;
+  tgPinCritical: boolean;
+  tgQuietEnd: string;
+  tgQuietStart: string;
+  tgReportDisabledInbounds: boolean;
+  tgReportFileThreshold: number;
+  tgReportNoInboundsNote: boolean;
+  tgReportSections: string;
+  tgReportSparklines: number;
+  tgReportSummaryThreshold: number;
+  tgReportSummaryTop: number;
   tgRunTime: string;
+  tgServerAddress: string;
+  tgSeverityEmoji: boolean;
+  tgSilentCategories: string;
+  tgStatusClientCounts: boolean;
+  tgTrafficDecimals: number;
+  tgTrafficHistoryDays: number;
+  tgTrafficResetNotify: boolean;
+  tgTrafficUnits: string;
+  tgXrayStartRetries: number;
+  tgXrayStartTimeout: number;
   timeLocation: string;
   trafficDiff: number;
   trustedProxyCIDRs: string;
@@ -97,6 +140,7 @@ export interface AllSettingView {
   hasApiToken: boolean;
   hasLdapPassword: boolean;
   hasNordSecret: boolean;
+  hasTgBotExtraBots: boolean;
   hasTgBotToken: boolean;
   hasTwoFactorToken: boolean;
   hasWarpSecret: boolean;
@@ -156,15 +200,54 @@ export interface AllSettingView {
   subURI: string;
   subUpdates: number;
   tgBotAPIServer: string;
+  tgBotAdminMenu: string;
   tgBotBackup: boolean;
+  tgBotChannelCategories: string;
+  tgBotChannelId: string;
   tgBotChatId: string;
+  tgBotClientMenu: string;
   tgBotEnable: boolean;
+  tgBotExtraBots: string;
+  tgBotFallbackWebhook: string;
+  tgBotJsonLog: boolean;
   tgBotLoginNotify: boolean;
   tgBotProxy: string;
+  tgBotStartupNotify: boolean;
   tgBotToken: string;
+  tgButtonTTL: number;
+  tgCertExpiryDays: number;
+  tgClientLimitInterval: number;
+  tgClientUsageDays: number;
+  tgClientUsageInterval: number;
+  tgCommandAliases: string;
   tgCpu: number;
+  tgCpuWindow: number;
+  tgDebugStats: boolean;
+  tgGeoMaxAge: number;
   tgLang: string;
+  tgNumberFormat: string;
+  tgOnlineHistoryDays: number;
+  tgPinCritical: boolean;
+  tgQuietEnd: string;
+  tgQuietStart: string;
+  tgReportDisabledInbounds: boolean;
+  tgReportFileThreshold: number;
+  tgReportNoInboundsNote: boolean;
+  tgReportSections: string;
+  tgReportSparklines: number;
+  tgReportSummaryThreshold: number;
+  tgReportSummaryTop: number;
   tgRunTime: string;
+  tgServerAddress: string;
+  tgSeverityEmoji: boolean;
+  tgSilentCategories: string;
+  tgStatusClientCounts: boolean;
+  tgTrafficDecimals: number;
+  tgTrafficHistoryDays: number;
+  tgTrafficResetNotify: boolean;
+  tgTrafficUnits: string;
+  tgXrayStartRetries: number;
+  tgXrayStartTimeout: number;
   timeLocation: string;
   trafficDiff: number;
   trustedProxyCIDRs: string;
@@ -275,6 +358,8 @@ export interface HistoryOfSeeders {
 }
 
 export interface Inbound {
+  alertExpiryDays?: number | null;
+  alertTrafficPercent?: number | null;
   clientStats: ClientTraffic[];
   down: number;
   enable: boolean;
@@ -284,6 +369,7 @@ export interface Inbound {
   lastTrafficResetTime: number;
   listen: string;
   nodeId?: number | null;
+  note?: string;
   originNodeGuid?: string;
   port: number;
   protocol: Protocol;
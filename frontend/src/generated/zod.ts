@@ -1,5 +1,11 @@
 // Code generated by tools/openapigen. DO NOT EDIT.
 import { z } from 'zod';
+export const ClientLimitStateSchema = z.number().int();
+export type ClientLimitState = z.infer<typeof ClientLimitStateSchema>;
+
+export const NotifierSchema = z.unknown();
+export type Notifier = z.infer<typeof NotifierSchema>;
+
 export const OnlineAPISupportSchema = z.number().int();
 export type OnlineAPISupport = z.infer<typeof OnlineAPISupportSchema>;
 
@@ -9,9 +15,15 @@ export type ProcessState = z.infer<typeof ProcessStateSchema>;
 export const ProtocolSchema = z.string();
 export type Protocol = z.infer<typeof ProtocolSchema>;
 
+export const ReportWaiterSchema = z.unknown();
+export type ReportWaiter = z.infer<typeof ReportWaiterSchema>;
+
 export const SubLinkProviderSchema = z.unknown();
 export type SubLinkProvider = z.infer<typeof SubLinkProviderSchema>;
 
+export const TrafficResetNotifierSchema = z.unknown();
+export type TrafficResetNotifier = z.infer<typeof TrafficResetNotifierSchema>;
+
 export const transportBitsSchema = z.number().int();
 export type transportBits = z.infer<typeof transportBitsSchema>;
 
@@ -76,15 +88,54 @@ export const AllSettingSchema = z.object({
   subURI: z.string(),
   subUpdates: z.number().int().min(0).max(525600),
   tgBotAPIServer: z.string(),
+  tgBotAdminMenu: z.string(),
   tgBotBackup: z.boolean(),
+  tgBotChannelCategories: z.string(),
+  tgBotChannelId: z.string(),
   tgBotChatId: z.string(),
+  tgBotClientMenu: z.string(),
   tgBotEnable: z.boolean(),
+  tgBotExtraBots: z.string(),
+  tgBotFallbackWebhook: z.string(),
+  tgBotJsonLog: z.boolean(),
   tgBotLoginNotify: z.boolean(),
   tgBotProxy: z.string(),
+  tgBotStartupNotify: z.boolean(),
   tgBotToken: z.string(),
+  tgButtonTTL: z.number().int().min(0).max(10080),
+  tgCertExpiryDays: z.number().int().min(0).max(365),
+  tgClientLimitInterval: z.number().int().min(0).max(3600),
+  tgClientUsageDays: z.number().int().min(1).max(90),
+  tgClientUsageInterval: z.number().int().min(0).max(60),
+  tgCommandAliases: z.string(),
   tgCpu: z.number().int().min(0).max(100),
+  tgCpuWindow: z.number().int().min(10).max(3600),
+  tgDebugStats: z.boolean(),
+  tgGeoMaxAge: z.number().int().min(0).max(3650),
   tgLang: z.string(),
+  tgNumberFormat: z.enum(['plain', 'en', 'eu', 'fr', 'ch']),
+  tgOnlineHistoryDays: z.number().int().min(1).max(365),
+  tgPinCritical: z.boolean(),
+  tgQuietEnd: z.string(),
+  tgQuietStart: z.string(),
+  tgReportDisabledInbounds: z.boolean(),
+  tgReportFileThreshold: z.number().int().min(0),
+  tgReportNoInboundsNote: z.boolean(),
+  tgReportSections: z.string(),
+  tgReportSparklines: z.number().int().min(0).max(10),
+  tgReportSummaryThreshold: z.number().int().min(0),
+  tgReportSummaryTop: z.number().int().min(1).max(50),
   tgRunTime: z.string(),
+  tgServerAddress: z.string(),
+  tgSeverityEmoji: z.boolean(),
+  tgSilentCategories: z.string(),
+  tgStatusClientCounts: z.boolean(),
+  tgTrafficDecimals: z.number().int().min(0).max(4),
+  tgTrafficHistoryDays: z.number().int().min(1).max(365),
+  tgTrafficResetNotify: z.boolean(),
+  tgTrafficUnits: z.enum(['binary', 'iec', 'si']),
+  tgXrayStartRetries: z.number().int().min(0).max(5),
+  tgXrayStartTimeout: z.number().int().min(2).max(300),
   timeLocation: z.string(),
   trafficDiff: z.number().int().min(0).max(100),
   trustedProxyCIDRs: z.string(),
@@ -108,6 +159,7 @@ export const AllSettingViewSchema = z.object({
   hasApiToken: z.boolean(),
   hasLdapPassword: z.boolean(),
   hasNordSecret: z.boolean(),
+  hasTgBotExtraBots: z.boolean(),
   hasTgBotToken: z.boolean(),
   hasTwoFactorToken: z.boolean(),
   hasWarpSecret: z.boolean(),
@@ -167,15 +219,54 @@ export const AllSettingViewSchema = z.object({
   subURI: z.string(),
   subUpdates: z.number().int().min(0).max(525600),
   tgBotAPIServer: z.string(),
+  tgBotAdminMenu: z.string(),
   tgBotBackup: z.boolean(),
+  tgBotChannelCategories: z.string(),
+  tgBotChannelId: z.string(),
   tgBotChatId: z.string(),
+  tgBotClientMenu: z.string(),
   tgBotEnable: z.boolean(),
+  tgBotExtraBots: z.string(),
+  tgBotFallbackWebhook: z.string(),
+  tgBotJsonLog: z.boolean(),
   tgBotLoginNotify: z.boolean(),
   tgBotProxy: z.string(),
+  tgBotStartupNotify: z.boolean(),
   tgBotToken: z.string(),
+  tgButtonTTL: z.number().int().min(0).max(10080),
+  tgCertExpiryDays: z.number().int().min(0).max(365),
+  tgClientLimitInterval: z.number().int().min(0).max(3600),
+  tgClientUsageDays: z.number().int().min(1).max(90),
+  tgClientUsageInterval: z.number().int().min(0).max(60),
+  tgCommandAliases: z.string(),
   tgCpu: z.number().int().min(0).max(100),
+  tgCpuWindow: z.number().int().min(10).max(3600),
+  tgDebugStats: z.boolean(),
+  tgGeoMaxAge: z.number().int().min(0).max(3650),
   tgLang: z.string(),
+  tgNumberFormat: z.enum(['plain', 'en', 'eu', 'fr', 'ch']),
+  tgOnlineHistoryDays: z.number().int().min(1).max(365),
+  tgPinCritical: z.boolean(),
+  tgQuietEnd: z.string(),
+  tgQuietStart: z.string(),
+  tgReportDisabledInbounds: z.boolean(),
+  tgReportFileThreshold: z.number().int().min(0),
+  tgReportNoInboundsNote: z.boolean(),
+  tgReportSections: z.string(),
+  tgReportSparklines: z.number().int().min(0).max(10),
+  tgReportSummaryThreshold: z.number().int().min(0),
+  tgReportSummaryTop: z.number().int().min(1).max(50),
   tgRunTime: z.string(),
+  tgServerAddress: z.string(),
+  tgSeverityEmoji: z.boolean(),
+  tgSilentCategories: z.string(),
+  tgStatusClientCounts: z.boolean(),
+  tgTrafficDecimals: z.number().int().min(0).max(4),
+  tgTrafficHistoryDays: z.number().int().min(1).max(365),
+  tgTrafficResetNotify: z.boolean(),
+  tgTrafficUnits: z.enum(['binary', 'iec', 'si']),
+  tgXrayStartRetries: z.number().int().min(0).max(5),
+  tgXrayStartTimeout: z.number().int().min(2).max(300),
   timeLocation: z.string(),
   trafficDiff: z.number().int().min(0).max(100),
   trustedProxyCIDRs: z.string(),
@@ -296,6 +387,8 @@ export const HistoryOfSeedersSchema = z.object({
 export type HistoryOfSeeders = z.infer<typeof HistoryOfSeedersSchema>;
 
 export const InboundSchema = z.object({
+  alertExpiryDays: z.number().int().nullable().optional(),
+  alertTrafficPercent: z.number().int().nullable().optional(),
   clientStats: z.array(z.lazy(() => ClientTrafficSchema)),
   down: z.number().int(),
   enable: z.boolean(),
@@ -305,6 +398,7 @@ export const InboundSchema = z.object({
   lastTrafficResetTime: z.number().int(),
   listen: z.string(),
   nodeId: z.number().int().nullable().optional(),
+  note: z.string().optional(),
   originNodeGuid: z.string().optional(),
   port: z.number().int().min(0).max(65535),
   protocol: z.enum(['vmess', 'vless', 'trojan', 'shadowsocks', 'wireguard', 'hysteria', 'http', 'mixed', 'tunnel', 'tun', 'mtproto']),
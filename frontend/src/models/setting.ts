@@ -24,7 +24,46 @@ export class AllSetting {
   tgBotBackup = false;
   tgBotLoginNotify = true;
   tgCpu = 80;
+  tgCpuWindow = 60;
   tgLang = 'en-US';
+  tgBotExtraBots = '';
+  tgBotFallbackWebhook = '';
+  tgBotChannelId = '';
+  tgBotChannelCategories = 'report';
+  tgBotJsonLog = false;
+  tgBotStartupNotify = true;
+  tgTrafficUnits = 'binary';
+  tgTrafficDecimals = 2;
+  tgNumberFormat = 'plain';
+  tgSeverityEmoji = true;
+  tgPinCritical = false;
+  tgQuietStart = '';
+  tgQuietEnd = '';
+  tgBotAdminMenu = '';
+  tgBotClientMenu = '';
+  tgReportFileThreshold = 0;
+  tgReportDisabledInbounds = true;
+  tgStatusClientCounts = true;
+  tgOnlineHistoryDays = 30;
+  tgTrafficHistoryDays = 15;
+  tgClientUsageInterval = 15;
+  tgClientUsageDays = 7;
+  tgClientLimitInterval = 60;
+  tgServerAddress = '';
+  tgButtonTTL = 60;
+  tgXrayStartTimeout = 10;
+  tgXrayStartRetries = 2;
+  tgReportSparklines = 3;
+  tgCertExpiryDays = 14;
+  tgReportSections = 'online,traffic';
+  tgReportNoInboundsNote = true;
+  tgTrafficResetNotify = true;
+  tgDebugStats = false;
+  tgGeoMaxAge = 30;
+  tgCommandAliases = '';
+  tgSilentCategories = 'report,reset';
+  tgReportSummaryThreshold = 100;
+  tgReportSummaryTop = 10;
   twoFactorEnable = false;
   twoFactorToken = '';
   xrayTemplateConfig = '';
@@ -85,6 +124,7 @@ export class AllSetting {
   ldapDefaultExpiryDays = 0;
   ldapDefaultLimitIP = 0;
   hasTgBotToken = false;
+  hasTgBotExtraBots = false;
   hasTwoFactorToken = false;
   hasLdapPassword = false;
   hasApiToken = false;
@@ -17,10 +17,11 @@ interface TelegramTabProps {
 // web.go startTask), which accepts @every <duration>, the @hourly/@daily/...
 // macros, and full crontab expressions. This builder covers the common cases
 // with dropdowns so users don't have to memorise the syntax, while "Custom"
-// preserves the raw crontab escape hatch.
+// preserves the raw crontab escape hatch. "off" (or an empty value) disables
+// the scheduled report entirely.
 type Unit = 's' | 'm' | 'h';
 type Macro = '@hourly' | '@daily' | '@weekly' | '@monthly';
-type Mode = 'every' | Macro | 'custom';
+type Mode = 'off' | 'every' | Macro | 'custom';
 const MACROS: Macro[] = ['@hourly', '@daily', '@weekly', '@monthly'];
 const EVERY_RE = /^@every\s+(\d+)\s*([smh])$/i;
 
@@ -33,6 +34,9 @@ interface RunTime {
 
 function parseRunTime(raw: string): RunTime {
   const v = (raw ?? '').trim();
+  if (v === '' || v.toLowerCase() === 'off') {
+    return { mode: 'off', num: 1, unit: 'h', custom: '' };
+  }
   const m = v.match(EVERY_RE);
   if (m) {
     return { mode: 'every', num: Math.max(1, Number(m[1]) || 1), unit: m[2].toLowerCase() as Unit, custom: '' };
@@ -44,6 +48,7 @@ function parseRunTime(raw: string): RunTime {
 }
 
 function composeRunTime(s: RunTime): string {
+  if (s.mode === 'off') return 'off';
   if (s.mode === 'every') return `@every ${Math.max(1, s.num || 1)}${s.unit}`;
   if (s.mode === 'custom') return s.custom;
   return s.mode;
@@ -93,6 +98,7 @@ function NotifyTimeField({ value, onChange }: { value: string; onChange: (v: str
   }
 
   const modeOptions = [
+    { value: 'off', label: t('pages.settings.notifyTime.off') },
     { value: 'every', label: t('pages.settings.notifyTime.every') },
     { value: '@hourly', label: t('pages.settings.notifyTime.hourly') },
     { value: '@daily', label: t('pages.settings.notifyTime.daily') },
@@ -194,10 +200,74 @@ export default function TelegramTab({ allSetting, updateSetting }: TelegramTabPr
               />
             </SettingListItem>
 
+            <SettingListItem paddings="small" title={t('pages.settings.tgTrafficUnits')} description={t('pages.settings.tgTrafficUnitsDesc')}>
+              <Select
+                value={allSetting.tgTrafficUnits}
+                onChange={(v) => updateSetting({ tgTrafficUnits: v })}
+                style={{ width: '100%' }}
+                options={[
+                  { value: 'binary', label: 'KB, MB, GB (1024)' },
+                  { value: 'iec', label: 'KiB, MiB, GiB (1024)' },
+                  { value: 'si', label: 'kB, MB, GB (1000)' },
+                ]}
+              />
+            </SettingListItem>
+            <SettingListItem paddings="small" title={t('pages.settings.tgTrafficDecimals')} description={t('pages.settings.tgTrafficDecimalsDesc')}>
+              <InputNumber value={allSetting.tgTrafficDecimals} min={0} max={4} style={{ width: '100%' }}
+                onChange={(v) => updateSetting({ tgTrafficDecimals: Number(v) || 0 })} />
+            </SettingListItem>
+            <SettingListItem paddings="small" title={t('pages.settings.tgNumberFormat')} description={t('pages.settings.tgNumberFormatDesc')}>
+              <Select
+                value={allSetting.tgNumberFormat}
+                onChange={(v) => updateSetting({ tgNumberFormat: v })}
+                style={{ width: '100%' }}
+                options={[
+                  { value: 'plain', label: '1234567.89' },
+                  { value: 'en', label: '1,234,567.89' },
+                  { value: 'eu', label: '1.234.567,89' },
+                  { value: 'fr', label: '1 234 567,89' },
+                  { value: 'ch', label: "1'234'567.89" },
+                ]}
+              />
+            </SettingListItem>
+            <SettingListItem paddings="small" title={t('pages.settings.tgSeverityEmoji')} description={t('pages.settings.tgSeverityEmojiDesc')}>
+              <Switch checked={allSetting.tgSeverityEmoji} onChange={(v) => updateSetting({ tgSeverityEmoji: v })} />
+            </SettingListItem>
+            <SettingListItem paddings="small" title={t('pages.settings.tgPinCritical')} description={t('pages.settings.tgPinCriticalDesc')}>
+              <Switch checked={allSetting.tgPinCritical} onChange={(v) => updateSetting({ tgPinCritical: v })} />
+            </SettingListItem>
+
+            <SettingListItem paddings="small" title={t('pages.settings.tgAdminMenu')} description={t('pages.settings.tgAdminMenuDesc')}>
+              <Input value={allSetting.tgBotAdminMenu} placeholder="serverUsage;inbounds,onlines;backup"
+                onChange={(e) => updateSetting({ tgBotAdminMenu: e.target.value })} />
+            </SettingListItem>
+            <SettingListItem paddings="small" title={t('pages.settings.tgClientMenu')} description={t('pages.settings.tgClientMenuDesc')}>
+              <Input value={allSetting.tgBotClientMenu} placeholder="usage,commands;subLinks,qrLinks"
+                onChange={(e) => updateSetting({ tgBotClientMenu: e.target.value })} />
+            </SettingListItem>
+
+            <SettingListItem paddings="small" title={t('pages.settings.tgJsonLog')} description={t('pages.settings.tgJsonLogDesc')}>
+              <Switch checked={allSetting.tgBotJsonLog} onChange={(v) => updateSetting({ tgBotJsonLog: v })} />
+            </SettingListItem>
+
             <SettingListItem paddings="small" title={t('pages.settings.telegramAPIServer')} description={t('pages.settings.telegramAPIServerDesc')}>
               <Input value={allSetting.tgBotAPIServer} placeholder="https://api.example.com"
                 onChange={(e) => updateSetting({ tgBotAPIServer: e.target.value })} />
             </SettingListItem>
+
+            <SettingListItem paddings="small" title={t('pages.settings.tgFallbackWebhook')} description={t('pages.settings.tgFallbackWebhookDesc')}>
+              <Input value={allSetting.tgBotFallbackWebhook} placeholder="https://hooks.example.com/3x-ui"
+                onChange={(e) => updateSetting({ tgBotFallbackWebhook: e.target.value })} />
+            </SettingListItem>
+
+            <SettingListItem paddings="small" title={t('pages.settings.tgChannelId')} description={t('pages.settings.tgChannelIdDesc')}>
+              <Input value={allSetting.tgBotChannelId} placeholder="-1001234567890"
+                onChange={(e) => updateSetting({ tgBotChannelId: e.target.value })} />
+            </SettingListItem>
+            <SettingListItem paddings="small" title={t('pages.settings.tgChannelCategories')} description={t('pages.settings.tgChannelCategoriesDesc')}>
+              <Input value={allSetting.tgBotChannelCategories} placeholder="report,xray"
+                onChange={(e) => updateSetting({ tgBotChannelCategories: e.target.value })} />
+            </SettingListItem>
           </>
         ),
       },
@@ -212,13 +282,139 @@ export default function TelegramTab({ allSetting, updateSetting }: TelegramTabPr
             <SettingListItem paddings="small" title={t('pages.settings.tgNotifyBackup')} description={t('pages.settings.tgNotifyBackupDesc')}>
               <Switch checked={allSetting.tgBotBackup} onChange={(v) => updateSetting({ tgBotBackup: v })} />
             </SettingListItem>
+            <SettingListItem paddings="small" title={t('pages.settings.tgReportFileThreshold')} description={t('pages.settings.tgReportFileThresholdDesc')}>
+              <InputNumber value={allSetting.tgReportFileThreshold} min={0} step={1000} style={{ width: '100%' }}
+                onChange={(v) => updateSetting({ tgReportFileThreshold: Number(v) || 0 })} />
+            </SettingListItem>
+            <SettingListItem paddings="small" title={t('pages.settings.tgReportSummaryThreshold')} description={t('pages.settings.tgReportSummaryThresholdDesc')}>
+              <InputNumber value={allSetting.tgReportSummaryThreshold} min={0} style={{ width: '100%' }}
+                onChange={(v) => updateSetting({ tgReportSummaryThreshold: Number(v) || 0 })} />
+            </SettingListItem>
+            <SettingListItem paddings="small" title={t('pages.settings.tgReportSummaryTop')} description={t('pages.settings.tgReportSummaryTopDesc')}>
+              <InputNumber value={allSetting.tgReportSummaryTop} min={1} max={50} style={{ width: '100%' }}
+                onChange={(v) => updateSetting({ tgReportSummaryTop: Number(v) || 10 })} />
+            </SettingListItem>
+            <SettingListItem paddings="small" title={t('pages.settings.tgReportDisabledInbounds')} description={t('pages.settings.tgReportDisabledInboundsDesc')}>
+              <Switch checked={allSetting.tgReportDisabledInbounds} onChange={(v) => updateSetting({ tgReportDisabledInbounds: v })} />
+            </SettingListItem>
+            <SettingListItem paddings="small" title={t('pages.settings.tgStatusClientCounts')} description={t('pages.settings.tgStatusClientCountsDesc')}>
+              <Switch checked={allSetting.tgStatusClientCounts} onChange={(v) => updateSetting({ tgStatusClientCounts: v })} />
+            </SettingListItem>
+            <SettingListItem paddings="small" title={t('pages.settings.tgOnlineHistoryDays')} description={t('pages.settings.tgOnlineHistoryDaysDesc')}>
+              <InputNumber value={allSetting.tgOnlineHistoryDays} min={1} max={365} style={{ width: '100%' }}
+                onChange={(v) => updateSetting({ tgOnlineHistoryDays: Number(v) || 30 })} />
+            </SettingListItem>
+            <SettingListItem paddings="small" title={t('pages.settings.tgTrafficHistoryDays')} description={t('pages.settings.tgTrafficHistoryDaysDesc')}>
+              <InputNumber value={allSetting.tgTrafficHistoryDays} min={1} max={365} style={{ width: '100%' }}
+                onChange={(v) => updateSetting({ tgTrafficHistoryDays: Number(v) || 15 })} />
+            </SettingListItem>
+            <SettingListItem paddings="small" title={t('pages.settings.tgClientUsageInterval')} description={t('pages.settings.tgClientUsageIntervalDesc')}>
+              <InputNumber value={allSetting.tgClientUsageInterval} min={0} max={60} style={{ width: '100%' }}
+                onChange={(v) => updateSetting({ tgClientUsageInterval: Number(v ?? 15) })} />
+            </SettingListItem>
+            <SettingListItem paddings="small" title={t('pages.settings.tgClientUsageDays')} description={t('pages.settings.tgClientUsageDaysDesc')}>
+              <InputNumber value={allSetting.tgClientUsageDays} min={1} max={90} style={{ width: '100%' }}
+                onChange={(v) => updateSetting({ tgClientUsageDays: Number(v) || 7 })} />
+            </SettingListItem>
+            <SettingListItem paddings="small" title={t('pages.settings.tgClientLimitInterval')} description={t('pages.settings.tgClientLimitIntervalDesc')}>
+              <InputNumber value={allSetting.tgClientLimitInterval} min={0} max={3600} style={{ width: '100%' }}
+                onChange={(v) => updateSetting({ tgClientLimitInterval: Number(v ?? 60) })} />
+            </SettingListItem>
+            <SettingListItem paddings="small" title={t('pages.settings.tgServerAddress')} description={t('pages.settings.tgServerAddressDesc')}>
+              <Input value={allSetting.tgServerAddress} placeholder="203.0.113.10"
+                onChange={(e) => updateSetting({ tgServerAddress: e.target.value })} />
+            </SettingListItem>
+            <SettingListItem paddings="small" title={t('pages.settings.tgButtonTTL')} description={t('pages.settings.tgButtonTTLDesc')}>
+              <InputNumber value={allSetting.tgButtonTTL} min={0} max={10080} style={{ width: '100%' }}
+                onChange={(v) => updateSetting({ tgButtonTTL: Number(v ?? 60) })} />
+            </SettingListItem>
+            <SettingListItem paddings="small" title={t('pages.settings.tgXrayStartTimeout')} description={t('pages.settings.tgXrayStartTimeoutDesc')}>
+              <InputNumber value={allSetting.tgXrayStartTimeout} min={2} max={300} style={{ width: '100%' }}
+                onChange={(v) => updateSetting({ tgXrayStartTimeout: Number(v ?? 10) })} />
+            </SettingListItem>
+            <SettingListItem paddings="small" title={t('pages.settings.tgXrayStartRetries')} description={t('pages.settings.tgXrayStartRetriesDesc')}>
+              <InputNumber value={allSetting.tgXrayStartRetries} min={0} max={5} style={{ width: '100%' }}
+                onChange={(v) => updateSetting({ tgXrayStartRetries: Number(v ?? 2) })} />
+            </SettingListItem>
+            <SettingListItem paddings="small" title={t('pages.settings.tgReportSparklines')} description={t('pages.settings.tgReportSparklinesDesc')}>
+              <InputNumber value={allSetting.tgReportSparklines} min={0} max={10} style={{ width: '100%' }}
+                onChange={(v) => updateSetting({ tgReportSparklines: Number(v ?? 3) })} />
+            </SettingListItem>
+            <SettingListItem paddings="small" title={t('pages.settings.tgCertExpiryDays')} description={t('pages.settings.tgCertExpiryDaysDesc')}>
+              <InputNumber value={allSetting.tgCertExpiryDays} min={0} max={365} style={{ width: '100%' }}
+                onChange={(v) => updateSetting({ tgCertExpiryDays: Number(v ?? 14) })} />
+            </SettingListItem>
+            <SettingListItem paddings="small" title={t('pages.settings.tgReportSections')} description={t('pages.settings.tgReportSectionsDesc')}>
+              <Select
+                mode="multiple"
+                value={allSetting.tgReportSections.split(',').filter(Boolean)}
+                onChange={(v: string[]) => updateSetting({ tgReportSections: v.join(',') })}
+                style={{ width: '100%' }}
+                options={['traffic', 'top', 'expiring', 'resources', 'online', 'logins'].map((section) => ({
+                  value: section,
+                  label: t(`pages.settings.tgReportSection.${section}`),
+                }))}
+              />
+            </SettingListItem>
+            <SettingListItem paddings="small" title={t('pages.settings.tgReportNoInboundsNote')} description={t('pages.settings.tgReportNoInboundsNoteDesc')}>
+              <Switch checked={allSetting.tgReportNoInboundsNote} onChange={(v) => updateSetting({ tgReportNoInboundsNote: v })} />
+            </SettingListItem>
+            <SettingListItem paddings="small" title={t('pages.settings.tgTrafficResetNotify')} description={t('pages.settings.tgTrafficResetNotifyDesc')}>
+              <Switch checked={allSetting.tgTrafficResetNotify} onChange={(v) => updateSetting({ tgTrafficResetNotify: v })} />
+            </SettingListItem>
+            <SettingListItem paddings="small" title={t('pages.settings.tgDebugStats')} description={t('pages.settings.tgDebugStatsDesc')}>
+              <Switch checked={allSetting.tgDebugStats} onChange={(v) => updateSetting({ tgDebugStats: v })} />
+            </SettingListItem>
+            <SettingListItem paddings="small" title={t('pages.settings.tgGeoMaxAge')} description={t('pages.settings.tgGeoMaxAgeDesc')}>
+              <InputNumber value={allSetting.tgGeoMaxAge} min={0} max={3650} style={{ width: '100%' }}
+                onChange={(v) => updateSetting({ tgGeoMaxAge: Number(v) || 0 })} />
+            </SettingListItem>
+            <SettingListItem paddings="small" title={t('pages.settings.tgCommandAliases')} description={t('pages.settings.tgCommandAliasesDesc')}>
+              <Input value={allSetting.tgCommandAliases} placeholder="s=status,r=reportnow"
+                onChange={(e) => updateSetting({ tgCommandAliases: e.target.value })} />
+            </SettingListItem>
             <SettingListItem paddings="small" title={t('pages.settings.tgNotifyLogin')} description={t('pages.settings.tgNotifyLoginDesc')}>
               <Switch checked={allSetting.tgBotLoginNotify} onChange={(v) => updateSetting({ tgBotLoginNotify: v })} />
             </SettingListItem>
+            <SettingListItem paddings="small" title={t('pages.settings.tgNotifyStartup')} description={t('pages.settings.tgNotifyStartupDesc')}>
+              <Switch checked={allSetting.tgBotStartupNotify} onChange={(v) => updateSetting({ tgBotStartupNotify: v })} />
+            </SettingListItem>
+            <SettingListItem paddings="small" title={t('pages.settings.tgSilentCategories')} description={t('pages.settings.tgSilentCategoriesDesc')}>
+              <Input value={allSetting.tgSilentCategories} placeholder="report,reset"
+                onChange={(e) => updateSetting({ tgSilentCategories: e.target.value })} />
+            </SettingListItem>
+            <SettingListItem paddings="small" title={t('pages.settings.tgQuietHours')} description={t('pages.settings.tgQuietHoursDesc')}>
+              <Space.Compact style={{ width: '100%' }}>
+                <Input value={allSetting.tgQuietStart} placeholder="23:00"
+                  onChange={(e) => updateSetting({ tgQuietStart: e.target.value })} />
+                <Input value={allSetting.tgQuietEnd} placeholder="07:00"
+                  onChange={(e) => updateSetting({ tgQuietEnd: e.target.value })} />
+              </Space.Compact>
+            </SettingListItem>
             <SettingListItem paddings="small" title={t('pages.settings.tgNotifyCpu')} description={t('pages.settings.tgNotifyCpuDesc')}>
               <InputNumber value={allSetting.tgCpu} min={0} max={100} style={{ width: '100%' }}
                 onChange={(v) => updateSetting({ tgCpu: Number(v) || 0 })} />
             </SettingListItem>
+            <SettingListItem paddings="small" title={t('pages.settings.tgNotifyCpuWindow')} description={t('pages.settings.tgNotifyCpuWindowDesc')}>
+              <InputNumber value={allSetting.tgCpuWindow} min={10} max={3600} style={{ width: '100%' }}
+                onChange={(v) => updateSetting({ tgCpuWindow: Number(v) || 60 })} />
+            </SettingListItem>
+            <SettingListItem
+              paddings="small"
+              title="Extra notification bots"
+              description={allSetting.hasTgBotExtraBots
+                ? 'Configured; leave blank to keep the current list.'
+                : 'JSON list of send-only bots, each with its own token, chat IDs, categories (report, login, cpu, xray) and report schedule.'}
+            >
+              <Input.TextArea
+                value={allSetting.tgBotExtraBots}
+                autoSize={{ minRows: 2, maxRows: 10 }}
+                placeholder={allSetting.hasTgBotExtraBots
+                  ? 'Configured - enter a new list to replace'
+                  : '[{"name":"customers","token":"","chatId":"","categories":["report"],"runTime":"@daily"}]'}
+                onChange={(e) => updateSetting({ tgBotExtraBots: e.target.value })}
+              />
+            </SettingListItem>
           </>
         ),
       },
@@ -28,7 +28,46 @@ export const AllSettingSchema = z.object({
   tgBotBackup: z.boolean().optional(),
   tgBotLoginNotify: z.boolean().optional(),
   tgCpu: z.number().int().min(0).max(100).optional(),
+  tgCpuWindow: z.number().int().min(10).max(3600).optional(),
   tgLang: z.string().optional(),
+  tgBotExtraBots: z.string().optional(),
+  tgBotFallbackWebhook: z.string().optional(),
+  tgBotChannelId: z.string().optional(),
+  tgBotChannelCategories: z.string().optional(),
+  tgBotJsonLog: z.boolean().optional(),
+  tgBotStartupNotify: z.boolean().optional(),
+  tgTrafficUnits: z.enum(['binary', 'iec', 'si']).optional(),
+  tgTrafficDecimals: z.number().int().min(0).max(4).optional(),
+  tgNumberFormat: z.enum(['plain', 'en', 'eu', 'fr', 'ch']).optional(),
+  tgSeverityEmoji: z.boolean().optional(),
+  tgPinCritical: z.boolean().optional(),
+  tgQuietStart: z.string().optional(),
+  tgQuietEnd: z.string().optional(),
+  tgBotAdminMenu: z.string().optional(),
+  tgBotClientMenu: z.string().optional(),
+  tgReportFileThreshold: z.number().int().min(0).optional(),
+  tgReportDisabledInbounds: z.boolean().optional(),
+  tgStatusClientCounts: z.boolean().optional(),
+  tgOnlineHistoryDays: z.number().int().min(1).max(365).optional(),
+  tgTrafficHistoryDays: z.number().int().min(1).max(365).optional(),
+  tgClientUsageInterval: z.number().int().min(0).max(60).optional(),
+  tgClientUsageDays: z.number().int().min(1).max(90).optional(),
+  tgClientLimitInterval: z.number().int().min(0).max(3600).optional(),
+  tgServerAddress: z.string().optional(),
+  tgButtonTTL: z.number().int().min(0).max(10080).optional(),
+  tgXrayStartTimeout: z.number().int().min(2).max(300).optional(),
+  tgXrayStartRetries: z.number().int().min(0).max(5).optional(),
+  tgReportSparklines: z.number().int().min(0).max(10).optional(),
+  tgCertExpiryDays: z.number().int().min(0).max(365).optional(),
+  tgReportSections: z.string().optional(),
+  tgReportNoInboundsNote: z.boolean().optional(),
+  tgTrafficResetNotify: z.boolean().optional(),
+  tgDebugStats: z.boolean().optional(),
+  tgGeoMaxAge: z.number().int().min(0).max(3650).optional(),
+  tgCommandAliases: z.string().optional(),
+  tgSilentCategories: z.string().optional(),
+  tgReportSummaryThreshold: z.number().int().min(0).optional(),
+  tgReportSummaryTop: z.number().int().min(1).max(50).optional(),
   twoFactorEnable: z.boolean().optional(),
   twoFactorToken: z.string().optional(),
   xrayTemplateConfig: z.string().optional(),
@@ -86,6 +125,7 @@ export const AllSettingSchema = z.object({
   ldapDefaultExpiryDays: nonNegativeInt.optional(),
   ldapDefaultLimitIP: nonNegativeInt.optional(),
   hasTgBotToken: z.boolean().optional(),
+  hasTgBotExtraBots: z.boolean().optional(),
   hasTwoFactorToken: z.boolean().optional(),
   hasLdapPassword: z.boolean().optional(),
   hasApiToken: z.boolean().optional(),
@@ -74,6 +74,17 @@ func initModels() error {
 		&model.NodeClientTraffic{},
 		&model.ClientGlobalTraffic{},
 		&model.OutboundSubscription{},
+		&model.OnlineSample{},
+		&model.InboundSchedule{},
+		&model.InboundTrafficSnapshot{},
+		&model.ClientTrafficBoost{},
+		&model.Reminder{},
+		&model.ClientDisableReason{},
+		&model.ClientUsageSample{},
+		&model.SettingChange{},
+		&model.PendingNotification{},
+		&model.AlertEvent{},
+		&model.TrafficReconciliation{},
 	}
 	for _, mdl := range models {
 		if err := db.AutoMigrate(mdl); err != nil {
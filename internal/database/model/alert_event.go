@@ -0,0 +1,11 @@
+package model
+
+// AlertEvent records one alert about a client that fired, such as a traffic
+// limit reached or a client disabled, so admins who were away can catch up
+// with /events. Events older than the retention are pruned.
+type AlertEvent struct {
+	Id      int    `json:"id" gorm:"primaryKey;autoIncrement"`
+	Type    string `json:"type" gorm:"index;not null"`
+	Email   string `json:"email"`
+	FiredAt int64  `json:"firedAt" gorm:"index"` // unix milliseconds
+}
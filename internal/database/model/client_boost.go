@@ -0,0 +1,13 @@
+package model
+
+// ClientTrafficBoost is a temporary increase of a client's traffic limit.
+// The bytes are added to the client's limit when granted and taken off again
+// when the boost is revoked or the client's inbound reaches its periodic
+// traffic reset, so the row tells the boost apart from the base limit.
+type ClientTrafficBoost struct {
+	Id        int    `json:"id" gorm:"primaryKey;autoIncrement"`
+	Email     string `json:"email" gorm:"uniqueIndex;not null"`
+	Bytes     int64  `json:"bytes" gorm:"not null"`
+	GrantedBy string `json:"grantedBy"`
+	GrantedAt int64  `json:"grantedAt"` // unix milliseconds
+}
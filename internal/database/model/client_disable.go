@@ -0,0 +1,12 @@
+package model
+
+// ClientDisableReason records why and by whom a client was disabled, so
+// other admins can tell later. It is dropped when the client is enabled
+// again.
+type ClientDisableReason struct {
+	Id         int    `json:"id" gorm:"primaryKey;autoIncrement"`
+	Email      string `json:"email" gorm:"uniqueIndex;not null"`
+	Reason     string `json:"reason"`
+	DisabledBy string `json:"disabledBy"`
+	DisabledAt int64  `json:"disabledAt"` // unix milliseconds
+}
@@ -0,0 +1,12 @@
+package model
+
+// ClientUsageSample is the traffic a client moved since the previous
+// sample. Only clients that moved traffic get a row, so idle clients take
+// no room. Rows older than the tgClientUsageDays setting are pruned daily.
+type ClientUsageSample struct {
+	Id        int    `json:"id" gorm:"primaryKey;autoIncrement"`
+	Email     string `json:"email" gorm:"index:idx_client_usage_email_at,priority:1;not null"`
+	SampledAt int64  `json:"sampledAt" gorm:"index:idx_client_usage_email_at,priority:2;index;not null"` // unix seconds
+	Up        int64  `json:"up"`
+	Down      int64  `json:"down"`
+}
@@ -0,0 +1,12 @@
+package model
+
+// InboundSchedule is a cron expression that enables or disables an inbound
+// or resets its traffic.
+// An inbound has at most one schedule per action; the panel cron runs them
+// and re-registers every row on start.
+type InboundSchedule struct {
+	Id        int    `json:"id" gorm:"primaryKey;autoIncrement"`
+	InboundId int    `json:"inboundId" gorm:"uniqueIndex:idx_inbound_schedule_action;not null"`
+	Action    string `json:"action" gorm:"uniqueIndex:idx_inbound_schedule_action;not null"` // "enable", "disable" or "reset"
+	Spec      string `json:"spec" gorm:"not null"`                                           // cron expression with seconds
+}
@@ -50,12 +50,15 @@ type Inbound struct {
 	Down                 int64                `json:"down" form:"down"`                                                                                                                                             // Download traffic in bytes
 	Total                int64                `json:"total" form:"total"`                                                                                                                                           // Total traffic limit in bytes
 	Remark               string               `json:"remark" form:"remark" example:"VLESS-443"`                                                                                                                     // Human-readable remark
+	Note                 string               `json:"note,omitempty" form:"note"`                                                                                                                                   // Free-text operator note (e.g. customer name or contact)
 	SubSortIndex         int                  `json:"subSortIndex" form:"subSortIndex" gorm:"default:1" validate:"omitempty,gte=1" example:"1"`                                                                     // 1-based sort order of this inbound's links in subscription output only (lower first; ties by id)
 	Enable               bool                 `json:"enable" form:"enable" gorm:"index:idx_enable_traffic_reset,priority:1" example:"true"`                                                                         // Whether the inbound is enabled
 	ExpiryTime           int64                `json:"expiryTime" form:"expiryTime"`                                                                                                                                 // Expiration timestamp
 	TrafficReset         string               `json:"trafficReset" form:"trafficReset" gorm:"default:never;index:idx_enable_traffic_reset,priority:2" validate:"omitempty,oneof=never hourly daily weekly monthly"` // Traffic reset schedule
 	LastTrafficResetTime int64                `json:"lastTrafficResetTime" form:"lastTrafficResetTime" gorm:"default:0"`                                                                                            // Last traffic reset timestamp
 	ClientStats          []xray.ClientTraffic `gorm:"foreignKey:InboundId;references:Id" json:"clientStats" form:"clientStats"`                                                                                     // Client traffic statistics
+	AlertTrafficPercent  *int                 `json:"alertTrafficPercent,omitempty" form:"alertTrafficPercent"`                                                                                                     // Near-limit alert once this % of the traffic limit is used; unset uses the panel's trafficDiff
+	AlertExpiryDays      *int                 `json:"alertExpiryDays,omitempty" form:"alertExpiryDays"`                                                                                                             // Near-expiry alert this many days ahead, 0 for none; unset uses the panel's expireDiff
 
 	// Xray configuration fields
 	Listen            string   `json:"listen" form:"listen"`
@@ -0,0 +1,10 @@
+package model
+
+// OnlineSample is one point of the online client count history used by the
+// Telegram report for daily peak/average figures. Rows older than the
+// tgOnlineHistoryDays setting are pruned daily, so the table stays bounded.
+type OnlineSample struct {
+	Id        int   `json:"id" gorm:"primaryKey;autoIncrement"`
+	SampledAt int64 `json:"sampledAt" gorm:"index;not null"` // unix seconds
+	Count     int   `json:"count"`
+}
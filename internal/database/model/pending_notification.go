@@ -0,0 +1,16 @@
+package model
+
+// PendingNotification is a critical bot notification waiting to be delivered
+// to one chat. It is deleted only once Telegram accepted it, so one that was
+// cut off by a crash or restart is sent again when the bot comes back.
+type PendingNotification struct {
+	Id            int    `json:"id" gorm:"primaryKey;autoIncrement"`
+	Category      string `json:"category"`
+	ChatId        int64  `json:"chatId" gorm:"index;not null"`
+	Text          string `json:"text" gorm:"not null"`
+	ReplyMarkup   string `json:"replyMarkup"` // JSON inline keyboard, if any
+	Attempts      int    `json:"attempts"`
+	LastError     string `json:"lastError"`
+	CreatedAt     int64  `json:"createdAt"`     // unix milliseconds
+	NextAttemptAt int64  `json:"nextAttemptAt"` // unix milliseconds
+}
@@ -0,0 +1,11 @@
+package model
+
+// Reminder is a one-off message to the admins, sent once at FireAt and then
+// deleted. Pending reminders are re-registered with the panel cron on start.
+type Reminder struct {
+	Id        int    `json:"id" gorm:"primaryKey;autoIncrement"`
+	FireAt    int64  `json:"fireAt" gorm:"index;not null"` // unix milliseconds
+	Text      string `json:"text" gorm:"not null"`
+	CreatedBy string `json:"createdBy"`
+	CreatedAt int64  `json:"createdAt"` // unix milliseconds
+}
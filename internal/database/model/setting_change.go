@@ -0,0 +1,13 @@
+package model
+
+// SettingChange records one change of a panel setting made outside the
+// settings form, with the value before and after, so admins can tell who
+// changed what and undo it.
+type SettingChange struct {
+	Id        int    `json:"id" gorm:"primaryKey;autoIncrement"`
+	Key       string `json:"key" gorm:"index;not null"`
+	OldValue  string `json:"oldValue"`
+	NewValue  string `json:"newValue"`
+	ChangedBy string `json:"changedBy"`
+	ChangedAt int64  `json:"changedAt"` // unix milliseconds
+}
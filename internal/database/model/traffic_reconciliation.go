@@ -0,0 +1,14 @@
+package model
+
+// TrafficReconciliation records one traffic sync an admin ran with the
+// bot's /reconcile and the traffic it moved into the database, so admins
+// can look back at who ran one and what it changed with /reconcile history.
+type TrafficReconciliation struct {
+	Id           int    `json:"id" gorm:"primaryKey;autoIncrement"`
+	RequestedBy  string `json:"requestedBy"`
+	Inbounds     int    `json:"inbounds"`
+	Clients      int    `json:"clients"`
+	Up           int64  `json:"up"`
+	Down         int64  `json:"down"`
+	ReconciledAt int64  `json:"reconciledAt" gorm:"index"` // unix milliseconds
+}
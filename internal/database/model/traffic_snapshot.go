@@ -0,0 +1,13 @@
+package model
+
+// InboundTrafficSnapshot is a sample of an inbound's cumulative traffic
+// counters. Every inbound is sampled at the same SampledAt, so the traffic
+// moved in a period is the difference between two samples. Rows older than
+// the tgTrafficHistoryDays setting are pruned daily.
+type InboundTrafficSnapshot struct {
+	Id        int   `json:"id" gorm:"primaryKey;autoIncrement"`
+	InboundId int   `json:"inboundId" gorm:"not null"`
+	SampledAt int64 `json:"sampledAt" gorm:"index;not null"` // unix seconds
+	Up        int64 `json:"up"`
+	Down      int64 `json:"down"`
+}
@@ -1,18 +1,149 @@
 package common
 
 import (
-	"fmt"
+	"strconv"
+	"strings"
 )
 
-// FormatTraffic formats traffic bytes into human-readable units (B, KB, MB, GB, TB, PB).
-func FormatTraffic(trafficBytes int64) string {
-	units := []string{"B", "KB", "MB", "GB", "TB", "PB"}
+// Unit systems accepted by TrafficFormat.
+const (
+	// TrafficUnitsBinary divides by 1024 and uses the short KB/MB/GB labels.
+	// It is the historical panel output.
+	TrafficUnitsBinary = "binary"
+	// TrafficUnitsIEC divides by 1024 and uses the IEC KiB/MiB/GiB labels.
+	TrafficUnitsIEC = "iec"
+	// TrafficUnitsSI divides by 1000 and uses the SI kB/MB/GB labels.
+	TrafficUnitsSI = "si"
+)
+
+// MaxTrafficDecimals caps the number of decimal places in formatted traffic.
+const MaxTrafficDecimals = 4
+
+var (
+	binaryTrafficUnits = []string{"B", "KB", "MB", "GB", "TB", "PB"}
+	iecTrafficUnits    = []string{"B", "KiB", "MiB", "GiB", "TiB", "PiB"}
+	siTrafficUnits     = []string{"B", "kB", "MB", "GB", "TB", "PB"}
+)
+
+// TrafficFormat describes how traffic byte counts are rendered.
+type TrafficFormat struct {
+	Units    string
+	Decimals int
+}
+
+// DefaultTrafficFormat is the format used by FormatTraffic.
+var DefaultTrafficFormat = TrafficFormat{Units: TrafficUnitsBinary, Decimals: 2}
+
+// NewTrafficFormat builds a TrafficFormat from setting values. Unknown unit
+// systems fall back to binary and decimals are clamped to [0, MaxTrafficDecimals].
+func NewTrafficFormat(units string, decimals int) TrafficFormat {
+	units = strings.ToLower(strings.TrimSpace(units))
+	switch units {
+	case TrafficUnitsBinary, TrafficUnitsIEC, TrafficUnitsSI:
+	default:
+		units = TrafficUnitsBinary
+	}
+	decimals = max(0, min(decimals, MaxTrafficDecimals))
+	return TrafficFormat{Units: units, Decimals: decimals}
+}
+
+// Format renders trafficBytes using the format's unit system and precision.
+func (f TrafficFormat) Format(trafficBytes int64) string {
+	return f.FormatWith(NumberFormat{}, trafficBytes)
+}
+
+// FormatWith is Format with the figure written in the given number format.
+func (f TrafficFormat) FormatWith(number NumberFormat, trafficBytes int64) string {
+	units, base := binaryTrafficUnits, 1024.0
+	switch f.Units {
+	case TrafficUnitsIEC:
+		units = iecTrafficUnits
+	case TrafficUnitsSI:
+		units, base = siTrafficUnits, 1000.0
+	}
 	unitIndex := 0
 	size := float64(trafficBytes)
 
-	for size >= 1024 && unitIndex < len(units)-1 {
-		size /= 1024
+	for size >= base && unitIndex < len(units)-1 {
+		size /= base
 		unitIndex++
 	}
-	return fmt.Sprintf("%.2f%s", size, units[unitIndex])
+	return number.Format(size, f.Decimals) + units[unitIndex]
+}
+
+// Number styles accepted by NewNumberFormat.
+const (
+	// NumberStylePlain has no digit grouping and a period decimal point,
+	// e.g. 1234567.89. It is the historical panel output.
+	NumberStylePlain = "plain"
+	// NumberStyleEN groups with commas, e.g. 1,234,567.89 (English).
+	NumberStyleEN = "en"
+	// NumberStyleEU groups with periods, e.g. 1.234.567,89 (German, Spanish,
+	// Italian, Turkish, Indonesian).
+	NumberStyleEU = "eu"
+	// NumberStyleFR groups with narrow no-break spaces, e.g. 1 234 567,89
+	// (French, Russian, Ukrainian).
+	NumberStyleFR = "fr"
+	// NumberStyleCH groups with apostrophes, e.g. 1'234'567.89 (Swiss).
+	NumberStyleCH = "ch"
+)
+
+// NumberFormat describes how the digits of a figure are grouped and which
+// decimal separator is used. The zero value is the plain style.
+type NumberFormat struct {
+	Group   string // thousands separator, empty for no grouping
+	Decimal string // decimal separator, empty for a period
+}
+
+var numberStyles = map[string]NumberFormat{
+	NumberStylePlain: {},
+	NumberStyleEN:    {Group: ",", Decimal: "."},
+	NumberStyleEU:    {Group: ".", Decimal: ","},
+	NumberStyleFR:    {Group: "\u202f", Decimal: ","},
+	NumberStyleCH:    {Group: "'", Decimal: "."},
+}
+
+// NewNumberFormat builds a NumberFormat from a setting value. Unknown styles
+// fall back to plain.
+func NewNumberFormat(style string) NumberFormat {
+	return numberStyles[strings.ToLower(strings.TrimSpace(style))]
+}
+
+// Format renders value with the given number of decimal places.
+func (n NumberFormat) Format(value float64, decimals int) string {
+	s := strconv.FormatFloat(value, 'f', max(0, decimals), 64)
+	if n == (NumberFormat{}) {
+		return s
+	}
+	sign := ""
+	if strings.HasPrefix(s, "-") {
+		sign, s = "-", s[1:]
+	}
+	whole, fraction, hasFraction := strings.Cut(s, ".")
+	if n.Group != "" && len(whole) > 3 {
+		var grouped strings.Builder
+		lead := len(whole) % 3
+		if lead == 0 {
+			lead = 3
+		}
+		grouped.WriteString(whole[:lead])
+		for i := lead; i < len(whole); i += 3 {
+			grouped.WriteString(n.Group)
+			grouped.WriteString(whole[i : i+3])
+		}
+		whole = grouped.String()
+	}
+	if !hasFraction {
+		return sign + whole
+	}
+	decimal := n.Decimal
+	if decimal == "" {
+		decimal = "."
+	}
+	return sign + whole + decimal + fraction
+}
+
+// FormatTraffic formats traffic bytes into human-readable units (B, KB, MB, GB, TB, PB).
+func FormatTraffic(trafficBytes int64) string {
+	return DefaultTrafficFormat.Format(trafficBytes)
 }
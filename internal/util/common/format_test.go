@@ -26,3 +26,136 @@ func TestFormatTraffic(t *testing.T) {
 		})
 	}
 }
+
+func TestTrafficFormat(t *testing.T) {
+	const (
+		kib = int64(1024)
+		mib = kib * 1024
+		gib = mib * 1024
+		tib = gib * 1024
+		pib = tib * 1024
+	)
+	cases := []struct {
+		name   string
+		format TrafficFormat
+		bytes  int64
+		want   string
+	}{
+		{"binary_zero", TrafficFormat{TrafficUnitsBinary, 2}, 0, "0.00B"},
+		{"binary_sub_kb", TrafficFormat{TrafficUnitsBinary, 2}, 1023, "1023.00B"},
+		{"binary_kb_boundary", TrafficFormat{TrafficUnitsBinary, 2}, kib, "1.00KB"},
+		{"binary_below_mb", TrafficFormat{TrafficUnitsBinary, 2}, mib - kib, "1023.00KB"},
+		{"binary_mb_boundary", TrafficFormat{TrafficUnitsBinary, 2}, mib, "1.00MB"},
+		{"binary_gb_boundary", TrafficFormat{TrafficUnitsBinary, 2}, gib, "1.00GB"},
+		{"binary_pb_boundary", TrafficFormat{TrafficUnitsBinary, 2}, pib, "1.00PB"},
+		{"binary_beyond_pb", TrafficFormat{TrafficUnitsBinary, 2}, 2048 * pib, "2048.00PB"},
+		{"iec_zero", TrafficFormat{TrafficUnitsIEC, 2}, 0, "0.00B"},
+		{"iec_sub_kib", TrafficFormat{TrafficUnitsIEC, 2}, 999, "999.00B"},
+		{"iec_kib_boundary", TrafficFormat{TrafficUnitsIEC, 2}, kib, "1.00KiB"},
+		{"iec_gib", TrafficFormat{TrafficUnitsIEC, 2}, 5 * gib / 2, "2.50GiB"},
+		{"iec_tib", TrafficFormat{TrafficUnitsIEC, 2}, tib, "1.00TiB"},
+		{"iec_pib", TrafficFormat{TrafficUnitsIEC, 2}, 3 * pib, "3.00PiB"},
+		{"si_zero", TrafficFormat{TrafficUnitsSI, 2}, 0, "0.00B"},
+		{"si_sub_kb", TrafficFormat{TrafficUnitsSI, 2}, 999, "999.00B"},
+		{"si_kb_boundary", TrafficFormat{TrafficUnitsSI, 2}, 1000, "1.00kB"},
+		{"si_binary_kb", TrafficFormat{TrafficUnitsSI, 2}, kib, "1.02kB"},
+		{"si_gb", TrafficFormat{TrafficUnitsSI, 2}, 1_500_000_000, "1.50GB"},
+		{"si_gib_in_gb", TrafficFormat{TrafficUnitsSI, 2}, gib, "1.07GB"},
+		{"si_pb_boundary", TrafficFormat{TrafficUnitsSI, 2}, 1_000_000_000_000_000, "1.00PB"},
+		{"si_beyond_pb", TrafficFormat{TrafficUnitsSI, 2}, 5_000_000_000_000_000_000, "5000.00PB"},
+		{"zero_decimals", TrafficFormat{TrafficUnitsBinary, 0}, 1536, "2KB"},
+		{"zero_decimals_bytes", TrafficFormat{TrafficUnitsBinary, 0}, 0, "0B"},
+		{"one_decimal", TrafficFormat{TrafficUnitsIEC, 1}, 1536, "1.5KiB"},
+		{"three_decimals", TrafficFormat{TrafficUnitsSI, 3}, 1_234_567, "1.235MB"},
+		{"negative_decimals", TrafficFormat{TrafficUnitsBinary, -1}, kib, "1KB"},
+		{"unknown_units", TrafficFormat{"bogus", 2}, kib, "1.00KB"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got := c.format.Format(c.bytes)
+			if got != c.want {
+				t.Fatalf("%+v.Format(%d) = %q, want %q", c.format, c.bytes, got, c.want)
+			}
+		})
+	}
+}
+
+func TestNewTrafficFormat(t *testing.T) {
+	cases := []struct {
+		units    string
+		decimals int
+		want     TrafficFormat
+	}{
+		{"binary", 2, TrafficFormat{TrafficUnitsBinary, 2}},
+		{" IEC ", 1, TrafficFormat{TrafficUnitsIEC, 1}},
+		{"si", 0, TrafficFormat{TrafficUnitsSI, 0}},
+		{"", 2, TrafficFormat{TrafficUnitsBinary, 2}},
+		{"decimal", 2, TrafficFormat{TrafficUnitsBinary, 2}},
+		{"si", -3, TrafficFormat{TrafficUnitsSI, 0}},
+		{"si", 12, TrafficFormat{TrafficUnitsSI, MaxTrafficDecimals}},
+	}
+	for _, c := range cases {
+		if got := NewTrafficFormat(c.units, c.decimals); got != c.want {
+			t.Fatalf("NewTrafficFormat(%q, %d) = %+v, want %+v", c.units, c.decimals, got, c.want)
+		}
+	}
+}
+
+func TestFormatTrafficMatchesDefault(t *testing.T) {
+	for _, b := range []int64{0, 1, 1023, 1024, 123456789, 1 << 50} {
+		if got, want := FormatTraffic(b), DefaultTrafficFormat.Format(b); got != want {
+			t.Fatalf("FormatTraffic(%d) = %q, default format gives %q", b, got, want)
+		}
+	}
+}
+
+func TestNumberFormat(t *testing.T) {
+	cases := []struct {
+		style    string
+		value    float64
+		decimals int
+		want     string
+	}{
+		{NumberStylePlain, 1234567.891, 2, "1234567.89"},
+		{"", 1234567.891, 2, "1234567.89"},
+		{"bogus", 1234.5, 1, "1234.5"},
+		{NumberStyleEN, 1234567.891, 2, "1,234,567.89"},
+		{NumberStyleEN, 123, 2, "123.00"},
+		{NumberStyleEN, 1000, 0, "1,000"},
+		{NumberStyleEN, -1234.5, 1, "-1,234.5"},
+		{NumberStyleEN, 999999.996, 2, "1,000,000.00"},
+		{" EU ", 1234567.891, 2, "1.234.567,89"},
+		{NumberStyleEU, 0.5, 2, "0,50"},
+		{NumberStyleEU, 12345, 0, "12.345"},
+		{NumberStyleFR, 1234567.891, 2, "1 234 567,89"},
+		{NumberStyleFR, -12.25, 1, "-12,2"},
+		{NumberStyleCH, 1234567.891, 2, "1'234'567.89"},
+		{NumberStyleCH, 100, -1, "100"},
+	}
+	for _, c := range cases {
+		if got := NewNumberFormat(c.style).Format(c.value, c.decimals); got != c.want {
+			t.Fatalf("NewNumberFormat(%q).Format(%v, %d) = %q, want %q", c.style, c.value, c.decimals, got, c.want)
+		}
+	}
+}
+
+func TestTrafficFormatWithNumbers(t *testing.T) {
+	const gib = int64(1024 * 1024 * 1024)
+	cases := []struct {
+		format TrafficFormat
+		style  string
+		bytes  int64
+		want   string
+	}{
+		{DefaultTrafficFormat, NumberStylePlain, 2048 * 1024 * 1024 * 1024 * 1024 * 1024, "2048.00PB"},
+		{DefaultTrafficFormat, NumberStyleEN, 2048 * 1024 * 1024 * 1024 * 1024 * 1024, "2,048.00PB"},
+		{DefaultTrafficFormat, NumberStyleEU, 5 * gib / 2, "2,50GB"},
+		{TrafficFormat{TrafficUnitsSI, 0}, NumberStyleFR, 999, "999B"},
+		{TrafficFormat{TrafficUnitsBinary, 2}, NumberStyleCH, 1023, "1'023.00B"},
+	}
+	for _, c := range cases {
+		if got := c.format.FormatWith(NewNumberFormat(c.style), c.bytes); got != c.want {
+			t.Fatalf("%+v.FormatWith(%q, %d) = %q, want %q", c.format, c.style, c.bytes, got, c.want)
+		}
+	}
+}
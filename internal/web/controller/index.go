@@ -82,7 +82,7 @@ func (a *IndexController) login(c *gin.Context) {
 		reason := "too many failed attempts"
 		logger.Warningf("failed login: username=%q, IP=%q, reason=%q, blocked_until=%s", safeUser, remoteIP, reason, blockedUntil.Format(time.RFC3339))
 		a.tgbot.UserLoginNotify(tgbot.LoginAttempt{
-			Username: safeUser,
+			Username: form.Username,
 			IP:       remoteIP,
 			Time:     timeStr,
 			Status:   tgbot.LoginFail,
@@ -102,7 +102,7 @@ func (a *IndexController) login(c *gin.Context) {
 			logger.Warningf("failed login: username=%q, IP=%q, reason=%q", safeUser, remoteIP, reason)
 		}
 		a.tgbot.UserLoginNotify(tgbot.LoginAttempt{
-			Username: safeUser,
+			Username: form.Username,
 			IP:       remoteIP,
 			Time:     timeStr,
 			Status:   tgbot.LoginFail,
@@ -115,7 +115,7 @@ func (a *IndexController) login(c *gin.Context) {
 	defaultLoginLimiter.registerSuccess(remoteIP, form.Username)
 	logger.Infof("%s logged in successfully, Ip Address: %s\n", safeUser, remoteIP)
 	a.tgbot.UserLoginNotify(tgbot.LoginAttempt{
-		Username: safeUser,
+		Username: form.Username,
 		IP:       remoteIP,
 		Time:     timeStr,
 		Status:   tgbot.LoginSuccess,
@@ -3,6 +3,7 @@ package controller
 import (
 	"errors"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/zixu5u/3xv/v3/internal/logger"
@@ -11,6 +12,7 @@ import (
 	"github.com/zixu5u/3xv/v3/internal/web/middleware"
 	"github.com/zixu5u/3xv/v3/internal/web/service"
 	"github.com/zixu5u/3xv/v3/internal/web/service/panel"
+	"github.com/zixu5u/3xv/v3/internal/web/service/tgbot"
 	"github.com/zixu5u/3xv/v3/internal/web/session"
 
 	"github.com/gin-gonic/gin"
@@ -31,6 +33,7 @@ type SettingController struct {
 	panelService    panel.PanelService
 	apiTokenService panel.ApiTokenService
 	xrayService     service.XrayService
+	tgbot           tgbot.Tgbot
 }
 
 // NewSettingController creates a new SettingController and initializes its routes.
@@ -82,9 +85,14 @@ func (a *SettingController) updateSetting(c *gin.Context) {
 	if !ok {
 		return
 	}
+	botUsername, err := a.checkTgBotToken(c, allSetting)
+	if err != nil {
+		jsonMsg(c, I18nWeb(c, "pages.settings.toasts.modifySettings"), err)
+		return
+	}
 	oldTwoFactor, twoFactorErr := a.settingService.GetTwoFactorEnable()
 	oldPanelOutbound, _ := a.settingService.GetPanelOutbound()
-	err := a.settingService.UpdateAllSetting(allSetting)
+	err = a.settingService.UpdateAllSetting(allSetting)
 	if err == nil && twoFactorErr == nil && !oldTwoFactor && allSetting.TwoFactorEnable {
 		if bumpErr := a.userService.BumpLoginEpoch(); bumpErr != nil {
 			err = bumpErr
@@ -98,7 +106,37 @@ func (a *SettingController) updateSetting(c *gin.Context) {
 			logger.Warning("apply panel outbound change failed:", applyErr)
 		}
 	}
-	jsonMsg(c, I18nWeb(c, "pages.settings.toasts.modifySettings"), err)
+	msg := I18nWeb(c, "pages.settings.toasts.modifySettings")
+	if botUsername != "" {
+		msg += " " + I18nWeb(c, "pages.settings.toasts.tgBotConnected", "Username=="+botUsername)
+	}
+	jsonMsg(c, msg, err)
+}
+
+// checkTgBotToken confirms a newly entered bot token with Telegram before it
+// is saved and returns the bot's username. Only a token Telegram refuses is
+// rejected: when Telegram can't be reached the token is kept, since the
+// panel may not have a route there yet.
+func (a *SettingController) checkTgBotToken(c *gin.Context, allSetting *entity.AllSetting) (string, error) {
+	if !allSetting.TgBotEnable || strings.TrimSpace(allSetting.TgBotToken) == "" {
+		return "", nil
+	}
+	token, err := service.NormalizeTgBotToken(allSetting.TgBotToken)
+	if err != nil {
+		return "", err
+	}
+	if current, err := a.settingService.GetTgBotToken(); err == nil && current == token {
+		return "", nil
+	}
+	username, err := a.tgbot.CheckToken(token, allSetting.TgBotProxy, allSetting.TgBotAPIServer)
+	if err != nil {
+		if tgbot.IsTokenRejected(err) {
+			return "", errors.New(I18nWeb(c, "pages.settings.toasts.tgBotTokenRejected"))
+		}
+		logger.Warning("Could not confirm the Telegram bot token with Telegram:", err)
+		return "", nil
+	}
+	return username, nil
 }
 
 // updateUser updates the current user's username and password.
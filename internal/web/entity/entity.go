@@ -39,16 +39,55 @@ type AllSetting struct {
 	Datepicker  string `json:"datepicker" form:"datepicker"`                            // Date picker format
 
 	// Telegram bot settings
-	TgBotEnable      bool   `json:"tgBotEnable" form:"tgBotEnable"`              // Enable Telegram bot notifications
-	TgBotToken       string `json:"tgBotToken" form:"tgBotToken"`                // Telegram bot token
-	TgBotProxy       string `json:"tgBotProxy" form:"tgBotProxy"`                // Proxy URL for Telegram bot
-	TgBotAPIServer   string `json:"tgBotAPIServer" form:"tgBotAPIServer"`        // Custom API server for Telegram bot
-	TgBotChatId      string `json:"tgBotChatId" form:"tgBotChatId"`              // Telegram chat ID for notifications
-	TgRunTime        string `json:"tgRunTime" form:"tgRunTime"`                  // Cron schedule for Telegram notifications
-	TgBotBackup      bool   `json:"tgBotBackup" form:"tgBotBackup"`              // Enable database backup via Telegram
-	TgBotLoginNotify bool   `json:"tgBotLoginNotify" form:"tgBotLoginNotify"`    // Send login notifications
-	TgCpu            int    `json:"tgCpu" form:"tgCpu" validate:"gte=0,lte=100"` // CPU usage threshold for alerts (percent)
-	TgLang           string `json:"tgLang" form:"tgLang"`                        // Telegram bot language
+	TgBotEnable              bool   `json:"tgBotEnable" form:"tgBotEnable"`                                                    // Enable Telegram bot notifications
+	TgBotToken               string `json:"tgBotToken" form:"tgBotToken"`                                                      // Telegram bot token
+	TgBotProxy               string `json:"tgBotProxy" form:"tgBotProxy"`                                                      // Proxy URL for Telegram bot
+	TgBotAPIServer           string `json:"tgBotAPIServer" form:"tgBotAPIServer"`                                              // Custom API server for Telegram bot
+	TgBotChatId              string `json:"tgBotChatId" form:"tgBotChatId"`                                                    // Telegram chat ID for notifications
+	TgRunTime                string `json:"tgRunTime" form:"tgRunTime"`                                                        // Cron schedule for Telegram notifications
+	TgBotBackup              bool   `json:"tgBotBackup" form:"tgBotBackup"`                                                    // Enable database backup via Telegram
+	TgBotLoginNotify         bool   `json:"tgBotLoginNotify" form:"tgBotLoginNotify"`                                          // Send login notifications
+	TgCpu                    int    `json:"tgCpu" form:"tgCpu" validate:"gte=0,lte=100"`                                       // CPU usage threshold for alerts (percent)
+	TgCpuWindow              int    `json:"tgCpuWindow" form:"tgCpuWindow" validate:"gte=10,lte=3600"`                         // Window in seconds the CPU usage is averaged over for alerts
+	TgLang                   string `json:"tgLang" form:"tgLang"`                                                              // Telegram bot language
+	TgBotExtraBots           string `json:"tgBotExtraBots" form:"tgBotExtraBots"`                                              // JSON list of additional notification-only bots
+	TgBotFallbackWebhook     string `json:"tgBotFallbackWebhook" form:"tgBotFallbackWebhook"`                                  // URL notified when Telegram is unreachable
+	TgBotChannelId           string `json:"tgBotChannelId" form:"tgBotChannelId"`                                              // Channel reports and selected notifications are posted to
+	TgBotChannelCategories   string `json:"tgBotChannelCategories" form:"tgBotChannelCategories"`                              // Notification categories posted to the channel
+	TgBotJsonLog             bool   `json:"tgBotJsonLog" form:"tgBotJsonLog"`                                                  // Log bot events as structured JSON lines
+	TgBotStartupNotify       bool   `json:"tgBotStartupNotify" form:"tgBotStartupNotify"`                                      // Notify admins when the panel starts
+	TgTrafficUnits           string `json:"tgTrafficUnits" form:"tgTrafficUnits" validate:"omitempty,oneof=binary iec si"`     // Unit system for traffic in bot messages
+	TgTrafficDecimals        int    `json:"tgTrafficDecimals" form:"tgTrafficDecimals" validate:"gte=0,lte=4"`                 // Decimal places for traffic in bot messages
+	TgNumberFormat           string `json:"tgNumberFormat" form:"tgNumberFormat" validate:"omitempty,oneof=plain en eu fr ch"` // Digit grouping and decimal separator in bot messages
+	TgSeverityEmoji          bool   `json:"tgSeverityEmoji" form:"tgSeverityEmoji"`                                            // Prefix bot messages with a severity emoji
+	TgPinCritical            bool   `json:"tgPinCritical" form:"tgPinCritical"`                                                // Pin critical alerts in the admin chats until they are resolved
+	TgQuietStart             string `json:"tgQuietStart" form:"tgQuietStart"`                                                  // Start of quiet hours (HH:MM); empty disables
+	TgQuietEnd               string `json:"tgQuietEnd" form:"tgQuietEnd"`                                                      // End of quiet hours (HH:MM)
+	TgBotAdminMenu           string `json:"tgBotAdminMenu" form:"tgBotAdminMenu"`                                              // Admin menu layout; empty uses the default
+	TgBotClientMenu          string `json:"tgBotClientMenu" form:"tgBotClientMenu"`                                            // Client menu layout; empty uses the default
+	TgReportFileThreshold    int    `json:"tgReportFileThreshold" form:"tgReportFileThreshold" validate:"gte=0"`               // Report size in bytes above which it is sent as a file; 0 disables
+	TgReportDisabledInbounds bool   `json:"tgReportDisabledInbounds" form:"tgReportDisabledInbounds"`                          // Include disabled inbounds, marked, in bot reports and status
+	TgStatusClientCounts     bool   `json:"tgStatusClientCounts" form:"tgStatusClientCounts"`                                  // Show total and enabled client counts for each inbound in status lists
+	TgOnlineHistoryDays      int    `json:"tgOnlineHistoryDays" form:"tgOnlineHistoryDays" validate:"gte=1,lte=365"`           // Days the online client samples are kept
+	TgTrafficHistoryDays     int    `json:"tgTrafficHistoryDays" form:"tgTrafficHistoryDays" validate:"gte=1,lte=365"`         // Days the inbound traffic snapshots are kept
+	TgClientUsageInterval    int    `json:"tgClientUsageInterval" form:"tgClientUsageInterval" validate:"gte=0,lte=60"`        // Minutes between per-client traffic samples; 0 disables
+	TgClientUsageDays        int    `json:"tgClientUsageDays" form:"tgClientUsageDays" validate:"gte=1,lte=90"`                // Days the per-client traffic samples are kept
+	TgClientLimitInterval    int    `json:"tgClientLimitInterval" form:"tgClientLimitInterval" validate:"gte=0,lte=3600"`      // Seconds between client limit scan batches; 0 disables the alerts
+	TgServerAddress          string `json:"tgServerAddress" form:"tgServerAddress"`                                            // Public address /server shows instead of the detected IP, for servers behind NAT
+	TgButtonTTL              int    `json:"tgButtonTTL" form:"tgButtonTTL" validate:"gte=0,lte=10080"`                         // Minutes a bot button that changes something stays valid; 0 disables the check
+	TgXrayStartTimeout       int    `json:"tgXrayStartTimeout" form:"tgXrayStartTimeout" validate:"gte=2,lte=300"`             // Seconds a bot-triggered Xray restart waits for the core to become ready
+	TgXrayStartRetries       int    `json:"tgXrayStartRetries" form:"tgXrayStartRetries" validate:"gte=0,lte=5"`               // Extra attempts of a bot-triggered Xray restart whose core fails to start
+	TgReportSparklines       int    `json:"tgReportSparklines" form:"tgReportSparklines" validate:"gte=0,lte=10"`              // Top inbounds whose daily traffic is drawn as a sparkline in reports; 0 disables it
+	TgCertExpiryDays         int    `json:"tgCertExpiryDays" form:"tgCertExpiryDays" validate:"gte=0,lte=365"`                 // Days before a TLS certificate expires when the bot warns about it; 0 disables the daily check
+	TgReportSections         string `json:"tgReportSections" form:"tgReportSections"`                                          // Comma-separated report sections in the order they are shown
+	TgReportNoInboundsNote   bool   `json:"tgReportNoInboundsNote" form:"tgReportNoInboundsNote"`                              // Send one note instead of the report while the panel has no inbounds
+	TgTrafficResetNotify     bool   `json:"tgTrafficResetNotify" form:"tgTrafficResetNotify"`                                  // Notify the admins of automatic traffic resets
+	TgDebugStats             bool   `json:"tgDebugStats" form:"tgDebugStats"`                                                  // Allow /debugstats to show the panel's runtime internals
+	TgGeoMaxAge              int    `json:"tgGeoMaxAge" form:"tgGeoMaxAge" validate:"gte=0,lte=3650"`                          // Days after which /geoinfo warns that the GeoIP file is stale (0 to never warn)
+	TgCommandAliases         string `json:"tgCommandAliases" form:"tgCommandAliases"`                                          // Comma-separated alias=command shortcuts of the bot commands
+	TgSilentCategories       string `json:"tgSilentCategories" form:"tgSilentCategories"`                                      // Notification categories sent without a sound; critical ones never are
+	TgReportSummaryThreshold int    `json:"tgReportSummaryThreshold" form:"tgReportSummaryThreshold" validate:"gte=0"`         // Inbound count above which the report is summarized (0 to always list every inbound)
+	TgReportSummaryTop       int    `json:"tgReportSummaryTop" form:"tgReportSummaryTop" validate:"gte=1,lte=50"`              // Number of busiest inbounds the report summary lists
 
 	// Security settings
 	TimeLocation    string `json:"timeLocation" form:"timeLocation"`       // Time zone location
@@ -125,6 +164,7 @@ type AllSettingView struct {
 	AllSetting
 
 	HasTgBotToken     bool `json:"hasTgBotToken"`
+	HasTgBotExtraBots bool `json:"hasTgBotExtraBots"`
 	HasTwoFactorToken bool `json:"hasTwoFactorToken"`
 	HasLdapPassword   bool `json:"hasLdapPassword"`
 	HasApiToken       bool `json:"hasApiToken"`
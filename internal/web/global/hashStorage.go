@@ -79,6 +79,15 @@ func (h *HashStorage) RemoveExpiredHashes() {
 	}
 }
 
+// SetExpiration changes how long entries are kept, including those already
+// stored.
+func (h *HashStorage) SetExpiration(expiration time.Duration) {
+	h.Lock()
+	defer h.Unlock()
+
+	h.Expiration = expiration
+}
+
 // Reset clears all stored hash entries.
 func (h *HashStorage) Reset() {
 	h.Lock()
@@ -0,0 +1,22 @@
+package job
+
+import (
+	"github.com/zixu5u/3xv/v3/internal/web/service/tgbot"
+)
+
+// CertExpiryJob warns the Telegram bot's admins about inbound TLS
+// certificates that are about to expire.
+type CertExpiryJob struct {
+	tgbotService tgbot.Tgbot
+}
+
+// NewCertExpiryJob creates a new certificate expiry monitoring job instance.
+func NewCertExpiryJob() *CertExpiryJob {
+	return new(CertExpiryJob)
+}
+
+// Run checks the certificates of the local TLS inbounds against the
+// tgCertExpiryDays setting.
+func (j *CertExpiryJob) Run() {
+	j.tgbotService.SendCertExpiryAlerts()
+}
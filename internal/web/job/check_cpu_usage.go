@@ -2,18 +2,16 @@ package job
 
 import (
 	"strconv"
-	"time"
 
 	"github.com/zixu5u/3xv/v3/internal/web/service"
 	"github.com/zixu5u/3xv/v3/internal/web/service/tgbot"
-
-	"github.com/shirou/gopsutil/v4/cpu"
 )
 
 // CheckCpuJob monitors CPU usage and sends Telegram notifications when usage exceeds the configured threshold.
 type CheckCpuJob struct {
 	tgbotService   tgbot.Tgbot
 	settingService service.SettingService
+	serverService  service.ServerService
 }
 
 // NewCheckCpuJob creates a new CPU monitoring job instance.
@@ -21,21 +19,24 @@ func NewCheckCpuJob() *CheckCpuJob {
 	return new(CheckCpuJob)
 }
 
-// Run checks CPU usage over the last minute and sends a Telegram alert if it exceeds the threshold.
+// Run compares the CPU usage averaged over the configured window with the
+// threshold and sends a Telegram alert if it is exceeded. Averaging the
+// background samples keeps brief spikes from raising alerts.
 func (j *CheckCpuJob) Run() {
 	threshold, err := j.settingService.GetTgCpu()
 	if err != nil || threshold <= 0 {
 		// If threshold cannot be retrieved or is not set, skip sending notifications
 		return
 	}
+	window, _ := j.settingService.GetTgCpuWindow()
 
-	// get latest status of server
-	percent, err := cpu.Percent(1*time.Minute, false)
-	if err == nil && percent[0] > float64(threshold) {
+	percent, ok := j.serverService.CpuAverage(window)
+	if ok && percent > float64(threshold) {
 		msg := j.tgbotService.I18nBot("tgbot.messages.cpuThreshold",
-			"Percent=="+strconv.FormatFloat(percent[0], 'f', 2, 64),
-			"Threshold=="+strconv.Itoa(threshold))
+			"Percent=="+strconv.FormatFloat(percent, 'f', 2, 64),
+			"Threshold=="+strconv.Itoa(threshold),
+			"Window=="+window.String())
 
-		j.tgbotService.SendMsgToTgbotAdmins(msg)
+		j.tgbotService.SendNotification(tgbot.NotifyCPU, msg)
 	}
 }
@@ -3,14 +3,21 @@
 package job
 
 import (
+	"html"
+
 	"github.com/zixu5u/3xv/v3/internal/logger"
 	"github.com/zixu5u/3xv/v3/internal/web/service"
+	"github.com/zixu5u/3xv/v3/internal/web/service/tgbot"
 )
 
 // CheckXrayRunningJob monitors Xray process health and restarts it if it crashes.
 type CheckXrayRunningJob struct {
-	xrayService service.XrayService
-	checkTime   int
+	xrayService  service.XrayService
+	tgbotService tgbot.Tgbot
+	checkTime    int
+	// alerted is set once the admins were told Xray is down, so they are
+	// told again when it is back.
+	alerted bool
 }
 
 // NewCheckXrayRunningJob creates a new Xray health check job instance.
@@ -19,9 +26,17 @@ func NewCheckXrayRunningJob() *CheckXrayRunningJob {
 }
 
 // Run checks if Xray has crashed and restarts it after confirming it's down for 2 consecutive checks.
+// While Xray is up, the config it runs on is kept as the last-known-good one.
 func (j *CheckXrayRunningJob) Run() {
 	if !j.xrayService.DidXrayCrash() {
 		j.checkTime = 0
+		if j.alerted && j.xrayService.IsXrayRunning() {
+			j.alerted = false
+			j.tgbotService.ResolveAlert(tgbot.NotifyXray, j.tgbotService.I18nBot("tgbot.messages.xrayRecovered"))
+		}
+		if err := j.xrayService.SnapshotGoodConfig(); err != nil {
+			logger.Warning("Saving the last-known-good Xray config failed:", err)
+		}
 	} else {
 		j.checkTime++
 		// only restart if it's down 2 times in a row
@@ -30,6 +45,9 @@ func (j *CheckXrayRunningJob) Run() {
 			j.checkTime = 0
 			if err != nil {
 				logger.Error("Restart xray failed:", err)
+				j.alerted = true
+				j.tgbotService.SendNotification(tgbot.NotifyXray,
+					j.tgbotService.I18nBot("tgbot.messages.xrayDown", "Error=="+html.EscapeString(err.Error())))
 			}
 		}
 	}
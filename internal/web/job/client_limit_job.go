@@ -0,0 +1,44 @@
+package job
+
+import (
+	"time"
+
+	"github.com/zixu5u/3xv/v3/internal/logger"
+	"github.com/zixu5u/3xv/v3/internal/web/service"
+	"github.com/zixu5u/3xv/v3/internal/web/service/tgbot"
+)
+
+// ClientLimitJob checks the next batch of clients against their traffic
+// limit and expiry date and alerts the Telegram bot's admins. Each run
+// covers service.ClientLimitBatchSize clients, so large panels are checked
+// gradually instead of in one expensive sweep.
+type ClientLimitJob struct {
+	clientLimitService service.ClientLimitService
+	alertHistory       service.AlertHistoryService
+	settingService     service.SettingService
+	tgbotService       tgbot.Tgbot
+}
+
+// NewClientLimitJob creates a new client limit monitoring job instance.
+func NewClientLimitJob() *ClientLimitJob {
+	return new(ClientLimitJob)
+}
+
+// Run scans the next batch of clients, using the panel's expireDiff and
+// trafficDiff settings as the near-expiry and near-limit margins of
+// inbounds that don't override them.
+func (j *ClientLimitJob) Run() {
+	expireDiff, _ := j.settingService.GetExpireDiff()
+	trafficDiff, _ := j.settingService.GetTrafficDiff()
+	now := time.Now()
+	alerts, err := j.clientLimitService.Scan(now, service.ClientLimitBatchSize,
+		service.DefaultAlertThresholds(trafficDiff, expireDiff))
+	if err != nil {
+		logger.Warning("scan client limits failed:", err)
+		return
+	}
+	if err := j.alertHistory.Record(service.ClientLimitEvents(alerts, now)); err != nil {
+		logger.Warning("record client limit events failed:", err)
+	}
+	j.tgbotService.SendClientLimitAlerts(alerts)
+}
@@ -0,0 +1,26 @@
+package job
+
+import (
+	"time"
+
+	"github.com/zixu5u/3xv/v3/internal/logger"
+	"github.com/zixu5u/3xv/v3/internal/web/service"
+)
+
+// ClientUsageJob samples the traffic of every client for the Telegram bot's
+// per-client usage over a period.
+type ClientUsageJob struct {
+	clientUsageService service.ClientUsageService
+}
+
+// NewClientUsageJob creates a new client traffic sampling job instance.
+func NewClientUsageJob() *ClientUsageJob {
+	return new(ClientUsageJob)
+}
+
+// Run records the traffic every client moved since the previous run.
+func (j *ClientUsageJob) Run() {
+	if err := j.clientUsageService.Record(time.Now()); err != nil {
+		logger.Warning("record client usage sample failed:", err)
+	}
+}
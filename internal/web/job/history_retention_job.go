@@ -0,0 +1,32 @@
+package job
+
+import (
+	"time"
+
+	"github.com/zixu5u/3xv/v3/internal/logger"
+	"github.com/zixu5u/3xv/v3/internal/web/service"
+)
+
+// HistoryRetentionJob removes online samples, traffic snapshots, client
+// usage samples and alert events older than their retention.
+type HistoryRetentionJob struct {
+	historyRetentionService service.HistoryRetentionService
+}
+
+// NewHistoryRetentionJob creates a new history pruning job instance.
+func NewHistoryRetentionJob() *HistoryRetentionJob {
+	return new(HistoryRetentionJob)
+}
+
+// Run prunes every history table with its configured retention.
+func (j *HistoryRetentionJob) Run() {
+	result, err := j.historyRetentionService.PruneConfigured(time.Now())
+	if err != nil {
+		logger.Warning("prune history failed:", err)
+		return
+	}
+	if result.OnlineSamples > 0 || result.TrafficSnapshots > 0 || result.ClientUsageSamples > 0 || result.AlertEvents > 0 {
+		logger.Infof("Pruned %d online samples, %d traffic snapshots, %d client usage samples and %d alert events",
+			result.OnlineSamples, result.TrafficSnapshots, result.ClientUsageSamples, result.AlertEvents)
+	}
+}
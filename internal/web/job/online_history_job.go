@@ -0,0 +1,31 @@
+package job
+
+import (
+	"time"
+
+	"github.com/zixu5u/3xv/v3/internal/logger"
+	"github.com/zixu5u/3xv/v3/internal/web/service"
+)
+
+// OnlineHistoryJob samples the online client count for the Telegram report's
+// daily peak and average.
+type OnlineHistoryJob struct {
+	onlineHistoryService service.OnlineHistoryService
+}
+
+// NewOnlineHistoryJob creates a new online client sampling job instance.
+func NewOnlineHistoryJob() *OnlineHistoryJob {
+	return new(OnlineHistoryJob)
+}
+
+// Run records the current online client count. Nothing is recorded while
+// Xray is down, so outages don't drag the average to zero.
+func (j *OnlineHistoryJob) Run() {
+	p := service.XrayProcess()
+	if p == nil || !p.IsRunning() {
+		return
+	}
+	if err := j.onlineHistoryService.Record(time.Now(), len(p.GetOnlineClients())); err != nil {
+		logger.Warning("record online client sample failed:", err)
+	}
+}
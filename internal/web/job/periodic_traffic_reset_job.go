@@ -1,6 +1,8 @@
 package job
 
 import (
+	"time"
+
 	"github.com/zixu5u/3xv/v3/internal/logger"
 	"github.com/zixu5u/3xv/v3/internal/web/service"
 )
@@ -12,6 +14,7 @@ type Period string
 type PeriodicTrafficResetJob struct {
 	inboundService service.InboundService
 	clientService  service.ClientService
+	xrayService    service.XrayService
 	period         Period
 }
 
@@ -35,21 +38,28 @@ func (j *PeriodicTrafficResetJob) Run() {
 	}
 	logger.Infof("Running periodic traffic reset job for period: %s (%d matching inbounds)", j.period, len(inbounds))
 
+	// A report due now still reads this period's traffic
+	service.WaitForReport(time.Now())
+
 	resetCount := 0
 
 	for _, inbound := range inbounds {
-		resetInboundErr := j.inboundService.ResetInboundTraffic(inbound.Id)
-		if resetInboundErr != nil {
-			logger.Warning("Failed to reset traffic for inbound", inbound.Id, ":", resetInboundErr)
+		summary, needRestart, err := j.clientService.ResetInboundPeriod(&j.inboundService, inbound)
+		if needRestart {
+			j.xrayService.SetToNeedRestart()
 		}
-
-		resetClientErr := j.clientService.ResetAllClientTraffics(&j.inboundService, inbound.Id)
-		if resetClientErr != nil {
-			logger.Warning("Failed to reset traffic for all users of inbound", inbound.Id, ":", resetClientErr)
+		if summary.Boosts > 0 {
+			logger.Infof("Expired %d traffic boosts of inbound %d", summary.Boosts, inbound.Id)
 		}
-
-		if resetInboundErr == nil && resetClientErr == nil {
-			resetCount++
+		if err != nil {
+			logger.Warning("Failed to reset traffic of inbound", inbound.Id, ":", err)
+			continue
+		}
+		resetCount++
+		// Hourly resets are too frequent to report each one
+		if j.period != "hourly" {
+			summary.Trigger = string(j.period)
+			service.NotifyTrafficReset(summary)
 		}
 	}
 
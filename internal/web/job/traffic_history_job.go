@@ -0,0 +1,26 @@
+package job
+
+import (
+	"time"
+
+	"github.com/zixu5u/3xv/v3/internal/logger"
+	"github.com/zixu5u/3xv/v3/internal/web/service"
+)
+
+// TrafficHistoryJob snapshots the inbound traffic counters for the Telegram
+// bot's period-over-period comparison.
+type TrafficHistoryJob struct {
+	trafficHistoryService service.TrafficHistoryService
+}
+
+// NewTrafficHistoryJob creates a new inbound traffic sampling job instance.
+func NewTrafficHistoryJob() *TrafficHistoryJob {
+	return new(TrafficHistoryJob)
+}
+
+// Run records the current traffic counters of every inbound.
+func (j *TrafficHistoryJob) Run() {
+	if err := j.trafficHistoryService.Record(time.Now()); err != nil {
+		logger.Warning("record inbound traffic snapshot failed:", err)
+	}
+}
@@ -1,23 +1,12 @@
 package job
 
 import (
-	"encoding/json"
-
-	"github.com/zixu5u/3xv/v3/internal/logger"
 	"github.com/zixu5u/3xv/v3/internal/web/service"
-	"github.com/zixu5u/3xv/v3/internal/web/service/outbound"
-	"github.com/zixu5u/3xv/v3/internal/web/websocket"
-	"github.com/zixu5u/3xv/v3/internal/xray"
-
-	"github.com/valyala/fasthttp"
 )
 
 // XrayTrafficJob collects and processes traffic statistics from Xray, updating the database and optionally informing external APIs.
 type XrayTrafficJob struct {
-	settingService  service.SettingService
-	xrayService     service.XrayService
-	inboundService  service.InboundService
-	outboundService outbound.OutboundService
+	trafficSync service.XrayTrafficSyncService
 }
 
 // NewXrayTrafficJob creates a new traffic collection job instance.
@@ -26,163 +15,7 @@ func NewXrayTrafficJob() *XrayTrafficJob {
 }
 
 // Run collects traffic statistics from Xray, updates the database, and pushes
-// real-time updates over WebSocket using compact delta payloads — no REST
-// fallback, scales to 10k–20k+ clients per inbound.
+// real-time updates over WebSocket. Failures are logged by the sync.
 func (j *XrayTrafficJob) Run() {
-	if !j.xrayService.IsXrayRunning() {
-		return
-	}
-	traffics, clientTraffics, err := j.xrayService.GetXrayTraffic()
-	if err != nil {
-		return
-	}
-	needRestart0, clientsDisabled, err := j.inboundService.AddTraffic(traffics, clientTraffics)
-	if err != nil {
-		logger.Warning("add inbound traffic failed:", err)
-	}
-	err, needRestart1 := j.outboundService.AddTraffic(traffics, clientTraffics)
-	if err != nil {
-		logger.Warning("add outbound traffic failed:", err)
-	}
-	if clientsDisabled {
-		restartOnDisable, settingErr := j.settingService.GetRestartXrayOnClientDisable()
-		if settingErr != nil {
-			logger.Warning("get RestartXrayOnClientDisable failed:", settingErr)
-		}
-		if restartOnDisable {
-			if err := j.xrayService.RestartXray(true); err != nil {
-				logger.Warning("restart xray after disabling clients failed:", err)
-				j.xrayService.SetToNeedRestart()
-			}
-		}
-		websocket.BroadcastInvalidate(websocket.MessageTypeInbounds)
-	}
-	if ExternalTrafficInformEnable, err := j.settingService.GetExternalTrafficInformEnable(); ExternalTrafficInformEnable {
-		j.informTrafficToExternalAPI(traffics, clientTraffics)
-	} else if err != nil {
-		logger.Warning("get ExternalTrafficInformEnable failed:", err)
-	}
-	if needRestart0 || needRestart1 {
-		j.xrayService.SetToNeedRestart()
-	}
-
-	// Derive the local online set from this poll's per-email deltas rather
-	// than the shared last_online column, which remote-node syncs also bump
-	// and would otherwise make a client active only on a remote node appear
-	// online on local inbounds.
-	activeEmails := make([]string, 0, len(clientTraffics))
-	deltaActive := make(map[string]bool, len(clientTraffics))
-	for _, ct := range clientTraffics {
-		if ct != nil && ct.Up+ct.Down > 0 {
-			activeEmails = append(activeEmails, ct.Email)
-			deltaActive[ct.Email] = true
-		}
-	}
-	// When the core supports the online-stats API, union in connection-based
-	// onlines. Neither signal alone covers everything: an idle-but-connected
-	// client moves no bytes between polls (the delta heuristic's blind spot),
-	// while a short-lived connection can close before this poll yet still show
-	// in the delta. Older cores fall back to deltas alone.
-	if onlineUsers, apiMode, ouErr := j.xrayService.GetOnlineUsers(); ouErr != nil {
-		logger.Debug("get online users from xray api failed:", ouErr)
-	} else if apiMode {
-		idleOnline := make([]string, 0, len(onlineUsers))
-		for _, u := range onlineUsers {
-			if !deltaActive[u.Email] {
-				activeEmails = append(activeEmails, u.Email)
-				idleOnline = append(idleOnline, u.Email)
-			}
-		}
-		// The traffic path only bumps last_online on a non-zero delta; keep the
-		// column fresh for clients kept online purely by a live connection.
-		if err := j.inboundService.BumpClientsLastOnline(idleOnline); err != nil {
-			logger.Warning("bump last online for connected clients failed:", err)
-		}
-	}
-	// Pair the email signal with the inbound tags that moved bytes this poll.
-	// Xray's user>>>email counter aggregates across every inbound a client is
-	// attached to, so an online email alone can't say which inbound it used —
-	// gating the per-inbound view on these tags keeps a multi-inbound client
-	// off inbounds that saw no traffic. See issue #4859.
-	activeInboundTags := make([]string, 0, len(traffics))
-	for _, tr := range traffics {
-		if tr != nil && tr.IsInbound && tr.Up+tr.Down > 0 {
-			activeInboundTags = append(activeInboundTags, tr.Tag)
-		}
-	}
-	j.inboundService.RefreshLocalOnlineClients(activeEmails, activeInboundTags)
-
-	if !websocket.HasClients() {
-		return
-	}
-
-	lastOnlineMap, err := j.inboundService.GetClientsLastOnline()
-	if err != nil {
-		logger.Warning("get clients last online failed:", err)
-	}
-	if lastOnlineMap == nil {
-		lastOnlineMap = make(map[string]int64)
-	}
-	onlineClients := j.inboundService.GetOnlineClients()
-	if onlineClients == nil {
-		onlineClients = []string{}
-	}
-	websocket.BroadcastTraffic(map[string]any{
-		"traffics":       traffics,
-		"clientTraffics": clientTraffics,
-		"onlineClients":  onlineClients,
-		"onlineByGuid":   j.inboundService.GetOnlineClientsByGuid(),
-		"activeInbounds": j.inboundService.GetActiveInboundsByGuid(),
-		"lastOnlineMap":  lastOnlineMap,
-	})
-
-	clientStatsPayload := map[string]any{}
-	if stats, err := j.inboundService.GetAllClientTraffics(); err != nil {
-		logger.Warning("get all client traffics for websocket failed:", err)
-	} else if len(stats) > 0 {
-		clientStatsPayload["clients"] = stats
-	}
-	if inboundSummary, err := j.inboundService.GetInboundsTrafficSummary(); err != nil {
-		logger.Warning("get inbounds traffic summary for websocket failed:", err)
-	} else if len(inboundSummary) > 0 {
-		clientStatsPayload["inbounds"] = inboundSummary
-	}
-	if len(clientStatsPayload) > 0 {
-		websocket.BroadcastClientStats(clientStatsPayload)
-	}
-
-	if updatedOutbounds, err := j.outboundService.GetOutboundsTraffic(); err == nil && updatedOutbounds != nil {
-		websocket.BroadcastOutbounds(updatedOutbounds)
-	} else if err != nil {
-		logger.Warning("get all outbounds for websocket failed:", err)
-	}
-}
-
-func (j *XrayTrafficJob) informTrafficToExternalAPI(inboundTraffics []*xray.Traffic, clientTraffics []*xray.ClientTraffic) {
-	informURL, err := j.settingService.GetExternalTrafficInformURI()
-	if err != nil {
-		logger.Warning("get ExternalTrafficInformURI failed:", err)
-		return
-	}
-	informURL, err = service.SanitizePublicHTTPURL(informURL, false)
-	if err != nil {
-		logger.Warning("ExternalTrafficInformURI blocked:", err)
-		return
-	}
-	requestBody, err := json.Marshal(map[string]any{"clientTraffics": clientTraffics, "inboundTraffics": inboundTraffics})
-	if err != nil {
-		logger.Warning("parse client/inbound traffic failed:", err)
-		return
-	}
-	request := fasthttp.AcquireRequest()
-	defer fasthttp.ReleaseRequest(request)
-	request.Header.SetMethod("POST")
-	request.Header.SetContentType("application/json; charset=UTF-8")
-	request.SetBody([]byte(requestBody))
-	request.SetRequestURI(informURL)
-	response := fasthttp.AcquireResponse()
-	defer fasthttp.ReleaseResponse(response)
-	if err := fasthttp.Do(request, response); err != nil {
-		logger.Warning("POST ExternalTrafficInformURI failed:", err)
-	}
+	j.trafficSync.Sync()
 }
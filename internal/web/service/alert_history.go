@@ -0,0 +1,78 @@
+package service
+
+import (
+	"time"
+
+	"github.com/zixu5u/3xv/v3/internal/database"
+	"github.com/zixu5u/3xv/v3/internal/database/model"
+
+	"gorm.io/gorm"
+)
+
+// Types of the alert events /events lists.
+const (
+	AlertEventTrafficNear      = "traffic_near"      // client near its traffic limit
+	AlertEventTrafficExhausted = "traffic_exhausted" // client used up its traffic limit
+	AlertEventExpiring         = "expiring"          // client near its expiry date
+	AlertEventExpired          = "expired"           // client expiry date passed
+	AlertEventDisabled         = "disabled"          // client disabled for reaching a limit
+)
+
+// AlertEventRetention is how long alert events are kept.
+const AlertEventRetention = 30 * 24 * time.Hour
+
+// AlertHistoryService keeps the alert events that fired, for catching up on
+// them later.
+type AlertHistoryService struct{}
+
+// ClientLimitEvents returns the events of alerts fired at now: one per
+// condition a client newly reached, the way the alert lists them.
+func ClientLimitEvents(alerts []ClientLimitAlert, now time.Time) []model.AlertEvent {
+	var events []model.AlertEvent
+	add := func(eventType string, email string) {
+		events = append(events, model.AlertEvent{Type: eventType, Email: email, FiredAt: now.UnixMilli()})
+	}
+	for _, alert := range alerts {
+		switch {
+		case alert.State&ClientTrafficExhausted != 0:
+			add(AlertEventTrafficExhausted, alert.Email)
+		case alert.State&ClientNearTrafficLimit != 0:
+			add(AlertEventTrafficNear, alert.Email)
+		}
+		switch {
+		case alert.State&ClientExpired != 0:
+			add(AlertEventExpired, alert.Email)
+		case alert.State&ClientNearExpiry != 0:
+			add(AlertEventExpiring, alert.Email)
+		}
+	}
+	return events
+}
+
+// Record stores events.
+func (s *AlertHistoryService) Record(events []model.AlertEvent) error {
+	return recordAlertEvents(database.GetDB(), events)
+}
+
+// recordAlertEvents stores events with db, e.g. in the transaction that
+// caused them.
+func recordAlertEvents(db *gorm.DB, events []model.AlertEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+	return db.CreateInBatches(events, 100).Error
+}
+
+// Recent returns up to limit events, newest first.
+func (s *AlertHistoryService) Recent(limit int) ([]model.AlertEvent, error) {
+	var events []model.AlertEvent
+	err := database.GetDB().Order("fired_at DESC, id DESC").Limit(limit).Find(&events).Error
+	return events, err
+}
+
+// Prune deletes the events fired before before and returns how many there
+// were.
+func (s *AlertHistoryService) Prune(before time.Time) (int64, error) {
+	result := database.GetDB().Where("fired_at < ?", before.UnixMilli()).Delete(&model.AlertEvent{})
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,53 @@
+package service
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/zixu5u/3xv/v3/internal/database"
+)
+
+func TestAlertHistory(t *testing.T) {
+	dbDir := t.TempDir()
+	t.Setenv("XUI_DB_FOLDER", dbDir)
+	if err := database.InitDB(filepath.Join(dbDir, "x-ui.db")); err != nil {
+		t.Fatalf("InitDB: %v", err)
+	}
+	t.Cleanup(func() { _ = database.CloseDB() })
+
+	svc := AlertHistoryService{}
+	old := time.Date(2024, 4, 1, 12, 0, 0, 0, time.UTC)
+	now := old.Add(40 * 24 * time.Hour)
+	if err := svc.Record(ClientLimitEvents([]ClientLimitAlert{
+		{Email: "old", State: ClientExpired},
+	}, old)); err != nil {
+		t.Fatalf("Record: %v", err)
+	}
+	events := ClientLimitEvents([]ClientLimitAlert{
+		{Email: "alice", State: ClientTrafficExhausted | ClientNearTrafficLimit | ClientNearExpiry},
+		{Email: "bob", State: ClientNearTrafficLimit},
+	}, now)
+	if len(events) != 3 || events[0].Type != AlertEventTrafficExhausted || events[1].Type != AlertEventExpiring {
+		t.Fatalf("ClientLimitEvents = %+v", events)
+	}
+	if err := svc.Record(events); err != nil {
+		t.Fatalf("Record: %v", err)
+	}
+
+	recent, err := svc.Recent(10)
+	if err != nil || len(recent) != 4 || recent[3].Email != "old" {
+		t.Fatalf("Recent = %+v, %v", recent, err)
+	}
+	if limited, err := svc.Recent(2); err != nil || len(limited) != 2 {
+		t.Fatalf("Recent(2) = %+v, %v", limited, err)
+	}
+
+	pruned, err := svc.Prune(now.Add(-AlertEventRetention))
+	if err != nil || pruned != 1 {
+		t.Fatalf("Prune = %d, %v", pruned, err)
+	}
+	if recent, _ := svc.Recent(10); len(recent) != 3 {
+		t.Fatalf("after Prune: %+v", recent)
+	}
+}
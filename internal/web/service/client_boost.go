@@ -0,0 +1,143 @@
+package service
+
+import (
+	"errors"
+	"time"
+
+	"github.com/zixu5u/3xv/v3/internal/database"
+	"github.com/zixu5u/3xv/v3/internal/database/model"
+	"github.com/zixu5u/3xv/v3/internal/logger"
+	"github.com/zixu5u/3xv/v3/internal/util/common"
+
+	"gorm.io/gorm"
+)
+
+// GetTrafficBoost returns the temporary traffic boost of a client, or nil
+// when it has none.
+func (s *ClientService) GetTrafficBoost(email string) (*model.ClientTrafficBoost, error) {
+	boost := &model.ClientTrafficBoost{}
+	err := database.GetDB().Where("email = ?", email).First(boost).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return boost, nil
+}
+
+// BoostTrafficLimit adds bytes to a client's traffic limit until the boost
+// is revoked or the client's inbound resets its traffic. Boosts granted
+// while one is active add up. A client that was cut off only for reaching
+// its limit is enabled again when the boost gives it room.
+func (s *ClientService) BoostTrafficLimit(inboundSvc *InboundService, email string, bytes int64, grantedBy string) (bool, error) {
+	if bytes <= 0 {
+		return false, common.NewError("boost must be greater than zero")
+	}
+	rec, err := s.GetRecordByEmail(nil, email)
+	if err != nil {
+		return false, err
+	}
+	client := rec.ToClient()
+	if client.TotalGB == 0 {
+		return false, common.NewError("client has no traffic limit to boost:", email)
+	}
+	if !client.Enable {
+		traffic, err := inboundSvc.GetClientTrafficByEmail(email)
+		if err != nil {
+			return false, err
+		}
+		if traffic != nil && boostReenables(client, traffic.Up+traffic.Down, bytes, time.Now().UnixMilli()) {
+			client.Enable = true
+		}
+	}
+	client.TotalGB += bytes
+	needRestart, err := s.Update(inboundSvc, rec.Id, *client)
+	if err != nil {
+		return needRestart, err
+	}
+
+	db := database.GetDB()
+	boost, err := s.GetTrafficBoost(email)
+	if err != nil {
+		return needRestart, err
+	}
+	if boost == nil {
+		boost = &model.ClientTrafficBoost{Email: email}
+	}
+	boost.Bytes += bytes
+	boost.GrantedBy = grantedBy
+	boost.GrantedAt = time.Now().UnixMilli()
+	if err := db.Save(boost).Error; err != nil {
+		return needRestart, err
+	}
+	logger.Infof("Traffic limit of %s boosted by %d bytes (%d in total) by %s", email, bytes, boost.Bytes, grantedBy)
+	return needRestart, nil
+}
+
+// boostReenables reports whether a disabled client with used bytes of
+// traffic was cut off only by its limit, and would be under it with extra
+// more bytes.
+func boostReenables(client *model.Client, used int64, extra int64, now int64) bool {
+	if client.ExpiryTime > 0 && client.ExpiryTime <= now {
+		return false
+	}
+	return used >= client.TotalGB && used < client.TotalGB+extra
+}
+
+// RevokeTrafficBoost takes a client's boost off its traffic limit. When the
+// limit was lowered or lifted since the boost was granted it is left as it
+// is; only the boost is dropped.
+func (s *ClientService) RevokeTrafficBoost(inboundSvc *InboundService, email string, reason string) (bool, error) {
+	boost, err := s.GetTrafficBoost(email)
+	if err != nil {
+		return false, err
+	}
+	if boost == nil {
+		return false, common.NewError("client has no traffic boost:", email)
+	}
+	needRestart := false
+	rec, err := s.GetRecordByEmail(nil, email)
+	if err == nil {
+		client := rec.ToClient()
+		if client.TotalGB > boost.Bytes {
+			client.TotalGB -= boost.Bytes
+			if needRestart, err = s.Update(inboundSvc, rec.Id, *client); err != nil {
+				return needRestart, err
+			}
+		}
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return false, err
+	}
+	if err := database.GetDB().Delete(boost).Error; err != nil {
+		return needRestart, err
+	}
+	logger.Infof("Traffic boost of %d bytes for %s revoked: %s", boost.Bytes, email, reason)
+	return needRestart, nil
+}
+
+// ExpireInboundBoosts revokes the boosts of the clients of an inbound. It
+// runs when the inbound's traffic is reset for a new period.
+func (s *ClientService) ExpireInboundBoosts(inboundSvc *InboundService, inboundId int) (int, bool, error) {
+	var emails []string
+	err := database.GetDB().Table("client_traffic_boosts").
+		Joins("JOIN clients ON clients.email = client_traffic_boosts.email").
+		Joins("JOIN client_inbounds ON client_inbounds.client_id = clients.id").
+		Where("client_inbounds.inbound_id = ?", inboundId).
+		Distinct().
+		Pluck("client_traffic_boosts.email", &emails).Error
+	if err != nil {
+		return 0, false, err
+	}
+	needRestart := false
+	expired := 0
+	for _, email := range emails {
+		nr, err := s.RevokeTrafficBoost(inboundSvc, email, "traffic reset")
+		needRestart = needRestart || nr
+		if err != nil {
+			return expired, needRestart, err
+		}
+		expired++
+	}
+	return expired, needRestart, nil
+}
@@ -0,0 +1,102 @@
+package service
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/zixu5u/3xv/v3/internal/database"
+	"github.com/zixu5u/3xv/v3/internal/database/model"
+	"github.com/zixu5u/3xv/v3/internal/xray"
+)
+
+func TestBoostTrafficLimit(t *testing.T) {
+	dbDir := t.TempDir()
+	t.Setenv("XUI_DB_FOLDER", dbDir)
+	if err := database.InitDB(filepath.Join(dbDir, "x-ui.db")); err != nil {
+		t.Fatalf("InitDB: %v", err)
+	}
+	t.Cleanup(func() { _ = database.CloseDB() })
+
+	db := database.GetDB()
+	const email = "capped@example.com"
+	const gb = int64(1 << 30)
+	inbound := &model.Inbound{Tag: "vless-boost", Enable: true, Port: 50101, Protocol: model.VLESS,
+		StreamSettings: `{"network":"tcp","security":"none"}`,
+		Settings: `{"clients":[{"email":"capped@example.com","id":"0b7d6a4e-6f3c-4d0e-9a61-3c1f0b5e7a11",` +
+			`"enable":false,"totalGB":53687091200,"subId":"sub-boost"}]}`}
+	if err := db.Create(inbound).Error; err != nil {
+		t.Fatalf("create inbound: %v", err)
+	}
+	clientSvc := ClientService{}
+	inboundSvc := InboundService{}
+	clients, err := inboundSvc.GetClients(inbound)
+	if err != nil {
+		t.Fatalf("GetClients: %v", err)
+	}
+	if err := clientSvc.SyncInbound(nil, inbound.Id, clients); err != nil {
+		t.Fatalf("SyncInbound: %v", err)
+	}
+	// disabled for using up its 50 GB
+	if err := db.Create(&xray.ClientTraffic{InboundId: inbound.Id, Email: email, Total: 50 * gb, Down: 50 * gb}).Error; err != nil {
+		t.Fatalf("create client_traffics: %v", err)
+	}
+
+	if _, err := clientSvc.BoostTrafficLimit(&inboundSvc, email, 10*gb, "telegram:1"); err != nil {
+		t.Fatalf("BoostTrafficLimit: %v", err)
+	}
+	rec, err := clientSvc.GetRecordByEmail(nil, email)
+	if err != nil {
+		t.Fatalf("GetRecordByEmail: %v", err)
+	}
+	if rec.TotalGB != 60*gb || !rec.Enable {
+		t.Fatalf("after boost: total=%d enable=%v, want %d and enabled", rec.TotalGB, rec.Enable, 60*gb)
+	}
+	boost, err := clientSvc.GetTrafficBoost(email)
+	if err != nil || boost == nil || boost.Bytes != 10*gb || boost.GrantedBy != "telegram:1" {
+		t.Fatalf("boost record = %+v, %v", boost, err)
+	}
+
+	// a second grant adds up
+	if _, err := clientSvc.BoostTrafficLimit(&inboundSvc, email, 5*gb, "telegram:2"); err != nil {
+		t.Fatalf("BoostTrafficLimit again: %v", err)
+	}
+	if boost, _ := clientSvc.GetTrafficBoost(email); boost == nil || boost.Bytes != 15*gb || boost.GrantedBy != "telegram:2" {
+		t.Fatalf("boosts did not add up: %+v", boost)
+	}
+
+	expired, _, err := clientSvc.ExpireInboundBoosts(&inboundSvc, inbound.Id)
+	if err != nil || expired != 1 {
+		t.Fatalf("ExpireInboundBoosts = %d, %v", expired, err)
+	}
+	rec, _ = clientSvc.GetRecordByEmail(nil, email)
+	if rec.TotalGB != 50*gb {
+		t.Fatalf("after expiry: total=%d, want the base %d", rec.TotalGB, 50*gb)
+	}
+	if boost, _ := clientSvc.GetTrafficBoost(email); boost != nil {
+		t.Fatalf("boost left after expiry: %+v", boost)
+	}
+	if _, err := clientSvc.RevokeTrafficBoost(&inboundSvc, email, "test"); err == nil {
+		t.Fatal("revoking a missing boost must fail")
+	}
+}
+
+func TestBoostReenables(t *testing.T) {
+	const now = int64(1_700_000_000_000)
+	cases := []struct {
+		name   string
+		client model.Client
+		used   int64
+		want   bool
+	}{
+		{"cut off at the limit", model.Client{TotalGB: 100}, 100, true},
+		{"still over after the boost", model.Client{TotalGB: 100}, 150, false},
+		{"disabled below the limit", model.Client{TotalGB: 100}, 40, false},
+		{"expired", model.Client{TotalGB: 100, ExpiryTime: now - 1}, 100, false},
+		{"expires later", model.Client{TotalGB: 100, ExpiryTime: now + 1}, 100, true},
+	}
+	for _, c := range cases {
+		if got := boostReenables(&c.client, c.used, 20, now); got != c.want {
+			t.Errorf("%s: boostReenables = %v, want %v", c.name, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package service
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/zixu5u/3xv/v3/internal/database"
+	"github.com/zixu5u/3xv/v3/internal/database/model"
+)
+
+func TestCountByInbound(t *testing.T) {
+	dbDir := t.TempDir()
+	t.Setenv("XUI_DB_FOLDER", dbDir)
+	if err := database.InitDB(filepath.Join(dbDir, "x-ui.db")); err != nil {
+		t.Fatalf("InitDB: %v", err)
+	}
+	t.Cleanup(func() { _ = database.CloseDB() })
+
+	db := database.GetDB()
+	clients := []struct {
+		email    string
+		enable   bool
+		inbounds []int
+	}{
+		{"a", true, []int{1, 2}},
+		{"b", true, []int{1}},
+		{"c", false, []int{1, 2}},
+	}
+	for _, c := range clients {
+		rec := &model.ClientRecord{Email: c.email, Enable: c.enable}
+		if err := db.Create(rec).Error; err != nil {
+			t.Fatalf("create client %s: %v", c.email, err)
+		}
+		if !c.enable {
+			// gorm skips the false zero value and applies the column default
+			if err := db.Model(rec).Update("enable", false).Error; err != nil {
+				t.Fatalf("disable client %s: %v", c.email, err)
+			}
+		}
+		for _, inboundId := range c.inbounds {
+			if err := db.Create(&model.ClientInbound{ClientId: rec.Id, InboundId: inboundId}).Error; err != nil {
+				t.Fatalf("link client %s: %v", c.email, err)
+			}
+		}
+	}
+
+	svc := ClientService{}
+	got, err := svc.CountByInbound()
+	if err != nil {
+		t.Fatalf("CountByInbound: %v", err)
+	}
+	want := map[int]InboundClientCount{1: {Total: 3, Enabled: 2}, 2: {Total: 2, Enabled: 1}}
+	if len(got) != len(want) || got[1] != want[1] || got[2] != want[2] {
+		t.Fatalf("want %+v, got %+v", want, got)
+	}
+	if _, ok := got[3]; ok {
+		t.Fatal("inbound without clients was counted")
+	}
+}
@@ -0,0 +1,68 @@
+package service
+
+import (
+	"errors"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/zixu5u/3xv/v3/internal/database"
+	"github.com/zixu5u/3xv/v3/internal/database/model"
+	"github.com/zixu5u/3xv/v3/internal/logger"
+	"github.com/zixu5u/3xv/v3/internal/util/common"
+
+	"gorm.io/gorm"
+)
+
+// MaxDisableReasonLength bounds the reason kept with a disabled client.
+const MaxDisableReasonLength = 100
+
+// GetDisableReason returns the record of why a client was disabled, or nil
+// when none was kept.
+func (s *ClientService) GetDisableReason(email string) (*model.ClientDisableReason, error) {
+	reason := &model.ClientDisableReason{}
+	err := database.GetDB().Where("email = ?", email).First(reason).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return reason, nil
+}
+
+// DisableClientWithReason disables a client and records who did it and why.
+// The reason is optional. Disabling a client that already is only updates
+// the record.
+func (s *ClientService) DisableClientWithReason(inboundSvc *InboundService, email string, reason string, disabledBy string) (bool, error) {
+	reason = strings.TrimSpace(reason)
+	if utf8.RuneCountInString(reason) > MaxDisableReasonLength {
+		return false, common.NewErrorf("reason is longer than %d characters", MaxDisableReasonLength)
+	}
+	_, needRestart, err := s.SetClientEnableByEmail(inboundSvc, email, false)
+	if err != nil {
+		return needRestart, err
+	}
+
+	record, err := s.GetDisableReason(email)
+	if err != nil {
+		return needRestart, err
+	}
+	if record == nil {
+		record = &model.ClientDisableReason{Email: email}
+	}
+	record.Reason = reason
+	record.DisabledBy = disabledBy
+	record.DisabledAt = time.Now().UnixMilli()
+	if err := database.GetDB().Save(record).Error; err != nil {
+		return needRestart, err
+	}
+	logger.Infof("Client %s disabled by %s, reason: %q", email, disabledBy, reason)
+	return needRestart, nil
+}
+
+// clearDisableReason drops the record of why a client was disabled once it
+// is enabled again.
+func clearDisableReason(email string) error {
+	return database.GetDB().Where("email = ?", email).Delete(&model.ClientDisableReason{}).Error
+}
@@ -0,0 +1,74 @@
+package service
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/zixu5u/3xv/v3/internal/database"
+	"github.com/zixu5u/3xv/v3/internal/database/model"
+)
+
+func TestDisableClientWithReason(t *testing.T) {
+	dbDir := t.TempDir()
+	t.Setenv("XUI_DB_FOLDER", dbDir)
+	if err := database.InitDB(filepath.Join(dbDir, "x-ui.db")); err != nil {
+		t.Fatalf("InitDB: %v", err)
+	}
+	t.Cleanup(func() { _ = database.CloseDB() })
+
+	db := database.GetDB()
+	const email = "abuser@example.com"
+	inbound := &model.Inbound{Tag: "vless-disable", Enable: true, Port: 50102, Protocol: model.VLESS,
+		StreamSettings: `{"network":"tcp","security":"none"}`,
+		Settings: `{"clients":[{"email":"abuser@example.com","id":"5c1e2f0a-8d4b-4b7e-9f3a-6e2d1c0b9a88",` +
+			`"enable":true,"subId":"sub-disable"}]}`}
+	if err := db.Create(inbound).Error; err != nil {
+		t.Fatalf("create inbound: %v", err)
+	}
+	clientSvc := ClientService{}
+	inboundSvc := InboundService{}
+	clients, err := inboundSvc.GetClients(inbound)
+	if err != nil {
+		t.Fatalf("GetClients: %v", err)
+	}
+	if err := clientSvc.SyncInbound(nil, inbound.Id, clients); err != nil {
+		t.Fatalf("SyncInbound: %v", err)
+	}
+	if err := inboundSvc.AddClientStat(db, inbound.Id, &clients[0]); err != nil {
+		t.Fatalf("AddClientStat: %v", err)
+	}
+
+	if _, err := clientSvc.DisableClientWithReason(&inboundSvc, email, strings.Repeat("x", MaxDisableReasonLength+1), "telegram:1"); err == nil {
+		t.Fatal("an overlong reason must be rejected")
+	}
+	if enabled, _ := clientSvc.CheckIsEnabledByEmail(&inboundSvc, email); !enabled {
+		t.Fatal("a rejected reason must leave the client enabled")
+	}
+
+	if _, err := clientSvc.DisableClientWithReason(&inboundSvc, email, "  abuse ", "telegram:1"); err != nil {
+		t.Fatalf("DisableClientWithReason: %v", err)
+	}
+	if enabled, _ := clientSvc.CheckIsEnabledByEmail(&inboundSvc, email); enabled {
+		t.Fatal("client still enabled")
+	}
+	record, err := clientSvc.GetDisableReason(email)
+	if err != nil || record == nil || record.Reason != "abuse" || record.DisabledBy != "telegram:1" || record.DisabledAt == 0 {
+		t.Fatalf("disable record = %+v, %v", record, err)
+	}
+
+	// disabling again only updates the record
+	if _, err := clientSvc.DisableClientWithReason(&inboundSvc, email, "", "telegram:2"); err != nil {
+		t.Fatalf("DisableClientWithReason again: %v", err)
+	}
+	if record, _ := clientSvc.GetDisableReason(email); record == nil || record.Reason != "" || record.DisabledBy != "telegram:2" {
+		t.Fatalf("record not updated: %+v", record)
+	}
+
+	if enabled, _, err := clientSvc.ToggleClientEnableByEmail(&inboundSvc, email); err != nil || !enabled {
+		t.Fatalf("ToggleClientEnableByEmail = %v, %v", enabled, err)
+	}
+	if record, _ := clientSvc.GetDisableReason(email); record != nil {
+		t.Fatalf("record left after enabling: %+v", record)
+	}
+}
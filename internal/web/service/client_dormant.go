@@ -0,0 +1,38 @@
+package service
+
+import (
+	"time"
+
+	"github.com/zixu5u/3xv/v3/internal/database"
+)
+
+// DormantClient is an enabled client that moved no traffic during the
+// dormancy window.
+type DormantClient struct {
+	Email string `json:"email"`
+	// LastOnline is the last time traffic was seen, in milliseconds; 0 means
+	// the client has never been used.
+	LastOnline int64 `json:"lastOnline"`
+	CreatedAt  int64 `json:"createdAt"`
+}
+
+// GetDormantClients returns enabled clients with no traffic for at least
+// window, least recently active first. last_online is bumped whenever a
+// traffic delta is recorded for a client, so it doubles as last activity.
+// Clients created within the window are never dormant, even if unused yet.
+func (s *ClientService) GetDormantClients(window time.Duration) ([]DormantClient, error) {
+	cutoff := time.Now().Add(-window).UnixMilli()
+	var rows []DormantClient
+	err := database.GetDB().Table("clients").
+		Select("clients.email AS email, COALESCE(client_traffics.last_online, 0) AS last_online, clients.created_at AS created_at").
+		Joins("LEFT JOIN client_traffics ON client_traffics.email = clients.email").
+		Where("clients.enable = ?", true).
+		Where("COALESCE(client_traffics.last_online, 0) < ?", cutoff).
+		Where("clients.created_at < ?", cutoff).
+		Order("last_online ASC, clients.email ASC").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
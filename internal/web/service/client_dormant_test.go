@@ -0,0 +1,76 @@
+package service
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/zixu5u/3xv/v3/internal/database"
+	"github.com/zixu5u/3xv/v3/internal/database/model"
+	"github.com/zixu5u/3xv/v3/internal/xray"
+)
+
+func TestGetDormantClients(t *testing.T) {
+	dbDir := t.TempDir()
+	t.Setenv("XUI_DB_FOLDER", dbDir)
+	if err := database.InitDB(filepath.Join(dbDir, "x-ui.db")); err != nil {
+		t.Fatalf("InitDB: %v", err)
+	}
+	t.Cleanup(func() { _ = database.CloseDB() })
+
+	db := database.GetDB()
+	now := time.Now()
+	old := now.Add(-90 * 24 * time.Hour).UnixMilli()
+	recent := now.Add(-time.Hour).UnixMilli()
+
+	clients := []struct {
+		email      string
+		enable     bool
+		createdAt  int64
+		lastOnline int64
+	}{
+		{"idle", true, old, now.Add(-60 * 24 * time.Hour).UnixMilli()},
+		{"never-used", true, old, 0},
+		{"active", true, old, recent},
+		{"new", true, recent, 0},
+		{"disabled", false, old, 0},
+	}
+	for _, c := range clients {
+		rec := &model.ClientRecord{Email: c.email, Enable: c.enable, CreatedAt: c.createdAt}
+		if err := db.Create(rec).Error; err != nil {
+			t.Fatalf("create client %s: %v", c.email, err)
+		}
+		if !c.enable {
+			// gorm skips the false zero value and applies the column default
+			if err := db.Model(rec).Update("enable", false).Error; err != nil {
+				t.Fatalf("disable client %s: %v", c.email, err)
+			}
+		}
+		if err := db.Create(&xray.ClientTraffic{InboundId: 1, Email: c.email, Enable: c.enable, LastOnline: c.lastOnline}).Error; err != nil {
+			t.Fatalf("create client_traffics %s: %v", c.email, err)
+		}
+	}
+
+	svc := ClientService{}
+	got, err := svc.GetDormantClients(30 * 24 * time.Hour)
+	if err != nil {
+		t.Fatalf("GetDormantClients: %v", err)
+	}
+	if len(got) != 2 || got[0].Email != "never-used" || got[1].Email != "idle" {
+		t.Fatalf("want [never-used idle], got %+v", got)
+	}
+	if got[0].LastOnline != 0 || got[1].LastOnline == 0 {
+		t.Fatalf("unexpected last online values %+v", got)
+	}
+
+	// a shorter window doesn't make the recently active client dormant
+	got, err = svc.GetDormantClients(2 * time.Hour)
+	if err != nil {
+		t.Fatalf("GetDormantClients: %v", err)
+	}
+	for _, c := range got {
+		if c.Email == "active" || c.Email == "new" {
+			t.Fatalf("%s must not be dormant within 2h, got %+v", c.Email, got)
+		}
+	}
+}
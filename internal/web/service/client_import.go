@@ -0,0 +1,136 @@
+package service
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/zixu5u/3xv/v3/internal/database"
+	"github.com/zixu5u/3xv/v3/internal/database/model"
+	"github.com/zixu5u/3xv/v3/internal/util/common"
+)
+
+// ClientImportRow is a client read from an import file, with the line of
+// the file it came from.
+type ClientImportRow struct {
+	Line   int
+	Client model.Client
+}
+
+// ClientImportFailure is a row of an import that was not created.
+type ClientImportFailure struct {
+	Line   int
+	Email  string
+	Reason string
+}
+
+// ClientImportResult reports how many clients an import created and which
+// rows failed.
+type ClientImportResult struct {
+	Created int
+	Failed  []ClientImportFailure
+}
+
+// CheckImport finds the rows that can't be created in inbound inboundId:
+// missing, invalid or repeated emails and emails already in use. Nothing is
+// modified.
+func (s *ClientService) CheckImport(inboundSvc *InboundService, inboundId int, rows []ClientImportRow) ([]ClientImportFailure, error) {
+	inbound, err := inboundSvc.GetInbound(inboundId)
+	if err != nil {
+		return nil, err
+	}
+	if !clientProtocols[inbound.Protocol] {
+		return nil, common.NewError(fmt.Sprintf("inbound %s uses %s, which has no clients", inbound.Tag, inbound.Protocol))
+	}
+
+	var failures []ClientImportFailure
+	fail := func(row ClientImportRow, reason string) {
+		failures = append(failures, ClientImportFailure{Line: row.Line, Email: row.Client.Email, Reason: reason})
+	}
+	seen := make(map[string]int, len(rows))
+	emails := make([]string, 0, len(rows))
+	for _, row := range rows {
+		email := strings.TrimSpace(row.Client.Email)
+		if email == "" {
+			fail(row, "client email is required")
+			continue
+		}
+		if err := validateClientEmail(email); err != nil {
+			fail(row, err.Error())
+			continue
+		}
+		if line, dup := seen[strings.ToLower(email)]; dup {
+			fail(row, fmt.Sprintf("email repeats line %d", line))
+			continue
+		}
+		seen[strings.ToLower(email)] = row.Line
+		emails = append(emails, email)
+	}
+
+	inUse := make(map[string]bool)
+	for start := 0; start < len(emails); start += sqlInChunk {
+		end := min(start+sqlInChunk, len(emails))
+		var records []model.ClientRecord
+		if err := database.GetDB().Where("email IN ?", emails[start:end]).Find(&records).Error; err != nil {
+			return nil, err
+		}
+		for _, rec := range records {
+			inUse[strings.ToLower(rec.Email)] = true
+		}
+	}
+	for _, row := range rows {
+		email := strings.ToLower(strings.TrimSpace(row.Client.Email))
+		if inUse[email] && seen[email] == row.Line {
+			fail(row, "email already in use: "+row.Client.Email)
+		}
+	}
+	slices.SortStableFunc(failures, func(a, b ClientImportFailure) int { return a.Line - b.Line })
+	return failures, nil
+}
+
+// Import creates the clients of rows in inbound inboundId. The rows are
+// checked first; unless partial is set, one failing row rejects the whole
+// import and nothing is created. The clients that pass are added in one
+// batch, so the inbound is saved in a single transaction and Xray is updated
+// once. The returned bool reports whether Xray needs a restart.
+func (s *ClientService) Import(inboundSvc *InboundService, inboundId int, rows []ClientImportRow, partial bool) (ClientImportResult, bool, error) {
+	result := ClientImportResult{}
+	failures, err := s.CheckImport(inboundSvc, inboundId, rows)
+	if err != nil {
+		return result, false, err
+	}
+	result.Failed = failures
+	if len(failures) > 0 && !partial {
+		return result, false, nil
+	}
+
+	failedLines := make(map[int]bool, len(failures))
+	for _, f := range failures {
+		failedLines[f.Line] = true
+	}
+	lineOf := make(map[string]int, len(rows))
+	payloads := make([]ClientCreatePayload, 0, len(rows))
+	for _, row := range rows {
+		if failedLines[row.Line] {
+			continue
+		}
+		client := row.Client
+		client.Email = strings.TrimSpace(client.Email)
+		lineOf[strings.ToLower(client.Email)] = row.Line
+		payloads = append(payloads, ClientCreatePayload{Client: client, InboundIds: []int{inboundId}})
+	}
+
+	created, needRestart, err := s.BulkCreate(inboundSvc, payloads)
+	if err != nil {
+		return result, needRestart, err
+	}
+	result.Created = created.Created
+	for _, skipped := range created.Skipped {
+		result.Failed = append(result.Failed, ClientImportFailure{
+			Line:   lineOf[strings.ToLower(skipped.Email)],
+			Email:  skipped.Email,
+			Reason: skipped.Reason,
+		})
+	}
+	return result, needRestart, nil
+}
@@ -0,0 +1,90 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/zixu5u/3xv/v3/internal/database"
+	"github.com/zixu5u/3xv/v3/internal/database/model"
+)
+
+func TestClientImport(t *testing.T) {
+	setupBulkDB(t)
+	db := database.GetDB()
+	svc := ClientService{}
+	inboundSvc := &InboundService{}
+
+	existing := model.Client{Email: "taken@example.com", ID: "6a1f0c2e-8b3d-4e5f-9a7b-1c2d3e4f5a60", SubID: "subtaken00000001", Enable: true}
+	inbound := &model.Inbound{
+		Tag: "vless-import", Enable: true, Port: 45001, Protocol: model.VLESS,
+		StreamSettings: `{"network":"tcp","security":"none"}`,
+		Settings:       clientsSettings(t, []model.Client{existing}),
+	}
+	if err := db.Create(inbound).Error; err != nil {
+		t.Fatalf("create inbound: %v", err)
+	}
+	if err := svc.SyncInbound(nil, inbound.Id, []model.Client{existing}); err != nil {
+		t.Fatalf("SyncInbound: %v", err)
+	}
+
+	rows := []ClientImportRow{
+		{Line: 2, Client: model.Client{Email: "alice@example.com", TotalGB: 10 << 30}},
+		{Line: 3, Client: model.Client{Email: "bob@example.com", ExpiryTime: 1893456000000}},
+		{Line: 4, Client: model.Client{Email: "Alice@example.com"}},
+		{Line: 5, Client: model.Client{Email: "taken@example.com"}},
+		{Line: 6, Client: model.Client{Email: "bad name"}},
+	}
+	failures, err := svc.CheckImport(inboundSvc, inbound.Id, rows)
+	if err != nil {
+		t.Fatalf("CheckImport: %v", err)
+	}
+	var lines []int
+	for _, f := range failures {
+		lines = append(lines, f.Line)
+	}
+	if len(lines) != 3 || lines[0] != 4 || lines[1] != 5 || lines[2] != 6 {
+		t.Fatalf("failed lines = %v, want 4, 5 and 6", lines)
+	}
+
+	result, _, err := svc.Import(inboundSvc, inbound.Id, rows, false)
+	if err != nil {
+		t.Fatalf("strict Import: %v", err)
+	}
+	if result.Created != 0 || len(result.Failed) != 3 {
+		t.Fatalf("strict import = %+v, want nothing created", result)
+	}
+	if list, _ := svc.ListForInbound(nil, inbound.Id); len(list) != 1 {
+		t.Fatalf("strict import with failures changed the inbound: %v", emailsOf(list))
+	}
+
+	result, _, err = svc.Import(inboundSvc, inbound.Id, rows, true)
+	if err != nil {
+		t.Fatalf("partial Import: %v", err)
+	}
+	if result.Created != 2 || len(result.Failed) != 3 {
+		t.Fatalf("partial import = %+v, want two clients created", result)
+	}
+	list, err := svc.ListForInbound(nil, inbound.Id)
+	if err != nil || len(list) != 3 {
+		t.Fatalf("inbound lists %v (err %v), want the existing and two imported clients", emailsOf(list), err)
+	}
+	for _, c := range list {
+		switch c.Email {
+		case "alice@example.com":
+			if c.TotalGB != 10<<30 || c.ID == "" || c.SubID == "" {
+				t.Errorf("imported alice = %+v, want her limit and generated credentials", c)
+			}
+		case "bob@example.com":
+			if c.ExpiryTime != 1893456000000 {
+				t.Errorf("imported bob expires at %d", c.ExpiryTime)
+			}
+		}
+	}
+
+	mixed := &model.Inbound{Tag: "mixed-import", Enable: true, Port: 45002, Protocol: model.Mixed, Settings: `{}`}
+	if err := db.Create(mixed).Error; err != nil {
+		t.Fatalf("create inbound: %v", err)
+	}
+	if _, err := svc.CheckImport(inboundSvc, mixed.Id, rows); err == nil {
+		t.Error("checked an import into an inbound without clients")
+	}
+}
@@ -923,6 +923,11 @@ func (s *ClientService) ToggleClientEnableByEmail(inboundSvc *InboundService, cl
 	if err != nil {
 		return false, needRestart, err
 	}
+	if !clientOldEnabled {
+		if err := clearDisableReason(clientEmail); err != nil {
+			logger.Warning("Failed to clear the disable reason of", clientEmail, err)
+		}
+	}
 
 	return !clientOldEnabled, needRestart, nil
 }
@@ -1035,6 +1040,18 @@ func (s *ClientService) ResetClientExpiryTimeByEmail(inboundSvc *InboundService,
 	})
 }
 
+// SetClientCommentByEmail replaces the client's comment, which doubles as
+// its operator note in the bot.
+func (s *ClientService) SetClientCommentByEmail(inboundSvc *InboundService, clientEmail string, comment string) (bool, error) {
+	comment, err := normalizeNote(comment)
+	if err != nil {
+		return false, err
+	}
+	return s.applyClientFieldByEmail(inboundSvc, clientEmail, func(c map[string]any) {
+		c["comment"] = comment
+	})
+}
+
 func (s *ClientService) ResetClientTrafficLimitByEmail(inboundSvc *InboundService, clientEmail string, totalGB int) (bool, error) {
 	if totalGB < 0 {
 		return false, common.NewError("totalGB must be >= 0")
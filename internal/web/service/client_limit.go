@@ -0,0 +1,157 @@
+package service
+
+import (
+	"sync"
+	"time"
+
+	"github.com/zixu5u/3xv/v3/internal/database"
+	"github.com/zixu5u/3xv/v3/internal/xray"
+)
+
+// ClientLimitBatchSize is how many clients one ClientLimitService.Scan
+// reads. A panel with thousands of clients is covered over several scans
+// instead of all at once.
+const ClientLimitBatchSize = 500
+
+// ClientLimitState is a set of limit conditions a client is in.
+type ClientLimitState uint8
+
+const (
+	ClientNearTrafficLimit ClientLimitState = 1 << iota // within the traffic alert threshold
+	ClientTrafficExhausted                              // traffic limit used up
+	ClientNearExpiry                                    // within the expiry alert threshold
+	ClientExpired                                       // expiry date passed
+)
+
+// ClientLimitAlert is a client that reached a limit condition since it was
+// last scanned.
+type ClientLimitAlert struct {
+	Email      string
+	InboundId  int
+	State      ClientLimitState // the newly reached conditions
+	Used       int64
+	Total      int64
+	ExpiryTime int64 // unix ms
+}
+
+// ClientLimitService watches the traffic limit and expiry date of every
+// client for the Telegram bot's client limit alerts.
+type ClientLimitService struct{}
+
+// clientLimitSeen is the state a client had when it was last scanned, and
+// in which pass over all clients that was.
+type clientLimitSeen struct {
+	state ClientLimitState
+	pass  uint32
+}
+
+// clientLimitScan is the progress of the scan over client_traffics. It is
+// kept in memory: the first full pass after a panel start only records
+// which clients are already at a limit, so a restart doesn't repeat every
+// alert. Clients in no condition aren't tracked.
+var clientLimitScan struct {
+	sync.Mutex
+	cursor int // id of the last client scanned in the current pass
+	pass   uint32
+	primed bool
+	states map[string]clientLimitSeen
+}
+
+// clientLimitStateOf returns the conditions traffic is in under the alert
+// thresholds of its inbound.
+func clientLimitStateOf(traffic *xray.ClientTraffic, nowMs int64, thresholds AlertThresholds) ClientLimitState {
+	var state ClientLimitState
+	if traffic.Total > 0 {
+		used := traffic.Up + traffic.Down
+		switch {
+		case used >= traffic.Total:
+			state |= ClientTrafficExhausted
+		case thresholds.NearTrafficLimit(used, traffic.Total):
+			state |= ClientNearTrafficLimit
+		}
+	}
+	// a negative expiry is a delayed start, which has no date yet
+	if traffic.ExpiryTime > 0 {
+		switch {
+		case traffic.ExpiryTime <= nowMs:
+			state |= ClientExpired
+		case thresholds.NearExpiry(traffic.ExpiryTime, nowMs):
+			state |= ClientNearExpiry
+		}
+	}
+	return state
+}
+
+// Scan checks the next batch of at most batch clients, in id order, and
+// returns those that reached a condition they weren't in at their previous
+// scan. It reads one bounded page per call and wraps around once every
+// client was checked, so a full pass takes len(clients)/batch calls. A call
+// made while the previous one still runs returns nothing. defaults are the
+// panel's thresholds; inbounds may override them.
+func (s *ClientLimitService) Scan(now time.Time, batch int, defaults AlertThresholds) ([]ClientLimitAlert, error) {
+	if !clientLimitScan.TryLock() {
+		return nil, nil
+	}
+	defer clientLimitScan.Unlock()
+
+	var traffics []xray.ClientTraffic
+	err := database.GetDB().Model(&xray.ClientTraffic{}).
+		Select("id, inbound_id, email, up, down, total, expiry_time").
+		Where("id > ?", clientLimitScan.cursor).
+		Order("id").
+		Limit(batch).
+		Find(&traffics).Error
+	if err != nil {
+		return nil, err
+	}
+	overrides, err := InboundAlertThresholds(defaults)
+	if err != nil {
+		return nil, err
+	}
+	if clientLimitScan.states == nil {
+		clientLimitScan.states = make(map[string]clientLimitSeen)
+	}
+
+	nowMs := now.UnixMilli()
+	var alerts []ClientLimitAlert
+	for i := range traffics {
+		traffic := &traffics[i]
+		thresholds, ok := overrides[traffic.InboundId]
+		if !ok {
+			thresholds = defaults
+		}
+		state := clientLimitStateOf(traffic, nowMs, thresholds)
+		previous := clientLimitScan.states[traffic.Email]
+		if reached := state &^ previous.state; reached != 0 && clientLimitScan.primed {
+			alerts = append(alerts, ClientLimitAlert{
+				Email:      traffic.Email,
+				InboundId:  traffic.InboundId,
+				State:      reached,
+				Used:       traffic.Up + traffic.Down,
+				Total:      traffic.Total,
+				ExpiryTime: traffic.ExpiryTime,
+			})
+		}
+		if state == 0 {
+			delete(clientLimitScan.states, traffic.Email)
+		} else {
+			clientLimitScan.states[traffic.Email] = clientLimitSeen{state: state, pass: clientLimitScan.pass}
+		}
+	}
+
+	if len(traffics) == batch {
+		clientLimitScan.cursor = traffics[len(traffics)-1].Id
+		return alerts, nil
+	}
+	// the pass is complete: forget clients that weren't seen in it, which
+	// were deleted, and start over
+	for email, seen := range clientLimitScan.states {
+		if seen.pass != clientLimitScan.pass {
+			delete(clientLimitScan.states, email)
+		}
+	}
+	clientLimitScan.cursor = 0
+	clientLimitScan.pass++
+	clientLimitScan.primed = true
+	return alerts, nil
+}
@@ -0,0 +1,172 @@
+package service
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/zixu5u/3xv/v3/internal/database/model"
+	"github.com/zixu5u/3xv/v3/internal/xray"
+)
+
+func TestClientLimitScan(t *testing.T) {
+	db := initTrafficTestDB(t)
+	resetScan := func() {
+		clientLimitScan.cursor, clientLimitScan.pass, clientLimitScan.primed, clientLimitScan.states = 0, 0, false, nil
+	}
+	resetScan()
+	t.Cleanup(resetScan)
+	svc := &ClientLimitService{}
+	now := time.Date(2024, 5, 3, 12, 0, 0, 0, time.UTC)
+	day := int64(24 * time.Hour / time.Millisecond)
+
+	// five clients, scanned two at a time
+	for i := range 5 {
+		traffic := &xray.ClientTraffic{InboundId: 1, Email: fmt.Sprintf("c%d@example.com", i), Enable: true, Total: 1000}
+		if err := db.Create(traffic).Error; err != nil {
+			t.Fatalf("create client_traffics: %v", err)
+		}
+	}
+	// already used up before the first pass: only recorded, never alerted
+	db.Model(&xray.ClientTraffic{}).Where("email = ?", "c0@example.com").Update("up", 1000)
+
+	scan := func() []ClientLimitAlert {
+		t.Helper()
+		alerts, err := svc.Scan(now, 2, AlertThresholds{ExpireDiffMs: 3 * day, TrafficDiffBytes: 100})
+		if err != nil {
+			t.Fatalf("Scan: %v", err)
+		}
+		return alerts
+	}
+	for range 3 {
+		if alerts := scan(); len(alerts) != 0 {
+			t.Fatalf("first pass alerted %+v", alerts)
+		}
+	}
+	if !clientLimitScan.primed || clientLimitScan.cursor != 0 {
+		t.Fatalf("after a full pass primed=%v cursor=%d", clientLimitScan.primed, clientLimitScan.cursor)
+	}
+
+	db.Model(&xray.ClientTraffic{}).Where("email = ?", "c1@example.com").Update("down", 950)
+	db.Model(&xray.ClientTraffic{}).Where("email = ?", "c3@example.com").Update("expiry_time", now.UnixMilli()+day)
+	var alerts []ClientLimitAlert
+	for range 3 {
+		alerts = append(alerts, scan()...)
+	}
+	if len(alerts) != 2 ||
+		alerts[0].Email != "c1@example.com" || alerts[0].State != ClientNearTrafficLimit || alerts[0].Used != 950 ||
+		alerts[1].Email != "c3@example.com" || alerts[1].State != ClientNearExpiry {
+		t.Fatalf("second pass alerts = %+v", alerts)
+	}
+
+	// unchanged conditions aren't repeated; a worse one is
+	db.Model(&xray.ClientTraffic{}).Where("email = ?", "c1@example.com").Update("down", 1000)
+	alerts = nil
+	for range 3 {
+		alerts = append(alerts, scan()...)
+	}
+	if len(alerts) != 1 || alerts[0].Email != "c1@example.com" || alerts[0].State != ClientTrafficExhausted {
+		t.Fatalf("third pass alerts = %+v", alerts)
+	}
+
+	// deleted clients are forgotten at the end of a pass
+	db.Where("email = ?", "c3@example.com").Delete(&xray.ClientTraffic{})
+	for range 3 {
+		scan()
+	}
+	if _, ok := clientLimitScan.states["c3@example.com"]; ok {
+		t.Fatal("deleted client is still tracked")
+	}
+}
+
+func TestClientLimitStateOf(t *testing.T) {
+	now := int64(1_000_000)
+	cases := []struct {
+		traffic xray.ClientTraffic
+		want    ClientLimitState
+	}{
+		{xray.ClientTraffic{Up: 500, Total: 0}, 0},
+		{xray.ClientTraffic{Up: 500, Total: 1000}, 0},
+		{xray.ClientTraffic{Up: 950, Total: 1000}, ClientNearTrafficLimit},
+		{xray.ClientTraffic{Up: 600, Down: 600, Total: 1000}, ClientTrafficExhausted},
+		{xray.ClientTraffic{ExpiryTime: -86400000}, 0},
+		{xray.ClientTraffic{ExpiryTime: now + 50}, ClientNearExpiry},
+		{xray.ClientTraffic{ExpiryTime: now, Up: 1000, Total: 1000}, ClientExpired | ClientTrafficExhausted},
+	}
+	for _, c := range cases {
+		if got := clientLimitStateOf(&c.traffic, now, AlertThresholds{ExpireDiffMs: 100, TrafficDiffBytes: 100}); got != c.want {
+			t.Errorf("clientLimitStateOf(%+v) = %b, want %b", c.traffic, got, c.want)
+		}
+	}
+}
+
+func TestAlertThresholds(t *testing.T) {
+	defaults := DefaultAlertThresholds(1, 3)
+	if defaults.TrafficDiffBytes != 1073741824 || defaults.ExpireDiffMs != 3*86400000 {
+		t.Fatalf("defaults = %+v", defaults)
+	}
+	if got := defaults.ForInbound(&model.Inbound{}); got != defaults {
+		t.Fatalf("an inbound without overrides must use the defaults, got %+v", got)
+	}
+
+	percent, days := 80, 0
+	custom := defaults.ForInbound(&model.Inbound{AlertTrafficPercent: &percent, AlertExpiryDays: &days})
+	if !custom.TrafficOverride || !custom.ExpiryOverride || custom.TrafficPercent != 80 || custom.ExpireDiffMs != 0 {
+		t.Fatalf("overridden = %+v", custom)
+	}
+	// 10 GB limit: 1 GB left is near under the default, 70% used is not near at 80%
+	total := int64(10 * 1073741824)
+	if !defaults.NearTrafficLimit(total-1073741823, total) || custom.NearTrafficLimit(total*7/10, total) {
+		t.Fatal("traffic thresholds misapplied")
+	}
+	if !custom.NearTrafficLimit(total*8/10, total) || custom.NearTrafficLimit(5, 0) {
+		t.Fatal("percent threshold misapplied")
+	}
+	now := int64(1_000_000_000)
+	if !defaults.NearExpiry(now+86400000, now) || custom.NearExpiry(now+86400000, now) {
+		t.Fatal("an expiry override of 0 days must turn near-expiry warnings off")
+	}
+	if defaults.NearExpiry(-86400000, now) {
+		t.Fatal("a delayed start is never near expiry")
+	}
+}
+
+func TestClientLimitScanOverrides(t *testing.T) {
+	db := initTrafficTestDB(t)
+	resetScan := func() {
+		clientLimitScan.cursor, clientLimitScan.pass, clientLimitScan.primed, clientLimitScan.states = 0, 0, false, nil
+	}
+	resetScan()
+	t.Cleanup(resetScan)
+
+	percent := 50
+	for _, inbound := range []*model.Inbound{
+		{Id: 1, Tag: "prod", Port: 1001},
+		{Id: 2, Tag: "test", Port: 1002, AlertTrafficPercent: &percent},
+	} {
+		if err := db.Create(inbound).Error; err != nil {
+			t.Fatalf("create inbound: %v", err)
+		}
+	}
+	for i, inboundId := range []int{1, 2} {
+		traffic := &xray.ClientTraffic{InboundId: inboundId, Email: fmt.Sprintf("c%d@example.com", i), Enable: true, Total: 1000}
+		if err := db.Create(traffic).Error; err != nil {
+			t.Fatalf("create client_traffics: %v", err)
+		}
+	}
+
+	svc := &ClientLimitService{}
+	now := time.Date(2024, 5, 3, 12, 0, 0, 0, time.UTC)
+	defaults := AlertThresholds{TrafficDiffBytes: 100}
+	if _, err := svc.Scan(now, 10, defaults); err != nil {
+		t.Fatalf("Scan: %v", err)
+	}
+	db.Model(&xray.ClientTraffic{}).Where("1 = 1").Update("up", 600)
+	alerts, err := svc.Scan(now, 10, defaults)
+	if err != nil {
+		t.Fatalf("Scan: %v", err)
+	}
+	if len(alerts) != 1 || alerts[0].Email != "c1@example.com" || alerts[0].State != ClientNearTrafficLimit {
+		t.Fatalf("only the client of the 50%% inbound is near its limit, alerts = %+v", alerts)
+	}
+}
@@ -186,3 +186,34 @@ func (s *ClientService) ListForInbound(tx *gorm.DB, inboundId int) ([]model.Clie
 	}
 	return out, nil
 }
+
+// InboundClientCount is how many clients are attached to an inbound and how
+// many of them are enabled.
+type InboundClientCount struct {
+	Total   int
+	Enabled int
+}
+
+// CountByInbound returns the client counts of every inbound that has
+// clients, keyed by inbound ID, in a single query.
+func (s *ClientService) CountByInbound() (map[int]InboundClientCount, error) {
+	var rows []struct {
+		InboundId int
+		Total     int
+		Enabled   int
+	}
+	err := database.GetDB().Table("client_inbounds").
+		Select("client_inbounds.inbound_id AS inbound_id, COUNT(*) AS total, " +
+			"SUM(CASE WHEN clients.enable THEN 1 ELSE 0 END) AS enabled").
+		Joins("JOIN clients ON clients.id = client_inbounds.client_id").
+		Group("client_inbounds.inbound_id").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	counts := make(map[int]InboundClientCount, len(rows))
+	for _, row := range rows {
+		counts[row.InboundId] = InboundClientCount{Total: row.Total, Enabled: row.Enabled}
+	}
+	return counts, nil
+}
@@ -187,3 +187,20 @@ func (s *ClientService) findInboundIdsByClientEmail(email string) ([]int, error)
 	}
 	return out, nil
 }
+
+// IpLimits returns the IP limit of every client that has one, by email.
+func (s *ClientService) IpLimits() (map[string]int, error) {
+	var rows []model.ClientRecord
+	err := database.GetDB().Model(&model.ClientRecord{}).
+		Select("email, limit_ip").
+		Where("limit_ip > 0").
+		Find(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	limits := make(map[string]int, len(rows))
+	for _, row := range rows {
+		limits[row.Email] = row.LimitIP
+	}
+	return limits, nil
+}
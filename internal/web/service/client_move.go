@@ -0,0 +1,139 @@
+package service
+
+import (
+	"fmt"
+
+	"github.com/zixu5u/3xv/v3/internal/database"
+	"github.com/zixu5u/3xv/v3/internal/database/model"
+	"github.com/zixu5u/3xv/v3/internal/util/common"
+	"github.com/zixu5u/3xv/v3/internal/xray"
+)
+
+// clientProtocols are the inbound protocols that hold a list of clients.
+var clientProtocols = map[model.Protocol]bool{
+	model.VMESS:       true,
+	model.VLESS:       true,
+	model.Trojan:      true,
+	model.Shadowsocks: true,
+	model.Hysteria:    true,
+}
+
+// ClientMove is a checked move of a client from one inbound to another,
+// with what changes on the client's side.
+type ClientMove struct {
+	Email string
+	From  *model.Inbound
+	To    *model.Inbound
+	// ProtocolChanged is set when the inbounds speak different protocols.
+	ProtocolChanged bool
+	// NewCredential is set when the client can't keep its UUID, password or
+	// auth on the target inbound and gets a generated one.
+	NewCredential bool
+	// FlowDropped is set when the target inbound can't use the client's
+	// XTLS flow.
+	FlowDropped bool
+}
+
+// clientCredential returns the secret a client authenticates with on an
+// inbound of protocol.
+func clientCredential(c model.Client, protocol model.Protocol) string {
+	switch protocol {
+	case model.Trojan, model.Shadowsocks:
+		return c.Password
+	case model.Hysteria:
+		return c.Auth
+	default:
+		return c.ID
+	}
+}
+
+// PlanMove checks that the client email can move from inbound fromId to
+// inbound toId and works out what changes for the client. Nothing is
+// modified.
+func (s *ClientService) PlanMove(inboundSvc *InboundService, email string, fromId, toId int) (*ClientMove, error) {
+	if fromId == toId {
+		return nil, common.NewError("source and target inbounds must be different")
+	}
+	rec, err := s.GetRecordByEmail(nil, email)
+	if err != nil {
+		return nil, err
+	}
+	attached, err := s.GetInboundIdsForRecord(rec.Id)
+	if err != nil {
+		return nil, err
+	}
+	inFrom := false
+	for _, id := range attached {
+		switch id {
+		case fromId:
+			inFrom = true
+		case toId:
+			return nil, common.NewError(fmt.Sprintf("client %q is already in inbound %d", rec.Email, toId))
+		}
+	}
+	if !inFrom {
+		return nil, fmt.Errorf("client %q in inbound %d: %w", rec.Email, fromId, ErrClientNotInInbound)
+	}
+
+	from, err := inboundSvc.GetInbound(fromId)
+	if err != nil {
+		return nil, err
+	}
+	to, err := inboundSvc.GetInbound(toId)
+	if err != nil {
+		return nil, err
+	}
+	if !clientProtocols[to.Protocol] {
+		return nil, common.NewError(fmt.Sprintf("inbound %s uses %s, which has no clients", to.Tag, to.Protocol))
+	}
+
+	current := *rec.ToClient()
+	moved := current
+	if err := s.fillProtocolDefaults(&moved, to); err != nil {
+		return nil, err
+	}
+	return &ClientMove{
+		Email:           rec.Email,
+		From:            from,
+		To:              to,
+		ProtocolChanged: from.Protocol != to.Protocol,
+		NewCredential:   clientCredential(moved, to.Protocol) != clientCredential(current, from.Protocol),
+		FlowDropped:     current.Flow != "" && clientWithInboundFlow(moved, to).Flow == "",
+	}, nil
+}
+
+// Move moves the client email from inbound fromId to inbound toId, keeping
+// its email, limits and subscription. It is attached to the target before
+// it leaves the source, so a failure leaves it in both rather than in
+// neither. The traffic counters follow the client unless keepTraffic is
+// false. The returned bool reports whether Xray needs a restart.
+func (s *ClientService) Move(inboundSvc *InboundService, email string, fromId, toId int, keepTraffic bool) (bool, error) {
+	move, err := s.PlanMove(inboundSvc, email, fromId, toId)
+	if err != nil {
+		return false, err
+	}
+	needRestart, err := s.AttachByEmail(inboundSvc, move.Email, []int{toId})
+	if err != nil {
+		return needRestart, err
+	}
+	detachRestart, err := s.DetachByEmail(inboundSvc, fromId, move.Email)
+	needRestart = needRestart || detachRestart
+	if err != nil {
+		return needRestart, err
+	}
+
+	err = submitTrafficWrite(func() error {
+		return database.GetDB().Model(xray.ClientTraffic{}).
+			Where("email = ?", move.Email).
+			Update("inbound_id", toId).Error
+	})
+	if err != nil {
+		return needRestart, err
+	}
+	if !keepTraffic {
+		if err := inboundSvc.ResetClientTrafficByEmail(move.Email); err != nil {
+			return needRestart, err
+		}
+	}
+	return needRestart, nil
+}
@@ -0,0 +1,92 @@
+package service
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/zixu5u/3xv/v3/internal/database"
+	"github.com/zixu5u/3xv/v3/internal/database/model"
+	"github.com/zixu5u/3xv/v3/internal/xray"
+)
+
+func TestClientMove(t *testing.T) {
+	setupBulkDB(t)
+	db := database.GetDB()
+	svc := ClientService{}
+	inboundSvc := &InboundService{}
+
+	const email = "mover@example.com"
+	const vision = "xtls-rprx-vision"
+	source := model.Client{Email: email, ID: "0d3e8f6a-3c1b-4a7e-9f2d-5b6c7d8e9f01", SubID: "submover00000001", Enable: true, Flow: vision}
+
+	reality := &model.Inbound{
+		Tag: "vless-reality", Enable: true, Port: 43001, Protocol: model.VLESS,
+		StreamSettings: `{"network":"tcp","security":"reality"}`,
+		Settings:       clientsSettings(t, []model.Client{source}),
+	}
+	vmess := &model.Inbound{Tag: "vmess-ws", Enable: true, Port: 43002, Protocol: model.VMESS,
+		StreamSettings: `{"network":"ws","security":"none"}`, Settings: `{"clients":[]}`}
+	trojan := &model.Inbound{Tag: "trojan", Enable: true, Port: 43003, Protocol: model.Trojan,
+		StreamSettings: `{"network":"tcp","security":"tls"}`, Settings: `{"clients":[]}`}
+	mixed := &model.Inbound{Tag: "mixed", Enable: true, Port: 43004, Protocol: model.Mixed, Settings: `{}`}
+	for _, inbound := range []*model.Inbound{reality, vmess, trojan, mixed} {
+		if err := db.Create(inbound).Error; err != nil {
+			t.Fatalf("create inbound %s: %v", inbound.Tag, err)
+		}
+	}
+	if err := svc.SyncInbound(nil, reality.Id, []model.Client{source}); err != nil {
+		t.Fatalf("SyncInbound: %v", err)
+	}
+	if err := db.Create(&xray.ClientTraffic{InboundId: reality.Id, Email: email, Up: 100, Down: 200, Enable: true}).Error; err != nil {
+		t.Fatalf("create traffic: %v", err)
+	}
+
+	move, err := svc.PlanMove(inboundSvc, email, reality.Id, trojan.Id)
+	if err != nil {
+		t.Fatalf("PlanMove(trojan): %v", err)
+	}
+	if !move.ProtocolChanged || !move.NewCredential || !move.FlowDropped {
+		t.Errorf("move to trojan = %+v, want protocol, credential and flow changes", move)
+	}
+	move, err = svc.PlanMove(inboundSvc, email, reality.Id, vmess.Id)
+	if err != nil {
+		t.Fatalf("PlanMove(vmess): %v", err)
+	}
+	if move.NewCredential || !move.FlowDropped {
+		t.Errorf("move to vmess = %+v, want the UUID kept and the flow dropped", move)
+	}
+	if _, err := svc.PlanMove(inboundSvc, email, reality.Id, mixed.Id); err == nil {
+		t.Error("moved a client to an inbound without clients")
+	}
+	if _, err := svc.PlanMove(inboundSvc, email, vmess.Id, trojan.Id); !errors.Is(err, ErrClientNotInInbound) {
+		t.Errorf("move from an inbound the client isn't in: %v", err)
+	}
+
+	if _, err := svc.Move(inboundSvc, email, reality.Id, vmess.Id, true); err != nil {
+		t.Fatalf("Move: %v", err)
+	}
+	if list, err := svc.ListForInbound(nil, reality.Id); err != nil || len(list) != 0 {
+		t.Errorf("source still lists %v (err %v)", emailsOf(list), err)
+	}
+	list, err := svc.ListForInbound(nil, vmess.Id)
+	if err != nil || len(list) != 1 || list[0].Email != email || list[0].ID != source.ID || list[0].SubID != source.SubID {
+		t.Fatalf("target lists %+v (err %v), want the client with its UUID and subscription", list, err)
+	}
+	var traffic xray.ClientTraffic
+	if err := db.Where("email = ?", email).First(&traffic).Error; err != nil {
+		t.Fatalf("load traffic: %v", err)
+	}
+	if traffic.InboundId != vmess.Id || traffic.Up != 100 || traffic.Down != 200 {
+		t.Errorf("traffic after a move keeping it = %+v", traffic)
+	}
+
+	if _, err := svc.Move(inboundSvc, email, vmess.Id, trojan.Id, false); err != nil {
+		t.Fatalf("Move: %v", err)
+	}
+	if err := db.Where("email = ?", email).First(&traffic).Error; err != nil {
+		t.Fatalf("load traffic: %v", err)
+	}
+	if traffic.InboundId != trojan.Id || traffic.Up != 0 || traffic.Down != 0 {
+		t.Errorf("traffic after a move resetting it = %+v", traffic)
+	}
+}
@@ -0,0 +1,117 @@
+package service
+
+import (
+	"sync"
+	"time"
+
+	"github.com/zixu5u/3xv/v3/internal/database"
+	"github.com/zixu5u/3xv/v3/internal/database/model"
+	"github.com/zixu5u/3xv/v3/internal/xray"
+)
+
+// ClientUsageService samples the traffic of every client over time, so the
+// Telegram bot can tell how much a client used in a recent period instead of
+// only since its last reset.
+type ClientUsageService struct{}
+
+// ClientUsage is the traffic a client moved in a period, in total and split
+// into equal buckets, oldest first.
+type ClientUsage struct {
+	Up      int64
+	Down    int64
+	Buckets []int64
+	// Since is when the samples covering the period start. It is later
+	// than the requested start while the history doesn't reach back that
+	// far yet.
+	Since time.Time
+}
+
+// clientUsageBaseline holds the counters every client had at the previous
+// sample. It is kept in memory: the first sample after a panel start only
+// sets it, so traffic moved while the panel was down is not put into any
+// period.
+var clientUsageBaseline struct {
+	sync.Mutex
+	counters map[string][2]int64
+}
+
+// Record stores the traffic every client moved since the previous call.
+// Clients that moved none are skipped. Old samples are removed by Prune.
+func (s *ClientUsageService) Record(at time.Time) error {
+	db := database.GetDB()
+	var traffics []xray.ClientTraffic
+	if err := db.Model(&xray.ClientTraffic{}).Select("email, up, down").Find(&traffics).Error; err != nil {
+		return err
+	}
+
+	clientUsageBaseline.Lock()
+	defer clientUsageBaseline.Unlock()
+	previous := clientUsageBaseline.counters
+	current := make(map[string][2]int64, len(traffics))
+	var samples []model.ClientUsageSample
+	for _, traffic := range traffics {
+		current[traffic.Email] = [2]int64{traffic.Up, traffic.Down}
+		before, ok := previous[traffic.Email]
+		if !ok {
+			continue
+		}
+		up := counterDelta(before[0], traffic.Up)
+		down := counterDelta(before[1], traffic.Down)
+		if up == 0 && down == 0 {
+			continue
+		}
+		samples = append(samples, model.ClientUsageSample{
+			Email:     traffic.Email,
+			SampledAt: at.Unix(),
+			Up:        up,
+			Down:      down,
+		})
+	}
+	if len(samples) > 0 {
+		if err := db.CreateInBatches(samples, 200).Error; err != nil {
+			return err
+		}
+	}
+	clientUsageBaseline.counters = current
+	return nil
+}
+
+// Prune deletes the samples taken before before and returns how many there
+// were.
+func (s *ClientUsageService) Prune(before time.Time) (int64, error) {
+	result := database.GetDB().Where("sampled_at < ?", before.Unix()).Delete(&model.ClientUsageSample{})
+	return result.RowsAffected, result.Error
+}
+
+// GetUsage returns the traffic email moved in the period ending at now,
+// split into buckets. ok is false while no samples were taken yet.
+func (s *ClientUsageService) GetUsage(email string, period time.Duration, buckets int, now time.Time) (usage ClientUsage, ok bool, err error) {
+	db := database.GetDB()
+	var oldest model.ClientUsageSample
+	if err := db.Order("sampled_at").Limit(1).Find(&oldest).Error; err != nil || oldest.Id == 0 {
+		return ClientUsage{}, false, err
+	}
+
+	start := now.Add(-period)
+	usage.Since = start
+	if first := time.Unix(oldest.SampledAt, 0); first.After(start) {
+		usage.Since = first
+	}
+	var samples []model.ClientUsageSample
+	err = db.Where("email = ? AND sampled_at > ? AND sampled_at <= ?", email, start.Unix(), now.Unix()).
+		Order("sampled_at").
+		Find(&samples).Error
+	if err != nil {
+		return ClientUsage{}, false, err
+	}
+
+	usage.Buckets = make([]int64, max(buckets, 1))
+	width := max(period/time.Duration(len(usage.Buckets)), time.Second)
+	for _, sample := range samples {
+		usage.Up += sample.Up
+		usage.Down += sample.Down
+		i := int(time.Unix(sample.SampledAt, 0).Sub(start) / width)
+		usage.Buckets[min(max(i, 0), len(usage.Buckets)-1)] += sample.Up + sample.Down
+	}
+	return usage, true, nil
+}
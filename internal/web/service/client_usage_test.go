@@ -0,0 +1,83 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/zixu5u/3xv/v3/internal/xray"
+)
+
+func TestClientUsage(t *testing.T) {
+	db := initTrafficTestDB(t)
+	clientUsageBaseline.counters = nil
+	t.Cleanup(func() { clientUsageBaseline.counters = nil })
+	svc := &ClientUsageService{}
+	now := time.Date(2024, 5, 3, 12, 0, 0, 0, time.UTC)
+
+	busy := &xray.ClientTraffic{InboundId: 1, Email: "busy@example.com", Enable: true}
+	idle := &xray.ClientTraffic{InboundId: 1, Email: "idle@example.com", Enable: true, Up: 500, Down: 500}
+	for _, traffic := range []*xray.ClientTraffic{busy, idle} {
+		if err := db.Create(traffic).Error; err != nil {
+			t.Fatalf("create client_traffics: %v", err)
+		}
+	}
+	setCounters := func(up, down int64) {
+		t.Helper()
+		if err := db.Model(busy).Updates(map[string]any{"up": up, "down": down}).Error; err != nil {
+			t.Fatalf("update counters: %v", err)
+		}
+	}
+
+	if _, ok, err := svc.GetUsage(busy.Email, time.Hour, 4, now); err != nil || ok {
+		t.Fatalf("usage without samples: ok=%v err=%v", ok, err)
+	}
+
+	// the first sample only sets the baseline
+	setCounters(100, 1000)
+	if err := svc.Record(now.Add(-2 * time.Hour)); err != nil {
+		t.Fatalf("Record: %v", err)
+	}
+	var count int64
+	db.Table("client_usage_samples").Count(&count)
+	if count != 0 {
+		t.Fatalf("baseline sample stored %d rows, want 0", count)
+	}
+
+	setCounters(150, 1200)
+	if err := svc.Record(now.Add(-50 * time.Minute)); err != nil {
+		t.Fatalf("Record: %v", err)
+	}
+	// counters reset in between count from zero
+	setCounters(10, 40)
+	if err := svc.Record(now.Add(-10 * time.Minute)); err != nil {
+		t.Fatalf("Record: %v", err)
+	}
+	db.Table("client_usage_samples").Count(&count)
+	if count != 2 {
+		t.Fatalf("stored %d samples, want 2 for the busy client only", count)
+	}
+
+	usage, ok, err := svc.GetUsage(busy.Email, time.Hour, 4, now)
+	if err != nil || !ok {
+		t.Fatalf("GetUsage: ok=%v err=%v", ok, err)
+	}
+	if usage.Up != 60 || usage.Down != 240 {
+		t.Fatalf("usage = ↑%d ↓%d, want ↑60 ↓240", usage.Up, usage.Down)
+	}
+	if want := []int64{250, 0, 0, 50}; len(usage.Buckets) != 4 || usage.Buckets[0] != want[0] || usage.Buckets[3] != want[3] {
+		t.Fatalf("buckets = %v, want %v", usage.Buckets, want)
+	}
+	// the history starts later than an hour ago
+	if !usage.Since.Equal(now.Add(-50 * time.Minute)) {
+		t.Fatalf("since = %v, want the oldest sample", usage.Since)
+	}
+	usage, _, _ = svc.GetUsage(busy.Email, 30*time.Minute, 4, now)
+	if !usage.Since.Equal(now.Add(-30 * time.Minute)) {
+		t.Fatalf("since = %v, want the start of the period", usage.Since)
+	}
+
+	deleted, err := svc.Prune(now.Add(-30 * time.Minute))
+	if err != nil || deleted != 1 {
+		t.Fatalf("Prune = %d, %v, want 1", deleted, err)
+	}
+}
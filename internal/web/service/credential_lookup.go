@@ -0,0 +1,105 @@
+package service
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/zixu5u/3xv/v3/internal/database"
+	"github.com/zixu5u/3xv/v3/internal/database/model"
+)
+
+// CredentialMatch is a client, or an inbound's own key, whose secret equals
+// a looked-up credential.
+type CredentialMatch struct {
+	InboundId     int
+	InboundTag    string
+	InboundRemark string
+	Protocol      model.Protocol
+	Email         string // "" when the inbound's own key matched
+	Field         string // the settings field that holds the secret
+}
+
+// ClientSecret returns the field Xray authenticates a client of protocol
+// by, and its value. Other fields of the client, such as a leftover id on a
+// trojan client, are never checked. Protocols without client secrets
+// return "".
+func ClientSecret(protocol model.Protocol, client model.Client) (field string, secret string) {
+	switch protocol {
+	case model.VMESS, model.VLESS:
+		return "id", client.ID
+	case model.Trojan, model.Shadowsocks:
+		return "password", client.Password
+	case model.Hysteria:
+		return "auth", client.Auth
+	default:
+		return "", ""
+	}
+}
+
+// credentialEqual compares a secret with a looked-up credential. UUIDs are
+// compared ignoring case, as Xray parses them; passwords must match exactly.
+func credentialEqual(field string, secret string, credential string) bool {
+	if secret == "" {
+		return false
+	}
+	if field == "id" {
+		return strings.EqualFold(secret, credential)
+	}
+	return secret == credential
+}
+
+// MatchCredential returns the clients of inbound whose secret is
+// credential, and the inbound itself when its shadowsocks server key is.
+func (s *InboundService) MatchCredential(inbound *model.Inbound, credential string) []CredentialMatch {
+	var matches []CredentialMatch
+	match := func(email string, field string) {
+		matches = append(matches, CredentialMatch{
+			InboundId:     inbound.Id,
+			InboundTag:    inbound.Tag,
+			InboundRemark: inbound.Remark,
+			Protocol:      inbound.Protocol,
+			Email:         email,
+			Field:         field,
+		})
+	}
+	if inbound.Protocol == model.Shadowsocks {
+		var settings struct {
+			Password string `json:"password"`
+		}
+		if json.Unmarshal([]byte(inbound.Settings), &settings) == nil && credentialEqual("password", settings.Password, credential) {
+			match("", "password")
+		}
+	}
+	clients, err := s.GetClients(inbound)
+	if err != nil {
+		return matches
+	}
+	for _, client := range clients {
+		if field, secret := ClientSecret(inbound.Protocol, client); credentialEqual(field, secret, credential) {
+			match(client.Email, field)
+		}
+	}
+	return matches
+}
+
+// FindByCredential scans every inbound for the clients, or shadowsocks
+// server keys, whose secret is credential: the reverse of a share link, for
+// finding out whose credential leaked.
+func (s *InboundService) FindByCredential(credential string) ([]CredentialMatch, error) {
+	credential = strings.TrimSpace(credential)
+	if credential == "" {
+		return nil, nil
+	}
+	var inbounds []*model.Inbound
+	err := database.GetDB().Model(model.Inbound{}).
+		Select("id, tag, remark, protocol, settings").
+		Find(&inbounds).Error
+	if err != nil {
+		return nil, err
+	}
+	var matches []CredentialMatch
+	for _, inbound := range inbounds {
+		matches = append(matches, s.MatchCredential(inbound, credential)...)
+	}
+	return matches, nil
+}
@@ -0,0 +1,50 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/zixu5u/3xv/v3/internal/database/model"
+)
+
+func TestMatchCredential(t *testing.T) {
+	svc := &InboundService{}
+	vless := &model.Inbound{Id: 1, Tag: "in-443", Protocol: model.VLESS, Settings: `{"clients":[
+		{"id":"0B2C6D3E-1111-4222-8333-944455556666","email":"alice"},
+		{"id":"7f000001-0000-4000-8000-000000000001","password":"hunter2","email":"bob"}]}`}
+	trojan := &model.Inbound{Id: 2, Tag: "in-8443", Protocol: model.Trojan, Settings: `{"clients":[
+		{"id":"0b2c6d3e-1111-4222-8333-944455556666","password":"hunter2","email":"carol"}]}`}
+	ss := &model.Inbound{Id: 3, Tag: "in-ss", Protocol: model.Shadowsocks, Settings: `{"method":"2022-blake3-aes-128-gcm",
+		"password":"c2VydmVyLWtleQ==","clients":[{"password":"Y2xpZW50LWtleQ==","email":"dave"}]}`}
+	hysteria := &model.Inbound{Id: 4, Tag: "in-hy", Protocol: model.Hysteria, Settings: `{"clients":[{"auth":"hunter2","email":"erin"}]}`}
+
+	cases := []struct {
+		inbound    *model.Inbound
+		credential string
+		want       []string // "email/field"
+	}{
+		// UUIDs match regardless of case.
+		{vless, "0b2c6d3e-1111-4222-8333-944455556666", []string{"alice/id"}},
+		// A password left on a vless client is not what Xray checks.
+		{vless, "hunter2", nil},
+		// Nor is a leftover id on a trojan client.
+		{trojan, "0b2c6d3e-1111-4222-8333-944455556666", nil},
+		{trojan, "hunter2", []string{"carol/password"}},
+		// Passwords are case-sensitive.
+		{trojan, "HUNTER2", nil},
+		{ss, "c2VydmVyLWtleQ==", []string{"/password"}},
+		{ss, "Y2xpZW50LWtleQ==", []string{"dave/password"}},
+		{hysteria, "hunter2", []string{"erin/auth"}},
+	}
+	for _, c := range cases {
+		var got []string
+		for _, m := range svc.MatchCredential(c.inbound, c.credential) {
+			if m.InboundId != c.inbound.Id || m.InboundTag != c.inbound.Tag {
+				t.Fatalf("match %+v names the wrong inbound", m)
+			}
+			got = append(got, m.Email+"/"+m.Field)
+		}
+		if len(got) != len(c.want) || (len(got) > 0 && got[0] != c.want[0]) {
+			t.Errorf("MatchCredential(%s, %q) = %v, want %v", c.inbound.Tag, c.credential, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,138 @@
+package service
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+// CronRegistry tracks named entries of a cron scheduler, so jobs that are
+// added, changed and removed at runtime can be addressed by name instead of
+// by the entry ID each caller would otherwise have to keep. All mutations of
+// named entries go through its mutex; the cron itself is safe for concurrent
+// use, so fixed jobs can still be added to it directly.
+type CronRegistry struct {
+	mu      sync.Mutex
+	cron    *cron.Cron
+	entries map[string]cron.EntryID
+}
+
+// panelCronParser matches the panel cron's parser (cron.WithSeconds()).
+var panelCronParser = cron.NewParser(cron.Second | cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor)
+
+// panelCron is the registry of the panel cron created on every panel start.
+var panelCron = &CronRegistry{}
+
+// PanelCron returns the registry of the panel's cron scheduler.
+func PanelCron() *CronRegistry {
+	return panelCron
+}
+
+// Attach makes c the scheduler of the registry. Named entries of the
+// previous scheduler are removed from it and forgotten.
+func (r *CronRegistry) Attach(c *cron.Cron) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.cron != nil {
+		for _, id := range r.entries {
+			r.cron.Remove(id)
+		}
+	}
+	r.cron = c
+	r.entries = map[string]cron.EntryID{}
+}
+
+// Set schedules job under name, replacing the entry of that name if there
+// is one. It returns false when no scheduler is attached yet.
+func (r *CronRegistry) Set(name string, schedule cron.Schedule, job cron.Job) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.cron == nil {
+		return false
+	}
+	if id, ok := r.entries[name]; ok {
+		r.cron.Remove(id)
+	}
+	r.entries[name] = r.cron.Schedule(schedule, job)
+	return true
+}
+
+// SetSpec is Set for an expression in the panel cron format, seconds first.
+func (r *CronRegistry) SetSpec(name string, spec string, job cron.Job) error {
+	schedule, err := panelCronParser.Parse(spec)
+	if err != nil {
+		return err
+	}
+	if !r.Set(name, schedule, job) {
+		return errors.New("cron scheduler not started")
+	}
+	return nil
+}
+
+// Remove takes the entry of name off the scheduler. It reports whether
+// there was one.
+func (r *CronRegistry) Remove(name string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	id, ok := r.entries[name]
+	if ok {
+		r.cron.Remove(id)
+		delete(r.entries, name)
+	}
+	return ok
+}
+
+// RemovePrefix removes every entry whose name starts with prefix and
+// returns how many there were.
+func (r *CronRegistry) RemovePrefix(prefix string) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	removed := 0
+	for name, id := range r.entries {
+		if strings.HasPrefix(name, prefix) {
+			r.cron.Remove(id)
+			delete(r.entries, name)
+			removed++
+		}
+	}
+	return removed
+}
+
+// Next returns when the entry of name runs next, or the zero time if there
+// is no such entry or the scheduler isn't running.
+func (r *CronRegistry) Next(name string) time.Time {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	id, ok := r.entries[name]
+	if !ok {
+		return time.Time{}
+	}
+	return r.cron.Entry(id).Next
+}
+
+// Names returns the names of all entries, sorted.
+func (r *CronRegistry) Names() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	names := make([]string, 0, len(r.entries))
+	for name := range r.entries {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// Location returns the time zone of the scheduler, or the local one when
+// none is attached.
+func (r *CronRegistry) Location() *time.Location {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.cron == nil {
+		return time.Local
+	}
+	return r.cron.Location()
+}
@@ -0,0 +1,81 @@
+package service
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/robfig/cron/v3"
+)
+
+func TestCronRegistry(t *testing.T) {
+	r := &CronRegistry{}
+	noop := cron.FuncJob(func() {})
+	if err := r.SetSpec("report", "@daily", noop); err == nil {
+		t.Fatal("SetSpec without a scheduler must fail")
+	}
+
+	c := cron.New(cron.WithSeconds())
+	r.Attach(c)
+	if err := r.SetSpec("report", "not a cron", noop); err == nil {
+		t.Fatal("invalid expression must be rejected")
+	}
+	if err := r.SetSpec("report", "0 30 8 * * *", noop); err != nil {
+		t.Fatalf("SetSpec: %v", err)
+	}
+	// setting a name again replaces its entry
+	if err := r.SetSpec("report", "0 0 9 * * *", noop); err != nil {
+		t.Fatalf("SetSpec again: %v", err)
+	}
+	for _, name := range []string{"inbound/1/enable", "inbound/1/disable", "inbound/12/enable"} {
+		if err := r.SetSpec(name, "@hourly", noop); err != nil {
+			t.Fatalf("SetSpec %s: %v", name, err)
+		}
+	}
+	if n := len(c.Entries()); n != 4 {
+		t.Fatalf("want 4 entries, got %d", n)
+	}
+
+	if n := r.RemovePrefix("inbound/1/"); n != 2 {
+		t.Fatalf("RemovePrefix removed %d entries, want 2", n)
+	}
+	if !r.Remove("report") || r.Remove("report") {
+		t.Fatal("Remove must report whether the entry existed")
+	}
+	if got := r.Names(); len(got) != 1 || got[0] != "inbound/12/enable" {
+		t.Fatalf("unexpected names %v", got)
+	}
+
+	// attaching a new scheduler takes the entries off the old one
+	r.Attach(cron.New(cron.WithSeconds()))
+	if n := len(c.Entries()); n != 0 {
+		t.Fatalf("entries left on the previous scheduler: %d", n)
+	}
+}
+
+func TestCronRegistryConcurrentUse(t *testing.T) {
+	r := &CronRegistry{}
+	c := cron.New(cron.WithSeconds())
+	c.Start()
+	defer c.Stop()
+	r.Attach(c)
+
+	var wg sync.WaitGroup
+	for i := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			name := fmt.Sprintf("job/%d", i%4)
+			for range 50 {
+				_ = r.SetSpec(name, "@every 1h", cron.FuncJob(func() {}))
+				r.Next(name)
+				r.Remove(name)
+			}
+			_ = r.SetSpec(name, "@every 1h", cron.FuncJob(func() {}))
+		}()
+	}
+	wg.Wait()
+	if n, names := len(c.Entries()), len(r.Names()); n != 4 || names != 4 {
+		t.Fatalf("want 4 entries and names, got %d entries and %d names", n, names)
+	}
+}
@@ -0,0 +1,107 @@
+package service
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/zixu5u/3xv/v3/internal/config"
+	"github.com/zixu5u/3xv/v3/internal/util/common"
+
+	"github.com/xtls/xray-core/common/geodata"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+)
+
+// GeoIPSampleIP is the address /geoinfo looks up to show the database works.
+const GeoIPSampleIP = "8.8.8.8"
+
+// GeoIPInfo describes a GeoIP database file of Xray.
+type GeoIPInfo struct {
+	Path        string
+	ModTime     time.Time // the release date for files fetched by UpdateGeofile
+	Size        int64
+	Codes       int      // country and list codes
+	IPv4        int      // IPv4 CIDRs across all codes
+	IPv6        int      // IPv6 CIDRs across all codes
+	SampleCodes []string // codes whose CIDRs contain the sample address
+}
+
+// GetGeoIPInfo reads the GeoIP file fileName in Xray's bin folder and looks
+// up sample in it. A missing file returns an error matching os.ErrNotExist.
+func (s *ServerService) GetGeoIPInfo(fileName string, sample net.IP) (*GeoIPInfo, error) {
+	if !s.IsValidGeofileName(fileName) || !strings.HasPrefix(fileName, "geoip") {
+		return nil, common.NewErrorf("invalid GeoIP file name: %q", fileName)
+	}
+	path := filepath.Join(config.GetBinFolderPath(), fileName)
+	stat, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	info, err := ParseGeoIPList(data, sample)
+	if err != nil {
+		return nil, common.NewErrorf("%s is not a valid GeoIP file: %v", fileName, err)
+	}
+	info.Path = path
+	info.ModTime = stat.ModTime()
+	info.Size = stat.Size()
+	return info, nil
+}
+
+// ParseGeoIPList counts the codes and CIDRs of a geoip.dat and finds the
+// codes containing sample. Entries are decoded one at a time, so the whole
+// list is never held in memory at once.
+func ParseGeoIPList(data []byte, sample net.IP) (*GeoIPInfo, error) {
+	info := &GeoIPInfo{}
+	if v4 := sample.To4(); v4 != nil {
+		sample = v4
+	}
+	for len(data) > 0 {
+		num, typ, n := protowire.ConsumeTag(data)
+		if n < 0 {
+			return nil, protowire.ParseError(n)
+		}
+		data = data[n:]
+		if num != 1 || typ != protowire.BytesType {
+			n = protowire.ConsumeFieldValue(num, typ, data)
+			if n < 0 {
+				return nil, protowire.ParseError(n)
+			}
+			data = data[n:]
+			continue
+		}
+		raw, n := protowire.ConsumeBytes(data)
+		if n < 0 {
+			return nil, protowire.ParseError(n)
+		}
+		data = data[n:]
+
+		var entry geodata.GeoIP
+		if err := proto.Unmarshal(raw, &entry); err != nil {
+			return nil, err
+		}
+		info.Codes++
+		matched := false
+		for _, cidr := range entry.Cidr {
+			if len(cidr.Ip) == net.IPv4len {
+				info.IPv4++
+			} else {
+				info.IPv6++
+			}
+			if !matched && len(cidr.Ip) == len(sample) {
+				ipNet := net.IPNet{IP: cidr.Ip, Mask: net.CIDRMask(int(cidr.Prefix), len(cidr.Ip)*8)}
+				matched = ipNet.Contains(sample)
+			}
+		}
+		if matched != entry.ReverseMatch && sample != nil {
+			info.SampleCodes = append(info.SampleCodes, entry.Code)
+		}
+	}
+	return info, nil
+}
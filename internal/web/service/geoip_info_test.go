@@ -0,0 +1,41 @@
+package service
+
+import (
+	"net"
+	"reflect"
+	"testing"
+
+	"github.com/xtls/xray-core/common/geodata"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestParseGeoIPList(t *testing.T) {
+	list := &geodata.GeoIPList{Entry: []*geodata.GeoIP{
+		{Code: "US", Cidr: []*geodata.CIDR{
+			{Ip: net.ParseIP("8.8.8.0").To4(), Prefix: 24},
+			{Ip: net.ParseIP("2001:4860::"), Prefix: 32},
+		}},
+		{Code: "GOOGLE", Cidr: []*geodata.CIDR{{Ip: net.ParseIP("8.0.0.0").To4(), Prefix: 8}}},
+		{Code: "PRIVATE", Cidr: []*geodata.CIDR{{Ip: net.ParseIP("10.0.0.0").To4(), Prefix: 8}}},
+		{Code: "NOT-PRIVATE", ReverseMatch: true, Cidr: []*geodata.CIDR{{Ip: net.ParseIP("10.0.0.0").To4(), Prefix: 8}}},
+	}}
+	data, err := proto.Marshal(list)
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+
+	info, err := ParseGeoIPList(data, net.ParseIP(GeoIPSampleIP))
+	if err != nil {
+		t.Fatalf("ParseGeoIPList: %v", err)
+	}
+	if info.Codes != 4 || info.IPv4 != 4 || info.IPv6 != 1 {
+		t.Fatalf("info = %+v", info)
+	}
+	if want := []string{"US", "GOOGLE", "NOT-PRIVATE"}; !reflect.DeepEqual(info.SampleCodes, want) {
+		t.Fatalf("SampleCodes = %v, want %v", info.SampleCodes, want)
+	}
+
+	if _, err := ParseGeoIPList([]byte("not a geoip file"), nil); err == nil {
+		t.Fatal("a corrupt file must fail to parse")
+	}
+}
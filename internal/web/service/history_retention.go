@@ -0,0 +1,58 @@
+package service
+
+import "time"
+
+// HistoryRetentionService removes history samples older than their
+// configured retention, per data type.
+type HistoryRetentionService struct {
+	settingService SettingService
+	onlineHistory  OnlineHistoryService
+	trafficHistory TrafficHistoryService
+	clientUsage    ClientUsageService
+	alertHistory   AlertHistoryService
+}
+
+// HistoryPruneResult is the number of rows removed per data type.
+type HistoryPruneResult struct {
+	OnlineSamples      int64
+	TrafficSnapshots   int64
+	ClientUsageSamples int64
+	AlertEvents        int64
+}
+
+// PruneConfigured prunes every history table with its configured
+// retention.
+func (s *HistoryRetentionService) PruneConfigured(now time.Time) (HistoryPruneResult, error) {
+	onlineDays, err := s.settingService.GetTgOnlineHistoryDays()
+	if err != nil {
+		return HistoryPruneResult{}, err
+	}
+	trafficDays, err := s.settingService.GetTgTrafficHistoryDays()
+	if err != nil {
+		return HistoryPruneResult{}, err
+	}
+	usageDays, err := s.settingService.GetTgClientUsageDays()
+	if err != nil {
+		return HistoryPruneResult{}, err
+	}
+	return s.Prune(onlineDays, trafficDays, usageDays, now)
+}
+
+// Prune keeps the last onlineDays of online samples, the last trafficDays
+// of traffic snapshots and the last usageDays of client usage samples.
+// Alert events are always kept for AlertEventRetention.
+func (s *HistoryRetentionService) Prune(onlineDays, trafficDays, usageDays int, now time.Time) (HistoryPruneResult, error) {
+	var result HistoryPruneResult
+	var err error
+	if result.OnlineSamples, err = s.onlineHistory.Prune(now.AddDate(0, 0, -onlineDays)); err != nil {
+		return result, err
+	}
+	if result.TrafficSnapshots, err = s.trafficHistory.Prune(now.AddDate(0, 0, -trafficDays)); err != nil {
+		return result, err
+	}
+	if result.ClientUsageSamples, err = s.clientUsage.Prune(now.AddDate(0, 0, -usageDays)); err != nil {
+		return result, err
+	}
+	result.AlertEvents, err = s.alertHistory.Prune(now.Add(-AlertEventRetention))
+	return result, err
+}
@@ -10,6 +10,7 @@ import (
 	"sort"
 	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/zixu5u/3xv/v3/internal/database"
 	"github.com/zixu5u/3xv/v3/internal/database/model"
@@ -858,6 +859,49 @@ func (s *InboundService) GetInboundDetail(id int) (*model.Inbound, error) {
 	return inbound, nil
 }
 
+// MaxNoteLength bounds the free-text notes kept on inbounds and clients.
+const MaxNoteLength = 200
+
+// normalizeNote trims a note and enforces MaxNoteLength.
+func normalizeNote(note string) (string, error) {
+	note = strings.TrimSpace(note)
+	if utf8.RuneCountInString(note) > MaxNoteLength {
+		return "", common.NewErrorf("note is longer than %d characters", MaxNoteLength)
+	}
+	return note, nil
+}
+
+// SetInboundNote stores a free-text note on an inbound. Notes are panel-side
+// metadata only, so neither Xray nor remote nodes are touched.
+func (s *InboundService) SetInboundNote(id int, note string) error {
+	note, err := normalizeNote(note)
+	if err != nil {
+		return err
+	}
+	if _, err := s.GetInbound(id); err != nil {
+		return err
+	}
+	db := database.GetDB()
+	return db.Model(model.Inbound{}).Where("id = ?", id).Update("note", note).Error
+}
+
+// SetInboundLimits changes the total traffic limit (bytes, 0 = unlimited)
+// and expiry time (unix ms, 0 = never) of an inbound through the regular
+// update path, so remote nodes and the running Xray are kept in sync.
+func (s *InboundService) SetInboundLimits(id int, total int64, expiryTime int64) (bool, error) {
+	if total < 0 || expiryTime < 0 {
+		return false, common.NewError("traffic limit and expiry time must not be negative")
+	}
+	inbound, err := s.GetInbound(id)
+	if err != nil {
+		return false, err
+	}
+	inbound.Total = total
+	inbound.ExpiryTime = expiryTime
+	_, needRestart, err := s.UpdateInbound(inbound)
+	return needRestart, err
+}
+
 func (s *InboundService) SetInboundEnable(id int, enable bool) (bool, error) {
 	inbound, err := s.GetInbound(id)
 	if err != nil {
@@ -1333,6 +1377,16 @@ func (s *InboundService) GetClientReverseTags() (string, error) {
 	return string(result), nil
 }
 
+// GetInboundByTag returns the inbound with the given Xray tag.
+func (s *InboundService) GetInboundByTag(tag string) (*model.Inbound, error) {
+	db := database.GetDB()
+	inbound := &model.Inbound{}
+	if err := db.Model(model.Inbound{}).Where("tag = ?", tag).First(inbound).Error; err != nil {
+		return nil, err
+	}
+	return inbound, nil
+}
+
 func (s *InboundService) SearchInbounds(query string) ([]*model.Inbound, error) {
 	db := database.GetDB()
 	var inbounds []*model.Inbound
@@ -0,0 +1,108 @@
+package service
+
+import (
+	"github.com/zixu5u/3xv/v3/internal/database"
+	"github.com/zixu5u/3xv/v3/internal/database/model"
+	"github.com/zixu5u/3xv/v3/internal/util/common"
+)
+
+// MaxAlertExpiryDays bounds the expiry warning an inbound may override.
+const MaxAlertExpiryDays = 3650
+
+// AlertThresholds are the margins at which an inbound, and the clients in
+// it, count as near their traffic limit or expiry: the panel's trafficDiff
+// and expireDiff, or the inbound's own overrides of them.
+type AlertThresholds struct {
+	TrafficPercent   int   // near the limit once this % of it is used; 0 when TrafficDiffBytes applies
+	TrafficDiffBytes int64 // near the limit with less than this left
+	ExpireDiffMs     int64 // near expiry within this many milliseconds
+	TrafficOverride  bool  // TrafficPercent comes from the inbound
+	ExpiryOverride   bool  // ExpireDiffMs comes from the inbound
+}
+
+// DefaultAlertThresholds returns the panel-wide thresholds, from the
+// trafficDiff (GB) and expireDiff (days) settings.
+func DefaultAlertThresholds(trafficDiffGB int, expireDiffDays int) AlertThresholds {
+	return AlertThresholds{
+		TrafficDiffBytes: int64(max(trafficDiffGB, 0)) * 1073741824,
+		ExpireDiffMs:     int64(max(expireDiffDays, 0)) * 86400000,
+	}
+}
+
+// ForInbound returns the thresholds of inbound: its overrides where set,
+// the receiver's values otherwise.
+func (d AlertThresholds) ForInbound(inbound *model.Inbound) AlertThresholds {
+	t := d
+	if inbound == nil {
+		return t
+	}
+	if inbound.AlertTrafficPercent != nil {
+		t.TrafficPercent = *inbound.AlertTrafficPercent
+		t.TrafficDiffBytes = 0
+		t.TrafficOverride = true
+	}
+	if inbound.AlertExpiryDays != nil {
+		t.ExpireDiffMs = int64(*inbound.AlertExpiryDays) * 86400000
+		t.ExpiryOverride = true
+	}
+	return t
+}
+
+// NearTrafficLimit reports whether used of a total limit is within the
+// threshold, which includes a limit already used up. An unlimited inbound
+// or client is never near.
+func (t AlertThresholds) NearTrafficLimit(used int64, total int64) bool {
+	if total <= 0 {
+		return false
+	}
+	if t.TrafficPercent > 0 {
+		return used*100 >= total*int64(t.TrafficPercent)
+	}
+	return total-used < t.TrafficDiffBytes
+}
+
+// NearExpiry reports whether expiryTime (unix ms) is within the threshold
+// of nowMs, which includes a date already passed. No expiry and a delayed
+// start, a negative expiryTime, are never near.
+func (t AlertThresholds) NearExpiry(expiryTime int64, nowMs int64) bool {
+	return expiryTime > 0 && expiryTime-nowMs < t.ExpireDiffMs
+}
+
+// InboundAlertThresholds returns the thresholds of every inbound that
+// overrides at least one of d's, by inbound id. Inbounds missing from the
+// map use d.
+func InboundAlertThresholds(d AlertThresholds) (map[int]AlertThresholds, error) {
+	var inbounds []model.Inbound
+	err := database.GetDB().Model(&model.Inbound{}).
+		Select("id, alert_traffic_percent, alert_expiry_days").
+		Where("alert_traffic_percent IS NOT NULL OR alert_expiry_days IS NOT NULL").
+		Find(&inbounds).Error
+	if err != nil {
+		return nil, err
+	}
+	thresholds := make(map[int]AlertThresholds, len(inbounds))
+	for i := range inbounds {
+		thresholds[inbounds[i].Id] = d.ForInbound(&inbounds[i])
+	}
+	return thresholds, nil
+}
+
+// SetInboundAlertThresholds stores the alert overrides of an inbound; nil
+// clears one so the panel default applies again. Like notes they are
+// panel-side metadata, so neither Xray nor remote nodes are touched.
+func (s *InboundService) SetInboundAlertThresholds(id int, trafficPercent *int, expiryDays *int) error {
+	if trafficPercent != nil && (*trafficPercent < 1 || *trafficPercent > 100) {
+		return common.NewErrorf("traffic alert threshold must be 1 to 100 percent, got %d", *trafficPercent)
+	}
+	if expiryDays != nil && (*expiryDays < 0 || *expiryDays > MaxAlertExpiryDays) {
+		return common.NewErrorf("expiry alert threshold must be 0 to %d days, got %d", MaxAlertExpiryDays, *expiryDays)
+	}
+	if _, err := s.GetInbound(id); err != nil {
+		return err
+	}
+	return database.GetDB().Model(&model.Inbound{}).Where("id = ?", id).
+		Updates(map[string]any{
+			"alert_traffic_percent": trafficPercent,
+			"alert_expiry_days":     expiryDays,
+		}).Error
+}
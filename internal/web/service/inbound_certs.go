@@ -0,0 +1,180 @@
+package service
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/zixu5u/3xv/v3/internal/database"
+	"github.com/zixu5u/3xv/v3/internal/database/model"
+	"github.com/zixu5u/3xv/v3/internal/util/common"
+)
+
+// CertSourceInline and CertSourceHandshake tell where an inbound's
+// certificate was read from when it wasn't a file.
+const (
+	CertSourceInline    = "inline"
+	CertSourceHandshake = "handshake"
+)
+
+// InboundCert is the certificate a TLS inbound serves. Err is set instead
+// of Subject and NotAfter when it could be neither read from the inbound's
+// settings nor fetched from the running listener.
+type InboundCert struct {
+	InboundId int
+	Tag       string
+	Remark    string
+	Source    string // the certificate file, CertSourceInline or CertSourceHandshake
+	Subject   string
+	NotAfter  time.Time
+	Err       error
+}
+
+// DaysLeft is how many whole days the certificate stays valid after now,
+// negative once it expired.
+func (c InboundCert) DaysLeft(now time.Time) int {
+	left := c.NotAfter.Sub(now)
+	if left < 0 {
+		return -int((-left + 24*time.Hour - 1) / (24 * time.Hour))
+	}
+	return int(left / (24 * time.Hour))
+}
+
+// tlsCertificateSettings are the certificates of streamSettings.tlsSettings.
+type tlsCertificateSettings struct {
+	TLSSettings struct {
+		Certificates []struct {
+			CertificateFile string   `json:"certificateFile"`
+			Certificate     []string `json:"certificate"`
+			Usage           string   `json:"usage"`
+		} `json:"certificates"`
+	} `json:"tlsSettings"`
+}
+
+// configuredCertificate reads the first serving certificate an inbound's
+// TLS settings name, from its file or inline PEM. ok is false when the
+// settings name none.
+func configuredCertificate(inbound *model.Inbound) (cert *x509.Certificate, source string, ok bool, err error) {
+	var stream tlsCertificateSettings
+	if err := json.Unmarshal([]byte(inbound.StreamSettings), &stream); err != nil {
+		return nil, "", false, nil
+	}
+	for _, c := range stream.TLSSettings.Certificates {
+		if c.Usage != "" && c.Usage != "encipherment" {
+			continue
+		}
+		var data []byte
+		switch {
+		case c.CertificateFile != "":
+			source = c.CertificateFile
+			data, err = os.ReadFile(c.CertificateFile)
+			if err != nil {
+				return nil, source, true, err
+			}
+		case len(c.Certificate) > 0:
+			source = CertSourceInline
+			data = []byte(strings.Join(c.Certificate, "\n"))
+		default:
+			continue
+		}
+		cert, err = parseFirstCertificate(data)
+		return cert, source, true, err
+	}
+	return nil, "", false, nil
+}
+
+// parseFirstCertificate returns the first certificate of a PEM bundle, the
+// leaf in the usual fullchain order.
+func parseFirstCertificate(data []byte) (*x509.Certificate, error) {
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			return nil, common.NewError("no PEM certificate found")
+		}
+		if block.Type == "CERTIFICATE" {
+			return x509.ParseCertificate(block.Bytes)
+		}
+	}
+}
+
+// handshakeCertificate fetches the certificate a local TLS inbound presents
+// by connecting to it, as ProbeInbound does.
+func handshakeCertificate(inbound *model.Inbound, timeout time.Duration) (*x509.Certificate, error) {
+	if inbound.Port <= 0 || strings.HasPrefix(inbound.Listen, "/") || strings.HasPrefix(inbound.Listen, "@") {
+		return nil, common.NewError("inbound has no TCP port")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	address := net.JoinHostPort(probeHost(inbound.Listen), strconv.Itoa(inbound.Port))
+	conn, err := (&tls.Dialer{Config: &tls.Config{
+		InsecureSkipVerify: true,
+		ServerName:         inboundServerName(inbound),
+	}}).DialContext(ctx, "tcp", address)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	peers := conn.(*tls.Conn).ConnectionState().PeerCertificates
+	if len(peers) == 0 {
+		return nil, common.NewError("no certificate presented")
+	}
+	return peers[0], nil
+}
+
+// inboundCert reads the certificate of a TLS inbound, falling back to a
+// handshake with the listener when the settings name none or it can't be
+// read.
+func inboundCert(inbound *model.Inbound, timeout time.Duration) InboundCert {
+	result := InboundCert{InboundId: inbound.Id, Tag: inbound.Tag, Remark: inbound.Remark}
+	cert, source, ok, err := configuredCertificate(inbound)
+	if !ok || err != nil {
+		live, liveErr := handshakeCertificate(inbound, timeout)
+		if liveErr == nil {
+			cert, source, err = live, CertSourceHandshake, nil
+		} else if !ok {
+			err = liveErr
+		}
+	}
+	result.Source = source
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	result.Subject = cert.Subject.CommonName
+	if result.Subject == "" && len(cert.DNSNames) > 0 {
+		result.Subject = cert.DNSNames[0]
+	}
+	result.NotAfter = cert.NotAfter
+	return result
+}
+
+// GetInboundCerts returns the certificates of the enabled TLS inbounds of
+// this panel, ordered by inbound ID. REALITY inbounds borrow their target's
+// certificate and inbounds on nodes keep theirs on the node, so both are
+// left out.
+func (s *InboundService) GetInboundCerts(timeout time.Duration) ([]InboundCert, error) {
+	var inbounds []*model.Inbound
+	err := database.GetDB().Model(&model.Inbound{}).
+		Where("enable = ? AND node_id IS NULL", true).
+		Order("id").
+		Find(&inbounds).Error
+	if err != nil {
+		return nil, err
+	}
+	var certs []InboundCert
+	for _, inbound := range inbounds {
+		if inboundSecurity(inbound) != "tls" {
+			continue
+		}
+		certs = append(certs, inboundCert(inbound, timeout))
+	}
+	return certs, nil
+}
@@ -0,0 +1,115 @@
+package service
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zixu5u/3xv/v3/internal/database"
+	"github.com/zixu5u/3xv/v3/internal/database/model"
+)
+
+// selfSignedPEM returns a PEM certificate for name valid until notAfter.
+func selfSignedPEM(t *testing.T, name string, notAfter time.Time) string {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    notAfter.Add(-90 * 24 * time.Hour),
+		NotAfter:     notAfter,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("create certificate: %v", err)
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}))
+}
+
+func tlsStreamSettings(t *testing.T, certificate map[string]any) string {
+	t.Helper()
+	stream, err := json.Marshal(map[string]any{
+		"network":     "tcp",
+		"security":    "tls",
+		"tlsSettings": map[string]any{"certificates": []any{certificate}},
+	})
+	if err != nil {
+		t.Fatalf("marshal stream settings: %v", err)
+	}
+	return string(stream)
+}
+
+func TestGetInboundCerts(t *testing.T) {
+	setupBulkDB(t)
+	db := database.GetDB()
+
+	notAfter := time.Now().Add(10 * 24 * time.Hour).Truncate(time.Second).UTC()
+	certFile := filepath.Join(t.TempDir(), "fullchain.pem")
+	if err := os.WriteFile(certFile, []byte(selfSignedPEM(t, "file.example.com", notAfter)), 0o600); err != nil {
+		t.Fatalf("write certificate: %v", err)
+	}
+	inline := strings.Split(strings.TrimSpace(selfSignedPEM(t, "inline.example.com", notAfter)), "\n")
+
+	inbounds := []*model.Inbound{
+		{Tag: "file", Enable: true, Port: 44001, Protocol: model.VLESS,
+			StreamSettings: tlsStreamSettings(t, map[string]any{"certificateFile": certFile})},
+		{Tag: "inline", Enable: true, Port: 44002, Protocol: model.Trojan,
+			StreamSettings: tlsStreamSettings(t, map[string]any{"certificate": inline})},
+		{Tag: "missing", Enable: true, Port: 44003, Protocol: model.VLESS,
+			StreamSettings: tlsStreamSettings(t, map[string]any{"certificateFile": filepath.Join(t.TempDir(), "gone.pem")})},
+		{Tag: "plain", Enable: true, Port: 44004, Protocol: model.VMESS,
+			StreamSettings: `{"network":"ws","security":"none"}`},
+		{Tag: "reality", Enable: true, Port: 44005, Protocol: model.VLESS,
+			StreamSettings: `{"network":"tcp","security":"reality"}`},
+	}
+	for _, inbound := range inbounds {
+		if err := db.Create(inbound).Error; err != nil {
+			t.Fatalf("create inbound %s: %v", inbound.Tag, err)
+		}
+	}
+
+	certs, err := (&InboundService{}).GetInboundCerts(time.Second)
+	if err != nil {
+		t.Fatalf("GetInboundCerts: %v", err)
+	}
+	if len(certs) != 3 {
+		t.Fatalf("got %d certificates, want the three TLS inbounds: %+v", len(certs), certs)
+	}
+	if c := certs[0]; c.Tag != "file" || c.Err != nil || c.Source != certFile || c.Subject != "file.example.com" || !c.NotAfter.Equal(notAfter) {
+		t.Errorf("file certificate = %+v", c)
+	}
+	if c := certs[1]; c.Tag != "inline" || c.Err != nil || c.Source != CertSourceInline || c.Subject != "inline.example.com" {
+		t.Errorf("inline certificate = %+v", c)
+	}
+	if c := certs[2]; c.Tag != "missing" || c.Err == nil {
+		t.Errorf("unreadable certificate with nothing listening = %+v, want an error", c)
+	}
+}
+
+func TestInboundCertDaysLeft(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	cases := map[time.Duration]int{
+		36 * time.Hour:  1,
+		23 * time.Hour:  0,
+		-1 * time.Hour:  -1,
+		-49 * time.Hour: -3,
+	}
+	for left, want := range cases {
+		if got := (InboundCert{NotAfter: now.Add(left)}).DaysLeft(now); got != want {
+			t.Errorf("DaysLeft with %v left = %d, want %d", left, got, want)
+		}
+	}
+}
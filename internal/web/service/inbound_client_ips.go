@@ -7,6 +7,7 @@ import (
 
 	"github.com/zixu5u/3xv/v3/internal/database"
 	"github.com/zixu5u/3xv/v3/internal/database/model"
+	"github.com/zixu5u/3xv/v3/internal/xray"
 
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
@@ -221,3 +222,29 @@ func (s *InboundService) ClearClientIps(clientEmail string) error {
 	}
 	return nil
 }
+
+// RecentClientIps returns the IPs recorded for each of emails at or after the
+// unix time since, newest first. The rows are filled from Xray's access log
+// when the core has no online-stats API, so they show where clients connected
+// from lately rather than their live connections. Emails without a recent IP
+// are left out.
+func (s *InboundService) RecentClientIps(emails []string, since int64) (map[string][]xray.OnlineIP, error) {
+	recent := make(map[string][]xray.OnlineIP, len(emails))
+	for start := 0; start < len(emails); start += sqlInChunk {
+		end := min(start+sqlInChunk, len(emails))
+		var rows []model.InboundClientIps
+		if err := database.GetDB().Where("client_email IN ?", emails[start:end]).Find(&rows).Error; err != nil {
+			return nil, err
+		}
+		for _, row := range rows {
+			var entries []clientIpEntry
+			if row.Ips == "" || json.Unmarshal([]byte(row.Ips), &entries) != nil {
+				continue
+			}
+			for _, e := range mergeClientIpEntries(nil, entries, since) {
+				recent[row.ClientEmail] = append(recent[row.ClientEmail], xray.OnlineIP{IP: e.IP, LastSeen: e.Timestamp})
+			}
+		}
+	}
+	return recent, nil
+}
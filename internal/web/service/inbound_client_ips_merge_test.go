@@ -209,3 +209,36 @@ func TestMergeInboundClientIps_SkipsBlankRows(t *testing.T) {
 		t.Fatalf("blank rows should be skipped, but %d row(s) created", count)
 	}
 }
+
+func TestRecentClientIps(t *testing.T) {
+	setupClientIpTestDB(t)
+	db := database.GetDB()
+	now := time.Now().Unix()
+
+	rows := []model.InboundClientIps{
+		{ClientEmail: "a@x", Ips: marshalIps(t,
+			clientIpEntry{IP: "1.1.1.1", Timestamp: now - 600},
+			clientIpEntry{IP: "2.2.2.2", Timestamp: now - 60},
+			clientIpEntry{IP: "3.3.3.3", Timestamp: now - 7200}, // before since -> dropped
+		)},
+		{ClientEmail: "b@x", Ips: marshalIps(t, clientIpEntry{IP: "4.4.4.4", Timestamp: now - 7200})},
+		{ClientEmail: "c@x", Ips: marshalIps(t, clientIpEntry{IP: "5.5.5.5", Timestamp: now})},
+	}
+	for i := range rows {
+		if err := db.Create(&rows[i]).Error; err != nil {
+			t.Fatalf("seed: %v", err)
+		}
+	}
+
+	recent, err := (&InboundService{}).RecentClientIps([]string{"a@x", "b@x", "missing@x"}, now-1800)
+	if err != nil {
+		t.Fatalf("RecentClientIps: %v", err)
+	}
+	if len(recent) != 1 {
+		t.Fatalf("want only a@x, got %v", recent)
+	}
+	ips := recent["a@x"]
+	if len(ips) != 2 || ips[0].IP != "2.2.2.2" || ips[1].IP != "1.1.1.1" || ips[0].LastSeen != now-60 {
+		t.Fatalf("a@x ips = %+v, want 2.2.2.2 then 1.1.1.1", ips)
+	}
+}
@@ -2,6 +2,7 @@ package service
 
 import (
 	"path/filepath"
+	"reflect"
 	"testing"
 	"time"
 
@@ -157,3 +158,95 @@ func TestAdjustTraffics_DelayedStartConvertsDespiteStaleInboundId(t *testing.T)
 		t.Errorf("inbound settings expiry not converted: %#v", cs)
 	}
 }
+
+func TestSearchClientTraffics(t *testing.T) {
+	dbDir := t.TempDir()
+	t.Setenv("XUI_DB_FOLDER", dbDir)
+	if err := database.InitDB(filepath.Join(dbDir, "x-ui.db")); err != nil {
+		t.Fatalf("InitDB: %v", err)
+	}
+	t.Cleanup(func() { _ = database.CloseDB() })
+
+	db := database.GetDB()
+	for _, email := range []string{"bob-phone", "alice-laptop", "alice-phone", "carol"} {
+		if err := db.Create(&xray.ClientTraffic{InboundId: 1, Email: email, Enable: true}).Error; err != nil {
+			t.Fatalf("create client_traffics %s: %v", email, err)
+		}
+	}
+
+	svc := InboundService{}
+	got, err := svc.SearchClientTraffics("phone", 10)
+	if err != nil {
+		t.Fatalf("SearchClientTraffics: %v", err)
+	}
+	if len(got) != 2 || got[0].Email != "alice-phone" || got[1].Email != "bob-phone" {
+		t.Fatalf("unexpected matches %+v", got)
+	}
+	if got, _ := svc.SearchClientTraffics("alice", 1); len(got) != 1 || got[0].Email != "alice-laptop" {
+		t.Fatalf("limit not applied: %+v", got)
+	}
+	if got, _ := svc.SearchClientTraffics("dave", 10); len(got) != 0 {
+		t.Fatalf("unexpected matches %+v", got)
+	}
+}
+
+func TestGetInboundPoolUsage(t *testing.T) {
+	dbDir := t.TempDir()
+	t.Setenv("XUI_DB_FOLDER", dbDir)
+	if err := database.InitDB(filepath.Join(dbDir, "x-ui.db")); err != nil {
+		t.Fatalf("InitDB: %v", err)
+	}
+	t.Cleanup(func() { _ = database.CloseDB() })
+
+	db := database.GetDB()
+	inbound := &model.Inbound{
+		Tag:      "pool",
+		Port:     2001,
+		Protocol: model.VLESS,
+		Up:       300,
+		Down:     500,
+		Total:    1000,
+		Settings: `{"clients":[{"email":"small","enable":true},{"email":"big","enable":true},{"email":"idle","enable":false}]}`,
+	}
+	if err := db.Create(inbound).Error; err != nil {
+		t.Fatalf("create inbound: %v", err)
+	}
+	for _, traffic := range []xray.ClientTraffic{
+		{InboundId: inbound.Id, Email: "small", Enable: true, Up: 100, Down: 100},
+		{InboundId: inbound.Id, Email: "big", Enable: true, Up: 200, Down: 400},
+		{InboundId: inbound.Id + 1, Email: "elsewhere", Enable: true, Up: 9000},
+	} {
+		if err := db.Create(&traffic).Error; err != nil {
+			t.Fatalf("create client_traffics %s: %v", traffic.Email, err)
+		}
+	}
+
+	svc := InboundService{}
+	usage, err := svc.GetInboundPoolUsage(inbound.Id)
+	if err != nil {
+		t.Fatalf("GetInboundPoolUsage: %v", err)
+	}
+	if usage.Limit != 1000 || usage.Used != 800 || usage.Remaining != 200 {
+		t.Fatalf("unexpected totals %+v", usage)
+	}
+	want := []ClientPoolShare{
+		{Email: "big", Enable: true, Used: 600, Percent: 75},
+		{Email: "small", Enable: true, Used: 200, Percent: 25},
+		{Email: "idle", Enable: false, Used: 0, Percent: 0},
+	}
+	if !reflect.DeepEqual(usage.Clients, want) {
+		t.Fatalf("clients = %+v, want %+v", usage.Clients, want)
+	}
+
+	// after the inbound alone is reset, shares are of the clients' total
+	if err := db.Model(inbound).Updates(map[string]any{"up": 0, "down": 0}).Error; err != nil {
+		t.Fatalf("reset inbound: %v", err)
+	}
+	usage, err = svc.GetInboundPoolUsage(inbound.Id)
+	if err != nil {
+		t.Fatalf("GetInboundPoolUsage: %v", err)
+	}
+	if usage.Remaining != 1000 || usage.Clients[0].Percent != 75 {
+		t.Fatalf("unexpected usage after reset %+v", usage)
+	}
+}
@@ -156,6 +156,13 @@ func (s *InboundService) disableInvalidClients(tx *gorm.DB) (bool, int64, []int,
 			Updates(map[string]any{"enable": false, "updated_at": now}).Error; err != nil {
 			logger.Warning("disableInvalidClients update clients.enable:", err)
 		}
+		events := make([]model.AlertEvent, 0, len(depletedEmails))
+		for _, email := range depletedEmails {
+			events = append(events, model.AlertEvent{Type: AlertEventDisabled, Email: email, FiredAt: now})
+		}
+		if err := recordAlertEvents(tx, events); err != nil {
+			logger.Warning("disableInvalidClients record alert events:", err)
+		}
 	}
 
 	disabledNodeIDs := make(map[int]struct{})
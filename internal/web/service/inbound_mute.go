@@ -0,0 +1,92 @@
+package service
+
+import (
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/zixu5u/3xv/v3/internal/util/common"
+)
+
+// mutedInboundsMutex serializes read-modify-write updates of the mute list.
+var mutedInboundsMutex sync.Mutex
+
+// InboundMuteService maintains the list of inbounds whose traffic, expiry
+// and threshold alerts are suppressed. Muting only affects notifications;
+// the inbound and its clients keep working as usual.
+type InboundMuteService struct {
+	settingService SettingService
+}
+
+// parseMutedInbounds splits the stored comma-separated tag list.
+func parseMutedInbounds(raw string) []string {
+	list := make([]string, 0)
+	for item := range strings.SplitSeq(raw, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+// GetMutedInbounds returns the tags of the muted inbounds.
+func (s *InboundMuteService) GetMutedInbounds() ([]string, error) {
+	raw, err := s.settingService.GetTgMutedInbounds()
+	if err != nil {
+		return nil, err
+	}
+	return parseMutedInbounds(raw), nil
+}
+
+// MuteInbound adds tag to the mute list. It returns whether the tag was
+// newly added.
+func (s *InboundMuteService) MuteInbound(tag string) (bool, error) {
+	tag = strings.TrimSpace(tag)
+	if tag == "" || strings.Contains(tag, ",") {
+		return false, common.NewError("invalid inbound tag:", tag)
+	}
+	mutedInboundsMutex.Lock()
+	defer mutedInboundsMutex.Unlock()
+
+	list, err := s.GetMutedInbounds()
+	if err != nil {
+		return false, err
+	}
+	if slices.Contains(list, tag) {
+		return false, nil
+	}
+	return true, s.settingService.SetTgMutedInbounds(strings.Join(append(list, tag), ","))
+}
+
+// UnmuteInbound removes tag from the mute list. It returns whether the tag
+// was present.
+func (s *InboundMuteService) UnmuteInbound(tag string) (bool, error) {
+	tag = strings.TrimSpace(tag)
+	mutedInboundsMutex.Lock()
+	defer mutedInboundsMutex.Unlock()
+
+	list, err := s.GetMutedInbounds()
+	if err != nil {
+		return false, err
+	}
+	idx := slices.Index(list, tag)
+	if idx < 0 {
+		return false, nil
+	}
+	return true, s.settingService.SetTgMutedInbounds(strings.Join(slices.Delete(list, idx, idx+1), ","))
+}
+
+// ClearMutedInbounds unmutes every inbound. It returns how many were muted.
+func (s *InboundMuteService) ClearMutedInbounds() (int, error) {
+	mutedInboundsMutex.Lock()
+	defer mutedInboundsMutex.Unlock()
+
+	list, err := s.GetMutedInbounds()
+	if err != nil {
+		return 0, err
+	}
+	if len(list) == 0 {
+		return 0, nil
+	}
+	return len(list), s.settingService.SetTgMutedInbounds("")
+}
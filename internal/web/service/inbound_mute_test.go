@@ -0,0 +1,58 @@
+package service
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestInboundMuteRoundTrip(t *testing.T) {
+	setupSettingTestDB(t)
+	s := &InboundMuteService{}
+
+	if added, err := s.MuteInbound("inbound-443"); err != nil || !added {
+		t.Fatalf("MuteInbound = %v, %v; want true, nil", added, err)
+	}
+	if added, err := s.MuteInbound(" inbound-443 "); err != nil || added {
+		t.Fatalf("muting twice = %v, %v; want false, nil", added, err)
+	}
+	if _, err := s.MuteInbound("inbound-8443"); err != nil {
+		t.Fatal(err)
+	}
+	list, err := s.GetMutedInbounds()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(list, []string{"inbound-443", "inbound-8443"}) {
+		t.Fatalf("unexpected mute list %v", list)
+	}
+
+	if removed, err := s.UnmuteInbound("inbound-443"); err != nil || !removed {
+		t.Fatalf("UnmuteInbound = %v, %v; want true, nil", removed, err)
+	}
+	if removed, err := s.UnmuteInbound("inbound-443"); err != nil || removed {
+		t.Fatalf("unmuting twice = %v, %v; want false, nil", removed, err)
+	}
+	if list, _ := s.GetMutedInbounds(); !slices.Equal(list, []string{"inbound-8443"}) {
+		t.Fatalf("unexpected mute list after unmute %v", list)
+	}
+
+	if n, err := s.ClearMutedInbounds(); err != nil || n != 1 {
+		t.Fatalf("ClearMutedInbounds = %d, %v; want 1, nil", n, err)
+	}
+	if n, err := s.ClearMutedInbounds(); err != nil || n != 0 {
+		t.Fatalf("clearing twice = %d, %v; want 0, nil", n, err)
+	}
+	if list, _ := s.GetMutedInbounds(); len(list) != 0 {
+		t.Fatalf("unexpected mute list after clear %v", list)
+	}
+}
+
+func TestMuteInboundRejectsInvalidTags(t *testing.T) {
+	setupSettingTestDB(t)
+	s := &InboundMuteService{}
+	for _, tag := range []string{"", "  ", "a,b"} {
+		if _, err := s.MuteInbound(tag); err == nil {
+			t.Fatalf("MuteInbound(%q) must fail", tag)
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package service
+
+import (
+	"slices"
+
+	"github.com/zixu5u/3xv/v3/internal/database"
+	"github.com/zixu5u/3xv/v3/internal/database/model"
+)
+
+// ListeningPort is a port Xray listens on for an enabled inbound of this
+// panel.
+type ListeningPort struct {
+	Port      int
+	Listen    string // listen address; empty listens on every address
+	Transport string // "tcp", "udp" or "tcpudp"
+	Protocol  model.Protocol
+	Tag       string
+	Remark    string
+}
+
+// listeningPorts returns the ports inbounds make Xray listen on, by port.
+// Disabled inbounds, inbounds hosted on a node and inbounds without a port,
+// such as those on a unix socket, are left out.
+func listeningPorts(inbounds []*model.Inbound) []ListeningPort {
+	var ports []ListeningPort
+	for _, inbound := range inbounds {
+		if !inbound.Enable || inbound.NodeID != nil || inbound.OriginNodeGuid != "" || inbound.Port <= 0 {
+			continue
+		}
+		ports = append(ports, ListeningPort{
+			Port:      inbound.Port,
+			Listen:    inbound.Listen,
+			Transport: transportTagSuffix(inboundTransports(inbound.Protocol, inbound.StreamSettings, inbound.Settings)),
+			Protocol:  inbound.Protocol,
+			Tag:       inbound.Tag,
+			Remark:    inbound.Remark,
+		})
+	}
+	slices.SortStableFunc(ports, func(a, b ListeningPort) int { return a.Port - b.Port })
+	return ports
+}
+
+// GetListeningPorts returns the ports Xray listens on for the enabled
+// inbounds of this panel, by port. Clients aren't loaded.
+func (s *InboundService) GetListeningPorts() ([]ListeningPort, error) {
+	var inbounds []*model.Inbound
+	if err := database.GetDB().Model(model.Inbound{}).Where("enable = ?", true).Find(&inbounds).Error; err != nil {
+		return nil, err
+	}
+	return listeningPorts(inbounds), nil
+}
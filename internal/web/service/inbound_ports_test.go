@@ -0,0 +1,27 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/zixu5u/3xv/v3/internal/database/model"
+)
+
+func TestListeningPorts(t *testing.T) {
+	nodeID := 1
+	ports := listeningPorts([]*model.Inbound{
+		{Enable: true, Port: 8443, Protocol: model.VLESS, StreamSettings: `{"network":"kcp"}`, Tag: "in-8443-udp"},
+		{Enable: true, Port: 443, Protocol: model.Shadowsocks, Settings: `{"network":"tcp,udp"}`, Listen: "127.0.0.1", Tag: "in-443"},
+		{Enable: false, Port: 80, Protocol: model.VLESS, Tag: "disabled"},
+		{Enable: true, Port: 2053, Protocol: model.VLESS, NodeID: &nodeID, Tag: "on-node"},
+		{Enable: true, Port: 0, Protocol: model.VLESS, Listen: "/run/xray.sock", Tag: "socket"},
+	})
+	if len(ports) != 2 {
+		t.Fatalf("listeningPorts = %+v, want the two enabled local inbounds", ports)
+	}
+	if ports[0].Tag != "in-443" || ports[0].Transport != "tcpudp" || ports[0].Listen != "127.0.0.1" {
+		t.Errorf("first port = %+v", ports[0])
+	}
+	if ports[1].Tag != "in-8443-udp" || ports[1].Transport != "udp" {
+		t.Errorf("second port = %+v", ports[1])
+	}
+}
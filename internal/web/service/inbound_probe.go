@@ -0,0 +1,139 @@
+package service
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"net"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/zixu5u/3xv/v3/internal/database/model"
+	"github.com/zixu5u/3xv/v3/internal/util/common"
+)
+
+// Probe failure kinds reported by ProbeInbound.
+const (
+	ProbeRefused     = "refused"
+	ProbeTimeout     = "timeout"
+	ProbeTLS         = "tls"
+	ProbeUnreachable = "unreachable"
+)
+
+// InboundProbe is the outcome of a reachability test against an inbound.
+type InboundProbe struct {
+	Tag       string
+	Address   string
+	TLS       bool          // whether a TLS handshake was attempted
+	Connect   time.Duration // TCP connect latency
+	Handshake time.Duration // TLS handshake latency, zero without TLS
+	Failure   string        // one of the Probe* kinds, empty on success
+	Err       error
+}
+
+// ProbeInbound connects to a local inbound's TCP port from the server itself
+// and, for TLS inbounds, completes a TLS handshake. Certificates are not
+// verified: the point is that the listener answers, not that the chain is
+// trusted from localhost. REALITY inbounds only get the TCP connect, since a
+// plain TLS handshake is forwarded to their target rather than answered.
+func (s *InboundService) ProbeInbound(tag string, timeout time.Duration) (*InboundProbe, error) {
+	inbound, err := s.GetInboundByTag(tag)
+	if err != nil {
+		return nil, err
+	}
+	if inbound.NodeID != nil {
+		return nil, common.NewError("inbound is hosted on a remote node:", tag)
+	}
+	if inboundTransports(inbound.Protocol, inbound.StreamSettings, inbound.Settings)&transportTCP == 0 {
+		return nil, common.NewError("inbound does not listen on TCP:", tag)
+	}
+	if inbound.Port <= 0 || strings.HasPrefix(inbound.Listen, "/") || strings.HasPrefix(inbound.Listen, "@") {
+		return nil, common.NewError("inbound has no TCP port:", tag)
+	}
+
+	probe := &InboundProbe{
+		Tag:     tag,
+		Address: net.JoinHostPort(probeHost(inbound.Listen), strconv.Itoa(inbound.Port)),
+		TLS:     inboundSecurity(inbound) == "tls",
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	start := time.Now()
+	conn, err := (&net.Dialer{}).DialContext(ctx, "tcp", probe.Address)
+	probe.Connect = time.Since(start)
+	if err != nil {
+		probe.Failure, probe.Err = classifyProbeError(err), err
+		return probe, nil
+	}
+	defer conn.Close()
+
+	if probe.TLS {
+		tlsConn := tls.Client(conn, &tls.Config{
+			InsecureSkipVerify: true,
+			ServerName:         inboundServerName(inbound),
+		})
+		start = time.Now()
+		err = tlsConn.HandshakeContext(ctx)
+		probe.Handshake = time.Since(start)
+		if err != nil {
+			probe.Failure, probe.Err = ProbeTLS, err
+			if errors.Is(err, context.DeadlineExceeded) || isTimeout(err) {
+				probe.Failure = ProbeTimeout
+			}
+		}
+	}
+	return probe, nil
+}
+
+// probeHost maps an inbound's listen address to one the panel can dial.
+// Wildcard and empty listens are reached over loopback.
+func probeHost(listen string) string {
+	if ip := net.ParseIP(listen); listen == "" || (ip != nil && ip.IsUnspecified()) {
+		return "127.0.0.1"
+	}
+	return listen
+}
+
+// inboundSecurity returns streamSettings.security ("tls", "reality", ...).
+func inboundSecurity(inbound *model.Inbound) string {
+	var stream struct {
+		Security string `json:"security"`
+	}
+	if json.Unmarshal([]byte(inbound.StreamSettings), &stream) != nil {
+		return ""
+	}
+	return stream.Security
+}
+
+// inboundServerName returns the SNI configured for a TLS inbound, if any.
+func inboundServerName(inbound *model.Inbound) string {
+	var stream struct {
+		TLSSettings struct {
+			ServerName string `json:"serverName"`
+		} `json:"tlsSettings"`
+	}
+	if json.Unmarshal([]byte(inbound.StreamSettings), &stream) != nil {
+		return ""
+	}
+	return stream.TLSSettings.ServerName
+}
+
+func classifyProbeError(err error) string {
+	switch {
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return ProbeRefused
+	case errors.Is(err, context.DeadlineExceeded) || isTimeout(err):
+		return ProbeTimeout
+	default:
+		return ProbeUnreachable
+	}
+}
+
+func isTimeout(err error) bool {
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
@@ -0,0 +1,53 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"syscall"
+	"testing"
+
+	"github.com/zixu5u/3xv/v3/internal/database/model"
+)
+
+func TestProbeHost(t *testing.T) {
+	cases := map[string]string{
+		"":            "127.0.0.1",
+		"0.0.0.0":     "127.0.0.1",
+		"::":          "127.0.0.1",
+		"10.0.0.5":    "10.0.0.5",
+		"example.com": "example.com",
+	}
+	for listen, want := range cases {
+		if got := probeHost(listen); got != want {
+			t.Fatalf("probeHost(%q) = %q, want %q", listen, got, want)
+		}
+	}
+}
+
+func TestInboundSecurityAndServerName(t *testing.T) {
+	ib := &model.Inbound{StreamSettings: `{"network":"tcp","security":"tls","tlsSettings":{"serverName":"vpn.example.com"}}`}
+	if got := inboundSecurity(ib); got != "tls" {
+		t.Fatalf("expected tls security, got %q", got)
+	}
+	if got := inboundServerName(ib); got != "vpn.example.com" {
+		t.Fatalf("expected SNI vpn.example.com, got %q", got)
+	}
+	if got := inboundSecurity(&model.Inbound{StreamSettings: "not json"}); got != "" {
+		t.Fatalf("unparsable stream settings must give no security, got %q", got)
+	}
+}
+
+func TestClassifyProbeError(t *testing.T) {
+	refused := &net.OpError{Op: "dial", Err: syscall.ECONNREFUSED}
+	if got := classifyProbeError(refused); got != ProbeRefused {
+		t.Fatalf("expected refused, got %q", got)
+	}
+	if got := classifyProbeError(fmt.Errorf("dial: %w", context.DeadlineExceeded)); got != ProbeTimeout {
+		t.Fatalf("expected timeout, got %q", got)
+	}
+	if got := classifyProbeError(errors.New("no route to host")); got != ProbeUnreachable {
+		t.Fatalf("expected unreachable, got %q", got)
+	}
+}
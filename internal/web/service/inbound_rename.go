@@ -0,0 +1,255 @@
+package service
+
+import (
+	"encoding/json"
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/zixu5u/3xv/v3/internal/database"
+	"github.com/zixu5u/3xv/v3/internal/database/model"
+	"github.com/zixu5u/3xv/v3/internal/logger"
+	"github.com/zixu5u/3xv/v3/internal/util/common"
+
+	"gorm.io/gorm"
+)
+
+// maxInboundTagLength caps tags set through RenameInboundTag.
+const maxInboundTagLength = 64
+
+// InboundRename reports what RenameInboundTag changed. Traffic counters are
+// keyed by inbound id and carry over on their own; the counts here are the
+// references that named the old tag.
+type InboundRename struct {
+	OldTag       string
+	NewTag       string
+	RoutingRules int      // routing rules of the Xray template whose inboundTag was updated
+	Nodes        int      // nodes whose selected inbound list was updated
+	Settings     []string // tag list settings that named the old tag, such as tgMutedInbounds
+	// Warnings lists references that couldn't be migrated and need a look.
+	Warnings []string
+}
+
+// validateInboundTag checks a tag typed by an admin. Commas are refused
+// because tag lists such as the mute list are comma-separated.
+func validateInboundTag(tag string) error {
+	if tag == "" {
+		return common.NewError("empty inbound tag")
+	}
+	if len(tag) > maxInboundTagLength {
+		return common.NewError("inbound tag is longer than", maxInboundTagLength, "characters")
+	}
+	if strings.ContainsFunc(tag, func(r rune) bool { return unicode.IsSpace(r) || r == ',' || !unicode.IsPrint(r) }) {
+		return common.NewError("inbound tag must not contain spaces or commas:", tag)
+	}
+	if tag == "api" || tag == PanelEgressInboundTag {
+		return common.NewError("inbound tag is reserved:", tag)
+	}
+	return nil
+}
+
+// renameRuleInboundTag replaces oldTag with newTag in the inboundTag lists
+// of the template's routing rules. It returns the updated template and how
+// many rules changed; the template is returned as is when none did.
+func renameRuleInboundTag(template string, oldTag string, newTag string) (string, int, error) {
+	var cfg map[string]any
+	if err := json.Unmarshal([]byte(template), &cfg); err != nil {
+		return template, 0, err
+	}
+	routing, _ := cfg["routing"].(map[string]any)
+	rules, _ := routing["rules"].([]any)
+	changed := 0
+	for _, r := range rules {
+		rule, ok := r.(map[string]any)
+		if !ok {
+			continue
+		}
+		switch tags := rule["inboundTag"].(type) {
+		case []any:
+			hit := false
+			for i, tag := range tags {
+				if tag == oldTag {
+					tags[i] = newTag
+					hit = true
+				}
+			}
+			if hit {
+				changed++
+			}
+		case string:
+			if tags == oldTag {
+				rule["inboundTag"] = newTag
+				changed++
+			}
+		}
+	}
+	if changed == 0 {
+		return template, 0, nil
+	}
+	out, err := json.MarshalIndent(cfg, "", "  ")
+	if err != nil {
+		return template, 0, err
+	}
+	return string(out), changed, nil
+}
+
+// renameInList replaces oldTag with newTag in a copy of tags, dropping it
+// instead when newTag is listed already. It reports whether oldTag was there.
+func renameInList(tags []string, oldTag string, newTag string) ([]string, bool) {
+	idx := slices.Index(tags, oldTag)
+	if idx < 0 {
+		return tags, false
+	}
+	tags = slices.Clone(tags)
+	if slices.Contains(tags, newTag) {
+		return slices.Delete(tags, idx, idx+1), true
+	}
+	tags[idx] = newTag
+	return tags, true
+}
+
+// RenameInboundTag gives an inbound a new, unique tag and moves every
+// reference to the old one along in the same transaction: routing rules of
+// the Xray template, the inbound lists of nodes and the tag list settings.
+// Xray has to be restarted afterwards to use the new tag. Traffic the
+// running Xray counted under the old tag should be collected before the
+// rename, since its counters are only matched to inbounds by tag.
+func (s *InboundService) RenameInboundTag(id int, newTag string) (*InboundRename, error) {
+	newTag = strings.TrimSpace(newTag)
+	if err := validateInboundTag(newTag); err != nil {
+		return nil, err
+	}
+	inbound, err := s.GetInbound(id)
+	if err != nil {
+		return nil, err
+	}
+	if inbound.Tag == newTag {
+		return nil, common.NewError("inbound already has tag", newTag)
+	}
+	taken, err := s.tagExists(newTag, id)
+	if err != nil {
+		return nil, err
+	}
+	if taken {
+		return nil, common.NewError("inbound tag is already in use:", newTag)
+	}
+
+	rename := &InboundRename{OldTag: inbound.Tag, NewTag: newTag}
+	err = database.GetDB().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&model.Inbound{}).Where("id = ?", id).Update("tag", newTag).Error; err != nil {
+			return err
+		}
+		if err := renameTemplateInboundTag(tx, rename); err != nil {
+			return err
+		}
+		if err := renameNodeInboundTag(tx, rename); err != nil {
+			return err
+		}
+		for _, key := range tagListSettings {
+			if err := renameSettingInboundTag(tx, key, rename); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if inbound.NodeID != nil {
+		if err := (&NodeService{}).MarkNodeDirty(*inbound.NodeID); err != nil {
+			logger.Warning("mark node dirty failed:", err)
+			rename.Warnings = append(rename.Warnings, "node sync: "+err.Error())
+		}
+	}
+	logger.Infof("Inbound %d renamed from %s to %s: %d routing rules, %d nodes, settings %v",
+		id, rename.OldTag, rename.NewTag, rename.RoutingRules, rename.Nodes, rename.Settings)
+	return rename, nil
+}
+
+// settingRow reads a stored setting inside tx. found is false when the
+// setting was never saved and still has its default.
+func settingRow(tx *gorm.DB, key string) (setting model.Setting, found bool, err error) {
+	err = tx.Model(&model.Setting{}).Where("key = ?", key).First(&setting).Error
+	if database.IsNotFound(err) {
+		return setting, false, nil
+	}
+	return setting, err == nil, err
+}
+
+func renameTemplateInboundTag(tx *gorm.DB, rename *InboundRename) error {
+	setting, found, err := settingRow(tx, "xrayTemplateConfig")
+	if err != nil || !found {
+		// the built-in template only refers to the api inbound
+		return err
+	}
+	template := UnwrapXrayTemplateConfig(setting.Value)
+	updated, changed, err := renameRuleInboundTag(template, rename.OldTag, rename.NewTag)
+	if err != nil {
+		rename.Warnings = append(rename.Warnings, "xray template: "+err.Error())
+		return nil
+	}
+	if changed == 0 {
+		return nil
+	}
+	rename.RoutingRules = changed
+	return tx.Model(&model.Setting{}).Where("id = ?", setting.Id).Update("value", updated).Error
+}
+
+func renameNodeInboundTag(tx *gorm.DB, rename *InboundRename) error {
+	var nodes []model.Node
+	if err := tx.Find(&nodes).Error; err != nil {
+		return err
+	}
+	for _, node := range nodes {
+		tags, ok := renameInList(node.InboundTags, rename.OldTag, rename.NewTag)
+		if !ok {
+			continue
+		}
+		buf, err := json.Marshal(tags)
+		if err != nil {
+			return err
+		}
+		if err := tx.Model(&model.Node{}).Where("id = ?", node.Id).Update("inbound_tags", string(buf)).Error; err != nil {
+			return err
+		}
+		rename.Nodes++
+	}
+	return nil
+}
+
+// tagListSettings are the settings holding comma-separated inbound tags.
+var tagListSettings = []string{"tgMutedInbounds", "ldapInboundTags"}
+
+func renameSettingInboundTag(tx *gorm.DB, key string, rename *InboundRename) error {
+	if key == "tgMutedInbounds" {
+		mutedInboundsMutex.Lock()
+		defer mutedInboundsMutex.Unlock()
+	}
+	setting, found, err := settingRow(tx, key)
+	if err != nil || !found {
+		return err
+	}
+	list, ok := renameInList(parseMutedInbounds(setting.Value), rename.OldTag, rename.NewTag)
+	if !ok {
+		return nil
+	}
+	rename.Settings = append(rename.Settings, key)
+	return tx.Model(&model.Setting{}).Where("id = ?", setting.Id).Update("value", strings.Join(list, ",")).Error
+}
+
+// SetInboundRemark changes an inbound's remark. The remark is only a label
+// in the panel, the bot and share links, so Xray needs no restart.
+func (s *InboundService) SetInboundRemark(id int, remark string) (string, error) {
+	remark = strings.TrimSpace(remark)
+	if remark == "" {
+		return "", common.NewError("empty inbound remark")
+	}
+	inbound, err := s.GetInbound(id)
+	if err != nil {
+		return "", err
+	}
+	if err := database.GetDB().Model(&model.Inbound{}).Where("id = ?", id).Update("remark", remark).Error; err != nil {
+		return "", err
+	}
+	return inbound.Remark, nil
+}
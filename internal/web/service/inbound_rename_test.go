@@ -0,0 +1,127 @@
+package service
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/zixu5u/3xv/v3/internal/database"
+	"github.com/zixu5u/3xv/v3/internal/database/model"
+)
+
+func TestRenameRuleInboundTag(t *testing.T) {
+	template := `{"routing":{"rules":[
+		{"type":"field","inboundTag":["api"],"outboundTag":"api"},
+		{"type":"field","inboundTag":["in-443","in-8443"],"outboundTag":"warp"},
+		{"type":"field","inboundTag":"in-443","outboundTag":"direct"}
+	]}}`
+	updated, changed, err := renameRuleInboundTag(template, "in-443", "office")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if changed != 2 {
+		t.Fatalf("changed = %d, want 2", changed)
+	}
+	if strings.Contains(updated, `"in-443"`) || !strings.Contains(updated, `"office"`) || !strings.Contains(updated, `"in-8443"`) {
+		t.Fatalf("unexpected template %s", updated)
+	}
+
+	if same, changed, err := renameRuleInboundTag(template, "missing", "office"); err != nil || changed != 0 || same != template {
+		t.Fatalf("renaming an unused tag = %d, %v; want the template untouched", changed, err)
+	}
+	if _, _, err := renameRuleInboundTag("{broken", "in-443", "office"); err == nil {
+		t.Fatal("a broken template must fail")
+	}
+}
+
+func TestRenameInList(t *testing.T) {
+	tags := []string{"a", "b", "c"}
+	if got, ok := renameInList(tags, "b", "x"); !ok || !slices.Equal(got, []string{"a", "x", "c"}) {
+		t.Fatalf("renameInList = %v, %v", got, ok)
+	}
+	if got, ok := renameInList(tags, "b", "c"); !ok || !slices.Equal(got, []string{"a", "c"}) {
+		t.Fatalf("renaming onto a listed tag = %v, %v; want the old one dropped", got, ok)
+	}
+	if _, ok := renameInList(tags, "z", "x"); ok {
+		t.Fatal("an unlisted tag must report false")
+	}
+	if !slices.Equal(tags, []string{"a", "b", "c"}) {
+		t.Fatalf("input list was modified: %v", tags)
+	}
+}
+
+func TestRenameInboundTag(t *testing.T) {
+	setupConflictDB(t)
+	db := database.GetDB()
+	seedInboundConflict(t, "in-443", "0.0.0.0", 443, model.VLESS, `{"network":"tcp"}`, `{"clients":[]}`)
+	seedInboundConflict(t, "in-8443", "0.0.0.0", 8443, model.VLESS, `{"network":"tcp"}`, `{"clients":[]}`)
+	var inbound model.Inbound
+	if err := db.Where("tag = ?", "in-443").First(&inbound).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	settings := &SettingService{}
+	if err := settings.saveSetting("xrayTemplateConfig", `{"routing":{"rules":[{"inboundTag":["in-443"],"outboundTag":"direct"}]}}`); err != nil {
+		t.Fatal(err)
+	}
+	if err := settings.saveSetting("tgMutedInbounds", "in-8443,in-443"); err != nil {
+		t.Fatal(err)
+	}
+	node := &model.Node{
+		Name: "rename-sel", Address: "127.0.0.1", Port: 2096, ApiToken: "tok",
+		InboundSyncMode: "selected", InboundTags: []string{"in-443", "other"},
+	}
+	if err := db.Create(node).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	svc := &InboundService{}
+	for _, tag := range []string{"", "has space", "a,b", "api", PanelEgressInboundTag, "in-8443", "in-443"} {
+		if _, err := svc.RenameInboundTag(inbound.Id, tag); err == nil {
+			t.Fatalf("RenameInboundTag(%q) must fail", tag)
+		}
+	}
+
+	rename, err := svc.RenameInboundTag(inbound.Id, " office ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rename.OldTag != "in-443" || rename.NewTag != "office" || rename.RoutingRules != 1 || rename.Nodes != 1 ||
+		!slices.Equal(rename.Settings, []string{"tgMutedInbounds"}) || len(rename.Warnings) != 0 {
+		t.Fatalf("unexpected rename %+v", rename)
+	}
+
+	var reloaded model.Inbound
+	if err := db.First(&reloaded, inbound.Id).Error; err != nil || reloaded.Tag != "office" {
+		t.Fatalf("inbound tag = %q, %v; want office", reloaded.Tag, err)
+	}
+	if template, _ := settings.GetXrayConfigTemplate(); !strings.Contains(template, `"office"`) || strings.Contains(template, `"in-443"`) {
+		t.Fatalf("routing rule not renamed: %s", template)
+	}
+	if muted, _ := settings.GetTgMutedInbounds(); muted != "in-8443,office" {
+		t.Fatalf("mute list = %q, want in-8443,office", muted)
+	}
+	var reloadedNode model.Node
+	if err := db.First(&reloadedNode, node.Id).Error; err != nil || !slices.Equal(reloadedNode.InboundTags, []string{"office", "other"}) {
+		t.Fatalf("node inbound tags = %v, %v", reloadedNode.InboundTags, err)
+	}
+}
+
+func TestSetInboundRemark(t *testing.T) {
+	setupConflictDB(t)
+	seedInboundConflict(t, "in-443", "0.0.0.0", 443, model.VLESS, `{"network":"tcp"}`, `{"clients":[]}`)
+	var inbound model.Inbound
+	if err := database.GetDB().Where("tag = ?", "in-443").First(&inbound).Error; err != nil {
+		t.Fatal(err)
+	}
+	svc := &InboundService{}
+	if _, err := svc.SetInboundRemark(inbound.Id, "  "); err == nil {
+		t.Fatal("an empty remark must fail")
+	}
+	if old, err := svc.SetInboundRemark(inbound.Id, "Office"); err != nil || old != "" {
+		t.Fatalf("SetInboundRemark = %q, %v", old, err)
+	}
+	if got, _ := svc.GetInbound(inbound.Id); got.Remark != "Office" {
+		t.Fatalf("remark = %q, want Office", got.Remark)
+	}
+}
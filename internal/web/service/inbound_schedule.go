@@ -0,0 +1,200 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/zixu5u/3xv/v3/internal/database"
+	"github.com/zixu5u/3xv/v3/internal/database/model"
+	"github.com/zixu5u/3xv/v3/internal/logger"
+
+	"github.com/robfig/cron/v3"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Inbound schedule actions.
+const (
+	InboundScheduleEnable  = "enable"
+	InboundScheduleDisable = "disable"
+	InboundScheduleReset   = "reset"
+)
+
+// IsInboundScheduleAction reports whether action is one of the inbound
+// schedule actions.
+func IsInboundScheduleAction(action string) bool {
+	return action == InboundScheduleEnable || action == InboundScheduleDisable || action == InboundScheduleReset
+}
+
+// InboundScheduleService stores cron schedules that enable or disable
+// inbounds or reset their traffic, and keeps them registered with the panel
+// cron.
+type InboundScheduleService struct {
+	inboundService InboundService
+	clientService  ClientService
+	xrayService    XrayService
+}
+
+// ParseInboundSchedule validates a schedule expression and returns its
+// schedule. Expressions use the panel cron format, seconds first.
+func ParseInboundSchedule(spec string) (cron.Schedule, error) {
+	if spec == "" {
+		return nil, errors.New("empty schedule")
+	}
+	return panelCronParser.Parse(spec)
+}
+
+// GetSchedules returns the schedules of one inbound.
+func (s *InboundScheduleService) GetSchedules(inboundId int) ([]model.InboundSchedule, error) {
+	var schedules []model.InboundSchedule
+	err := database.GetDB().Where("inbound_id = ?", inboundId).Order("action ASC").Find(&schedules).Error
+	return schedules, err
+}
+
+// GetAllSchedules returns every stored schedule, grouped by inbound.
+func (s *InboundScheduleService) GetAllSchedules() ([]model.InboundSchedule, error) {
+	var schedules []model.InboundSchedule
+	err := database.GetDB().Order("inbound_id ASC, action ASC").Find(&schedules).Error
+	return schedules, err
+}
+
+// SetSchedule stores the schedule for one action of an inbound, replacing
+// the previous one, and registers it right away.
+func (s *InboundScheduleService) SetSchedule(inboundId int, action string, spec string) error {
+	if !IsInboundScheduleAction(action) {
+		return fmt.Errorf("unknown schedule action %q", action)
+	}
+	if _, err := ParseInboundSchedule(spec); err != nil {
+		return err
+	}
+	if _, err := s.inboundService.GetInbound(inboundId); err != nil {
+		return err
+	}
+	err := database.GetDB().Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "inbound_id"}, {Name: "action"}},
+		DoUpdates: clause.AssignmentColumns([]string{"spec"}),
+	}).Create(&model.InboundSchedule{InboundId: inboundId, Action: action, Spec: spec}).Error
+	if err != nil {
+		return err
+	}
+	return s.register(inboundId)
+}
+
+// ClearSchedules removes every schedule of an inbound.
+func (s *InboundScheduleService) ClearSchedules(inboundId int) error {
+	if err := database.GetDB().Where("inbound_id = ?", inboundId).Delete(&model.InboundSchedule{}).Error; err != nil {
+		return err
+	}
+	return s.register(inboundId)
+}
+
+// RegisterAll registers every stored schedule with the panel cron. It runs
+// on each panel start, so schedules survive restarts.
+func (s *InboundScheduleService) RegisterAll() error {
+	schedules, err := s.GetAllSchedules()
+	if err != nil {
+		return err
+	}
+	for _, schedule := range schedules {
+		s.addEntry(schedule)
+	}
+	return nil
+}
+
+// inboundScheduleEntryPrefix is the prefix of the panel cron entry names of
+// an inbound's schedules.
+func inboundScheduleEntryPrefix(inboundId int) string {
+	return "inbound-schedule/" + strconv.Itoa(inboundId) + "/"
+}
+
+// register replaces the cron entries of an inbound with its stored
+// schedules. Before the panel cron is attached nothing is registered; the
+// schedules are picked up on start.
+func (s *InboundScheduleService) register(inboundId int) error {
+	schedules, err := s.GetSchedules(inboundId)
+	if err != nil {
+		return err
+	}
+	PanelCron().RemovePrefix(inboundScheduleEntryPrefix(inboundId))
+	for _, schedule := range schedules {
+		s.addEntry(schedule)
+	}
+	return nil
+}
+
+func (s *InboundScheduleService) addEntry(schedule model.InboundSchedule) {
+	parsed, err := ParseInboundSchedule(schedule.Spec)
+	if err != nil {
+		logger.Warningf("Skipping invalid %s schedule %q of inbound %d: %v", schedule.Action, schedule.Spec, schedule.InboundId, err)
+		return
+	}
+	inboundId, enable, spec := schedule.InboundId, schedule.Action == InboundScheduleEnable, schedule.Spec
+	job := cron.FuncJob(func() { s.apply(inboundId, enable) })
+	if schedule.Action == InboundScheduleReset {
+		job = func() { s.reset(inboundId, spec) }
+	}
+	PanelCron().Set(inboundScheduleEntryPrefix(inboundId)+schedule.Action, parsed, job)
+}
+
+// NextRun returns when a schedule fires next, or the zero time when its
+// expression is invalid.
+func (s *InboundScheduleService) NextRun(schedule model.InboundSchedule) time.Time {
+	parsed, err := ParseInboundSchedule(schedule.Spec)
+	if err != nil {
+		return time.Time{}
+	}
+	return parsed.Next(time.Now().In(PanelCron().Location()))
+}
+
+// apply runs a scheduled enable or disable through the same path as the
+// panel's enable switch. Schedules of deleted inbounds are dropped.
+func (s *InboundScheduleService) apply(inboundId int, enable bool) {
+	needRestart, err := s.inboundService.SetInboundEnable(inboundId, enable)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		logger.Infof("Inbound %d no longer exists, dropping its schedules", inboundId)
+		if err := s.ClearSchedules(inboundId); err != nil {
+			logger.Warning("Failed to clear schedules of deleted inbound:", err)
+		}
+		return
+	}
+	if err != nil {
+		logger.Warningf("Scheduled enable=%v of inbound %d failed: %v", enable, inboundId, err)
+		return
+	}
+	logger.Infof("Inbound %d set to enable=%v by schedule", inboundId, enable)
+	if needRestart {
+		s.xrayService.SetToNeedRestart()
+	}
+}
+
+// reset runs a scheduled traffic reset of an inbound and its clients, the
+// same reset its trafficReset period does. A report due at the same time is
+// let through first, so it still shows the period's traffic.
+func (s *InboundScheduleService) reset(inboundId int, spec string) {
+	WaitForReport(time.Now())
+	inbound, err := s.inboundService.GetInbound(inboundId)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		logger.Infof("Inbound %d no longer exists, dropping its schedules", inboundId)
+		if err := s.ClearSchedules(inboundId); err != nil {
+			logger.Warning("Failed to clear schedules of deleted inbound:", err)
+		}
+		return
+	}
+	if err != nil {
+		logger.Warningf("Scheduled traffic reset of inbound %d failed: %v", inboundId, err)
+		return
+	}
+	summary, needRestart, err := s.clientService.ResetInboundPeriod(&s.inboundService, inbound)
+	if needRestart {
+		s.xrayService.SetToNeedRestart()
+	}
+	if err != nil {
+		logger.Warningf("Scheduled traffic reset of inbound %d failed: %v", inboundId, err)
+		return
+	}
+	logger.Infof("Traffic of inbound %d reset by schedule", inboundId)
+	summary.Trigger = spec
+	NotifyTrafficReset(summary)
+}
@@ -0,0 +1,156 @@
+package service
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/zixu5u/3xv/v3/internal/database"
+	"github.com/zixu5u/3xv/v3/internal/database/model"
+	"github.com/zixu5u/3xv/v3/internal/xray"
+
+	"github.com/robfig/cron/v3"
+)
+
+func TestInboundSchedules(t *testing.T) {
+	dbDir := t.TempDir()
+	t.Setenv("XUI_DB_FOLDER", dbDir)
+	if err := database.InitDB(filepath.Join(dbDir, "x-ui.db")); err != nil {
+		t.Fatalf("InitDB: %v", err)
+	}
+	t.Cleanup(func() { _ = database.CloseDB() })
+
+	inbound := &model.Inbound{Tag: "inbound-443", Port: 443, Protocol: model.VLESS, Enable: true}
+	if err := database.GetDB().Create(inbound).Error; err != nil {
+		t.Fatalf("create inbound: %v", err)
+	}
+
+	c := cron.New(cron.WithSeconds())
+	PanelCron().Attach(c)
+	t.Cleanup(func() { PanelCron().Attach(nil) })
+	svc := InboundScheduleService{}
+	if err := svc.RegisterAll(); err != nil {
+		t.Fatalf("RegisterAll: %v", err)
+	}
+
+	if err := svc.SetSchedule(inbound.Id, InboundScheduleEnable, "not a cron"); err == nil {
+		t.Fatal("invalid expression must be rejected")
+	}
+	if err := svc.SetSchedule(inbound.Id, "pause", "0 0 9 * * *"); err == nil {
+		t.Fatal("unknown action must be rejected")
+	}
+	if err := svc.SetSchedule(inbound.Id+1, InboundScheduleEnable, "0 0 9 * * *"); err == nil {
+		t.Fatal("missing inbound must be rejected")
+	}
+
+	if err := svc.SetSchedule(inbound.Id, InboundScheduleEnable, "0 0 9 * * *"); err != nil {
+		t.Fatalf("SetSchedule enable: %v", err)
+	}
+	if err := svc.SetSchedule(inbound.Id, InboundScheduleDisable, "0 0 18 * * *"); err != nil {
+		t.Fatalf("SetSchedule disable: %v", err)
+	}
+	// setting an action again replaces its schedule
+	if err := svc.SetSchedule(inbound.Id, InboundScheduleEnable, "0 30 8 * * *"); err != nil {
+		t.Fatalf("SetSchedule enable again: %v", err)
+	}
+
+	got, err := svc.GetSchedules(inbound.Id)
+	if err != nil {
+		t.Fatalf("GetSchedules: %v", err)
+	}
+	if len(got) != 2 || got[0].Action != InboundScheduleDisable || got[1].Spec != "0 30 8 * * *" {
+		t.Fatalf("unexpected schedules %+v", got)
+	}
+	if n := len(c.Entries()); n != 2 {
+		t.Fatalf("want 2 cron entries, got %d", n)
+	}
+
+	// a fresh cron, as after a panel restart, gets the stored schedules back
+	restarted := cron.New(cron.WithSeconds())
+	PanelCron().Attach(restarted)
+	if n := len(c.Entries()); n != 0 {
+		t.Fatalf("entries left on the previous cron: %d", n)
+	}
+	if err := svc.RegisterAll(); err != nil {
+		t.Fatalf("RegisterAll after restart: %v", err)
+	}
+	if n := len(restarted.Entries()); n != 2 {
+		t.Fatalf("want 2 cron entries after restart, got %d", n)
+	}
+
+	if err := svc.ClearSchedules(inbound.Id); err != nil {
+		t.Fatalf("ClearSchedules: %v", err)
+	}
+	if got, _ := svc.GetSchedules(inbound.Id); len(got) != 0 {
+		t.Fatalf("schedules left after clear: %+v", got)
+	}
+	if n := len(restarted.Entries()); n != 0 {
+		t.Fatalf("cron entries left after clear: %d", n)
+	}
+}
+
+// resetRecorder is a notifier that keeps the traffic resets it is told of.
+type resetRecorder struct {
+	resets []TrafficResetSummary
+}
+
+func (r *resetRecorder) Notify(string, string) {}
+
+func (r *resetRecorder) NotifyTrafficReset(summary TrafficResetSummary) {
+	r.resets = append(r.resets, summary)
+}
+
+func TestInboundScheduleReset(t *testing.T) {
+	dbDir := t.TempDir()
+	t.Setenv("XUI_DB_FOLDER", dbDir)
+	if err := database.InitDB(filepath.Join(dbDir, "x-ui.db")); err != nil {
+		t.Fatalf("InitDB: %v", err)
+	}
+	t.Cleanup(func() { _ = database.CloseDB() })
+	db := database.GetDB()
+
+	inbound := &model.Inbound{Tag: "inbound-443", Port: 443, Protocol: model.VLESS, Enable: true, Up: 300, Down: 700}
+	if err := db.Create(inbound).Error; err != nil {
+		t.Fatalf("create inbound: %v", err)
+	}
+	if err := db.Create(&xray.ClientTraffic{InboundId: inbound.Id, Email: "a@x", Enable: true, Up: 100, Down: 200}).Error; err != nil {
+		t.Fatalf("create client traffic: %v", err)
+	}
+
+	c := cron.New(cron.WithSeconds())
+	PanelCron().Attach(c)
+	t.Cleanup(func() { PanelCron().Attach(nil) })
+	recorder := &resetRecorder{}
+	SetNotifier(recorder)
+	t.Cleanup(func() { SetNotifier(nil) })
+
+	svc := InboundScheduleService{}
+	if err := svc.SetSchedule(inbound.Id, InboundScheduleReset, "0 0 0 1 * *"); err != nil {
+		t.Fatalf("SetSchedule reset: %v", err)
+	}
+	if n := len(c.Entries()); n != 1 {
+		t.Fatalf("want 1 cron entry, got %d", n)
+	}
+
+	svc.reset(inbound.Id, "0 0 0 1 * *")
+
+	var after model.Inbound
+	if err := db.First(&after, inbound.Id).Error; err != nil {
+		t.Fatalf("read inbound: %v", err)
+	}
+	if after.Up != 0 || after.Down != 0 {
+		t.Errorf("inbound traffic after reset = %d/%d, want 0/0", after.Up, after.Down)
+	}
+	var traffic xray.ClientTraffic
+	if err := db.Where("email = ?", "a@x").First(&traffic).Error; err != nil {
+		t.Fatalf("read client traffic: %v", err)
+	}
+	if traffic.Up != 0 || traffic.Down != 0 {
+		t.Errorf("client traffic after reset = %d/%d, want 0/0", traffic.Up, traffic.Down)
+	}
+	if len(recorder.resets) != 1 {
+		t.Fatalf("want one reset notification, got %+v", recorder.resets)
+	}
+	if got := recorder.resets[0]; got.Tag != "inbound-443" || got.Up != 300 || got.Down != 700 || got.Clients != 1 || got.Trigger != "0 0 0 1 * *" {
+		t.Errorf("reset summary = %+v", got)
+	}
+}
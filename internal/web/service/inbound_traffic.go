@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"sort"
 	"strings"
 	"time"
 
@@ -910,6 +911,21 @@ func (s *InboundService) GetInboundsTrafficSummary() ([]InboundTrafficSummary, e
 	return summaries, nil
 }
 
+// GetClientTrafficById returns the client traffic record id, or nil when
+// there is none.
+func (s *InboundService) GetClientTrafficById(id int) (*xray.ClientTraffic, error) {
+	db := database.GetDB()
+	var traffics []*xray.ClientTraffic
+	if err := db.Model(xray.ClientTraffic{}).Where("id = ?", id).Find(&traffics).Error; err != nil {
+		return nil, err
+	}
+	if len(traffics) == 0 {
+		return nil, nil
+	}
+	overlayGlobalTraffic(db, traffics)
+	return traffics[0], nil
+}
+
 func (s *InboundService) GetClientTrafficByEmail(email string) (traffic *xray.ClientTraffic, err error) {
 	db := database.GetDB()
 	var traffics []*xray.ClientTraffic
@@ -943,6 +959,106 @@ func (s *InboundService) GetClientTrafficByEmail(email string) (traffic *xray.Cl
 	return nil, nil
 }
 
+// SearchClientTraffics returns up to limit client traffics whose email
+// contains query, ordered by email.
+func (s *InboundService) SearchClientTraffics(query string, limit int) ([]*xray.ClientTraffic, error) {
+	db := database.GetDB()
+	var traffics []*xray.ClientTraffic
+	err := db.Model(xray.ClientTraffic{}).
+		Where("email like ?", "%"+query+"%").
+		Order("email").
+		Limit(limit).
+		Find(&traffics).Error
+	if err != nil {
+		return nil, err
+	}
+	overlayGlobalTraffic(db, traffics)
+	return traffics, nil
+}
+
+// InboundPoolUsage is how the clients of an inbound share its traffic
+// limit.
+type InboundPoolUsage struct {
+	Limit     int64 // 0 is unlimited
+	Used      int64
+	Remaining int64 // 0 when unlimited or exhausted
+	Clients   []ClientPoolShare
+}
+
+// ClientPoolShare is one client's contribution to an inbound's traffic.
+type ClientPoolShare struct {
+	Email   string
+	Enable  bool
+	Used    int64
+	Percent float64
+}
+
+// GetInboundPoolUsage breaks the traffic of an inbound down per client,
+// biggest consumer first. Percentages are of the inbound's traffic, or of
+// the clients' total when their counters add up to more, as they do after
+// the inbound alone was reset.
+func (s *InboundService) GetInboundPoolUsage(id int) (*InboundPoolUsage, error) {
+	inbound, err := s.GetInbound(id)
+	if err != nil {
+		return nil, err
+	}
+	clients, err := s.GetClients(inbound)
+	if err != nil {
+		return nil, err
+	}
+	usage := &InboundPoolUsage{Limit: inbound.Total, Used: inbound.Up + inbound.Down}
+	if usage.Limit > 0 && usage.Used < usage.Limit {
+		usage.Remaining = usage.Limit - usage.Used
+	}
+
+	emails := make([]string, 0, len(clients))
+	for _, client := range clients {
+		if client.Email != "" {
+			emails = append(emails, client.Email)
+		}
+	}
+	db := database.GetDB()
+	var traffics []*xray.ClientTraffic
+	for _, batch := range chunkStrings(emails, sqlInChunk) {
+		var page []*xray.ClientTraffic
+		if err := db.Model(xray.ClientTraffic{}).Where("email IN ?", batch).Find(&page).Error; err != nil {
+			return nil, err
+		}
+		traffics = append(traffics, page...)
+	}
+	overlayGlobalTraffic(db, traffics)
+	byEmail := make(map[string]*xray.ClientTraffic, len(traffics))
+	for _, traffic := range traffics {
+		byEmail[strings.ToLower(traffic.Email)] = traffic
+	}
+
+	var clientsTotal int64
+	for _, client := range clients {
+		if client.Email == "" {
+			continue
+		}
+		share := ClientPoolShare{Email: client.Email, Enable: client.Enable}
+		if traffic, ok := byEmail[strings.ToLower(client.Email)]; ok {
+			share.Used = traffic.Up + traffic.Down
+		}
+		clientsTotal += share.Used
+		usage.Clients = append(usage.Clients, share)
+	}
+	base := max(usage.Used, clientsTotal)
+	for i := range usage.Clients {
+		if base > 0 {
+			usage.Clients[i].Percent = float64(usage.Clients[i].Used) / float64(base) * 100
+		}
+	}
+	sort.SliceStable(usage.Clients, func(i, j int) bool {
+		if usage.Clients[i].Used != usage.Clients[j].Used {
+			return usage.Clients[i].Used > usage.Clients[j].Used
+		}
+		return usage.Clients[i].Email < usage.Clients[j].Email
+	})
+	return usage, nil
+}
+
 func (s *InboundService) UpdateClientTrafficByEmail(email string, upload int64, download int64) error {
 	return submitTrafficWrite(func() error {
 		db := database.GetDB()
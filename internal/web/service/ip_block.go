@@ -0,0 +1,187 @@
+package service
+
+import (
+	"encoding/json"
+	"net"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/zixu5u/3xv/v3/internal/logger"
+	"github.com/zixu5u/3xv/v3/internal/util/common"
+	"github.com/zixu5u/3xv/v3/internal/util/json_util"
+	"github.com/zixu5u/3xv/v3/internal/xray"
+)
+
+// PanelBlockOutboundTag is the blackhole outbound injected into the generated
+// config for the panel-wide IP block list.
+const PanelBlockOutboundTag = "panel-blocked"
+
+// blockedIpsMutex serializes read-modify-write updates of the block list.
+var blockedIpsMutex sync.Mutex
+
+// IpBlockService maintains the panel-wide list of blocked source IPs/CIDRs.
+// The list is persisted in settings and applied to every inbound through a
+// routing rule in the generated Xray config.
+type IpBlockService struct {
+	settingService SettingService
+	xrayService    XrayService
+}
+
+// NormalizeBlockTarget validates an IP address or CIDR and returns its
+// canonical form. Loopback and catch-all targets are rejected, since they
+// would cut off the panel's own loopback bridges or every client at once.
+func NormalizeBlockTarget(target string) (string, error) {
+	target = strings.TrimSpace(target)
+	if strings.Contains(target, "/") {
+		_, ipNet, err := net.ParseCIDR(target)
+		if err != nil {
+			return "", common.NewError("invalid CIDR:", target)
+		}
+		if ones, _ := ipNet.Mask.Size(); ones == 0 ||
+			ipNet.Contains(net.IPv4(127, 0, 0, 1)) || ipNet.Contains(net.IPv6loopback) {
+			return "", common.NewError("refusing to block loopback or all addresses:", target)
+		}
+		return ipNet.String(), nil
+	}
+	ip := net.ParseIP(target)
+	if ip == nil {
+		return "", common.NewError("invalid IP address:", target)
+	}
+	if ip.IsLoopback() || ip.IsUnspecified() {
+		return "", common.NewError("refusing to block loopback or unspecified address:", target)
+	}
+	return ip.String(), nil
+}
+
+// parseBlockedIps splits the stored comma-separated block list.
+func parseBlockedIps(raw string) []string {
+	list := make([]string, 0)
+	for item := range strings.SplitSeq(raw, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+// GetBlockedIps returns the current block list.
+func (s *IpBlockService) GetBlockedIps() ([]string, error) {
+	raw, err := s.settingService.GetBlockedIps()
+	if err != nil {
+		return nil, err
+	}
+	return parseBlockedIps(raw), nil
+}
+
+// BlockIp adds target to the block list and applies it to the running core.
+// It returns the normalized target and whether it was newly added.
+func (s *IpBlockService) BlockIp(target string) (string, bool, error) {
+	normalized, err := NormalizeBlockTarget(target)
+	if err != nil {
+		return "", false, err
+	}
+	blockedIpsMutex.Lock()
+	defer blockedIpsMutex.Unlock()
+
+	list, err := s.GetBlockedIps()
+	if err != nil {
+		return "", false, err
+	}
+	if slices.Contains(list, normalized) {
+		return normalized, false, nil
+	}
+	return normalized, true, s.saveBlockedIps(append(list, normalized))
+}
+
+// UnblockIp removes target from the block list and applies the change.
+// It returns the normalized target and whether it was present.
+func (s *IpBlockService) UnblockIp(target string) (string, bool, error) {
+	normalized, err := NormalizeBlockTarget(target)
+	if err != nil {
+		return "", false, err
+	}
+	blockedIpsMutex.Lock()
+	defer blockedIpsMutex.Unlock()
+
+	list, err := s.GetBlockedIps()
+	if err != nil {
+		return "", false, err
+	}
+	idx := slices.Index(list, normalized)
+	if idx < 0 {
+		return normalized, false, nil
+	}
+	return normalized, true, s.saveBlockedIps(slices.Delete(list, idx, idx+1))
+}
+
+// saveBlockedIps persists the list and hot-applies it when Xray is running;
+// a stopped core picks it up on its next start.
+func (s *IpBlockService) saveBlockedIps(list []string) error {
+	if err := s.settingService.SetBlockedIps(strings.Join(list, ",")); err != nil {
+		return err
+	}
+	if !s.xrayService.IsXrayRunning() {
+		return nil
+	}
+	return s.xrayService.RestartXray(false)
+}
+
+// injectBlockedIps prepends a routing rule sending every listed source to a
+// blackhole outbound, appending that outbound when the template lacks it.
+// Like injectPanelEgress, it only touches the generated config and is
+// hot-appliable.
+func injectBlockedIps(cfg *xray.Config, ips []string) {
+	if len(ips) == 0 {
+		return
+	}
+	var outbounds []any
+	if len(cfg.OutboundConfigs) > 0 {
+		if err := json.Unmarshal(cfg.OutboundConfigs, &outbounds); err != nil {
+			logger.Warning("ip block: outbounds section is unparsable, skipping injection:", err)
+			return
+		}
+	}
+	routing := map[string]any{}
+	if len(cfg.RouterConfig) > 0 {
+		if err := json.Unmarshal(cfg.RouterConfig, &routing); err != nil {
+			logger.Warning("ip block: routing section is unparsable, skipping injection:", err)
+			return
+		}
+	}
+
+	hasOutbound := false
+	for _, ob := range outbounds {
+		if m, ok := ob.(map[string]any); ok && m["tag"] == PanelBlockOutboundTag {
+			hasOutbound = true
+			break
+		}
+	}
+	if !hasOutbound {
+		outbounds = append(outbounds, map[string]any{"protocol": "blackhole", "tag": PanelBlockOutboundTag})
+		newOutbounds, err := json.MarshalIndent(outbounds, "", "  ")
+		if err != nil {
+			logger.Warning("ip block: failed to rebuild outbounds section, skipping injection:", err)
+			return
+		}
+		cfg.OutboundConfigs = json_util.RawMessage(newOutbounds)
+	}
+
+	source := make([]any, 0, len(ips))
+	for _, ip := range ips {
+		source = append(source, ip)
+	}
+	rules, _ := routing["rules"].([]any)
+	rule := map[string]any{
+		"type":        "field",
+		"source":      source,
+		"outboundTag": PanelBlockOutboundTag,
+	}
+	routing["rules"] = append([]any{rule}, rules...)
+	newRouting, err := json.Marshal(routing)
+	if err != nil {
+		logger.Warning("ip block: failed to rebuild routing section, skipping injection:", err)
+		return
+	}
+	cfg.RouterConfig = json_util.RawMessage(newRouting)
+}
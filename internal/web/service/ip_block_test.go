@@ -0,0 +1,105 @@
+package service
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/zixu5u/3xv/v3/internal/util/json_util"
+	"github.com/zixu5u/3xv/v3/internal/xray"
+)
+
+func TestNormalizeBlockTarget(t *testing.T) {
+	valid := map[string]string{
+		"203.0.113.7":      "203.0.113.7",
+		" 203.0.113.7 ":    "203.0.113.7",
+		"203.0.113.7/24":   "203.0.113.0/24",
+		"2001:db8::1":      "2001:db8::1",
+		"2001:DB8::/32":    "2001:db8::/32",
+		"198.51.100.10/32": "198.51.100.10/32",
+	}
+	for in, want := range valid {
+		got, err := NormalizeBlockTarget(in)
+		if err != nil {
+			t.Fatalf("NormalizeBlockTarget(%q) failed: %v", in, err)
+		}
+		if got != want {
+			t.Fatalf("NormalizeBlockTarget(%q) = %q, want %q", in, got, want)
+		}
+	}
+
+	// garbage, loopback and catch-all targets must be refused
+	for _, in := range []string{"", "example.com", "300.1.1.1", "10.0.0.0/33", "127.0.0.1", "::1", "0.0.0.0", "0.0.0.0/0", "::/0", "127.0.0.0/8"} {
+		if got, err := NormalizeBlockTarget(in); err == nil {
+			t.Fatalf("NormalizeBlockTarget(%q) = %q, want error", in, got)
+		}
+	}
+}
+
+func TestParseBlockedIps(t *testing.T) {
+	got := parseBlockedIps(" 203.0.113.7, ,10.0.0.0/8,")
+	if len(got) != 2 || got[0] != "203.0.113.7" || got[1] != "10.0.0.0/8" {
+		t.Fatalf("unexpected list %v", got)
+	}
+	if got := parseBlockedIps(""); len(got) != 0 {
+		t.Fatalf("empty setting must give an empty list, got %v", got)
+	}
+}
+
+func TestInjectBlockedIps(t *testing.T) {
+	cfg := &xray.Config{
+		OutboundConfigs: json_util.RawMessage(`[{"protocol":"freedom","tag":"direct"}]`),
+		RouterConfig:    json_util.RawMessage(`{"domainStrategy":"AsIs","rules":[{"type":"field","inboundTag":["api"],"outboundTag":"api"}]}`),
+	}
+	injectBlockedIps(cfg, []string{"203.0.113.7", "10.0.0.0/8"})
+
+	var outbounds []map[string]any
+	if err := json.Unmarshal(cfg.OutboundConfigs, &outbounds); err != nil {
+		t.Fatal(err)
+	}
+	if len(outbounds) != 2 || outbounds[1]["tag"] != PanelBlockOutboundTag || outbounds[1]["protocol"] != "blackhole" {
+		t.Fatalf("blackhole outbound must be appended, got %v", outbounds)
+	}
+
+	var routing struct {
+		DomainStrategy string           `json:"domainStrategy"`
+		Rules          []map[string]any `json:"rules"`
+	}
+	if err := json.Unmarshal(cfg.RouterConfig, &routing); err != nil {
+		t.Fatal(err)
+	}
+	if routing.DomainStrategy != "AsIs" {
+		t.Fatalf("routing siblings must be preserved, got %q", routing.DomainStrategy)
+	}
+	if len(routing.Rules) != 2 || routing.Rules[0]["outboundTag"] != PanelBlockOutboundTag {
+		t.Fatalf("block rule must be first, got %v", routing.Rules)
+	}
+	if src, _ := routing.Rules[0]["source"].([]any); len(src) != 2 || src[0] != "203.0.113.7" {
+		t.Fatalf("unexpected block rule sources %v", routing.Rules[0]["source"])
+	}
+
+	// an existing outbound with the same tag is reused, not duplicated
+	injectBlockedIps(cfg, []string{"203.0.113.8"})
+	if err := json.Unmarshal(cfg.OutboundConfigs, &outbounds); err != nil {
+		t.Fatal(err)
+	}
+	if len(outbounds) != 2 {
+		t.Fatalf("blackhole outbound must not be duplicated, got %v", outbounds)
+	}
+
+	// an empty list leaves the config untouched
+	before := string(cfg.RouterConfig)
+	injectBlockedIps(cfg, nil)
+	if string(cfg.RouterConfig) != before {
+		t.Fatal("empty block list must not touch routing")
+	}
+
+	// unparsable outbounds skip injection instead of routing to a missing tag
+	broken := &xray.Config{
+		OutboundConfigs: json_util.RawMessage(`not json`),
+		RouterConfig:    json_util.RawMessage(`{"rules":[]}`),
+	}
+	injectBlockedIps(broken, []string{"203.0.113.7"})
+	if string(broken.RouterConfig) != `{"rules":[]}` {
+		t.Fatalf("routing must be untouched when outbounds are unparsable, got %s", broken.RouterConfig)
+	}
+}
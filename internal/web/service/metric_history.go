@@ -65,6 +65,24 @@ func (h *metricHistory) drop(metric string) {
 	h.mu.Unlock()
 }
 
+// average returns the mean of the samples of metric recorded within window
+// before now, and how many samples that was.
+func (h *metricHistory) average(metric string, window time.Duration, now time.Time) (float64, int) {
+	cutoff := now.Add(-window).Unix()
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	hist := h.metrics[metric]
+	sum, n := 0.0, 0
+	for i := len(hist) - 1; i >= 0 && hist[i].T > cutoff; i-- {
+		sum += hist[i].V
+		n++
+	}
+	if n == 0 {
+		return 0, 0
+	}
+	return sum / float64(n), n
+}
+
 // snapshot returns a deep copy of every series, safe to serialize without
 // holding the lock during disk I/O.
 func (h *metricHistory) snapshot() map[string][]MetricSample {
@@ -0,0 +1,26 @@
+package service
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMetricHistoryAverage(t *testing.T) {
+	h := newMetricHistory()
+	now := time.Unix(1_700_000_000, 0)
+	h.append("cpu", now.Add(-90*time.Second), 100) // outside a 60s window
+	h.append("cpu", now.Add(-50*time.Second), 10)
+	h.append("cpu", now.Add(-30*time.Second), 20)
+	h.append("cpu", now, 90)
+
+	avg, n := h.average("cpu", time.Minute, now)
+	if n != 3 || avg != 40 {
+		t.Fatalf("average = %v over %d samples, want 40 over 3", avg, n)
+	}
+	if _, n := h.average("cpu", time.Second, now.Add(time.Hour)); n != 0 {
+		t.Fatalf("want no samples in a window after the last one, got %d", n)
+	}
+	if _, n := h.average("mem", time.Minute, now); n != 0 {
+		t.Fatalf("want no samples for an unknown metric, got %d", n)
+	}
+}
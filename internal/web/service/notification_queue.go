@@ -0,0 +1,115 @@
+package service
+
+import (
+	"time"
+	"unicode/utf8"
+
+	"github.com/zixu5u/3xv/v3/internal/database"
+	"github.com/zixu5u/3xv/v3/internal/database/model"
+)
+
+const (
+	// notificationRetryBase is the delay before the first retry of a queued
+	// notification; each further failure doubles it.
+	notificationRetryBase = 5 * time.Second
+	// notificationRetryMax caps the delay between retries.
+	notificationRetryMax = 10 * time.Minute
+	// notificationMaxAge is how long a queued notification is retried before
+	// it is given up on; an alert a day late no longer helps anyone.
+	notificationMaxAge = 24 * time.Hour
+	// maxLastErrorLen caps the delivery error kept with a notification.
+	maxLastErrorLen = 500
+)
+
+// NotificationQueueService stores critical bot notifications until each of
+// their chats has confirmed delivery. Notifications are kept per chat, so a
+// chat that accepted one is never sent it again because another failed.
+type NotificationQueueService struct{}
+
+// Enqueue stores text for every chat in chatIds, in one transaction, due
+// right away. replyMarkup is the JSON inline keyboard to send with it, if any.
+func (s *NotificationQueueService) Enqueue(category string, text string, replyMarkup string, chatIds []int64, now time.Time) error {
+	if len(chatIds) == 0 {
+		return nil
+	}
+	entries := make([]model.PendingNotification, 0, len(chatIds))
+	for _, chatId := range chatIds {
+		entries = append(entries, model.PendingNotification{
+			Category:      category,
+			ChatId:        chatId,
+			Text:          text,
+			ReplyMarkup:   replyMarkup,
+			CreatedAt:     now.UnixMilli(),
+			NextAttemptAt: now.UnixMilli(),
+		})
+	}
+	return database.GetDB().Create(&entries).Error
+}
+
+// QueuedChats returns the chats that have queued notifications.
+func (s *NotificationQueueService) QueuedChats() ([]int64, error) {
+	var chatIds []int64
+	err := database.GetDB().Model(&model.PendingNotification{}).
+		Distinct("chat_id").Order("chat_id ASC").Pluck("chat_id", &chatIds).Error
+	return chatIds, err
+}
+
+// Pending returns up to limit notifications queued for chatId in the order
+// they were queued, whether or not they are due yet.
+func (s *NotificationQueueService) Pending(chatId int64, limit int) ([]model.PendingNotification, error) {
+	var entries []model.PendingNotification
+	err := database.GetDB().Where("chat_id = ?", chatId).Order("id ASC").Limit(limit).Find(&entries).Error
+	return entries, err
+}
+
+// Delivered removes a notification its chat accepted.
+func (s *NotificationQueueService) Delivered(id int) error {
+	return database.GetDB().Delete(&model.PendingNotification{}, id).Error
+}
+
+// Drop removes a notification that will not be delivered.
+func (s *NotificationQueueService) Drop(id int) error {
+	return s.Delivered(id)
+}
+
+// Failed records a failed delivery attempt and schedules the next one with
+// exponential backoff. Once the notification is older than
+// notificationMaxAge it is removed instead and dropped is true.
+func (s *NotificationQueueService) Failed(entry *model.PendingNotification, sendErr error, now time.Time) (dropped bool, err error) {
+	if now.Sub(time.UnixMilli(entry.CreatedAt)) >= notificationMaxAge {
+		return true, s.Drop(entry.Id)
+	}
+	entry.Attempts++
+	entry.NextAttemptAt = now.Add(NotificationRetryDelay(entry.Attempts)).UnixMilli()
+	entry.LastError = truncateLastError(sendErr.Error())
+	return false, database.GetDB().Model(&model.PendingNotification{}).Where("id = ?", entry.Id).
+		Updates(map[string]any{
+			"attempts":        entry.Attempts,
+			"next_attempt_at": entry.NextAttemptAt,
+			"last_error":      entry.LastError,
+		}).Error
+}
+
+// truncateLastError cuts msg to at most maxLastErrorLen bytes, at a rune
+// boundary so the stored error stays valid UTF-8.
+func truncateLastError(msg string) string {
+	if len(msg) <= maxLastErrorLen {
+		return msg
+	}
+	cut := maxLastErrorLen
+	for cut > 0 && !utf8.RuneStart(msg[cut]) {
+		cut--
+	}
+	return msg[:cut]
+}
+
+// NotificationRetryDelay is how long to wait after the given number of
+// failed attempts: notificationRetryBase, doubled per failure up to
+// notificationRetryMax.
+func NotificationRetryDelay(attempts int) time.Duration {
+	delay := notificationRetryBase
+	for i := 1; i < attempts && delay < notificationRetryMax; i++ {
+		delay *= 2
+	}
+	return min(delay, notificationRetryMax)
+}
@@ -0,0 +1,131 @@
+package service
+
+import (
+	"errors"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/zixu5u/3xv/v3/internal/database"
+)
+
+func TestNotificationQueue(t *testing.T) {
+	dbDir := t.TempDir()
+	t.Setenv("XUI_DB_FOLDER", dbDir)
+	if err := database.InitDB(filepath.Join(dbDir, "x-ui.db")); err != nil {
+		t.Fatalf("InitDB: %v", err)
+	}
+	t.Cleanup(func() { _ = database.CloseDB() })
+
+	svc := NotificationQueueService{}
+	now := time.Date(2024, 5, 31, 12, 0, 0, 0, time.UTC)
+	if err := svc.Enqueue("xray", "Xray is down", `{"inline_keyboard":[]}`, []int64{10, 20}, now); err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+	if err := svc.Enqueue("settings", "Settings unreadable", "", []int64{10}, now); err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+
+	chats, err := svc.QueuedChats()
+	if err != nil || !reflect.DeepEqual(chats, []int64{10, 20}) {
+		t.Fatalf("QueuedChats = %v, %v", chats, err)
+	}
+	pending, err := svc.Pending(10, 10)
+	if err != nil {
+		t.Fatalf("Pending: %v", err)
+	}
+	if len(pending) != 2 || pending[0].Category != "xray" || pending[1].Category != "settings" {
+		t.Fatalf("pending = %+v", pending)
+	}
+	other, err := svc.Pending(20, 10)
+	if err != nil || len(other) != 1 || other[0].ChatId != 20 {
+		t.Fatalf("pending for 20 = %+v, %v", other, err)
+	}
+
+	if err := svc.Delivered(pending[0].Id); err != nil {
+		t.Fatalf("Delivered: %v", err)
+	}
+	dropped, err := svc.Failed(&other[0], errors.New("chat not found"), now)
+	if err != nil || dropped {
+		t.Fatalf("Failed = %v, %v", dropped, err)
+	}
+
+	if pending, _ = svc.Pending(10, 10); len(pending) != 1 {
+		t.Fatalf("a delivered notification must be removed, pending = %+v", pending)
+	}
+	other, err = svc.Pending(20, 10)
+	if err != nil || len(other) != 1 {
+		t.Fatalf("Pending: %+v, %v", other, err)
+	}
+	retry := other[0]
+	if retry.Attempts != 1 || retry.LastError != "chat not found" || retry.NextAttemptAt != now.Add(5*time.Second).UnixMilli() {
+		t.Fatalf("failed entry = %+v", retry)
+	}
+
+	dropped, err = svc.Failed(&retry, errors.New("chat not found"), now.Add(25*time.Hour))
+	if err != nil || !dropped {
+		t.Fatalf("a day-old notification must be dropped, got %v, %v", dropped, err)
+	}
+	if chats, _ = svc.QueuedChats(); !reflect.DeepEqual(chats, []int64{10}) {
+		t.Fatalf("queued chats after drop = %v", chats)
+	}
+}
+
+func TestNotificationQueuePagesPerChat(t *testing.T) {
+	dbDir := t.TempDir()
+	t.Setenv("XUI_DB_FOLDER", dbDir)
+	if err := database.InitDB(filepath.Join(dbDir, "x-ui.db")); err != nil {
+		t.Fatalf("InitDB: %v", err)
+	}
+	t.Cleanup(func() { _ = database.CloseDB() })
+
+	svc := NotificationQueueService{}
+	now := time.Date(2024, 5, 31, 12, 0, 0, 0, time.UTC)
+	for range 5 {
+		if err := svc.Enqueue("xray", "Xray is down", "", []int64{10}, now); err != nil {
+			t.Fatalf("Enqueue: %v", err)
+		}
+	}
+	if err := svc.Enqueue("xray", "Xray is down", "", []int64{20}, now); err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+
+	// A chat with a backlog larger than the page must not hide the others.
+	pending, err := svc.Pending(20, 2)
+	if err != nil || len(pending) != 1 || pending[0].ChatId != 20 {
+		t.Fatalf("pending for 20 = %+v, %v", pending, err)
+	}
+	if pending, _ = svc.Pending(10, 2); len(pending) != 2 {
+		t.Fatalf("a page must stop at its limit, pending = %+v", pending)
+	}
+}
+
+func TestNotificationRetryDelay(t *testing.T) {
+	cases := map[int]time.Duration{
+		1:  5 * time.Second,
+		2:  10 * time.Second,
+		4:  40 * time.Second,
+		10: 10 * time.Minute,
+		50: 10 * time.Minute,
+	}
+	for attempts, want := range cases {
+		if got := NotificationRetryDelay(attempts); got != want {
+			t.Errorf("NotificationRetryDelay(%d) = %v, want %v", attempts, got, want)
+		}
+	}
+}
+
+func TestTruncateLastError(t *testing.T) {
+	if got := truncateLastError("timeout"); got != "timeout" {
+		t.Fatalf("short error changed: %q", got)
+	}
+	// "é" is two bytes, so byte maxLastErrorLen falls inside one.
+	msg := "a" + strings.Repeat("é", maxLastErrorLen)
+	got := truncateLastError(msg)
+	if !utf8.ValidString(got) || len(got) > maxLastErrorLen || len(got) < maxLastErrorLen-1 {
+		t.Fatalf("truncateLastError = %d bytes, valid UTF-8 %v", len(got), utf8.ValidString(got))
+	}
+}
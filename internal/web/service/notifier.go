@@ -0,0 +1,68 @@
+package service
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// Notifier delivers panel notifications to admins. The Telegram bot
+// registers itself on start; it applies category subscriptions, quiet
+// hours and message paging, so callers only pick a category and the text.
+type Notifier interface {
+	Notify(category string, text string)
+}
+
+type notifierHolder struct{ n Notifier }
+
+var notifier atomic.Pointer[notifierHolder]
+
+// SetNotifier registers the panel-wide notifier; nil removes it.
+func SetNotifier(n Notifier) {
+	if n == nil {
+		notifier.Store(nil)
+		return
+	}
+	notifier.Store(&notifierHolder{n: n})
+}
+
+// Notify sends text to the admins through the registered notifier, for
+// services that can't import the bot. Without a notifier, e.g. while the
+// bot is disabled, it does nothing.
+func Notify(category string, text string) {
+	if h := notifier.Load(); h != nil {
+		h.n.Notify(category, text)
+	}
+}
+
+// ReportWaiter is implemented by a notifier that also sends the scheduled
+// report. Traffic resets wait for it, so a report due at the same moment
+// still shows the traffic of the period that ends.
+type ReportWaiter interface {
+	WaitForReport(at time.Time)
+}
+
+// WaitForReport blocks while a report due around at hasn't been built yet,
+// if the registered notifier sends reports.
+func WaitForReport(at time.Time) {
+	if h := notifier.Load(); h != nil {
+		if w, ok := h.n.(ReportWaiter); ok {
+			w.WaitForReport(at)
+		}
+	}
+}
+
+// TrafficResetNotifier is implemented by a notifier that reports automatic
+// traffic resets to the admins.
+type TrafficResetNotifier interface {
+	NotifyTrafficReset(summary TrafficResetSummary)
+}
+
+// NotifyTrafficReset tells the admins about an automatic traffic reset
+// through the registered notifier, if it reports them.
+func NotifyTrafficReset(summary TrafficResetSummary) {
+	if h := notifier.Load(); h != nil {
+		if n, ok := h.n.(TrafficResetNotifier); ok {
+			n.NotifyTrafficReset(summary)
+		}
+	}
+}
@@ -0,0 +1,27 @@
+package service
+
+import "testing"
+
+type recordingNotifier struct {
+	sent []string
+}
+
+func (r *recordingNotifier) Notify(category string, text string) {
+	r.sent = append(r.sent, category+": "+text)
+}
+
+func TestNotify(t *testing.T) {
+	t.Cleanup(func() { SetNotifier(nil) })
+
+	Notify("report", "dropped") // no notifier registered yet
+
+	r := &recordingNotifier{}
+	SetNotifier(r)
+	Notify("backup", "done")
+	SetNotifier(nil)
+	Notify("backup", "dropped")
+
+	if len(r.sent) != 1 || r.sent[0] != "backup: done" {
+		t.Fatalf("unexpected notifications %q", r.sent)
+	}
+}
@@ -0,0 +1,65 @@
+package service
+
+import (
+	"time"
+
+	"github.com/zixu5u/3xv/v3/internal/database"
+	"github.com/zixu5u/3xv/v3/internal/database/model"
+)
+
+// OnlineHistoryService records the online client count over time so reports
+// can show the day's peak and average concurrency.
+type OnlineHistoryService struct{}
+
+// OnlineDayStats summarizes one calendar day of online samples.
+type OnlineDayStats struct {
+	Peak    int
+	PeakAt  time.Time
+	Average float64
+	Samples int
+}
+
+// Record stores a sample. Old samples are removed by Prune.
+func (s *OnlineHistoryService) Record(at time.Time, count int) error {
+	return database.GetDB().Create(&model.OnlineSample{SampledAt: at.Unix(), Count: count}).Error
+}
+
+// Prune deletes the samples taken before before and returns how many there
+// were.
+func (s *OnlineHistoryService) Prune(before time.Time) (int64, error) {
+	result := database.GetDB().Where("sampled_at < ?", before.Unix()).Delete(&model.OnlineSample{})
+	return result.RowsAffected, result.Error
+}
+
+// GetDayStats returns the peak and average online count for the calendar day
+// containing day, in day's location. Samples is 0 when nothing was recorded.
+func (s *OnlineHistoryService) GetDayStats(day time.Time) (*OnlineDayStats, error) {
+	start := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, day.Location())
+	end := start.AddDate(0, 0, 1)
+
+	var samples []model.OnlineSample
+	err := database.GetDB().
+		Where("sampled_at >= ? AND sampled_at < ?", start.Unix(), end.Unix()).
+		Order("sampled_at").
+		Find(&samples).Error
+	if err != nil {
+		return nil, err
+	}
+
+	stats := &OnlineDayStats{Samples: len(samples)}
+	if len(samples) == 0 {
+		return stats, nil
+	}
+	sum := 0
+	peakAt := samples[0].SampledAt
+	for _, sample := range samples {
+		sum += sample.Count
+		if sample.Count > stats.Peak {
+			stats.Peak = sample.Count
+			peakAt = sample.SampledAt
+		}
+	}
+	stats.PeakAt = time.Unix(peakAt, 0).In(day.Location())
+	stats.Average = float64(sum) / float64(len(samples))
+	return stats, nil
+}
@@ -0,0 +1,66 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/zixu5u/3xv/v3/internal/database/model"
+)
+
+func TestOnlineHistoryDayStats(t *testing.T) {
+	initTrafficTestDB(t)
+	svc := &OnlineHistoryService{}
+	day := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+
+	for _, s := range []struct {
+		at    time.Time
+		count int
+	}{
+		{day.Add(-time.Minute), 99}, // previous day, must be ignored
+		{day.Add(9 * time.Hour), 10},
+		{day.Add(21*time.Hour + 15*time.Minute), 47},
+		{day.Add(23 * time.Hour), 4},
+	} {
+		if err := svc.Record(s.at, s.count); err != nil {
+			t.Fatalf("Record: %v", err)
+		}
+	}
+
+	stats, err := svc.GetDayStats(day.Add(12 * time.Hour))
+	if err != nil {
+		t.Fatalf("GetDayStats: %v", err)
+	}
+	if stats.Samples != 3 || stats.Peak != 47 || stats.PeakAt.Format("15:04") != "21:15" {
+		t.Fatalf("unexpected stats: %+v", stats)
+	}
+	if stats.Average != 61.0/3 {
+		t.Errorf("average = %v, want %v", stats.Average, 61.0/3)
+	}
+}
+
+func TestOnlineHistoryPrune(t *testing.T) {
+	db := initTrafficTestDB(t)
+	svc := &OnlineHistoryService{}
+	now := time.Now()
+
+	for _, at := range []time.Time{now.AddDate(0, 0, -31), now.AddDate(0, 0, -30).Add(-time.Minute), now} {
+		if err := svc.Record(at, 1); err != nil {
+			t.Fatalf("Record: %v", err)
+		}
+	}
+
+	deleted, err := svc.Prune(now.AddDate(0, 0, -30))
+	if err != nil {
+		t.Fatalf("Prune: %v", err)
+	}
+	if deleted != 2 {
+		t.Errorf("deleted = %d, want 2", deleted)
+	}
+	var count int64
+	if err := db.Model(&model.OnlineSample{}).Count(&count).Error; err != nil {
+		t.Fatalf("count: %v", err)
+	}
+	if count != 1 {
+		t.Errorf("expected only the recent sample to be kept, %d rows left", count)
+	}
+}
@@ -0,0 +1,65 @@
+package service
+
+import (
+	"sync"
+	"time"
+)
+
+// publicIPv4Services and publicIPv6Services answer with the address a
+// request came from; they are tried in order until one responds.
+var (
+	publicIPv4Services = []string{
+		"https://api4.ipify.org",
+		"https://ipv4.icanhazip.com",
+		"https://v4.api.ipinfo.io/ip",
+		"https://ipv4.myexternalip.com/raw",
+		"https://4.ident.me",
+		"https://check-host.net/ip",
+	}
+	publicIPv6Services = []string{
+		"https://api6.ipify.org",
+		"https://ipv6.icanhazip.com",
+		"https://v6.api.ipinfo.io/ip",
+		"https://ipv6.myexternalip.com/raw",
+		"https://6.ident.me",
+	}
+)
+
+// publicIPCacheTTL is how long a PublicIPs lookup is reused. Failed lookups
+// are cached as well, so a server without IPv6 isn't probed on every call.
+const publicIPCacheTTL = 30 * time.Minute
+
+var publicIPCache struct {
+	sync.Mutex
+	ipv4, ipv6 string
+	at         time.Time
+}
+
+// lookupPublicIP asks services in order and returns the first answer, or
+// "N/A" when none responds.
+func lookupPublicIP(services []string) string {
+	for _, url := range services {
+		if ip := getPublicIP(url); ip != "N/A" {
+			return ip
+		}
+	}
+	return "N/A"
+}
+
+// PublicIPs returns the server's public IPv4 and IPv6 addresses as seen
+// from outside, "N/A" for a family that couldn't be detected. Both are
+// looked up in parallel and cached for publicIPCacheTTL.
+func (s *ServerService) PublicIPs() (ipv4, ipv6 string) {
+	publicIPCache.Lock()
+	defer publicIPCache.Unlock()
+	if !publicIPCache.at.IsZero() && time.Since(publicIPCache.at) < publicIPCacheTTL {
+		return publicIPCache.ipv4, publicIPCache.ipv6
+	}
+
+	var wg sync.WaitGroup
+	wg.Go(func() { ipv4 = lookupPublicIP(publicIPv4Services) })
+	wg.Go(func() { ipv6 = lookupPublicIP(publicIPv6Services) })
+	wg.Wait()
+	publicIPCache.ipv4, publicIPCache.ipv6, publicIPCache.at = ipv4, ipv6, time.Now()
+	return ipv4, ipv6
+}
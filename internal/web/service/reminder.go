@@ -0,0 +1,154 @@
+package service
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/zixu5u/3xv/v3/internal/database"
+	"github.com/zixu5u/3xv/v3/internal/database/model"
+	"github.com/zixu5u/3xv/v3/internal/logger"
+	"github.com/zixu5u/3xv/v3/internal/util/common"
+
+	"github.com/robfig/cron/v3"
+)
+
+// ReminderTimeLayout is the format reminders are scheduled with, read in the
+// panel's time zone.
+const ReminderTimeLayout = "2006-01-02 15:04"
+
+// reminderCategory is the notification category reminders are sent under,
+// tgbot.NotifyReminder.
+const reminderCategory = "reminder"
+
+// maxReminderRunes caps the text of a reminder.
+const maxReminderRunes = 1000
+
+// overdueReminderDelay is how long after a panel start a reminder that came
+// due while the panel was down is sent, so the bot has started by then.
+const overdueReminderDelay = 30 * time.Second
+
+// reminderEntryPrefix is the prefix of the panel cron entry names of
+// reminders.
+const reminderEntryPrefix = "reminder/"
+
+// ReminderService stores one-off admin reminders and keeps them registered
+// with the panel cron until they are sent.
+type ReminderService struct{}
+
+// onceSchedule is a cron schedule that fires a single time.
+type onceSchedule struct {
+	at time.Time
+}
+
+// Next returns the firing time while it is still ahead of t; the zero time
+// tells the cron never to run the entry again.
+func (s onceSchedule) Next(t time.Time) time.Time {
+	if s.at.After(t) {
+		return s.at
+	}
+	return time.Time{}
+}
+
+// ParseReminderTime reads a "YYYY-MM-DD HH:MM" time in loc.
+func ParseReminderTime(value string, loc *time.Location) (time.Time, error) {
+	at, err := time.ParseInLocation(ReminderTimeLayout, strings.TrimSpace(value), loc)
+	if err != nil {
+		return time.Time{}, common.NewError("invalid reminder time, expected", ReminderTimeLayout+":", value)
+	}
+	return at, nil
+}
+
+// AddReminder stores a reminder for at and registers it right away. at must
+// lie in the future relative to now.
+func (s *ReminderService) AddReminder(at time.Time, text string, createdBy string, now time.Time) (*model.Reminder, error) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return nil, errors.New("empty reminder text")
+	}
+	if utf8.RuneCountInString(text) > maxReminderRunes {
+		return nil, common.NewError("reminder text is longer than", maxReminderRunes, "characters")
+	}
+	if !at.After(now) {
+		return nil, common.NewError("reminder time is in the past:", at.Format(ReminderTimeLayout))
+	}
+	reminder := &model.Reminder{
+		FireAt:    at.UnixMilli(),
+		Text:      text,
+		CreatedBy: createdBy,
+		CreatedAt: now.UnixMilli(),
+	}
+	if err := database.GetDB().Create(reminder).Error; err != nil {
+		return nil, err
+	}
+	s.addEntry(*reminder, now)
+	return reminder, nil
+}
+
+// GetReminders returns the pending reminders, soonest first.
+func (s *ReminderService) GetReminders() ([]model.Reminder, error) {
+	var reminders []model.Reminder
+	err := database.GetDB().Order("fire_at ASC, id ASC").Find(&reminders).Error
+	return reminders, err
+}
+
+// CancelReminder deletes a pending reminder. It reports whether there was
+// one with that id.
+func (s *ReminderService) CancelReminder(id int) (bool, error) {
+	result := database.GetDB().Delete(&model.Reminder{}, id)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	PanelCron().Remove(reminderEntryPrefix + strconv.Itoa(id))
+	return result.RowsAffected > 0, nil
+}
+
+// RegisterAll registers every pending reminder with the panel cron. It runs
+// on each panel start, so reminders survive restarts; those that came due
+// while the panel was down are sent shortly after the start.
+func (s *ReminderService) RegisterAll() error {
+	reminders, err := s.GetReminders()
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	for _, reminder := range reminders {
+		s.addEntry(reminder, now)
+	}
+	return nil
+}
+
+// reminderFireTime is when a reminder is sent: at its time, or after
+// overdueReminderDelay when that has already passed.
+func reminderFireTime(reminder model.Reminder, now time.Time) time.Time {
+	at := time.UnixMilli(reminder.FireAt)
+	if !at.After(now) {
+		return now.Add(overdueReminderDelay)
+	}
+	return at
+}
+
+func (s *ReminderService) addEntry(reminder model.Reminder, now time.Time) {
+	id := reminder.Id
+	PanelCron().Set(reminderEntryPrefix+strconv.Itoa(id), onceSchedule{at: reminderFireTime(reminder, now)},
+		cron.FuncJob(func() { s.fire(id) }))
+}
+
+// fire sends a reminder and deletes it. A reminder cancelled in the
+// meantime is skipped.
+func (s *ReminderService) fire(id int) {
+	PanelCron().Remove(reminderEntryPrefix + strconv.Itoa(id))
+	var reminder model.Reminder
+	if err := database.GetDB().First(&reminder, id).Error; err != nil {
+		logger.Infof("Reminder %d is gone, not sending it: %v", id, err)
+		return
+	}
+	if err := database.GetDB().Delete(&reminder).Error; err != nil {
+		logger.Warningf("Failed to delete reminder %d, not sending it to avoid repeats: %v", id, err)
+		return
+	}
+	logger.Infof("Sending reminder %d: %s", id, reminder.Text)
+	Notify(reminderCategory, reminder.Text)
+}
@@ -0,0 +1,110 @@
+package service
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/zixu5u/3xv/v3/internal/database"
+	"github.com/zixu5u/3xv/v3/internal/database/model"
+
+	"github.com/robfig/cron/v3"
+)
+
+func TestReminders(t *testing.T) {
+	dbDir := t.TempDir()
+	t.Setenv("XUI_DB_FOLDER", dbDir)
+	if err := database.InitDB(filepath.Join(dbDir, "x-ui.db")); err != nil {
+		t.Fatalf("InitDB: %v", err)
+	}
+	t.Cleanup(func() { _ = database.CloseDB() })
+	c := cron.New(cron.WithSeconds())
+	PanelCron().Attach(c)
+	t.Cleanup(func() { PanelCron().Attach(nil) })
+
+	svc := ReminderService{}
+	now := time.Date(2024, 5, 31, 12, 0, 0, 0, time.UTC)
+	if _, err := svc.AddReminder(now.Add(-time.Minute), "late", "1", now); err == nil {
+		t.Fatal("a reminder in the past must be rejected")
+	}
+	if _, err := svc.AddReminder(now.Add(time.Hour), "  ", "1", now); err == nil {
+		t.Fatal("an empty reminder must be rejected")
+	}
+
+	later, err := svc.AddReminder(now.Add(48*time.Hour), "Renew the TLS cert", "1", now)
+	if err != nil {
+		t.Fatalf("AddReminder: %v", err)
+	}
+	sooner, err := svc.AddReminder(now.Add(time.Hour), "Rotate keys", "1", now)
+	if err != nil {
+		t.Fatalf("AddReminder: %v", err)
+	}
+	list, err := svc.GetReminders()
+	if err != nil {
+		t.Fatalf("GetReminders: %v", err)
+	}
+	if len(list) != 2 || list[0].Id != sooner.Id || list[1].Id != later.Id {
+		t.Fatalf("unexpected reminders %+v", list)
+	}
+	if n := len(c.Entries()); n != 2 {
+		t.Fatalf("want 2 cron entries, got %d", n)
+	}
+
+	if removed, err := svc.CancelReminder(later.Id); err != nil || !removed {
+		t.Fatalf("CancelReminder = %v, %v; want true, nil", removed, err)
+	}
+	if removed, err := svc.CancelReminder(later.Id); err != nil || removed {
+		t.Fatalf("cancelling twice = %v, %v; want false, nil", removed, err)
+	}
+	if n := len(c.Entries()); n != 1 {
+		t.Fatalf("want 1 cron entry after cancel, got %d", n)
+	}
+
+	r := &recordingNotifier{}
+	SetNotifier(r)
+	t.Cleanup(func() { SetNotifier(nil) })
+	svc.fire(sooner.Id)
+	if len(r.sent) != 1 || r.sent[0] != "reminder: Rotate keys" {
+		t.Fatalf("unexpected notifications %v", r.sent)
+	}
+	if list, _ := svc.GetReminders(); len(list) != 0 {
+		t.Fatalf("a sent reminder must be deleted, got %+v", list)
+	}
+	if n := len(c.Entries()); n != 0 {
+		t.Fatalf("want no cron entries after sending, got %d", n)
+	}
+	// firing again, e.g. after a cancel raced the cron, sends nothing
+	svc.fire(sooner.Id)
+	if len(r.sent) != 1 {
+		t.Fatalf("a reminder must be sent once, got %v", r.sent)
+	}
+}
+
+func TestReminderFireTime(t *testing.T) {
+	now := time.Date(2024, 6, 1, 9, 0, 0, 0, time.UTC)
+	due := model.Reminder{FireAt: now.Add(time.Hour).UnixMilli()}
+	if got := reminderFireTime(due, now); !got.Equal(now.Add(time.Hour)) {
+		t.Fatalf("pending reminder fires at %v", got)
+	}
+	overdue := model.Reminder{FireAt: now.Add(-time.Hour).UnixMilli()}
+	if got := reminderFireTime(overdue, now); !got.Equal(now.Add(overdueReminderDelay)) {
+		t.Fatalf("overdue reminder fires at %v", got)
+	}
+	if next := (onceSchedule{at: now}).Next(now); !next.IsZero() {
+		t.Fatalf("a once schedule must not fire again, got %v", next)
+	}
+}
+
+func TestParseReminderTime(t *testing.T) {
+	loc := time.FixedZone("UTC+3", 3*60*60)
+	at, err := ParseReminderTime("2024-06-01 09:00", loc)
+	if err != nil {
+		t.Fatalf("ParseReminderTime: %v", err)
+	}
+	if want := time.Date(2024, 6, 1, 6, 0, 0, 0, time.UTC); !at.Equal(want) {
+		t.Fatalf("got %v, want %v", at, want)
+	}
+	if _, err := ParseReminderTime("tomorrow 9am", loc); err == nil {
+		t.Fatal("an invalid time must be rejected")
+	}
+}
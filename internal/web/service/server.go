@@ -477,24 +477,8 @@ func (s *ServerService) GetStatus(lastStatus *Status) *Status {
 	}
 
 	// IP fetching with caching
-	showIp4ServiceLists := []string{
-		"https://api4.ipify.org",
-		"https://ipv4.icanhazip.com",
-		"https://v4.api.ipinfo.io/ip",
-		"https://ipv4.myexternalip.com/raw",
-		"https://4.ident.me",
-		"https://check-host.net/ip",
-	}
-	showIp6ServiceLists := []string{
-		"https://api6.ipify.org",
-		"https://ipv6.icanhazip.com",
-		"https://v6.api.ipinfo.io/ip",
-		"https://ipv6.myexternalip.com/raw",
-		"https://6.ident.me",
-	}
-
 	if s.cachedIPv4 == "" {
-		for _, ip4Service := range showIp4ServiceLists {
+		for _, ip4Service := range publicIPv4Services {
 			s.cachedIPv4 = getPublicIP(ip4Service)
 			if s.cachedIPv4 != "N/A" {
 				break
@@ -503,7 +487,7 @@ func (s *ServerService) GetStatus(lastStatus *Status) *Status {
 	}
 
 	if s.cachedIPv6 == "" && !s.noIPv6 {
-		for _, ip6Service := range showIp6ServiceLists {
+		for _, ip6Service := range publicIPv6Services {
 			s.cachedIPv6 = getPublicIP(ip6Service)
 			if s.cachedIPv6 != "N/A" {
 				break
@@ -557,6 +541,14 @@ func (s *ServerService) AppendCpuSample(t time.Time, v float64) {
 	systemMetrics.append("cpu", t, v)
 }
 
+// CpuAverage returns the mean host CPU usage over the last window, from the
+// samples RefreshStatus records every 2s. ok is false while no sample falls
+// in the window, e.g. right after start.
+func (s *ServerService) CpuAverage(window time.Duration) (avg float64, ok bool) {
+	avg, n := systemMetrics.average("cpu", window, time.Now())
+	return avg, n > 0
+}
+
 // AppendStatusSample writes one tick of every metric we keep — CPU, memory
 // percent, network throughput (bytes/s), online client count, and the three
 // load averages. Called by RefreshStatus on the same @2s cadence as
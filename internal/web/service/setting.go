@@ -9,6 +9,7 @@ import (
 	"net/http"
 	"os"
 	"reflect"
+	"regexp"
 	"strconv"
 	"strings"
 	"time"
@@ -55,7 +56,49 @@ var defaultValueMap = map[string]string{
 	"tgBotBackup":                 "false",
 	"tgBotLoginNotify":            "true",
 	"tgCpu":                       "80",
+	"tgCpuWindow":                 "60",
 	"tgLang":                      "en-US",
+	"tgBotExtraBots":              "",
+	"tgBotFallbackWebhook":        "",
+	"tgBotChannelId":              "",
+	"tgBotChannelCategories":      "report",
+	"tgBotJsonLog":                "false",
+	"tgBotStartupNotify":          "true",
+	"tgTrafficUnits":              "binary",
+	"tgTrafficDecimals":           "2",
+	"tgNumberFormat":              "plain",
+	"tgSeverityEmoji":             "true",
+	"tgPinCritical":               "false",
+	"tgQuietStart":                "",
+	"tgQuietEnd":                  "",
+	"tgBotAdminMenu":              "",
+	"tgBotClientMenu":             "",
+	"tgReportFileThreshold":       "0",
+	"tgReportDisabledInbounds":    "true",
+	"tgStatusClientCounts":        "true",
+	"tgOnlineHistoryDays":         "30",
+	"tgTrafficHistoryDays":        "15",
+	"tgClientUsageInterval":       "15",
+	"tgClientUsageDays":           "7",
+	"tgClientLimitInterval":       "60",
+	"tgServerAddress":             "",
+	"tgButtonTTL":                 "60",
+	"tgXrayStartTimeout":          "10",
+	"tgXrayStartRetries":          "2",
+	"tgReportSparklines":          "3",
+	"tgCertExpiryDays":            "14",
+	"tgReportSections":            "online,traffic",
+	"tgReportNoInboundsNote":      "true",
+	"tgTrafficResetNotify":        "true",
+	"tgDebugStats":                "false",
+	"tgGeoMaxAge":                 "30",
+	"tgCommandAliases":            "",
+	"tgSilentCategories":          "report,reset",
+	"tgReportSummaryThreshold":    "100",
+	"tgReportSummaryTop":          "10",
+	"tgMutedInbounds":             "",
+	"panelRunning":                "false",
+	"blockedIps":                  "",
 	"twoFactorEnable":             "false",
 	"twoFactorToken":              "",
 	"subEnable":                   "true",
@@ -216,6 +259,7 @@ func (s *SettingService) GetAllSettingView() (*entity.AllSettingView, error) {
 	}
 	view := &entity.AllSettingView{AllSetting: *allSetting}
 	view.HasTgBotToken = secretConfigured(allSetting.TgBotToken)
+	view.HasTgBotExtraBots = secretConfigured(allSetting.TgBotExtraBots)
 	view.HasTwoFactorToken = secretConfigured(allSetting.TwoFactorToken)
 	view.HasLdapPassword = secretConfigured(allSetting.LdapPassword)
 	view.HasWarpSecret = secretConfigured(mustString(s.GetWarp()))
@@ -225,6 +269,7 @@ func (s *SettingService) GetAllSettingView() (*entity.AllSettingView, error) {
 		view.HasApiToken = apiTokenCount > 0
 	}
 	view.TgBotToken = ""
+	view.TgBotExtraBots = ""
 	view.TwoFactorToken = ""
 	view.LdapPassword = ""
 	return view, nil
@@ -456,6 +501,22 @@ func (s *SettingService) SetTgBotAPIServer(token string) error {
 	return s.setString("tgBotAPIServer", token)
 }
 
+func (s *SettingService) GetTgBotFallbackWebhook() (string, error) {
+	return s.getString("tgBotFallbackWebhook")
+}
+
+// GetTgBotChannelId returns the numeric ID of the channel reports and
+// selected notifications are also posted to. Empty disables it.
+func (s *SettingService) GetTgBotChannelId() (string, error) {
+	return s.getString("tgBotChannelId")
+}
+
+// GetTgBotChannelCategories returns the comma-separated notification
+// categories posted to the channel.
+func (s *SettingService) GetTgBotChannelCategories() (string, error) {
+	return s.getString("tgBotChannelCategories")
+}
+
 func (s *SettingService) GetTgBotChatId() (string, error) {
 	return s.getString("tgBotChatId")
 }
@@ -492,10 +553,255 @@ func (s *SettingService) GetTgCpu() (int, error) {
 	return s.getInt("tgCpu")
 }
 
+// GetTgCpuWindow returns the window CPU usage is averaged over before it is
+// compared with the alert threshold.
+func (s *SettingService) GetTgCpuWindow() (time.Duration, error) {
+	seconds, err := s.getInt("tgCpuWindow")
+	if err != nil {
+		return time.Minute, err
+	}
+	return time.Duration(seconds) * time.Second, nil
+}
+
 func (s *SettingService) GetTgLang() (string, error) {
 	return s.getString("tgLang")
 }
 
+func (s *SettingService) GetTgBotJsonLog() (bool, error) {
+	return s.getBool("tgBotJsonLog")
+}
+
+func (s *SettingService) GetTgBotStartupNotify() (bool, error) {
+	return s.getBool("tgBotStartupNotify")
+}
+
+func (s *SettingService) GetTgQuietStart() (string, error) {
+	return s.getString("tgQuietStart")
+}
+
+func (s *SettingService) GetTgQuietEnd() (string, error) {
+	return s.getString("tgQuietEnd")
+}
+
+func (s *SettingService) GetTgBotAdminMenu() (string, error) {
+	return s.getString("tgBotAdminMenu")
+}
+
+func (s *SettingService) GetTgBotClientMenu() (string, error) {
+	return s.getString("tgBotClientMenu")
+}
+
+func (s *SettingService) GetTgReportFileThreshold() (int, error) {
+	return s.getInt("tgReportFileThreshold")
+}
+
+// GetTgReportDisabledInbounds reports whether disabled inbounds are listed,
+// marked as disabled, in bot reports and status messages.
+func (s *SettingService) GetTgReportDisabledInbounds() (bool, error) {
+	return s.getBool("tgReportDisabledInbounds")
+}
+
+// GetTgStatusClientCounts reports whether status lists show how many
+// clients each inbound has.
+func (s *SettingService) GetTgStatusClientCounts() (bool, error) {
+	return s.getBool("tgStatusClientCounts")
+}
+
+// GetTgOnlineHistoryDays returns how many days of online client samples
+// are kept.
+func (s *SettingService) GetTgOnlineHistoryDays() (int, error) {
+	return s.getInt("tgOnlineHistoryDays")
+}
+
+// GetTgTrafficHistoryDays returns how many days of inbound traffic
+// snapshots are kept.
+func (s *SettingService) GetTgTrafficHistoryDays() (int, error) {
+	return s.getInt("tgTrafficHistoryDays")
+}
+
+// GetTgClientUsageInterval returns every how many minutes the traffic of
+// each client is sampled for /usage; 0 turns sampling off.
+func (s *SettingService) GetTgClientUsageInterval() (int, error) {
+	return s.getInt("tgClientUsageInterval")
+}
+
+// GetTgClientUsageDays returns how many days of per-client usage samples
+// are kept.
+func (s *SettingService) GetTgClientUsageDays() (int, error) {
+	return s.getInt("tgClientUsageDays")
+}
+
+// GetTgClientLimitInterval returns every how many seconds the next batch
+// of clients is checked for client limit alerts; 0 turns the alerts off.
+func (s *SettingService) GetTgClientLimitInterval() (int, error) {
+	return s.getInt("tgClientLimitInterval")
+}
+
+// GetTgServerAddress returns the public address /server shows instead of
+// the detected one, or "" to detect it.
+func (s *SettingService) GetTgServerAddress() (string, error) {
+	address, err := s.getString("tgServerAddress")
+	return strings.TrimSpace(address), err
+}
+
+// GetTgButtonTTL returns for how many minutes a bot button that changes
+// something can be used; 0 turns the check off.
+func (s *SettingService) GetTgButtonTTL() (int, error) {
+	return s.getInt("tgButtonTTL")
+}
+
+// GetTgXrayStartTimeout returns how many seconds a bot-triggered Xray
+// restart waits for the core to become ready before reporting it.
+func (s *SettingService) GetTgXrayStartTimeout() (int, error) {
+	return s.getInt("tgXrayStartTimeout")
+}
+
+// GetTgXrayStartRetries returns how many more times a bot-triggered Xray
+// restart is tried when the core fails to start or doesn't become ready.
+func (s *SettingService) GetTgXrayStartRetries() (int, error) {
+	return s.getInt("tgXrayStartRetries")
+}
+
+// GetTgReportSparklines returns for how many of the busiest inbounds the
+// report draws the daily traffic as a sparkline; 0 leaves it out.
+func (s *SettingService) GetTgReportSparklines() (int, error) {
+	return s.getInt("tgReportSparklines")
+}
+
+// GetTgCertExpiryDays returns how many days before a TLS certificate of an
+// inbound expires the bot starts warning about it; 0 disables the check.
+func (s *SettingService) GetTgCertExpiryDays() (int, error) {
+	return s.getInt("tgCertExpiryDays")
+}
+
+// GetTgReportSections returns the comma-separated sections of the report,
+// in the order they are shown.
+func (s *SettingService) GetTgReportSections() (string, error) {
+	return s.getString("tgReportSections")
+}
+
+// GetTgReportNoInboundsNote returns whether the admins are told once that
+// the report is skipped because the panel has no inbounds.
+func (s *SettingService) GetTgReportNoInboundsNote() (bool, error) {
+	return s.getBool("tgReportNoInboundsNote")
+}
+
+// GetTgTrafficResetNotify returns whether the admins are told when the
+// traffic of an inbound is reset by its period or a schedule.
+func (s *SettingService) GetTgTrafficResetNotify() (bool, error) {
+	return s.getBool("tgTrafficResetNotify")
+}
+
+// GetTgDebugStats returns whether /debugstats may show the panel's Go
+// runtime stats and goroutine profile.
+func (s *SettingService) GetTgDebugStats() (bool, error) {
+	return s.getBool("tgDebugStats")
+}
+
+// GetTgGeoMaxAge returns the age in days after which /geoinfo warns that
+// the GeoIP file is stale; 0 never warns.
+func (s *SettingService) GetTgGeoMaxAge() (int, error) {
+	return s.getInt("tgGeoMaxAge")
+}
+
+// GetTgCommandAliases returns the comma-separated alias=command shortcuts
+// of the bot commands, e.g. "s=status,r=reportnow".
+func (s *SettingService) GetTgCommandAliases() (string, error) {
+	return s.getString("tgCommandAliases")
+}
+
+// GetTgSilentCategories returns the comma-separated notification categories
+// that are sent without a sound.
+func (s *SettingService) GetTgSilentCategories() (string, error) {
+	return s.getString("tgSilentCategories")
+}
+
+// GetTgReportSummaryThreshold returns the inbound count above which the
+// report sends a summary instead of every inbound; 0 never does.
+func (s *SettingService) GetTgReportSummaryThreshold() (int, error) {
+	return s.getInt("tgReportSummaryThreshold")
+}
+
+// GetTgReportSummaryTop returns how many inbounds the report summary lists.
+func (s *SettingService) GetTgReportSummaryTop() (int, error) {
+	return s.getInt("tgReportSummaryTop")
+}
+
+// GetTgTrafficFormat returns the format used for traffic in bot messages.
+func (s *SettingService) GetTgTrafficFormat() (common.TrafficFormat, error) {
+	units, err := s.getString("tgTrafficUnits")
+	if err != nil {
+		return common.DefaultTrafficFormat, err
+	}
+	decimals, err := s.getInt("tgTrafficDecimals")
+	if err != nil {
+		return common.DefaultTrafficFormat, err
+	}
+	return common.NewTrafficFormat(units, decimals), nil
+}
+
+// GetTgPinCritical reports whether critical alerts are pinned in the admin
+// chats until they are resolved.
+func (s *SettingService) GetTgPinCritical() (bool, error) {
+	return s.getBool("tgPinCritical")
+}
+
+// GetTgSeverityEmoji reports whether bot messages start with a severity
+// emoji. When off, leading emoji are left out of messages altogether.
+func (s *SettingService) GetTgSeverityEmoji() (bool, error) {
+	return s.getBool("tgSeverityEmoji")
+}
+
+// GetTgNumberFormat returns how figures are written in bot messages.
+func (s *SettingService) GetTgNumberFormat() (common.NumberFormat, error) {
+	style, err := s.getString("tgNumberFormat")
+	if err != nil {
+		return common.NumberFormat{}, err
+	}
+	return common.NewNumberFormat(style), nil
+}
+
+// MarkPanelRunning flags the panel as running and reports whether the
+// previous run shut down cleanly, i.e. went through MarkPanelStopped.
+func (s *SettingService) MarkPanelRunning() (bool, error) {
+	wasRunning, err := s.getBool("panelRunning")
+	if err != nil {
+		return true, err
+	}
+	return !wasRunning, s.setBool("panelRunning", true)
+}
+
+// MarkPanelStopped records a clean shutdown.
+func (s *SettingService) MarkPanelStopped() error {
+	return s.setBool("panelRunning", false)
+}
+
+// GetBlockedIps returns the comma-separated panel-wide IP/CIDR block list
+// maintained by IpBlockService.
+func (s *SettingService) GetBlockedIps() (string, error) {
+	return s.getString("blockedIps")
+}
+
+func (s *SettingService) SetBlockedIps(value string) error {
+	return s.setString("blockedIps", value)
+}
+
+// GetTgMutedInbounds returns the comma-separated list of inbound tags whose
+// bot alerts are muted, maintained by InboundMuteService.
+func (s *SettingService) GetTgMutedInbounds() (string, error) {
+	return s.getString("tgMutedInbounds")
+}
+
+func (s *SettingService) SetTgMutedInbounds(value string) error {
+	return s.setString("tgMutedInbounds", value)
+}
+
+// GetTgBotExtraBots returns the JSON list of additional notification-only
+// bots. It holds bot tokens, so it is redacted from the settings view.
+func (s *SettingService) GetTgBotExtraBots() (string, error) {
+	return s.getString("tgBotExtraBots")
+}
+
 func (s *SettingService) GetTwoFactorEnable() (bool, error) {
 	return s.getBool("twoFactorEnable")
 }
@@ -899,12 +1205,20 @@ func (s *SettingService) GetLdapDefaultLimitIP() (int, error) {
 }
 
 func (s *SettingService) UpdateAllSetting(allSetting *entity.AllSetting) error {
+	token, err := NormalizeTgBotToken(allSetting.TgBotToken)
+	if err != nil {
+		return err
+	}
+	allSetting.TgBotToken = token
 	if err := s.preserveRedactedSecrets(allSetting); err != nil {
 		return err
 	}
 	if err := validateSettingsURLs(allSetting); err != nil {
 		return err
 	}
+	if err := validateTgBotChannelId(allSetting); err != nil {
+		return err
+	}
 	if err := allSetting.CheckValid(); err != nil {
 		return err
 	}
@@ -933,6 +1247,13 @@ func (s *SettingService) preserveRedactedSecrets(allSetting *entity.AllSetting)
 		}
 		allSetting.TgBotToken = value
 	}
+	if strings.TrimSpace(allSetting.TgBotExtraBots) == "" {
+		value, err := s.GetTgBotExtraBots()
+		if err != nil {
+			return err
+		}
+		allSetting.TgBotExtraBots = value
+	}
 	if strings.TrimSpace(allSetting.LdapPassword) == "" {
 		value, err := s.GetLdapPassword()
 		if err != nil {
@@ -965,12 +1286,55 @@ func validateSettingsURLs(allSetting *entity.AllSetting) error {
 		}
 		allSetting.TgBotAPIServer = u
 	}
+	if allSetting.TgBotFallbackWebhook != "" {
+		u, err := SanitizeHTTPURL(allSetting.TgBotFallbackWebhook)
+		if err != nil {
+			return common.NewError("telegram fallback webhook URL is invalid:", err)
+		}
+		allSetting.TgBotFallbackWebhook = u
+	}
+	return nil
+}
+
+// validateTgBotChannelId checks the channel ID is numeric. The bot posts with
+// the numeric ID, as shown by /id when forwarded a channel post, so a
+// @username is rejected rather than silently never delivered.
+func validateTgBotChannelId(allSetting *entity.AllSetting) error {
+	allSetting.TgBotChannelId = strings.TrimSpace(allSetting.TgBotChannelId)
+	if allSetting.TgBotChannelId == "" {
+		return nil
+	}
+	if _, err := strconv.ParseInt(allSetting.TgBotChannelId, 10, 64); err != nil {
+		return common.NewError("telegram channel ID must be numeric, like -1001234567890:", allSetting.TgBotChannelId)
+	}
 	return nil
 }
 
+// tgBotTokenPattern is the token shape the Telegram client library accepts:
+// the bot's numeric ID, a colon and a 35-character secret.
+var tgBotTokenPattern = regexp.MustCompile(`^\d+:[\w-]{35}$`)
+
+// NormalizeTgBotToken trims whitespace from a bot token and checks its shape,
+// so a mistyped or badly pasted token is rejected when it is saved rather
+// than failing at bot start with only a log line. An empty token is left
+// empty.
+func NormalizeTgBotToken(token string) (string, error) {
+	token = strings.TrimSpace(token)
+	if token == "" || tgBotTokenPattern.MatchString(token) {
+		return token, nil
+	}
+	return "", common.NewError("telegram bot token is invalid: expected <bot ID>:<35 characters>, as issued by @BotFather")
+}
+
 func (s *SettingService) UpdateSecret(key string, value string) error {
 	switch key {
-	case "tgBotToken", "ldapPassword", "twoFactorToken":
+	case "tgBotToken":
+		token, err := NormalizeTgBotToken(value)
+		if err != nil {
+			return err
+		}
+		return s.saveSetting(key, token)
+	case "tgBotExtraBots", "ldapPassword", "twoFactorToken":
 		return s.saveSetting(key, strings.TrimSpace(value))
 	default:
 		return common.NewError("secret key is not replaceable:", key)
@@ -0,0 +1,91 @@
+package service
+
+import (
+	"time"
+
+	"github.com/zixu5u/3xv/v3/internal/database"
+	"github.com/zixu5u/3xv/v3/internal/database/model"
+	"github.com/zixu5u/3xv/v3/internal/util/common"
+
+	"gorm.io/gorm"
+)
+
+// MaskedSettingValue stands in for the value of a secret setting wherever
+// it would otherwise be shown or recorded.
+const MaskedSettingValue = "********"
+
+// secretSettings hold credentials, which are never shown outside the
+// settings form. Webhook URLs count as credentials: they usually carry a
+// token in the path or query.
+var secretSettings = map[string]bool{
+	"secret":                   true,
+	"apiToken":                 true,
+	"tgBotToken":               true,
+	"tgBotExtraBots":           true,
+	"tgBotFallbackWebhook":     true,
+	"externalTrafficInformURI": true,
+	"twoFactorToken":           true,
+	"ldapPassword":             true,
+	"warp":                     true,
+	"nord":                     true,
+}
+
+// IsSecretSetting reports whether key holds a credential.
+func IsSecretSetting(key string) bool {
+	return secretSettings[key]
+}
+
+// GetSettingValue returns the value of the setting key, or its default when
+// it was never saved. Unknown keys are an error.
+func (s *SettingService) GetSettingValue(key string) (string, error) {
+	if _, ok := defaultValueMap[key]; !ok {
+		return "", common.NewErrorf("unknown setting: %s", key)
+	}
+	return s.getString(key)
+}
+
+// ChangeSetting stores value as the setting key and records the change, with
+// the value it replaces, in the same transaction. The caller validates
+// value. Secret values are masked in the record.
+func (s *SettingService) ChangeSetting(key string, value string, changedBy string) (string, error) {
+	old, err := s.GetSettingValue(key)
+	if err != nil {
+		return "", err
+	}
+	change := &model.SettingChange{
+		Key:       key,
+		OldValue:  old,
+		NewValue:  value,
+		ChangedBy: changedBy,
+		ChangedAt: time.Now().UnixMilli(),
+	}
+	if IsSecretSetting(key) {
+		change.OldValue, change.NewValue = MaskedSettingValue, MaskedSettingValue
+	}
+	err = database.GetDB().Transaction(func(tx *gorm.DB) error {
+		setting := &model.Setting{}
+		err := tx.Where("key = ?", key).First(setting).Error
+		switch {
+		case database.IsNotFound(err):
+			err = tx.Create(&model.Setting{Key: key, Value: value}).Error
+		case err == nil:
+			err = tx.Model(setting).Update("value", value).Error
+		}
+		if err != nil {
+			return err
+		}
+		return tx.Create(change).Error
+	})
+	if err != nil {
+		return "", err
+	}
+	return old, nil
+}
+
+// GetSettingChanges returns the last limit recorded changes of key, newest
+// first.
+func (s *SettingService) GetSettingChanges(key string, limit int) ([]model.SettingChange, error) {
+	var changes []model.SettingChange
+	err := database.GetDB().Where("key = ?", key).Order("id DESC").Limit(limit).Find(&changes).Error
+	return changes, err
+}
@@ -0,0 +1,49 @@
+package service
+
+import "testing"
+
+func TestChangeSettingRecordsChange(t *testing.T) {
+	setupSettingTestDB(t)
+	s := &SettingService{}
+
+	old, err := s.ChangeSetting("tgCpu", "90", "telegram:1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if old != "80" {
+		t.Fatalf("old value = %q, want the default", old)
+	}
+	if got, _ := s.GetTgCpu(); got != 90 {
+		t.Fatalf("tgCpu = %d, want 90", got)
+	}
+	if _, err := s.ChangeSetting("tgCpu", "70", "telegram:2"); err != nil {
+		t.Fatal(err)
+	}
+	changes, err := s.GetSettingChanges("tgCpu", 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 2 || changes[0].OldValue != "90" || changes[0].NewValue != "70" || changes[0].ChangedBy != "telegram:2" {
+		t.Fatalf("changes = %+v", changes)
+	}
+
+	if _, err := s.ChangeSetting("tgBotToken", "123:abc", "telegram:1"); err != nil {
+		t.Fatal(err)
+	}
+	changes, _ = s.GetSettingChanges("tgBotToken", 1)
+	if len(changes) != 1 || changes[0].NewValue != MaskedSettingValue {
+		t.Fatalf("secret change was recorded in clear: %+v", changes)
+	}
+	for _, key := range []string{"tgBotFallbackWebhook", "externalTrafficInformURI"} {
+		if !IsSecretSetting(key) {
+			t.Errorf("%s can carry a token and must be masked", key)
+		}
+	}
+
+	if _, err := s.ChangeSetting("noSuchSetting", "1", "telegram:1"); err == nil {
+		t.Fatal("unknown setting was changed")
+	}
+	if _, err := s.GetSettingValue("noSuchSetting"); err == nil {
+		t.Fatal("unknown setting was read")
+	}
+}
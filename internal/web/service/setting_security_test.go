@@ -91,3 +91,50 @@ func TestSanitizePublicHTTPURLBlocksPrivateAddressUnlessAllowed(t *testing.T) {
 		t.Fatalf("allowPrivate result = %q, %v", got, err)
 	}
 }
+
+func TestNormalizeTgBotToken(t *testing.T) {
+	const valid = "123456789:AAHdqTcvCH1vGWJxfSeofSAs0K5PALDsaw-"
+	cases := []struct {
+		in      string
+		want    string
+		wantErr bool
+	}{
+		{"", "", false},
+		{"  ", "", false},
+		{valid, valid, false},
+		{" \t" + valid + "\n", valid, false},
+		{"AAHdqTcvCH1vGWJxfSeofSAs0K5PALDsaw-", "", true},
+		{"bot123456789:AAHdqTcvCH1vGWJxfSeofSAs0K5PALDsaw-", "", true},
+		{"123456789:AAHdqTcvCH1vGWJxfSeofSAs0K5PALDsaw", "", true},
+		{"123456789:AAHdqTcvCH1vGWJxfSeofSAs0K5 ALDsaw-", "", true},
+	}
+	for _, c := range cases {
+		got, err := NormalizeTgBotToken(c.in)
+		if (err != nil) != c.wantErr || got != c.want {
+			t.Errorf("NormalizeTgBotToken(%q) = %q, %v; want %q, error %v", c.in, got, err, c.want, c.wantErr)
+		}
+	}
+}
+
+func TestUpdateAllSettingNormalizesTgBotToken(t *testing.T) {
+	setupSettingTestDB(t)
+	s := &SettingService{}
+	view, err := s.GetAllSettingView()
+	if err != nil {
+		t.Fatal(err)
+	}
+	settings := &view.AllSetting
+
+	settings.TgBotToken = "not-a-token"
+	if err := s.UpdateAllSetting(settings); err == nil {
+		t.Fatal("malformed token was accepted")
+	}
+
+	settings.TgBotToken = " 123456789:AAHdqTcvCH1vGWJxfSeofSAs0K5PALDsaw- "
+	if err := s.UpdateAllSetting(settings); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := s.GetTgBotToken(); got != "123456789:AAHdqTcvCH1vGWJxfSeofSAs0K5PALDsaw-" {
+		t.Fatalf("tg token = %q, want it trimmed", got)
+	}
+}
@@ -12,7 +12,6 @@ import (
 	"os"
 	"regexp"
 	"slices"
-	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -95,7 +94,8 @@ const (
 )
 
 // LoginAttempt contains safe metadata for panel login notifications.
-// It intentionally does not include attempted passwords.
+// It intentionally does not include attempted passwords. Values are raw; the
+// bot escapes them for its message format.
 type LoginAttempt struct {
 	Username string
 	IP       string
@@ -107,12 +107,23 @@ type LoginAttempt struct {
 // Tgbot provides business logic for Telegram bot integration.
 // It handles bot commands, user interactions, and status reporting via Telegram.
 type Tgbot struct {
-	inboundService service.InboundService
-	clientService  service.ClientService
-	settingService service.SettingService
-	serverService  service.ServerService
-	xrayService    service.XrayService
-	lastStatus     *service.Status
+	inboundService   service.InboundService
+	clientService    service.ClientService
+	settingService   service.SettingService
+	serverService    service.ServerService
+	xrayService      service.XrayService
+	onlineHistory    service.OnlineHistoryService
+	ipBlockService   service.IpBlockService
+	schedules        service.InboundScheduleService
+	trafficHistory   service.TrafficHistoryService
+	clientUsage      service.ClientUsageService
+	historyRetention service.HistoryRetentionService
+	inboundMute      service.InboundMuteService
+	reminders        service.ReminderService
+	outbox           service.NotificationQueueService
+	alertHistory     service.AlertHistoryService
+	trafficSync      service.XrayTrafficSyncService
+	lastStatus       *service.Status
 }
 
 // NewTgbot creates a new Tgbot instance.
@@ -183,7 +194,7 @@ func (t *Tgbot) Start(i18nFS embed.FS) error {
 	StopBot()
 
 	// Initialize hash storage to store callback queries
-	hashStorage = global.NewHashStorage(20 * time.Minute)
+	hashStorage = global.NewHashStorage(hashStorageTTL)
 
 	// Initialize worker pool for concurrent message processing (max 10 concurrent handlers)
 	messageWorkerPool = make(chan struct{}, 10)
@@ -201,6 +212,8 @@ func (t *Tgbot) Start(i18nFS embed.FS) error {
 
 	t.SetHostname()
 
+	t.loadRuntimeSettings()
+
 	// Get Telegram bot token
 	tgBotToken, err := t.settingService.GetTgBotToken()
 	if err != nil || tgBotToken == "" {
@@ -209,27 +222,23 @@ func (t *Tgbot) Start(i18nFS embed.FS) error {
 	}
 
 	// Get Telegram bot chat ID(s)
+	// An unreadable list keeps the chats of the previous start, if any, so
+	// the bot can still reach someone; the startup check reports the failure.
+	var parsedAdminIds []int64
 	tgBotID, err := t.settingService.GetTgBotChatId()
 	if err != nil {
-		logger.Warning("Failed to get Telegram bot chat ID:", err)
-		return err
-	}
-
-	parsedAdminIds := make([]int64, 0)
-	// Parse admin IDs from comma-separated string
-	if tgBotID != "" {
-		for adminID := range strings.SplitSeq(tgBotID, ",") {
-			id, err := strconv.ParseInt(adminID, 10, 64)
-			if err != nil {
-				logger.Warning("Failed to parse admin ID from Telegram bot chat ID:", err)
-				return err
-			}
-			parsedAdminIds = append(parsedAdminIds, int64(id))
+		logger.Errorf("[tgbot] setting tgBotChatId unreadable, keeping %d known admin chats: %v", len(adminChatIds()), err)
+		parsedAdminIds = adminChatIds()
+	} else {
+		var invalidIds []string
+		parsedAdminIds, invalidIds = parseAdminIds(tgBotID)
+		if len(invalidIds) > 0 {
+			logger.Warningf("Ignoring invalid Telegram chat IDs in settings: %s", strings.Join(invalidIds, ", "))
 		}
+		tgBotMutex.Lock()
+		adminIds = parsedAdminIds
+		tgBotMutex.Unlock()
 	}
-	tgBotMutex.Lock()
-	adminIds = parsedAdminIds
-	tgBotMutex.Unlock()
 
 	// Get Telegram bot proxy URL
 	tgBotProxy, err := t.settingService.GetTgBotProxy()
@@ -240,9 +249,11 @@ func (t *Tgbot) Start(i18nFS embed.FS) error {
 	// Fall back to the panel-wide egress bridge when no dedicated bot proxy is
 	// set. Resolved once at bot start: if Xray comes up later, the bot keeps
 	// its direct connection until it is restarted.
+	proxyFromEgress := false
 	if tgBotProxy == "" {
 		if egress := t.settingService.PanelEgressProxyURL(); egress != "" && isSupportedBotProxyScheme(egress) {
 			tgBotProxy = egress
+			proxyFromEgress = true
 		}
 	}
 
@@ -260,6 +271,7 @@ func (t *Tgbot) Start(i18nFS embed.FS) error {
 	}
 
 	t.trySetBotCommands(bot)
+	t.startExtraBots(tgBotProxy, tgBotAPIServer)
 
 	// Start receiving Telegram bot messages
 	tgBotMutex.Lock()
@@ -272,9 +284,15 @@ func (t *Tgbot) Start(i18nFS embed.FS) error {
 
 	// Start the scheduler for daily reports
 	scheduleTime, err := t.settingService.GetTgbotRuntime()
-	if err == nil && len(scheduleTime) > 0 {
-		t.StartScheduler(scheduleTime)
+	if err != nil {
+		logger.Errorf("[tgbot] setting tgRunTime unreadable, using %q: %v", defaultReportRunTime, err)
+		scheduleTime = defaultReportRunTime
 	}
+	t.StartScheduler(scheduleTime)
+	t.startHeartbeat()
+	t.startOutbox()
+	t.recordBotConfig(tgBotToken, parsedAdminIds, scheduleTime, tgBotProxy, proxyFromEgress, tgBotAPIServer)
+	service.SetNotifier(t)
 
 	return nil
 }
@@ -286,13 +304,14 @@ func (t *Tgbot) trySetBotCommands(bot *telego.Bot) {
 		}
 	}()
 
+	commands := []telego.BotCommand{
+		{Command: "start", Description: t.I18nBot("tgbot.commands.startDesc")},
+		{Command: "help", Description: t.I18nBot("tgbot.commands.helpDesc")},
+		{Command: "status", Description: t.I18nBot("tgbot.commands.statusDesc")},
+		{Command: "id", Description: t.I18nBot("tgbot.commands.idDesc")},
+	}
 	err := bot.SetMyCommands(context.Background(), &telego.SetMyCommandsParams{
-		Commands: []telego.BotCommand{
-			{Command: "start", Description: t.I18nBot("tgbot.commands.startDesc")},
-			{Command: "help", Description: t.I18nBot("tgbot.commands.helpDesc")},
-			{Command: "status", Description: t.I18nBot("tgbot.commands.statusDesc")},
-			{Command: "id", Description: t.I18nBot("tgbot.commands.idDesc")},
-		},
+		Commands: append(commands, t.aliasBotCommands()...),
 	})
 	if err != nil {
 		logger.Warning("Failed to set bot commands:", err)
@@ -399,6 +418,10 @@ func (t *Tgbot) SetHostname() {
 func (t *Tgbot) Stop() {
 	StopBot()
 	t.StopScheduler()
+	stopExtraBots()
+	stopQuietQueue()
+	stopHeartbeat()
+	stopOutbox()
 	logger.Info("Stop Telegram receiver ...")
 	tgBotMutex.Lock()
 	adminIds = nil
@@ -429,10 +452,16 @@ func StopBot() {
 		botWG.Wait()
 		logger.Info("Telegram bot successfully stopped.")
 	}
+
+	// Commands and callbacks run outside the handler; let them finish so a
+	// backup or export isn't cut off halfway.
+	drainHandlers(handlerDrainTimeout)
 }
 
-// encodeQuery encodes the query string if it's longer than 64 characters.
+// encodeQuery signs the query string when it is a destructive action and
+// encodes it if it's longer than 64 characters.
 func (t *Tgbot) encodeQuery(query string) string {
+	query = callbackSigning.Load().signCallback(query, time.Now())
 	// NOTE: we only need to hash for more than 64 chars
 	if len(query) <= 64 {
 		return query
@@ -482,7 +511,7 @@ func (t *Tgbot) isSingleWord(text string) bool {
 func (t *Tgbot) buildRichStatus() string {
 	cached, found := t.getCachedServerStats()
 	if found {
-		return cached
+		return t.withTrafficFreshness(cached)
 	}
 
 	// Get system status
@@ -508,42 +537,44 @@ func (t *Tgbot) buildRichStatus() string {
 	sb.WriteString(fmt.Sprintf("🍪面板版本:%s\r\n", config.GetVersion()))
 
 	// Memory and online clients
-	sb.WriteString(fmt.Sprintf("📋 RAM:%s/%s\r\n", common.FormatTraffic(int64(status.Mem.Current)), common.FormatTraffic(int64(status.Mem.Total))))
+	sb.WriteString(fmt.Sprintf("📋 RAM:%s/%s\r\n", formatTraffic(int64(status.Mem.Current)), formatTraffic(int64(status.Mem.Total))))
 	onlines := service.XrayProcess().GetOnlineClients()
 	sb.WriteString(fmt.Sprintf("🌐 在线客户:%d\r\n", len(onlines)))
 	sb.WriteString(fmt.Sprintf("🔹 TCP:%d\r\n", status.TcpCount))
 	sb.WriteString(fmt.Sprintf("🔸 UDP:%d\r\n", status.UdpCount))
 	totalTraffic := status.NetTraffic.Sent + status.NetTraffic.Recv
 	sb.WriteString(fmt.Sprintf("🚦 流量:%s (↑%s,↓%s)\r\n\r\n",
-		common.FormatTraffic(int64(totalTraffic)),
-		common.FormatTraffic(int64(status.NetTraffic.Sent)),
-		common.FormatTraffic(int64(status.NetTraffic.Recv))))
+		formatTraffic(int64(totalTraffic)),
+		formatTraffic(int64(status.NetTraffic.Sent)),
+		formatTraffic(int64(status.NetTraffic.Recv))))
 
 	// Inbound nodes details
-	inbounds, _ := t.inboundService.GetAllInbounds()
-	for _, in := range inbounds {
-		if !in.Enable {
-			continue
-		}
+	inbounds, err := t.inboundService.GetAllInbounds()
+	if err == nil && len(inbounds) == 0 {
+		sb.WriteString(t.I18nBot("tgbot.messages.noInbounds"))
+	}
+	counts := t.inboundClientCounts()
+	for _, in := range reportInbounds(inbounds, t.reportDisabledInbounds()) {
 		total := in.Up + in.Down
 		expire := "♾️"
 		if in.ExpiryTime > 0 {
 			expire = time.Unix(in.ExpiryTime/1000, 0).Format("2006-01-02")
 		}
 
-		sb.WriteString(fmt.Sprintf("🆔节点名称:%s\r\n", in.Remark))
+		sb.WriteString(fmt.Sprintf("🆔节点名称:%s\r\n", reportRemark(in)))
 		sb.WriteString(fmt.Sprintf("🔗节点类型:%s\r\n", in.Protocol))
 		sb.WriteString(fmt.Sprintf("🎯节点端口:%d\r\n", in.Port))
-		sb.WriteString(fmt.Sprintf("⏫上行流量↑:%s\r\n", common.FormatTraffic(in.Up)))
-		sb.WriteString(fmt.Sprintf("⏬下行流量↓:%s\r\n", common.FormatTraffic(in.Down)))
-		sb.WriteString(fmt.Sprintf("📊整体流量:%s\r\n", common.FormatTraffic(total)))
-		sb.WriteString(fmt.Sprintf("❄️流量限制:%s\r\n", common.FormatTraffic(in.Total)))
+		sb.WriteString(fmt.Sprintf("⏫上行流量↑:%s\r\n", formatTraffic(in.Up)))
+		sb.WriteString(fmt.Sprintf("⏬下行流量↓:%s\r\n", formatTraffic(in.Down)))
+		sb.WriteString(fmt.Sprintf("📊整体流量:%s\r\n", formatTraffic(total)))
+		sb.WriteString(t.inboundClientsLine(counts, in.Id))
+		sb.WriteString(fmt.Sprintf("❄️流量限制:%s\r\n", formatTraffic(in.Total)))
 		sb.WriteString(fmt.Sprintf("⏰到期时间:%s\r\n\r\n", expire))
 	}
 
 	result := sb.String()
 	t.setCachedServerStats(result)
-	return result
+	return t.withTrafficFreshness(result)
 }
 
 // getPublicIP gets the server's public IP address
@@ -0,0 +1,162 @@
+package tgbot
+
+import (
+	"slices"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/zixu5u/3xv/v3/internal/logger"
+
+	"github.com/mymmrac/telego"
+	tu "github.com/mymmrac/telego/telegoutil"
+)
+
+// maxTrackedAlerts caps how many alerts are remembered for acknowledgement;
+// the button of an older alert only answers that it expired.
+const maxTrackedAlerts = 200
+
+// alertAck is an alert sent to the admins and who acknowledged it, if
+// anyone did yet.
+type alertAck struct {
+	category string
+	sentAt   time.Time
+	ackedBy  string
+	ackedAt  time.Time
+}
+
+// alertAcks remembers the recent alerts that carry an acknowledge button.
+// Each admin gets a copy of the alert; the first tap on any copy counts for
+// all of them.
+var alertAcks struct {
+	sync.Mutex
+	nextId atomic.Int64
+	alerts map[int64]*alertAck
+	order  []int64
+}
+
+// trackAlert remembers an alert of category and returns its id.
+func trackAlert(category string, now time.Time) int64 {
+	id := alertAcks.nextId.Add(1)
+	alertAcks.Lock()
+	defer alertAcks.Unlock()
+	if alertAcks.alerts == nil {
+		alertAcks.alerts = make(map[int64]*alertAck)
+	}
+	alertAcks.alerts[id] = &alertAck{category: category, sentAt: now}
+	alertAcks.order = append(alertAcks.order, id)
+	if len(alertAcks.order) > maxTrackedAlerts {
+		delete(alertAcks.alerts, alertAcks.order[0])
+		alertAcks.order = alertAcks.order[1:]
+	}
+	return id
+}
+
+// ackAlert records that by acknowledged alert id at now. It returns the
+// alert as it stands afterwards, whether this call acknowledged it, and
+// false as the last value when the alert is unknown or expired.
+func ackAlert(id int64, by string, now time.Time) (alertAck, bool, bool) {
+	alertAcks.Lock()
+	defer alertAcks.Unlock()
+	alert, ok := alertAcks.alerts[id]
+	if !ok {
+		return alertAck{}, false, false
+	}
+	if alert.ackedBy != "" {
+		return *alert, false, true
+	}
+	alert.ackedBy = by
+	alert.ackedAt = now
+	return *alert, true, true
+}
+
+// ackCallbackData is the callback data of the acknowledge button of alert id.
+func ackCallbackData(id int64) string {
+	return "alert_ack " + strconv.FormatInt(id, 10)
+}
+
+// withAckButton adds an acknowledge button to the alert keyboard, below the
+// buttons the alert already has. Other reply markups are left alone.
+func (t *Tgbot) withAckButton(id int64, replyMarkup []telego.ReplyMarkup) []telego.ReplyMarkup {
+	button := tu.InlineKeyboardButton(t.I18nBot("tgbot.buttons.ackAlert")).WithCallbackData(ackCallbackData(id))
+	if len(replyMarkup) == 0 {
+		return []telego.ReplyMarkup{tu.InlineKeyboard(tu.InlineKeyboardRow(button))}
+	}
+	keyboard, ok := replyMarkup[0].(*telego.InlineKeyboardMarkup)
+	if !ok {
+		return replyMarkup
+	}
+	rows := append(slices.Clone(keyboard.InlineKeyboard), tu.InlineKeyboardRow(button))
+	return []telego.ReplyMarkup{tu.InlineKeyboard(rows...)}
+}
+
+// markAcked replaces the acknowledge button with data in keyboard by a
+// button reading label, keeping every other button.
+func markAcked(keyboard *telego.InlineKeyboardMarkup, data string, label string) *telego.InlineKeyboardMarkup {
+	rows := make([][]telego.InlineKeyboardButton, 0, len(keyboard.InlineKeyboard))
+	for _, row := range keyboard.InlineKeyboard {
+		newRow := make([]telego.InlineKeyboardButton, len(row))
+		for i, button := range row {
+			if button.CallbackData == data {
+				button.Text = label
+			}
+			newRow[i] = button
+		}
+		rows = append(rows, newRow)
+	}
+	return tu.InlineKeyboard(rows...)
+}
+
+// ackerName is how an admin is named in acknowledgements.
+func ackerName(user telego.User) string {
+	if user.Username != "" {
+		return "@" + user.Username
+	}
+	if user.FirstName != "" {
+		return user.FirstName
+	}
+	return strconv.FormatInt(user.ID, 10)
+}
+
+// acknowledgeAlert handles a tap on an alert's acknowledge button: the first
+// admin to tap it is recorded in the logs, the button shows who took it and
+// the other admins are told.
+func (t *Tgbot) acknowledgeAlert(callbackQuery *telego.CallbackQuery, arg string) {
+	id, err := strconv.ParseInt(arg, 10, 64)
+	if err != nil {
+		t.sendCallbackAnswerTgBot(callbackQuery.ID, t.I18nBot("tgbot.answers.ackExpired"))
+		return
+	}
+	chatId := callbackQuery.Message.GetChat().ID
+	by := ackerName(callbackQuery.From)
+	alert, acked, ok := ackAlert(id, by, time.Now())
+	if !ok {
+		t.sendCallbackAnswerTgBot(callbackQuery.ID, t.I18nBot("tgbot.answers.ackExpired"))
+		return
+	}
+	label := t.I18nBot("tgbot.buttons.ackedAlert", "By=="+alert.ackedBy, "Time=="+alert.ackedAt.Format("15:04"))
+	if message, isMessage := callbackQuery.Message.(*telego.Message); isMessage && message.ReplyMarkup != nil {
+		t.editMessageCallbackTgBot(chatId, message.GetMessageID(), markAcked(message.ReplyMarkup, ackCallbackData(id), label))
+	}
+	if !acked {
+		t.sendCallbackAnswerTgBot(callbackQuery.ID, t.I18nBot("tgbot.answers.ackAlready", "By=="+alert.ackedBy))
+		return
+	}
+
+	logger.Infof("Alert %d (%s) sent at %s acknowledged by %s (%d)",
+		id, alert.category, alert.sentAt.Format(time.DateTime), by, callbackQuery.From.ID)
+	logBotEvent(botEvent{Event: "alert_ack", ChatID: callbackQuery.From.ID, Category: alert.category,
+		Latency: alert.ackedAt.Sub(alert.sentAt)})
+	t.sendCallbackAnswerTgBot(callbackQuery.ID, t.I18nBot("tgbot.answers.ackDone"))
+
+	notice := t.I18nBot("tgbot.messages.alertAcked",
+		"By=="+escapeField(by),
+		"Category=="+escapeField(alert.category),
+		"Time=="+alert.sentAt.Format("15:04"))
+	for _, adminId := range adminChatIds() {
+		if adminId != chatId {
+			t.SendMsgToTgbot(adminId, notice)
+		}
+	}
+}
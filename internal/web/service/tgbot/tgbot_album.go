@@ -0,0 +1,125 @@
+package tgbot
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"slices"
+	"strconv"
+	"time"
+	"unicode/utf8"
+
+	"github.com/zixu5u/3xv/v3/internal/logger"
+	"github.com/zixu5u/3xv/v3/internal/util/common"
+
+	"github.com/mymmrac/telego"
+	tu "github.com/mymmrac/telego/telegoutil"
+	"github.com/skip2/go-qrcode"
+)
+
+const (
+	// albumMaxItems is the most photos Telegram accepts in one media group.
+	albumMaxItems = 10
+	// albumMaxLinks caps the links sent as albums; the rest are only counted,
+	// since the individual links list shows them all.
+	albumMaxLinks = 20
+	// captionMaxRunes is Telegram's limit for a photo caption.
+	captionMaxRunes = 1024
+)
+
+// albumChunks splits links into groups that fit in one media group each.
+func albumChunks(links []string) [][]string {
+	return slices.Collect(slices.Chunk(links, albumMaxItems))
+}
+
+// linkCaption renders a share link as a copyable caption. It reports false
+// when the link is too long to be a caption.
+func linkCaption(link string) (string, bool) {
+	if utf8.RuneCountInString(link) > captionMaxRunes {
+		return "", false
+	}
+	return "<code>" + html.EscapeString(link) + "</code>", true
+}
+
+// sendClientLinkAlbum sends the individual links of a client as QR codes
+// captioned with their link, grouped into albums, so each link arrives
+// together with its QR code. A group Telegram refuses is resent as separate
+// QR and link messages.
+func (t *Tgbot) sendClientLinkAlbum(chatId int64, email string, flavor string) {
+	links, err := t.fetchIndividualLinks(email, flavor)
+	if err != nil {
+		t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.answers.errorOperation")+"\r\n"+err.Error())
+		return
+	}
+	if len(links) == 0 {
+		t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.noResult"))
+		return
+	}
+
+	shown := links[:min(len(links), albumMaxLinks)]
+	for i, chunk := range albumChunks(shown) {
+		if i > 0 {
+			time.Sleep(500 * time.Millisecond)
+		}
+		if err := t.sendLinkAlbum(chatId, email, i*albumMaxItems, chunk); err != nil {
+			logger.Warning("Sending link album failed, sending QR codes and links separately:", err)
+			t.sendLinkAlbumFallback(chatId, email, i*albumMaxItems, chunk)
+		}
+	}
+	if more := len(links) - len(shown); more > 0 {
+		t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.messages.albumMore", "Count=="+strconv.Itoa(more)))
+	}
+	t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.messages.linkFlavorOther", "Flavor=="+t.linkFlavorLabel(flavor)),
+		t.linkFlavorKeyboard("album", email, flavor))
+}
+
+// albumFileName names the QR image of the link at index.
+func albumFileName(email string, index int) string {
+	return fmt.Sprintf("%s-%d.png", email, index+1)
+}
+
+// sendLinkAlbum sends links as one media group, or as a single captioned
+// photo when there is only one, since a media group needs at least two.
+// offset is the index of the first link among all of the client's links.
+func (t *Tgbot) sendLinkAlbum(chatId int64, email string, offset int, links []string) error {
+	media := make([]telego.InputMedia, 0, len(links))
+	for i, link := range links {
+		caption, ok := linkCaption(link)
+		if !ok {
+			return common.NewError("link is too long for a caption:", albumFileName(email, offset+i))
+		}
+		png, err := qrcode.Encode(link, qrcode.Medium, 320)
+		if err != nil {
+			return err
+		}
+		media = append(media, tu.MediaPhoto(tu.FileFromBytes(png, albumFileName(email, offset+i))).
+			WithCaption(caption).WithParseMode("HTML"))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	var err error
+	if photo, ok := media[0].(*telego.InputMediaPhoto); ok && len(media) == 1 {
+		_, err = bot.SendPhoto(ctx, tu.Photo(tu.ID(chatId), photo.Media).
+			WithCaption(photo.Caption).WithParseMode("HTML"))
+	} else {
+		_, err = bot.SendMediaGroup(ctx, tu.MediaGroup(tu.ID(chatId), media...))
+	}
+	recordSend(err)
+	return err
+}
+
+// sendLinkAlbumFallback sends every link as a QR document followed by the
+// link itself. A link too long for a QR code is sent as text only.
+func (t *Tgbot) sendLinkAlbumFallback(chatId int64, email string, offset int, links []string) {
+	for i, link := range links {
+		if png, err := qrcode.Encode(link, qrcode.Medium, 320); err == nil {
+			document := tu.Document(
+				tu.ID(chatId),
+				tu.FileFromBytes(png, albumFileName(email, offset+i)),
+			)
+			_, _ = bot.SendDocument(context.Background(), document)
+		}
+		t.SendMsgToTgbot(chatId, "<code>"+html.EscapeString(link)+"</code>")
+	}
+}
@@ -0,0 +1,170 @@
+package tgbot
+
+import (
+	"errors"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/zixu5u/3xv/v3/internal/database/model"
+	"github.com/zixu5u/3xv/v3/internal/logger"
+	"github.com/zixu5u/3xv/v3/internal/web/service"
+
+	"github.com/mymmrac/telego"
+	tu "github.com/mymmrac/telego/telegoutil"
+)
+
+const (
+	// defaultEventsShown is how many events /events covers without an
+	// argument.
+	defaultEventsShown = 100
+	maxEventsShown     = 1000
+	// eventGroupsPerPage is how many day and type groups one page lists.
+	eventGroupsPerPage = 8
+	// maxEventEmails caps the clients one group names; the rest are only
+	// counted.
+	maxEventEmails = 10
+)
+
+// eventTypeMessages are the messages of the alert event types, in the order
+// a day lists them.
+var eventTypeMessages = []struct {
+	eventType string
+	key       string
+}{
+	{service.AlertEventDisabled, "tgbot.messages.eventsDisabled"},
+	{service.AlertEventTrafficExhausted, "tgbot.messages.eventsTrafficExhausted"},
+	{service.AlertEventExpired, "tgbot.messages.eventsExpired"},
+	{service.AlertEventTrafficNear, "tgbot.messages.eventsTrafficNear"},
+	{service.AlertEventExpiring, "tgbot.messages.eventsExpiring"},
+}
+
+// eventGroup is the events of one type fired on one day.
+type eventGroup struct {
+	Day    string
+	Type   string
+	Emails []string
+}
+
+// parseEventsArgs reads the optional number of events of "/events [n]".
+func parseEventsArgs(args []string) (int, error) {
+	switch len(args) {
+	case 0:
+		return defaultEventsShown, nil
+	case 1:
+		n, err := strconv.Atoi(args[0])
+		if err != nil || n < 1 || n > maxEventsShown {
+			return 0, errors.New("invalid number of events")
+		}
+		return n, nil
+	default:
+		return 0, errors.New("too many arguments")
+	}
+}
+
+// groupEvents groups events, newest first, by the day they fired in loc
+// and then by type in the order of eventTypeMessages. A client is named
+// once per group.
+func groupEvents(events []model.AlertEvent, loc *time.Location) []eventGroup {
+	var days []string
+	byDay := make(map[string]map[string][]string)
+	for _, event := range events {
+		day := time.UnixMilli(event.FiredAt).In(loc).Format("2006-01-02")
+		if byDay[day] == nil {
+			byDay[day] = make(map[string][]string)
+			days = append(days, day)
+		}
+		emails := byDay[day][event.Type]
+		if !slices.Contains(emails, event.Email) {
+			byDay[day][event.Type] = append(emails, event.Email)
+		}
+	}
+	var groups []eventGroup
+	for _, day := range days {
+		for _, m := range eventTypeMessages {
+			if emails := byDay[day][m.eventType]; len(emails) > 0 {
+				groups = append(groups, eventGroup{Day: day, Type: m.eventType, Emails: emails})
+			}
+		}
+	}
+	return groups
+}
+
+// eventGroupLine renders a group: its type, how many clients and which.
+func (t *Tgbot) eventGroupLine(group eventGroup) string {
+	key := ""
+	for _, m := range eventTypeMessages {
+		if m.eventType == group.Type {
+			key = m.key
+		}
+	}
+	names := make([]string, 0, maxEventEmails)
+	for _, email := range group.Emails[:min(len(group.Emails), maxEventEmails)] {
+		names = append(names, "<code>"+escapeField(email)+"</code>")
+	}
+	line := t.I18nBot(key, "Count=="+strconv.Itoa(len(group.Emails)), "Emails=="+strings.Join(names, ", "))
+	if more := len(group.Emails) - maxEventEmails; more > 0 {
+		line += " " + t.I18nBot("tgbot.messages.eventsMore", "Count=="+strconv.Itoa(more))
+	}
+	return line
+}
+
+// sendEvents implements /events: a recap of the last n client alerts that
+// fired, from the alert history rather than the chat, grouped by day and
+// type, one page at a time. With messageID the existing recap is edited in
+// place.
+func (t *Tgbot) sendEvents(chatId int64, n int, page int, messageID ...int) {
+	events, err := t.alertHistory.Recent(n)
+	if err != nil {
+		logger.Warning("Failed to get alert events:", err)
+		t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.wentWrong"))
+		return
+	}
+	if len(events) == 0 {
+		t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.messages.eventsNone",
+			"Days=="+strconv.Itoa(int(service.AlertEventRetention/(24*time.Hour)))))
+		return
+	}
+	groups := groupEvents(events, t.timeLocation())
+
+	pages := (len(groups) + eventGroupsPerPage - 1) / eventGroupsPerPage
+	page = max(1, min(page, pages))
+	start := (page - 1) * eventGroupsPerPage
+	end := min(start+eventGroupsPerPage, len(groups))
+
+	var output strings.Builder
+	output.WriteString(t.I18nBot("tgbot.messages.eventsHeader", "Count=="+strconv.Itoa(len(events))))
+	day := ""
+	for _, group := range groups[start:end] {
+		if group.Day != day {
+			day = group.Day
+			output.WriteString("\r\n\r\n📅 <b>" + day + "</b>")
+		}
+		output.WriteString("\r\n" + t.eventGroupLine(group))
+	}
+	if pages > 1 {
+		output.WriteString("\r\n\r\n" + t.I18nBot("tgbot.messages.dormantPage",
+			"Page=="+strconv.Itoa(page),
+			"Pages=="+strconv.Itoa(pages)))
+	}
+
+	nStr := strconv.Itoa(n)
+	var nav []telego.InlineKeyboardButton
+	if page > 1 {
+		nav = append(nav, tu.InlineKeyboardButton("⬅️").WithCallbackData(t.encodeQuery("events_page "+nStr+" "+strconv.Itoa(page-1))))
+	}
+	if page < pages {
+		nav = append(nav, tu.InlineKeyboardButton("➡️").WithCallbackData(t.encodeQuery("events_page "+nStr+" "+strconv.Itoa(page+1))))
+	}
+	if len(nav) == 0 {
+		t.SendMsgToTgbot(chatId, output.String())
+		return
+	}
+	keyboard := tu.InlineKeyboard(nav)
+	if len(messageID) > 0 {
+		t.editMessageTgBot(chatId, messageID[0], output.String(), keyboard)
+		return
+	}
+	t.SendMsgToTgbot(chatId, output.String(), keyboard)
+}
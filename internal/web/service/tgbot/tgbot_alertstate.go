@@ -0,0 +1,159 @@
+package tgbot
+
+import (
+	"html"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/zixu5u/3xv/v3/internal/logger"
+)
+
+// Entry types /alertstate clear accepts besides a single tag or setting.
+const (
+	alertStateMutes    = "mute"
+	alertStateSettings = "setting"
+)
+
+// settingThrottle is a setting whose unreadable alerts are held back until
+// Until; failures before then are only logged.
+type settingThrottle struct {
+	Name  string
+	Until time.Time
+}
+
+// throttledSettings lists the settings still inside their alert interval at
+// now, ordered by name.
+func throttledSettings(now time.Time) []settingThrottle {
+	settingAlertsMutex.Lock()
+	defer settingAlertsMutex.Unlock()
+	var list []settingThrottle
+	for name, last := range settingAlerts {
+		if until := last.Add(settingAlertInterval); until.After(now) {
+			list = append(list, settingThrottle{Name: name, Until: until})
+		}
+	}
+	slices.SortFunc(list, func(a, b settingThrottle) int { return strings.Compare(a.Name, b.Name) })
+	return list
+}
+
+// clearSettingAlerts forgets when name was last alerted, or every setting
+// when name is empty, so the next failure alerts right away. It returns the
+// number of entries removed.
+func clearSettingAlerts(name string) int {
+	settingAlertsMutex.Lock()
+	defer settingAlertsMutex.Unlock()
+	if name == "" {
+		n := len(settingAlerts)
+		clear(settingAlerts)
+		return n
+	}
+	if _, ok := settingAlerts[name]; !ok {
+		return 0
+	}
+	delete(settingAlerts, name)
+	return 1
+}
+
+// heldQuietNotifications returns what quiet hours hold back right now.
+func heldQuietNotifications() (held int, dropped int, report bool) {
+	quietQueue.Lock()
+	defer quietQueue.Unlock()
+	return len(quietQueue.messages), quietQueue.dropped, quietQueue.report
+}
+
+// sendAlertState implements /alertstate: every rule currently keeping an
+// alert from going out.
+func (t *Tgbot) sendAlertState(chatId int64) {
+	var output strings.Builder
+	output.WriteString(t.I18nBot("tgbot.messages.alertStateHeader"))
+	empty := true
+
+	muted, err := t.inboundMute.GetMutedInbounds()
+	if err != nil {
+		output.WriteString(t.I18nBot("tgbot.messages.muteFailed", "Error=="+html.EscapeString(err.Error())))
+	} else if len(muted) > 0 {
+		empty = false
+		output.WriteString(t.I18nBot("tgbot.messages.alertStateMuted"))
+		for _, tag := range muted {
+			output.WriteString("\r\n<code>" + escapeField(tag) + "</code>")
+		}
+		output.WriteString("\r\n")
+	}
+
+	if throttled := throttledSettings(time.Now()); len(throttled) > 0 {
+		empty = false
+		output.WriteString(t.I18nBot("tgbot.messages.alertStateSettings"))
+		for _, s := range throttled {
+			output.WriteString(t.I18nBot("tgbot.messages.alertStateSetting",
+				"Name=="+escapeField(s.Name),
+				"Until=="+s.Until.Format("15:04")))
+		}
+	}
+
+	if held, dropped, report := heldQuietNotifications(); held > 0 || report {
+		empty = false
+		until := "-"
+		if end := t.quietUntil(); !end.IsZero() {
+			until = end.Format("15:04")
+		}
+		output.WriteString(t.I18nBot("tgbot.messages.alertStateQuiet",
+			"Count=="+strconv.Itoa(held+dropped),
+			"Until=="+until))
+		if report {
+			output.WriteString(t.I18nBot("tgbot.messages.alertStateQuietReport"))
+		}
+	}
+
+	if empty {
+		output.WriteString(t.I18nBot("tgbot.messages.alertStateEmpty"))
+	} else {
+		output.WriteString(t.I18nBot("tgbot.messages.alertStateClearHint"))
+	}
+	t.SendMsgToTgbot(chatId, output.String())
+}
+
+// clearAlertState implements /alertstate clear. target is empty for every
+// entry, a type (mute or setting) for all entries of it, or a setting name
+// or inbound tag for a single one. Held quiet-hours notifications aren't
+// suppressed, only deferred, and are left alone.
+func (t *Tgbot) clearAlertState(chatId int64, target string, requestedBy int64) {
+	cleared := 0
+	var err error
+	switch strings.ToLower(target) {
+	case "", "all":
+		cleared = clearSettingAlerts("")
+		var n int
+		n, err = t.inboundMute.ClearMutedInbounds()
+		cleared += n
+	case alertStateMutes, "mutes", "muted":
+		cleared, err = t.inboundMute.ClearMutedInbounds()
+	case alertStateSettings, "settings":
+		cleared = clearSettingAlerts("")
+	default:
+		cleared = clearSettingAlerts(target)
+		if cleared == 0 {
+			var removed bool
+			removed, err = t.inboundMute.UnmuteInbound(target)
+			if removed {
+				cleared = 1
+			}
+		}
+	}
+	if err != nil {
+		logBotEvent(botEvent{Event: "alert_state_clear", ChatID: requestedBy, Command: "alertstate", Err: err})
+		t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.messages.muteFailed", "Error=="+html.EscapeString(err.Error())))
+		return
+	}
+	if cleared == 0 {
+		if target == "" {
+			target = "all"
+		}
+		t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.messages.alertStateNothing", "Target=="+escapeField(target)))
+		return
+	}
+	logger.Infof("Alert state %q cleared by Telegram user %d: %d entries", target, requestedBy, cleared)
+	logBotEvent(botEvent{Event: "alert_state_clear", ChatID: requestedBy, Command: "alertstate"})
+	t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.messages.alertStateCleared", "Count=="+strconv.Itoa(cleared)))
+}
@@ -0,0 +1,138 @@
+package tgbot
+
+import (
+	"errors"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+	"sync/atomic"
+
+	"github.com/zixu5u/3xv/v3/internal/logger"
+
+	"github.com/mymmrac/telego"
+)
+
+// maxCommandAliases caps the tgCommandAliases shortcuts, well below the 100
+// commands Telegram shows in the menu.
+const maxCommandAliases = 20
+
+// botCommands are the commands answerCommand handles. An alias can only
+// point to one of them and can't take the name of any.
+var botCommands = []string{
+	"addchat", "alertstate", "blockip", "blocklist", "boost", "botconfig",
+	"botstats", "cancelreminder", "certs", "connections", "cronstatus",
+	"debugstats", "dormant", "errors", "events", "expiring", "export",
+	"geoinfo", "getsetting", "help", "history", "id", "import", "inbound",
+	"iplimit", "iplogging", "listchats", "loglevel", "move", "mute",
+	"muted", "panel", "perf", "pool", "previewconfig", "prunelogs",
+	"quotalink", "reconcile", "reloadrules", "reloadsettings", "remind",
+	"reminders", "removechat", "rename", "reportconfig", "reportnow",
+	"reportpreview", "reportschedule", "restart", "restartxray", "schedule",
+	"server", "sessions", "setsetting", "showinbound", "start", "status",
+	"subscription", "terminate", "test", "testnotify", "thresholds",
+	"trend", "unblockip", "unboost", "unmute", "usage", "whois",
+}
+
+// commandAliasName is what Telegram accepts as a command name.
+var commandAliasName = regexp.MustCompile(`^[a-z0-9_]{1,32}$`)
+
+// commandAliases maps the tgCommandAliases shortcuts to their commands. It
+// is loaded by Start; nil means there are none.
+var commandAliases atomic.Pointer[map[string]string]
+
+// parseCommandAliases reads a comma-separated list of alias=command pairs,
+// e.g. "s=status,r=reportnow". A leading slash on either side is dropped.
+// An alias must be a valid command name, point to one of botCommands and
+// not collide with any of them or with another alias.
+func parseCommandAliases(value string) (map[string]string, error) {
+	aliases := make(map[string]string)
+	for part := range strings.SplitSeq(value, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		alias, command, ok := strings.Cut(part, "=")
+		if !ok {
+			return nil, errors.New("expected alias=command, got " + part)
+		}
+		alias = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(alias), "/"))
+		command = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(command), "/"))
+		switch {
+		case !commandAliasName.MatchString(alias):
+			return nil, errors.New("alias " + alias + " must be 1-32 lowercase letters, digits or underscores")
+		case slices.Contains(botCommands, alias):
+			return nil, errors.New("alias " + alias + " is already a command")
+		case !slices.Contains(botCommands, command):
+			return nil, errors.New("alias " + alias + " points to unknown command " + command)
+		}
+		if _, ok := aliases[alias]; ok {
+			return nil, errors.New("alias " + alias + " is defined twice")
+		}
+		aliases[alias] = command
+	}
+	if len(aliases) > maxCommandAliases {
+		return nil, errors.New("too many aliases")
+	}
+	return aliases, nil
+}
+
+// commandAliasList normalizes a tgCommandAliases value for /setsetting.
+func commandAliasList(value string) (string, error) {
+	aliases, err := parseCommandAliases(value)
+	if err != nil {
+		return "", err
+	}
+	pairs := make([]string, 0, len(aliases))
+	for _, alias := range slices.Sorted(maps.Keys(aliases)) {
+		pairs = append(pairs, alias+"="+aliases[alias])
+	}
+	return strings.Join(pairs, ","), nil
+}
+
+// loadCommandAliases reads tgCommandAliases. An invalid value is logged and
+// no alias is used, since the web UI saves it without checking.
+func (t *Tgbot) loadCommandAliases() {
+	value, err := t.settingService.GetTgCommandAliases()
+	if err != nil {
+		logger.Warning("Failed to get Telegram bot command aliases:", err)
+		commandAliases.Store(nil)
+		return
+	}
+	aliases, err := parseCommandAliases(value)
+	if err != nil {
+		logger.Warning("Ignoring invalid Telegram bot command aliases:", err)
+		commandAliases.Store(nil)
+		return
+	}
+	commandAliases.Store(&aliases)
+}
+
+// resolveCommandAlias returns the command alias is a shortcut for, or
+// command itself when it isn't one.
+func resolveCommandAlias(command string) string {
+	aliases := commandAliases.Load()
+	if aliases == nil {
+		return command
+	}
+	if target, ok := (*aliases)[command]; ok {
+		return target
+	}
+	return command
+}
+
+// aliasBotCommands returns the menu entries of the aliases, sorted.
+func (t *Tgbot) aliasBotCommands() []telego.BotCommand {
+	aliases := commandAliases.Load()
+	if aliases == nil {
+		return nil
+	}
+	commands := make([]telego.BotCommand, 0, len(*aliases))
+	for _, alias := range slices.Sorted(maps.Keys(*aliases)) {
+		commands = append(commands, telego.BotCommand{
+			Command:     alias,
+			Description: t.I18nBot("tgbot.commands.aliasDesc", "Command=="+(*aliases)[alias]),
+		})
+	}
+	return commands
+}
@@ -0,0 +1,80 @@
+package tgbot
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/zixu5u/3xv/v3/internal/logger"
+)
+
+// parseBoostArgs reads the email and size of /boost, such as
+// "alice 10GB". A bare size is in GB, like typed traffic limits.
+func parseBoostArgs(args []string) (email string, bytes int64, err error) {
+	if len(args) != 2 {
+		return "", 0, errors.New("expected an email and a size")
+	}
+	bytes, err = parseTrafficSize(args[1])
+	if err != nil {
+		return "", 0, err
+	}
+	if bytes == 0 {
+		return "", 0, errors.New("boost must be greater than zero")
+	}
+	return args[0], bytes, nil
+}
+
+// telegramActor names a Telegram user in the records the service keeps,
+// such as who granted a boost.
+func telegramActor(userId int64) string {
+	return "telegram:" + strconv.FormatInt(userId, 10)
+}
+
+// grantTrafficBoost implements /boost: a temporary addition to a client's
+// traffic limit that lasts until the client's inbound resets its traffic.
+func (t *Tgbot) grantTrafficBoost(chatId int64, email string, bytes int64, requestedBy int64) {
+	needRestart, err := t.clientService.BoostTrafficLimit(&t.inboundService, email, bytes, telegramActor(requestedBy))
+	if needRestart {
+		t.xrayService.SetToNeedRestart()
+	}
+	if err != nil {
+		logger.Warning("Failed to boost traffic limit:", err)
+		t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.messages.boostFailed", "Email=="+escapeField(email), "Error=="+escapeField(err.Error())))
+		return
+	}
+	logBotEvent(botEvent{Event: "traffic_boost", ChatID: requestedBy, Command: "boost"})
+	msg := t.I18nBot("tgbot.messages.boostGranted", "Email=="+escapeField(email), "Boost=="+formatTraffic(bytes))
+	if traffic, err := t.inboundService.GetClientTrafficByEmail(email); err == nil && traffic != nil {
+		msg += t.clientInfoMsg(traffic, true, false, false, true, true, false)
+	}
+	t.SendMsgToTgbot(chatId, msg)
+}
+
+// revokeTrafficBoost implements /unboost.
+func (t *Tgbot) revokeTrafficBoost(chatId int64, email string, requestedBy int64) {
+	needRestart, err := t.clientService.RevokeTrafficBoost(&t.inboundService, email, "revoked by "+telegramActor(requestedBy))
+	if needRestart {
+		t.xrayService.SetToNeedRestart()
+	}
+	if err != nil {
+		logger.Warning("Failed to revoke traffic boost:", err)
+		t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.messages.boostFailed", "Email=="+escapeField(email), "Error=="+escapeField(err.Error())))
+		return
+	}
+	logBotEvent(botEvent{Event: "traffic_unboost", ChatID: requestedBy, Command: "unboost"})
+	t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.messages.boostRevoked", "Email=="+escapeField(email)))
+}
+
+// formatClientLimit renders a client's traffic limit, with the part a
+// temporary boost adds shown separately.
+func (t *Tgbot) formatClientLimit(email string, total int64) string {
+	if total == 0 {
+		return t.I18nBot("tgbot.unlimited")
+	}
+	boost, err := t.clientService.GetTrafficBoost(email)
+	if err != nil || boost == nil || boost.Bytes >= total {
+		return formatTraffic(total)
+	}
+	return t.I18nBot("tgbot.messages.limitWithBoost",
+		"Base=="+formatTraffic(total-boost.Bytes),
+		"Boost=="+formatTraffic(boost.Bytes))
+}
@@ -0,0 +1,279 @@
+package tgbot
+
+import (
+	"encoding/json"
+	"errors"
+	"html"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/zixu5u/3xv/v3/internal/logger"
+
+	"github.com/mymmrac/telego"
+	"github.com/robfig/cron/v3"
+)
+
+// Notification categories an extra bot can subscribe to.
+const (
+	NotifyReport       = "report"   // scheduled status report
+	NotifyLogin        = "login"    // panel login attempts
+	NotifyCPU          = "cpu"      // CPU threshold alerts
+	NotifyXray         = "xray"     // Xray down and not restartable (critical)
+	NotifySettings     = "settings" // bot settings unreadable (critical)
+	NotifyReminder     = "reminder" // admin reminders set with /remind (critical)
+	NotifyClients      = "clients"  // clients near or past their traffic limit or expiry
+	NotifyCertExpiring = "certs"    // inbound TLS certificates near or past expiry
+	NotifyTrafficReset = "reset"    // automatic traffic resets of inbounds
+)
+
+// extraBotConfig is one entry of the tgBotExtraBots setting, e.g.
+//
+//	[{"name":"customers","token":"123:abc","chatId":"-1001234","categories":["report"],"runTime":"0 0 9 * * *"}]
+//
+// runTime uses the same syntax as tgRunTime, a cron expression or an
+// "HH:MM" clock time; an empty runTime falls back to the primary bot's
+// tgRunTime.
+type extraBotConfig struct {
+	Name       string   `json:"name"`
+	Token      string   `json:"token"`
+	ChatId     string   `json:"chatId"`
+	Categories []string `json:"categories"`
+	RunTime    string   `json:"runTime"`
+}
+
+// effectiveRunTime is the bot's report schedule: its own runTime, or the
+// primary bot's when it has none.
+func (cfg extraBotConfig) effectiveRunTime(defaultRunTime string) string {
+	if cfg.RunTime == "" {
+		return defaultRunTime
+	}
+	return cfg.RunTime
+}
+
+// extraBot is a notification-only bot: it never receives updates, so
+// commands and callbacks stay on the primary bot and customers in its
+// chats can't reach the admin menu.
+type extraBot struct {
+	name       string
+	bot        *telego.Bot
+	chatIds    []int64
+	categories []string
+	runTime    string
+}
+
+var (
+	// extraBotsMutex protects extraBots
+	extraBotsMutex sync.Mutex
+	// extraBots holds the running notification-only bots
+	extraBots []*extraBot
+	// extraBotsCron runs the extra bots' own report schedules
+	extraBotsCron *cron.Cron
+)
+
+// parseExtraBots decodes the tgBotExtraBots setting. An empty value means
+// a single-bot setup.
+func parseExtraBots(raw string) ([]extraBotConfig, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return nil, nil
+	}
+	var configs []extraBotConfig
+	if err := json.Unmarshal([]byte(raw), &configs); err != nil {
+		return nil, err
+	}
+	return configs, nil
+}
+
+// parseChatIds parses a comma-separated list of Telegram chat IDs.
+func parseChatIds(raw string) ([]int64, error) {
+	ids := make([]int64, 0)
+	for part := range strings.SplitSeq(raw, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		id, err := strconv.ParseInt(part, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// startExtraBots creates the notification-only bots configured in
+// tgBotExtraBots. A broken entry is logged and skipped so it can't take
+// the primary bot down with it.
+func (t *Tgbot) startExtraBots(proxyUrl string, apiServerUrl string) {
+	stopExtraBots()
+
+	raw, err := t.settingService.GetTgBotExtraBots()
+	if err != nil {
+		logger.Warning("Failed to get extra Telegram bots:", err)
+		return
+	}
+	configs, err := parseExtraBots(raw)
+	if err != nil {
+		logger.Warning("Failed to parse extra Telegram bots:", err)
+		return
+	}
+	defaultRunTime, err := t.settingService.GetTgbotRuntime()
+	if err != nil {
+		defaultRunTime = t.settingFallback("tgRunTime", err, defaultReportRunTime)
+	}
+
+	started := make([]*extraBot, 0, len(configs))
+	for i, cfg := range configs {
+		name := cfg.Name
+		if name == "" {
+			name = "#" + strconv.Itoa(i+1)
+		}
+		if cfg.Token == "" {
+			logger.Warningf("Extra Telegram bot %s has no token, skipping", name)
+			continue
+		}
+		chatIds, err := parseChatIds(cfg.ChatId)
+		if err != nil || len(chatIds) == 0 {
+			logger.Warningf("Extra Telegram bot %s has no valid chat ID, skipping: %v", name, err)
+			continue
+		}
+		b, err := t.NewBot(cfg.Token, proxyUrl, apiServerUrl)
+		if err != nil {
+			logger.Warningf("Failed to initialize extra Telegram bot %s: %v", name, err)
+			continue
+		}
+		started = append(started, &extraBot{
+			name:       name,
+			bot:        b,
+			chatIds:    chatIds,
+			categories: cfg.Categories,
+			runTime:    cfg.effectiveRunTime(defaultRunTime),
+		})
+	}
+	if len(started) == 0 {
+		return
+	}
+
+	loc, err := t.settingService.GetTimeLocation()
+	if err != nil {
+		loc = time.Local
+	}
+	c := cron.New(cron.WithLocation(loc), cron.WithSeconds())
+	for _, eb := range started {
+		if eb.wants(NotifyReport) && !IsReportScheduleOff(eb.runTime) {
+			if _, err := c.AddFunc(clockReportSpec(eb.runTime), func() { t.sendExtraBotReport(eb) }); err != nil {
+				logger.Warningf("Extra Telegram bot %s: failed to schedule report %q: %v", eb.name, eb.runTime, err)
+			}
+		}
+		logger.Infof("Extra Telegram bot %s started for %v", eb.name, eb.categories)
+	}
+	c.Start()
+
+	extraBotsMutex.Lock()
+	extraBots = started
+	extraBotsCron = c
+	extraBotsMutex.Unlock()
+}
+
+// stopExtraBots stops the extra bots' report schedules and forgets them.
+func stopExtraBots() {
+	extraBotsMutex.Lock()
+	c := extraBotsCron
+	extraBotsCron = nil
+	extraBots = nil
+	extraBotsMutex.Unlock()
+
+	if c != nil {
+		<-c.Stop().Done()
+	}
+}
+
+// wants reports whether the bot is subscribed to the category.
+func (eb *extraBot) wants(category string) bool {
+	return slices.Contains(eb.categories, category)
+}
+
+// notifyExtraBots sends msg to every extra bot subscribed to category.
+func (t *Tgbot) notifyExtraBots(category string, msg string) {
+	extraBotsMutex.Lock()
+	bots := slices.Clone(extraBots)
+	extraBotsMutex.Unlock()
+
+	for _, eb := range bots {
+		if !eb.wants(category) {
+			continue
+		}
+		fanOut(eb.chatIds, func(chatId int64) error {
+			start := time.Now()
+			err := t.sendNotificationVia(eb.bot, chatId, category, msg)
+			logBotEvent(botEvent{Event: "notification", Bot: eb.name, ChatID: chatId, Category: category, Latency: time.Since(start), Err: err})
+			return err
+		}).logSummary("Notification via bot " + eb.name)
+	}
+}
+
+// SendNotification sends msg to the primary bot's admins and to every extra
+// bot subscribed to category. replyMarkup only goes to the primary admins,
+// since extra bots don't receive callbacks. Non-critical categories are held
+// during quiet hours and delivered when the window ends.
+func (t *Tgbot) SendNotification(category string, msg string, replyMarkup ...telego.ReplyMarkup) {
+	if !t.IsRunning() {
+		return
+	}
+	if t.holdIfQuiet(queuedNotification{category: category, msg: msg, replyMarkup: replyMarkup}) {
+		logBotEvent(botEvent{Event: "alert", Category: category, Outcome: "held"})
+		return
+	}
+	t.deliverNotification(category, msg, replyMarkup...)
+}
+
+// Notify implements service.Notifier, so services that can't import the
+// bot can send notifications through service.Notify. category may be one of
+// the Notify* constants or any other name extra bots subscribe to.
+// Reminders carry the admin's plain text and are escaped here.
+func (t *Tgbot) Notify(category string, text string) {
+	if category == NotifyReminder {
+		text = t.I18nBot("tgbot.messages.reminderFired", "Text=="+html.EscapeString(text))
+	}
+	t.SendNotification(category, text)
+}
+
+// deliverNotification sends a notification without consulting quiet hours.
+// Critical ones reach the admins through the outbox, which retries them
+// until delivered; extra bots and the channel are always best effort.
+func (t *Tgbot) deliverNotification(category string, msg string, replyMarkup ...telego.ReplyMarkup) {
+	logBotEvent(botEvent{Event: "alert", Category: category})
+	recordNotification(category)
+	msg = decorate(categorySeverity(category), msg)
+	if pinnableCategories[category] {
+		markAlertActive(category)
+	}
+	if category != NotifyReport {
+		replyMarkup = t.withAckButton(trackAlert(category, time.Now()), replyMarkup)
+	}
+	if !criticalNotifications[category] || !t.queueCritical(category, msg, replyMarkup) {
+		t.sendToAdmins(category, msg, replyMarkup...)
+	}
+	t.notifyExtraBots(category, msg)
+	t.notifyChannel(category, msg)
+}
+
+// sendExtraBotReport sends the status report to an extra bot's chats. It is
+// skipped while the panel has no inbounds; the note about that only goes to
+// the admins.
+func (t *Tgbot) sendExtraBotReport(eb *extraBot) {
+	if t.hasNoInbounds() {
+		return
+	}
+	msg, info := t.buildReport(eb.runTime)
+	fanOut(eb.chatIds, func(chatId int64) error {
+		err := t.sendNotificationVia(eb.bot, chatId, NotifyReport, msg)
+		if info != "" {
+			err = errors.Join(err, t.sendNotificationVia(eb.bot, chatId, NotifyReport, info))
+		}
+		return err
+	}).logSummary("Report via bot " + eb.name)
+}
@@ -0,0 +1,172 @@
+package tgbot
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/zixu5u/3xv/v3/internal/logger"
+)
+
+// callbackTokenSep separates a signed callback query from its token. The
+// token is the issue time in base 36 seconds followed by callbackMacLen
+// characters of HMAC, about 15 bytes in all, which leaves room within
+// Telegram's 64-byte callback data for most queries.
+const (
+	callbackTokenSep = "|"
+	callbackMacLen   = 8
+)
+
+// hashStorageTTL is how long a callback query too long for Telegram is kept
+// in hashStorage. A longer tgButtonTTL raises it, since signed queries are
+// the ones that most often don't fit.
+const hashStorageTTL = 20 * time.Minute
+
+// signedCallbackActions are the buttons that change something. They carry
+// a token and stop working once tgButtonTTL passed, so an old or forwarded
+// message can't replay them. Every other button keeps working.
+var signedCallbackActions = map[string]bool{
+	"reset_traffic_c":              true,
+	"limit_traffic_c":              true,
+	"reset_exp_c":                  true,
+	"ip_limit_c":                   true,
+	"clear_ips_c":                  true,
+	"tgid_remove_c":                true,
+	"toggle_enable_c":              true,
+	"client_disable":               true,
+	"dormant_disable_confirm":      true,
+	"ip_logging_on":                true,
+	"ip_logging_off":               true,
+	"block_ip":                     true,
+	"remove_chat_confirm":          true,
+	"inbound_edit_save":            true,
+	"inbound_rename":               true,
+	"client_move":                  true,
+	"client_import":                true,
+	"reload_rules_restart":         true,
+	"restore_good_config":          true,
+	"setting_confirm":              true,
+	"restart_xray_c":               true,
+	"reset_all_traffics_c":         true,
+	"reset_all_inbound_traffics_c": true,
+	"rs_apply":                     true,
+	"terminate_c":                  true,
+	"undo_reset":                   true,
+	"show_inbound_full":            true,
+}
+
+// callbackSigner holds the key and lifetime of signed buttons.
+type callbackSigner struct {
+	key []byte
+	ttl time.Duration
+}
+
+// callbackSigning is loaded by Start; nil turns signing off.
+var callbackSigning atomic.Pointer[callbackSigner]
+
+// loadCallbackSigning reads tgButtonTTL and derives the signing key from the
+// panel secret, so buttons stay valid across a restart within their TTL.
+func (t *Tgbot) loadCallbackSigning() {
+	minutes, err := t.settingService.GetTgButtonTTL()
+	if err != nil {
+		logger.Warning("Failed to get Telegram bot button TTL setting:", err)
+		minutes = 60
+	}
+	if minutes <= 0 {
+		callbackSigning.Store(nil)
+		setHashStorageTTL(hashStorageTTL)
+		return
+	}
+	ttl := time.Duration(minutes) * time.Minute
+	setHashStorageTTL(max(hashStorageTTL, ttl))
+	secret, err := t.settingService.GetSecret()
+	if err != nil || len(secret) == 0 {
+		logger.Warning("Failed to get panel secret, signed buttons won't survive a restart:", err)
+		secret = make([]byte, 32)
+		rand.Read(secret)
+	}
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte("tgbot-callback"))
+	callbackSigning.Store(&callbackSigner{key: mac.Sum(nil), ttl: ttl})
+}
+
+// setHashStorageTTL sets how long hashStorage keeps callback queries.
+func setHashStorageTTL(ttl time.Duration) {
+	if hashStorage != nil {
+		hashStorage.SetExpiration(ttl)
+	}
+}
+
+// callbackMac is the token MAC over query and its issue time.
+func callbackMac(key []byte, query string, issued string) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(query + callbackTokenSep + issued))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))[:callbackMacLen]
+}
+
+// callbackAction returns the action a callback query starts with.
+func callbackAction(data string) string {
+	action, _, _ := strings.Cut(data, " ")
+	action, _, _ = strings.Cut(action, callbackTokenSep)
+	return action
+}
+
+// signCallback appends a token to query when its action is one of
+// signedCallbackActions.
+func (s *callbackSigner) signCallback(query string, now time.Time) string {
+	if s == nil || !signedCallbackActions[callbackAction(query)] {
+		return query
+	}
+	issued := strconv.FormatInt(now.Unix(), 36)
+	return query + callbackTokenSep + issued + callbackMac(s.key, query, issued)
+}
+
+// verifyCallback strips and checks the token of a callback query. ok is
+// false for a destructive action whose token is missing, forged or older
+// than the TTL; other actions pass unchanged.
+func (s *callbackSigner) verifyCallback(data string, now time.Time) (query string, ok bool) {
+	if s == nil || !signedCallbackActions[callbackAction(data)] {
+		return data, true
+	}
+	i := strings.LastIndex(data, callbackTokenSep)
+	if i < 0 {
+		return "", false
+	}
+	query, token := data[:i], data[i+1:]
+	if len(token) <= callbackMacLen {
+		return "", false
+	}
+	issued, mac := token[:len(token)-callbackMacLen], token[len(token)-callbackMacLen:]
+	if !hmac.Equal([]byte(mac), []byte(callbackMac(s.key, query, issued))) {
+		return "", false
+	}
+	seconds, err := strconv.ParseInt(issued, 36, 64)
+	if err != nil {
+		return "", false
+	}
+	age := now.Sub(time.Unix(seconds, 0))
+	if age > s.ttl || age < -time.Minute {
+		return "", false
+	}
+	return query, true
+}
+
+// verifyCallbackData checks the callback data of a pressed button, looking
+// it up in the hash storage first when encodeQuery had to hash it. It
+// returns the query without its token; data whose hash is gone is returned
+// as is, for the caller to report.
+func (t *Tgbot) verifyCallbackData(data string, now time.Time) (string, bool) {
+	if hashStorage.IsMD5(data) {
+		decoded, exists := hashStorage.GetValue(data)
+		if !exists {
+			return data, true
+		}
+		data = decoded
+	}
+	return callbackSigning.Load().verifyCallback(data, now)
+}
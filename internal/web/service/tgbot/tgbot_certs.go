@@ -0,0 +1,157 @@
+package tgbot
+
+import (
+	"html"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/zixu5u/3xv/v3/internal/logger"
+	"github.com/zixu5u/3xv/v3/internal/web/service"
+)
+
+// defaultCertExpiryDays is the warning window /certs uses when the
+// tgCertExpiryDays setting is 0 or unreadable.
+const defaultCertExpiryDays = 14
+
+// maxCertExpiryDays bounds the window /certs accepts.
+const maxCertExpiryDays = 365
+
+// certReadTimeout bounds each TLS handshake used to read a certificate the
+// inbound's settings don't name.
+const certReadTimeout = 5 * time.Second
+
+// parseCertsArgs reads "/certs [days]". ok is false for anything else.
+func parseCertsArgs(args []string) (days int, ok bool) {
+	switch len(args) {
+	case 0:
+		return 0, true
+	case 1:
+		days, err := strconv.Atoi(strings.TrimSuffix(strings.ToLower(args[0]), "d"))
+		if err != nil || days < 1 || days > maxCertExpiryDays {
+			return 0, false
+		}
+		return days, true
+	default:
+		return 0, false
+	}
+}
+
+// certExpiryDays returns the tgCertExpiryDays setting, 0 when the check is
+// disabled.
+func (t *Tgbot) certExpiryDays() int {
+	days, err := t.settingService.GetTgCertExpiryDays()
+	if err != nil {
+		t.settingFallback("tgCertExpiryDays", err, strconv.Itoa(defaultCertExpiryDays))
+		return defaultCertExpiryDays
+	}
+	return days
+}
+
+// expiringCerts returns the readable certificates that expire within days
+// of now, including those already expired, soonest first.
+func expiringCerts(certs []service.InboundCert, days int, now time.Time) []service.InboundCert {
+	var expiring []service.InboundCert
+	for _, cert := range certs {
+		if cert.Err == nil && cert.DaysLeft(now) < days {
+			expiring = append(expiring, cert)
+		}
+	}
+	slices.SortFunc(expiring, func(a, b service.InboundCert) int {
+		if c := a.NotAfter.Compare(b.NotAfter); c != 0 {
+			return c
+		}
+		return a.InboundId - b.InboundId
+	})
+	return expiring
+}
+
+// certLine renders one certificate for /certs and the expiry alert.
+// Certificates within days of expiring are flagged.
+func (t *Tgbot) certLine(cert service.InboundCert, days int, now time.Time, loc *time.Location) string {
+	label := cert.Remark
+	if label == "" {
+		label = cert.Tag
+	}
+	name := "Name==" + escapeField(label)
+	if cert.Err != nil {
+		return t.I18nBot("tgbot.messages.certUnreadable", name,
+			"Error=="+html.EscapeString(cert.Err.Error()))
+	}
+	left := cert.DaysLeft(now)
+	params := []string{
+		name,
+		"Subject==" + escapeField(cert.Subject),
+		"Date==" + cert.NotAfter.In(loc).Format("2006-01-02"),
+		"Days==" + strconv.Itoa(left),
+		"Source==" + escapeField(cert.Source),
+	}
+	switch {
+	case cert.NotAfter.Before(now):
+		return t.I18nBot("tgbot.messages.certExpired", params...)
+	case left < days:
+		return t.I18nBot("tgbot.messages.certExpiring", params...)
+	default:
+		return t.I18nBot("tgbot.messages.certValid", params...)
+	}
+}
+
+// sendCerts implements "/certs [days]": the certificate of every local TLS
+// inbound with its expiry date, flagging those expiring within days, by
+// default the tgCertExpiryDays setting.
+func (t *Tgbot) sendCerts(chatId int64, days int) {
+	if days == 0 {
+		days = t.certExpiryDays()
+		if days == 0 {
+			days = defaultCertExpiryDays
+		}
+	}
+	certs, err := t.inboundService.GetInboundCerts(certReadTimeout)
+	if err != nil {
+		logger.Warning("Failed to read inbound certificates:", err)
+		t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.wentWrong"))
+		return
+	}
+	if len(certs) == 0 {
+		t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.messages.certsNone"))
+		return
+	}
+	now := time.Now()
+	loc := t.timeLocation()
+	var msg strings.Builder
+	msg.WriteString(t.I18nBot("tgbot.messages.certsHeader",
+		"Count=="+strconv.Itoa(len(certs)),
+		"Days=="+strconv.Itoa(days)))
+	for _, cert := range certs {
+		msg.WriteString("\r\n" + t.certLine(cert, days, now, loc))
+	}
+	t.SendMsgToTgbot(chatId, msg.String())
+}
+
+// SendCertExpiryAlerts notifies the admins of inbound certificates that
+// expire within tgCertExpiryDays days or already expired. Certificates that
+// can't be read are left to /certs.
+func (t *Tgbot) SendCertExpiryAlerts() {
+	days := t.certExpiryDays()
+	if days <= 0 {
+		return
+	}
+	certs, err := t.inboundService.GetInboundCerts(certReadTimeout)
+	if err != nil {
+		logger.Warning("Failed to read inbound certificates:", err)
+		return
+	}
+	now := time.Now()
+	expiring := expiringCerts(certs, days, now)
+	if len(expiring) == 0 {
+		return
+	}
+	loc := t.timeLocation()
+	var msg strings.Builder
+	msg.WriteString(t.I18nBot("tgbot.messages.certAlertHeader", "Count=="+strconv.Itoa(len(expiring))))
+	for _, cert := range expiring {
+		msg.WriteString("\r\n" + t.certLine(cert, days, now, loc))
+	}
+	t.SendNotification(NotifyCertExpiring, msg.String())
+}
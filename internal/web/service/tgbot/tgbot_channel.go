@@ -0,0 +1,135 @@
+package tgbot
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/zixu5u/3xv/v3/internal/logger"
+
+	"github.com/mymmrac/telego/telegoapi"
+)
+
+// Problems posting to the channel that only an admin can fix.
+const (
+	channelNoRights = "no_rights" // the bot isn't a channel admin allowed to post
+	channelNotFound = "not_found" // wrong ID, or the bot was never added
+)
+
+var channelProblemKeys = map[string]string{
+	channelNoRights: "tgbot.messages.channelNoRights",
+	channelNotFound: "tgbot.messages.channelNotFound",
+}
+
+// channelProblem is the problem admins were last told about, so a broken
+// channel raises one alert rather than one per post. A successful post
+// clears it.
+var channelProblem struct {
+	sync.Mutex
+	last string
+}
+
+// parseChannelCategories parses the comma-separated tgBotChannelCategories
+// setting.
+func parseChannelCategories(raw string) []string {
+	categories := make([]string, 0)
+	for part := range strings.SplitSeq(raw, ",") {
+		part = strings.ToLower(strings.TrimSpace(part))
+		if part != "" && !slices.Contains(categories, part) {
+			categories = append(categories, part)
+		}
+	}
+	return categories
+}
+
+// classifyChannelError returns the problem behind a failed channel post, or
+// "" when it isn't one an admin can fix, such as a network error. Telegram
+// answers 403 when the bot isn't a member of the channel, and 400 with a
+// "rights" description when it is a member but not an admin who may post.
+func classifyChannelError(err error) string {
+	var apiErr *telegoapi.Error
+	if !errors.As(err, &apiErr) {
+		return ""
+	}
+	description := strings.ToLower(apiErr.Description)
+	switch {
+	case apiErr.ErrorCode == http.StatusForbidden,
+		strings.Contains(description, "rights"),
+		strings.Contains(description, "administrator"):
+		return channelNoRights
+	case strings.Contains(description, "chat not found"):
+		return channelNotFound
+	}
+	return ""
+}
+
+// channel reads the channel settings. ok is false when no channel is
+// configured. They are read on every post, so a change applies without
+// restarting the bot.
+func (t *Tgbot) channel() (id int64, categories []string, ok bool) {
+	raw, err := t.settingService.GetTgBotChannelId()
+	if err != nil {
+		raw = t.settingFallback("tgBotChannelId", err, "")
+	}
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return 0, nil, false
+	}
+	id, err = strconv.ParseInt(raw, 10, 64)
+	if err != nil {
+		logger.Warning("Invalid Telegram channel ID:", err)
+		return 0, nil, false
+	}
+	rawCategories, err := t.settingService.GetTgBotChannelCategories()
+	if err != nil {
+		rawCategories = t.settingFallback("tgBotChannelCategories", err, NotifyReport)
+	}
+	return id, parseChannelCategories(rawCategories), true
+}
+
+// notifyChannel posts msgs to the channel if it subscribes to category.
+// Channel posts never carry buttons: the channel's readers aren't admins,
+// so they couldn't use them.
+func (t *Tgbot) notifyChannel(category string, msgs ...string) {
+	id, categories, ok := t.channel()
+	if !ok || !slices.Contains(categories, category) {
+		return
+	}
+	start := time.Now()
+	var err error
+	for _, msg := range msgs {
+		if err = t.sendNotificationVia(bot, id, category, msg); err != nil {
+			break
+		}
+	}
+	logBotEvent(botEvent{Event: "channel_post", ChatID: id, Category: category, Latency: time.Since(start), Err: err})
+	t.trackChannelProblem(id, err)
+}
+
+// channelProblemMessage explains to admins why posting to channel id fails.
+func (t *Tgbot) channelProblemMessage(id int64, problem string) string {
+	return t.I18nBot(channelProblemKeys[problem], "Channel=="+strconv.FormatInt(id, 10))
+}
+
+// trackChannelProblem tells the admins when posts to the channel start
+// failing for a reason they must fix. Other errors leave the state alone,
+// since they say nothing about the bot's rights.
+func (t *Tgbot) trackChannelProblem(id int64, err error) {
+	problem := classifyChannelError(err)
+	if err != nil && problem == "" {
+		return
+	}
+	channelProblem.Lock()
+	changed := channelProblem.last != problem
+	channelProblem.last = problem
+	channelProblem.Unlock()
+	if !changed || problem == "" {
+		return
+	}
+	logger.Warningf("Posting to Telegram channel %d failed: %v", id, err)
+	t.SendMsgToTgbotAdmins(t.channelProblemMessage(id, problem))
+}
@@ -0,0 +1,180 @@
+package tgbot
+
+import (
+	"errors"
+	"html"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/zixu5u/3xv/v3/internal/logger"
+
+	tu "github.com/mymmrac/telego/telegoutil"
+)
+
+// chatListMutex serializes edits of the tgBotChatId setting from the bot so
+// two admins can't overwrite each other's change.
+var chatListMutex sync.Mutex
+
+// parseAdminIds parses the comma-separated tgBotChatId setting. Entries are
+// trimmed and empty ones skipped, so stray commas and spaces are harmless.
+// Entries that aren't a valid chat ID are returned in invalid instead of
+// failing the whole list: a typo in one entry must neither lock the other
+// admins out nor grant anything. Duplicates are dropped.
+func parseAdminIds(raw string) (ids []int64, invalid []string) {
+	ids = make([]int64, 0)
+	for field := range strings.SplitSeq(raw, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		id, err := parseChatId(field)
+		if err != nil {
+			invalid = append(invalid, field)
+			continue
+		}
+		if !slices.Contains(ids, id) {
+			ids = append(ids, id)
+		}
+	}
+	return ids, invalid
+}
+
+// parseChatId validates a chat ID given to /addchat or /removechat.
+// Telegram chat IDs are non-zero; groups and channels are negative.
+func parseChatId(arg string) (int64, error) {
+	id, err := strconv.ParseInt(strings.TrimSpace(arg), 10, 64)
+	if err != nil || id == 0 {
+		return 0, errors.New("invalid chat id")
+	}
+	return id, nil
+}
+
+// saveAdminIds stores ids in the tgBotChatId setting and applies them to
+// the running bot without restarting it.
+func (t *Tgbot) saveAdminIds(ids []int64) error {
+	fields := make([]string, len(ids))
+	for i, id := range ids {
+		fields[i] = strconv.FormatInt(id, 10)
+	}
+	if err := t.settingService.SetTgBotChatId(strings.Join(fields, ",")); err != nil {
+		return err
+	}
+	tgBotMutex.Lock()
+	adminIds = ids
+	tgBotMutex.Unlock()
+	return nil
+}
+
+// currentAdminIds reads the recipient list from the settings.
+func (t *Tgbot) currentAdminIds() ([]int64, error) {
+	raw, err := t.settingService.GetTgBotChatId()
+	if err != nil {
+		return nil, err
+	}
+	ids, invalid := parseAdminIds(raw)
+	if len(invalid) > 0 {
+		logger.Warningf("Ignoring invalid Telegram chat IDs in settings: %s", strings.Join(invalid, ", "))
+	}
+	return ids, nil
+}
+
+// addChat adds a chat to the recipient list.
+func (t *Tgbot) addChat(chatId int64, arg string, requestedBy int64) {
+	id, err := parseChatId(arg)
+	if err != nil {
+		t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.messages.chatInvalid", "ID=="+html.EscapeString(arg)))
+		return
+	}
+
+	chatListMutex.Lock()
+	defer chatListMutex.Unlock()
+
+	ids, err := t.currentAdminIds()
+	if err != nil {
+		t.sendChatListFailed(chatId, err)
+		return
+	}
+	if slices.Contains(ids, id) {
+		t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.messages.chatAlready", "ID=="+strconv.FormatInt(id, 10)))
+		return
+	}
+	if err := t.saveAdminIds(append(ids, id)); err != nil {
+		t.sendChatListFailed(chatId, err)
+		return
+	}
+	logger.Infof("Chat %d added to the Telegram recipient list by %d", id, requestedBy)
+	logBotEvent(botEvent{Event: "chat_add", ChatID: requestedBy, Command: "addchat"})
+	t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.messages.chatAdded", "ID=="+strconv.FormatInt(id, 10)))
+}
+
+// removeChat removes a chat from the recipient list. Removing the last chat
+// is refused, and an admin removing their own chat has to confirm first
+// unless confirmed is already set.
+func (t *Tgbot) removeChat(chatId int64, arg string, requestedBy int64, confirmed bool) {
+	id, err := parseChatId(arg)
+	if err != nil {
+		t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.messages.chatInvalid", "ID=="+html.EscapeString(arg)))
+		return
+	}
+	idStr := strconv.FormatInt(id, 10)
+
+	chatListMutex.Lock()
+	defer chatListMutex.Unlock()
+
+	ids, err := t.currentAdminIds()
+	if err != nil {
+		t.sendChatListFailed(chatId, err)
+		return
+	}
+	index := slices.Index(ids, id)
+	if index < 0 {
+		t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.messages.chatNotFound", "ID=="+idStr))
+		return
+	}
+	if len(ids) == 1 {
+		t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.messages.chatLastOne"))
+		return
+	}
+	if (id == requestedBy || id == chatId) && !confirmed {
+		inlineKeyboard := tu.InlineKeyboard(
+			tu.InlineKeyboardRow(
+				tu.InlineKeyboardButton(t.I18nBot("tgbot.buttons.confirmRemoveChat")).WithCallbackData(t.encodeQuery("remove_chat_confirm "+idStr)),
+				tu.InlineKeyboardButton(t.I18nBot("tgbot.buttons.cancel")).WithCallbackData(t.encodeQuery("remove_chat_cancel "+idStr)),
+			),
+		)
+		t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.messages.chatRemoveSelf", "ID=="+idStr), inlineKeyboard)
+		return
+	}
+	if err := t.saveAdminIds(slices.Delete(ids, index, index+1)); err != nil {
+		t.sendChatListFailed(chatId, err)
+		return
+	}
+	logger.Infof("Chat %d removed from the Telegram recipient list by %d", id, requestedBy)
+	logBotEvent(botEvent{Event: "chat_remove", ChatID: requestedBy, Command: "removechat"})
+	t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.messages.chatRemoved", "ID=="+idStr))
+}
+
+// sendChatList lists the chats receiving reports and notifications.
+func (t *Tgbot) sendChatList(chatId int64, requestedBy int64) {
+	ids, err := t.currentAdminIds()
+	if err != nil {
+		t.sendChatListFailed(chatId, err)
+		return
+	}
+	var output strings.Builder
+	output.WriteString(t.I18nBot("tgbot.messages.chatListHeader"))
+	for _, id := range ids {
+		output.WriteString("\r\n<code>" + strconv.FormatInt(id, 10) + "</code>")
+		if id == requestedBy || id == chatId {
+			output.WriteString(" " + t.I18nBot("tgbot.messages.chatListSelf"))
+		}
+	}
+	t.SendMsgToTgbot(chatId, output.String())
+}
+
+func (t *Tgbot) sendChatListFailed(chatId int64, err error) {
+	logger.Warning("Failed to update the Telegram recipient list:", err)
+	t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.messages.chatFailed", "Error=="+html.EscapeString(err.Error())))
+}
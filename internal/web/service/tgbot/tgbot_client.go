@@ -6,6 +6,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"html"
 	"io"
 	"net/http"
 	"slices"
@@ -15,7 +16,6 @@ import (
 
 	"github.com/zixu5u/3xv/v3/internal/database/model"
 	"github.com/zixu5u/3xv/v3/internal/logger"
-	"github.com/zixu5u/3xv/v3/internal/util/common"
 	"github.com/zixu5u/3xv/v3/internal/web/service"
 	"github.com/zixu5u/3xv/v3/internal/xray"
 
@@ -49,7 +49,7 @@ func (t *Tgbot) BuildClientDraftMessage() string {
 
 	traffic := "♾️ Unlimited(Reset)"
 	if client_TotalGB > 0 {
-		traffic = common.FormatTraffic(client_TotalGB)
+		traffic = formatTraffic(client_TotalGB)
 	}
 
 	ipLimit := "♾️ Unlimited(Reset)"
@@ -74,7 +74,7 @@ func (t *Tgbot) BuildClientDraftMessage() string {
 
 	var b strings.Builder
 	b.WriteString("📝 *New client draft*\r\n")
-	b.WriteString(fmt.Sprintf("📧 Email: `%s`\r\n", client_Email))
+	b.WriteString(fmt.Sprintf("📧 Email: `%s`\r\n", escapeField(client_Email)))
 	b.WriteString(fmt.Sprintf("🔗 Attached: %s\r\n", attached))
 	b.WriteString(fmt.Sprintf("📊 Traffic: %s\r\n", traffic))
 	b.WriteString(fmt.Sprintf("📅 Expire: %s\r\n", expiry))
@@ -146,6 +146,9 @@ func (t *Tgbot) buildSubscriptionURLs(email string) (string, string, error) {
 	if err != nil || client == nil {
 		return "", "", errors.New("client not found")
 	}
+	if client.SubID == "" {
+		return "", "", errors.New("client has no subscription ID")
+	}
 
 	// Gather settings to construct absolute URLs
 	subURI, _ := t.settingService.GetSubURI()
@@ -244,24 +247,27 @@ func (t *Tgbot) sendClientSubLinks(chatId int64, email string) {
 		tu.InlineKeyboardRow(
 			tu.InlineKeyboardButton(t.I18nBot("qrCode")).WithCallbackData(t.encodeQuery("client_qr_links "+email)),
 		),
+		tu.InlineKeyboardRow(
+			tu.InlineKeyboardButton(t.I18nBot("tgbot.buttons.qrAlbum")).
+				WithCallbackData(t.encodeQuery("client_links_flavor album "+linkFlavorCompat+" "+email)),
+		),
 	)
 	t.SendMsgToTgbot(chatId, msg, inlineKeyboard)
 }
 
-// sendClientIndividualLinks fetches the subscription content (individual links) and sends it to the user
-func (t *Tgbot) sendClientIndividualLinks(chatId int64, email string) {
+// fetchIndividualLinks fetches the subscription content of a client and returns its individual links,
+// encoded for the given link flavor
+func (t *Tgbot) fetchIndividualLinks(email string, flavor string) ([]string, error) {
 	// Build the HTML sub page URL; we'll call it with header Accept to get raw content
 	subURL, _, err := t.buildSubscriptionURLs(email)
 	if err != nil {
-		t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.answers.errorOperation")+"\r\n"+err.Error())
-		return
+		return nil, err
 	}
 
 	// Try to fetch raw subscription links. Prefer plain text response.
 	req, err := http.NewRequest("GET", subURL, nil)
 	if err != nil {
-		t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.answers.errorOperation")+"\r\n"+err.Error())
-		return
+		return nil, err
 	}
 	// Force plain text to avoid HTML page; controller respects Accept header
 	req.Header.Set("Accept", "text/plain, */*;q=0.1")
@@ -273,15 +279,13 @@ func (t *Tgbot) sendClientIndividualLinks(chatId int64, email string) {
 
 	resp, err := optimizedHTTPClient.Do(req)
 	if err != nil {
-		t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.answers.errorOperation")+"\r\n"+err.Error())
-		return
+		return nil, err
 	}
 	defer resp.Body.Close()
 
 	bodyBytes, err := io.ReadAll(resp.Body)
 	if err != nil {
-		t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.answers.errorOperation")+"\r\n"+err.Error())
-		return
+		return nil, err
 	}
 
 	// If service is configured to encode (Base64), decode it
@@ -305,9 +309,19 @@ func (t *Tgbot) sendClientIndividualLinks(chatId int64, email string) {
 	for _, l := range lines {
 		l = strings.TrimSpace(l)
 		if l != "" {
-			cleaned = append(cleaned, l)
+			cleaned = append(cleaned, encodeShareLink(l, flavor))
 		}
 	}
+	return cleaned, nil
+}
+
+// sendClientIndividualLinks sends the individual links of a client, encoded for the given link flavor
+func (t *Tgbot) sendClientIndividualLinks(chatId int64, email string, flavor string) {
+	cleaned, err := t.fetchIndividualLinks(email, flavor)
+	if err != nil {
+		t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.answers.errorOperation")+"\r\n"+err.Error())
+		return
+	}
 	if len(cleaned) == 0 {
 		t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.noResult"))
 		return
@@ -320,19 +334,24 @@ func (t *Tgbot) sendClientIndividualLinks(chatId int64, email string) {
 		chunk := cleaned[i:j]
 		var msg strings.Builder
 		msg.WriteString(t.I18nBot("subscription.individualLinks"))
-		msg.WriteString(":\r\n")
+		msg.WriteString(" (" + t.linkFlavorLabel(flavor) + "):\r\n")
 		for _, link := range chunk {
 			// wrap each link in <code>
 			msg.WriteString("<code>")
-			msg.WriteString(link)
+			msg.WriteString(html.EscapeString(link))
 			msg.WriteString("</code>\r\n")
 		}
-		t.SendMsgToTgbot(chatId, msg.String())
+		if j == len(cleaned) {
+			t.SendMsgToTgbot(chatId, msg.String(), t.linkFlavorKeyboard("links", email, flavor))
+		} else {
+			t.SendMsgToTgbot(chatId, msg.String())
+		}
 	}
 }
 
-// sendClientQRLinks generates QR images for subscription URL, JSON URL, and a few individual links, then sends them
-func (t *Tgbot) sendClientQRLinks(chatId int64, email string) {
+// sendClientQRLinks generates QR images for subscription URL, JSON URL, and a few individual links, then sends them.
+// Individual links are encoded for the given link flavor, which their captions name.
+func (t *Tgbot) sendClientQRLinks(chatId int64, email string, flavor string) {
 	subURL, subJsonURL, err := t.buildSubscriptionURLs(email)
 	if err != nil {
 		t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.answers.errorOperation")+"\r\n"+err.Error())
@@ -401,7 +420,7 @@ func (t *Tgbot) sendClientQRLinks(chatId int64, email string) {
 			for _, l := range lines {
 				l = strings.TrimSpace(l)
 				if l != "" {
-					cleaned = append(cleaned, l)
+					cleaned = append(cleaned, encodeShareLink(l, flavor))
 				}
 			}
 			if len(cleaned) > 0 {
@@ -413,7 +432,9 @@ func (t *Tgbot) sendClientQRLinks(chatId int64, email string) {
 						document := tu.Document(
 							tu.ID(chatId),
 							tu.FileFromBytes(png, filename),
-						)
+						).WithCaption(t.I18nBot("tgbot.messages.linkFlavorCaption",
+							"Email=="+email,
+							"Flavor=="+t.linkFlavorLabel(flavor)))
 						_, _ = bot.SendDocument(context.Background(), document)
 						// Reduced delay for better performance
 						if i < max-1 { // Only delay between documents, not after the last one
@@ -421,6 +442,8 @@ func (t *Tgbot) sendClientQRLinks(chatId int64, email string) {
 						}
 					}
 				}
+				t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.messages.linkFlavorOther", "Flavor=="+t.linkFlavorLabel(flavor)),
+					t.linkFlavorKeyboard("qr", email, flavor))
 			}
 		}
 	}
@@ -468,12 +491,7 @@ func (t *Tgbot) clientInfoMsg(
 		flag = true
 	}
 
-	total := ""
-	if traffic.Total == 0 {
-		total = t.I18nBot("tgbot.unlimited")
-	} else {
-		total = common.FormatTraffic((traffic.Total))
-	}
+	total := t.formatClientLimit(traffic.Email, traffic.Total)
 
 	enabled := ""
 	isEnabled, err := t.clientService.CheckIsEnabledByEmail(&t.inboundService, traffic.Email)
@@ -503,7 +521,7 @@ func (t *Tgbot) clientInfoMsg(
 	}
 
 	output := ""
-	output += t.I18nBot("tgbot.messages.email", "Email=="+traffic.Email)
+	output += t.I18nBot("tgbot.messages.email", "Email=="+escapeField(traffic.Email))
 	if attachIds, err := t.clientService.GetInboundIdsForEmail(nil, traffic.Email); err == nil && len(attachIds) > 0 {
 		output += fmt.Sprintf("🔗 Inbounds: %s\r\n", t.describeAttachedInbounds(attachIds))
 	}
@@ -527,9 +545,9 @@ func (t *Tgbot) clientInfoMsg(
 		}
 	}
 	if printTraffic {
-		output += t.I18nBot("tgbot.messages.upload", "Upload=="+common.FormatTraffic(traffic.Up))
-		output += t.I18nBot("tgbot.messages.download", "Download=="+common.FormatTraffic(traffic.Down))
-		output += t.I18nBot("tgbot.messages.total", "UpDown=="+common.FormatTraffic((traffic.Up+traffic.Down)), "Total=="+total)
+		output += t.I18nBot("tgbot.messages.upload", "Upload=="+formatTraffic(traffic.Up))
+		output += t.I18nBot("tgbot.messages.download", "Download=="+formatTraffic(traffic.Down))
+		output += t.I18nBot("tgbot.messages.total", "UpDown=="+formatTraffic((traffic.Up+traffic.Down)), "Total=="+total)
 	}
 	if printRefreshed {
 		output += t.I18nBot("tgbot.messages.refreshedOn", "Time=="+time.Now().Format("2006-01-02 15:04:05"))
@@ -621,8 +639,10 @@ func (t *Tgbot) searchClientIps(chatId int64, email string, messageID ...int) {
 	}
 
 	output := ""
-	output += t.I18nBot("tgbot.messages.email", "Email=="+email)
+	output += t.I18nBot("tgbot.messages.email", "Email=="+escapeField(email))
 	output += t.I18nBot("tgbot.messages.ips", "IPs=="+formattedIps)
+	logging := t.currentIPLogging()
+	output += t.ipLoggingLine(logging)
 	output += t.I18nBot("tgbot.messages.refreshedOn", "Time=="+time.Now().Format("2006-01-02 15:04:05"))
 
 	inlineKeyboard := tu.InlineKeyboard(
@@ -633,6 +653,9 @@ func (t *Tgbot) searchClientIps(chatId int64, email string, messageID ...int) {
 			tu.InlineKeyboardButton(t.I18nBot("tgbot.buttons.clearIPs")).WithCallbackData(t.encodeQuery("clear_ips "+email)),
 		),
 	)
+	if button, ok := t.ipLoggingButton(logging, email); ok {
+		inlineKeyboard.InlineKeyboard = append(inlineKeyboard.InlineKeyboard, tu.InlineKeyboardRow(button))
+	}
 
 	if len(messageID) > 0 {
 		t.editMessageTgBot(chatId, messageID[0], output, inlineKeyboard)
@@ -661,7 +684,7 @@ func (t *Tgbot) clientTelegramUserInfo(chatId int64, email string, messageID ...
 	}
 
 	output := ""
-	output += t.I18nBot("tgbot.messages.email", "Email=="+email)
+	output += t.I18nBot("tgbot.messages.email", "Email=="+escapeField(email))
 	output += t.I18nBot("tgbot.messages.TGUser", "TelegramID=="+tgId)
 	output += t.I18nBot("tgbot.messages.refreshedOn", "Time=="+time.Now().Format("2006-01-02 15:04:05"))
 
@@ -710,6 +733,9 @@ func (t *Tgbot) searchClient(chatId int64, email string, messageID ...int) {
 	}
 
 	output := t.clientInfoMsg(traffic, true, true, true, true, true, true)
+	output += t.clientIpLimitLine(email)
+	output += t.clientNoteLine(email)
+	output += t.clientDisableReasonLine(email, traffic.Enable)
 
 	inlineKeyboard := tu.InlineKeyboard(
 		tu.InlineKeyboardRow(
@@ -728,6 +754,7 @@ func (t *Tgbot) searchClient(chatId int64, email string, messageID ...int) {
 		),
 		tu.InlineKeyboardRow(
 			tu.InlineKeyboardButton(t.I18nBot("tgbot.buttons.setTGUser")).WithCallbackData(t.encodeQuery("tg_user "+email)),
+			tu.InlineKeyboardButton(t.I18nBot("tgbot.buttons.note")).WithCallbackData(t.encodeQuery("client_note "+email)),
 		),
 		tu.InlineKeyboardRow(
 			tu.InlineKeyboardButton(t.I18nBot("tgbot.buttons.toggle")).WithCallbackData(t.encodeQuery("toggle_enable "+email)),
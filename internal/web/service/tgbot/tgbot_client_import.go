@@ -0,0 +1,306 @@
+package tgbot
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/zixu5u/3xv/v3/internal/database/model"
+	"github.com/zixu5u/3xv/v3/internal/logger"
+	"github.com/zixu5u/3xv/v3/internal/web/service"
+
+	"github.com/mymmrac/telego"
+	tu "github.com/mymmrac/telego/telegoutil"
+)
+
+// stateAwaitingImportFile is the conversation state after /import, waiting
+// for the CSV document. The target is kept in clientImports for the chat.
+const stateAwaitingImportFile = "awaiting_import_file"
+
+const (
+	// maxImportFileSize bounds the CSV document /import downloads.
+	maxImportFileSize = 1 << 20
+	// maxImportRows bounds the clients one import creates.
+	maxImportRows = 1000
+	// maxImportFailuresShown caps the failing rows one message lists; the
+	// rest are only counted.
+	maxImportFailuresShown = 20
+)
+
+// clientImport is an /import waiting for its file or for confirmation.
+type clientImport struct {
+	inboundId int
+	tag       string
+	partial   bool
+	rows      []service.ClientImportRow
+	failures  []service.ClientImportFailure
+}
+
+var clientImports = make(map[int64]*clientImport)
+
+// parseImportArgs reads "/import [InboundTag] [strict|partial]". Strict is
+// the default.
+func parseImportArgs(args []string) (tag string, partial bool, ok bool) {
+	if len(args) < 1 || len(args) > 2 {
+		return "", false, false
+	}
+	if len(args) == 2 {
+		switch strings.ToLower(args[1]) {
+		case "strict":
+		case "partial":
+			partial = true
+		default:
+			return "", false, false
+		}
+	}
+	return args[0], partial, true
+}
+
+// parseClientCSV reads clients from CSV rows of email, traffic limit and
+// expiry, the last two optional and in the forms /edit accepts. A first row
+// starting with "email" is taken as a header, and lines starting with "#"
+// are skipped. Rows that can't be read are returned as failures with their
+// line; err is only set when the file itself is unreadable.
+func parseClientCSV(r io.Reader, now time.Time, loc *time.Location) (rows []service.ClientImportRow, failures []service.ClientImportFailure, err error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	reader.Comment = '#'
+	first := true
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		line, _ := reader.FieldPos(0)
+		if first {
+			first = false
+			if strings.EqualFold(strings.TrimSpace(record[0]), "email") {
+				continue
+			}
+		}
+		email := strings.TrimSpace(record[0])
+		fail := func(reason string) {
+			failures = append(failures, service.ClientImportFailure{Line: line, Email: email, Reason: reason})
+		}
+		if len(record) > 3 {
+			fail("expected email, limit and expiry, got " + strconv.Itoa(len(record)) + " columns")
+			continue
+		}
+		client := model.Client{Email: email}
+		if len(record) > 1 && strings.TrimSpace(record[1]) != "" {
+			total, err := parseTrafficSize(record[1])
+			if err != nil {
+				fail("limit: " + err.Error())
+				continue
+			}
+			client.TotalGB = total
+		}
+		if len(record) > 2 && strings.TrimSpace(record[2]) != "" {
+			expiry, err := parseExpiryInput(record[2], now, loc)
+			if err != nil {
+				fail("expiry: " + err.Error())
+				continue
+			}
+			client.ExpiryTime = expiry
+		}
+		rows = append(rows, service.ClientImportRow{Line: line, Client: client})
+		if len(rows)+len(failures) > maxImportRows {
+			return nil, nil, fmt.Errorf("more than %d rows", maxImportRows)
+		}
+	}
+	return rows, failures, nil
+}
+
+// promptClientImport implements "/import [InboundTag] [strict|partial]" by
+// asking for the CSV document.
+func (t *Tgbot) promptClientImport(chatId int64, tag string, partial bool) {
+	inbound, err := t.inboundService.GetInboundByTag(tag)
+	if err != nil {
+		t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.messages.muteNoInbound", "Tag=="+escapeField(tag)))
+		return
+	}
+	clientImports[chatId] = &clientImport{inboundId: inbound.Id, tag: inbound.Tag, partial: partial}
+	userStates[chatId] = stateAwaitingImportFile
+	inlineKeyboard := tu.InlineKeyboard(
+		tu.InlineKeyboardRow(
+			tu.InlineKeyboardButton(t.I18nBot("tgbot.buttons.cancel")).WithCallbackData(t.encodeQuery("client_import_cancel")),
+		),
+	)
+	t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.messages.importPrompt",
+		"Tag=="+escapeField(inbound.Tag),
+		"Max=="+strconv.Itoa(maxImportRows)), inlineKeyboard)
+}
+
+// downloadImportFile fetches the document sent for /import.
+func (t *Tgbot) downloadImportFile(document *telego.Document) ([]byte, error) {
+	if document.FileSize > maxImportFileSize {
+		return nil, fmt.Errorf("file larger than %d KB", maxImportFileSize>>10)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	file, err := bot.GetFile(ctx, &telego.GetFileParams{FileID: document.FileID})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, "GET", bot.FileDownloadURL(file.FilePath), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := optimizedHTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("download failed: %s", resp.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxImportFileSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxImportFileSize {
+		return nil, fmt.Errorf("file larger than %d KB", maxImportFileSize>>10)
+	}
+	return data, nil
+}
+
+// receiveClientImport reads the CSV document of a pending /import, checks
+// its rows and shows what would be created for confirmation. A strict
+// import with failing rows is rejected right away.
+func (t *Tgbot) receiveClientImport(chatId int64, document *telego.Document) {
+	pending := clientImports[chatId]
+	if pending == nil {
+		return
+	}
+	delete(userStates, chatId)
+	if document == nil {
+		delete(clientImports, chatId)
+		t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.messages.importNoFile"))
+		return
+	}
+	data, err := t.downloadImportFile(document)
+	if err == nil {
+		pending.rows, pending.failures, err = parseClientCSV(bytes.NewReader(data), time.Now(), t.timeLocation())
+	}
+	if err != nil {
+		delete(clientImports, chatId)
+		t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.messages.importUnreadable", "Error=="+html.EscapeString(err.Error())))
+		return
+	}
+	checked, err := t.clientService.CheckImport(&t.inboundService, pending.inboundId, pending.rows)
+	if err != nil {
+		delete(clientImports, chatId)
+		t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.messages.importFailed", "Error=="+html.EscapeString(err.Error())))
+		return
+	}
+	failures := mergeImportFailures(pending.failures, checked)
+	valid := len(pending.rows) - len(checked)
+
+	mode := t.I18nBot("tgbot.messages.importModeStrict")
+	if pending.partial {
+		mode = t.I18nBot("tgbot.messages.importModePartial")
+	}
+	msg := t.I18nBot("tgbot.messages.importPreview",
+		"Tag=="+escapeField(pending.tag),
+		"Mode=="+mode,
+		"Valid=="+strconv.Itoa(valid),
+		"Failed=="+strconv.Itoa(len(failures)))
+	msg += t.importFailureLines(failures)
+
+	if valid == 0 || (!pending.partial && len(failures) > 0) {
+		delete(clientImports, chatId)
+		msg += "\r\n\r\n" + t.I18nBot("tgbot.messages.importRejected")
+		t.SendMsgToTgbot(chatId, msg)
+		return
+	}
+	id := strconv.Itoa(pending.inboundId)
+	inlineKeyboard := tu.InlineKeyboard(
+		tu.InlineKeyboardRow(
+			tu.InlineKeyboardButton(t.I18nBot("tgbot.buttons.importApply", "Count=="+strconv.Itoa(valid))).WithCallbackData(t.encodeQuery("client_import "+id)),
+			tu.InlineKeyboardButton(t.I18nBot("tgbot.buttons.cancel")).WithCallbackData(t.encodeQuery("client_import_cancel")),
+		),
+	)
+	t.SendMsgToTgbot(chatId, msg, inlineKeyboard)
+}
+
+// applyClientImport creates the clients of a confirmed /import and reports
+// which rows failed.
+func (t *Tgbot) applyClientImport(chatId int64, inboundId int, requestedBy int64) {
+	pending := clientImports[chatId]
+	if pending == nil || pending.inboundId != inboundId || pending.rows == nil {
+		t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.answers.errorOperation"))
+		return
+	}
+	delete(clientImports, chatId)
+
+	result, needRestart, err := t.clientService.Import(&t.inboundService, pending.inboundId, pending.rows, pending.partial)
+	if needRestart {
+		t.xrayService.SetToNeedRestart()
+	}
+	logBotEvent(botEvent{Event: "client_import", ChatID: requestedBy, Command: "import", Err: err})
+	if err != nil {
+		logger.Warningf("Importing clients into inbound %d requested by %d failed: %v", pending.inboundId, requestedBy, err)
+		t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.messages.importFailed", "Error=="+html.EscapeString(err.Error())))
+		return
+	}
+	logger.Infof("%d client(s) imported into inbound %d by Telegram user %d", result.Created, pending.inboundId, requestedBy)
+
+	failures := mergeImportFailures(pending.failures, result.Failed)
+	msg := t.I18nBot("tgbot.messages.importDone",
+		"Tag=="+escapeField(pending.tag),
+		"Created=="+strconv.Itoa(result.Created),
+		"Failed=="+strconv.Itoa(len(failures)))
+	msg += t.importFailureLines(failures)
+	t.SendMsgToTgbot(chatId, msg)
+}
+
+// cancelClientImport drops an import waiting for its file or confirmation.
+func (t *Tgbot) cancelClientImport(chatId int64) {
+	delete(clientImports, chatId)
+	delete(userStates, chatId)
+	t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.messages.importCanceled"))
+}
+
+// mergeImportFailures combines the rows that failed parsing with those the
+// service rejected, in file order.
+func mergeImportFailures(parsed, checked []service.ClientImportFailure) []service.ClientImportFailure {
+	merged := make([]service.ClientImportFailure, 0, len(parsed)+len(checked))
+	i, j := 0, 0
+	for i < len(parsed) || j < len(checked) {
+		if j == len(checked) || (i < len(parsed) && parsed[i].Line <= checked[j].Line) {
+			merged = append(merged, parsed[i])
+			i++
+		} else {
+			merged = append(merged, checked[j])
+			j++
+		}
+	}
+	return merged
+}
+
+// importFailureLines lists the first maxImportFailuresShown failing rows.
+func (t *Tgbot) importFailureLines(failures []service.ClientImportFailure) string {
+	var sb strings.Builder
+	for _, f := range failures[:min(len(failures), maxImportFailuresShown)] {
+		sb.WriteString("\r\n" + t.I18nBot("tgbot.messages.importFailureLine",
+			"Line=="+strconv.Itoa(f.Line),
+			"Email=="+escapeField(f.Email),
+			"Reason=="+html.EscapeString(f.Reason)))
+	}
+	if more := len(failures) - maxImportFailuresShown; more > 0 {
+		sb.WriteString("\r\n" + t.I18nBot("tgbot.messages.importFailuresMore", "Count=="+strconv.Itoa(more)))
+	}
+	return sb.String()
+}
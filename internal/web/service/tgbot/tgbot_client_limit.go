@@ -0,0 +1,56 @@
+package tgbot
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/zixu5u/3xv/v3/internal/web/service"
+)
+
+// maxClientLimitAlerts caps how many clients one client limit alert lists;
+// the rest are only counted.
+const maxClientLimitAlerts = 20
+
+// clientLimitLine renders the conditions alert reached, one line each.
+func (t *Tgbot) clientLimitLine(alert service.ClientLimitAlert, loc *time.Location) string {
+	email := "Email==" + escapeField(alert.Email)
+	date := "Date==" + time.UnixMilli(alert.ExpiryTime).In(loc).Format("2006-01-02")
+	var lines []string
+	switch {
+	case alert.State&service.ClientTrafficExhausted != 0:
+		lines = append(lines, t.I18nBot("tgbot.messages.clientLimitExhausted", email,
+			"Used=="+formatTraffic(alert.Used),
+			"Total=="+formatTraffic(alert.Total)))
+	case alert.State&service.ClientNearTrafficLimit != 0:
+		lines = append(lines, t.I18nBot("tgbot.messages.clientLimitNear", email,
+			"Left=="+formatTraffic(alert.Total-alert.Used),
+			"Total=="+formatTraffic(alert.Total)))
+	}
+	switch {
+	case alert.State&service.ClientExpired != 0:
+		lines = append(lines, t.I18nBot("tgbot.messages.clientLimitExpired", email, date))
+	case alert.State&service.ClientNearExpiry != 0:
+		lines = append(lines, t.I18nBot("tgbot.messages.clientLimitExpiring", email, date))
+	}
+	return strings.Join(lines, "\r\n")
+}
+
+// SendClientLimitAlerts notifies the admins of clients that reached their
+// traffic limit or expiry date, or came within the panel's margins of it.
+// A scan that finds many lists the first maxClientLimitAlerts.
+func (t *Tgbot) SendClientLimitAlerts(alerts []service.ClientLimitAlert) {
+	if len(alerts) == 0 {
+		return
+	}
+	loc := t.timeLocation()
+	var msg strings.Builder
+	msg.WriteString(t.I18nBot("tgbot.messages.clientLimitHeader", "Count=="+strconv.Itoa(len(alerts))))
+	for _, alert := range alerts[:min(len(alerts), maxClientLimitAlerts)] {
+		msg.WriteString("\r\n" + t.clientLimitLine(alert, loc))
+	}
+	if more := len(alerts) - maxClientLimitAlerts; more > 0 {
+		msg.WriteString("\r\n" + t.I18nBot("tgbot.messages.clientLimitMore", "Count=="+strconv.Itoa(more)))
+	}
+	t.SendNotification(NotifyClients, msg.String())
+}
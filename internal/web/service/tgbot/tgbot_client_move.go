@@ -0,0 +1,140 @@
+package tgbot
+
+import (
+	"html"
+	"strconv"
+
+	"github.com/zixu5u/3xv/v3/internal/logger"
+
+	tu "github.com/mymmrac/telego/telegoutil"
+)
+
+// clientMove is a /move waiting for the admin's confirmation.
+type clientMove struct {
+	email  string
+	fromId int
+	toId   int
+}
+
+var clientMoves = make(map[int64]*clientMove)
+
+// promptClientMove implements "/move [Email] [ToTag] [FromTag]". The source
+// may be left out when the client is in a single inbound. It shows the
+// move and what changes for the client, and asks whether to keep the
+// traffic counters.
+func (t *Tgbot) promptClientMove(chatId int64, email string, toTag string, fromTag string) {
+	to, err := t.inboundService.GetInboundByTag(toTag)
+	if err != nil {
+		t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.messages.muteNoInbound", "Tag=="+escapeField(toTag)))
+		return
+	}
+	var fromId int
+	if fromTag != "" {
+		from, err := t.inboundService.GetInboundByTag(fromTag)
+		if err != nil {
+			t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.messages.muteNoInbound", "Tag=="+escapeField(fromTag)))
+			return
+		}
+		fromId = from.Id
+	} else {
+		rec, err := t.clientService.GetRecordByEmail(nil, email)
+		if err != nil {
+			t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.noResult"))
+			return
+		}
+		ids, err := t.clientService.GetInboundIdsForRecord(rec.Id)
+		if err != nil {
+			logger.Warning("Failed to get the inbounds of client", email, err)
+			t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.wentWrong"))
+			return
+		}
+		if len(ids) != 1 {
+			t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.messages.moveSourceRequired", "Email=="+escapeField(email)))
+			return
+		}
+		fromId = ids[0]
+	}
+
+	move, err := t.clientService.PlanMove(&t.inboundService, email, fromId, to.Id)
+	if err != nil {
+		t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.messages.moveFailed", "Error=="+html.EscapeString(err.Error())))
+		return
+	}
+	clientMoves[chatId] = &clientMove{email: move.Email, fromId: move.From.Id, toId: move.To.Id}
+
+	msg := t.I18nBot("tgbot.messages.moveConfirm",
+		"Email=="+escapeField(move.Email),
+		"From=="+escapeField(move.From.Tag),
+		"To=="+escapeField(move.To.Tag))
+	if move.ProtocolChanged {
+		msg += t.I18nBot("tgbot.messages.moveProtocolChanged",
+			"From=="+string(move.From.Protocol),
+			"To=="+string(move.To.Protocol))
+	}
+	if move.NewCredential {
+		msg += t.I18nBot("tgbot.messages.moveNewCredential")
+	}
+	if move.FlowDropped {
+		msg += t.I18nBot("tgbot.messages.moveFlowDropped")
+	}
+	msg += t.I18nBot("tgbot.messages.moveNewLink")
+
+	toId := strconv.Itoa(move.To.Id)
+	inlineKeyboard := tu.InlineKeyboard(
+		tu.InlineKeyboardRow(
+			tu.InlineKeyboardButton(t.I18nBot("tgbot.buttons.moveKeepTraffic")).WithCallbackData(t.encodeQuery("client_move "+toId+" 1")),
+			tu.InlineKeyboardButton(t.I18nBot("tgbot.buttons.moveResetTraffic")).WithCallbackData(t.encodeQuery("client_move "+toId+" 0")),
+		),
+		tu.InlineKeyboardRow(
+			tu.InlineKeyboardButton(t.I18nBot("tgbot.buttons.cancel")).WithCallbackData(t.encodeQuery("client_move_cancel")),
+		),
+	)
+	t.SendMsgToTgbot(chatId, msg, inlineKeyboard)
+}
+
+// applyClientMove carries out a confirmed /move. Both inbounds are updated
+// through the Xray API where possible; otherwise Xray is restarted once.
+func (t *Tgbot) applyClientMove(chatId int64, toId int, keepTraffic bool, requestedBy int64) {
+	move := clientMoves[chatId]
+	if move == nil || move.toId != toId {
+		t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.answers.errorOperation"))
+		return
+	}
+	delete(clientMoves, chatId)
+
+	needRestart, err := t.clientService.Move(&t.inboundService, move.email, move.fromId, move.toId, keepTraffic)
+	if needRestart {
+		t.xrayService.SetToNeedRestart()
+	}
+	logBotEvent(botEvent{Event: "client_move", ChatID: requestedBy, Command: "move", Err: err})
+	if err != nil {
+		logger.Warningf("Moving client %s from inbound %d to %d requested by %d failed: %v", move.email, move.fromId, move.toId, requestedBy, err)
+		t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.messages.moveFailed", "Error=="+html.EscapeString(err.Error())))
+		return
+	}
+	logger.Infof("Client %s moved from inbound %d to %d by Telegram user %d", move.email, move.fromId, move.toId, requestedBy)
+
+	doneKey := "tgbot.messages.moveDoneKept"
+	if !keepTraffic {
+		doneKey = "tgbot.messages.moveDoneReset"
+	}
+	t.SendMsgToTgbot(chatId, t.I18nBot(doneKey, "Email=="+escapeField(move.email)))
+	t.searchClient(chatId, move.email)
+}
+
+// cancelClientMove drops a move waiting for confirmation.
+func (t *Tgbot) cancelClientMove(chatId int64) {
+	delete(clientMoves, chatId)
+	t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.messages.moveCanceled"))
+}
+
+// parseMoveArgs reads "/move [Email] [ToTag] [FromTag]".
+func parseMoveArgs(args []string) (email string, toTag string, fromTag string, ok bool) {
+	if len(args) < 2 || len(args) > 3 {
+		return "", "", "", false
+	}
+	if len(args) == 3 {
+		fromTag = args[2]
+	}
+	return args[0], args[1], fromTag, true
+}
@@ -0,0 +1,121 @@
+package tgbot
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/zixu5u/3xv/v3/internal/logger"
+)
+
+const (
+	// maxUsagePeriod matches the upper bound of the tgClientUsageDays
+	// setting.
+	maxUsagePeriod = 90 * 24 * time.Hour
+	// usageBuckets is the number of bars in the usage chart.
+	usageBuckets = 12
+)
+
+// parseUsagePeriod reads the period of "/usage <email> [period]": a number
+// of hours or days such as "6h" or "7d", or "hour", "day" or "week".
+func parseUsagePeriod(arg string) (time.Duration, error) {
+	arg = strings.ToLower(arg)
+	switch arg {
+	case "hour":
+		return time.Hour, nil
+	case "day":
+		return 24 * time.Hour, nil
+	case "week":
+		return 7 * 24 * time.Hour, nil
+	}
+	unit := time.Hour
+	switch {
+	case strings.HasSuffix(arg, "d"):
+		unit = 24 * time.Hour
+	case !strings.HasSuffix(arg, "h"):
+		return 0, errors.New("period needs an h or d suffix")
+	}
+	n, err := strconv.Atoi(arg[:len(arg)-1])
+	if err != nil || n < 1 {
+		return 0, errors.New("invalid period")
+	}
+	period := time.Duration(n) * unit
+	if period > maxUsagePeriod {
+		return 0, errors.New("period too long")
+	}
+	return period, nil
+}
+
+// formatUsagePeriod renders a period the way /usage accepts it.
+func formatUsagePeriod(period time.Duration) string {
+	if period%(24*time.Hour) == 0 {
+		return strconv.Itoa(int(period/(24*time.Hour))) + "d"
+	}
+	return strconv.Itoa(int(period/time.Hour)) + "h"
+}
+
+// sparkline draws values as a row of bars scaled to the largest one.
+func sparkline(values []int64) string {
+	const bars = "▁▂▃▄▅▆▇█"
+	levels := []rune(bars)
+	var peak int64
+	for _, v := range values {
+		peak = max(peak, v)
+	}
+	var b strings.Builder
+	for _, v := range values {
+		if peak == 0 {
+			b.WriteRune(levels[0])
+			continue
+		}
+		b.WriteRune(levels[int(v*int64(len(levels)-1)/peak)])
+	}
+	return b.String()
+}
+
+// sendClientUsage implements "/usage <email> <period>": the traffic a
+// client moved in the last period, with a chart of how it was spread.
+func (t *Tgbot) sendClientUsage(chatId int64, email string, period time.Duration) {
+	traffic, err := t.inboundService.GetClientTrafficByEmail(email)
+	if err != nil {
+		logger.Warning(err)
+		t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.wentWrong"))
+		return
+	}
+	if traffic == nil {
+		t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.noResult"))
+		return
+	}
+
+	now := time.Now()
+	usage, ok, err := t.clientUsage.GetUsage(email, period, usageBuckets, now)
+	if err != nil {
+		logger.Warning("Failed to get client usage:", err)
+		t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.wentWrong"))
+		return
+	}
+	if !ok {
+		if interval, err := t.settingService.GetTgClientUsageInterval(); err == nil && interval == 0 {
+			t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.messages.clientUsageOff"))
+			return
+		}
+		t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.messages.clientUsageNoHistory"))
+		return
+	}
+
+	msg := t.I18nBot("tgbot.messages.clientUsageHeader",
+		"Email=="+escapeField(email),
+		"Period=="+formatUsagePeriod(period))
+	msg += t.I18nBot("tgbot.messages.upload", "Upload=="+formatTraffic(usage.Up))
+	msg += t.I18nBot("tgbot.messages.download", "Download=="+formatTraffic(usage.Down))
+	msg += t.I18nBot("tgbot.messages.clientUsageTotal", "Total=="+formatTraffic(usage.Up+usage.Down))
+	msg += "<code>" + sparkline(usage.Buckets) + "</code>\r\n"
+	msg += t.I18nBot("tgbot.messages.clientUsageBucket",
+		"Bucket=="+(period/usageBuckets).Round(time.Minute).String())
+	if start := now.Add(-period); usage.Since.After(start) {
+		msg += t.I18nBot("tgbot.messages.clientUsagePartial",
+			"Time=="+usage.Since.In(t.timeLocation()).Format("2006-01-02 15:04"))
+	}
+	t.SendMsgToTgbot(chatId, msg)
+}
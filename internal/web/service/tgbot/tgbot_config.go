@@ -0,0 +1,166 @@
+package tgbot
+
+import (
+	"html"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// botConfig is the configuration the running bot was started with. Settings
+// changed in the panel only take effect on the next Start, so /botconfig
+// shows this snapshot rather than the stored settings.
+type botConfig struct {
+	loadedAt        time.Time
+	token           string
+	adminIds        []int64
+	runTime         string
+	timezone        string
+	proxy           string
+	proxyFromEgress bool
+	apiServer       string
+	categories      []string
+}
+
+// effectiveConfig is the snapshot of the last successful Start.
+var effectiveConfig atomic.Pointer[botConfig]
+
+// maskToken hides all but the last 4 characters of a bot token.
+func maskToken(token string) string {
+	if len(token) <= 4 {
+		return strings.Repeat("*", len(token))
+	}
+	return "…" + token[len(token)-4:]
+}
+
+// redactProxy hides the password of a proxy URL.
+func redactProxy(proxy string) string {
+	u, err := url.Parse(proxy)
+	if err != nil {
+		return "(invalid URL)"
+	}
+	return u.Redacted()
+}
+
+// recordBotConfig stores the settings Start applied. Notification
+// categories are read here as well so the snapshot reflects one point in
+// time.
+func (t *Tgbot) recordBotConfig(token string, ids []int64, runTime string, proxy string, proxyFromEgress bool, apiServer string) {
+	categories := []string{NotifyXray, NotifySettings, NotifyReminder}
+	if !IsReportScheduleOff(runTime) {
+		categories = append(categories, NotifyReport)
+	}
+	if enabled, err := t.settingService.GetTgBotLoginNotify(); err == nil && enabled {
+		categories = append(categories, NotifyLogin)
+	}
+	if threshold, err := t.settingService.GetTgCpu(); err == nil && threshold > 0 {
+		categories = append(categories, NotifyCPU)
+	}
+	if interval, err := t.settingService.GetTgClientLimitInterval(); err == nil && interval > 0 {
+		categories = append(categories, NotifyClients)
+	}
+	if days, err := t.settingService.GetTgCertExpiryDays(); err == nil && days > 0 {
+		categories = append(categories, NotifyCertExpiring)
+	}
+	if enabled, err := t.settingService.GetTgTrafficResetNotify(); err == nil && enabled {
+		categories = append(categories, NotifyTrafficReset)
+	}
+	slices.Sort(categories)
+	loc, err := t.settingService.GetTimeLocation()
+	if err != nil {
+		loc = time.Local
+	}
+
+	effectiveConfig.Store(&botConfig{
+		loadedAt:        time.Now(),
+		token:           token,
+		adminIds:        slices.Clone(ids),
+		runTime:         runTime,
+		timezone:        loc.String(),
+		proxy:           proxy,
+		proxyFromEgress: proxyFromEgress,
+		apiServer:       apiServer,
+		categories:      categories,
+	})
+}
+
+// pendingConfigChanges lists the settings whose stored value differs from
+// what the running bot uses, i.e. changes that need a bot restart.
+func (t *Tgbot) pendingConfigChanges(cfg *botConfig) []string {
+	var changed []string
+	if token, err := t.settingService.GetTgBotToken(); err == nil && token != cfg.token {
+		changed = append(changed, "tgBotToken")
+	}
+	if raw, err := t.settingService.GetTgBotChatId(); err == nil {
+		if ids, _ := parseAdminIds(raw); !slices.Equal(ids, cfg.adminIds) {
+			changed = append(changed, "tgBotChatId")
+		}
+	}
+	if runTime, err := t.settingService.GetTgbotRuntime(); err == nil && runTime != cfg.runTime {
+		changed = append(changed, "tgRunTime")
+	}
+	if proxy, err := t.settingService.GetTgBotProxy(); err == nil && !cfg.proxyFromEgress && proxy != cfg.proxy {
+		changed = append(changed, "tgBotProxy")
+	}
+	if apiServer, err := t.settingService.GetTgBotAPIServer(); err == nil && apiServer != cfg.apiServer {
+		changed = append(changed, "tgBotAPIServer")
+	}
+	return changed
+}
+
+// sendBotConfig shows the configuration the bot is running with.
+func (t *Tgbot) sendBotConfig(chatId int64) {
+	cfg := effectiveConfig.Load()
+	if cfg == nil {
+		t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.wentWrong"))
+		return
+	}
+
+	chats := make([]string, 0, len(cfg.adminIds))
+	for _, id := range cfg.adminIds {
+		chats = append(chats, "<code>"+strconv.FormatInt(id, 10)+"</code>")
+	}
+	proxy := t.I18nBot("tgbot.messages.botConfigNone")
+	if cfg.proxy != "" {
+		proxy = "<code>" + html.EscapeString(redactProxy(cfg.proxy)) + "</code>"
+		if cfg.proxyFromEgress {
+			proxy += " " + t.I18nBot("tgbot.messages.botConfigEgress")
+		}
+	}
+	apiServer := t.I18nBot("tgbot.messages.botConfigDefault")
+	if cfg.apiServer != "" {
+		apiServer = "<code>" + html.EscapeString(cfg.apiServer) + "</code>"
+	}
+	extraBotsMutex.Lock()
+	extra := make([]string, 0, len(extraBots))
+	for _, eb := range extraBots {
+		extra = append(extra, html.EscapeString(eb.name)+" ["+html.EscapeString(strings.Join(eb.categories, ", "))+"]")
+	}
+	extraBotsMutex.Unlock()
+	if len(extra) == 0 {
+		extra = append(extra, t.I18nBot("tgbot.messages.botConfigNone"))
+	}
+	channel := t.I18nBot("tgbot.messages.botConfigNone")
+	if id, categories, ok := t.channel(); ok {
+		channel = "<code>" + strconv.FormatInt(id, 10) + "</code> [" + html.EscapeString(strings.Join(categories, ", ")) + "]"
+	}
+
+	msg := t.I18nBot("tgbot.messages.botConfig",
+		"Loaded=="+cfg.loadedAt.Format("2006-01-02 15:04:05"),
+		"Token==<code>"+html.EscapeString(maskToken(cfg.token))+"</code>",
+		"Chats=="+strings.Join(chats, ", "),
+		"Schedule=="+t.describeSchedule(cfg.runTime),
+		"Timezone=="+html.EscapeString(cfg.timezone),
+		"Proxy=="+proxy,
+		"APIServer=="+apiServer,
+		"Categories=="+strings.Join(cfg.categories, ", "),
+		"ExtraBots=="+strings.Join(extra, "; "),
+		"Channel=="+channel)
+	if changed := t.pendingConfigChanges(cfg); len(changed) > 0 {
+		msg += "\r\n\r\n" + t.I18nBot("tgbot.messages.botConfigPending", "Settings=="+strings.Join(changed, ", "))
+	}
+	t.SendMsgToTgbot(chatId, msg)
+}
@@ -0,0 +1,85 @@
+package tgbot
+
+import (
+	"html"
+	"sync"
+	"time"
+
+	"github.com/zixu5u/3xv/v3/internal/logger"
+	"github.com/zixu5u/3xv/v3/internal/web/service"
+
+	tu "github.com/mymmrac/telego/telegoutil"
+)
+
+// xrayConfigAlert holds the config test failure found on startup until a
+// bot is up to send it. The test may finish before or after the bot starts,
+// so both sides try to send it and whoever comes second does.
+var xrayConfigAlert struct {
+	sync.Mutex
+	err     error
+	pending bool
+}
+
+// QueueXrayConfigAlert arms the critical alert for a config the Xray core
+// rejected on startup. A running bot sends it right away.
+func QueueXrayConfigAlert(err error) {
+	xrayConfigAlert.Lock()
+	xrayConfigAlert.err = err
+	xrayConfigAlert.pending = true
+	xrayConfigAlert.Unlock()
+	if isRunning {
+		go new(Tgbot).sendXrayConfigAlert()
+	}
+}
+
+// takeXrayConfigAlert returns the queued failure, if any, and clears it.
+func takeXrayConfigAlert() (error, bool) {
+	xrayConfigAlert.Lock()
+	defer xrayConfigAlert.Unlock()
+	if !xrayConfigAlert.pending {
+		return nil, false
+	}
+	xrayConfigAlert.pending = false
+	return xrayConfigAlert.err, true
+}
+
+// sendXrayConfigAlert sends the queued config failure to the admins, with a
+// button to go back to the last config Xray ran fine on when there is one.
+func (t *Tgbot) sendXrayConfigAlert() {
+	err, ok := takeXrayConfigAlert()
+	if !ok {
+		return
+	}
+	msg := t.I18nBot("tgbot.messages.xrayConfigBroken", "Error=="+html.EscapeString(err.Error()))
+	if !service.HasGoodConfig() {
+		msg += "\r\n\r\n" + t.I18nBot("tgbot.messages.xrayConfigNoSnapshot")
+		t.SendNotification(NotifyXray, msg)
+		return
+	}
+	keyboard := tu.InlineKeyboard(tu.InlineKeyboardRow(
+		tu.InlineKeyboardButton(t.I18nBot("tgbot.buttons.restoreGoodConfig")).WithCallbackData(t.encodeQuery("restore_good_config")),
+	))
+	t.SendNotification(NotifyXray, msg, keyboard)
+}
+
+// restoreGoodConfig restarts Xray on the last-known-good config and reports
+// whether the core stayed up on it.
+func (t *Tgbot) restoreGoodConfig(chatId int64, requestedBy int64) {
+	if err := t.xrayService.RestoreGoodConfig(); err != nil {
+		logger.Warningf("Restoring the last-known-good Xray config requested by %d failed: %v", requestedBy, err)
+		t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.messages.restoreGoodConfigFailed", "Error=="+html.EscapeString(err.Error())))
+		return
+	}
+	logger.Infof("Last-known-good Xray config restored by Telegram user %d", requestedBy)
+	logBotEvent(botEvent{Event: "xray_restore", ChatID: requestedBy})
+	up, healthy := t.waitXrayReady()
+	if !healthy {
+		reason := t.I18nBot("tgbot.commands.xrayNotRunning")
+		if err := t.xrayService.GetXrayErr(); err != nil {
+			reason = html.EscapeString(err.Error())
+		}
+		t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.messages.restoreGoodConfigFailed", "Error=="+reason))
+		return
+	}
+	t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.messages.restoreGoodConfigDone", "Time=="+up.Round(time.Millisecond).String()))
+}
@@ -0,0 +1,122 @@
+package tgbot
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/zixu5u/3xv/v3/internal/logger"
+	"github.com/zixu5u/3xv/v3/internal/web/service"
+	"github.com/zixu5u/3xv/v3/internal/xray"
+
+	tu "github.com/mymmrac/telego/telegoutil"
+)
+
+// connectionsLogWindow is how far back /connections looks for IPs recorded
+// from the access log when the core has no online-stats API. It matches the
+// age at which the IP limit job forgets an IP.
+const connectionsLogWindow = 30 * time.Minute
+
+// maxConnectionsShown caps the clients one /connections message lists, so
+// the message can still be edited in place; the rest are only counted.
+const maxConnectionsShown = 50
+
+// onlineConnections returns the clients that are online now with the IPs
+// they connect from. live is true when the IPs come from Xray's online-stats
+// API; otherwise the online clients are those of the last traffic poll and
+// their IPs the ones the access log recorded within connectionsLogWindow.
+func (t *Tgbot) onlineConnections(now time.Time) (users []xray.OnlineUser, live bool, err error) {
+	users, live, err = t.xrayService.GetOnlineUsers()
+	if live {
+		sortConnections(users)
+		return users, true, nil
+	}
+	if err != nil {
+		logger.Debug("Online-stats API unavailable for /connections, using the access log:", err)
+	}
+	onlines := service.XrayProcess().GetOnlineClients()
+	recent, err := t.inboundService.RecentClientIps(onlines, now.Add(-connectionsLogWindow).Unix())
+	if err != nil {
+		return nil, false, err
+	}
+	users = make([]xray.OnlineUser, 0, len(onlines))
+	for _, email := range onlines {
+		users = append(users, xray.OnlineUser{Email: email, IPs: recent[email]})
+	}
+	sortConnections(users)
+	return users, false, nil
+}
+
+// sortConnections orders clients by email and each client's IPs by when they
+// were last seen, most recent first.
+func sortConnections(users []xray.OnlineUser) {
+	slices.SortFunc(users, func(a, b xray.OnlineUser) int { return strings.Compare(a.Email, b.Email) })
+	for _, user := range users {
+		slices.SortStableFunc(user.IPs, func(a, b xray.OnlineIP) int {
+			switch {
+			case a.LastSeen > b.LastSeen:
+				return -1
+			case a.LastSeen < b.LastSeen:
+				return 1
+			}
+			return strings.Compare(a.IP, b.IP)
+		})
+	}
+}
+
+// sendConnections implements /connections: every online client with the
+// IPs it is connected from, noting whether they are live or taken from the
+// access log. With messageID the message is refreshed in place.
+func (t *Tgbot) sendConnections(chatId int64, messageID ...int) {
+	if !service.XrayProcess().IsRunning() {
+		t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.messages.connectionsXrayStopped"))
+		return
+	}
+	now := time.Now()
+	users, live, err := t.onlineConnections(now)
+	if err != nil {
+		logger.Warning("Failed to read online connections:", err)
+		t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.wentWrong"))
+		return
+	}
+	loc := t.timeLocation()
+
+	var msg strings.Builder
+	msg.WriteString(t.I18nBot("tgbot.messages.connectionsHeader", "Count=="+strconv.Itoa(len(users))))
+	if live {
+		msg.WriteString("\r\n" + t.I18nBot("tgbot.messages.connectionsLive"))
+	} else {
+		msg.WriteString("\r\n" + t.I18nBot("tgbot.messages.connectionsFromLog",
+			"Minutes=="+strconv.Itoa(int(connectionsLogWindow/time.Minute))))
+	}
+	for _, user := range users[:min(len(users), maxConnectionsShown)] {
+		msg.WriteString("\r\n\r\n" + t.I18nBot("tgbot.messages.connectionsClient",
+			"Email=="+escapeField(user.Email),
+			"Count=="+strconv.Itoa(len(user.IPs))))
+		if len(user.IPs) == 0 {
+			msg.WriteString("\r\n" + t.I18nBot("tgbot.messages.connectionsNoIp"))
+		}
+		for _, ip := range user.IPs {
+			if ip.LastSeen > 0 {
+				msg.WriteString("\r\n" + t.I18nBot("tgbot.messages.connectionsIpSeen",
+					"IP=="+escapeField(ip.IP),
+					"Time=="+time.Unix(ip.LastSeen, 0).In(loc).Format("15:04:05")))
+			} else {
+				msg.WriteString("\r\n" + t.I18nBot("tgbot.messages.connectionsIp", "IP=="+escapeField(ip.IP)))
+			}
+		}
+	}
+	if more := len(users) - maxConnectionsShown; more > 0 {
+		msg.WriteString("\r\n\r\n" + t.I18nBot("tgbot.messages.connectionsMore", "Count=="+strconv.Itoa(more)))
+	}
+	msg.WriteString(t.I18nBot("tgbot.messages.refreshedOn", "Time=="+now.In(loc).Format("2006-01-02 15:04:05")))
+
+	keyboard := tu.InlineKeyboard(tu.InlineKeyboardRow(
+		tu.InlineKeyboardButton(t.I18nBot("tgbot.buttons.refresh")).WithCallbackData(t.encodeQuery("connections_refresh"))))
+	if len(messageID) > 0 {
+		t.editMessageTgBot(chatId, messageID[0], msg.String(), keyboard)
+	} else {
+		t.SendMsgToTgbot(chatId, msg.String(), keyboard)
+	}
+}
@@ -0,0 +1,128 @@
+package tgbot
+
+import (
+	"bytes"
+	"context"
+	"html"
+	"runtime"
+	"runtime/pprof"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/zixu5u/3xv/v3/internal/logger"
+
+	tu "github.com/mymmrac/telego/telegoutil"
+)
+
+// debugStats is a snapshot of the panel's Go runtime.
+type debugStats struct {
+	Goroutines  int
+	HeapAlloc   uint64
+	HeapSys     uint64
+	HeapObjects uint64
+	Sys         uint64
+	NumGC       uint32
+	LastPause   time.Duration
+	TotalPause  time.Duration
+	LastGC      time.Time // zero before the first collection
+	MaxProcs    int
+}
+
+// newDebugStats builds a snapshot from mem and the goroutine count.
+func newDebugStats(mem *runtime.MemStats, goroutines int) debugStats {
+	stats := debugStats{
+		Goroutines:  goroutines,
+		HeapAlloc:   mem.HeapAlloc,
+		HeapSys:     mem.HeapSys,
+		HeapObjects: mem.HeapObjects,
+		Sys:         mem.Sys,
+		NumGC:       mem.NumGC,
+		TotalPause:  time.Duration(mem.PauseTotalNs),
+		MaxProcs:    runtime.GOMAXPROCS(0),
+	}
+	if mem.NumGC > 0 {
+		// PauseNs is a ring buffer; the latest pause sits at (NumGC+255)%256.
+		stats.LastPause = time.Duration(mem.PauseNs[(mem.NumGC+255)%256])
+		stats.LastGC = time.Unix(0, int64(mem.LastGC))
+	}
+	return stats
+}
+
+// parseDebugStatsArgs reads "/debugstats [goroutines]". ok is false for
+// anything else.
+func parseDebugStatsArgs(args []string) (profile bool, ok bool) {
+	switch {
+	case len(args) == 0:
+		return false, true
+	case len(args) == 1 && strings.EqualFold(args[0], "goroutines"):
+		return true, true
+	default:
+		return false, false
+	}
+}
+
+// debugStatsEnabled returns the tgDebugStats setting. It exposes the
+// panel's internals, so an unreadable setting counts as off.
+func (t *Tgbot) debugStatsEnabled() bool {
+	enabled, err := t.settingService.GetTgDebugStats()
+	if err != nil {
+		t.settingFallback("tgDebugStats", err, "false")
+		return false
+	}
+	return enabled
+}
+
+// sendDebugStats implements /debugstats: a short summary of the panel's Go
+// runtime, to tell a leaking or hung panel from a busy host. With profile
+// the full goroutine profile is sent as a document as well. Both need the
+// tgDebugStats setting.
+func (t *Tgbot) sendDebugStats(chatId int64, profile bool, requestedBy int64) {
+	if !t.debugStatsEnabled() {
+		t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.messages.debugStatsOff"))
+		return
+	}
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+	stats := newDebugStats(&mem, runtime.NumGoroutine())
+
+	lastGC := t.I18nBot("tgbot.messages.debugStatsNoGC")
+	if !stats.LastGC.IsZero() {
+		lastGC = stats.LastGC.In(t.timeLocation()).Format("2006-01-02 15:04:05")
+	}
+	msg := t.I18nBot("tgbot.messages.debugStats",
+		"Goroutines=="+strconv.Itoa(stats.Goroutines),
+		"HeapAlloc=="+formatTraffic(int64(stats.HeapAlloc)),
+		"HeapSys=="+formatTraffic(int64(stats.HeapSys)),
+		"HeapObjects=="+strconv.FormatUint(stats.HeapObjects, 10),
+		"Sys=="+formatTraffic(int64(stats.Sys)),
+		"NumGC=="+strconv.FormatUint(uint64(stats.NumGC), 10),
+		"LastPause=="+stats.LastPause.String(),
+		"TotalPause=="+stats.TotalPause.Round(time.Microsecond).String(),
+		"LastGC=="+lastGC,
+		"MaxProcs=="+strconv.Itoa(stats.MaxProcs),
+		"GoVersion=="+runtime.Version())
+	if !profile {
+		logBotEvent(botEvent{Event: "debug_stats", ChatID: requestedBy, Command: "debugstats", Outcome: "summary"})
+		t.SendMsgToTgbot(chatId, msg)
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := pprof.Lookup("goroutine").WriteTo(&buf, 1); err != nil {
+		t.SendMsgToTgbot(chatId, msg+"\r\n\r\n"+t.I18nBot("tgbot.messages.debugStatsProfileFailed", "Error=="+html.EscapeString(err.Error())))
+		return
+	}
+	logger.Infof("Goroutine profile of the panel sent to Telegram user %d", requestedBy)
+	logBotEvent(botEvent{Event: "debug_stats", ChatID: requestedBy, Command: "debugstats", Outcome: "profile"})
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	document := tu.Document(
+		tu.ID(chatId),
+		tu.FileFromBytes(buf.Bytes(), "goroutines-"+time.Now().Format("20060102-150405")+".txt"),
+	).WithCaption(msg).WithParseMode("HTML")
+	if _, err := bot.SendDocument(ctx, document); err != nil {
+		logger.Warning("Error in uploading the goroutine profile:", err)
+		t.SendMsgToTgbot(chatId, msg+"\r\n\r\n"+t.I18nBot("tgbot.messages.debugStatsProfileFailed", "Error=="+html.EscapeString(err.Error())))
+	}
+}
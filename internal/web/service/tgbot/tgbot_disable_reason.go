@@ -0,0 +1,97 @@
+package tgbot
+
+import (
+	"html"
+	"strconv"
+	"time"
+
+	"github.com/zixu5u/3xv/v3/internal/logger"
+	"github.com/zixu5u/3xv/v3/internal/web/service"
+
+	tu "github.com/mymmrac/telego/telegoutil"
+)
+
+// stateAwaitingDisableReason is the conversation state for typing a custom
+// reason. The client's email is kept in disableTargets for the same chat.
+const stateAwaitingDisableReason = "awaiting_disable_reason"
+
+var disableTargets = make(map[int64]string)
+
+// disableReasonPresets maps the preset buttons to the reason they record.
+// "none" disables without a reason.
+var disableReasonPresets = map[string]string{
+	"abuse":      "abuse",
+	"nonpayment": "non-payment",
+	"expired":    "expired",
+	"none":       "",
+}
+
+// promptDisableReason replaces the client card's keyboard with the reasons
+// a client can be disabled for.
+func (t *Tgbot) promptDisableReason(chatId int64, messageID int, email string) {
+	inlineKeyboard := tu.InlineKeyboard(
+		tu.InlineKeyboardRow(
+			tu.InlineKeyboardButton(t.I18nBot("tgbot.buttons.reasonAbuse")).WithCallbackData(t.encodeQuery("client_disable "+email+" abuse")),
+			tu.InlineKeyboardButton(t.I18nBot("tgbot.buttons.reasonNonPayment")).WithCallbackData(t.encodeQuery("client_disable "+email+" nonpayment")),
+			tu.InlineKeyboardButton(t.I18nBot("tgbot.buttons.reasonExpired")).WithCallbackData(t.encodeQuery("client_disable "+email+" expired")),
+		),
+		tu.InlineKeyboardRow(
+			tu.InlineKeyboardButton(t.I18nBot("tgbot.buttons.reasonCustom")).WithCallbackData(t.encodeQuery("client_disable_custom "+email)),
+			tu.InlineKeyboardButton(t.I18nBot("tgbot.buttons.reasonNone")).WithCallbackData(t.encodeQuery("client_disable "+email+" none")),
+		),
+		tu.InlineKeyboardRow(
+			tu.InlineKeyboardButton(t.I18nBot("tgbot.buttons.cancel")).WithCallbackData(t.encodeQuery("client_cancel "+email)),
+		),
+	)
+	t.editMessageCallbackTgBot(chatId, messageID, inlineKeyboard)
+}
+
+// promptCustomDisableReason asks for the reason as free text.
+func (t *Tgbot) promptCustomDisableReason(chatId int64, email string) {
+	userStates[chatId] = stateAwaitingDisableReason
+	disableTargets[chatId] = email
+	inlineKeyboard := tu.InlineKeyboard(
+		tu.InlineKeyboardRow(
+			tu.InlineKeyboardButton(t.I18nBot("tgbot.buttons.cancel")).WithCallbackData(t.encodeQuery("client_cancel " + email)),
+		),
+	)
+	t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.messages.disableReasonPrompt",
+		"Email=="+escapeField(email),
+		"Max=="+strconv.Itoa(service.MaxDisableReasonLength)), inlineKeyboard)
+}
+
+// disableClient disables a client with reason and shows its card again,
+// editing messageID when given.
+func (t *Tgbot) disableClient(chatId int64, email string, reason string, requestedBy int64, messageID ...int) {
+	needRestart, err := t.clientService.DisableClientWithReason(&t.inboundService, email, reason, telegramActor(requestedBy))
+	if needRestart {
+		t.xrayService.SetToNeedRestart()
+	}
+	logBotEvent(botEvent{Event: "client_disable", ChatID: requestedBy, Command: "toggle_enable", Err: err})
+	if err != nil {
+		logger.Warning("Failed to disable client:", err)
+		t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.messages.disableFailed", "Email=="+escapeField(email), "Error=="+html.EscapeString(err.Error())))
+		return
+	}
+	t.searchClient(chatId, email, messageID...)
+}
+
+// clientDisableReasonLine renders why a disabled client was disabled, or ""
+// when the client is enabled or no record was kept.
+func (t *Tgbot) clientDisableReasonLine(email string, enabled bool) string {
+	if enabled {
+		return ""
+	}
+	record, err := t.clientService.GetDisableReason(email)
+	if err != nil || record == nil {
+		return ""
+	}
+	reason := record.Reason
+	if reason == "" {
+		reason = "—"
+	}
+	return t.I18nBot("tgbot.messages.disableReason",
+		"Reason=="+escapeField(reason),
+		"By=="+escapeField(record.DisabledBy),
+		"Time=="+time.UnixMilli(record.DisabledAt).In(t.timeLocation()).Format("2006-01-02 15:04"))
+}
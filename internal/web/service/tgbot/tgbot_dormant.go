@@ -0,0 +1,155 @@
+package tgbot
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/zixu5u/3xv/v3/internal/logger"
+
+	"github.com/mymmrac/telego"
+	tu "github.com/mymmrac/telego/telegoutil"
+)
+
+const (
+	// defaultDormantDays is the dormancy window used by /dormant without
+	// an argument.
+	defaultDormantDays = 30
+	maxDormantDays     = 3650
+	dormantPageSize    = 20
+)
+
+// parseDormantDays reads the optional window argument of /dormant.
+func parseDormantDays(args []string) (int, error) {
+	if len(args) == 0 {
+		return defaultDormantDays, nil
+	}
+	days, err := strconv.Atoi(strings.TrimSuffix(strings.ToLower(args[0]), "d"))
+	if err != nil || days < 1 || days > maxDormantDays {
+		return 0, errors.New("invalid dormancy window")
+	}
+	return days, nil
+}
+
+// dormantWindow converts a dormancy window in days to a duration.
+func dormantWindow(days int) time.Duration {
+	return time.Duration(days) * 24 * time.Hour
+}
+
+// sendDormantClients lists enabled clients without traffic for the last
+// days, one page at a time. With messageID the existing list is edited in
+// place.
+func (t *Tgbot) sendDormantClients(chatId int64, days int, page int, messageID ...int) {
+	clients, err := t.clientService.GetDormantClients(dormantWindow(days))
+	if err != nil {
+		logger.Warning("Failed to get dormant clients:", err)
+		t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.wentWrong"))
+		return
+	}
+	daysStr := strconv.Itoa(days)
+	if len(clients) == 0 {
+		t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.messages.dormantNone", "Days=="+daysStr))
+		return
+	}
+
+	pages := (len(clients) + dormantPageSize - 1) / dormantPageSize
+	page = max(1, min(page, pages))
+	start := (page - 1) * dormantPageSize
+	end := min(start+dormantPageSize, len(clients))
+
+	var output strings.Builder
+	output.WriteString(t.I18nBot("tgbot.messages.dormantHeader",
+		"Count=="+strconv.Itoa(len(clients)),
+		"Days=="+daysStr))
+	for _, c := range clients[start:end] {
+		lastSeen := t.I18nBot("tgbot.messages.dormantNeverUsed")
+		if c.LastOnline > 0 {
+			lastSeen = time.UnixMilli(c.LastOnline).Format("2006-01-02")
+		}
+		output.WriteString("\r\n<code>" + escapeField(c.Email) + "</code> — " + lastSeen)
+	}
+	if pages > 1 {
+		output.WriteString("\r\n\r\n" + t.I18nBot("tgbot.messages.dormantPage",
+			"Page=="+strconv.Itoa(page),
+			"Pages=="+strconv.Itoa(pages)))
+	}
+
+	var rows [][]telego.InlineKeyboardButton
+	var nav []telego.InlineKeyboardButton
+	if page > 1 {
+		nav = append(nav, tu.InlineKeyboardButton("⬅️").WithCallbackData(t.encodeQuery("dormant_page "+daysStr+" "+strconv.Itoa(page-1))))
+	}
+	if page < pages {
+		nav = append(nav, tu.InlineKeyboardButton("➡️").WithCallbackData(t.encodeQuery("dormant_page "+daysStr+" "+strconv.Itoa(page+1))))
+	}
+	if len(nav) > 0 {
+		rows = append(rows, nav)
+	}
+	rows = append(rows, tu.InlineKeyboardRow(
+		tu.InlineKeyboardButton(t.I18nBot("tgbot.buttons.disableDormant")).WithCallbackData(t.encodeQuery("dormant_disable "+daysStr)),
+	))
+	keyboard := tu.InlineKeyboard(rows...)
+
+	if len(messageID) > 0 {
+		t.editMessageTgBot(chatId, messageID[0], output.String(), keyboard)
+		return
+	}
+	t.SendMsgToTgbot(chatId, output.String(), keyboard)
+}
+
+// confirmDisableDormant asks before disabling every dormant client.
+func (t *Tgbot) confirmDisableDormant(chatId int64, days int) {
+	clients, err := t.clientService.GetDormantClients(dormantWindow(days))
+	if err != nil {
+		logger.Warning("Failed to get dormant clients:", err)
+		t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.wentWrong"))
+		return
+	}
+	daysStr := strconv.Itoa(days)
+	if len(clients) == 0 {
+		t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.messages.dormantNone", "Days=="+daysStr))
+		return
+	}
+	keyboard := tu.InlineKeyboard(
+		tu.InlineKeyboardRow(
+			tu.InlineKeyboardButton(t.I18nBot("tgbot.buttons.confirmDisableDormant")).WithCallbackData(t.encodeQuery("dormant_disable_confirm "+daysStr)),
+			tu.InlineKeyboardButton(t.I18nBot("tgbot.buttons.cancel")).WithCallbackData(t.encodeQuery("dormant_page "+daysStr+" 1")),
+		),
+	)
+	t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.messages.dormantDisableConfirm",
+		"Count=="+strconv.Itoa(len(clients)),
+		"Days=="+daysStr), keyboard)
+}
+
+// disableDormantClients disables every client that is still dormant. The
+// list is rebuilt so clients that came back online since it was shown are
+// left alone.
+func (t *Tgbot) disableDormantClients(chatId int64, days int, requestedBy int64) {
+	clients, err := t.clientService.GetDormantClients(dormantWindow(days))
+	if err != nil {
+		logger.Warning("Failed to get dormant clients:", err)
+		t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.wentWrong"))
+		return
+	}
+	disabled, failed := 0, 0
+	needRestart := false
+	for _, c := range clients {
+		ok, restart, err := t.clientService.SetClientEnableByEmail(&t.inboundService, c.Email, false)
+		needRestart = needRestart || restart
+		if err != nil || !ok {
+			logger.Warningf("Failed to disable dormant client %s: %v", c.Email, err)
+			failed++
+			continue
+		}
+		disabled++
+	}
+	if needRestart {
+		t.xrayService.SetToNeedRestart()
+	}
+	logger.Infof("%d dormant clients (%d days) disabled by Telegram user %d, %d failed", disabled, days, requestedBy, failed)
+	logBotEvent(botEvent{Event: "dormant_disable", ChatID: requestedBy, Command: "dormant"})
+	t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.messages.dormantDisabled",
+		"Count=="+strconv.Itoa(disabled),
+		"Failed=="+strconv.Itoa(failed)))
+}
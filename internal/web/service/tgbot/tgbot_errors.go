@@ -0,0 +1,77 @@
+package tgbot
+
+import (
+	"errors"
+	"html"
+	"strconv"
+	"strings"
+
+	"github.com/zixu5u/3xv/v3/internal/logger"
+	"github.com/zixu5u/3xv/v3/internal/web/service"
+)
+
+const (
+	// defaultErrorPatterns is how many error patterns /errors lists without
+	// an argument.
+	defaultErrorPatterns = 10
+	// maxErrorPatterns bounds the count /errors accepts, so the summary fits
+	// in one message.
+	maxErrorPatterns = 30
+)
+
+// parseErrorsArgs reads "/errors [n]". ok is false for anything else.
+func parseErrorsArgs(args []string) (n int, ok bool) {
+	switch len(args) {
+	case 0:
+		return defaultErrorPatterns, true
+	case 1:
+		n, err := strconv.Atoi(args[0])
+		if err != nil || n < 1 || n > maxErrorPatterns {
+			return 0, false
+		}
+		return n, true
+	default:
+		return 0, false
+	}
+}
+
+// sendXrayErrors implements /errors: the n error patterns most recently
+// seen in the Xray error log, each with how often it occurred, instead of
+// the raw lines. The log holds client addresses, so only admins get it.
+func (t *Tgbot) sendXrayErrors(chatId int64, n int) {
+	patterns, total, path, err := t.serverService.RecentXrayErrors(n)
+	if errors.Is(err, service.ErrXrayErrorLogOff) {
+		t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.messages.xrayErrorsLogOff"))
+		return
+	}
+	if err != nil {
+		logger.Warning("Failed to read the Xray error log:", err)
+		t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.messages.xrayErrorsUnreadable",
+			"Error=="+html.EscapeString(err.Error())))
+		return
+	}
+
+	var source string
+	if path == "" {
+		source = t.I18nBot("tgbot.messages.xrayErrorsFromPanel")
+	} else {
+		source = t.I18nBot("tgbot.messages.xrayErrorsFromFile", "Path=="+html.EscapeString(path))
+	}
+	if len(patterns) == 0 {
+		t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.messages.xrayErrorsNone")+"\r\n"+source)
+		return
+	}
+
+	var msg strings.Builder
+	msg.WriteString(t.I18nBot("tgbot.messages.xrayErrorsHeader",
+		"Count=="+strconv.Itoa(len(patterns)),
+		"Lines=="+strconv.Itoa(total)))
+	msg.WriteString("\r\n" + source)
+	for _, p := range patterns {
+		msg.WriteString("\r\n\r\n" + t.I18nBot("tgbot.messages.xrayErrorsPattern",
+			"Count=="+strconv.Itoa(p.Count),
+			"Signature=="+html.EscapeString(p.Signature),
+			"Time=="+html.EscapeString(p.LastSeen)))
+	}
+	t.SendMsgToTgbot(chatId, msg.String())
+}
@@ -0,0 +1,29 @@
+package tgbot
+
+import (
+	"html"
+	"strings"
+	"unicode/utf8"
+)
+
+// maxFieldRunes caps a user-controlled value interpolated into a message, so
+// a huge tag or email can't push a message page past Telegram's limit.
+const maxFieldRunes = 256
+
+// escapeField prepares a user-controlled value such as an inbound tag or
+// remark, a client email or a login username for a message sent with the
+// HTML parse mode. Invalid UTF-8 is replaced, overlong values are cut short
+// and the HTML metacharacters are escaped. Markdown characters need no
+// escaping in this mode and are kept as they are.
+func escapeField(value string) string {
+	value = strings.ToValidUTF8(value, "�")
+	return html.EscapeString(truncateRunes(value, maxFieldRunes))
+}
+
+// truncateRunes cuts value to at most n runes, marking a cut with "…".
+func truncateRunes(value string, n int) string {
+	if utf8.RuneCountInString(value) <= n {
+		return value
+	}
+	return string([]rune(value)[:n-1]) + "…"
+}
@@ -0,0 +1,76 @@
+package tgbot
+
+import (
+	"encoding/json"
+	"sync/atomic"
+	"time"
+
+	"github.com/zixu5u/3xv/v3/internal/logger"
+)
+
+// jsonEventLog mirrors the tgBotJsonLog setting. It is refreshed on every
+// bot Start so logging an event never touches the database.
+var jsonEventLog atomic.Bool
+
+// botEvent is one structured bot event: a command, a notification delivery,
+// or an alert. Zero-valued fields are left out of the JSON line.
+type botEvent struct {
+	Event    string
+	Bot      string
+	ChatID   int64
+	Command  string
+	Category string
+	Outcome  string
+	Latency  time.Duration
+	Err      error
+}
+
+// botEventRecord is the wire format of a botEvent.
+type botEventRecord struct {
+	Event     string `json:"event"`
+	Bot       string `json:"bot,omitempty"`
+	ChatID    int64  `json:"chatId,omitempty"`
+	Command   string `json:"command,omitempty"`
+	Category  string `json:"category,omitempty"`
+	Outcome   string `json:"outcome"`
+	LatencyMs int64  `json:"latencyMs"`
+	Error     string `json:"error,omitempty"`
+}
+
+// marshalBotEvent renders e as a single JSON line. An event with an error
+// and no explicit outcome is reported as "failed", otherwise as "ok".
+func marshalBotEvent(e botEvent) ([]byte, error) {
+	record := botEventRecord{
+		Event:     e.Event,
+		Bot:       e.Bot,
+		ChatID:    e.ChatID,
+		Command:   e.Command,
+		Category:  e.Category,
+		Outcome:   e.Outcome,
+		LatencyMs: e.Latency.Milliseconds(),
+	}
+	if e.Err != nil {
+		record.Error = e.Err.Error()
+	}
+	if record.Outcome == "" {
+		record.Outcome = "ok"
+		if e.Err != nil {
+			record.Outcome = "failed"
+		}
+	}
+	return json.Marshal(record)
+}
+
+// logBotEvent writes e as a "tgbot_event {...}" log line when structured
+// logging is enabled. The free-form log lines are kept either way.
+func logBotEvent(e botEvent) {
+	if !jsonEventLog.Load() {
+		return
+	}
+	line, err := marshalBotEvent(e)
+	if err != nil {
+		logger.Warning("marshal bot event failed:", err)
+		return
+	}
+	logger.Info("tgbot_event " + string(line))
+}
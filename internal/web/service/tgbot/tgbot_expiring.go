@@ -0,0 +1,158 @@
+package tgbot
+
+import (
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/zixu5u/3xv/v3/internal/database/model"
+	"github.com/zixu5u/3xv/v3/internal/logger"
+
+	"github.com/mymmrac/telego"
+	tu "github.com/mymmrac/telego/telegoutil"
+)
+
+const (
+	// defaultExpiringDays is the window used by /expiring without an
+	// argument.
+	defaultExpiringDays = 7
+	maxExpiringDays     = 3650
+	expiringPageSize    = 20
+)
+
+// expiringEntry is an inbound, or a client when email is set, with a fixed
+// expiry date.
+type expiringEntry struct {
+	remark     string // inbound remark
+	tag        string // inbound tag
+	email      string
+	expiryTime int64 // unix ms
+}
+
+// parseExpiringArgs reads "/expiring [days] [clients]".
+func parseExpiringArgs(args []string) (days int, withClients bool, err error) {
+	days = defaultExpiringDays
+	if len(args) > 2 {
+		return 0, false, errors.New("too many arguments")
+	}
+	for _, arg := range args {
+		arg = strings.ToLower(arg)
+		if arg == "clients" || arg == "client" {
+			withClients = true
+			continue
+		}
+		days, err = strconv.Atoi(strings.TrimSuffix(arg, "d"))
+		if err != nil || days < 1 || days > maxExpiringDays {
+			return 0, false, errors.New("invalid expiry window")
+		}
+	}
+	return days, withClients, nil
+}
+
+// collectExpiring returns the inbounds, and with withClients their clients,
+// whose expiry date lies before now plus days, soonest first. Entries that
+// already expired are included and come first. Clients with a delayed start
+// have no date yet and are left out.
+func collectExpiring(inbounds []*model.Inbound, days int, withClients bool, now time.Time) []expiringEntry {
+	limit := now.Add(time.Duration(days) * 24 * time.Hour).UnixMilli()
+	var entries []expiringEntry
+	for _, inbound := range inbounds {
+		if inbound.ExpiryTime > 0 && inbound.ExpiryTime <= limit {
+			entries = append(entries, expiringEntry{remark: inbound.Remark, tag: inbound.Tag, expiryTime: inbound.ExpiryTime})
+		}
+		if !withClients {
+			continue
+		}
+		for _, client := range inbound.ClientStats {
+			if client.ExpiryTime > 0 && client.ExpiryTime <= limit {
+				entries = append(entries, expiringEntry{remark: inbound.Remark, tag: inbound.Tag, email: client.Email, expiryTime: client.ExpiryTime})
+			}
+		}
+	}
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].expiryTime < entries[j].expiryTime })
+	return entries
+}
+
+// daysUntil is the number of days from now to expiryTime, rounded up, so an
+// entry expiring later today has 1 day left. It is negative or zero once
+// the entry expired: -2 means two full days ago.
+func daysUntil(expiryTime int64, now time.Time) int {
+	left := time.UnixMilli(expiryTime).Sub(now)
+	if left <= 0 {
+		return int(left / (24 * time.Hour))
+	}
+	return int((left + 24*time.Hour - 1) / (24 * time.Hour))
+}
+
+// sendExpiring lists what expires within days, one page at a time. With
+// messageID the existing list is edited in place.
+func (t *Tgbot) sendExpiring(chatId int64, days int, withClients bool, page int, messageID ...int) {
+	inbounds, err := t.inboundService.GetAllInbounds()
+	if err != nil {
+		logger.Warning("Failed to get inbounds for /expiring:", err)
+		t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.wentWrong"))
+		return
+	}
+	now := time.Now()
+	entries := collectExpiring(inbounds, days, withClients, now)
+	daysStr := strconv.Itoa(days)
+	if len(entries) == 0 {
+		t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.messages.expiringNone", "Days=="+daysStr))
+		return
+	}
+
+	pages := (len(entries) + expiringPageSize - 1) / expiringPageSize
+	page = max(1, min(page, pages))
+	start := (page - 1) * expiringPageSize
+	end := min(start+expiringPageSize, len(entries))
+
+	loc := t.timeLocation()
+	var output strings.Builder
+	output.WriteString(t.I18nBot("tgbot.messages.expiringHeader",
+		"Count=="+strconv.Itoa(len(entries)),
+		"Days=="+daysStr))
+	for _, e := range entries[start:end] {
+		name := "<b>" + escapeField(e.remark) + "</b> <code>" + escapeField(e.tag) + "</code>"
+		if e.email != "" {
+			name = "👤 <code>" + escapeField(e.email) + "</code> · " + escapeField(e.remark)
+		}
+		date := time.UnixMilli(e.expiryTime).In(loc).Format("2006-01-02 15:04")
+		left := daysUntil(e.expiryTime, now)
+		if left <= 0 {
+			output.WriteString("\r\n⛔ " + name + " — " + date + " · " +
+				t.I18nBot("tgbot.messages.expiringExpired", "Days=="+strconv.Itoa(-left)))
+		} else {
+			output.WriteString("\r\n⏳ " + name + " — " + date + " · " +
+				t.I18nBot("tgbot.messages.expiringLeft", "Days=="+strconv.Itoa(left)))
+		}
+	}
+	if pages > 1 {
+		output.WriteString("\r\n\r\n" + t.I18nBot("tgbot.messages.dormantPage",
+			"Page=="+strconv.Itoa(page),
+			"Pages=="+strconv.Itoa(pages)))
+	}
+
+	clients := "0"
+	if withClients {
+		clients = "1"
+	}
+	var nav []telego.InlineKeyboardButton
+	if page > 1 {
+		nav = append(nav, tu.InlineKeyboardButton("⬅️").WithCallbackData(t.encodeQuery("expiring_page "+daysStr+" "+clients+" "+strconv.Itoa(page-1))))
+	}
+	if page < pages {
+		nav = append(nav, tu.InlineKeyboardButton("➡️").WithCallbackData(t.encodeQuery("expiring_page "+daysStr+" "+clients+" "+strconv.Itoa(page+1))))
+	}
+	if len(nav) == 0 {
+		t.SendMsgToTgbot(chatId, output.String())
+		return
+	}
+	keyboard := tu.InlineKeyboard(nav)
+	if len(messageID) > 0 {
+		t.editMessageTgBot(chatId, messageID[0], output.String(), keyboard)
+		return
+	}
+	t.SendMsgToTgbot(chatId, output.String(), keyboard)
+}
@@ -0,0 +1,147 @@
+package tgbot
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/zixu5u/3xv/v3/internal/logger"
+)
+
+// chronicFailureStreak is the number of consecutive failed deliveries after
+// which a recipient is called out in the fan-out summary as a cleanup
+// candidate.
+const chronicFailureStreak = 3
+
+// failureStreaks counts consecutive failed deliveries per chat, across all
+// fan-outs, and lastFailures keeps how the latest of them failed. A
+// successful delivery resets both.
+var (
+	failureStreaksMutex sync.Mutex
+	failureStreaks      = map[int64]int{}
+	lastFailures        = map[int64]string{}
+)
+
+// chronicChat is a recipient that has failed chronicFailureStreak or more
+// deliveries in a row.
+type chronicChat struct {
+	ChatId int64
+	Streak int
+	Kind   string // classifyDelivery of the latest failure
+}
+
+// fanOutResult is the per-chat outcome of one fan-out.
+type fanOutResult struct {
+	delivered []int64
+	failed    map[int64]error
+}
+
+// fanOut calls send for every chat in ids, in order, regardless of what the
+// previous sends returned: a blocked or invalid chat never keeps the rest
+// from being served. A panicking send counts as a failure for that chat.
+func fanOut(ids []int64, send func(chatId int64) error) fanOutResult {
+	result := fanOutResult{failed: map[int64]error{}}
+	for _, chatId := range ids {
+		err := sendRecovered(chatId, send)
+		recordDelivery(chatId, err)
+		if err != nil {
+			result.failed[chatId] = err
+			continue
+		}
+		result.delivered = append(result.delivered, chatId)
+	}
+	return result
+}
+
+func sendRecovered(chatId int64, send func(chatId int64) error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic while sending to %d: %v", chatId, r)
+		}
+	}()
+	return send(chatId)
+}
+
+func recordDelivery(chatId int64, err error) {
+	failureStreaksMutex.Lock()
+	defer failureStreaksMutex.Unlock()
+	if err == nil {
+		delete(failureStreaks, chatId)
+		delete(lastFailures, chatId)
+		return
+	}
+	failureStreaks[chatId]++
+	lastFailures[chatId] = classifyDelivery(err)
+}
+
+// failureStreak returns the number of consecutive failed deliveries to
+// chatId.
+func failureStreak(chatId int64) int {
+	failureStreaksMutex.Lock()
+	defer failureStreaksMutex.Unlock()
+	return failureStreaks[chatId]
+}
+
+// chronicChats returns the recipients failing chronicFailureStreak or more
+// times in a row, longest streak first.
+func chronicChats() []chronicChat {
+	failureStreaksMutex.Lock()
+	defer failureStreaksMutex.Unlock()
+	var chats []chronicChat
+	for chatId, streak := range failureStreaks {
+		if streak >= chronicFailureStreak {
+			chats = append(chats, chronicChat{ChatId: chatId, Streak: streak, Kind: lastFailures[chatId]})
+		}
+	}
+	slices.SortFunc(chats, func(a, b chronicChat) int {
+		if c := cmp.Compare(b.Streak, a.Streak); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.ChatId, b.ChatId)
+	})
+	return chats
+}
+
+// logSummary logs the failed recipients of a fan-out, if any, and flags the
+// ones that keep failing. The same failures of the same fan-out are logged
+// once per sendFailureLogWindow.
+func (r fanOutResult) logSummary(what string) {
+	if len(r.failed) == 0 {
+		return
+	}
+	ids := make([]int64, 0, len(r.failed))
+	for chatId := range r.failed {
+		ids = append(ids, chatId)
+	}
+	slices.Sort(ids)
+
+	failed := make([]string, 0, len(ids))
+	var chronic []string
+	for _, chatId := range ids {
+		failed = append(failed, fmt.Sprintf("%d (%s)", chatId, classifyDelivery(r.failed[chatId])))
+		if failureStreak(chatId) >= chronicFailureStreak {
+			chronic = append(chronic, strconv.FormatInt(chatId, 10))
+		}
+	}
+	logOk, suppressed := sendFailureLog.allow(what+" "+strings.Join(failed, ", "), time.Now())
+	if !logOk {
+		return
+	}
+	logger.Warningf("%s: delivered to %d of %d chats, failed: %s%s",
+		what, len(r.delivered), len(r.delivered)+len(r.failed), strings.Join(failed, ", "), repeatsNote(suppressed))
+	if len(chronic) > 0 {
+		logger.Warningf("%s: chats failing %d or more times in a row, consider removing them: %s",
+			what, chronicFailureStreak, strings.Join(chronic, ", "))
+	}
+}
+
+// adminChatIds returns a snapshot of the primary bot's admin chats.
+func adminChatIds() []int64 {
+	tgBotMutex.Lock()
+	defer tgBotMutex.Unlock()
+	return slices.Clone(adminIds)
+}
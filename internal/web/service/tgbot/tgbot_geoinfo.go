@@ -0,0 +1,85 @@
+package tgbot
+
+import (
+	"errors"
+	"html"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/zixu5u/3xv/v3/internal/web/service"
+)
+
+// defaultGeoIPFile is the GeoIP file /geoinfo checks without an argument.
+const defaultGeoIPFile = "geoip.dat"
+
+// parseGeoInfoArgs reads "/geoinfo [file]", where file is one of the
+// geoip*.dat files in Xray's bin folder. ok is false for anything else.
+func parseGeoInfoArgs(args []string) (file string, ok bool) {
+	switch len(args) {
+	case 0:
+		return defaultGeoIPFile, true
+	case 1:
+		// Regional files are named like geoip_IR.dat.
+		if !strings.HasPrefix(args[0], "geoip") || !strings.HasSuffix(args[0], ".dat") {
+			return "", false
+		}
+		return args[0], true
+	default:
+		return "", false
+	}
+}
+
+// geoIPStale reports whether a GeoIP file last updated at modTime is older
+// than maxAgeDays. A maxAgeDays of 0 never counts as stale.
+func geoIPStale(modTime time.Time, now time.Time, maxAgeDays int) bool {
+	return maxAgeDays > 0 && now.Sub(modTime) > time.Duration(maxAgeDays)*24*time.Hour
+}
+
+// sendGeoInfo implements /geoinfo: it reads one of Xray's GeoIP files,
+// reports its age and size, and looks up a well-known address in it, so an
+// admin can tell whether the file used by geoip: routing rules is present,
+// readable and current.
+func (t *Tgbot) sendGeoInfo(chatId int64, file string) {
+	info, err := t.serverService.GetGeoIPInfo(file, net.ParseIP(service.GeoIPSampleIP))
+	if errors.Is(err, os.ErrNotExist) {
+		t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.messages.geoInfoMissing", "File=="+html.EscapeString(file)))
+		return
+	}
+	if err != nil {
+		t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.messages.geoInfoUnreadable",
+			"File=="+html.EscapeString(file),
+			"Error=="+html.EscapeString(err.Error())))
+		return
+	}
+
+	now := time.Now()
+	ageDays := int(now.Sub(info.ModTime).Hours() / 24)
+	msg := t.I18nBot("tgbot.messages.geoInfo",
+		"Path=="+html.EscapeString(info.Path),
+		"Updated=="+info.ModTime.In(t.timeLocation()).Format("2006-01-02 15:04"),
+		"Age=="+strconv.Itoa(ageDays),
+		"Size=="+formatTraffic(info.Size),
+		"Codes=="+strconv.Itoa(info.Codes),
+		"IPv4=="+strconv.Itoa(info.IPv4),
+		"IPv6=="+strconv.Itoa(info.IPv6))
+	if len(info.SampleCodes) > 0 {
+		msg += t.I18nBot("tgbot.messages.geoInfoSample",
+			"IP=="+service.GeoIPSampleIP,
+			"Codes=="+html.EscapeString(strings.Join(info.SampleCodes, ", ")))
+	} else {
+		msg += t.I18nBot("tgbot.messages.geoInfoNoMatch", "IP=="+service.GeoIPSampleIP)
+	}
+
+	maxAge, err := t.settingService.GetTgGeoMaxAge()
+	if err != nil {
+		t.settingFallback("tgGeoMaxAge", err, "30")
+		maxAge = 30
+	}
+	if geoIPStale(info.ModTime, now, maxAge) {
+		msg += t.I18nBot("tgbot.messages.geoInfoStale", "Days=="+strconv.Itoa(maxAge))
+	}
+	t.SendMsgToTgbot(chatId, msg)
+}
@@ -0,0 +1,209 @@
+package tgbot
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/zixu5u/3xv/v3/internal/logger"
+)
+
+const (
+	// heartbeatInterval is how often the bot checks that Telegram answers.
+	heartbeatInterval = time.Minute
+	// heartbeatTimeout bounds a single check.
+	heartbeatTimeout = 15 * time.Second
+	// heartbeatFailures is how many checks in a row must fail before the
+	// connection counts as lost, so a single slow request doesn't alert.
+	heartbeatFailures = 3
+	// fallbackWebhookTimeout bounds a POST to the fallback webhook.
+	fallbackWebhookTimeout = 10 * time.Second
+)
+
+// heartbeatChange is what a single check changed about the connection.
+type heartbeatChange int
+
+const (
+	heartbeatSteady heartbeatChange = iota
+	heartbeatLost
+	heartbeatRecovered
+)
+
+// heartbeatTracker counts failed checks and reports when the connection is
+// lost and when it comes back.
+type heartbeatTracker struct {
+	failures  int
+	down      bool
+	downSince time.Time // time of the first failed check of the outage
+}
+
+// observe records the result of one check made at now.
+func (h *heartbeatTracker) observe(err error, now time.Time) heartbeatChange {
+	if err == nil {
+		h.failures = 0
+		if h.down {
+			h.down = false
+			return heartbeatRecovered
+		}
+		h.downSince = time.Time{}
+		return heartbeatSteady
+	}
+	if h.failures == 0 {
+		h.downSince = now
+	}
+	h.failures++
+	if !h.down && h.failures >= heartbeatFailures {
+		h.down = true
+		return heartbeatLost
+	}
+	return heartbeatSteady
+}
+
+// heartbeat holds the running check loop, if any.
+var heartbeat struct {
+	sync.Mutex
+	cancel context.CancelFunc
+}
+
+// fallbackEvent is the JSON body posted to the fallback webhook.
+type fallbackEvent struct {
+	Event    string `json:"event"`
+	Host     string `json:"host"`
+	Since    string `json:"since"`
+	Time     string `json:"time"`
+	Failures int    `json:"failures,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+// startHeartbeat starts checking the Telegram connection in the background,
+// replacing a loop left from a previous start.
+func (t *Tgbot) startHeartbeat() {
+	heartbeat.Lock()
+	defer heartbeat.Unlock()
+	if heartbeat.cancel != nil {
+		heartbeat.cancel()
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	heartbeat.cancel = cancel
+	go t.runHeartbeat(ctx)
+}
+
+// stopHeartbeat stops the check loop.
+func stopHeartbeat() {
+	heartbeat.Lock()
+	defer heartbeat.Unlock()
+	if heartbeat.cancel != nil {
+		heartbeat.cancel()
+		heartbeat.cancel = nil
+	}
+}
+
+func (t *Tgbot) runHeartbeat(ctx context.Context) {
+	ticker := time.NewTicker(heartbeatInterval)
+	defer ticker.Stop()
+	var tracker heartbeatTracker
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		err := checkTelegram(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		now := time.Now()
+		switch tracker.observe(err, now) {
+		case heartbeatLost:
+			t.onTelegramLost(tracker, err, now)
+		case heartbeatRecovered:
+			t.onTelegramRecovered(tracker.downSince, now)
+		}
+	}
+}
+
+// checkTelegram asks Telegram who the bot is, the cheapest authenticated
+// call there is.
+func checkTelegram(ctx context.Context) error {
+	b := bot
+	if b == nil {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, heartbeatTimeout)
+	defer cancel()
+	_, err := b.GetMe(ctx)
+	return err
+}
+
+// onTelegramLost runs once per outage. Telegram can't carry the alert, so
+// it goes to the log and the fallback webhook instead.
+func (t *Tgbot) onTelegramLost(tracker heartbeatTracker, err error, now time.Time) {
+	logger.Errorf("[tgbot] TELEGRAM UNREACHABLE: %d connection checks failed since %s, bot notifications are not being delivered: %v",
+		tracker.failures, tracker.downSince.Format(time.DateTime), err)
+	logBotEvent(botEvent{Event: "heartbeat", Outcome: "lost", Err: err})
+	t.postFallbackWebhook(fallbackEvent{
+		Event:    "telegram_unreachable",
+		Host:     hostname,
+		Since:    tracker.downSince.Format(time.RFC3339),
+		Time:     now.Format(time.RFC3339),
+		Failures: tracker.failures,
+		Error:    err.Error(),
+	})
+}
+
+// onTelegramRecovered tells the admins, and the fallback webhook, that the
+// bot is reachable again and for how long it wasn't.
+func (t *Tgbot) onTelegramRecovered(downSince time.Time, now time.Time) {
+	downtime := now.Sub(downSince).Round(time.Second)
+	logger.Infof("[tgbot] Telegram reachable again after %s", downtime)
+	logBotEvent(botEvent{Event: "heartbeat", Outcome: "recovered", Latency: downtime})
+	t.postFallbackWebhook(fallbackEvent{
+		Event: "telegram_recovered",
+		Host:  hostname,
+		Since: downSince.Format(time.RFC3339),
+		Time:  now.Format(time.RFC3339),
+	})
+	t.SendMsgToTgbotAdmins(t.I18nBot("tgbot.messages.heartbeatRecovered",
+		"Since=="+downSince.Format(time.DateTime),
+		"Downtime=="+downtime.String()))
+}
+
+// postFallbackWebhook posts event to the tgBotFallbackWebhook URL, if set.
+// It goes out directly, not through the bot proxy, which may be what broke.
+func (t *Tgbot) postFallbackWebhook(event fallbackEvent) {
+	url, err := t.settingService.GetTgBotFallbackWebhook()
+	if err != nil {
+		logger.Warning("Unable to read the Telegram fallback webhook:", err)
+		return
+	}
+	if url == "" {
+		return
+	}
+	body, err := json.Marshal(event)
+	if err != nil {
+		logger.Warning("Unable to encode the Telegram fallback webhook event:", err)
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), fallbackWebhookTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		logger.Warning("Invalid Telegram fallback webhook:", err)
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		logger.Warning("Telegram fallback webhook failed:", err)
+		return
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		logger.Warningf("Telegram fallback webhook answered %s", resp.Status)
+		return
+	}
+	logger.Infof("Telegram fallback webhook notified: %s", event.Event)
+}
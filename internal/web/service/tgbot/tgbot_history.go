@@ -0,0 +1,179 @@
+package tgbot
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mymmrac/telego"
+	tu "github.com/mymmrac/telego/telegoutil"
+)
+
+const (
+	// historyPerChat bounds the commands kept for each chat.
+	historyPerChat = 50
+	// historyPageSize is the number of commands on a /history page.
+	historyPageSize = 10
+	// historyButtonRunes caps the command shown on a re-run button.
+	historyButtonRunes = 40
+)
+
+// historyEntry is one command an admin ran. Seq identifies it for the
+// re-run button, since positions shift as new commands come in.
+type historyEntry struct {
+	Seq     uint64
+	Command string
+	Args    []string
+	At      time.Time
+}
+
+// Text renders the entry as it would be typed.
+func (e historyEntry) Text() string {
+	return strings.Join(append([]string{"/" + e.Command}, e.Args...), " ")
+}
+
+// commandHistory keeps the recent commands of each chat in memory, newest
+// last, for /history. Only recognized admin commands are recorded, so
+// arbitrary input can't grow it.
+var commandHistory = struct {
+	sync.Mutex
+	seq     uint64
+	entries map[int64][]historyEntry
+}{entries: make(map[int64][]historyEntry)}
+
+// rerunnableCommands are the commands /history offers to run again: they
+// only read state, so an accidental tap changes nothing.
+var rerunnableCommands = map[string]bool{
+	"status": true, "usage": true, "inbound": true, "subscription": true,
+	"dormant": true, "expiring": true, "trend": true, "pool": true,
+	"muted": true, "botstats": true, "reminders": true, "listchats": true,
+	"blocklist": true, "perf": true, "cronstatus": true, "botconfig": true,
+	"test": true, "previewconfig": true, "showinbound": true, "events": true, "id": true, "getsetting": true,
+	"server": true, "certs": true, "connections": true, "sessions": true, "panel": true, "errors": true,
+	"debugstats": true, "geoinfo": true, "reportpreview": true, "export": true,
+}
+
+// secretArgCommands are the commands whose arguments are credentials; they
+// are recorded without them, so /history never shows a secret.
+var secretArgCommands = map[string]bool{"whois": true}
+
+// isRerunnable reports whether command with args may be run again from
+// /history. Commands that change state with some arguments, such as
+// "/alertstate clear" or "/iplogging on", are only rerunnable without them.
+func isRerunnable(command string, args []string) bool {
+	switch command {
+	case "alertstate", "iplogging", "loglevel":
+		return len(args) == 0
+	case "iplimit":
+		return len(args) < 2
+	case "showinbound":
+		return len(args) < 2
+	}
+	return rerunnableCommands[command]
+}
+
+// recordCommandHistory adds a command run in chatId to its history.
+func recordCommandHistory(chatId int64, command string, args []string, at time.Time) {
+	commandHistory.Lock()
+	defer commandHistory.Unlock()
+	if secretArgCommands[command] {
+		args = nil
+	}
+	commandHistory.seq++
+	buf := append(commandHistory.entries[chatId], historyEntry{
+		Seq:     commandHistory.seq,
+		Command: command,
+		Args:    append([]string(nil), args...),
+		At:      at,
+	})
+	if len(buf) > historyPerChat {
+		buf = buf[len(buf)-historyPerChat:]
+	}
+	commandHistory.entries[chatId] = buf
+}
+
+// chatHistory returns the history of chatId, newest first.
+func chatHistory(chatId int64) []historyEntry {
+	commandHistory.Lock()
+	defer commandHistory.Unlock()
+	buf := commandHistory.entries[chatId]
+	entries := make([]historyEntry, len(buf))
+	for i, e := range buf {
+		entries[len(buf)-1-i] = e
+	}
+	return entries
+}
+
+// historyEntryBySeq finds an entry of chatId's history.
+func historyEntryBySeq(chatId int64, seq uint64) (historyEntry, bool) {
+	commandHistory.Lock()
+	defer commandHistory.Unlock()
+	for _, e := range commandHistory.entries[chatId] {
+		if e.Seq == seq {
+			return e, true
+		}
+	}
+	return historyEntry{}, false
+}
+
+// sendHistory implements /history: the chat's recent commands, newest
+// first, with a button to run the read-only ones again. With messageID the
+// existing list is edited in place.
+func (t *Tgbot) sendHistory(chatId int64, page int, messageID ...int) {
+	entries := chatHistory(chatId)
+	if len(entries) == 0 {
+		t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.messages.historyEmpty"))
+		return
+	}
+
+	pages := (len(entries) + historyPageSize - 1) / historyPageSize
+	page = max(1, min(page, pages))
+	start := (page - 1) * historyPageSize
+	end := min(start+historyPageSize, len(entries))
+
+	loc := t.timeLocation()
+	var output strings.Builder
+	output.WriteString(t.I18nBot("tgbot.messages.historyHeader", "Count=="+strconv.Itoa(len(entries))))
+	var rows [][]telego.InlineKeyboardButton
+	for i, e := range entries[start:end] {
+		n := strconv.Itoa(start + i + 1)
+		output.WriteString("\r\n" + n + ". " + e.At.In(loc).Format("01-02 15:04") + " <code>" + escapeField(e.Text()) + "</code>")
+		if isRerunnable(e.Command, e.Args) {
+			rows = append(rows, tu.InlineKeyboardRow(
+				tu.InlineKeyboardButton("🔁 "+n+". "+truncateRunes(e.Text(), historyButtonRunes)).WithCallbackData(t.encodeQuery("history_rerun "+strconv.FormatUint(e.Seq, 10))),
+			))
+		}
+	}
+	if pages > 1 {
+		output.WriteString("\r\n\r\n" + t.I18nBot("tgbot.messages.dormantPage",
+			"Page=="+strconv.Itoa(page),
+			"Pages=="+strconv.Itoa(pages)))
+		var nav []telego.InlineKeyboardButton
+		if page > 1 {
+			nav = append(nav, tu.InlineKeyboardButton("⬅️").WithCallbackData(t.encodeQuery("history_page "+strconv.Itoa(page-1))))
+		}
+		if page < pages {
+			nav = append(nav, tu.InlineKeyboardButton("➡️").WithCallbackData(t.encodeQuery("history_page "+strconv.Itoa(page+1))))
+		}
+		rows = append(rows, nav)
+	}
+
+	if len(rows) == 0 {
+		t.SendMsgToTgbot(chatId, output.String())
+		return
+	}
+	keyboard := tu.InlineKeyboard(rows...)
+	if len(messageID) > 0 {
+		t.editMessageTgBot(chatId, messageID[0], output.String(), keyboard)
+		return
+	}
+	t.SendMsgToTgbot(chatId, output.String(), keyboard)
+}
+
+// rerunHistory runs a command of the chat's history again, as if typed by
+// from.
+func (t *Tgbot) rerunHistory(chat telego.Chat, from telego.User, entry historyEntry) {
+	message := &telego.Message{Chat: chat, From: &from, Text: entry.Text()}
+	t.answerCommand(message, chat.ID, true)
+}
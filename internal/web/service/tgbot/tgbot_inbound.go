@@ -3,18 +3,91 @@ package tgbot
 import (
 	"errors"
 	"fmt"
+	"html"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/zixu5u/3xv/v3/internal/database/model"
 	"github.com/zixu5u/3xv/v3/internal/logger"
-	"github.com/zixu5u/3xv/v3/internal/util/common"
+	"github.com/zixu5u/3xv/v3/internal/web/service"
 
 	"github.com/mymmrac/telego"
 	tu "github.com/mymmrac/telego/telegoutil"
 )
 
+// disabledInboundMark is put in front of the remark of a disabled inbound
+// listed in a report.
+const disabledInboundMark = "🚫 "
+
+// reportInbounds returns the inbounds that reports and status messages
+// list, dropping disabled ones unless includeDisabled is set.
+func reportInbounds(inbounds []*model.Inbound, includeDisabled bool) []*model.Inbound {
+	if includeDisabled {
+		return inbounds
+	}
+	enabled := make([]*model.Inbound, 0, len(inbounds))
+	for _, inbound := range inbounds {
+		if inbound.Enable {
+			enabled = append(enabled, inbound)
+		}
+	}
+	return enabled
+}
+
+// reportRemark returns the escaped remark of an inbound, marked when the
+// inbound is disabled.
+func reportRemark(inbound *model.Inbound) string {
+	if !inbound.Enable {
+		return disabledInboundMark + escapeField(inbound.Remark)
+	}
+	return escapeField(inbound.Remark)
+}
+
+// reportDisabledInbounds mirrors the tgReportDisabledInbounds setting,
+// listing disabled inbounds when it can't be read.
+func (t *Tgbot) reportDisabledInbounds() bool {
+	include, err := t.settingService.GetTgReportDisabledInbounds()
+	if err != nil {
+		t.settingFallback("tgReportDisabledInbounds", err, "true")
+		return true
+	}
+	return include
+}
+
+// inboundClientCounts returns the client counts of every inbound for status
+// lists, or nil when tgStatusClientCounts is off or the counts can't be
+// read.
+func (t *Tgbot) inboundClientCounts() map[int]service.InboundClientCount {
+	show, err := t.settingService.GetTgStatusClientCounts()
+	if err != nil {
+		t.settingFallback("tgStatusClientCounts", err, "true")
+		show = true
+	}
+	if !show {
+		return nil
+	}
+	counts, err := t.clientService.CountByInbound()
+	if err != nil {
+		logger.Warning("Failed to count clients per inbound:", err)
+		return nil
+	}
+	return counts
+}
+
+// inboundClientsLine renders the client count of an inbound, or nothing
+// when counts is nil. Inbounds without clients aren't in counts and show
+// zero.
+func (t *Tgbot) inboundClientsLine(counts map[int]service.InboundClientCount, inboundId int) string {
+	if counts == nil {
+		return ""
+	}
+	count := counts[inboundId]
+	return t.I18nBot("tgbot.messages.inboundClients",
+		"Total=="+strconv.Itoa(count.Total),
+		"Active=="+strconv.Itoa(count.Enabled))
+}
+
 // getInboundUsages retrieves and formats inbound usage information.
 func (t *Tgbot) getInboundUsages() string {
 	var info strings.Builder
@@ -24,15 +97,15 @@ func (t *Tgbot) getInboundUsages() string {
 		info.WriteString(t.I18nBot("tgbot.answers.getInboundsFailed"))
 		return info.String()
 	}
-	for _, inbound := range inbounds {
-		info.WriteString(t.I18nBot("tgbot.messages.inbound", "Remark=="+inbound.Remark))
+	if len(inbounds) == 0 {
+		return t.I18nBot("tgbot.messages.noInbounds")
+	}
+	counts := t.inboundClientCounts()
+	for _, inbound := range reportInbounds(inbounds, t.reportDisabledInbounds()) {
+		info.WriteString(t.I18nBot("tgbot.messages.inbound", "Remark=="+reportRemark(inbound)))
 		info.WriteString(t.I18nBot("tgbot.messages.port", "Port=="+strconv.Itoa(inbound.Port)))
-		info.WriteString(t.I18nBot("tgbot.messages.traffic", "Total=="+common.FormatTraffic((inbound.Up+inbound.Down)), "Upload=="+common.FormatTraffic(inbound.Up), "Download=="+common.FormatTraffic(inbound.Down)))
-
-		clients, listErr := t.clientService.ListForInbound(nil, inbound.Id)
-		if listErr == nil {
-			info.WriteString(fmt.Sprintf("👥 Clients: %d\r\n", len(clients)))
-		}
+		info.WriteString(t.I18nBot("tgbot.messages.traffic", "Total=="+formatTraffic((inbound.Up+inbound.Down)), "Upload=="+formatTraffic(inbound.Up), "Download=="+formatTraffic(inbound.Down)))
+		info.WriteString(t.inboundClientsLine(counts, inbound.Id))
 
 		if inbound.ExpiryTime == 0 {
 			info.WriteString(t.I18nBot("tgbot.messages.expire", "Time=="+t.I18nBot("tgbot.unlimited")))
@@ -41,7 +114,7 @@ func (t *Tgbot) getInboundUsages() string {
 		}
 		info.WriteString("\r\n")
 	}
-	return info.String()
+	return t.withTrafficFreshness(info.String())
 }
 
 // getInbounds creates an inline keyboard with all inbounds.
@@ -285,24 +358,79 @@ func (t *Tgbot) searchInbound(chatId int64, remark string) {
 	}
 
 	for _, inbound := range inbounds {
-		info := ""
-		info += t.I18nBot("tgbot.messages.inbound", "Remark=="+inbound.Remark)
-		info += t.I18nBot("tgbot.messages.port", "Port=="+strconv.Itoa(inbound.Port))
-		info += t.I18nBot("tgbot.messages.traffic", "Total=="+common.FormatTraffic((inbound.Up+inbound.Down)), "Upload=="+common.FormatTraffic(inbound.Up), "Download=="+common.FormatTraffic(inbound.Down))
-
-		if inbound.ExpiryTime == 0 {
-			info += t.I18nBot("tgbot.messages.expire", "Time=="+t.I18nBot("tgbot.unlimited"))
-		} else {
-			info += t.I18nBot("tgbot.messages.expire", "Time=="+time.Unix((inbound.ExpiryTime/1000), 0).Format("2006-01-02 15:04:05"))
-		}
-		t.SendMsgToTgbot(chatId, info)
+		info := t.inboundInfoMsg(inbound)
+		inboundKeyboard := tu.InlineKeyboard(tu.InlineKeyboardRow(
+			tu.InlineKeyboardButton(t.I18nBot("tgbot.buttons.note")).WithCallbackData(t.encodeQuery("inbound_note "+strconv.Itoa(inbound.Id))),
+			tu.InlineKeyboardButton(t.I18nBot("tgbot.buttons.editLimits")).WithCallbackData(t.encodeQuery("inbound_edit "+strconv.Itoa(inbound.Id))),
+		))
+		t.SendMsgToTgbot(chatId, info, inboundKeyboard)
 
 		if len(inbound.ClientStats) > 0 {
 			var output strings.Builder
 			for _, traffic := range inbound.ClientStats {
 				output.WriteString(t.clientInfoMsg(&traffic, true, true, true, true, true, true))
+				output.WriteString(t.clientNoteLine(traffic.Email))
 			}
 			t.SendMsgToTgbot(chatId, output.String())
 		}
 	}
 }
+
+// inboundInfoMsg formats the remark, port, traffic, expiry, note and
+// effective alert thresholds of an inbound.
+func (t *Tgbot) inboundInfoMsg(inbound *model.Inbound) string {
+	info := ""
+	info += t.I18nBot("tgbot.messages.inbound", "Remark=="+escapeField(inbound.Remark))
+	info += t.I18nBot("tgbot.messages.port", "Port=="+strconv.Itoa(inbound.Port))
+	info += t.I18nBot("tgbot.messages.traffic", "Total=="+formatTraffic((inbound.Up+inbound.Down)), "Upload=="+formatTraffic(inbound.Up), "Download=="+formatTraffic(inbound.Down))
+
+	if inbound.ExpiryTime == 0 {
+		info += t.I18nBot("tgbot.messages.expire", "Time=="+t.I18nBot("tgbot.unlimited"))
+	} else {
+		info += t.I18nBot("tgbot.messages.expire", "Time=="+time.Unix((inbound.ExpiryTime/1000), 0).Format("2006-01-02 15:04:05"))
+	}
+	info += t.noteLine(inbound.Note)
+	info += t.thresholdsLine(inbound)
+	return info
+}
+
+// inboundTestTimeout bounds each /test probe, connect and TLS handshake
+// together.
+const inboundTestTimeout = 5 * time.Second
+
+// sendInboundTest probes an inbound's port from the server and reports the
+// connect (and TLS handshake) latency or the specific failure.
+func (t *Tgbot) sendInboundTest(chatId int64, tag string) {
+	probe, err := t.inboundService.ProbeInbound(tag, inboundTestTimeout)
+	if err != nil {
+		t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.messages.testError", "Tag=="+escapeField(tag), "Error=="+html.EscapeString(err.Error())))
+		return
+	}
+	if probe.Failure != "" {
+		reason := ""
+		switch probe.Failure {
+		case service.ProbeRefused:
+			reason = t.I18nBot("tgbot.messages.testRefused")
+		case service.ProbeTimeout:
+			reason = t.I18nBot("tgbot.messages.testTimeout", "Timeout=="+inboundTestTimeout.String())
+		case service.ProbeTLS:
+			reason = t.I18nBot("tgbot.messages.testTls")
+		default:
+			reason = t.I18nBot("tgbot.messages.testUnreachable")
+		}
+		t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.messages.testFailed",
+			"Tag=="+escapeField(probe.Tag),
+			"Address=="+probe.Address,
+			"Reason=="+reason,
+			"Error=="+html.EscapeString(probe.Err.Error())))
+		return
+	}
+	output := t.I18nBot("tgbot.messages.testOk",
+		"Tag=="+escapeField(probe.Tag),
+		"Address=="+probe.Address,
+		"Connect=="+probe.Connect.Round(time.Millisecond).String())
+	if probe.TLS {
+		output += t.I18nBot("tgbot.messages.testHandshake", "Handshake=="+probe.Handshake.Round(time.Millisecond).String())
+	}
+	t.SendMsgToTgbot(chatId, output)
+}
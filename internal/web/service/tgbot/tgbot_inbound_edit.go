@@ -0,0 +1,197 @@
+package tgbot
+
+import (
+	"html"
+	"strconv"
+	"time"
+
+	"github.com/zixu5u/3xv/v3/internal/logger"
+
+	"github.com/mymmrac/telego"
+	tu "github.com/mymmrac/telego/telegoutil"
+)
+
+// Conversation states for editing an inbound's traffic limit and expiry.
+// The values collected so far are kept in inboundEdits for the same chat.
+const (
+	stateAwaitingInboundLimit  = "awaiting_inbound_limit"
+	stateAwaitingInboundExpiry = "awaiting_inbound_expiry"
+)
+
+// inboundEdit is an inbound limit and expiry edit in progress.
+type inboundEdit struct {
+	inboundId     int
+	step          string
+	remark        string
+	oldTotal      int64
+	oldExpiryTime int64
+	total         int64
+	expiryTime    int64
+}
+
+var inboundEdits = make(map[int64]*inboundEdit)
+
+// startInboundEdit begins the guided edit of an inbound's traffic limit and
+// expiry, starting with the limit.
+func (t *Tgbot) startInboundEdit(chatId int64, inboundId int) {
+	inbound, err := t.inboundService.GetInbound(inboundId)
+	if err != nil {
+		t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.answers.errorOperation"))
+		return
+	}
+	edit := &inboundEdit{
+		inboundId:     inbound.Id,
+		step:          stateAwaitingInboundLimit,
+		remark:        inbound.Remark,
+		oldTotal:      inbound.Total,
+		oldExpiryTime: inbound.ExpiryTime,
+		total:         inbound.Total,
+		expiryTime:    inbound.ExpiryTime,
+	}
+	inboundEdits[chatId] = edit
+	userStates[chatId] = edit.step
+	t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.messages.inboundEditLimitPrompt",
+		"Remark=="+escapeField(edit.remark),
+		"Current=="+t.formatInboundLimit(edit.total)), t.inboundEditKeyboard(edit.inboundId))
+}
+
+// promptInboundExpiry asks for the expiry, the second step of the edit.
+func (t *Tgbot) promptInboundExpiry(chatId int64, edit *inboundEdit) {
+	edit.step = stateAwaitingInboundExpiry
+	userStates[chatId] = edit.step
+	t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.messages.inboundEditExpiryPrompt",
+		"Remark=="+escapeField(edit.remark),
+		"Current=="+t.formatInboundExpiry(edit.expiryTime)), t.inboundEditKeyboard(edit.inboundId))
+}
+
+func (t *Tgbot) inboundEditKeyboard(inboundId int) *telego.InlineKeyboardMarkup {
+	id := strconv.Itoa(inboundId)
+	return tu.InlineKeyboard(
+		tu.InlineKeyboardRow(
+			tu.InlineKeyboardButton(t.I18nBot("tgbot.buttons.keepCurrent")).WithCallbackData(t.encodeQuery("inbound_edit_keep "+id)),
+			tu.InlineKeyboardButton(t.I18nBot("tgbot.buttons.cancel")).WithCallbackData(t.encodeQuery("inbound_edit_cancel "+id)),
+		),
+	)
+}
+
+// handleInboundEditInput takes a typed limit or expiry. Invalid input is
+// reported and asked for again; the edit stays at the same step.
+func (t *Tgbot) handleInboundEditInput(chatId int64, state string, text string) {
+	edit := inboundEdits[chatId]
+	if edit == nil {
+		delete(userStates, chatId)
+		return
+	}
+	if state == stateAwaitingInboundLimit {
+		total, err := parseTrafficSize(text)
+		if err != nil {
+			t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.messages.inboundEditInvalid", "Error=="+html.EscapeString(err.Error())),
+				t.inboundEditKeyboard(edit.inboundId))
+			return
+		}
+		edit.total = total
+		t.promptInboundExpiry(chatId, edit)
+		return
+	}
+
+	expiryTime, err := parseExpiryInput(text, time.Now(), t.timeLocation())
+	if err != nil {
+		t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.messages.inboundEditInvalid", "Error=="+html.EscapeString(err.Error())),
+			t.inboundEditKeyboard(edit.inboundId))
+		return
+	}
+	edit.expiryTime = expiryTime
+	t.sendInboundEditSummary(chatId, edit)
+}
+
+// keepInboundEditValue keeps the current value of the step the edit is at
+// and moves on. The step comes from the edit since pressing a button clears
+// the chat's conversation state.
+func (t *Tgbot) keepInboundEditValue(chatId int64, inboundId int) {
+	edit := inboundEdits[chatId]
+	if edit == nil || edit.inboundId != inboundId {
+		t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.answers.errorOperation"))
+		return
+	}
+	if edit.step == stateAwaitingInboundLimit {
+		t.promptInboundExpiry(chatId, edit)
+		return
+	}
+	t.sendInboundEditSummary(chatId, edit)
+}
+
+// sendInboundEditSummary shows the old and new values for confirmation.
+func (t *Tgbot) sendInboundEditSummary(chatId int64, edit *inboundEdit) {
+	delete(userStates, chatId)
+	id := strconv.Itoa(edit.inboundId)
+	inlineKeyboard := tu.InlineKeyboard(
+		tu.InlineKeyboardRow(
+			tu.InlineKeyboardButton(t.I18nBot("tgbot.buttons.saveChanges")).WithCallbackData(t.encodeQuery("inbound_edit_save "+id)),
+			tu.InlineKeyboardButton(t.I18nBot("tgbot.buttons.cancel")).WithCallbackData(t.encodeQuery("inbound_edit_cancel "+id)),
+		),
+	)
+	t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.messages.inboundEditSummary",
+		"Remark=="+escapeField(edit.remark),
+		"OldLimit=="+t.formatInboundLimit(edit.oldTotal),
+		"NewLimit=="+t.formatInboundLimit(edit.total),
+		"OldExpiry=="+t.formatInboundExpiry(edit.oldExpiryTime),
+		"NewExpiry=="+t.formatInboundExpiry(edit.expiryTime)), inlineKeyboard)
+}
+
+// saveInboundEdit applies a confirmed edit and shows the updated inbound.
+func (t *Tgbot) saveInboundEdit(chatId int64, inboundId int, requestedBy int64) {
+	edit := inboundEdits[chatId]
+	if edit == nil || edit.inboundId != inboundId {
+		t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.answers.errorOperation"))
+		return
+	}
+	delete(inboundEdits, chatId)
+	delete(userStates, chatId)
+
+	needRestart, err := t.inboundService.SetInboundLimits(inboundId, edit.total, edit.expiryTime)
+	if needRestart {
+		t.xrayService.SetToNeedRestart()
+	}
+	if err != nil {
+		logger.Warning("Failed to set inbound limits:", err)
+		t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.messages.inboundEditFailed", "Error=="+html.EscapeString(err.Error())))
+		return
+	}
+	logger.Infof("Traffic limit and expiry of inbound %d changed by Telegram user %d", inboundId, requestedBy)
+	msg := t.I18nBot("tgbot.messages.inboundEditSaved")
+	if inbound, err := t.inboundService.GetInbound(inboundId); err == nil {
+		msg += "\r\n" + t.inboundInfoMsg(inbound)
+	}
+	t.SendMsgToTgbot(chatId, msg)
+}
+
+// cancelInboundEdit drops an edit in progress.
+func (t *Tgbot) cancelInboundEdit(chatId int64) {
+	delete(inboundEdits, chatId)
+	delete(userStates, chatId)
+	t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.messages.inboundEditCanceled"))
+}
+
+func (t *Tgbot) formatInboundLimit(total int64) string {
+	if total == 0 {
+		return t.I18nBot("tgbot.unlimited")
+	}
+	return formatTraffic(total)
+}
+
+func (t *Tgbot) formatInboundExpiry(expiryTime int64) string {
+	if expiryTime == 0 {
+		return t.I18nBot("tgbot.unlimited")
+	}
+	return time.UnixMilli(expiryTime).In(t.timeLocation()).Format("2006-01-02 15:04")
+}
+
+// timeLocation returns the panel time zone, or the local one if it can't
+// be read.
+func (t *Tgbot) timeLocation() *time.Location {
+	loc, err := t.settingService.GetTimeLocation()
+	if err != nil {
+		return time.Local
+	}
+	return loc
+}
@@ -0,0 +1,180 @@
+package tgbot
+
+import (
+	"errors"
+	"html"
+	"strconv"
+	"strings"
+
+	"github.com/zixu5u/3xv/v3/internal/database/model"
+	"github.com/zixu5u/3xv/v3/internal/logger"
+	"github.com/zixu5u/3xv/v3/internal/web/service"
+)
+
+// splitQuotedArgs splits the arguments of a command message on whitespace,
+// keeping quoted parts together so cron expressions can be passed as one
+// argument. Typographic quotes some clients substitute are accepted too.
+func splitQuotedArgs(text string) ([]string, error) {
+	_, rest, _ := strings.Cut(strings.TrimSpace(text), " ")
+	var args []string
+	var cur strings.Builder
+	var quote rune
+	inArg := false
+	for _, r := range rest {
+		switch {
+		case quote != 0:
+			if r == quote || (quote == '‘' && r == '’') || (quote == '“' && r == '”') {
+				quote = 0
+			} else {
+				cur.WriteRune(r)
+			}
+		case r == '\'' || r == '"' || r == '‘' || r == '“':
+			quote, inArg = r, true
+		case r == ' ' || r == '\t' || r == '\n':
+			if inArg {
+				args = append(args, cur.String())
+				cur.Reset()
+				inArg = false
+			}
+		default:
+			cur.WriteRune(r)
+			inArg = true
+		}
+	}
+	if quote != 0 {
+		return nil, errors.New("unterminated quote")
+	}
+	if inArg {
+		args = append(args, cur.String())
+	}
+	return args, nil
+}
+
+// parseScheduleActions reads the "enable <spec> disable <spec> reset <spec>"
+// pairs of /schedule. Any action may be left out, but not all of them.
+func parseScheduleActions(args []string) (map[string]string, error) {
+	if len(args) == 0 || len(args)%2 != 0 {
+		return nil, errors.New("expected action and schedule pairs")
+	}
+	actions := map[string]string{}
+	for i := 0; i < len(args); i += 2 {
+		action := strings.ToLower(args[i])
+		if !service.IsInboundScheduleAction(action) {
+			return nil, errors.New("unknown action " + args[i])
+		}
+		if _, err := service.ParseInboundSchedule(args[i+1]); err != nil {
+			return nil, err
+		}
+		actions[action] = args[i+1]
+	}
+	return actions, nil
+}
+
+// handleScheduleCommand implements /schedule: without arguments it lists
+// every schedule, with a tag it shows that inbound's schedules, and
+// "clear" or action pairs change them.
+func (t *Tgbot) handleScheduleCommand(chatId int64, text string, requestedBy int64) {
+	args, err := splitQuotedArgs(text)
+	if err != nil {
+		t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.messages.scheduleUsage"))
+		return
+	}
+	if len(args) == 0 {
+		t.sendAllSchedules(chatId)
+		return
+	}
+
+	inbound, err := t.inboundService.GetInboundByTag(args[0])
+	if err != nil {
+		t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.messages.scheduleNoInbound", "Tag=="+escapeField(args[0])))
+		return
+	}
+	switch {
+	case len(args) == 1:
+	case len(args) == 2 && strings.EqualFold(args[1], "clear"):
+		if err := t.schedules.ClearSchedules(inbound.Id); err != nil {
+			logger.Warning("Failed to clear inbound schedules:", err)
+			t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.wentWrong"))
+			return
+		}
+		logger.Infof("Schedules of inbound %s cleared by Telegram user %d", inbound.Tag, requestedBy)
+	default:
+		actions, err := parseScheduleActions(args[1:])
+		if err != nil {
+			t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.messages.scheduleInvalid", "Error=="+html.EscapeString(err.Error()))+
+				t.I18nBot("tgbot.messages.scheduleUsage"))
+			return
+		}
+		for action, spec := range actions {
+			if err := t.schedules.SetSchedule(inbound.Id, action, spec); err != nil {
+				logger.Warning("Failed to save inbound schedule:", err)
+				t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.wentWrong"))
+				return
+			}
+		}
+		logger.Infof("Schedules of inbound %s set by Telegram user %d", inbound.Tag, requestedBy)
+	}
+	t.sendInboundSchedules(chatId, inbound)
+}
+
+// sendInboundSchedules shows the schedules of one inbound with their next
+// run.
+func (t *Tgbot) sendInboundSchedules(chatId int64, inbound *model.Inbound) {
+	schedules, err := t.schedules.GetSchedules(inbound.Id)
+	if err != nil {
+		logger.Warning("Failed to get inbound schedules:", err)
+		t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.wentWrong"))
+		return
+	}
+	if len(schedules) == 0 {
+		t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.messages.scheduleNone", "Tag=="+escapeField(inbound.Tag)))
+		return
+	}
+	msg := t.I18nBot("tgbot.messages.scheduleHeader", "Tag=="+escapeField(inbound.Tag))
+	for _, schedule := range schedules {
+		msg += t.formatSchedule(schedule)
+	}
+	t.SendMsgToTgbot(chatId, msg)
+}
+
+// sendAllSchedules lists the schedules of every inbound.
+func (t *Tgbot) sendAllSchedules(chatId int64) {
+	schedules, err := t.schedules.GetAllSchedules()
+	if err != nil {
+		logger.Warning("Failed to get inbound schedules:", err)
+		t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.wentWrong"))
+		return
+	}
+	if len(schedules) == 0 {
+		t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.messages.scheduleEmpty")+t.I18nBot("tgbot.messages.scheduleUsage"))
+		return
+	}
+	var msg strings.Builder
+	lastInbound := 0
+	for _, schedule := range schedules {
+		if schedule.InboundId != lastInbound {
+			lastInbound = schedule.InboundId
+			tag := "#" + strconv.Itoa(schedule.InboundId)
+			if inbound, err := t.inboundService.GetInbound(schedule.InboundId); err == nil {
+				tag = inbound.Tag
+			}
+			if msg.Len() > 0 {
+				msg.WriteString("\r\n")
+			}
+			msg.WriteString(t.I18nBot("tgbot.messages.scheduleHeader", "Tag=="+escapeField(tag)))
+		}
+		msg.WriteString(t.formatSchedule(schedule))
+	}
+	t.SendMsgToTgbot(chatId, msg.String())
+}
+
+func (t *Tgbot) formatSchedule(schedule model.InboundSchedule) string {
+	next := "-"
+	if at := t.schedules.NextRun(schedule); !at.IsZero() {
+		next = at.Format("2006-01-02 15:04:05")
+	}
+	return t.I18nBot("tgbot.messages.scheduleLine",
+		"Action=="+schedule.Action,
+		"Schedule=="+html.EscapeString(schedule.Spec),
+		"Next=="+next)
+}
@@ -0,0 +1,103 @@
+package tgbot
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/zixu5u/3xv/v3/internal/database/model"
+	"github.com/zixu5u/3xv/v3/internal/logger"
+	"github.com/zixu5u/3xv/v3/internal/xray"
+
+	"github.com/mymmrac/telego"
+	tu "github.com/mymmrac/telego/telegoutil"
+)
+
+// inlineResultLimit caps the clients and the inbounds returned for one
+// inline query; Telegram accepts at most 50 results in total.
+const inlineResultLimit = 10
+
+// answerInlineQuery answers "@bot <query>" typed in any chat with cards for
+// the clients whose email and the inbounds whose remark contain the query.
+// Only admins get results; answers are personal so Telegram never serves
+// an admin's cached results to someone else.
+func (t *Tgbot) answerInlineQuery(query *telego.InlineQuery, isAdmin bool) {
+	results := make([]telego.InlineQueryResult, 0)
+	if text := strings.TrimSpace(query.Query); isAdmin && text != "" {
+		results = t.inlineResults(text)
+	}
+	params := tu.InlineQuery(query.ID, results...).WithIsPersonal().WithCacheTime(0)
+	if err := bot.AnswerInlineQuery(context.Background(), params); err != nil {
+		logger.Warning("Failed to answer inline query:", err)
+	}
+}
+
+// inlineResults builds the result cards for an admin's inline query.
+func (t *Tgbot) inlineResults(text string) []telego.InlineQueryResult {
+	results := make([]telego.InlineQueryResult, 0)
+
+	traffics, err := t.inboundService.SearchClientTraffics(text, inlineResultLimit)
+	if err != nil {
+		logger.Warning("Failed to search clients for inline query:", err)
+	}
+	for _, traffic := range traffics {
+		results = append(results, t.inlineClientResult(traffic))
+	}
+
+	inbounds, err := t.inboundService.SearchInbounds(text)
+	if err != nil {
+		logger.Warning("Failed to search inbounds for inline query:", err)
+	}
+	for i, inbound := range inbounds {
+		if i == inlineResultLimit {
+			break
+		}
+		results = append(results, t.inlineInboundResult(inbound))
+	}
+	return results
+}
+
+func (t *Tgbot) inlineClientResult(traffic *xray.ClientTraffic) telego.InlineQueryResult {
+	total := t.I18nBot("tgbot.unlimited")
+	if traffic.Total > 0 {
+		total = formatTraffic(traffic.Total)
+	}
+	expiry := t.I18nBot("tgbot.unlimited")
+	if traffic.ExpiryTime > 0 {
+		expiry = time.UnixMilli(traffic.ExpiryTime).Format("2006-01-02")
+	} else if traffic.ExpiryTime < 0 {
+		expiry = strconv.FormatInt(traffic.ExpiryTime/-86400000, 10) + " " + t.I18nBot("tgbot.days")
+	}
+	card := t.clientInfoMsg(traffic, true, true, true, true, true, false) + t.clientNoteLine(traffic.Email)
+
+	return tu.ResultArticle("client-"+strconv.Itoa(traffic.Id), traffic.Email,
+		tu.TextMessage(card).WithParseMode(telego.ModeHTML)).
+		WithDescription(t.I18nBot("tgbot.messages.inlineClient",
+			"Status=="+enabledMark(traffic.Enable),
+			"UpDown=="+formatTraffic(traffic.Up+traffic.Down),
+			"Total=="+total,
+			"Expiry=="+expiry))
+}
+
+func (t *Tgbot) inlineInboundResult(inbound *model.Inbound) telego.InlineQueryResult {
+	title := inbound.Remark
+	if title == "" {
+		title = inbound.Tag
+	}
+	return tu.ResultArticle("inbound-"+strconv.Itoa(inbound.Id), title,
+		tu.TextMessage(t.inboundInfoMsg(inbound)).WithParseMode(telego.ModeHTML)).
+		WithDescription(t.I18nBot("tgbot.messages.inlineInbound",
+			"Status=="+enabledMark(inbound.Enable),
+			"Protocol=="+string(inbound.Protocol),
+			"Port=="+strconv.Itoa(inbound.Port),
+			"Clients=="+strconv.Itoa(len(inbound.ClientStats)),
+			"Total=="+formatTraffic(inbound.Up+inbound.Down)))
+}
+
+func enabledMark(enabled bool) string {
+	if enabled {
+		return "✅"
+	}
+	return "🛑"
+}
@@ -0,0 +1,85 @@
+package tgbot
+
+import (
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// trafficUnits are the size suffixes accepted by parseTrafficSize, longest
+// first so "GB" isn't read as "B".
+var trafficUnits = []struct {
+	suffix string
+	bytes  float64
+}{
+	{"TB", 1 << 40}, {"GB", 1 << 30}, {"MB", 1 << 20}, {"KB", 1 << 10},
+	{"T", 1 << 40}, {"G", 1 << 30}, {"M", 1 << 20}, {"K", 1 << 10}, {"B", 1},
+}
+
+const (
+	// maxTrafficLimit bounds typed traffic limits; anything larger is a typo.
+	maxTrafficLimit = 1 << 60
+	// maxExpiryDays bounds an expiry typed as a number of days.
+	maxExpiryDays = 3650
+)
+
+// parseTrafficSize reads a traffic limit typed by an admin, such as "50",
+// "50GB", "1.5 TB" or "500mb". A bare number is in GB, like the limit
+// buttons. "0", "unlimited" and "-" mean no limit and return 0.
+func parseTrafficSize(input string) (int64, error) {
+	text := strings.ToUpper(strings.ReplaceAll(strings.TrimSpace(input), " ", ""))
+	if text == "" {
+		return 0, errors.New("empty size")
+	}
+	if text == "-" || text == "0" || strings.EqualFold(text, "unlimited") {
+		return 0, nil
+	}
+	multiplier := float64(1 << 30)
+	for _, unit := range trafficUnits {
+		if strings.HasSuffix(text, unit.suffix) {
+			text, multiplier = strings.TrimSuffix(text, unit.suffix), unit.bytes
+			break
+		}
+	}
+	value, err := strconv.ParseFloat(strings.ReplaceAll(text, ",", "."), 64)
+	if err != nil || math.IsNaN(value) || value < 0 {
+		return 0, errors.New("invalid size")
+	}
+	size := value * multiplier
+	if size > maxTrafficLimit {
+		return 0, errors.New("size too large")
+	}
+	return int64(size), nil
+}
+
+// parseExpiryInput reads an expiry typed by an admin and returns it in unix
+// milliseconds: "0", "unlimited" or "-" for none, a number of days from now
+// ("30", "30d", "+30d"), or a date "2006-01-02" (end of that day) or
+// "2006-01-02 15:04" in loc. Dates in the past are rejected.
+func parseExpiryInput(input string, now time.Time, loc *time.Location) (int64, error) {
+	text := strings.TrimSpace(input)
+	if text == "" {
+		return 0, errors.New("empty expiry")
+	}
+	if text == "-" || text == "0" || strings.EqualFold(text, "unlimited") {
+		return 0, nil
+	}
+	if days, err := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(strings.ToLower(text), "+"), "d")); err == nil {
+		if days < 1 || days > maxExpiryDays {
+			return 0, errors.New("invalid number of days")
+		}
+		return now.AddDate(0, 0, days).UnixMilli(), nil
+	}
+	var at time.Time
+	if date, err := time.ParseInLocation("2006-01-02", text, loc); err == nil {
+		at = date.AddDate(0, 0, 1).Add(-time.Second)
+	} else if at, err = time.ParseInLocation("2006-01-02 15:04", text, loc); err != nil {
+		return 0, errors.New("invalid date")
+	}
+	if !at.After(now) {
+		return 0, errors.New("date is in the past")
+	}
+	return at.UnixMilli(), nil
+}
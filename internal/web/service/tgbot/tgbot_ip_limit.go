@@ -0,0 +1,216 @@
+package tgbot
+
+import (
+	"errors"
+	"html"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/zixu5u/3xv/v3/internal/logger"
+	"github.com/zixu5u/3xv/v3/internal/web/service"
+	"github.com/zixu5u/3xv/v3/internal/xray"
+)
+
+// maxIpLimitShown caps the clients one /iplimit message lists as over their
+// limit; the rest are only counted.
+const maxIpLimitShown = 50
+
+// parseIpLimitArgs reads "/iplimit [email] [limit]". Without arguments
+// /iplimit lists the clients over their limit; a limit of 0 lifts it.
+// set is false when only the email is given.
+func parseIpLimitArgs(args []string) (email string, limit int, set bool, err error) {
+	switch len(args) {
+	case 0:
+		return "", 0, false, nil
+	case 1:
+		return args[0], 0, false, nil
+	case 2:
+		limit, err = strconv.Atoi(args[1])
+		if err != nil || limit < 0 {
+			return "", 0, false, errors.New("the limit must be a number of IPs, or 0 for none")
+		}
+		return args[0], limit, true, nil
+	default:
+		return "", 0, false, errors.New("expected an email and an optional limit")
+	}
+}
+
+// overIpLimit reports whether count distinct IPs break limit. A limit of
+// 0 is no limit.
+func overIpLimit(count int, limit int) bool {
+	return limit > 0 && count > limit
+}
+
+// clientIpCounts returns how many distinct IPs each online client connects
+// from, by email, from the same source /connections uses: live from the
+// online-stats API when the core has it, otherwise from the access log.
+func (t *Tgbot) clientIpCounts(now time.Time) (map[string]int, error) {
+	users, _, err := t.onlineConnections(now)
+	if err != nil {
+		return nil, err
+	}
+	counts := make(map[string]int, len(users))
+	for _, user := range users {
+		ips := make(map[string]bool, len(user.IPs))
+		for _, ip := range user.IPs {
+			ips[ip.IP] = true
+		}
+		counts[user.Email] = len(ips)
+	}
+	return counts, nil
+}
+
+// ipLimitLine renders count IPs against limit for a client, flagged when
+// the client is over it.
+func (t *Tgbot) ipLimitLine(count int, limit int) string {
+	limitText := t.I18nBot("tgbot.unlimited")
+	if limit > 0 {
+		limitText = strconv.Itoa(limit)
+	}
+	line := t.I18nBot("tgbot.messages.ipLimitCount", "Count=="+strconv.Itoa(count), "Limit=="+limitText)
+	if overIpLimit(count, limit) {
+		line += t.I18nBot("tgbot.messages.ipLimitOver")
+	}
+	return line + "\r\n"
+}
+
+// clientIpCount returns how many distinct IPs email connects from now.
+// Unlike clientIpCounts it only reads the IPs of that one client, so a
+// client card doesn't load every online client's IPs to show one line.
+func (t *Tgbot) clientIpCount(email string, now time.Time) (int, error) {
+	var ips []xray.OnlineIP
+	users, live, err := t.xrayService.GetOnlineUsers()
+	if live {
+		for _, user := range users {
+			if user.Email == email {
+				ips = user.IPs
+				break
+			}
+		}
+	} else {
+		if err != nil {
+			logger.Debug("Online-stats API unavailable for the IP count, using the access log:", err)
+		}
+		if !slices.Contains(service.XrayProcess().GetOnlineClients(), email) {
+			return 0, nil
+		}
+		recent, err := t.inboundService.RecentClientIps([]string{email}, now.Add(-connectionsLogWindow).Unix())
+		if err != nil {
+			return 0, err
+		}
+		ips = recent[email]
+	}
+	distinct := make(map[string]bool, len(ips))
+	for _, ip := range ips {
+		distinct[ip.IP] = true
+	}
+	return len(distinct), nil
+}
+
+// clientIpLimitLine is the IP limit line of the client card. It is left
+// out when the live count can't be read, so the card still renders.
+func (t *Tgbot) clientIpLimitLine(email string) string {
+	record, err := t.clientService.GetRecordByEmail(nil, email)
+	if err != nil {
+		return ""
+	}
+	count := 0
+	if t.xrayService.IsXrayRunning() {
+		if count, err = t.clientIpCount(email, time.Now()); err != nil {
+			logger.Debug("Failed to count client IPs:", err)
+			return ""
+		}
+	}
+	return t.ipLimitLine(count, record.LimitIP)
+}
+
+// sendIpLimit implements /iplimit with an email: the client's IP limit and
+// the distinct IPs it connects from now.
+func (t *Tgbot) sendIpLimit(chatId int64, email string) {
+	if _, err := t.clientService.GetRecordByEmail(nil, email); err != nil {
+		t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.noResult"))
+		return
+	}
+	msg := t.I18nBot("tgbot.messages.email", "Email=="+escapeField(email))
+	msg += t.clientIpLimitLine(email)
+	msg += t.ipLoggingLine(t.currentIPLogging())
+	t.SendMsgToTgbot(chatId, msg)
+}
+
+// setIpLimit implements /iplimit with an email and a limit: it updates the
+// client on every inbound it is attached to and restarts Xray to apply it.
+// Enforcement relies on IP logging, so the reply says when it is off.
+func (t *Tgbot) setIpLimit(chatId int64, email string, limit int, requestedBy int64) {
+	needRestart, err := t.clientService.ResetClientIpLimitByEmail(&t.inboundService, email, limit)
+	logBotEvent(botEvent{Event: "ip_limit", ChatID: requestedBy, Command: "iplimit", Err: err})
+	if err != nil {
+		logger.Warning("Failed to set client IP limit:", err)
+		t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.messages.ipLimitFailed",
+			"Email=="+escapeField(email), "Error=="+html.EscapeString(err.Error())))
+		return
+	}
+	logger.Infof("IP limit of %s set to %d by Telegram user %d", email, limit, requestedBy)
+	if needRestart {
+		if t.xrayService.IsXrayRunning() {
+			if err := t.xrayService.RestartXray(true); err != nil {
+				t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.messages.ipLimitFailed",
+					"Email=="+escapeField(email), "Error=="+html.EscapeString(err.Error())))
+				return
+			}
+		} else {
+			t.xrayService.SetToNeedRestart()
+		}
+	}
+	msg := t.I18nBot("tgbot.messages.ipLimitSet", "Email=="+escapeField(email))
+	msg += t.clientIpLimitLine(email)
+	if logging := t.currentIPLogging(); limit > 0 && logging == ipLoggingOff {
+		msg += t.ipLoggingLine(logging)
+	}
+	t.SendMsgToTgbot(chatId, msg)
+}
+
+// sendIpLimitOverview implements /iplimit without arguments: the clients
+// connecting from more IPs than their limit allows.
+func (t *Tgbot) sendIpLimitOverview(chatId int64) {
+	if !t.xrayService.IsXrayRunning() {
+		t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.messages.connectionsXrayStopped"))
+		return
+	}
+	limits, err := t.clientService.IpLimits()
+	if err != nil {
+		logger.Warning("Failed to read client IP limits:", err)
+		t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.wentWrong"))
+		return
+	}
+	counts, err := t.clientIpCounts(time.Now())
+	if err != nil {
+		logger.Warning("Failed to count client IPs:", err)
+		t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.wentWrong"))
+		return
+	}
+	var over []string
+	for email, limit := range limits {
+		if overIpLimit(counts[email], limit) {
+			over = append(over, email)
+		}
+	}
+	slices.Sort(over)
+
+	var msg strings.Builder
+	msg.WriteString(t.I18nBot("tgbot.messages.ipLimitHeader",
+		"Count=="+strconv.Itoa(len(over)),
+		"Limited=="+strconv.Itoa(len(limits))))
+	for _, email := range over[:min(len(over), maxIpLimitShown)] {
+		msg.WriteString(t.I18nBot("tgbot.messages.ipLimitClient",
+			"Email=="+escapeField(email),
+			"Count=="+strconv.Itoa(counts[email]),
+			"Limit=="+strconv.Itoa(limits[email])))
+	}
+	if more := len(over) - maxIpLimitShown; more > 0 {
+		msg.WriteString(t.I18nBot("tgbot.messages.connectionsMore", "Count=="+strconv.Itoa(more)) + "\r\n")
+	}
+	msg.WriteString(t.ipLoggingLine(t.currentIPLogging()))
+	t.SendMsgToTgbot(chatId, msg.String())
+}
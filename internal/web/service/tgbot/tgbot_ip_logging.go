@@ -0,0 +1,115 @@
+package tgbot
+
+import (
+	"errors"
+	"html"
+	"strings"
+
+	"github.com/zixu5u/3xv/v3/internal/logger"
+	"github.com/zixu5u/3xv/v3/internal/web/service"
+	"github.com/zixu5u/3xv/v3/internal/xray"
+
+	"github.com/mymmrac/telego"
+	tu "github.com/mymmrac/telego/telegoutil"
+)
+
+// ipLoggingMode is how client IPs are being recorded.
+type ipLoggingMode int
+
+const (
+	ipLoggingOff       ipLoggingMode = iota
+	ipLoggingAccessLog               // parsed from the Xray access log
+	ipLoggingOnlineAPI               // reported by the core's online-stats API
+)
+
+// parseIPLoggingArgs reads "/iplogging [on|off]". set is false without an
+// argument, which only reports the state.
+func parseIPLoggingArgs(args []string) (enable bool, set bool, err error) {
+	if len(args) == 0 {
+		return false, false, nil
+	}
+	if len(args) > 1 {
+		return false, false, errors.New("too many arguments")
+	}
+	switch strings.ToLower(args[0]) {
+	case "on", "enable":
+		return true, true, nil
+	case "off", "disable":
+		return false, true, nil
+	}
+	return false, false, errors.New("expected on or off")
+}
+
+// currentIPLogging tells how client IPs are recorded by the running core.
+func (t *Tgbot) currentIPLogging() ipLoggingMode {
+	if t.xrayService.OnlineIPTracking() {
+		return ipLoggingOnlineAPI
+	}
+	if path, err := xray.GetAccessLogPath(); err == nil && path != "" && path != "none" {
+		return ipLoggingAccessLog
+	}
+	return ipLoggingOff
+}
+
+// ipLoggingLine renders the IP logging state for the client IP view, so an
+// empty IP list can be told apart from IPs not being recorded at all.
+func (t *Tgbot) ipLoggingLine(mode ipLoggingMode) string {
+	switch mode {
+	case ipLoggingOnlineAPI:
+		return t.I18nBot("tgbot.messages.ipLoggingOnlineAPI")
+	case ipLoggingAccessLog:
+		return t.I18nBot("tgbot.messages.ipLoggingOn")
+	default:
+		return t.I18nBot("tgbot.messages.ipLoggingOff")
+	}
+}
+
+// ipLoggingButton returns the button that flips the access log from the
+// client IP view of email. With the online-stats API the access log isn't
+// read, so there is nothing to flip.
+func (t *Tgbot) ipLoggingButton(mode ipLoggingMode, email string) (telego.InlineKeyboardButton, bool) {
+	switch mode {
+	case ipLoggingOff:
+		return tu.InlineKeyboardButton(t.I18nBot("tgbot.buttons.ipLoggingOn")).WithCallbackData(t.encodeQuery("ip_logging_on " + email)), true
+	case ipLoggingAccessLog:
+		return tu.InlineKeyboardButton(t.I18nBot("tgbot.buttons.ipLoggingOff")).WithCallbackData(t.encodeQuery("ip_logging_off " + email)), true
+	}
+	return telego.InlineKeyboardButton{}, false
+}
+
+// sendIPLogging implements /iplogging without an argument.
+func (t *Tgbot) sendIPLogging(chatId int64) {
+	t.SendMsgToTgbot(chatId, t.ipLoggingLine(t.currentIPLogging())+t.I18nBot("tgbot.messages.ipLoggingScope"))
+}
+
+// setIPLogging turns the Xray access log on or off and restarts Xray to
+// apply it. The access log is global in Xray, so it covers every inbound.
+func (t *Tgbot) setIPLogging(chatId int64, enable bool, requestedBy int64) bool {
+	var xraySettings service.XraySettingService
+	changed, err := xraySettings.SetAccessLog(enable)
+	logBotEvent(botEvent{Event: "ip_logging", ChatID: requestedBy, Command: "iplogging", Err: err})
+	if err != nil {
+		logger.Warning("Failed to set the Xray access log:", err)
+		t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.messages.ipLoggingFailed", "Error=="+html.EscapeString(err.Error())))
+		return false
+	}
+	state, stateKey := "off", "tgbot.messages.ipLoggingStateOff"
+	if enable {
+		state, stateKey = "on", "tgbot.messages.ipLoggingStateOn"
+	}
+	if !changed {
+		t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.messages.ipLoggingUnchanged", "State=="+t.I18nBot(stateKey)))
+		return true
+	}
+	logger.Infof("Xray access log turned %s by Telegram user %d", state, requestedBy)
+	if t.xrayService.IsXrayRunning() {
+		if err := t.xrayService.RestartXray(true); err != nil {
+			t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.messages.ipLoggingFailed", "Error=="+html.EscapeString(err.Error())))
+			return false
+		}
+	} else {
+		t.xrayService.SetToNeedRestart()
+	}
+	t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.messages.ipLoggingChanged", "State=="+t.I18nBot(stateKey)))
+	return true
+}
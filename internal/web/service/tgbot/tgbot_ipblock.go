@@ -0,0 +1,64 @@
+package tgbot
+
+import (
+	"html"
+	"strings"
+
+	"github.com/zixu5u/3xv/v3/internal/logger"
+)
+
+// blockIp adds target to the panel-wide block list on behalf of an admin
+// and reports the outcome back to the chat.
+func (t *Tgbot) blockIp(chatId int64, target string, requestedBy int64) {
+	normalized, added, err := t.ipBlockService.BlockIp(target)
+	if err != nil {
+		logger.Warningf("Blocking IP %s requested by %d failed: %v", target, requestedBy, err)
+		logBotEvent(botEvent{Event: "ip_block", ChatID: requestedBy, Command: "blockip", Err: err})
+		t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.messages.blockIpFailed", "Error=="+html.EscapeString(err.Error())))
+		return
+	}
+	if !added {
+		t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.messages.blockIpAlready", "IP=="+normalized))
+		return
+	}
+	logger.Infof("IP %s blocked by Telegram user %d", normalized, requestedBy)
+	logBotEvent(botEvent{Event: "ip_block", ChatID: requestedBy, Command: "blockip"})
+	t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.messages.blockIpSuccess", "IP=="+normalized))
+}
+
+// unblockIp removes target from the panel-wide block list.
+func (t *Tgbot) unblockIp(chatId int64, target string, requestedBy int64) {
+	normalized, removed, err := t.ipBlockService.UnblockIp(target)
+	if err != nil {
+		logger.Warningf("Unblocking IP %s requested by %d failed: %v", target, requestedBy, err)
+		logBotEvent(botEvent{Event: "ip_unblock", ChatID: requestedBy, Command: "unblockip", Err: err})
+		t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.messages.blockIpFailed", "Error=="+html.EscapeString(err.Error())))
+		return
+	}
+	if !removed {
+		t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.messages.blockIpNotFound", "IP=="+normalized))
+		return
+	}
+	logger.Infof("IP %s unblocked by Telegram user %d", normalized, requestedBy)
+	logBotEvent(botEvent{Event: "ip_unblock", ChatID: requestedBy, Command: "unblockip"})
+	t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.messages.unblockIpSuccess", "IP=="+normalized))
+}
+
+// sendBlockList sends the current block list.
+func (t *Tgbot) sendBlockList(chatId int64) {
+	list, err := t.ipBlockService.GetBlockedIps()
+	if err != nil {
+		t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.messages.blockIpFailed", "Error=="+html.EscapeString(err.Error())))
+		return
+	}
+	if len(list) == 0 {
+		t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.messages.blockListEmpty"))
+		return
+	}
+	var output strings.Builder
+	output.WriteString(t.I18nBot("tgbot.messages.blockListHeader"))
+	for _, ip := range list {
+		output.WriteString("\r\n<code>" + html.EscapeString(ip) + "</code>")
+	}
+	t.SendMsgToTgbot(chatId, output.String())
+}
@@ -0,0 +1,118 @@
+package tgbot
+
+import (
+	"encoding/base64"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/mymmrac/telego"
+	tu "github.com/mymmrac/telego/telegoutil"
+)
+
+// Share link flavors. Client apps differ in which URI forms they import:
+// some reject a raw non-ASCII remark, empty parameters or Xray-only
+// parameters, and Shadowrocket wants Shadowsocks credentials in base64.
+const (
+	// linkFlavorCompat is the default and imports in the most apps.
+	linkFlavorCompat = "compat"
+	// linkFlavorXray keeps every parameter, for Xray-core based apps
+	// such as v2rayN, v2rayNG and Streisand.
+	linkFlavorXray         = "xray"
+	linkFlavorShadowrocket = "shadowrocket"
+	// linkFlavorRaw is the link exactly as the subscription serves it.
+	linkFlavorRaw = "raw"
+)
+
+var linkFlavors = []string{linkFlavorCompat, linkFlavorXray, linkFlavorShadowrocket, linkFlavorRaw}
+
+// compatDroppedParams are optional Xray parameters that less common apps
+// reject instead of ignoring.
+var compatDroppedParams = []string{"spx", "pqv", "extra"}
+
+// normalizeLinkFlavor returns flavor if it is known, else the default.
+func normalizeLinkFlavor(flavor string) string {
+	flavor = strings.ToLower(strings.TrimSpace(flavor))
+	if slices.Contains(linkFlavors, flavor) {
+		return flavor
+	}
+	return linkFlavorCompat
+}
+
+// escapeRemark percent-encodes everything but unreserved characters, the
+// one remark form every app decodes.
+func escapeRemark(remark string) string {
+	return strings.ReplaceAll(url.QueryEscape(remark), "+", "%20")
+}
+
+// encodeShareLink rewrites a share link for the given flavor. vmess links
+// carry their remark inside base64 JSON and are left alone, as is any link
+// that doesn't parse.
+func encodeShareLink(link string, flavor string) string {
+	flavor = normalizeLinkFlavor(flavor)
+	if flavor == linkFlavorRaw || strings.HasPrefix(link, "vmess://") {
+		return link
+	}
+	u, err := url.Parse(link)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return link
+	}
+
+	if flavor != linkFlavorXray {
+		query := u.Query()
+		for key, values := range query {
+			if slices.Contains(compatDroppedParams, key) || len(values) == 0 || values[0] == "" {
+				query.Del(key)
+			}
+		}
+		u.RawQuery = query.Encode()
+	}
+	if flavor == linkFlavorShadowrocket && u.Scheme == "ss" && u.User != nil {
+		if password, ok := u.User.Password(); ok {
+			u.User = url.User(base64.RawURLEncoding.EncodeToString([]byte(u.User.Username() + ":" + password)))
+		}
+	}
+	if u.Fragment != "" {
+		u.RawFragment = escapeRemark(u.Fragment)
+	}
+	return u.String()
+}
+
+// linkFlavorLabel returns the display name of a flavor.
+func (t *Tgbot) linkFlavorLabel(flavor string) string {
+	return t.I18nBot("tgbot.linkFlavors." + flavor)
+}
+
+// linkFlavorKeyboard offers the other flavors for the links or QR codes of
+// a client. kind is "links", "qr" or "album".
+func (t *Tgbot) linkFlavorKeyboard(kind string, email string, current string) *telego.InlineKeyboardMarkup {
+	var row []telego.InlineKeyboardButton
+	for _, flavor := range linkFlavors {
+		if flavor == current {
+			continue
+		}
+		row = append(row, tu.InlineKeyboardButton(t.linkFlavorLabel(flavor)).
+			WithCallbackData(t.encodeQuery("client_links_flavor "+kind+" "+flavor+" "+email)))
+	}
+	return tu.InlineKeyboard(row)
+}
+
+// sendLinksInFlavor answers a flavor button below individual links, QR
+// codes or link albums.
+func (t *Tgbot) sendLinksInFlavor(chatId int64, data string, tgUserID int64, isAdmin bool) {
+	parts := strings.SplitN(data, " ", 3)
+	if len(parts) != 3 || !t.canSeeSubscription(tgUserID, parts[2], isAdmin) {
+		t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.noResult"))
+		return
+	}
+	kind, flavor, email := parts[0], normalizeLinkFlavor(parts[1]), parts[2]
+	switch kind {
+	case "qr":
+		t.sendClientQRLinks(chatId, email, flavor)
+		return
+	case "album":
+		t.sendClientLinkAlbum(chatId, email, flavor)
+		return
+	}
+	t.sendClientIndividualLinks(chatId, email, flavor)
+}
@@ -0,0 +1,73 @@
+package tgbot
+
+import (
+	"errors"
+	"html"
+	"slices"
+	"strings"
+
+	"github.com/zixu5u/3xv/v3/internal/logger"
+	"github.com/zixu5u/3xv/v3/internal/web/service"
+)
+
+// parseLogLevelArgs reads "/loglevel [level]". set is false without an
+// argument, which only reports the level.
+func parseLogLevelArgs(args []string) (level string, set bool, err error) {
+	if len(args) == 0 {
+		return "", false, nil
+	}
+	if len(args) > 1 {
+		return "", false, errors.New("too many arguments")
+	}
+	level = strings.ToLower(args[0])
+	if level == "warn" {
+		level = "warning"
+	}
+	if !slices.Contains(service.XrayLogLevels, level) {
+		return "", false, errors.New("expected one of " + strings.Join(service.XrayLogLevels, ", "))
+	}
+	return level, true, nil
+}
+
+// sendLogLevel implements /loglevel without an argument.
+func (t *Tgbot) sendLogLevel(chatId int64) {
+	var xraySettings service.XraySettingService
+	level, err := xraySettings.GetLogLevel()
+	if err != nil {
+		t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.messages.logLevelFailed", "Error=="+html.EscapeString(err.Error())))
+		return
+	}
+	t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.messages.logLevelCurrent", "Level=="+level))
+}
+
+// setLogLevel sets the Xray log level and restarts Xray to apply it. Debug
+// logs every connection, so the admin is warned it can fill the disk.
+func (t *Tgbot) setLogLevel(chatId int64, level string, requestedBy int64) {
+	var xraySettings service.XraySettingService
+	old, _ := xraySettings.GetLogLevel()
+	changed, err := xraySettings.SetLogLevel(level)
+	logBotEvent(botEvent{Event: "log_level", ChatID: requestedBy, Command: "loglevel", Err: err})
+	if err != nil {
+		logger.Warning("Failed to set the Xray log level:", err)
+		t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.messages.logLevelFailed", "Error=="+html.EscapeString(err.Error())))
+		return
+	}
+	if !changed {
+		t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.messages.logLevelUnchanged", "Level=="+level))
+		return
+	}
+	logger.Infof("Xray log level changed from %s to %s by Telegram user %d", old, level, requestedBy)
+	if t.xrayService.IsXrayRunning() {
+		if err := t.xrayService.RestartXray(true); err != nil {
+			t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.messages.logLevelFailed", "Error=="+html.EscapeString(err.Error())))
+			return
+		}
+	} else {
+		t.xrayService.SetToNeedRestart()
+	}
+	msg := t.I18nBot("tgbot.messages.logLevelChanged", "Old=="+old, "Level=="+level)
+	if level == "debug" {
+		msg += "\r\n" + t.I18nBot("tgbot.messages.logLevelDebugWarning")
+	}
+	t.SendMsgToTgbot(chatId, msg)
+}
@@ -0,0 +1,86 @@
+package tgbot
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/zixu5u/3xv/v3/internal/logger"
+)
+
+const (
+	// sendFailureLogWindow is how long a send failure stays out of the log
+	// after it was logged, while it keeps repeating.
+	sendFailureLogWindow = 10 * time.Minute
+	// logThrottleMaxAge is how long a held-back repeat count is kept for a
+	// failure that stopped recurring.
+	logThrottleMaxAge = 24 * time.Hour
+)
+
+// sendFailureLog throttles the send failure warnings, so a chat that is
+// permanently broken logs once per window instead of on every attempt.
+var sendFailureLog = newLogThrottle(sendFailureLogWindow)
+
+// logThrottle lets a repeated log message through once per window and
+// counts the repeats it held back in between.
+type logThrottle struct {
+	mu      sync.Mutex
+	window  time.Duration
+	entries map[string]*throttledLog
+}
+
+type throttledLog struct {
+	logged     time.Time
+	suppressed int
+}
+
+func newLogThrottle(window time.Duration) *logThrottle {
+	return &logThrottle{window: window, entries: make(map[string]*throttledLog)}
+}
+
+// allow reports whether the message identified by key may be logged at now.
+// When it may, suppressed is the number of repeats held back since it was
+// last logged.
+func (l *logThrottle) allow(key string, now time.Time) (ok bool, suppressed int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if entry, found := l.entries[key]; found {
+		if now.Sub(entry.logged) < l.window {
+			entry.suppressed++
+			return false, 0
+		}
+		suppressed = entry.suppressed
+	}
+	for k, entry := range l.entries {
+		age := now.Sub(entry.logged)
+		if age >= logThrottleMaxAge || (age >= l.window && entry.suppressed == 0) {
+			delete(l.entries, k)
+		}
+	}
+	l.entries[key] = &throttledLog{logged: now}
+	return true, suppressed
+}
+
+// repeatsNote renders the repeats held back before a throttled message, or
+// "" when there were none.
+func repeatsNote(suppressed int) string {
+	if suppressed == 0 {
+		return ""
+	}
+	return " (" + strconv.Itoa(suppressed) + " more since last logged)"
+}
+
+// errorDigits matches the numbers in send errors, such as the seconds of a
+// "retry after", so repeats of one failure share a key.
+var errorDigits = regexp.MustCompile(`\d+`)
+
+// logSendFailure logs a failed send to chatId, throttled per chat and
+// error.
+func logSendFailure(chatId int64, err error) {
+	key := fmt.Sprintf("%d %s", chatId, errorDigits.ReplaceAllString(err.Error(), "#"))
+	if ok, suppressed := sendFailureLog.allow(key, time.Now()); ok {
+		logger.Warningf("Error sending telegram message to %d: %v%s", chatId, err, repeatsNote(suppressed))
+	}
+}
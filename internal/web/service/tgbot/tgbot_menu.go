@@ -0,0 +1,102 @@
+package tgbot
+
+import (
+	"strings"
+
+	"github.com/mymmrac/telego"
+	tu "github.com/mymmrac/telego/telegoutil"
+)
+
+// menuItem is a button the main menu can show. label is an i18n key and
+// callback the query answerCallback dispatches on.
+type menuItem struct {
+	label    string
+	callback string
+}
+
+// adminMenuItems and clientMenuItems are the buttons each role may place
+// in its menu, keyed by the ids used in the tgBotAdminMenu and
+// tgBotClientMenu settings. A role can never get a button from the other
+// registry, whatever the setting says.
+var (
+	adminMenuItems = map[string]menuItem{
+		"trafficReport":   {"tgbot.buttons.SortedTrafficUsageReport", "get_sorted_traffic_usage_report"},
+		"serverUsage":     {"tgbot.buttons.serverUsage", "get_usage"},
+		"resetTraffics":   {"tgbot.buttons.ResetAllTraffics", "reset_all_traffics"},
+		"resetInbounds":   {"tgbot.buttons.ResetAllInboundTraffics", "reset_all_inbound_traffics"},
+		"backup":          {"tgbot.buttons.dbBackup", "get_backup"},
+		"banLogs":         {"tgbot.buttons.getBanLogs", "get_banlogs"},
+		"inbounds":        {"tgbot.buttons.getInbounds", "inbounds"},
+		"depleteSoon":     {"tgbot.buttons.depleteSoon", "deplete_soon"},
+		"commands":        {"tgbot.buttons.commands", "commands"},
+		"onlines":         {"tgbot.buttons.onlines", "onlines"},
+		"allClients":      {"tgbot.buttons.allClients", "get_inbounds"},
+		"addClient":       {"tgbot.buttons.addClient", "add_client"},
+		"subLinks":        {"pages.settings.subSettings", "admin_client_sub_links"},
+		"individualLinks": {"subscription.individualLinks", "admin_client_individual_links"},
+		"qrLinks":         {"qrCode", "admin_client_qr_links"},
+		"restartXray":     {"tgbot.buttons.restartXray", "restart_xray"},
+	}
+	clientMenuItems = map[string]menuItem{
+		"usage":           {"tgbot.buttons.clientUsage", "client_traffic"},
+		"commands":        {"tgbot.buttons.commands", "client_commands"},
+		"subLinks":        {"pages.settings.subSettings", "client_sub_links"},
+		"individualLinks": {"subscription.individualLinks", "client_individual_links"},
+		"qrLinks":         {"qrCode", "client_qr_links"},
+	}
+)
+
+// Default menu layouts, used while the settings are empty. Rows are
+// separated by ";" and buttons within a row by ",".
+const (
+	defaultAdminMenu  = "trafficReport;serverUsage;resetTraffics,resetInbounds;backup,banLogs;inbounds,depleteSoon;commands,onlines;allClients,addClient;subLinks,individualLinks,qrLinks"
+	defaultClientMenu = "usage,commands;subLinks,individualLinks;qrLinks"
+)
+
+// parseMenuLayout turns a layout setting into rows of item ids. Ids missing
+// from items and empty rows are dropped; a spec without any known id gives
+// the fallback layout, so a typo can't leave the menu empty.
+func parseMenuLayout(spec string, fallback string, items map[string]menuItem) [][]string {
+	if rows := splitMenuLayout(spec, items); len(rows) > 0 {
+		return rows
+	}
+	return splitMenuLayout(fallback, items)
+}
+
+func splitMenuLayout(spec string, items map[string]menuItem) [][]string {
+	var rows [][]string
+	for rowSpec := range strings.SplitSeq(spec, ";") {
+		var row []string
+		for id := range strings.SplitSeq(rowSpec, ",") {
+			id = strings.TrimSpace(id)
+			if _, ok := items[id]; ok {
+				row = append(row, id)
+			}
+		}
+		if len(row) > 0 {
+			rows = append(rows, row)
+		}
+	}
+	return rows
+}
+
+// buildMenu renders the main menu for admins or clients from the
+// configured layout.
+func (t *Tgbot) buildMenu(isAdmin bool) *telego.InlineKeyboardMarkup {
+	items, fallback := clientMenuItems, defaultClientMenu
+	spec, _ := t.settingService.GetTgBotClientMenu()
+	if isAdmin {
+		items, fallback = adminMenuItems, defaultAdminMenu
+		spec, _ = t.settingService.GetTgBotAdminMenu()
+	}
+	var rows [][]telego.InlineKeyboardButton
+	for _, ids := range parseMenuLayout(spec, fallback, items) {
+		row := make([]telego.InlineKeyboardButton, 0, len(ids))
+		for _, id := range ids {
+			item := items[id]
+			row = append(row, tu.InlineKeyboardButton(t.I18nBot(item.label)).WithCallbackData(t.encodeQuery(item.callback)))
+		}
+		rows = append(rows, row)
+	}
+	return tu.InlineKeyboard(rows...)
+}
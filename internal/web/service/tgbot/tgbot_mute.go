@@ -0,0 +1,111 @@
+package tgbot
+
+import (
+	"html"
+	"strings"
+
+	"github.com/zixu5u/3xv/v3/internal/database/model"
+	"github.com/zixu5u/3xv/v3/internal/logger"
+	"github.com/zixu5u/3xv/v3/internal/xray"
+)
+
+// mutedInboundTags returns the set of muted inbound tags. When the list
+// can't be read nothing is treated as muted, so alerts still go out.
+func (t *Tgbot) mutedInboundTags() map[string]bool {
+	list, err := t.inboundMute.GetMutedInbounds()
+	if err != nil {
+		logger.Warning("Unable to load muted inbounds:", err)
+		return nil
+	}
+	muted := make(map[string]bool, len(list))
+	for _, tag := range list {
+		muted[tag] = true
+	}
+	return muted
+}
+
+// mutedInboundIds maps the muted tags onto the ids of the given inbounds.
+func mutedInboundIds(inbounds []*model.Inbound, muted map[string]bool) map[int]bool {
+	ids := make(map[int]bool)
+	for _, inbound := range inbounds {
+		if muted[inbound.Tag] {
+			ids[inbound.Id] = true
+		}
+	}
+	return ids
+}
+
+// withoutMutedTraffics drops the traffic rows that belong to muted inbounds.
+func withoutMutedTraffics(traffics []*xray.ClientTraffic, mutedIds map[int]bool) []*xray.ClientTraffic {
+	if len(mutedIds) == 0 {
+		return traffics
+	}
+	kept := make([]*xray.ClientTraffic, 0, len(traffics))
+	for _, traffic := range traffics {
+		if !mutedIds[traffic.InboundId] {
+			kept = append(kept, traffic)
+		}
+	}
+	return kept
+}
+
+// muteInbound implements /mute: the inbound keeps serving clients, but its
+// traffic and expiry alerts are no longer sent.
+func (t *Tgbot) muteInbound(chatId int64, tag string, requestedBy int64) {
+	if _, err := t.inboundService.GetInboundByTag(tag); err != nil {
+		t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.messages.muteNoInbound", "Tag=="+escapeField(tag)))
+		return
+	}
+	added, err := t.inboundMute.MuteInbound(tag)
+	if err != nil {
+		logger.Warningf("Muting inbound %s requested by %d failed: %v", tag, requestedBy, err)
+		logBotEvent(botEvent{Event: "inbound_mute", ChatID: requestedBy, Command: "mute", Err: err})
+		t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.messages.muteFailed", "Error=="+html.EscapeString(err.Error())))
+		return
+	}
+	if !added {
+		t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.messages.muteAlready", "Tag=="+escapeField(tag)))
+		return
+	}
+	logger.Infof("Alerts for inbound %s muted by Telegram user %d", tag, requestedBy)
+	logBotEvent(botEvent{Event: "inbound_mute", ChatID: requestedBy, Command: "mute"})
+	t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.messages.muteSuccess", "Tag=="+escapeField(tag)))
+}
+
+// unmuteInbound implements /unmute. A tag whose inbound was deleted can
+// still be removed from the list.
+func (t *Tgbot) unmuteInbound(chatId int64, tag string, requestedBy int64) {
+	removed, err := t.inboundMute.UnmuteInbound(tag)
+	if err != nil {
+		logger.Warningf("Unmuting inbound %s requested by %d failed: %v", tag, requestedBy, err)
+		logBotEvent(botEvent{Event: "inbound_unmute", ChatID: requestedBy, Command: "unmute", Err: err})
+		t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.messages.muteFailed", "Error=="+html.EscapeString(err.Error())))
+		return
+	}
+	if !removed {
+		t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.messages.muteNotFound", "Tag=="+escapeField(tag)))
+		return
+	}
+	logger.Infof("Alerts for inbound %s unmuted by Telegram user %d", tag, requestedBy)
+	logBotEvent(botEvent{Event: "inbound_unmute", ChatID: requestedBy, Command: "unmute"})
+	t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.messages.unmuteSuccess", "Tag=="+escapeField(tag)))
+}
+
+// sendMutedInbounds implements /muted.
+func (t *Tgbot) sendMutedInbounds(chatId int64) {
+	list, err := t.inboundMute.GetMutedInbounds()
+	if err != nil {
+		t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.messages.muteFailed", "Error=="+html.EscapeString(err.Error())))
+		return
+	}
+	if len(list) == 0 {
+		t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.messages.mutedListEmpty"))
+		return
+	}
+	var output strings.Builder
+	output.WriteString(t.I18nBot("tgbot.messages.mutedListHeader"))
+	for _, tag := range list {
+		output.WriteString("\r\n<code>" + escapeField(tag) + "</code>")
+	}
+	t.SendMsgToTgbot(chatId, output.String())
+}
@@ -0,0 +1,109 @@
+package tgbot
+
+import (
+	"html"
+	"strconv"
+
+	"github.com/zixu5u/3xv/v3/internal/logger"
+	"github.com/zixu5u/3xv/v3/internal/web/service"
+
+	tu "github.com/mymmrac/telego/telegoutil"
+)
+
+// Conversation states for editing a note. The note's target (client email
+// or inbound id) is kept in noteTargets for the same chat.
+const (
+	stateAwaitingClientNote  = "awaiting_client_note"
+	stateAwaitingInboundNote = "awaiting_inbound_note"
+)
+
+var noteTargets = make(map[int64]string)
+
+// noteLine renders a note for admin listings, or "" when there is none.
+func (t *Tgbot) noteLine(note string) string {
+	if note == "" {
+		return ""
+	}
+	return t.I18nBot("tgbot.messages.note", "Note=="+html.EscapeString(note))
+}
+
+// clientNoteLine renders the client's comment as a note line. Client
+// comments are only shown to admins, never in customer-facing messages.
+func (t *Tgbot) clientNoteLine(email string) string {
+	record, err := t.clientService.GetRecordByEmail(nil, email)
+	if err != nil {
+		return ""
+	}
+	return t.noteLine(record.Comment)
+}
+
+// promptClientNote starts editing a client's note.
+func (t *Tgbot) promptClientNote(chatId int64, email string) {
+	current := ""
+	if record, err := t.clientService.GetRecordByEmail(nil, email); err == nil {
+		current = record.Comment
+	}
+	userStates[chatId] = stateAwaitingClientNote
+	noteTargets[chatId] = email
+
+	inlineKeyboard := tu.InlineKeyboard(
+		tu.InlineKeyboardRow(
+			tu.InlineKeyboardButton(t.I18nBot("tgbot.buttons.clearNote")).WithCallbackData(t.encodeQuery("client_note_clear "+email)),
+			tu.InlineKeyboardButton(t.I18nBot("tgbot.buttons.cancel")).WithCallbackData(t.encodeQuery("client_cancel "+email)),
+		),
+	)
+	t.SendMsgToTgbot(chatId, t.notePrompt(current), inlineKeyboard)
+}
+
+// promptInboundNote starts editing an inbound's note.
+func (t *Tgbot) promptInboundNote(chatId int64, inboundId int) {
+	inbound, err := t.inboundService.GetInbound(inboundId)
+	if err != nil {
+		t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.answers.errorOperation"))
+		return
+	}
+	userStates[chatId] = stateAwaitingInboundNote
+	noteTargets[chatId] = strconv.Itoa(inboundId)
+
+	inlineKeyboard := tu.InlineKeyboard(
+		tu.InlineKeyboardRow(
+			tu.InlineKeyboardButton(t.I18nBot("tgbot.buttons.clearNote")).WithCallbackData(t.encodeQuery("inbound_note_clear " + strconv.Itoa(inboundId))),
+		),
+	)
+	t.SendMsgToTgbot(chatId, t.notePrompt(inbound.Note), inlineKeyboard)
+}
+
+// notePrompt asks for the new note text, showing the current one.
+func (t *Tgbot) notePrompt(current string) string {
+	if current == "" {
+		current = "—"
+	}
+	return t.I18nBot("tgbot.messages.notePrompt",
+		"Note=="+html.EscapeString(current),
+		"Max=="+strconv.Itoa(service.MaxNoteLength))
+}
+
+// saveClientNote stores note as the client's comment and shows the client.
+func (t *Tgbot) saveClientNote(chatId int64, email string, note string) {
+	needRestart, err := t.clientService.SetClientCommentByEmail(&t.inboundService, email, note)
+	if needRestart {
+		t.xrayService.SetToNeedRestart()
+	}
+	if err != nil {
+		logger.Warning("Failed to set client note:", err)
+		t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.messages.noteFailed", "Error=="+html.EscapeString(err.Error())))
+		return
+	}
+	t.SendMsgToTgbotDeleteAfter(chatId, t.I18nBot("tgbot.messages.noteSaved"), 3, tu.ReplyKeyboardRemove())
+	t.searchClient(chatId, email)
+}
+
+// saveInboundNote stores note on the inbound.
+func (t *Tgbot) saveInboundNote(chatId int64, inboundId int, note string) {
+	if err := t.inboundService.SetInboundNote(inboundId, note); err != nil {
+		logger.Warning("Failed to set inbound note:", err)
+		t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.messages.noteFailed", "Error=="+html.EscapeString(err.Error())))
+		return
+	}
+	t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.messages.noteSaved"))
+}
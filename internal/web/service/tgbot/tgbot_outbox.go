@@ -0,0 +1,210 @@
+package tgbot
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/zixu5u/3xv/v3/internal/database/model"
+	"github.com/zixu5u/3xv/v3/internal/logger"
+
+	"github.com/mymmrac/telego"
+)
+
+const (
+	// outboxInterval is how often the outbox worker looks for queued
+	// notifications that are due for a retry.
+	outboxInterval = 5 * time.Second
+	// outboxBatch bounds how many queued notifications of one chat a pass
+	// reads.
+	outboxBatch = 100
+)
+
+// outboxWorker holds the running outbox worker, if any. Critical
+// notifications to the admins go through it: they are stored before the
+// first attempt and removed per chat once Telegram accepted them, so a crash
+// or restart mid-send replays the ones that were not delivered. Everything
+// else is sent once, best effort.
+var outboxWorker struct {
+	sync.Mutex
+	cancel context.CancelFunc
+}
+
+// outboxWake nudges the worker to send a notification queued just now
+// instead of waiting for the next tick.
+var outboxWake = make(chan struct{}, 1)
+
+// outboxDrain serializes passes over the queue, so a notification is never
+// sent by two passes at once.
+var outboxDrain sync.Mutex
+
+// queueCritical stores a critical notification for every admin chat and
+// wakes the outbox worker. It reports false when there is no admin chat or
+// the queue can't be written, and the caller sends it directly instead.
+func (t *Tgbot) queueCritical(category string, msg string, replyMarkup []telego.ReplyMarkup) bool {
+	ids := adminChatIds()
+	if len(ids) == 0 {
+		return false
+	}
+	markup, err := encodeOutboxMarkup(replyMarkup)
+	if err == nil {
+		err = t.outbox.Enqueue(category, msg, markup, ids, time.Now())
+	}
+	if err != nil {
+		logger.Warning("Failed to queue a critical notification, sending it directly:", err)
+		return false
+	}
+	select {
+	case outboxWake <- struct{}{}:
+	default:
+	}
+	return true
+}
+
+// encodeOutboxMarkup stores the inline keyboard of a queued notification as
+// JSON. Notifications carry no other kind of markup.
+func encodeOutboxMarkup(replyMarkup []telego.ReplyMarkup) (string, error) {
+	if len(replyMarkup) == 0 || replyMarkup[0] == nil {
+		return "", nil
+	}
+	keyboard, ok := replyMarkup[0].(*telego.InlineKeyboardMarkup)
+	if !ok {
+		return "", fmt.Errorf("unsupported reply markup %T", replyMarkup[0])
+	}
+	data, err := json.Marshal(keyboard)
+	return string(data), err
+}
+
+// decodeOutboxMarkup restores the keyboard stored by encodeOutboxMarkup. One
+// that no longer parses is left out rather than holding the message back.
+func decodeOutboxMarkup(raw string) []telego.ReplyMarkup {
+	if raw == "" {
+		return nil
+	}
+	var keyboard telego.InlineKeyboardMarkup
+	if err := json.Unmarshal([]byte(raw), &keyboard); err != nil {
+		logger.Warning("Dropping the unreadable keyboard of a queued notification:", err)
+		return nil
+	}
+	return []telego.ReplyMarkup{&keyboard}
+}
+
+// startOutbox starts the outbox worker, replacing one left from a previous
+// start. Its first pass sends what an earlier run left undelivered.
+func (t *Tgbot) startOutbox() {
+	outboxWorker.Lock()
+	defer outboxWorker.Unlock()
+	if outboxWorker.cancel != nil {
+		outboxWorker.cancel()
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	outboxWorker.cancel = cancel
+	go t.runOutbox(ctx)
+}
+
+// stopOutbox stops the outbox worker. Undelivered notifications stay queued
+// for the next start.
+func stopOutbox() {
+	outboxWorker.Lock()
+	defer outboxWorker.Unlock()
+	if outboxWorker.cancel != nil {
+		outboxWorker.cancel()
+		outboxWorker.cancel = nil
+	}
+}
+
+func (t *Tgbot) runOutbox(ctx context.Context) {
+	ticker := time.NewTicker(outboxInterval)
+	defer ticker.Stop()
+	for {
+		t.drainOutbox(ctx, time.Now())
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-outboxWake:
+		}
+	}
+}
+
+// drainOutbox sends the queued notifications that are due, each chat's
+// oldest first. Every chat's queue is read on its own, so a chat whose
+// notifications keep failing can't fill the batch and hold up the alerts of
+// the others. A chat whose notification fails or isn't due yet gets none of
+// its later ones in the same pass, so each chat receives them in order.
+// Chats that are no longer admins, or that blocked the bot, are dropped from
+// the queue.
+func (t *Tgbot) drainOutbox(ctx context.Context, now time.Time) {
+	outboxDrain.Lock()
+	defer outboxDrain.Unlock()
+	admins := adminChatIds()
+	if len(admins) == 0 {
+		return
+	}
+	chatIds, err := t.outbox.QueuedChats()
+	if err != nil {
+		logger.Warning("Failed to read the notification queue:", err)
+		return
+	}
+	for _, chatId := range chatIds {
+		entries, err := t.outbox.Pending(chatId, outboxBatch)
+		if err != nil {
+			logger.Warning("Failed to read the notification queue:", err)
+			return
+		}
+		for i := range entries {
+			if ctx.Err() != nil || !t.IsRunning() {
+				return
+			}
+			entry := &entries[i]
+			if !slices.Contains(admins, entry.ChatId) {
+				logger.Infof("Dropping a queued %s notification for %d, no longer an admin chat", entry.Category, entry.ChatId)
+				t.dropQueued(entry)
+				continue
+			}
+			if entry.NextAttemptAt > now.UnixMilli() || !t.sendQueued(entry) {
+				break
+			}
+		}
+	}
+}
+
+// sendQueued makes one delivery attempt of a queued notification and
+// records its outcome. It reports whether the chat accepted it. A
+// delivered alert of an unresolved condition is pinned, if enabled.
+func (t *Tgbot) sendQueued(entry *model.PendingNotification) bool {
+	start := time.Now()
+	messageId, err := t.sendMsgPages(bot, entry.ChatId, entry.Text, false, decodeOutboxMarkup(entry.ReplyMarkup)...)
+	recordDelivery(entry.ChatId, err)
+	logBotEvent(botEvent{Event: "notification", ChatID: entry.ChatId, Category: entry.Category, Latency: time.Since(start), Err: err})
+	if err == nil {
+		if err := t.outbox.Delivered(entry.Id); err != nil {
+			logger.Warningf("Queued %s notification delivered to %d but still queued, it may be sent again: %v", entry.Category, entry.ChatId, err)
+		}
+		t.pinAlert(entry.Category, entry.ChatId, messageId)
+		return true
+	}
+	if classifyDelivery(err) == deliveryBlocked {
+		logger.Warningf("Dropping a queued %s notification for %d, the chat does not accept messages from the bot: %v", entry.Category, entry.ChatId, err)
+		t.dropQueued(entry)
+		return false
+	}
+	dropped, qerr := t.outbox.Failed(entry, err, time.Now())
+	switch {
+	case qerr != nil:
+		logger.Warning("Failed to update the notification queue:", qerr)
+	case dropped:
+		logger.Errorf("Gave up on a queued %s notification for %d after %d attempts: %v", entry.Category, entry.ChatId, entry.Attempts+1, err)
+	}
+	return false
+}
+
+// dropQueued removes a notification that will not be delivered.
+func (t *Tgbot) dropQueued(entry *model.PendingNotification) {
+	if err := t.outbox.Drop(entry.Id); err != nil {
+		logger.Warning("Failed to update the notification queue:", err)
+	}
+}
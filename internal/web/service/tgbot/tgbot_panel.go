@@ -0,0 +1,123 @@
+package tgbot
+
+import (
+	"errors"
+	"html"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/zixu5u/3xv/v3/internal/logger"
+)
+
+// panelURL builds the address of the web panel's login page on host, a
+// name or an unbracketed IP, from the panel port, whether it serves TLS and
+// its base path. Standard ports are left out.
+func panelURL(host string, port int, tls bool, basePath string) string {
+	u := url.URL{Scheme: "http", Host: host}
+	if tls {
+		u.Scheme = "https"
+	}
+	if (tls && port != 443) || (!tls && port != 80) {
+		u.Host = net.JoinHostPort(host, strconv.Itoa(port))
+	} else if strings.Contains(host, ":") {
+		u.Host = "[" + host + "]"
+	}
+	if !strings.HasPrefix(basePath, "/") {
+		basePath = "/" + basePath
+	}
+	if !strings.HasSuffix(basePath, "/") {
+		basePath += "/"
+	}
+	u.Path = basePath
+	return u.String()
+}
+
+// validatePanelDomain checks that the webDomain setting is a bare host name
+// or IP, without scheme, port or path, so a URL can be built from it.
+func validatePanelDomain(domain string) error {
+	if strings.ContainsAny(domain, " /\\?#@") || strings.Contains(domain, "://") {
+		return errors.New("expected a host name without scheme or path")
+	}
+	host := strings.TrimSuffix(strings.TrimPrefix(domain, "["), "]")
+	if net.ParseIP(host) != nil {
+		return nil
+	}
+	if strings.Contains(domain, ":") {
+		return errors.New("the port belongs in the panel port setting")
+	}
+	for _, label := range strings.Split(strings.TrimSuffix(domain, "."), ".") {
+		if label == "" || len(label) > 63 || strings.HasPrefix(label, "-") || strings.HasSuffix(label, "-") {
+			return errors.New("not a valid host name")
+		}
+	}
+	return nil
+}
+
+// panelHost returns the host the panel link points to: the webDomain
+// setting, or else the listen address or the server's own address, in
+// which case guessed is true.
+func (t *Tgbot) panelHost() (host string, guessed bool, err error) {
+	domain, err := t.settingService.GetWebDomain()
+	if err != nil {
+		return "", false, err
+	}
+	if domain = strings.TrimSpace(domain); domain != "" {
+		if err := validatePanelDomain(domain); err != nil {
+			return domain, false, err
+		}
+		return strings.TrimSuffix(strings.TrimPrefix(domain, "["), "]"), false, nil
+	}
+	if listen, _ := t.settingService.GetListen(); listen != "" {
+		if ip := net.ParseIP(listen); ip == nil || !ip.IsUnspecified() {
+			return listen, true, nil
+		}
+	}
+	if ipv4, _ := t.getServerIPs(); ipv4 != "" {
+		return ipv4, true, nil
+	}
+	if hostname != "" {
+		return hostname, true, nil
+	}
+	return "localhost", true, nil
+}
+
+// sendPanelLink implements /panel: a link to the web panel's login page
+// built from the panel settings. The panel has no one-time login, so only
+// the address is sent and the admin signs in as usual; no credential or
+// session ever goes through the chat.
+func (t *Tgbot) sendPanelLink(chatId int64) {
+	host, guessed, err := t.panelHost()
+	if err != nil {
+		if host == "" {
+			logger.Warning("Failed to read the panel domain:", err)
+			t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.wentWrong"))
+			return
+		}
+		t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.messages.panelDomainInvalid",
+			"Domain=="+escapeField(host),
+			"Error=="+html.EscapeString(err.Error())))
+		return
+	}
+	port, err := t.settingService.GetPort()
+	if err != nil {
+		t.settingFallback("webPort", err, "2053")
+		port = 2053
+	}
+	certFile, _ := t.settingService.GetCertFile()
+	keyFile, _ := t.settingService.GetKeyFile()
+	basePath, err := t.settingService.GetBasePath()
+	if err != nil {
+		t.settingFallback("webBasePath", err, "/")
+		basePath = "/"
+	}
+
+	link := panelURL(host, port, certFile != "" && keyFile != "", basePath)
+	msg := t.I18nBot("tgbot.messages.panelLink", "URL=="+html.EscapeString(link))
+	if guessed {
+		msg += "\r\n\r\n" + t.I18nBot("tgbot.messages.panelDomainUnset")
+	}
+	msg += "\r\n\r\n" + t.I18nBot("tgbot.messages.panelSignIn")
+	t.SendMsgToTgbot(chatId, msg)
+}
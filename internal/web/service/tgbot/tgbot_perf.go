@@ -0,0 +1,97 @@
+package tgbot
+
+import (
+	"cmp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// perfSamplesPerCommand bounds the latency window kept for each command.
+const perfSamplesPerCommand = 100
+
+// commandLatency keeps the most recent handler latencies per command for
+// /perf. Only recognized commands are recorded, so arbitrary user input
+// can't grow the map.
+var commandLatency = struct {
+	sync.Mutex
+	samples map[string][]time.Duration
+}{samples: make(map[string][]time.Duration)}
+
+// commandPerf summarizes the recent latencies of one command.
+type commandPerf struct {
+	Command string
+	Count   int
+	Avg     time.Duration
+	P95     time.Duration
+	Max     time.Duration
+}
+
+// recordCommandLatency adds one handler run to the command's window.
+func recordCommandLatency(command string, d time.Duration) {
+	commandLatency.Lock()
+	defer commandLatency.Unlock()
+	buf := append(commandLatency.samples[command], d)
+	if len(buf) > perfSamplesPerCommand {
+		buf = buf[len(buf)-perfSamplesPerCommand:]
+	}
+	commandLatency.samples[command] = buf
+}
+
+// commandPerfSnapshot returns per-command stats, slowest p95 first.
+func commandPerfSnapshot() []commandPerf {
+	commandLatency.Lock()
+	stats := make([]commandPerf, 0, len(commandLatency.samples))
+	for command, buf := range commandLatency.samples {
+		stats = append(stats, summarizeLatencies(command, buf))
+	}
+	commandLatency.Unlock()
+
+	slices.SortFunc(stats, func(a, b commandPerf) int {
+		if c := cmp.Compare(b.P95, a.P95); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Command, b.Command)
+	})
+	return stats
+}
+
+// summarizeLatencies computes count, mean, nearest-rank p95 and max.
+func summarizeLatencies(command string, buf []time.Duration) commandPerf {
+	perf := commandPerf{Command: command, Count: len(buf)}
+	if len(buf) == 0 {
+		return perf
+	}
+	sorted := slices.Clone(buf)
+	slices.Sort(sorted)
+	var sum time.Duration
+	for _, d := range sorted {
+		sum += d
+	}
+	perf.Avg = sum / time.Duration(len(sorted))
+	perf.P95 = sorted[(len(sorted)*95+99)/100-1]
+	perf.Max = sorted[len(sorted)-1]
+	return perf
+}
+
+// sendPerf reports command latencies, slowest first.
+func (t *Tgbot) sendPerf(chatId int64) {
+	stats := commandPerfSnapshot()
+	if len(stats) == 0 {
+		t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.messages.perfEmpty"))
+		return
+	}
+	var output strings.Builder
+	output.WriteString(t.I18nBot("tgbot.messages.perfHeader", "Samples=="+strconv.Itoa(perfSamplesPerCommand)))
+	for _, s := range stats {
+		output.WriteString(t.I18nBot("tgbot.messages.perfCommand",
+			"Command=="+s.Command,
+			"Avg=="+s.Avg.Round(time.Millisecond).String(),
+			"P95=="+s.P95.Round(time.Millisecond).String(),
+			"Max=="+s.Max.Round(time.Millisecond).String(),
+			"Count=="+strconv.Itoa(s.Count)))
+	}
+	t.SendMsgToTgbot(chatId, output.String())
+}
@@ -0,0 +1,144 @@
+package tgbot
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/zixu5u/3xv/v3/internal/logger"
+
+	"github.com/mymmrac/telego"
+	tu "github.com/mymmrac/telego/telegoutil"
+)
+
+// pinnableCategories are the critical alerts that, with tgPinCritical on,
+// stay pinned in the admin chats until ResolveAlert reports their condition
+// cleared. Only these have a recovery to unpin on; pinning the others would
+// leave them at the top of the chat for good.
+var pinnableCategories = map[string]bool{
+	NotifyXray: true,
+}
+
+// pinnedAlerts tracks the conditions with an unresolved alert and the
+// message pinned for each in every admin chat. It lives in memory: after a
+// restart the pins of earlier alerts are no longer known, and alerts the
+// outbox replays are sent without a pin.
+var pinnedAlerts struct {
+	sync.Mutex
+	active map[string]bool
+	pins   map[string]map[int64]int
+}
+
+// markAlertActive records that an alert of category was raised, so its
+// messages are pinned as they are delivered.
+func markAlertActive(category string) {
+	pinnedAlerts.Lock()
+	defer pinnedAlerts.Unlock()
+	if pinnedAlerts.active == nil {
+		pinnedAlerts.active = make(map[string]bool)
+	}
+	pinnedAlerts.active[category] = true
+}
+
+// recordPin remembers messageId as the pinned alert of category in chatId.
+// It returns the message pinned for an earlier alert of category there, if
+// any, and false when the condition was resolved in the meantime.
+func recordPin(category string, chatId int64, messageId int) (previous int, ok bool) {
+	pinnedAlerts.Lock()
+	defer pinnedAlerts.Unlock()
+	if !pinnedAlerts.active[category] {
+		return 0, false
+	}
+	if pinnedAlerts.pins == nil {
+		pinnedAlerts.pins = make(map[string]map[int64]int)
+	}
+	chats := pinnedAlerts.pins[category]
+	if chats == nil {
+		chats = make(map[int64]int)
+		pinnedAlerts.pins[category] = chats
+	}
+	previous = chats[chatId]
+	chats[chatId] = messageId
+	return previous, true
+}
+
+// resolvePins marks the condition of category resolved and returns the
+// messages pinned for it, by chat.
+func resolvePins(category string) map[int64]int {
+	pinnedAlerts.Lock()
+	defer pinnedAlerts.Unlock()
+	delete(pinnedAlerts.active, category)
+	pins := pinnedAlerts.pins[category]
+	delete(pinnedAlerts.pins, category)
+	return pins
+}
+
+// pinCritical reports whether tgPinCritical is on.
+func (t *Tgbot) pinCritical() bool {
+	on, err := t.settingService.GetTgPinCritical()
+	if err != nil {
+		logger.Warning("Failed to get Telegram bot pin setting:", err)
+		return false
+	}
+	return on
+}
+
+// pinAlert pins the alert message messageId in chatId and unpins the one
+// pinned for an earlier alert of the same condition. A chat where the bot
+// may not pin keeps the alert as a plain message.
+func (t *Tgbot) pinAlert(category string, chatId int64, messageId int) {
+	if messageId == 0 || !pinnableCategories[category] || !t.pinCritical() {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	err := bot.PinChatMessage(ctx, &telego.PinChatMessageParams{
+		ChatID:              tu.ID(chatId),
+		MessageID:           messageId,
+		DisableNotification: true,
+	})
+	if err != nil {
+		logger.Warningf("Could not pin the %s alert in %d, the bot may lack the permission to pin: %v", category, chatId, err)
+		return
+	}
+	previous, ok := recordPin(category, chatId, messageId)
+	switch {
+	case !ok:
+		// Resolved while this alert was on its way.
+		t.unpinAlert(category, chatId, messageId)
+	case previous != 0 && previous != messageId:
+		t.unpinAlert(category, chatId, previous)
+	}
+}
+
+// unpinAlert unpins an alert message. The admin may have unpinned or
+// deleted it already, so a failure is only logged.
+func (t *Tgbot) unpinAlert(category string, chatId int64, messageId int) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	err := bot.UnpinChatMessage(ctx, &telego.UnpinChatMessageParams{
+		ChatID:    tu.ID(chatId),
+		MessageID: messageId,
+	})
+	if err != nil {
+		logger.Debugf("Could not unpin the %s alert in %d: %v", category, chatId, err)
+	}
+}
+
+// ResolveAlert reports that the condition behind the alerts of category
+// cleared: msg goes out like the alert did, as a success, and the alerts
+// pinned for the condition are unpinned.
+func (t *Tgbot) ResolveAlert(category string, msg string) {
+	pins := resolvePins(category)
+	if !t.IsRunning() {
+		return
+	}
+	for chatId, messageId := range pins {
+		t.unpinAlert(category, chatId, messageId)
+	}
+	logBotEvent(botEvent{Event: "alert", Category: category, Outcome: "resolved"})
+	msg = decorate(severitySuccess, msg)
+	t.SendMsgToTgbotAdmins(msg)
+	t.notifyExtraBots(category, msg)
+	t.notifyChannel(category, msg)
+}
@@ -0,0 +1,62 @@
+package tgbot
+
+import (
+	"strconv"
+
+	"github.com/zixu5u/3xv/v3/internal/logger"
+)
+
+// poolListSize caps the clients listed by /pool; the biggest consumers come
+// first.
+const poolListSize = 30
+
+// formatPoolPercent renders a client's share of an inbound's traffic. Shares
+// too small to show as 0.1% read as "<0.1%" rather than "0.0%".
+func formatPoolPercent(percent float64) string {
+	if percent > 0 && percent < 0.05 {
+		return "<0.1%"
+	}
+	return formatDecimal(percent, 1) + "%"
+}
+
+// sendInboundPool implements /pool: the traffic limit of an inbound, how
+// much of it is used and left, and each client's share of the usage.
+func (t *Tgbot) sendInboundPool(chatId int64, tag string) {
+	inbound, err := t.inboundService.GetInboundByTag(tag)
+	if err != nil {
+		t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.messages.poolNoInbound", "Tag=="+escapeField(tag)))
+		return
+	}
+	usage, err := t.inboundService.GetInboundPoolUsage(inbound.Id)
+	if err != nil {
+		logger.Warning("Failed to get inbound pool usage:", err)
+		t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.wentWrong"))
+		return
+	}
+
+	remaining := t.I18nBot("tgbot.unlimited")
+	if usage.Limit > 0 {
+		remaining = formatTraffic(usage.Remaining)
+	}
+	msg := t.I18nBot("tgbot.messages.poolHeader",
+		"Remark=="+escapeField(inbound.Remark),
+		"Limit=="+t.formatInboundLimit(usage.Limit),
+		"Used=="+formatTraffic(usage.Used),
+		"Remaining=="+remaining)
+	if len(usage.Clients) == 0 {
+		t.SendMsgToTgbot(chatId, msg+t.I18nBot("tgbot.messages.poolNoClients"))
+		return
+	}
+	for i, client := range usage.Clients {
+		if i == poolListSize {
+			msg += t.I18nBot("tgbot.messages.poolMore", "Count=="+strconv.Itoa(len(usage.Clients)-poolListSize))
+			break
+		}
+		msg += t.I18nBot("tgbot.messages.poolLine",
+			"Status=="+enabledMark(client.Enable),
+			"Email=="+escapeField(client.Email),
+			"Used=="+formatTraffic(client.Used),
+			"Percent=="+formatPoolPercent(client.Percent))
+	}
+	t.SendMsgToTgbot(chatId, msg)
+}
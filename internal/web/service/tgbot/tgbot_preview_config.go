@@ -0,0 +1,154 @@
+package tgbot
+
+import (
+	"context"
+	"encoding/json"
+	"html"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/zixu5u/3xv/v3/internal/logger"
+
+	tu "github.com/mymmrac/telego/telegoutil"
+)
+
+// configSnippetBytes caps the config shown inline by /previewconfig, leaving
+// room for the summary within Telegram's message limit.
+const configSnippetBytes = 3000
+
+// redactedValue replaces secrets in the inline config preview.
+const redactedValue = "***"
+
+// configSecretKeys are the config keys whose values are credentials: client
+// ids and passwords, TLS and REALITY keys and the like.
+var configSecretKeys = map[string]bool{
+	"id":           true,
+	"password":     true,
+	"pass":         true,
+	"auth":         true,
+	"secret":       true,
+	"token":        true,
+	"key":          true,
+	"privateKey":   true,
+	"secretKey":    true,
+	"preSharedKey": true,
+	"seed":         true,
+	"decryption":   true,
+}
+
+// redactConfigSecrets replaces the secrets in a decoded config in place.
+// "none", used by VLESS for decryption, is not a secret and is kept.
+func redactConfigSecrets(v any) {
+	switch value := v.(type) {
+	case map[string]any:
+		for k, item := range value {
+			if !configSecretKeys[k] {
+				redactConfigSecrets(item)
+				continue
+			}
+			switch secret := item.(type) {
+			case string:
+				if secret != "" && secret != "none" {
+					value[k] = redactedValue
+				}
+			case []any, map[string]any:
+				value[k] = redactedValue
+			}
+		}
+	case []any:
+		for _, item := range value {
+			redactConfigSecrets(item)
+		}
+	}
+}
+
+// configSnippet cuts data to at most limit bytes at a line break. It
+// reports whether anything was cut.
+func configSnippet(data []byte, limit int) (string, bool) {
+	if len(data) <= limit {
+		return string(data), false
+	}
+	cut := data[:limit]
+	if i := strings.LastIndexByte(string(cut), '\n'); i > 0 {
+		cut = cut[:i]
+	}
+	return string(cut), true
+}
+
+// configCounts counts the inbounds, outbounds and routing rules of a
+// decoded config.
+func configCounts(cfg map[string]any) (inbounds int, outbounds int, rules int) {
+	if list, ok := cfg["inbounds"].([]any); ok {
+		inbounds = len(list)
+	}
+	if list, ok := cfg["outbounds"].([]any); ok {
+		outbounds = len(list)
+	}
+	if routing, ok := cfg["routing"].(map[string]any); ok {
+		if list, ok := routing["rules"].([]any); ok {
+			rules = len(list)
+		}
+	}
+	return inbounds, outbounds, rules
+}
+
+// sendConfigPreview implements /previewconfig. It builds the Xray config
+// the way a restart would, from the template and the current inbounds, but
+// neither writes it nor touches the running core. The inline preview has its
+// secrets redacted; asFile sends the whole config, secrets included, as a
+// document instead.
+func (t *Tgbot) sendConfigPreview(chatId int64, asFile bool, requestedBy int64) {
+	xrayConfig, err := t.xrayService.GetXrayConfig()
+	if err != nil {
+		t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.messages.previewConfigFailed", "Error=="+html.EscapeString(err.Error())))
+		return
+	}
+	// marshalled like the config file Xray is started with
+	data, err := json.MarshalIndent(xrayConfig, "", "  ")
+	if err != nil {
+		t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.messages.previewConfigFailed", "Error=="+html.EscapeString(err.Error())))
+		return
+	}
+	var cfg map[string]any
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.messages.previewConfigFailed", "Error=="+html.EscapeString(err.Error())))
+		return
+	}
+	inbounds, outbounds, rules := configCounts(cfg)
+	summary := t.I18nBot("tgbot.messages.previewConfigSummary",
+		"Inbounds=="+strconv.Itoa(inbounds),
+		"Outbounds=="+strconv.Itoa(outbounds),
+		"Rules=="+strconv.Itoa(rules),
+		"Size=="+formatTraffic(int64(len(data))))
+
+	if asFile {
+		logger.Infof("Full Xray config preview sent to Telegram user %d", requestedBy)
+		logBotEvent(botEvent{Event: "config_preview", ChatID: requestedBy, Command: "previewconfig", Outcome: "file"})
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		document := tu.Document(
+			tu.ID(chatId),
+			tu.FileFromBytes(data, "config-preview-"+time.Now().Format("20060102-150405")+".json"),
+		).WithCaption(summary + "\r\n" + t.I18nBot("tgbot.messages.previewConfigFileCaption")).WithParseMode("HTML")
+		if _, err := bot.SendDocument(ctx, document); err != nil {
+			logger.Warning("Error in uploading the config preview:", err)
+			t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.messages.previewConfigFailed", "Error=="+html.EscapeString(err.Error())))
+		}
+		return
+	}
+
+	redactConfigSecrets(cfg)
+	redacted, err := json.MarshalIndent(cfg, "", "  ")
+	if err != nil {
+		t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.messages.previewConfigFailed", "Error=="+html.EscapeString(err.Error())))
+		return
+	}
+	snippet, truncated := configSnippet(redacted, configSnippetBytes)
+	logBotEvent(botEvent{Event: "config_preview", ChatID: requestedBy, Command: "previewconfig", Outcome: "inline"})
+	msg := summary + "\r\n<pre><code class=\"language-json\">" + html.EscapeString(snippet) + "</code></pre>"
+	if truncated {
+		msg += "\r\n" + t.I18nBot("tgbot.messages.previewConfigTruncated")
+	}
+	t.SendMsgToTgbot(chatId, msg)
+}
@@ -0,0 +1,104 @@
+package tgbot
+
+import (
+	"cmp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/zixu5u/3xv/v3/internal/database/model"
+	"github.com/zixu5u/3xv/v3/internal/logger"
+)
+
+// protocolGroup sums up the inbounds of one protocol for /status protocol.
+type protocolGroup struct {
+	Protocol string
+	Inbounds int
+	Enabled  int
+	Up       int64
+	Down     int64
+	Ports    []int
+}
+
+// isProtocolStatusArg reports whether a /status argument asks for the view
+// grouped by protocol.
+func isProtocolStatusArg(arg string) bool {
+	switch strings.ToLower(arg) {
+	case "protocol", "protocols", "group", "grouped":
+		return true
+	}
+	return false
+}
+
+// groupInboundsByProtocol groups inbounds by protocol, busiest protocol
+// first. Ports are sorted and listed once each.
+func groupInboundsByProtocol(inbounds []*model.Inbound) []protocolGroup {
+	byProtocol := make(map[string]*protocolGroup)
+	for _, inbound := range inbounds {
+		protocol := string(inbound.Protocol)
+		group, ok := byProtocol[protocol]
+		if !ok {
+			group = &protocolGroup{Protocol: protocol}
+			byProtocol[protocol] = group
+		}
+		group.Inbounds++
+		if inbound.Enable {
+			group.Enabled++
+		}
+		group.Up += inbound.Up
+		group.Down += inbound.Down
+		group.Ports = append(group.Ports, inbound.Port)
+	}
+
+	groups := make([]protocolGroup, 0, len(byProtocol))
+	for _, group := range byProtocol {
+		slices.Sort(group.Ports)
+		group.Ports = slices.Compact(group.Ports)
+		groups = append(groups, *group)
+	}
+	slices.SortFunc(groups, func(a, b protocolGroup) int {
+		if c := cmp.Compare(b.Up+b.Down, a.Up+a.Down); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Protocol, b.Protocol)
+	})
+	return groups
+}
+
+// buildProtocolStatus implements /status protocol: inbounds summed up per
+// protocol instead of listed one by one, for panels with many inbounds.
+func (t *Tgbot) buildProtocolStatus() string {
+	inbounds, err := t.inboundService.GetAllInbounds()
+	if err != nil {
+		logger.Warning("GetAllInbounds failed:", err)
+		return t.I18nBot("tgbot.answers.getInboundsFailed")
+	}
+	listed := reportInbounds(inbounds, t.reportDisabledInbounds())
+	if len(listed) == 0 {
+		return t.I18nBot("tgbot.messages.protocolStatusEmpty")
+	}
+
+	var output strings.Builder
+	var up, down int64
+	output.WriteString(t.I18nBot("tgbot.messages.protocolStatusHeader"))
+	for _, group := range groupInboundsByProtocol(listed) {
+		ports := make([]string, len(group.Ports))
+		for i, port := range group.Ports {
+			ports[i] = strconv.Itoa(port)
+		}
+		output.WriteString(t.I18nBot("tgbot.messages.protocolStatusGroup",
+			"Protocol=="+escapeField(group.Protocol),
+			"Count=="+strconv.Itoa(group.Inbounds),
+			"Enabled=="+strconv.Itoa(group.Enabled),
+			"Total=="+formatTraffic(group.Up+group.Down),
+			"Upload=="+formatTraffic(group.Up),
+			"Download=="+formatTraffic(group.Down),
+			"Ports=="+escapeField(strings.Join(ports, ", "))))
+		up += group.Up
+		down += group.Down
+	}
+	output.WriteString(t.I18nBot("tgbot.messages.protocolStatusTotal",
+		"Count=="+strconv.Itoa(len(listed)),
+		"Total=="+formatTraffic(up+down)))
+	return t.withTrafficFreshness(output.String())
+}
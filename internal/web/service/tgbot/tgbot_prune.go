@@ -0,0 +1,54 @@
+package tgbot
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/zixu5u/3xv/v3/internal/logger"
+	"github.com/zixu5u/3xv/v3/internal/web/service"
+)
+
+// maxPruneDays matches the upper bound of the history retention settings.
+const maxPruneDays = 365
+
+// parsePruneDays reads the optional retention of /prunelogs. It returns 0
+// when none is given, meaning the configured retention of each data type.
+func parsePruneDays(args []string) (int, error) {
+	if len(args) == 0 {
+		return 0, nil
+	}
+	if len(args) > 1 {
+		return 0, errors.New("too many arguments")
+	}
+	days, err := strconv.Atoi(strings.TrimSuffix(strings.ToLower(args[0]), "d"))
+	if err != nil || days < 1 || days > maxPruneDays {
+		return 0, errors.New("invalid retention")
+	}
+	return days, nil
+}
+
+// pruneHistory implements /prunelogs: it removes online samples, traffic
+// snapshots and client usage samples older than days, or than their
+// configured retention when days is 0, and reports how many rows went.
+func (t *Tgbot) pruneHistory(chatId int64, days int, requestedBy int64) {
+	var result service.HistoryPruneResult
+	var err error
+	if days > 0 {
+		result, err = t.historyRetention.Prune(days, days, days, time.Now())
+	} else {
+		result, err = t.historyRetention.PruneConfigured(time.Now())
+	}
+	if err != nil {
+		logger.Warning("Failed to prune history:", err)
+		t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.wentWrong"))
+		return
+	}
+	logger.Infof("History pruned by Telegram user %d: %d online samples, %d traffic snapshots, %d client usage samples",
+		requestedBy, result.OnlineSamples, result.TrafficSnapshots, result.ClientUsageSamples)
+	t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.messages.pruneLogsDone",
+		"Online=="+strconv.FormatInt(result.OnlineSamples, 10),
+		"Traffic=="+strconv.FormatInt(result.TrafficSnapshots, 10),
+		"Usage=="+strconv.FormatInt(result.ClientUsageSamples, 10)))
+}
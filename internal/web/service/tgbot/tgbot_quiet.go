@@ -0,0 +1,207 @@
+package tgbot
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/zixu5u/3xv/v3/internal/logger"
+
+	"github.com/mymmrac/telego"
+)
+
+// criticalNotifications are delivered immediately even during quiet hours.
+// Every other category is informational and held until the window ends.
+var criticalNotifications = map[string]bool{
+	NotifyXray:     true,
+	NotifySettings: true,
+	NotifyReminder: true,
+}
+
+// maxQuietQueue caps how many notifications are held during quiet hours;
+// older ones are dropped and only counted.
+const maxQuietQueue = 50
+
+// quietWindow is a daily quiet-hours window in minutes since midnight.
+// It may wrap past midnight, e.g. 23:00-07:00.
+type quietWindow struct {
+	start int
+	end   int
+}
+
+// parseQuietWindow parses the tgQuietStart and tgQuietEnd settings. ok is
+// false when either is empty or invalid, or both are equal, which disables
+// quiet hours.
+func parseQuietWindow(start, end string) (w quietWindow, ok bool) {
+	s, err := time.Parse("15:04", strings.TrimSpace(start))
+	if err != nil {
+		return quietWindow{}, false
+	}
+	e, err := time.Parse("15:04", strings.TrimSpace(end))
+	if err != nil {
+		return quietWindow{}, false
+	}
+	w = quietWindow{start: s.Hour()*60 + s.Minute(), end: e.Hour()*60 + e.Minute()}
+	return w, w.start != w.end
+}
+
+// contains reports whether now falls inside the window.
+func (w quietWindow) contains(now time.Time) bool {
+	m := now.Hour()*60 + now.Minute()
+	if w.start < w.end {
+		return m >= w.start && m < w.end
+	}
+	return m >= w.start || m < w.end
+}
+
+// nextEnd returns the first end of the window after now.
+func (w quietWindow) nextEnd(now time.Time) time.Time {
+	midnight := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	end := midnight.Add(time.Duration(w.end) * time.Minute)
+	if !end.After(now) {
+		end = end.AddDate(0, 0, 1)
+	}
+	return end
+}
+
+// queuedNotification is a notification held back by quiet hours.
+type queuedNotification struct {
+	category    string
+	msg         string
+	replyMarkup []telego.ReplyMarkup
+}
+
+// quietQueue holds notifications and a deferred report until the current
+// quiet-hours window ends.
+var quietQueue struct {
+	sync.Mutex
+	messages []queuedNotification
+	report   bool
+	dropped  int
+	timer    *time.Timer
+}
+
+// quietUntil returns the end of the quiet-hours window now is in, or the
+// zero time when quiet hours are disabled or not active.
+func (t *Tgbot) quietUntil() time.Time {
+	start, err := t.settingService.GetTgQuietStart()
+	if err != nil {
+		t.settingFallback("tgQuietStart", err, "")
+		return time.Time{}
+	}
+	end, err := t.settingService.GetTgQuietEnd()
+	if err != nil {
+		t.settingFallback("tgQuietEnd", err, "")
+		return time.Time{}
+	}
+	w, ok := parseQuietWindow(start, end)
+	if !ok {
+		return time.Time{}
+	}
+	loc, err := t.settingService.GetTimeLocation()
+	if err != nil {
+		loc = time.Local
+	}
+	now := time.Now().In(loc)
+	if !w.contains(now) {
+		return time.Time{}
+	}
+	return w.nextEnd(now)
+}
+
+// holdIfQuiet queues n when quiet hours are active and n isn't critical.
+// It reports whether n was held.
+func (t *Tgbot) holdIfQuiet(n queuedNotification) bool {
+	if criticalNotifications[n.category] {
+		return false
+	}
+	until := t.quietUntil()
+	if until.IsZero() {
+		return false
+	}
+	quietQueue.Lock()
+	defer quietQueue.Unlock()
+	if len(quietQueue.messages) >= maxQuietQueue {
+		quietQueue.messages = quietQueue.messages[1:]
+		quietQueue.dropped++
+	}
+	quietQueue.messages = append(quietQueue.messages, n)
+	t.scheduleQuietFlushLocked(until)
+	return true
+}
+
+// holdReportIfQuiet defers the scheduled report to the end of quiet hours.
+// The report is built when it is finally sent, so it is never stale.
+func (t *Tgbot) holdReportIfQuiet() bool {
+	until := t.quietUntil()
+	if until.IsZero() {
+		return false
+	}
+	quietQueue.Lock()
+	defer quietQueue.Unlock()
+	quietQueue.report = true
+	t.scheduleQuietFlushLocked(until)
+	logger.Infof("Scheduled report deferred until quiet hours end at %s", until.Format("15:04"))
+	return true
+}
+
+// scheduleQuietFlushLocked arms the flush timer for until unless one is
+// already pending. quietQueue must be locked.
+func (t *Tgbot) scheduleQuietFlushLocked(until time.Time) {
+	if quietQueue.timer != nil {
+		return
+	}
+	quietQueue.timer = time.AfterFunc(time.Until(until), t.flushQuietQueue)
+}
+
+// flushQuietQueue delivers everything held during quiet hours. If the
+// window was moved and is still active, it waits for the new end instead.
+func (t *Tgbot) flushQuietQueue() {
+	quietQueue.Lock()
+	quietQueue.timer = nil
+	if until := t.quietUntil(); !until.IsZero() {
+		t.scheduleQuietFlushLocked(until)
+		quietQueue.Unlock()
+		return
+	}
+	messages := quietQueue.messages
+	report := quietQueue.report
+	dropped := quietQueue.dropped
+	quietQueue.messages = nil
+	quietQueue.report = false
+	quietQueue.dropped = 0
+	quietQueue.Unlock()
+
+	if !t.IsRunning() {
+		return
+	}
+	if len(messages) > 0 {
+		logger.Infof("Quiet hours ended, delivering %d held notifications (%d dropped)", len(messages), dropped)
+		t.SendMsgToTgbotAdmins(t.I18nBot("tgbot.messages.quietHoursEnded",
+			"Count=="+strconv.Itoa(len(messages)+dropped),
+			"Dropped=="+strconv.Itoa(dropped)))
+		for _, n := range messages {
+			t.deliverNotification(n.category, n.msg, n.replyMarkup...)
+		}
+	}
+	if report {
+		t.SendReport()
+	}
+}
+
+// stopQuietQueue cancels a pending flush and discards what was held.
+func stopQuietQueue() {
+	quietQueue.Lock()
+	defer quietQueue.Unlock()
+	if quietQueue.timer != nil {
+		quietQueue.timer.Stop()
+		quietQueue.timer = nil
+	}
+	if n := len(quietQueue.messages); n > 0 {
+		logger.Infof("Discarding %d notifications held for quiet hours", n)
+	}
+	quietQueue.messages = nil
+	quietQueue.report = false
+	quietQueue.dropped = 0
+}
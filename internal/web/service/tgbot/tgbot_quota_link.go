@@ -0,0 +1,164 @@
+package tgbot
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"html"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/zixu5u/3xv/v3/internal/logger"
+)
+
+// A quota link is https://t.me/<bot>?start=<token>, which shows one client
+// its own traffic and expiry. The token is quotaLinkPrefix, the client
+// traffic ID and the expiry time in base 36 seconds, separated by a dash,
+// followed by quotaLinkMacLen characters of HMAC over them and the email.
+// Renaming the client or changing the panel secret voids its links.
+const (
+	quotaLinkPrefix      = "q"
+	quotaLinkMacLen      = 16
+	defaultQuotaLinkDays = 30
+	maxQuotaLinkDays     = 365
+)
+
+// quotaLinkKey is the key quota links are signed with. It is loaded by
+// Start.
+var quotaLinkKey atomic.Pointer[[]byte]
+
+// loadQuotaLinkSigning derives the quota link key from the panel secret, so
+// links stay valid across a restart.
+func (t *Tgbot) loadQuotaLinkSigning() {
+	secret, err := t.settingService.GetSecret()
+	if err != nil || len(secret) == 0 {
+		logger.Warning("Failed to get panel secret, quota links won't survive a restart:", err)
+		secret = make([]byte, 32)
+		rand.Read(secret)
+	}
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte("tgbot-quota-link"))
+	key := mac.Sum(nil)
+	quotaLinkKey.Store(&key)
+}
+
+// quotaLinkMac is the token MAC over the signed part of a token and the
+// email of its client.
+func quotaLinkMac(key []byte, signed string, email string) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(signed + "|" + email))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))[:quotaLinkMacLen]
+}
+
+// signQuotaLink returns the token of a quota link to the client traffic id
+// of email, valid until expires.
+func signQuotaLink(key []byte, id int, email string, expires time.Time) string {
+	signed := quotaLinkPrefix + strconv.FormatInt(int64(id), 36) + "-" + strconv.FormatInt(expires.Unix(), 36)
+	return signed + quotaLinkMac(key, signed, email)
+}
+
+// parseQuotaLink returns the client traffic ID a token points to. ok is
+// false when payload isn't shaped like a quota link token; the token still
+// has to pass checkQuotaLink.
+func parseQuotaLink(payload string) (id int, ok bool) {
+	if !strings.HasPrefix(payload, quotaLinkPrefix) || len(payload) <= len(quotaLinkPrefix)+quotaLinkMacLen {
+		return 0, false
+	}
+	signed := payload[:len(payload)-quotaLinkMacLen]
+	idPart, expiresPart, found := strings.Cut(strings.TrimPrefix(signed, quotaLinkPrefix), "-")
+	if !found {
+		return 0, false
+	}
+	if _, err := strconv.ParseInt(expiresPart, 36, 64); err != nil {
+		return 0, false
+	}
+	n, err := strconv.ParseInt(idPart, 36, 32)
+	if err != nil || n <= 0 {
+		return 0, false
+	}
+	return int(n), true
+}
+
+// checkQuotaLink reports whether payload was signed with key for email and
+// hasn't expired at now.
+func checkQuotaLink(key []byte, payload string, email string, now time.Time) bool {
+	if _, ok := parseQuotaLink(payload); !ok {
+		return false
+	}
+	signed, mac := payload[:len(payload)-quotaLinkMacLen], payload[len(payload)-quotaLinkMacLen:]
+	if !hmac.Equal([]byte(mac), []byte(quotaLinkMac(key, signed, email))) {
+		return false
+	}
+	_, expiresPart, _ := strings.Cut(signed, "-")
+	expires, _ := strconv.ParseInt(expiresPart, 36, 64)
+	return now.Before(time.Unix(expires, 0))
+}
+
+// parseQuotaLinkArgs reads "/quotalink <email> [days]".
+func parseQuotaLinkArgs(args []string) (email string, days int, ok bool) {
+	switch len(args) {
+	case 1:
+		return args[0], defaultQuotaLinkDays, true
+	case 2:
+		days, err := strconv.Atoi(args[1])
+		if err != nil || days < 1 || days > maxQuotaLinkDays {
+			return "", 0, false
+		}
+		return args[0], days, true
+	}
+	return "", 0, false
+}
+
+// sendQuotaLink implements /quotalink: a personal link for the client email
+// to check its own quota, valid for days.
+func (t *Tgbot) sendQuotaLink(chatId int64, email string, days int, requestedBy int64) {
+	key, username := quotaLinkKey.Load(), botUsername()
+	if key == nil || username == "" {
+		t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.wentWrong"))
+		return
+	}
+	traffic, err := t.inboundService.GetClientTrafficByEmail(email)
+	if err != nil {
+		logger.Warning(err)
+		t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.wentWrong"))
+		return
+	}
+	if traffic == nil {
+		t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.noResult"))
+		return
+	}
+	expires := time.Now().AddDate(0, 0, days)
+	link := "https://t.me/" + username + "?start=" + signQuotaLink(*key, traffic.Id, traffic.Email, expires)
+	logger.Infof("Quota link for %s created by Telegram user %d, valid for %d days", traffic.Email, requestedBy, days)
+	t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.messages.quotaLinkCreated",
+		"Email=="+escapeField(traffic.Email),
+		"Link=="+html.EscapeString(link),
+		"Expires=="+expires.Format("2006-01-02 15:04")))
+}
+
+// sendQuotaSelfCheck answers /start with a quota link token: the traffic and
+// expiry of the client it was made for, and nothing else.
+func (t *Tgbot) sendQuotaSelfCheck(chatId int64, payload string, requestedBy int64) {
+	key := quotaLinkKey.Load()
+	id, ok := parseQuotaLink(payload)
+	if key == nil || !ok {
+		t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.messages.quotaLinkInvalid"))
+		return
+	}
+	traffic, err := t.inboundService.GetClientTrafficById(id)
+	if err != nil {
+		logger.Warning(err)
+		t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.wentWrong"))
+		return
+	}
+	if traffic == nil || !checkQuotaLink(*key, payload, traffic.Email, time.Now()) {
+		logBotEvent(botEvent{Event: "quota_link", ChatID: requestedBy, Command: "start", Outcome: "invalid"})
+		t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.messages.quotaLinkInvalid"))
+		return
+	}
+	logBotEvent(botEvent{Event: "quota_link", ChatID: requestedBy, Command: "start"})
+	t.SendMsgToTgbot(chatId, t.clientInfoMsg(traffic, true, false, true, true, true, true))
+}
@@ -0,0 +1,240 @@
+package tgbot
+
+import (
+	"errors"
+	"maps"
+	"net/url"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/zixu5u/3xv/v3/internal/logger"
+)
+
+// botTokenPattern is the shape of a token issued by BotFather.
+var botTokenPattern = regexp.MustCompile(`^[0-9]+:[A-Za-z0-9_-]{30,}$`)
+
+// botSettingValidators check the settings the bot depends on that
+// /setsetting can't change. The settableSettings are checked with their
+// normalize.
+var botSettingValidators = map[string]func(value string) error{
+	"tgBotToken":     validateBotToken,
+	"tgBotChatId":    validateChatIds,
+	"tgBotProxy":     validateBotProxy,
+	"tgBotAPIServer": validateAPIServer,
+	"timeLocation": func(value string) error {
+		_, err := time.LoadLocation(value)
+		return err
+	},
+	"trafficDiff": nonNegative,
+	"expireDiff":  nonNegative,
+}
+
+func validateBotToken(value string) error {
+	switch {
+	case value == "":
+		return errors.New("not set")
+	case !botTokenPattern.MatchString(value):
+		return errors.New("not a bot token")
+	}
+	return nil
+}
+
+// validateChatIds rejects a list without a valid chat ID, which would leave
+// the bot without admins. Invalid entries are skipped with a warning, as
+// Start does.
+func validateChatIds(value string) error {
+	ids, invalid := parseAdminIds(value)
+	if len(invalid) > 0 {
+		logger.Warningf("Ignoring invalid Telegram chat IDs in settings: %s", strings.Join(invalid, ", "))
+	}
+	if len(ids) == 0 {
+		return errors.New("no valid chat ID set")
+	}
+	return nil
+}
+
+func validateBotProxy(value string) error {
+	if value == "" {
+		return nil
+	}
+	if !isSupportedBotProxyScheme(value) {
+		return errors.New("expected a socks5, http or https URL")
+	}
+	if u, err := url.Parse(value); err != nil || u.Host == "" {
+		return errors.New("not a valid URL")
+	}
+	return nil
+}
+
+func validateAPIServer(value string) error {
+	if value == "" {
+		return nil
+	}
+	u, err := url.Parse(value)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return errors.New("expected an http or https URL")
+	}
+	return nil
+}
+
+func nonNegative(value string) error {
+	n, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil || n < 0 {
+		return errors.New("expected a whole number of at least 0")
+	}
+	return nil
+}
+
+// reloadedSettingKeys lists every setting /reloadsettings validates, sorted.
+func reloadedSettingKeys() []string {
+	keys := slices.Collect(maps.Keys(botSettingValidators))
+	keys = append(keys, settableSettingKeys()...)
+	slices.Sort(keys)
+	return slices.Compact(keys)
+}
+
+// settingProblems validates values, keyed by setting, and returns the
+// invalid ones as "name: problem" lines, sorted by name.
+func settingProblems(values map[string]string) []string {
+	var problems []string
+	for _, key := range slices.Sorted(maps.Keys(values)) {
+		var err error
+		if validate, ok := botSettingValidators[key]; ok {
+			err = validate(values[key])
+		} else if setting, ok := settableSettings[key]; ok {
+			_, err = setting.normalize(values[key])
+		}
+		if err != nil {
+			problems = append(problems, key+": "+err.Error())
+		}
+	}
+	return problems
+}
+
+// validateSettings reads the stored bot settings and returns the unreadable
+// or invalid ones as "name: problem" lines.
+func (t *Tgbot) validateSettings() []string {
+	values := make(map[string]string)
+	var problems []string
+	for _, key := range reloadedSettingKeys() {
+		value, err := t.settingService.GetSettingValue(key)
+		if err != nil {
+			problems = append(problems, key+": "+err.Error())
+			continue
+		}
+		values[key] = value
+	}
+	return append(problems, settingProblems(values)...)
+}
+
+// loadRuntimeSettings reads the settings the bot keeps in memory for
+// formatting and routing messages.
+func (t *Tgbot) loadRuntimeSettings() {
+	jsonLog, err := t.settingService.GetTgBotJsonLog()
+	if err != nil {
+		logger.Warning("Failed to get Telegram bot JSON log setting:", err)
+	}
+	jsonEventLog.Store(jsonLog)
+
+	format, err := t.settingService.GetTgTrafficFormat()
+	if err != nil {
+		logger.Warning("Failed to get Telegram bot traffic format setting:", err)
+	}
+	trafficFormat.Store(&format)
+
+	numbers, err := t.settingService.GetTgNumberFormat()
+	if err != nil {
+		logger.Warning("Failed to get Telegram bot number format setting:", err)
+	}
+	numberFormat.Store(&numbers)
+
+	emojiOn, err := t.settingService.GetTgSeverityEmoji()
+	if err != nil {
+		logger.Warning("Failed to get Telegram bot severity emoji setting:", err)
+		emojiOn = true
+	}
+	severityEmojiOff.Store(!emojiOn)
+
+	t.loadCallbackSigning()
+	t.loadQuotaLinkSigning()
+	t.loadCommandAliases()
+	t.loadSilentCategories()
+}
+
+// Reload applies the stored bot settings without restarting the update
+// loop: the in-memory settings, the admin chats, the report schedule and
+// the command menu. The token, proxy and API server need a new connection
+// to Telegram, so they keep their values until the panel restarts; Reload
+// returns the names of those that changed since.
+func (t *Tgbot) Reload() (pending []string) {
+	t.loadRuntimeSettings()
+
+	ids := adminChatIds()
+	if raw, err := t.settingService.GetTgBotChatId(); err != nil {
+		logger.Errorf("[tgbot] setting tgBotChatId unreadable, keeping %d known admin chats: %v", len(ids), err)
+	} else {
+		var invalid []string
+		ids, invalid = parseAdminIds(raw)
+		if len(invalid) > 0 {
+			logger.Warningf("Ignoring invalid Telegram chat IDs in settings: %s", strings.Join(invalid, ", "))
+		}
+		tgBotMutex.Lock()
+		adminIds = ids
+		tgBotMutex.Unlock()
+	}
+
+	runTime, err := t.settingService.GetTgbotRuntime()
+	if err != nil {
+		runTime = t.settingFallback("tgRunTime", err, defaultReportRunTime)
+	}
+	if err := t.ReloadReportSchedule(runTime); err != nil {
+		logger.Warning("Failed to reload the report schedule:", err)
+	}
+
+	if bot != nil {
+		t.trySetBotCommands(bot)
+	}
+
+	cfg := effectiveConfig.Load()
+	if cfg == nil {
+		return nil
+	}
+	if token, err := t.settingService.GetTgBotToken(); err == nil && token != cfg.token {
+		pending = append(pending, "tgBotToken")
+	}
+	if proxy, err := t.settingService.GetTgBotProxy(); err == nil && (cfg.proxyFromEgress && proxy != "" || !cfg.proxyFromEgress && proxy != cfg.proxy) {
+		pending = append(pending, "tgBotProxy")
+	}
+	if apiServer, err := t.settingService.GetTgBotAPIServer(); err == nil && apiServer != cfg.apiServer {
+		pending = append(pending, "tgBotAPIServer")
+	}
+	t.recordBotConfig(cfg.token, ids, runTime, cfg.proxy, cfg.proxyFromEgress, cfg.apiServer)
+	return pending
+}
+
+// reloadSettings implements /reloadsettings: it validates the stored bot
+// settings and, when they are all valid, applies them with Reload.
+func (t *Tgbot) reloadSettings(chatId int64, requestedBy int64) {
+	if problems := t.validateSettings(); len(problems) > 0 {
+		lines := make([]string, len(problems))
+		for i, problem := range problems {
+			lines[i] = "• " + escapeField(problem)
+		}
+		logBotEvent(botEvent{Event: "reload_settings", ChatID: requestedBy, Command: "reloadsettings", Outcome: "invalid"})
+		t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.messages.reloadSettingsInvalid",
+			"Count=="+strconv.Itoa(len(problems)),
+			"Problems=="+strings.Join(lines, "\r\n")))
+		return
+	}
+	pending := t.Reload()
+	logger.Infof("Telegram bot settings reloaded by Telegram user %d", requestedBy)
+	logBotEvent(botEvent{Event: "reload_settings", ChatID: requestedBy, Command: "reloadsettings"})
+	msg := t.I18nBot("tgbot.messages.reloadSettingsDone")
+	if len(pending) > 0 {
+		msg += "\r\n" + t.I18nBot("tgbot.messages.reloadSettingsRestart", "Names=="+strings.Join(pending, ", "))
+	}
+	t.SendMsgToTgbot(chatId, msg)
+}
@@ -0,0 +1,108 @@
+package tgbot
+
+import (
+	"html"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/zixu5u/3xv/v3/internal/logger"
+	"github.com/zixu5u/3xv/v3/internal/web/service"
+)
+
+// cutWord splits s after its first whitespace-separated word.
+func cutWord(s string) (string, string) {
+	s = strings.TrimLeftFunc(s, unicode.IsSpace)
+	if i := strings.IndexFunc(s, unicode.IsSpace); i >= 0 {
+		return s[:i], s[i:]
+	}
+	return s, ""
+}
+
+// parseRemindArgs splits "/remind 2024-06-01 09:00 Renew the TLS cert" into
+// the time and the text. The text is cut from the raw message, so its line
+// breaks survive.
+func parseRemindArgs(text string) (string, string, bool) {
+	_, rest := cutWord(text)
+	date, rest := cutWord(rest)
+	clock, rest := cutWord(rest)
+	rest = strings.TrimSpace(rest)
+	if date == "" || clock == "" || rest == "" {
+		return "", "", false
+	}
+	return date + " " + clock, rest, true
+}
+
+// addReminder implements /remind: the text is sent once to the admins at the
+// given time, read in the panel's time zone.
+func (t *Tgbot) addReminder(chatId int64, text string, requestedBy int64) {
+	when, body, ok := parseRemindArgs(text)
+	if !ok {
+		t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.messages.remindUsage"))
+		return
+	}
+	loc := service.PanelCron().Location()
+	at, err := service.ParseReminderTime(when, loc)
+	if err != nil {
+		t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.messages.remindUsage"))
+		return
+	}
+	reminder, err := t.reminders.AddReminder(at, body, strconv.FormatInt(requestedBy, 10), time.Now())
+	if err != nil {
+		logBotEvent(botEvent{Event: "reminder_add", ChatID: requestedBy, Command: "remind", Err: err})
+		t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.messages.remindFailed", "Error=="+html.EscapeString(err.Error())))
+		return
+	}
+	logger.Infof("Reminder %d for %s added by Telegram user %d", reminder.Id, when, requestedBy)
+	logBotEvent(botEvent{Event: "reminder_add", ChatID: requestedBy, Command: "remind"})
+	t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.messages.remindSuccess",
+		"Id=="+strconv.Itoa(reminder.Id),
+		"Time=="+at.Format(service.ReminderTimeLayout),
+		"Zone=="+escapeField(loc.String())))
+}
+
+// sendReminders implements /reminders.
+func (t *Tgbot) sendReminders(chatId int64) {
+	reminders, err := t.reminders.GetReminders()
+	if err != nil {
+		t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.messages.remindFailed", "Error=="+html.EscapeString(err.Error())))
+		return
+	}
+	if len(reminders) == 0 {
+		t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.messages.remindersEmpty"))
+		return
+	}
+	loc := service.PanelCron().Location()
+	var output strings.Builder
+	output.WriteString(t.I18nBot("tgbot.messages.remindersHeader", "Zone=="+escapeField(loc.String())))
+	for _, reminder := range reminders {
+		output.WriteString(t.I18nBot("tgbot.messages.remindersItem",
+			"Id=="+strconv.Itoa(reminder.Id),
+			"Time=="+time.UnixMilli(reminder.FireAt).In(loc).Format(service.ReminderTimeLayout),
+			"Text=="+escapeField(reminder.Text)))
+	}
+	t.SendMsgToTgbot(chatId, output.String())
+}
+
+// cancelReminder implements /cancelreminder.
+func (t *Tgbot) cancelReminder(chatId int64, arg string, requestedBy int64) {
+	id, err := strconv.Atoi(arg)
+	if err != nil || id <= 0 {
+		t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.messages.cancelReminderUsage"))
+		return
+	}
+	removed, err := t.reminders.CancelReminder(id)
+	if err != nil {
+		logBotEvent(botEvent{Event: "reminder_cancel", ChatID: requestedBy, Command: "cancelreminder", Err: err})
+		t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.messages.remindFailed", "Error=="+html.EscapeString(err.Error())))
+		return
+	}
+	if !removed {
+		t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.messages.reminderNotFound", "Id=="+arg))
+		return
+	}
+	logger.Infof("Reminder %d cancelled by Telegram user %d", id, requestedBy)
+	logBotEvent(botEvent{Event: "reminder_cancel", ChatID: requestedBy, Command: "cancelreminder"})
+	t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.messages.reminderCancelled", "Id=="+arg))
+}
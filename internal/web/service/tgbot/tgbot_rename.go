@@ -0,0 +1,156 @@
+package tgbot
+
+import (
+	"html"
+	"strconv"
+	"strings"
+
+	"github.com/zixu5u/3xv/v3/internal/logger"
+	"github.com/zixu5u/3xv/v3/internal/web/service"
+
+	tu "github.com/mymmrac/telego/telegoutil"
+)
+
+// Fields /rename can change.
+const (
+	renameFieldTag    = "tag"
+	renameFieldRemark = "remark"
+)
+
+// inboundRename is a rename waiting for the admin's confirmation.
+type inboundRename struct {
+	inboundId int
+	field     string
+	oldValue  string
+	newValue  string
+}
+
+var inboundRenames = make(map[int64]*inboundRename)
+
+// parseRenameArgs reads "/rename [Tag] tag [NewTag]" and
+// "/rename [Tag] remark [NewRemark]"; a remark may contain spaces.
+func parseRenameArgs(args []string) (tag string, field string, value string, ok bool) {
+	if len(args) < 3 {
+		return "", "", "", false
+	}
+	field = strings.ToLower(args[1])
+	if field != renameFieldTag && field != renameFieldRemark {
+		return "", "", "", false
+	}
+	if field == renameFieldTag && len(args) != 3 {
+		return "", "", "", false
+	}
+	return args[0], field, strings.Join(args[2:], " "), true
+}
+
+// promptRename implements /rename: it shows the old and new value and asks
+// for confirmation before anything is changed.
+func (t *Tgbot) promptRename(chatId int64, tag string, field string, value string) {
+	inbound, err := t.inboundService.GetInboundByTag(tag)
+	if err != nil {
+		t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.messages.muteNoInbound", "Tag=="+escapeField(tag)))
+		return
+	}
+	rename := &inboundRename{inboundId: inbound.Id, field: field, oldValue: inbound.Tag, newValue: value}
+	confirmKey := "tgbot.messages.renameTagConfirm"
+	if field == renameFieldRemark {
+		rename.oldValue = inbound.Remark
+		confirmKey = "tgbot.messages.renameRemarkConfirm"
+	}
+	inboundRenames[chatId] = rename
+
+	id := strconv.Itoa(inbound.Id)
+	inlineKeyboard := tu.InlineKeyboard(
+		tu.InlineKeyboardRow(
+			tu.InlineKeyboardButton(t.I18nBot("tgbot.buttons.confirmRename")).WithCallbackData(t.encodeQuery("inbound_rename "+id)),
+			tu.InlineKeyboardButton(t.I18nBot("tgbot.buttons.cancel")).WithCallbackData(t.encodeQuery("inbound_rename_cancel "+id)),
+		),
+	)
+	t.SendMsgToTgbot(chatId, t.I18nBot(confirmKey,
+		"Tag=="+escapeField(inbound.Tag),
+		"Old=="+escapeField(rename.oldValue),
+		"New=="+escapeField(rename.newValue)), inlineKeyboard)
+}
+
+// applyRename carries out a confirmed /rename.
+func (t *Tgbot) applyRename(chatId int64, inboundId int, requestedBy int64) {
+	rename := inboundRenames[chatId]
+	if rename == nil || rename.inboundId != inboundId {
+		t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.answers.errorOperation"))
+		return
+	}
+	delete(inboundRenames, chatId)
+
+	if rename.field == renameFieldRemark {
+		old, err := t.inboundService.SetInboundRemark(inboundId, rename.newValue)
+		if err != nil {
+			logger.Warningf("Renaming inbound %d requested by %d failed: %v", inboundId, requestedBy, err)
+			t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.messages.renameFailed", "Error=="+html.EscapeString(err.Error())))
+			return
+		}
+		logger.Infof("Remark of inbound %d changed from %q to %q by Telegram user %d", inboundId, old, rename.newValue, requestedBy)
+		logBotEvent(botEvent{Event: "inbound_rename", ChatID: requestedBy, Command: "rename", Category: renameFieldRemark})
+		t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.messages.renameRemarkDone",
+			"Old=="+escapeField(old),
+			"New=="+escapeField(strings.TrimSpace(rename.newValue))))
+		return
+	}
+	t.renameInboundTag(chatId, inboundId, rename.newValue, requestedBy)
+}
+
+// renameInboundTag renames the tag and restarts Xray to use it. Xray counts
+// traffic by tag, so what it counted so far is collected first; otherwise
+// the counters left under the old tag would match no inbound.
+func (t *Tgbot) renameInboundTag(chatId int64, inboundId int, newTag string, requestedBy int64) {
+	running := t.xrayService.IsXrayRunning()
+	if running {
+		if _, _, err := t.reconcileTraffic(); err != nil {
+			logger.Warningf("Collecting traffic before renaming inbound %d failed: %v", inboundId, err)
+			t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.messages.renameFailed", "Error=="+html.EscapeString(err.Error())))
+			return
+		}
+	}
+	rename, err := t.inboundService.RenameInboundTag(inboundId, newTag)
+	if err != nil {
+		logger.Warningf("Renaming inbound %d requested by %d failed: %v", inboundId, requestedBy, err)
+		logBotEvent(botEvent{Event: "inbound_rename", ChatID: requestedBy, Command: "rename", Category: renameFieldTag, Err: err})
+		t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.messages.renameFailed", "Error=="+html.EscapeString(err.Error())))
+		return
+	}
+	logger.Infof("Inbound %d renamed from %s to %s by Telegram user %d", inboundId, rename.OldTag, rename.NewTag, requestedBy)
+	logBotEvent(botEvent{Event: "inbound_rename", ChatID: requestedBy, Command: "rename", Category: renameFieldTag})
+
+	warnings := rename.Warnings
+	if running {
+		if err := t.xrayService.RestartXray(true); err != nil {
+			warnings = append(warnings, "xray restart: "+err.Error())
+		}
+	} else {
+		t.xrayService.SetToNeedRestart()
+	}
+	t.SendMsgToTgbot(chatId, t.renameTagDoneMsg(rename, warnings))
+}
+
+func (t *Tgbot) renameTagDoneMsg(rename *service.InboundRename, warnings []string) string {
+	settings := "-"
+	if len(rename.Settings) > 0 {
+		settings = strings.Join(rename.Settings, ", ")
+	}
+	msg := t.I18nBot("tgbot.messages.renameTagDone",
+		"Old=="+escapeField(rename.OldTag),
+		"New=="+escapeField(rename.NewTag),
+		"Rules=="+strconv.Itoa(rename.RoutingRules),
+		"Nodes=="+strconv.Itoa(rename.Nodes),
+		"Settings=="+escapeField(settings))
+	if len(warnings) > 0 {
+		msg += "\r\n\r\n" + t.I18nBot("tgbot.messages.renameWarnings",
+			"Warnings=="+html.EscapeString(strings.Join(warnings, "\n")))
+	}
+	return msg
+}
+
+// cancelRename drops a rename waiting for confirmation.
+func (t *Tgbot) cancelRename(chatId int64) {
+	delete(inboundRenames, chatId)
+	t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.messages.renameCanceled"))
+}
@@ -2,18 +2,19 @@ package tgbot
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net"
 	"os"
 	"strconv"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/zixu5u/3xv/v3/internal/config"
 	"github.com/zixu5u/3xv/v3/internal/database"
 	"github.com/zixu5u/3xv/v3/internal/database/model"
 	"github.com/zixu5u/3xv/v3/internal/logger"
-	"github.com/zixu5u/3xv/v3/internal/util/common"
 	"github.com/zixu5u/3xv/v3/internal/web/service"
 	"github.com/zixu5u/3xv/v3/internal/xray"
 
@@ -23,23 +24,103 @@ import (
 
 // SendReport sends a periodic report to admin chats.
 func (t *Tgbot) SendReport() {
+	if t.holdReportIfQuiet() {
+		return
+	}
+	if t.hasNoInbounds() {
+		t.noteNoInbounds()
+	} else {
+		noInboundsNoted.Store(false)
+		msg, info := t.buildReport(t.reportRunTime())
+		lastReportBuilt.Store(time.Now().UnixMilli())
+		t.deliverReport(msg, info)
+		t.sendExhaustedToAdmins()
+		t.notifyExhausted()
+	}
+
+	backupEnable, err := t.settingService.GetTgBotBackup()
+	if err != nil {
+		t.settingFallback("tgBotBackup", err, "false")
+	}
+	if err == nil && backupEnable {
+		t.SendBackupToAdmins()
+	}
+}
+
+// reportRunTime returns the tgRunTime schedule shown in the report header.
+func (t *Tgbot) reportRunTime() string {
 	runTime, err := t.settingService.GetTgbotRuntime()
-	if err == nil && len(runTime) > 0 {
-		msg := ""
-		msg += t.I18nBot("tgbot.messages.report", "RunTime=="+runTime)
-		msg += t.I18nBot("tgbot.messages.datetime", "DateTime=="+time.Now().Format("2006-01-02 15:04:05"))
-		t.SendMsgToTgbotAdmins(msg)
+	if err != nil {
+		runTime = t.settingFallback("tgRunTime", err, defaultReportRunTime)
 	}
+	return runTime
+}
 
-	info := t.buildRichStatus()
-    t.SendMsgToTgbotAdmins(info)
+// deliverReport sends a built report to every admin and the channel. The
+// status goes out as a document when it is over tgReportFileThreshold.
+func (t *Tgbot) deliverReport(msg string, status string) {
+	t.sendToAdmins(NotifyReport, msg)
+	if status != "" && !t.sendReportAsFile(status, adminChatIds()) {
+		t.sendToAdmins(NotifyReport, status)
+	}
+	if status != "" {
+		t.notifyChannel(NotifyReport, msg, status)
+	} else {
+		t.notifyChannel(NotifyReport, msg)
+	}
+}
 
+// sendReportNow implements /reportnow: the report is built and delivered to
+// every admin and the channel right away, even in quiet hours. The client
+// notifications and the backup that follow a scheduled report are left to
+// the schedule.
+func (t *Tgbot) sendReportNow(chatId int64, requestedBy int64) {
+	if t.hasNoInbounds() {
+		t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.messages.reportNoInbounds"))
+		return
+	}
+	logger.Infof("Report sent on demand by Telegram user %d", requestedBy)
+	msg, status := t.buildReport(t.reportRunTime())
+	t.deliverReport(msg, status)
 	t.sendExhaustedToAdmins()
-	t.notifyExhausted()
+}
 
-	backupEnable, err := t.settingService.GetTgBotBackup()
-	if err == nil && backupEnable {
-		t.SendBackupToAdmins()
+// sendReportPreview implements /reportpreview: the report as it would go
+// out now, sent only to chatId.
+func (t *Tgbot) sendReportPreview(chatId int64) {
+	msg, status := t.buildReport(t.reportRunTime())
+	t.SendMsgToTgbot(chatId, msg)
+	if status != "" && !t.sendReportAsFile(status, []int64{chatId}) {
+		t.SendMsgToTgbot(chatId, status)
+	}
+}
+
+// noInboundsNoted is set once the admins were told the report is skipped
+// because the panel has no inbounds, so later empty reports stay silent. It
+// is cleared by the first report sent after inbounds are added.
+var noInboundsNoted atomic.Bool
+
+// hasNoInbounds reports whether the panel has no inbounds at all. A failed
+// lookup counts as having some, so the report still goes out.
+func (t *Tgbot) hasNoInbounds() bool {
+	inbounds, err := t.inboundService.GetAllInbounds()
+	return err == nil && len(inbounds) == 0
+}
+
+// noteNoInbounds stands in for the report of a panel without inbounds: the
+// first time, and only if tgReportNoInboundsNote is on, it tells the admins
+// that there is nothing to report yet.
+func (t *Tgbot) noteNoInbounds() {
+	if noInboundsNoted.Swap(true) {
+		return
+	}
+	note, err := t.settingService.GetTgReportNoInboundsNote()
+	if err != nil {
+		t.settingFallback("tgReportNoInboundsNote", err, "true")
+		note = true
+	}
+	if note {
+		t.SendMsgToTgbotAdmins(t.I18nBot("tgbot.messages.reportNoInbounds"))
 	}
 }
 
@@ -48,13 +129,15 @@ func (t *Tgbot) SendBackupToAdmins() {
 	if !t.IsRunning() {
 		return
 	}
-	for i, adminId := range adminIds {
-		t.sendBackup(int64(adminId))
+	first := true
+	fanOut(adminChatIds(), func(adminId int64) error {
 		// Add delay between sends to avoid Telegram rate limits
-		if i < len(adminIds)-1 {
+		if !first {
 			time.Sleep(1 * time.Second)
 		}
-	}
+		first = false
+		return t.sendBackup(adminId)
+	}).logSummary("Backup")
 }
 
 // sendExhaustedToAdmins sends notifications about exhausted clients to admins.
@@ -62,8 +145,9 @@ func (t *Tgbot) sendExhaustedToAdmins() {
 	if !t.IsRunning() {
 		return
 	}
+	muted := t.mutedInboundTags()
 	for _, adminId := range adminIds {
-		t.getExhausted(int64(adminId))
+		t.getExhausted(int64(adminId), muted)
 	}
 }
 
@@ -107,7 +191,7 @@ func (t *Tgbot) prepareServerUsageInfo() string {
 	}
 	onlines := service.XrayProcess().GetOnlineClients()
 
-	info += t.I18nBot("tgbot.messages.hostname", "Hostname=="+hostname)
+	info += t.I18nBot("tgbot.messages.hostname", "Hostname=="+escapeField(hostname))
 	info += t.I18nBot("tgbot.messages.version", "Version=="+config.GetVersion())
 	info += t.I18nBot("tgbot.messages.xrayVersion", "XrayVersion=="+fmt.Sprint(t.lastStatus.Xray.Version))
 
@@ -140,11 +224,12 @@ func (t *Tgbot) prepareServerUsageInfo() string {
 
 	info += t.I18nBot("tgbot.messages.serverUpTime", "UpTime=="+strconv.FormatUint(t.lastStatus.Uptime/86400, 10), "Unit=="+t.I18nBot("tgbot.days"))
 	info += t.I18nBot("tgbot.messages.serverLoad", "Load1=="+strconv.FormatFloat(t.lastStatus.Loads[0], 'f', 2, 64), "Load2=="+strconv.FormatFloat(t.lastStatus.Loads[1], 'f', 2, 64), "Load3=="+strconv.FormatFloat(t.lastStatus.Loads[2], 'f', 2, 64))
-	info += t.I18nBot("tgbot.messages.serverMemory", "Current=="+common.FormatTraffic(int64(t.lastStatus.Mem.Current)), "Total=="+common.FormatTraffic(int64(t.lastStatus.Mem.Total)))
+	info += t.I18nBot("tgbot.messages.serverMemory", "Current=="+formatTraffic(int64(t.lastStatus.Mem.Current)), "Total=="+formatTraffic(int64(t.lastStatus.Mem.Total)))
 	info += t.I18nBot("tgbot.messages.onlinesCount", "Count=="+fmt.Sprint(len(onlines)))
+	info += t.getOnlineTrend()
 	info += t.I18nBot("tgbot.messages.tcpCount", "Count=="+strconv.Itoa(t.lastStatus.TcpCount))
 	info += t.I18nBot("tgbot.messages.udpCount", "Count=="+strconv.Itoa(t.lastStatus.UdpCount))
-	info += t.I18nBot("tgbot.messages.traffic", "Total=="+common.FormatTraffic(int64(t.lastStatus.NetTraffic.Sent+t.lastStatus.NetTraffic.Recv)), "Upload=="+common.FormatTraffic(int64(t.lastStatus.NetTraffic.Sent)), "Download=="+common.FormatTraffic(int64(t.lastStatus.NetTraffic.Recv)))
+	info += t.I18nBot("tgbot.messages.traffic", "Total=="+formatTraffic(int64(t.lastStatus.NetTraffic.Sent+t.lastStatus.NetTraffic.Recv)), "Upload=="+formatTraffic(int64(t.lastStatus.NetTraffic.Sent)), "Download=="+formatTraffic(int64(t.lastStatus.NetTraffic.Recv)))
 	info += t.I18nBot("tgbot.messages.xrayStatus", "State=="+fmt.Sprint(t.lastStatus.Xray.State))
 
 	// Cache the complete server stats
@@ -153,6 +238,25 @@ func (t *Tgbot) prepareServerUsageInfo() string {
 	return info
 }
 
+// getOnlineTrend formats today's peak and average online client count. It
+// returns "" until the first sample of the day has been recorded.
+func (t *Tgbot) getOnlineTrend() string {
+	loc, err := t.settingService.GetTimeLocation()
+	if err != nil {
+		loc = time.Local
+	}
+	stats, err := t.onlineHistory.GetDayStats(time.Now().In(loc))
+	if err != nil {
+		logger.Warning("get online client history failed:", err)
+		return ""
+	}
+	if stats.Samples == 0 {
+		return ""
+	}
+	return t.I18nBot("tgbot.messages.onlinePeak", "Count=="+strconv.Itoa(stats.Peak), "Time=="+stats.PeakAt.Format("15:04")) +
+		t.I18nBot("tgbot.messages.onlineAverage", "Average=="+strconv.FormatFloat(stats.Average, 'f', 1, 64))
+}
+
 // UserLoginNotify sends a notification about user login attempts to admins.
 func (t *Tgbot) UserLoginNotify(attempt LoginAttempt) {
 	if !t.IsRunning() {
@@ -163,9 +267,16 @@ func (t *Tgbot) UserLoginNotify(attempt LoginAttempt) {
 		logger.Warning("UserLoginNotify failed, invalid info!")
 		return
 	}
+	if attempt.Status == LoginFail {
+		recordFailedLogin(attempt.IP, time.Now())
+	}
 
 	loginNotifyEnabled, err := t.settingService.GetTgBotLoginNotify()
-	if err != nil || !loginNotifyEnabled {
+	if err != nil {
+		t.settingFallback("tgBotLoginNotify", err, "false")
+		return
+	}
+	if !loginNotifyEnabled {
 		return
 	}
 
@@ -173,54 +284,60 @@ func (t *Tgbot) UserLoginNotify(attempt LoginAttempt) {
 	switch attempt.Status {
 	case LoginSuccess:
 		msg += t.I18nBot("tgbot.messages.loginSuccess")
-		msg += t.I18nBot("tgbot.messages.hostname", "Hostname=="+hostname)
+		msg += t.I18nBot("tgbot.messages.hostname", "Hostname=="+escapeField(hostname))
 	case LoginFail:
 		msg += t.I18nBot("tgbot.messages.loginFailed")
-		msg += t.I18nBot("tgbot.messages.hostname", "Hostname=="+hostname)
+		msg += t.I18nBot("tgbot.messages.hostname", "Hostname=="+escapeField(hostname))
 		if attempt.Reason != "" {
-			msg += t.I18nBot("tgbot.messages.reason", "Reason=="+attempt.Reason)
+			msg += t.I18nBot("tgbot.messages.reason", "Reason=="+escapeField(attempt.Reason))
 		}
 	}
-	msg += t.I18nBot("tgbot.messages.username", "Username=="+attempt.Username)
-	msg += t.I18nBot("tgbot.messages.ip", "IP=="+attempt.IP)
+	msg += t.I18nBot("tgbot.messages.username", "Username=="+escapeField(attempt.Username))
+	msg += t.I18nBot("tgbot.messages.ip", "IP=="+escapeField(attempt.IP))
 	msg += t.I18nBot("tgbot.messages.time", "Time=="+attempt.Time)
-	go t.SendMsgToTgbotAdmins(msg)
+	if attempt.Status == LoginFail {
+		blockKeyboard := tu.InlineKeyboard(tu.InlineKeyboardRow(
+			tu.InlineKeyboardButton(t.I18nBot("tgbot.buttons.blockIp")).WithCallbackData(t.encodeQuery("block_ip " + attempt.IP)),
+		))
+		go t.SendNotification(NotifyLogin, msg, blockKeyboard)
+		return
+	}
+	go t.SendNotification(NotifyLogin, msg)
 }
 
 // getExhausted retrieves and sends information about exhausted clients.
-func (t *Tgbot) getExhausted(chatId int64) {
-	trDiff := int64(0)
-	exDiff := int64(0)
+// Inbounds whose tag is in muted are left out of the report. Each inbound's
+// alert thresholds decide what counts as depleting soon.
+func (t *Tgbot) getExhausted(chatId int64, muted map[string]bool) {
 	now := time.Now().Unix() * 1000
 	var exhaustedInbounds []model.Inbound
 	var exhaustedClients []xray.ClientTraffic
 	var disabledInbounds []model.Inbound
 	var disabledClients []xray.ClientTraffic
+	mutedCount := 0
 
-	TrafficThreshold, err := t.settingService.GetTrafficDiff()
-	if err == nil && TrafficThreshold > 0 {
-		trDiff = int64(TrafficThreshold) * 1073741824
-	}
-	ExpireThreshold, err := t.settingService.GetExpireDiff()
-	if err == nil && ExpireThreshold > 0 {
-		exDiff = int64(ExpireThreshold) * 86400000
-	}
+	defaults := t.alertThresholds()
 	inbounds, err := t.inboundService.GetAllInbounds()
 	if err != nil {
 		logger.Warning("Unable to load Inbounds", err)
 	}
 
 	for _, inbound := range inbounds {
+		if muted[inbound.Tag] {
+			mutedCount++
+			continue
+		}
 		if inbound.Enable {
-			if (inbound.ExpiryTime > 0 && (inbound.ExpiryTime-now < exDiff)) ||
-				(inbound.Total > 0 && (inbound.Total-(inbound.Up+inbound.Down) < trDiff)) {
+			thresholds := defaults.ForInbound(inbound)
+			if thresholds.NearExpiry(inbound.ExpiryTime, now) ||
+				thresholds.NearTrafficLimit(inbound.Up+inbound.Down, inbound.Total) {
 				exhaustedInbounds = append(exhaustedInbounds, *inbound)
 			}
 			if len(inbound.ClientStats) > 0 {
 				for _, client := range inbound.ClientStats {
 					if client.Enable {
-						if (client.ExpiryTime > 0 && (client.ExpiryTime-now < exDiff)) ||
-							(client.Total > 0 && (client.Total-(client.Up+client.Down) < trDiff)) {
+						if thresholds.NearExpiry(client.ExpiryTime, now) ||
+							thresholds.NearTrafficLimit(client.Up+client.Down, client.Total) {
 							exhaustedClients = append(exhaustedClients, client)
 						}
 					} else {
@@ -243,14 +360,15 @@ func (t *Tgbot) getExhausted(chatId int64) {
 		output += t.I18nBot("tgbot.messages.depleteSoon", "Deplete=="+t.I18nBot("tgbot.inbounds"))
 
 		for _, inbound := range exhaustedInbounds {
-			output += t.I18nBot("tgbot.messages.inbound", "Remark=="+inbound.Remark)
+			output += t.I18nBot("tgbot.messages.inbound", "Remark=="+escapeField(inbound.Remark))
 			output += t.I18nBot("tgbot.messages.port", "Port=="+strconv.Itoa(inbound.Port))
-			output += t.I18nBot("tgbot.messages.traffic", "Total=="+common.FormatTraffic((inbound.Up+inbound.Down)), "Upload=="+common.FormatTraffic(inbound.Up), "Download=="+common.FormatTraffic(inbound.Down))
+			output += t.I18nBot("tgbot.messages.traffic", "Total=="+formatTraffic((inbound.Up+inbound.Down)), "Upload=="+formatTraffic(inbound.Up), "Download=="+formatTraffic(inbound.Down))
 			if inbound.ExpiryTime == 0 {
 				output += t.I18nBot("tgbot.messages.expire", "Time=="+t.I18nBot("tgbot.unlimited"))
 			} else {
 				output += t.I18nBot("tgbot.messages.expire", "Time=="+time.Unix((inbound.ExpiryTime/1000), 0).Format("2006-01-02 15:04:05"))
 			}
+			output += t.noteLine(inbound.Note)
 			output += "\r\n"
 		}
 	}
@@ -261,11 +379,16 @@ func (t *Tgbot) getExhausted(chatId int64) {
 	output += t.I18nBot("tgbot.messages.disabled", "Disabled=="+strconv.Itoa(len(disabledClients)))
 	output += t.I18nBot("tgbot.messages.depleteSoon", "Deplete=="+strconv.Itoa(exhaustedCC))
 
+	if mutedCount > 0 {
+		output += t.I18nBot("tgbot.messages.exhaustedMuted", "Count=="+strconv.Itoa(mutedCount))
+	}
+
 	if exhaustedCC > 0 {
 		output += t.I18nBot("tgbot.messages.depleteSoon", "Deplete=="+t.I18nBot("tgbot.clients"))
 		var buttons []telego.InlineKeyboardButton
 		for _, traffic := range exhaustedClients {
 			output += t.clientInfoMsg(&traffic, true, false, false, true, true, false)
+			output += t.clientNoteLine(traffic.Email)
 			output += "\r\n"
 			buttons = append(buttons, tu.InlineKeyboardButton(traffic.Email).WithCallbackData(t.encodeQuery("client_get_usage "+traffic.Email)))
 		}
@@ -284,28 +407,26 @@ func (t *Tgbot) getExhausted(chatId int64) {
 	}
 }
 
-// notifyExhausted sends notifications for exhausted clients.
+// notifyExhausted sends notifications for exhausted clients, judged by the
+// alert thresholds of their inbounds.
 func (t *Tgbot) notifyExhausted() {
-	trDiff := int64(0)
-	exDiff := int64(0)
 	now := time.Now().Unix() * 1000
 
-	TrafficThreshold, err := t.settingService.GetTrafficDiff()
-	if err == nil && TrafficThreshold > 0 {
-		trDiff = int64(TrafficThreshold) * 1073741824
-	}
-	ExpireThreshold, err := t.settingService.GetExpireDiff()
-	if err == nil && ExpireThreshold > 0 {
-		exDiff = int64(ExpireThreshold) * 86400000
-	}
+	defaults := t.alertThresholds()
 	inbounds, err := t.inboundService.GetAllInbounds()
 	if err != nil {
 		logger.Warning("Unable to load Inbounds", err)
 	}
 
+	muted := t.mutedInboundTags()
+	mutedIds := mutedInboundIds(inbounds, muted)
+	thresholds := make(map[int]service.AlertThresholds, len(inbounds))
+	for _, inbound := range inbounds {
+		thresholds[inbound.Id] = defaults.ForInbound(inbound)
+	}
 	var chatIDsDone []int64
 	for _, inbound := range inbounds {
-		if inbound.Enable {
+		if inbound.Enable && !mutedIds[inbound.Id] {
 			if len(inbound.ClientStats) > 0 {
 				clients, err := t.inboundService.GetClients(inbound)
 				if err == nil {
@@ -316,13 +437,18 @@ func (t *Tgbot) notifyExhausted() {
 								var disabledClients []xray.ClientTraffic
 								var exhaustedClients []xray.ClientTraffic
 								traffics, err := t.inboundService.GetClientTrafficTgBot(client.TgID)
+								traffics = withoutMutedTraffics(traffics, mutedIds)
 								if err == nil && len(traffics) > 0 {
 									var output strings.Builder
 									output.WriteString(t.I18nBot("tgbot.messages.exhaustedCount", "Type=="+t.I18nBot("tgbot.clients")))
 									for _, traffic := range traffics {
 										if traffic.Enable {
-											if (traffic.ExpiryTime > 0 && (traffic.ExpiryTime-now < exDiff)) ||
-												(traffic.Total > 0 && (traffic.Total-(traffic.Up+traffic.Down) < trDiff)) {
+											th, ok := thresholds[traffic.InboundId]
+											if !ok {
+												th = defaults
+											}
+											if th.NearExpiry(traffic.ExpiryTime, now) ||
+												th.NearTrafficLimit(traffic.Up+traffic.Down, traffic.Total) {
 												exhaustedClients = append(exhaustedClients, *traffic)
 											}
 										} else {
@@ -394,10 +520,11 @@ func (t *Tgbot) onlineClients(chatId int64, messageID ...int) {
 	}
 }
 
-// sendBackup sends a backup of the database and configuration files.
-func (t *Tgbot) sendBackup(chatId int64) {
+// sendBackup sends a backup of the database and configuration files. It
+// returns the errors of the sends to chatId, if any failed.
+func (t *Tgbot) sendBackup(chatId int64) error {
 	output := t.I18nBot("tgbot.messages.backupTime", "Time=="+time.Now().Format("2006-01-02 15:04:05"))
-	t.SendMsgToTgbot(chatId, output)
+	sendErr := t.sendMsgVia(bot, chatId, output)
 
 	// Send database backup (SQLite file, or a pg_dump archive on PostgreSQL)
 	dbData, err := t.serverService.GetDb()
@@ -415,6 +542,7 @@ func (t *Tgbot) sendBackup(chatId int64) {
 		cancel()
 		if err != nil {
 			logger.Error("Error in uploading backup: ", err)
+			sendErr = errors.Join(sendErr, err)
 		}
 	} else {
 		logger.Error("Error in getting db backup: ", err)
@@ -436,10 +564,12 @@ func (t *Tgbot) sendBackup(chatId int64) {
 		_, err = bot.SendDocument(ctx, document)
 		if err != nil {
 			logger.Error("Error in uploading config.json: ", err)
+			sendErr = errors.Join(sendErr, err)
 		}
 	} else {
 		logger.Error("Error in opening config.json file for backup: ", err)
 	}
+	return sendErr
 }
 
 // sendBanLogs sends the ban logs to the specified chat.
@@ -0,0 +1,73 @@
+package tgbot
+
+import (
+	"context"
+	"time"
+
+	"github.com/zixu5u/3xv/v3/internal/database/model"
+	"github.com/zixu5u/3xv/v3/internal/logger"
+
+	tu "github.com/mymmrac/telego/telegoutil"
+)
+
+// reportAsFile reports whether a status report of size bytes goes out as a
+// document rather than chunked messages. A threshold of 0 disables it.
+func reportAsFile(size int, threshold int) bool {
+	return threshold > 0 && size > threshold
+}
+
+// reportGrandTotal sums the traffic of the enabled inbounds, the same ones
+// the status report lists.
+func reportGrandTotal(inbounds []*model.Inbound) int64 {
+	var total int64
+	for _, in := range inbounds {
+		if in.Enable {
+			total += in.Up + in.Down
+		}
+	}
+	return total
+}
+
+// sendReportAsFile sends the status report to chatIds as a text document
+// when it exceeds the tgReportFileThreshold setting. The caption carries the
+// date and grand total so the key figure stays visible in the chat. It
+// returns false when the report should be sent as messages instead.
+func (t *Tgbot) sendReportAsFile(info string, chatIds []int64) bool {
+	if !isRunning {
+		return false
+	}
+	threshold, err := t.settingService.GetTgReportFileThreshold()
+	if err != nil || !reportAsFile(len(info), threshold) {
+		return false
+	}
+	inbounds, err := t.inboundService.GetAllInbounds()
+	if err != nil {
+		logger.Warning("Failed to get inbounds for the report caption:", err)
+		return false
+	}
+
+	now := time.Now()
+	name := "report-" + now.Format("2006-01-02") + ".txt"
+	caption := t.I18nBot("tgbot.messages.reportFileCaption",
+		"Date=="+now.Format("2006-01-02"),
+		"Total=="+formatTraffic(reportGrandTotal(inbounds)))
+
+	fanOut(chatIds, func(adminId int64) error {
+		start := time.Now()
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		document := tu.Document(
+			tu.ID(adminId),
+			tu.FileFromBytes([]byte(info), name),
+		).WithCaption(caption)
+		document.DisableNotification = isSilent(NotifyReport)
+		_, err := bot.SendDocument(ctx, document)
+		cancel()
+		logBotEvent(botEvent{Event: "notification", ChatID: adminId, Latency: time.Since(start), Err: err})
+		if err != nil {
+			logger.Warningf("Error in uploading report to %d, sending it as messages: %v", adminId, err)
+			return t.sendNotificationVia(bot, adminId, NotifyReport, info)
+		}
+		return nil
+	}).logSummary("Report file")
+	return true
+}
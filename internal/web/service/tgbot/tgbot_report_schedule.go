@@ -0,0 +1,248 @@
+package tgbot
+
+import (
+	"html"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/zixu5u/3xv/v3/internal/logger"
+	"github.com/zixu5u/3xv/v3/internal/web/service"
+
+	"github.com/mymmrac/telego"
+	tu "github.com/mymmrac/telego/telegoutil"
+)
+
+// ReportCronName is the panel cron entry of a report scheduled with a cron
+// expression. Clock times run on the bot's own scheduler instead.
+const ReportCronName = "tgbot-report"
+
+// reportEveryHours are the intervals /reportschedule offers.
+var reportEveryHours = []int{1, 2, 3, 4, 6, 8, 12}
+
+// reportCronJob sends the scheduled report from the panel cron while Xray
+// runs, like job.StatsNotifyJob, which this package can't import.
+type reportCronJob struct {
+	t *Tgbot
+}
+
+func (j reportCronJob) Run() {
+	if j.t.xrayService.IsXrayRunning() {
+		j.t.SendReport()
+	}
+}
+
+// ReloadReportSchedule moves the primary bot's scheduled report to runTime
+// without a restart: a clock time goes to the bot's scheduler, a cron
+// expression to the panel cron, and "off" stops both. Extra bots that
+// inherit tgRunTime keep their schedule until the next restart.
+func (t *Tgbot) ReloadReportSchedule(runTime string) error {
+	switch {
+	case IsReportScheduleOff(runTime):
+		service.PanelCron().Remove(ReportCronName)
+		t.StopScheduler()
+	case IsClockSchedule(runTime):
+		service.PanelCron().Remove(ReportCronName)
+		t.StartScheduler(strings.TrimSpace(runTime))
+	default:
+		t.StopScheduler()
+		if err := service.PanelCron().SetSpec(ReportCronName, runTime, reportCronJob{t: t}); err != nil {
+			return err
+		}
+	}
+
+	if cfg := effectiveConfig.Load(); cfg != nil {
+		updated := *cfg
+		updated.runTime = runTime
+		updated.categories = slices.DeleteFunc(slices.Clone(cfg.categories), func(c string) bool { return c == NotifyReport })
+		if !IsReportScheduleOff(runTime) {
+			updated.categories = append(updated.categories, NotifyReport)
+			slices.Sort(updated.categories)
+		}
+		effectiveConfig.Store(&updated)
+	}
+	return nil
+}
+
+// hourReportSpec is the cron expression of a report at hour o'clock on the
+// days dow names, "*" for every day.
+func hourReportSpec(hour int, dow string) string {
+	return "0 0 " + strconv.Itoa(hour) + " * * " + dow
+}
+
+// clockReportSpec is the cron expression of a daily report at the "HH:MM"
+// clock time runTime, which the primary bot runs on its own scheduler
+// instead. Anything else is already a cron expression and returned as is.
+func clockReportSpec(runTime string) string {
+	clock, err := time.Parse("15:04", strings.TrimSpace(runTime))
+	if err != nil {
+		return runTime
+	}
+	return "0 " + strconv.Itoa(clock.Minute()) + " " + strconv.Itoa(clock.Hour()) + " * * *"
+}
+
+// everyHoursReportSpec is the cron expression of a report every hours
+// hours, on the hour.
+func everyHoursReportSpec(hours int) string {
+	return "0 0 */" + strconv.Itoa(hours) + " * * *"
+}
+
+// weekdayLabels returns the translated short names of the days of the
+// week, Sunday first.
+func (t *Tgbot) weekdayLabels() []string {
+	labels := strings.Split(t.I18nBot("tgbot.weekdays"), ",")
+	if len(labels) != 7 {
+		labels = []string{"Sun", "Mon", "Tue", "Wed", "Thu", "Fri", "Sat"}
+	}
+	return labels
+}
+
+// reportHourKeyboard offers the hours of the day, building the cron
+// expression for each with dow as its day-of-week field.
+func (t *Tgbot) reportHourKeyboard(dow string) *telego.InlineKeyboardMarkup {
+	var rows [][]telego.InlineKeyboardButton
+	var row []telego.InlineKeyboardButton
+	for hour := range 24 {
+		spec := hourReportSpec(hour, dow)
+		label := strconv.Itoa(hour) + ":00"
+		if hour < 10 {
+			label = "0" + label
+		}
+		row = append(row, tu.InlineKeyboardButton(label).WithCallbackData(t.encodeQuery("rs_preview "+spec)))
+		if len(row) == 6 {
+			rows = append(rows, row)
+			row = nil
+		}
+	}
+	rows = append(rows, tu.InlineKeyboardRow(
+		tu.InlineKeyboardButton(t.I18nBot("tgbot.buttons.back")).WithCallbackData(t.encodeQuery("rs_menu"))))
+	return tu.InlineKeyboard(rows...)
+}
+
+// reportScheduleMenu is the first step of /reportschedule.
+func (t *Tgbot) reportScheduleMenu() *telego.InlineKeyboardMarkup {
+	return tu.InlineKeyboard(
+		tu.InlineKeyboardRow(
+			tu.InlineKeyboardButton(t.I18nBot("tgbot.buttons.scheduleDaily")).WithCallbackData(t.encodeQuery("rs_daily")),
+			tu.InlineKeyboardButton(t.I18nBot("tgbot.buttons.scheduleEveryHours")).WithCallbackData(t.encodeQuery("rs_hours")),
+			tu.InlineKeyboardButton(t.I18nBot("tgbot.buttons.scheduleWeekly")).WithCallbackData(t.encodeQuery("rs_weekly")),
+		),
+		tu.InlineKeyboardRow(
+			tu.InlineKeyboardButton(t.I18nBot("tgbot.buttons.scheduleOff")).WithCallbackData(t.encodeQuery("rs_preview "+ReportScheduleOff)),
+		),
+	)
+}
+
+// currentReportSchedule describes the stored report schedule.
+func (t *Tgbot) currentReportSchedule() string {
+	runTime, err := t.settingService.GetTgbotRuntime()
+	if err != nil {
+		runTime = t.settingFallback("tgRunTime", err, defaultReportRunTime)
+	}
+	return t.I18nBot("tgbot.messages.cronStatusReport", "Schedule=="+t.describeSchedule(runTime))
+}
+
+// sendReportSchedule implements "/reportschedule [expression]". Without an
+// argument it shows the schedule with buttons for common report times;
+// with one, the expression is checked and offered for confirmation.
+func (t *Tgbot) sendReportSchedule(chatId int64, spec string) {
+	if spec == "" {
+		t.SendMsgToTgbot(chatId, t.currentReportSchedule()+t.I18nBot("tgbot.messages.reportScheduleChoose"), t.reportScheduleMenu())
+		return
+	}
+	spec, err := reportSchedule(spec)
+	if err != nil {
+		t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.messages.reportScheduleInvalid", "Error=="+html.EscapeString(err.Error())))
+		return
+	}
+	text, keyboard := t.reportSchedulePreview(spec)
+	t.SendMsgToTgbot(chatId, text, keyboard)
+}
+
+// reportSchedulePreview shows spec and its next run with a button to apply
+// it.
+func (t *Tgbot) reportSchedulePreview(spec string) (string, *telego.InlineKeyboardMarkup) {
+	text := t.I18nBot("tgbot.messages.reportSchedulePreview", "Schedule=="+t.describeSchedule(spec))
+	keyboard := tu.InlineKeyboard(tu.InlineKeyboardRow(
+		tu.InlineKeyboardButton(t.I18nBot("tgbot.buttons.scheduleApply")).WithCallbackData(t.encodeQuery("rs_apply "+spec)),
+		tu.InlineKeyboardButton(t.I18nBot("tgbot.buttons.back")).WithCallbackData(t.encodeQuery("rs_menu")),
+	))
+	return text, keyboard
+}
+
+// handleReportScheduleCallback handles the buttons of /reportschedule,
+// editing the message in place until a schedule is applied.
+func (t *Tgbot) handleReportScheduleCallback(callbackQuery *telego.CallbackQuery, dataArray []string) {
+	chatId := callbackQuery.Message.GetChat().ID
+	messageID := callbackQuery.Message.GetMessageID()
+	arg := strings.Join(dataArray[1:], " ")
+	switch dataArray[0] {
+	case "rs_menu":
+		t.editMessageTgBot(chatId, messageID, t.currentReportSchedule()+t.I18nBot("tgbot.messages.reportScheduleChoose"), t.reportScheduleMenu())
+	case "rs_daily":
+		t.editMessageCallbackTgBot(chatId, messageID, t.reportHourKeyboard("*"))
+	case "rs_hours":
+		var row []telego.InlineKeyboardButton
+		for _, hours := range reportEveryHours {
+			row = append(row, tu.InlineKeyboardButton(strconv.Itoa(hours)+"h").
+				WithCallbackData(t.encodeQuery("rs_preview "+everyHoursReportSpec(hours))))
+		}
+		t.editMessageCallbackTgBot(chatId, messageID, tu.InlineKeyboard(row, tu.InlineKeyboardRow(
+			tu.InlineKeyboardButton(t.I18nBot("tgbot.buttons.back")).WithCallbackData(t.encodeQuery("rs_menu")))))
+	case "rs_weekly":
+		var row []telego.InlineKeyboardButton
+		for day, label := range t.weekdayLabels() {
+			row = append(row, tu.InlineKeyboardButton(label).WithCallbackData(t.encodeQuery("rs_weekday "+strconv.Itoa(day))))
+		}
+		t.editMessageCallbackTgBot(chatId, messageID, tu.InlineKeyboard(row, tu.InlineKeyboardRow(
+			tu.InlineKeyboardButton(t.I18nBot("tgbot.buttons.back")).WithCallbackData(t.encodeQuery("rs_menu")))))
+	case "rs_weekday":
+		if day, err := strconv.Atoi(arg); err == nil && day >= 0 && day <= 6 {
+			t.editMessageCallbackTgBot(chatId, messageID, t.reportHourKeyboard(arg))
+		}
+	case "rs_preview":
+		spec, err := reportSchedule(arg)
+		if err != nil {
+			t.answerCallbackTgBot(callbackQuery.ID, err.Error(), true)
+			return
+		}
+		text, keyboard := t.reportSchedulePreview(spec)
+		t.editMessageTgBot(chatId, messageID, text, keyboard)
+	case "rs_apply":
+		t.applyReportSchedule(chatId, messageID, arg, callbackQuery.From.ID)
+	}
+}
+
+// applyReportSchedule stores spec as tgRunTime, recording the change like
+// /setsetting does, and moves the report to it right away.
+func (t *Tgbot) applyReportSchedule(chatId int64, messageID int, spec string, changedBy int64) {
+	spec, err := reportSchedule(spec)
+	if err != nil {
+		t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.messages.reportScheduleInvalid", "Error=="+html.EscapeString(err.Error())))
+		return
+	}
+	actor := telegramActor(changedBy)
+	old, err := t.settingService.ChangeSetting("tgRunTime", spec, actor)
+	if err == nil {
+		err = t.ReloadReportSchedule(spec)
+	}
+	logBotEvent(botEvent{Event: "setting_change", ChatID: changedBy, Command: "reportschedule", Err: err})
+	if err != nil {
+		logger.Warning("Failed to change the report schedule:", err)
+		t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.messages.reportScheduleFailed", "Error=="+html.EscapeString(err.Error())))
+		return
+	}
+	logger.Infof("Report schedule changed from %q to %q by %s", old, spec, actor)
+
+	msg := t.I18nBot("tgbot.messages.reportScheduleApplied", "Schedule=="+t.describeSchedule(spec))
+	extraBotsMutex.Lock()
+	for _, eb := range extraBots {
+		if eb.wants(NotifyReport) {
+			msg += t.I18nBot("tgbot.messages.reportScheduleExtraBots")
+			break
+		}
+	}
+	extraBotsMutex.Unlock()
+	t.editMessageTgBot(chatId, messageID, msg)
+}
@@ -0,0 +1,353 @@
+package tgbot
+
+import (
+	"errors"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/zixu5u/3xv/v3/internal/logger"
+	"github.com/zixu5u/3xv/v3/internal/xray"
+)
+
+// Report sections the tgReportSections setting can list.
+const (
+	ReportSectionTraffic   = "traffic"   // inbound traffic totals and sparklines
+	ReportSectionTop       = "top"       // clients that used the most traffic
+	ReportSectionExpiring  = "expiring"  // inbounds and clients expiring soon
+	ReportSectionResources = "resources" // CPU, memory, disk and load
+	ReportSectionOnline    = "online"    // today's online peak and average
+	ReportSectionLogins    = "logins"    // failed panel logins of the last day
+)
+
+// reportSectionNames lists every report section, in the order /reportconfig
+// offers them.
+var reportSectionNames = []string{
+	ReportSectionTraffic, ReportSectionTop, ReportSectionExpiring,
+	ReportSectionResources, ReportSectionOnline, ReportSectionLogins,
+}
+
+// defaultReportSections is the report before sections were configurable.
+const defaultReportSections = ReportSectionOnline + "," + ReportSectionTraffic
+
+const (
+	// reportTopClients is how many clients the top consumers section lists.
+	reportTopClients = 5
+	// reportExpiringShown is how many entries the expiring section lists.
+	reportExpiringShown = 5
+	// reportFailedLoginIPs is how many source IPs the failed logins section
+	// names.
+	reportFailedLoginIPs = 3
+	// failedLoginWindow is how far back the failed logins section counts.
+	failedLoginWindow = 24 * time.Hour
+	// maxFailedLogins caps the failed logins kept for the report.
+	maxFailedLogins = 1000
+)
+
+// parseReportSections reads a comma-separated list of report sections. The
+// order is kept and repeats are dropped; at least one section is required.
+func parseReportSections(value string) ([]string, error) {
+	var sections []string
+	for part := range strings.SplitSeq(value, ",") {
+		part = strings.ToLower(strings.TrimSpace(part))
+		if part == "" || slices.Contains(sections, part) {
+			continue
+		}
+		if !slices.Contains(reportSectionNames, part) {
+			return nil, errors.New("expected sections from " + strings.Join(reportSectionNames, ", "))
+		}
+		sections = append(sections, part)
+	}
+	if len(sections) == 0 {
+		return nil, errors.New("expected at least one of " + strings.Join(reportSectionNames, ", "))
+	}
+	return sections, nil
+}
+
+// reportSectionList normalizes a tgReportSections value for /setsetting.
+func reportSectionList(value string) (string, error) {
+	sections, err := parseReportSections(value)
+	if err != nil {
+		return "", err
+	}
+	return strings.Join(sections, ","), nil
+}
+
+// reportSections returns the sections the report shows, in order. An
+// unreadable or invalid setting falls back to defaultReportSections.
+func (t *Tgbot) reportSections() []string {
+	raw, err := t.settingService.GetTgReportSections()
+	if err != nil {
+		raw = t.settingFallback("tgReportSections", err, defaultReportSections)
+	}
+	sections, err := parseReportSections(raw)
+	if err != nil {
+		logger.Warningf("Invalid tgReportSections %q, using %q: %v", raw, defaultReportSections, err)
+		sections, _ = parseReportSections(defaultReportSections)
+	}
+	return sections
+}
+
+// buildReport assembles the report from the enabled sections. The short
+// sections follow the header in msg; the traffic section's per-inbound
+// status is returned in status, which is "" without it, since it is sent on
+// its own and may go out as a file.
+func (t *Tgbot) buildReport(runTime string) (msg string, status string) {
+	header := t.I18nBot("tgbot.messages.report", "RunTime=="+runTime)
+	header += t.I18nBot("tgbot.messages.datetime", "DateTime=="+time.Now().Format("2006-01-02 15:04:05"))
+	return composeReport(header, t.reportSections(), t.renderReportSection)
+}
+
+// composeReport joins header and the rendered sections, in order, into the
+// report message and the status sent after it.
+func composeReport(header string, sections []string, render func(section string) (text string, status string)) (msg string, status string) {
+	msg = header
+	for _, section := range sections {
+		text, sectionStatus := render(section)
+		msg += text
+		if sectionStatus != "" {
+			status = sectionStatus
+		}
+	}
+	return decorate(severityInfo, msg), status
+}
+
+// renderReportSection renders one report section. Only the traffic section
+// has a status.
+func (t *Tgbot) renderReportSection(section string) (text string, status string) {
+	switch section {
+	case ReportSectionTraffic:
+		return t.reportSparklines(), t.reportTrafficStatus()
+	case ReportSectionTop:
+		return t.reportTopConsumers(), ""
+	case ReportSectionExpiring:
+		return t.reportExpiringSoon(), ""
+	case ReportSectionResources:
+		return t.reportResources(), ""
+	case ReportSectionOnline:
+		return t.getOnlineTrend(), ""
+	case ReportSectionLogins:
+		return t.reportFailedLogins(), ""
+	}
+	return "", ""
+}
+
+// topClientTraffics returns the n clients with the most traffic, busiest
+// first, ties by email. Clients without traffic are left out.
+func topClientTraffics(traffics []*xray.ClientTraffic, n int) []*xray.ClientTraffic {
+	top := slices.DeleteFunc(slices.Clone(traffics), func(c *xray.ClientTraffic) bool { return c.Up+c.Down == 0 })
+	sort.Slice(top, func(i, j int) bool {
+		if ti, tj := top[i].Up+top[i].Down, top[j].Up+top[j].Down; ti != tj {
+			return ti > tj
+		}
+		return top[i].Email < top[j].Email
+	})
+	return top[:min(len(top), n)]
+}
+
+// reportTopConsumers lists the clients that used the most traffic.
+func (t *Tgbot) reportTopConsumers() string {
+	traffics, err := t.inboundService.GetAllClientTraffics()
+	if err != nil {
+		logger.Warning("get client traffics for the report failed:", err)
+		return ""
+	}
+	top := topClientTraffics(traffics, reportTopClients)
+	if len(top) == 0 {
+		return ""
+	}
+	var sb strings.Builder
+	sb.WriteString(t.I18nBot("tgbot.messages.reportTopHeader", "Count=="+strconv.Itoa(len(top))))
+	for i, c := range top {
+		sb.WriteString(t.I18nBot("tgbot.messages.reportTopLine",
+			"Rank=="+strconv.Itoa(i+1),
+			"Email=="+escapeField(c.Email),
+			"Total=="+formatTraffic(c.Up+c.Down)))
+	}
+	return sb.String()
+}
+
+// reportExpiringSoon lists the inbounds and clients expiring within the
+// panel's expireDiff, or defaultExpiringDays when it is 0.
+func (t *Tgbot) reportExpiringSoon() string {
+	days, err := t.settingService.GetExpireDiff()
+	if err != nil {
+		t.settingFallback("expireDiff", err, "0")
+	}
+	if days <= 0 {
+		days = defaultExpiringDays
+	}
+	inbounds, err := t.inboundService.GetAllInbounds()
+	if err != nil {
+		logger.Warning("get inbounds for the report failed:", err)
+		return ""
+	}
+	now := time.Now()
+	entries := collectExpiring(inbounds, days, true, now)
+	if len(entries) == 0 {
+		return ""
+	}
+	loc := t.timeLocation()
+	var sb strings.Builder
+	sb.WriteString(t.I18nBot("tgbot.messages.reportExpiringHeader",
+		"Count=="+strconv.Itoa(len(entries)),
+		"Days=="+strconv.Itoa(days)))
+	for _, e := range entries[:min(len(entries), reportExpiringShown)] {
+		name := e.remark
+		if e.email != "" {
+			name = e.email
+		}
+		sb.WriteString(t.I18nBot("tgbot.messages.reportExpiringLine",
+			"Name=="+escapeField(name),
+			"Date=="+time.UnixMilli(e.expiryTime).In(loc).Format("2006-01-02")))
+	}
+	return sb.String()
+}
+
+// reportResources shows the server's CPU, memory, disk and load.
+func (t *Tgbot) reportResources() string {
+	status := t.serverService.GetStatus(t.lastStatus)
+	if status == nil {
+		return ""
+	}
+	t.lastStatus = status
+	load := "0.00"
+	if len(status.Loads) > 0 {
+		load = strconv.FormatFloat(status.Loads[0], 'f', 2, 64)
+	}
+	return t.I18nBot("tgbot.messages.reportResources",
+		"Cpu=="+strconv.FormatFloat(status.Cpu, 'f', 1, 64),
+		"Mem=="+formatTraffic(int64(status.Mem.Current)),
+		"MemTotal=="+formatTraffic(int64(status.Mem.Total)),
+		"Disk=="+formatTraffic(int64(status.Disk.Current)),
+		"DiskTotal=="+formatTraffic(int64(status.Disk.Total)),
+		"Load=="+load)
+}
+
+// failedLogin is a failed panel login kept for the report.
+type failedLogin struct {
+	at time.Time
+	ip string
+}
+
+// failedLogins are the failed panel logins of the last failedLoginWindow,
+// oldest first.
+var failedLogins struct {
+	sync.Mutex
+	list []failedLogin
+}
+
+// recordFailedLogin keeps a failed login from ip for the report.
+func recordFailedLogin(ip string, now time.Time) {
+	failedLogins.Lock()
+	defer failedLogins.Unlock()
+	failedLogins.list = append(pruneFailedLogins(failedLogins.list, now), failedLogin{at: now, ip: ip})
+	if over := len(failedLogins.list) - maxFailedLogins; over > 0 {
+		failedLogins.list = slices.Delete(failedLogins.list, 0, over)
+	}
+}
+
+// pruneFailedLogins drops the logins older than failedLoginWindow.
+func pruneFailedLogins(list []failedLogin, now time.Time) []failedLogin {
+	cutoff := now.Add(-failedLoginWindow)
+	i := 0
+	for i < len(list) && !list[i].at.After(cutoff) {
+		i++
+	}
+	return slices.Delete(list, 0, i)
+}
+
+// ipCount is how many failed logins came from an IP.
+type ipCount struct {
+	ip    string
+	count int
+}
+
+// failedLoginSummary counts the failed logins of the last failedLoginWindow
+// and returns the n IPs most of them came from.
+func failedLoginSummary(now time.Time, n int) (total int, top []ipCount) {
+	failedLogins.Lock()
+	failedLogins.list = pruneFailedLogins(failedLogins.list, now)
+	counts := make(map[string]int)
+	for _, login := range failedLogins.list {
+		counts[login.ip]++
+	}
+	total = len(failedLogins.list)
+	failedLogins.Unlock()
+
+	for ip, count := range counts {
+		top = append(top, ipCount{ip: ip, count: count})
+	}
+	slices.SortFunc(top, func(a, b ipCount) int {
+		if a.count != b.count {
+			return b.count - a.count
+		}
+		return strings.Compare(a.ip, b.ip)
+	})
+	return total, top[:min(len(top), n)]
+}
+
+// reportFailedLogins counts the failed panel logins of the last day and
+// names the IPs most of them came from. Attempts are counted while the bot
+// runs, so a restart starts the count over.
+func (t *Tgbot) reportFailedLogins() string {
+	total, top := failedLoginSummary(time.Now(), reportFailedLoginIPs)
+	if total == 0 {
+		return t.I18nBot("tgbot.messages.reportLoginsNone")
+	}
+	ips := make([]string, len(top))
+	for i, c := range top {
+		ips[i] = escapeField(c.ip) + " ×" + strconv.Itoa(c.count)
+	}
+	return t.I18nBot("tgbot.messages.reportLogins",
+		"Count=="+strconv.Itoa(total),
+		"IPs=="+strings.Join(ips, ", "))
+}
+
+// reportSectionLabels returns the translated names of sections.
+func (t *Tgbot) reportSectionLabels(sections []string) string {
+	labels := make([]string, len(sections))
+	for i, section := range sections {
+		labels[i] = "<code>" + section + "</code> " + t.I18nBot("tgbot.reportSections."+section)
+	}
+	return strings.Join(labels, "\r\n")
+}
+
+// sendReportConfig implements "/reportconfig" without arguments: the
+// sections the report shows, in order, and the ones it leaves out.
+func (t *Tgbot) sendReportConfig(chatId int64) {
+	enabled := t.reportSections()
+	disabled := slices.DeleteFunc(slices.Clone(reportSectionNames), func(s string) bool { return slices.Contains(enabled, s) })
+	msg := t.I18nBot("tgbot.messages.reportConfigEnabled", "Sections=="+t.reportSectionLabels(enabled))
+	if len(disabled) > 0 {
+		msg += t.I18nBot("tgbot.messages.reportConfigDisabled", "Sections=="+t.reportSectionLabels(disabled))
+	}
+	msg += t.I18nBot("tgbot.messages.reportConfigUsage")
+	t.SendMsgToTgbot(chatId, msg)
+}
+
+// setReportSections implements "/reportconfig [sections]": it stores the
+// comma-separated sections as tgReportSections, recording the change like
+// /setsetting does. The next report uses them.
+func (t *Tgbot) setReportSections(chatId int64, value string, changedBy int64) {
+	value, err := reportSectionList(value)
+	if err != nil {
+		t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.messages.reportConfigInvalid", "Error=="+escapeField(err.Error()))+
+			t.I18nBot("tgbot.messages.reportConfigUsage"))
+		return
+	}
+	actor := telegramActor(changedBy)
+	old, err := t.settingService.ChangeSetting("tgReportSections", value, actor)
+	logBotEvent(botEvent{Event: "setting_change", ChatID: changedBy, Command: "reportconfig", Err: err})
+	if err != nil {
+		logger.Warning("Failed to change the report sections:", err)
+		t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.messages.reportConfigFailed", "Error=="+escapeField(err.Error())))
+		return
+	}
+	logger.Infof("Report sections changed from %q to %q by %s", old, value, actor)
+	sections, _ := parseReportSections(value)
+	t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.messages.reportConfigSaved", "Sections=="+t.reportSectionLabels(sections)))
+}
@@ -0,0 +1,136 @@
+package tgbot
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/zixu5u/3xv/v3/internal/database/model"
+	"github.com/zixu5u/3xv/v3/internal/logger"
+
+	tu "github.com/mymmrac/telego/telegoutil"
+)
+
+// defaultReportSummaryTop is the tgReportSummaryTop default, used when it
+// can't be read.
+const defaultReportSummaryTop = 10
+
+// reportAsSummary reports whether a report of count inbounds is summarized
+// rather than listing every inbound. A threshold of 0 disables it.
+func reportAsSummary(count int, threshold int) bool {
+	return threshold > 0 && count > threshold
+}
+
+// reportSummary is what the summarized report shows of the inbounds.
+type reportSummary struct {
+	total    int64
+	enabled  int
+	disabled int
+	top      []*model.Inbound
+}
+
+// summarizeInbounds totals the traffic of the enabled inbounds, as the full
+// report does, counts the inbounds and picks the n busiest, ties by remark.
+func summarizeInbounds(inbounds []*model.Inbound, n int) reportSummary {
+	summary := reportSummary{total: reportGrandTotal(inbounds)}
+	for _, in := range inbounds {
+		if in.Enable {
+			summary.enabled++
+		} else {
+			summary.disabled++
+		}
+	}
+	top := make([]*model.Inbound, 0, len(inbounds))
+	for _, in := range inbounds {
+		if in.Up+in.Down > 0 {
+			top = append(top, in)
+		}
+	}
+	sort.Slice(top, func(i, j int) bool {
+		if ti, tj := top[i].Up+top[i].Down, top[j].Up+top[j].Down; ti != tj {
+			return ti > tj
+		}
+		return top[i].Remark < top[j].Remark
+	})
+	summary.top = top[:min(len(top), n)]
+	return summary
+}
+
+// reportTrafficStatus is the status of the report's traffic section: every
+// inbound, or a summary once there are more than tgReportSummaryThreshold.
+func (t *Tgbot) reportTrafficStatus() string {
+	threshold, err := t.settingService.GetTgReportSummaryThreshold()
+	if err != nil {
+		t.settingFallback("tgReportSummaryThreshold", err, "0")
+		return t.buildRichStatus()
+	}
+	inbounds, err := t.inboundService.GetAllInbounds()
+	if err != nil || !reportAsSummary(len(inbounds), threshold) {
+		return t.buildRichStatus()
+	}
+	n, err := t.settingService.GetTgReportSummaryTop()
+	if err != nil {
+		t.settingFallback("tgReportSummaryTop", err, strconv.Itoa(defaultReportSummaryTop))
+		n = defaultReportSummaryTop
+	}
+	return t.formatReportSummary(summarizeInbounds(inbounds, n), len(inbounds), threshold)
+}
+
+// formatReportSummary renders summary of count inbounds.
+func (t *Tgbot) formatReportSummary(summary reportSummary, count int, threshold int) string {
+	var sb strings.Builder
+	sb.WriteString(t.I18nBot("tgbot.messages.reportSummaryHeader",
+		"Count=="+strconv.Itoa(count),
+		"Threshold=="+strconv.Itoa(threshold)))
+	sb.WriteString(t.I18nBot("tgbot.messages.reportSummaryTotal", "Total=="+formatTraffic(summary.total)))
+	clients, enabledClients := 0, 0
+	if counts, err := t.clientService.CountByInbound(); err == nil {
+		for _, c := range counts {
+			clients += c.Total
+			enabledClients += c.Enabled
+		}
+	} else {
+		logger.Warning("Failed to count clients for the report summary:", err)
+	}
+	sb.WriteString(t.I18nBot("tgbot.messages.reportSummaryCounts",
+		"Enabled=="+strconv.Itoa(summary.enabled),
+		"Disabled=="+strconv.Itoa(summary.disabled),
+		"Clients=="+strconv.Itoa(clients),
+		"EnabledClients=="+strconv.Itoa(enabledClients)))
+	if len(summary.top) > 0 {
+		sb.WriteString(t.I18nBot("tgbot.messages.reportSummaryTopHeader", "Count=="+strconv.Itoa(len(summary.top))))
+		for i, in := range summary.top {
+			sb.WriteString(t.I18nBot("tgbot.messages.reportSummaryTopLine",
+				"Rank=="+strconv.Itoa(i+1),
+				"Remark=="+reportRemark(in),
+				"Total=="+formatTraffic(in.Up+in.Down)))
+		}
+	}
+	sb.WriteString(t.I18nBot("tgbot.messages.reportSummaryExport"))
+	return sb.String()
+}
+
+// sendExport implements /export: the full status with every inbound, as a
+// document, for when the report only carries a summary.
+func (t *Tgbot) sendExport(chatId int64) {
+	status := t.buildRichStatus()
+	inbounds, err := t.inboundService.GetAllInbounds()
+	if err != nil {
+		logger.Warning("Failed to get inbounds for the export caption:", err)
+	}
+	now := time.Now()
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	document := tu.Document(
+		tu.ID(chatId),
+		tu.FileFromBytes([]byte(status), "report-"+now.Format("2006-01-02")+".txt"),
+	).WithCaption(t.I18nBot("tgbot.messages.reportFileCaption",
+		"Date=="+now.Format("2006-01-02"),
+		"Total=="+formatTraffic(reportGrandTotal(inbounds))))
+	if _, err := bot.SendDocument(ctx, document); err != nil {
+		logger.Warning("Error in uploading the export, sending it as messages:", err)
+		t.SendMsgToTgbot(chatId, status)
+	}
+}
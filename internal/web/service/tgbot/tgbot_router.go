@@ -36,9 +36,18 @@ func (t *Tgbot) OnReceive() {
 	// before this receiver goroutine is accounted for.
 	botWG.Add(1)
 	tgBotMutex.Unlock()
+	acceptHandlers()
+	resetBotStats(time.Now())
 
 	// Get updates channel using the context with shorter timeout for better error recovery
-	updates, _ := bot.UpdatesViaLongPolling(ctx, &params)
+	updates, err := bot.UpdatesViaLongPolling(ctx, &params)
+	if err != nil {
+		logger.Warning("Failed to start Telegram bot long polling:", err)
+	} else {
+		go t.sendStartupNotice()
+		go t.sendXrayConfigAlert()
+		go t.checkSettings()
+	}
 	go func() {
 		defer botWG.Done()
 		h, _ := th.NewBotHandler(bot, updates)
@@ -46,6 +55,11 @@ func (t *Tgbot) OnReceive() {
 		botHandler = h
 		tgBotMutex.Unlock()
 
+		h.Use(func(ctx *th.Context, update telego.Update) error {
+			botStats.updates.Add(1)
+			return ctx.Next(update)
+		})
+
 		h.HandleMessage(func(ctx *th.Context, message telego.Message) error {
 			delete(userStates, message.Chat.ID)
 			t.SendMsgToTgbot(message.Chat.ID, t.I18nBot("tgbot.keyboardClosed"), tu.ReplyKeyboardRemove())
@@ -57,30 +71,38 @@ func (t *Tgbot) OnReceive() {
 				return nil
 			}
 
-			// Use goroutine with worker pool for concurrent command processing
-			go func() {
-				messageWorkerPool <- struct{}{}        // Acquire worker
-				defer func() { <-messageWorkerPool }() // Release worker
-
+			// Run on the worker pool for concurrent command processing
+			runHandler(func() {
 				delete(userStates, message.Chat.ID)
 				t.answerCommand(&message, message.Chat.ID, checkAdmin(message.From.ID))
-			}()
+			})
 			return nil
 		}, th.AnyCommand())
 
 		h.HandleCallbackQuery(func(ctx *th.Context, query telego.CallbackQuery) error {
-			// Use goroutine with worker pool for concurrent callback processing
-			go func() {
-				messageWorkerPool <- struct{}{}        // Acquire worker
-				defer func() { <-messageWorkerPool }() // Release worker
-
+			// Run on the worker pool for concurrent callback processing
+			runHandler(func() {
 				delete(userStates, query.Message.GetChat().ID)
 				t.answerCallback(&query, checkAdmin(query.From.ID))
-			}()
+			})
 			return nil
 		}, th.AnyCallbackQueryWithMessage())
 
+		h.HandleInlineQuery(func(ctx *th.Context, query telego.InlineQuery) error {
+			runHandler(func() {
+				t.answerInlineQuery(&query, checkAdmin(query.From.ID))
+			})
+			return nil
+		}, th.AnyInlineQuery())
+
 		h.HandleMessage(func(ctx *th.Context, message telego.Message) error {
+			// In groups only an admin's reply to a pending prompt is handled;
+			// ordinary chatter is ignored.
+			if isGroupChat(message.Chat) {
+				if _, pending := userStates[message.Chat.ID]; !pending || message.From == nil || !checkAdmin(message.From.ID) {
+					return nil
+				}
+			}
 			if userState, exists := userStates[message.Chat.ID]; exists {
 				switch userState {
 				case "awaiting_email":
@@ -139,6 +161,40 @@ func (t *Tgbot) OnReceive() {
 					t.SendMsgToTgbotDeleteAfter(message.Chat.ID, t.I18nBot("tgbot.messages.userSaved"), 3, tu.ReplyKeyboardRemove())
 					delete(userStates, message.Chat.ID)
 					t.addClient(message.Chat.ID, t.BuildClientDraftMessage())
+				case stateAwaitingInboundLimit, stateAwaitingInboundExpiry:
+					if !checkAdmin(message.From.ID) {
+						delete(userStates, message.Chat.ID)
+						delete(inboundEdits, message.Chat.ID)
+						return nil
+					}
+					t.handleInboundEditInput(message.Chat.ID, userState, message.Text)
+				case stateAwaitingDisableReason:
+					email := disableTargets[message.Chat.ID]
+					delete(userStates, message.Chat.ID)
+					delete(disableTargets, message.Chat.ID)
+					if !checkAdmin(message.From.ID) {
+						return nil
+					}
+					t.disableClient(message.Chat.ID, email, message.Text, message.From.ID)
+				case stateAwaitingImportFile:
+					if !checkAdmin(message.From.ID) {
+						delete(userStates, message.Chat.ID)
+						delete(clientImports, message.Chat.ID)
+						return nil
+					}
+					t.receiveClientImport(message.Chat.ID, message.Document)
+				case stateAwaitingClientNote, stateAwaitingInboundNote:
+					target := noteTargets[message.Chat.ID]
+					delete(userStates, message.Chat.ID)
+					delete(noteTargets, message.Chat.ID)
+					if !checkAdmin(message.From.ID) {
+						return nil
+					}
+					if userState == stateAwaitingClientNote {
+						t.saveClientNote(message.Chat.ID, target, message.Text)
+					} else if inboundId, err := strconv.Atoi(target); err == nil {
+						t.saveInboundNote(message.Chat.ID, inboundId, message.Text)
+					}
 				}
 
 			} else {
@@ -174,10 +230,24 @@ func (t *Tgbot) OnReceive() {
 func (t *Tgbot) answerCommand(message *telego.Message, chatId int64, isAdmin bool) {
 	msg, onlyMessage := "", false
 
-	command, _, commandArgs := tu.ParseCommand(message.Text)
+	command, commandArgs := parseCommand(message.Text)
+	command = resolveCommandAlias(command)
+
+	start, outcome := time.Now(), "ok"
+	defer func() {
+		latency := time.Since(start)
+		if outcome != "unknown" {
+			recordCommandLatency(command, latency)
+		}
+		logBotEvent(botEvent{Event: "command", ChatID: chatId, Command: command, Outcome: outcome, Latency: latency})
+		if isAdmin && outcome != "unknown" && command != "history" {
+			recordCommandHistory(chatId, command, commandArgs, start)
+		}
+	}()
 
 	// Helper function to handle unknown commands.
 	handleUnknownCommand := func() {
+		outcome = "unknown"
 		msg += t.I18nBot("tgbot.commands.unknown")
 	}
 
@@ -187,21 +257,37 @@ func (t *Tgbot) answerCommand(message *telego.Message, chatId int64, isAdmin boo
 		msg += t.I18nBot("tgbot.commands.help")
 		msg += t.I18nBot("tgbot.commands.pleaseChoose")
 	case "start":
-		msg += t.I18nBot("tgbot.commands.start", "Firstname=="+html.EscapeString(message.From.FirstName))
-		if isAdmin {
-			msg += t.I18nBot("tgbot.commands.welcome", "Hostname=="+hostname)
+		if len(commandArgs) > 0 && strings.HasPrefix(commandArgs[0], quotaLinkPrefix) {
+			// Opened from a quota link: only that client's quota is shown.
+			onlyMessage = true
+			t.sendQuotaSelfCheck(chatId, commandArgs[0], message.From.ID)
+		} else {
+			msg += t.I18nBot("tgbot.commands.start", "Firstname=="+escapeField(message.From.FirstName))
+			if isAdmin {
+				msg += t.I18nBot("tgbot.commands.welcome", "Hostname=="+escapeField(hostname))
+			}
+			msg += "\n\n" + t.I18nBot("tgbot.commands.pleaseChoose")
 		}
-		msg += "\n\n" + t.I18nBot("tgbot.commands.pleaseChoose")
 	case "status":
-    onlyMessage = true
-    msg += t.buildRichStatus()
+		onlyMessage = true
+		if len(commandArgs) > 0 && isProtocolStatusArg(commandArgs[0]) {
+			msg += t.buildProtocolStatus()
+		} else {
+			msg += t.buildRichStatus()
+		}
 	case "id":
 		onlyMessage = true
 		msg += t.I18nBot("tgbot.commands.getID", "ID=="+strconv.FormatInt(message.From.ID, 10))
 	case "usage":
 		onlyMessage = true
 		if len(commandArgs) > 0 {
-			if isAdmin {
+			if isAdmin && len(commandArgs) > 1 {
+				if period, err := parseUsagePeriod(commandArgs[1]); err != nil {
+					msg += t.I18nBot("tgbot.messages.clientUsageUsage")
+				} else {
+					t.sendClientUsage(chatId, commandArgs[0], period)
+				}
+			} else if isAdmin {
 				t.searchClient(chatId, commandArgs[0])
 			} else {
 				t.getClientUsage(chatId, int64(message.From.ID), commandArgs[0])
@@ -209,6 +295,13 @@ func (t *Tgbot) answerCommand(message *telego.Message, chatId int64, isAdmin boo
 		} else {
 			msg += t.I18nBot("tgbot.commands.usage")
 		}
+	case "subscription":
+		onlyMessage = true
+		if len(commandArgs) > 0 {
+			t.sendSubscription(chatId, commandArgs[0], message.From.ID, isAdmin)
+		} else {
+			msg += t.I18nBot("tgbot.messages.subscriptionUsage")
+		}
 	case "inbound":
 		onlyMessage = true
 		if isAdmin && len(commandArgs) > 0 {
@@ -216,17 +309,494 @@ func (t *Tgbot) answerCommand(message *telego.Message, chatId int64, isAdmin boo
 		} else {
 			handleUnknownCommand()
 		}
-	case "restart":
+	case "test":
+		onlyMessage = true
+		if !isAdmin {
+			handleUnknownCommand()
+		} else if len(commandArgs) == 0 {
+			msg += t.I18nBot("tgbot.messages.testUsage")
+		} else {
+			t.sendInboundTest(chatId, commandArgs[0])
+		}
+	case "reconcile":
+		onlyMessage = true
+		if !isAdmin {
+			handleUnknownCommand()
+		} else if len(commandArgs) > 0 && strings.EqualFold(commandArgs[0], "history") {
+			t.sendReconcileHistory(chatId)
+		} else {
+			t.sendReconcile(chatId, message.From.ID)
+		}
+	case "cronstatus":
+		onlyMessage = true
+		if isAdmin {
+			t.sendCronStatus(chatId)
+		} else {
+			handleUnknownCommand()
+		}
+	case "perf":
+		onlyMessage = true
+		if isAdmin {
+			t.sendPerf(chatId)
+		} else {
+			handleUnknownCommand()
+		}
+	case "botstats":
+		onlyMessage = true
+		if isAdmin {
+			t.sendBotStats(chatId)
+		} else {
+			handleUnknownCommand()
+		}
+	case "blockip", "unblockip":
+		onlyMessage = true
+		if !isAdmin {
+			handleUnknownCommand()
+		} else if len(commandArgs) == 0 {
+			msg += t.I18nBot("tgbot.messages.blockIpUsage")
+		} else if command == "blockip" {
+			t.blockIp(chatId, commandArgs[0], message.From.ID)
+		} else {
+			t.unblockIp(chatId, commandArgs[0], message.From.ID)
+		}
+	case "blocklist":
+		onlyMessage = true
+		if isAdmin {
+			t.sendBlockList(chatId)
+		} else {
+			handleUnknownCommand()
+		}
+	case "addchat", "removechat":
+		onlyMessage = true
+		if !isAdmin {
+			handleUnknownCommand()
+		} else if len(commandArgs) == 0 {
+			msg += t.I18nBot("tgbot.messages.chatUsage")
+		} else if command == "addchat" {
+			t.addChat(chatId, commandArgs[0], message.From.ID)
+		} else {
+			t.removeChat(chatId, commandArgs[0], message.From.ID, false)
+		}
+	case "listchats":
+		onlyMessage = true
+		if isAdmin {
+			t.sendChatList(chatId, message.From.ID)
+		} else {
+			handleUnknownCommand()
+		}
+	case "testnotify":
+		onlyMessage = true
+		if !isAdmin {
+			handleUnknownCommand()
+		} else {
+			category := ""
+			if len(commandArgs) > 0 {
+				category = commandArgs[0]
+			}
+			t.sendTestNotification(chatId, category, message.From.ID)
+		}
+	case "botconfig":
+		onlyMessage = true
+		if !isAdmin {
+			handleUnknownCommand()
+		} else {
+			t.sendBotConfig(chatId)
+		}
+	case "schedule":
+		onlyMessage = true
+		if !isAdmin {
+			handleUnknownCommand()
+		} else {
+			t.handleScheduleCommand(chatId, message.Text, message.From.ID)
+		}
+	case "trend":
+		onlyMessage = true
+		if !isAdmin {
+			handleUnknownCommand()
+		} else {
+			t.sendTrend(chatId, commandArgs)
+		}
+	case "remind":
+		onlyMessage = true
+		if !isAdmin {
+			handleUnknownCommand()
+		} else {
+			t.addReminder(chatId, message.Text, message.From.ID)
+		}
+	case "reminders":
+		onlyMessage = true
+		if !isAdmin {
+			handleUnknownCommand()
+		} else {
+			t.sendReminders(chatId)
+		}
+	case "cancelreminder":
+		onlyMessage = true
+		if !isAdmin {
+			handleUnknownCommand()
+		} else if len(commandArgs) != 1 {
+			msg += t.I18nBot("tgbot.messages.cancelReminderUsage")
+		} else {
+			t.cancelReminder(chatId, commandArgs[0], message.From.ID)
+		}
+	case "alertstate":
+		onlyMessage = true
+		if !isAdmin {
+			handleUnknownCommand()
+		} else if len(commandArgs) == 0 {
+			t.sendAlertState(chatId)
+		} else if strings.ToLower(commandArgs[0]) != "clear" || len(commandArgs) > 2 {
+			msg += t.I18nBot("tgbot.messages.alertStateUsage")
+		} else if len(commandArgs) == 1 {
+			t.clearAlertState(chatId, "", message.From.ID)
+		} else {
+			t.clearAlertState(chatId, commandArgs[1], message.From.ID)
+		}
+	case "previewconfig":
+		onlyMessage = true
+		if !isAdmin {
+			handleUnknownCommand()
+		} else if len(commandArgs) > 1 || (len(commandArgs) == 1 && !strings.EqualFold(commandArgs[0], "file")) {
+			msg += t.I18nBot("tgbot.messages.previewConfigUsage")
+		} else {
+			t.sendConfigPreview(chatId, len(commandArgs) == 1, message.From.ID)
+		}
+	case "showinbound":
+		onlyMessage = true
+		if !isAdmin {
+			handleUnknownCommand()
+		} else if tag, full, ok := parseShowInboundArgs(commandArgs); !ok {
+			msg += t.I18nBot("tgbot.messages.showInboundUsage")
+		} else {
+			t.sendShowInbound(chatId, tag, full, message.From.ID)
+		}
+	case "rename":
+		onlyMessage = true
+		if !isAdmin {
+			handleUnknownCommand()
+		} else if tag, field, value, ok := parseRenameArgs(commandArgs); !ok {
+			msg += t.I18nBot("tgbot.messages.renameUsage")
+		} else {
+			t.promptRename(chatId, tag, field, value)
+		}
+	case "move":
+		onlyMessage = true
+		if !isAdmin {
+			handleUnknownCommand()
+		} else if email, toTag, fromTag, ok := parseMoveArgs(commandArgs); !ok {
+			msg += t.I18nBot("tgbot.messages.moveUsage")
+		} else {
+			t.promptClientMove(chatId, email, toTag, fromTag)
+		}
+	case "mute", "unmute":
+		onlyMessage = true
+		if !isAdmin {
+			handleUnknownCommand()
+		} else if len(commandArgs) != 1 {
+			msg += t.I18nBot("tgbot.messages.muteUsage")
+		} else if command == "mute" {
+			t.muteInbound(chatId, commandArgs[0], message.From.ID)
+		} else {
+			t.unmuteInbound(chatId, commandArgs[0], message.From.ID)
+		}
+	case "muted":
+		onlyMessage = true
+		if isAdmin {
+			t.sendMutedInbounds(chatId)
+		} else {
+			handleUnknownCommand()
+		}
+	case "pool":
+		onlyMessage = true
+		if !isAdmin {
+			handleUnknownCommand()
+		} else if len(commandArgs) != 1 {
+			msg += t.I18nBot("tgbot.messages.poolUsage")
+		} else {
+			t.sendInboundPool(chatId, commandArgs[0])
+		}
+	case "boost":
+		onlyMessage = true
+		if !isAdmin {
+			handleUnknownCommand()
+		} else if email, bytes, err := parseBoostArgs(commandArgs); err != nil {
+			msg += t.I18nBot("tgbot.messages.boostUsage")
+		} else {
+			t.grantTrafficBoost(chatId, email, bytes, message.From.ID)
+		}
+	case "unboost":
+		onlyMessage = true
+		if !isAdmin {
+			handleUnknownCommand()
+		} else if len(commandArgs) != 1 {
+			msg += t.I18nBot("tgbot.messages.unboostUsage")
+		} else {
+			t.revokeTrafficBoost(chatId, commandArgs[0], message.From.ID)
+		}
+	case "prunelogs":
+		onlyMessage = true
+		if !isAdmin {
+			handleUnknownCommand()
+		} else if days, err := parsePruneDays(commandArgs); err != nil {
+			msg += t.I18nBot("tgbot.messages.pruneLogsUsage")
+		} else {
+			t.pruneHistory(chatId, days, message.From.ID)
+		}
+	case "dormant":
+		onlyMessage = true
+		if !isAdmin {
+			handleUnknownCommand()
+		} else if days, err := parseDormantDays(commandArgs); err != nil {
+			msg += t.I18nBot("tgbot.messages.dormantUsage")
+		} else {
+			t.sendDormantClients(chatId, days, 1)
+		}
+	case "expiring":
+		onlyMessage = true
+		if !isAdmin {
+			handleUnknownCommand()
+		} else if days, withClients, err := parseExpiringArgs(commandArgs); err != nil {
+			msg += t.I18nBot("tgbot.messages.expiringUsage")
+		} else {
+			t.sendExpiring(chatId, days, withClients, 1)
+		}
+	case "import":
+		onlyMessage = true
+		if !isAdmin {
+			handleUnknownCommand()
+		} else if tag, partial, ok := parseImportArgs(commandArgs); !ok {
+			msg += t.I18nBot("tgbot.messages.importUsage")
+		} else {
+			t.promptClientImport(chatId, tag, partial)
+		}
+	case "panel":
+		onlyMessage = true
+		if !isAdmin {
+			handleUnknownCommand()
+		} else {
+			t.sendPanelLink(chatId)
+		}
+	case "connections":
+		onlyMessage = true
+		if !isAdmin {
+			handleUnknownCommand()
+		} else {
+			t.sendConnections(chatId)
+		}
+	case "events":
+		onlyMessage = true
+		if !isAdmin {
+			handleUnknownCommand()
+		} else if n, err := parseEventsArgs(commandArgs); err != nil {
+			msg += t.I18nBot("tgbot.messages.eventsUsage")
+		} else {
+			t.sendEvents(chatId, n, 1)
+		}
+	case "sessions":
+		onlyMessage = true
+		if !isAdmin {
+			handleUnknownCommand()
+		} else if minAge, err := parseSessionsArgs(commandArgs); err != nil {
+			msg += t.I18nBot("tgbot.messages.sessionsUsage")
+		} else {
+			t.sendSessions(chatId, minAge)
+		}
+	case "terminate":
+		onlyMessage = true
+		if !isAdmin {
+			handleUnknownCommand()
+		} else if len(commandArgs) != 1 {
+			msg += t.I18nBot("tgbot.messages.terminateUsage")
+		} else {
+			t.confirmTerminate(chatId, commandArgs[0])
+		}
+	case "thresholds":
+		onlyMessage = true
+		if !isAdmin {
+			handleUnknownCommand()
+		} else if tag, changes, ok := parseThresholdsArgs(commandArgs); !ok {
+			msg += t.I18nBot("tgbot.messages.thresholdsUsage")
+		} else {
+			t.handleThresholdsCommand(chatId, tag, changes, message.From.ID)
+		}
+	case "iplimit":
+		onlyMessage = true
+		if !isAdmin {
+			handleUnknownCommand()
+		} else if email, limit, set, err := parseIpLimitArgs(commandArgs); err != nil {
+			msg += t.I18nBot("tgbot.messages.ipLimitUsage")
+		} else if set {
+			t.setIpLimit(chatId, email, limit, message.From.ID)
+		} else if email != "" {
+			t.sendIpLimit(chatId, email)
+		} else {
+			t.sendIpLimitOverview(chatId)
+		}
+	case "whois":
+		onlyMessage = true
+		if !isAdmin {
+			handleUnknownCommand()
+		} else if credential, ok := parseWhoisArgs(commandArgs); !ok {
+			msg += t.I18nBot("tgbot.messages.whoisUsage")
+		} else {
+			t.sendWhois(chatId, credential, message.From.ID)
+		}
+	case "geoinfo":
+		onlyMessage = true
+		if !isAdmin {
+			handleUnknownCommand()
+		} else if file, ok := parseGeoInfoArgs(commandArgs); !ok {
+			msg += t.I18nBot("tgbot.messages.geoInfoUsage")
+		} else {
+			t.sendGeoInfo(chatId, file)
+		}
+	case "debugstats":
+		onlyMessage = true
+		if !isAdmin {
+			handleUnknownCommand()
+		} else if profile, ok := parseDebugStatsArgs(commandArgs); !ok {
+			msg += t.I18nBot("tgbot.messages.debugStatsUsage")
+		} else {
+			t.sendDebugStats(chatId, profile, message.From.ID)
+		}
+	case "errors":
+		onlyMessage = true
+		if !isAdmin {
+			handleUnknownCommand()
+		} else if n, ok := parseErrorsArgs(commandArgs); !ok {
+			msg += t.I18nBot("tgbot.messages.xrayErrorsUsage")
+		} else {
+			t.sendXrayErrors(chatId, n)
+		}
+	case "certs":
+		onlyMessage = true
+		if !isAdmin {
+			handleUnknownCommand()
+		} else if days, ok := parseCertsArgs(commandArgs); !ok {
+			msg += t.I18nBot("tgbot.messages.certsUsage")
+		} else {
+			t.sendCerts(chatId, days)
+		}
+	case "history":
+		onlyMessage = true
+		if isAdmin {
+			t.sendHistory(chatId, 1)
+		} else {
+			handleUnknownCommand()
+		}
+	case "getsetting":
+		onlyMessage = true
+		if !isAdmin {
+			handleUnknownCommand()
+		} else if len(commandArgs) != 1 {
+			msg += t.I18nBot("tgbot.messages.getSettingUsage")
+		} else {
+			t.sendSetting(chatId, commandArgs[0])
+		}
+	case "setsetting":
+		onlyMessage = true
+		if !isAdmin {
+			handleUnknownCommand()
+		} else if len(commandArgs) < 2 {
+			msg += t.I18nBot("tgbot.messages.setSettingUsage", "Keys=="+strings.Join(settableSettingKeys(), ", "))
+		} else {
+			t.confirmSettingChange(chatId, commandArgs[0], strings.Join(commandArgs[1:], " "))
+		}
+	case "reportschedule":
+		onlyMessage = true
+		if isAdmin {
+			t.sendReportSchedule(chatId, strings.Join(commandArgs, " "))
+		} else {
+			handleUnknownCommand()
+		}
+	case "reportnow":
+		onlyMessage = true
+		if isAdmin {
+			t.sendReportNow(chatId, message.From.ID)
+		} else {
+			handleUnknownCommand()
+		}
+	case "reportpreview":
+		onlyMessage = true
+		if isAdmin {
+			t.sendReportPreview(chatId)
+		} else {
+			handleUnknownCommand()
+		}
+	case "export":
+		onlyMessage = true
+		if isAdmin {
+			t.sendExport(chatId)
+		} else {
+			handleUnknownCommand()
+		}
+	case "reportconfig":
+		onlyMessage = true
+		if !isAdmin {
+			handleUnknownCommand()
+		} else if len(commandArgs) == 0 {
+			t.sendReportConfig(chatId)
+		} else {
+			t.setReportSections(chatId, strings.Join(commandArgs, ","), message.From.ID)
+		}
+	case "server":
+		onlyMessage = true
+		if isAdmin {
+			t.sendServerInfo(chatId)
+		} else {
+			handleUnknownCommand()
+		}
+	case "iplogging":
+		onlyMessage = true
+		if !isAdmin {
+			handleUnknownCommand()
+		} else if enable, set, err := parseIPLoggingArgs(commandArgs); err != nil {
+			msg += t.I18nBot("tgbot.messages.ipLoggingUsage")
+		} else if set {
+			t.setIPLogging(chatId, enable, message.From.ID)
+		} else {
+			t.sendIPLogging(chatId)
+		}
+	case "loglevel":
+		onlyMessage = true
+		if !isAdmin {
+			handleUnknownCommand()
+		} else if level, set, err := parseLogLevelArgs(commandArgs); err != nil {
+			msg += t.I18nBot("tgbot.messages.logLevelUsage")
+		} else if set {
+			t.setLogLevel(chatId, level, message.From.ID)
+		} else {
+			t.sendLogLevel(chatId)
+		}
+	case "reloadrules":
+		onlyMessage = true
+		if isAdmin {
+			t.reloadRoutingRules(chatId, message.From.ID)
+		} else {
+			handleUnknownCommand()
+		}
+	case "quotalink":
+		onlyMessage = true
+		if !isAdmin {
+			handleUnknownCommand()
+		} else if email, days, ok := parseQuotaLinkArgs(commandArgs); !ok {
+			msg += t.I18nBot("tgbot.messages.quotaLinkUsage", "Days=="+strconv.Itoa(defaultQuotaLinkDays), "Max=="+strconv.Itoa(maxQuotaLinkDays))
+		} else {
+			t.sendQuotaLink(chatId, email, days, message.From.ID)
+		}
+	case "reloadsettings":
+		onlyMessage = true
+		if isAdmin {
+			t.reloadSettings(chatId, message.From.ID)
+		} else {
+			handleUnknownCommand()
+		}
+	case "restart", "restartxray":
 		onlyMessage = true
 		if isAdmin {
 			if len(commandArgs) == 0 {
 				if t.xrayService.IsXrayRunning() {
-					err := t.xrayService.RestartXray(true)
-					if err != nil {
-						msg += t.I18nBot("tgbot.commands.restartFailed", "Error=="+err.Error())
-					} else {
-						msg += t.I18nBot("tgbot.commands.restartSuccess")
-					}
+					t.confirmRestartXray(chatId)
 				} else {
 					msg += t.I18nBot("tgbot.commands.xrayNotRunning")
 				}
@@ -247,9 +817,32 @@ func (t *Tgbot) answerCommand(message *telego.Message, chatId int64, isAdmin boo
 }
 
 func (t *Tgbot) isCommandForCurrentBot(message *telego.Message) bool {
+	if isGroupChat(message.Chat) {
+		return isGroupCommandForBot(message, botUsername())
+	}
 	return isCommandForBot(message.Text, botUsername())
 }
 
+// isGroupChat reports whether the chat is a group or supergroup.
+func isGroupChat(chat telego.Chat) bool {
+	return chat.Type == telego.ChatTypeGroup || chat.Type == telego.ChatTypeSupergroup
+}
+
+// isGroupCommandForBot reports whether a group command is addressed to this
+// bot, either via the "@botusername" suffix or as a reply to one of its
+// messages. Bare commands in busy groups are usually meant for other bots.
+func isGroupCommandForBot(message *telego.Message, username string) bool {
+	_, commandUsername, _ := tu.ParseCommand(message.Text)
+	if commandUsername != "" {
+		return username == "" || strings.EqualFold(commandUsername, username)
+	}
+	reply := message.ReplyToMessage
+	if reply == nil || reply.From == nil || !reply.From.IsBot {
+		return false
+	}
+	return username == "" || strings.EqualFold(reply.From.Username, username)
+}
+
 func botUsername() string {
 	if bot == nil {
 		return ""
@@ -257,6 +850,15 @@ func botUsername() string {
 	return bot.Username()
 }
 
+// parseCommand splits a command message into its base command and
+// whitespace-separated arguments. The "@botusername" suffix Telegram adds in
+// groups is dropped, and the command is lower-cased so "/Status" dispatches
+// like "/status", matching the case-insensitive telego predicates.
+func parseCommand(text string) (string, []string) {
+	command, _, args := tu.ParseCommand(text)
+	return strings.ToLower(command), args
+}
+
 func isCommandForBot(text string, username string) bool {
 	_, commandUsername, _ := tu.ParseCommand(text)
 	return commandUsername == "" || username == "" || strings.EqualFold(commandUsername, username)
@@ -266,6 +868,14 @@ func isCommandForBot(text string, username string) bool {
 func (t *Tgbot) answerCallback(callbackQuery *telego.CallbackQuery, isAdmin bool) {
 	chatId := callbackQuery.Message.GetChat().ID
 
+	data, ok := t.verifyCallbackData(callbackQuery.Data, time.Now())
+	if !ok {
+		logBotEvent(botEvent{Event: "callback_expired", ChatID: callbackQuery.From.ID, Command: callbackAction(callbackQuery.Data)})
+		t.answerCallbackTgBot(callbackQuery.ID, t.I18nBot("tgbot.answers.actionExpired"), true)
+		return
+	}
+	callbackQuery.Data = data
+
 	if isAdmin {
 		// get query from hash storage
 		decodedQuery, err := t.decodeQuery(callbackQuery.Data)
@@ -274,6 +884,10 @@ func (t *Tgbot) answerCallback(callbackQuery *telego.CallbackQuery, isAdmin bool
 			return
 		}
 		dataArray := strings.Split(decodedQuery, " ")
+		if strings.HasPrefix(dataArray[0], "rs_") {
+			t.handleReportScheduleCallback(callbackQuery, dataArray)
+			return
+		}
 
 		if len(dataArray) >= 2 && len(dataArray[1]) > 0 {
 			email := dataArray[1]
@@ -282,52 +896,255 @@ func (t *Tgbot) answerCallback(callbackQuery *telego.CallbackQuery, isAdmin bool
 				inboundId := dataArray[1]
 				inboundIdInt, err := strconv.Atoi(inboundId)
 				if err != nil {
-					t.sendCallbackAnswerTgBot(callbackQuery.ID, err.Error())
+					t.answerCallbackTgBot(callbackQuery.ID, err.Error(), true)
 					return
 				}
 				clientsKB, err := t.getInboundClientsFor(inboundIdInt, "client_sub_links")
 				if err != nil {
-					t.sendCallbackAnswerTgBot(callbackQuery.ID, err.Error())
+					t.answerCallbackTgBot(callbackQuery.ID, err.Error(), true)
 					return
 				}
 				inbound, _ := t.inboundService.GetInbound(inboundIdInt)
-				t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.answers.chooseClient", "Inbound=="+inbound.Remark), clientsKB)
+				t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.answers.chooseClient", "Inbound=="+escapeField(inbound.Remark)), clientsKB)
 			case "get_clients_for_individual":
 				inboundId := dataArray[1]
 				inboundIdInt, err := strconv.Atoi(inboundId)
 				if err != nil {
-					t.sendCallbackAnswerTgBot(callbackQuery.ID, err.Error())
+					t.answerCallbackTgBot(callbackQuery.ID, err.Error(), true)
 					return
 				}
 				clientsKB, err := t.getInboundClientsFor(inboundIdInt, "client_individual_links")
 				if err != nil {
-					t.sendCallbackAnswerTgBot(callbackQuery.ID, err.Error())
+					t.answerCallbackTgBot(callbackQuery.ID, err.Error(), true)
 					return
 				}
 				inbound, _ := t.inboundService.GetInbound(inboundIdInt)
-				t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.answers.chooseClient", "Inbound=="+inbound.Remark), clientsKB)
+				t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.answers.chooseClient", "Inbound=="+escapeField(inbound.Remark)), clientsKB)
 			case "get_clients_for_qr":
 				inboundId := dataArray[1]
 				inboundIdInt, err := strconv.Atoi(inboundId)
 				if err != nil {
-					t.sendCallbackAnswerTgBot(callbackQuery.ID, err.Error())
+					t.answerCallbackTgBot(callbackQuery.ID, err.Error(), true)
 					return
 				}
 				clientsKB, err := t.getInboundClientsFor(inboundIdInt, "client_qr_links")
 				if err != nil {
-					t.sendCallbackAnswerTgBot(callbackQuery.ID, err.Error())
+					t.answerCallbackTgBot(callbackQuery.ID, err.Error(), true)
 					return
 				}
 				inbound, _ := t.inboundService.GetInbound(inboundIdInt)
-				t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.answers.chooseClient", "Inbound=="+inbound.Remark), clientsKB)
+				t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.answers.chooseClient", "Inbound=="+escapeField(inbound.Remark)), clientsKB)
 			case "client_sub_links":
 				t.sendClientSubLinks(chatId, email)
 				return
 			case "client_individual_links":
-				t.sendClientIndividualLinks(chatId, email)
+				t.sendClientIndividualLinks(chatId, email, linkFlavorCompat)
 				return
 			case "client_qr_links":
-				t.sendClientQRLinks(chatId, email)
+				t.sendClientQRLinks(chatId, email, linkFlavorCompat)
+				return
+			case "client_links_flavor":
+				t.sendLinksInFlavor(chatId, strings.TrimPrefix(decodedQuery, "client_links_flavor "), callbackQuery.From.ID, isAdmin)
+				return
+			case "client_sub_qr":
+				t.sendCallbackAnswerTgBot(callbackQuery.ID, t.I18nBot("qrCode"))
+				t.sendSubscriptionQR(chatId, email, callbackQuery.From.ID, isAdmin)
+				return
+			case "client_note":
+				t.sendCallbackAnswerTgBot(callbackQuery.ID, t.I18nBot("tgbot.messages.email", "Email=="+email))
+				t.promptClientNote(chatId, email)
+				return
+			case "client_disable":
+				reason, ok := "", false
+				if len(dataArray) == 3 {
+					reason, ok = disableReasonPresets[dataArray[2]]
+				}
+				if !ok {
+					t.answerCallbackTgBot(callbackQuery.ID, t.I18nBot("tgbot.answers.errorOperation"), true)
+					return
+				}
+				t.sendCallbackAnswerTgBot(callbackQuery.ID, t.I18nBot("tgbot.answers.disableSuccess", "Email=="+email))
+				t.disableClient(chatId, email, reason, callbackQuery.From.ID, callbackQuery.Message.GetMessageID())
+				return
+			case "client_disable_custom":
+				t.sendCallbackAnswerTgBot(callbackQuery.ID, t.I18nBot("tgbot.messages.email", "Email=="+email))
+				t.promptCustomDisableReason(chatId, email)
+				return
+			case "client_note_clear":
+				t.sendCallbackAnswerTgBot(callbackQuery.ID, t.I18nBot("tgbot.messages.noteSaved"))
+				t.saveClientNote(chatId, email, "")
+				return
+			case "inbound_note", "inbound_note_clear":
+				inboundId, err := strconv.Atoi(dataArray[1])
+				if err != nil {
+					t.answerCallbackTgBot(callbackQuery.ID, err.Error(), true)
+					return
+				}
+				if dataArray[0] == "inbound_note" {
+					t.sendCallbackAnswerTgBot(callbackQuery.ID, t.I18nBot("tgbot.buttons.note"))
+					t.promptInboundNote(chatId, inboundId)
+				} else {
+					t.sendCallbackAnswerTgBot(callbackQuery.ID, t.I18nBot("tgbot.messages.noteSaved"))
+					t.saveInboundNote(chatId, inboundId, "")
+				}
+				return
+			case "inbound_edit", "inbound_edit_keep", "inbound_edit_save", "inbound_edit_cancel":
+				inboundId, err := strconv.Atoi(dataArray[1])
+				if err != nil {
+					t.answerCallbackTgBot(callbackQuery.ID, err.Error(), true)
+					return
+				}
+				t.sendCallbackAnswerTgBot(callbackQuery.ID, t.I18nBot("tgbot.buttons.editLimits"))
+				switch dataArray[0] {
+				case "inbound_edit":
+					t.startInboundEdit(chatId, inboundId)
+				case "inbound_edit_keep":
+					t.keepInboundEditValue(chatId, inboundId)
+				case "inbound_edit_save":
+					t.saveInboundEdit(chatId, inboundId, callbackQuery.From.ID)
+				default:
+					t.cancelInboundEdit(chatId)
+				}
+				return
+			case "inbound_rename", "inbound_rename_cancel":
+				inboundId, err := strconv.Atoi(dataArray[1])
+				if err != nil {
+					t.answerCallbackTgBot(callbackQuery.ID, err.Error(), true)
+					return
+				}
+				if dataArray[0] == "inbound_rename" {
+					t.sendCallbackAnswerTgBot(callbackQuery.ID, t.I18nBot("tgbot.buttons.confirmRename"))
+					t.applyRename(chatId, inboundId, callbackQuery.From.ID)
+				} else {
+					t.sendCallbackAnswerTgBot(callbackQuery.ID, t.I18nBot("tgbot.buttons.cancel"))
+					t.cancelRename(chatId)
+				}
+				return
+			case "client_import":
+				inboundId, err := strconv.Atoi(dataArray[1])
+				if err != nil {
+					t.answerCallbackTgBot(callbackQuery.ID, t.I18nBot("tgbot.answers.errorOperation"), true)
+					return
+				}
+				t.sendCallbackAnswerTgBot(callbackQuery.ID, t.I18nBot("tgbot.answers.importStarted"))
+				t.applyClientImport(chatId, inboundId, callbackQuery.From.ID)
+				return
+			case "client_move":
+				toId, err := strconv.Atoi(dataArray[1])
+				if err != nil || len(dataArray) < 3 {
+					t.answerCallbackTgBot(callbackQuery.ID, t.I18nBot("tgbot.answers.errorOperation"), true)
+					return
+				}
+				t.sendCallbackAnswerTgBot(callbackQuery.ID, t.I18nBot("tgbot.answers.moveStarted"))
+				t.applyClientMove(chatId, toId, dataArray[2] == "1", callbackQuery.From.ID)
+				return
+			case "dormant_page", "dormant_disable", "dormant_disable_confirm":
+				days, err := strconv.Atoi(dataArray[1])
+				if err != nil {
+					t.answerCallbackTgBot(callbackQuery.ID, err.Error(), true)
+					return
+				}
+				switch dataArray[0] {
+				case "dormant_page":
+					page := 1
+					if len(dataArray) > 2 {
+						page, _ = strconv.Atoi(dataArray[2])
+					}
+					t.sendCallbackAnswerTgBot(callbackQuery.ID, t.I18nBot("tgbot.answers.successfulOperation"))
+					t.sendDormantClients(chatId, days, page, callbackQuery.Message.GetMessageID())
+				case "dormant_disable":
+					t.sendCallbackAnswerTgBot(callbackQuery.ID, t.I18nBot("tgbot.buttons.disableDormant"))
+					t.confirmDisableDormant(chatId, days)
+				default:
+					t.sendCallbackAnswerTgBot(callbackQuery.ID, t.I18nBot("tgbot.buttons.confirmDisableDormant"))
+					t.disableDormantClients(chatId, days, callbackQuery.From.ID)
+				}
+				return
+			case "events_page":
+				n, err := strconv.Atoi(dataArray[1])
+				if err != nil {
+					t.answerCallbackTgBot(callbackQuery.ID, err.Error(), true)
+					return
+				}
+				page := 1
+				if len(dataArray) > 2 {
+					page, _ = strconv.Atoi(dataArray[2])
+				}
+				t.sendCallbackAnswerTgBot(callbackQuery.ID, t.I18nBot("tgbot.answers.successfulOperation"))
+				t.sendEvents(chatId, n, page, callbackQuery.Message.GetMessageID())
+				return
+			case "history_page":
+				page, err := strconv.Atoi(dataArray[1])
+				if err != nil {
+					t.answerCallbackTgBot(callbackQuery.ID, err.Error(), true)
+					return
+				}
+				t.sendCallbackAnswerTgBot(callbackQuery.ID, t.I18nBot("tgbot.answers.successfulOperation"))
+				t.sendHistory(chatId, page, callbackQuery.Message.GetMessageID())
+				return
+			case "history_rerun":
+				seq, err := strconv.ParseUint(dataArray[1], 10, 64)
+				if err != nil {
+					t.answerCallbackTgBot(callbackQuery.ID, err.Error(), true)
+					return
+				}
+				entry, ok := historyEntryBySeq(chatId, seq)
+				if !ok || !isRerunnable(entry.Command, entry.Args) {
+					t.sendCallbackAnswerTgBot(callbackQuery.ID, t.I18nBot("tgbot.messages.historyGone"))
+					return
+				}
+				t.sendCallbackAnswerTgBot(callbackQuery.ID, t.I18nBot("tgbot.answers.successfulOperation"))
+				t.rerunHistory(callbackQuery.Message.GetChat(), callbackQuery.From, entry)
+				return
+			case "expiring_page":
+				days, err := strconv.Atoi(dataArray[1])
+				if err != nil || len(dataArray) < 4 {
+					t.answerCallbackTgBot(callbackQuery.ID, t.I18nBot("tgbot.answers.errorOperation"), true)
+					return
+				}
+				page, _ := strconv.Atoi(dataArray[3])
+				t.sendCallbackAnswerTgBot(callbackQuery.ID, t.I18nBot("tgbot.answers.successfulOperation"))
+				t.sendExpiring(chatId, days, dataArray[2] == "1", page, callbackQuery.Message.GetMessageID())
+				return
+			case "remove_chat_confirm":
+				t.sendCallbackAnswerTgBot(callbackQuery.ID, dataArray[1])
+				t.removeChat(chatId, dataArray[1], callbackQuery.From.ID, true)
+				return
+			case "remove_chat_cancel":
+				t.sendCallbackAnswerTgBot(callbackQuery.ID, t.I18nBot("tgbot.answers.canceled", "Email=="+dataArray[1]))
+				return
+			case "terminate_c":
+				t.sendCallbackAnswerTgBot(callbackQuery.ID, t.I18nBot("tgbot.buttons.confirmTerminate"))
+				t.terminateConnections(chatId, email, callbackQuery.From.ID)
+				return
+			case "show_inbound_full":
+				t.sendCallbackAnswerTgBot(callbackQuery.ID, t.I18nBot("tgbot.buttons.confirmShowInbound"))
+				t.deleteMessageTgBot(chatId, callbackQuery.Message.GetMessageID())
+				t.showInboundFull(chatId, dataArray[1], callbackQuery.From.ID)
+				return
+			case "show_inbound_cancel":
+				t.sendCallbackAnswerTgBot(callbackQuery.ID, t.I18nBot("tgbot.answers.cancelDone"))
+				t.deleteMessageTgBot(chatId, callbackQuery.Message.GetMessageID())
+				return
+			case "undo_reset":
+				seq, err := strconv.ParseUint(dataArray[1], 10, 64)
+				if err != nil {
+					t.answerCallbackTgBot(callbackQuery.ID, t.I18nBot("tgbot.answers.errorOperation"), true)
+					return
+				}
+				t.sendCallbackAnswerTgBot(callbackQuery.ID, t.I18nBot("tgbot.buttons.undo"))
+				t.undoReset(chatId, callbackQuery.Message.GetMessageID(), seq, callbackQuery.From.ID)
+				return
+			case "terminate_cancel":
+				t.sendCallbackAnswerTgBot(callbackQuery.ID, t.I18nBot("tgbot.answers.canceled", "Email=="+email))
+				return
+			case "alert_ack":
+				t.acknowledgeAlert(callbackQuery, dataArray[1])
+				return
+			case "block_ip":
+				ip := dataArray[1]
+				t.sendCallbackAnswerTgBot(callbackQuery.ID, ip)
+				t.blockIp(chatId, ip, callbackQuery.From.ID)
 				return
 			case "client_get_usage":
 				t.sendCallbackAnswerTgBot(callbackQuery.ID, t.I18nBot("tgbot.messages.email", "Email=="+email))
@@ -361,12 +1178,14 @@ func (t *Tgbot) answerCallback(callbackQuery *telego.CallbackQuery, isAdmin bool
 				)
 				t.editMessageCallbackTgBot(chatId, callbackQuery.Message.GetMessageID(), inlineKeyboard)
 			case "reset_traffic_c":
+				snapshot := t.snapshotClients(email)
 				err := t.inboundService.ResetClientTrafficByEmail(email)
 				if err == nil {
 					t.sendCallbackAnswerTgBot(callbackQuery.ID, t.I18nBot("tgbot.answers.resetTrafficSuccess", "Email=="+email))
 					t.searchClient(chatId, email, callbackQuery.Message.GetMessageID())
+					t.offerUndo(chatId, t.I18nBot("tgbot.messages.SuccessResetTraffic", "ClientEmail=="+escapeField(email)), snapshot)
 				} else {
-					t.sendCallbackAnswerTgBot(callbackQuery.ID, t.I18nBot("tgbot.answers.errorOperation"))
+					t.answerCallbackTgBot(callbackQuery.ID, t.I18nBot("tgbot.answers.errorOperation"), true)
 				}
 			case "limit_traffic":
 				inlineKeyboard := tu.InlineKeyboard(
@@ -414,7 +1233,7 @@ func (t *Tgbot) answerCallback(callbackQuery *telego.CallbackQuery, isAdmin bool
 						}
 					}
 				}
-				t.sendCallbackAnswerTgBot(callbackQuery.ID, t.I18nBot("tgbot.answers.errorOperation"))
+				t.answerCallbackTgBot(callbackQuery.ID, t.I18nBot("tgbot.answers.errorOperation"), true)
 				t.searchClient(chatId, email, callbackQuery.Message.GetMessageID())
 			case "limit_traffic_in":
 				if len(dataArray) >= 3 {
@@ -440,7 +1259,7 @@ func (t *Tgbot) answerCallback(callbackQuery *telego.CallbackQuery, isAdmin bool
 								return
 							}
 							if inputNumber >= 999999 {
-								t.sendCallbackAnswerTgBot(callbackQuery.ID, t.I18nBot("tgbot.answers.errorOperation"))
+								t.answerCallbackTgBot(callbackQuery.ID, t.I18nBot("tgbot.answers.errorOperation"), true)
 								return
 							}
 						}
@@ -476,7 +1295,7 @@ func (t *Tgbot) answerCallback(callbackQuery *telego.CallbackQuery, isAdmin bool
 						return
 					}
 				}
-				t.sendCallbackAnswerTgBot(callbackQuery.ID, t.I18nBot("tgbot.answers.errorOperation"))
+				t.answerCallbackTgBot(callbackQuery.ID, t.I18nBot("tgbot.answers.errorOperation"), true)
 				t.searchClient(chatId, email, callbackQuery.Message.GetMessageID())
 			case "add_client_limit_traffic_c":
 				limitTraffic, _ := strconv.ParseInt(dataArray[1], 10, 64)
@@ -510,7 +1329,7 @@ func (t *Tgbot) answerCallback(callbackQuery *telego.CallbackQuery, isAdmin bool
 								return
 							}
 							if inputNumber >= 999999 {
-								t.sendCallbackAnswerTgBot(callbackQuery.ID, t.I18nBot("tgbot.answers.errorOperation"))
+								t.answerCallbackTgBot(callbackQuery.ID, t.I18nBot("tgbot.answers.errorOperation"), true)
 								return
 							}
 						}
@@ -614,7 +1433,7 @@ func (t *Tgbot) answerCallback(callbackQuery *telego.CallbackQuery, isAdmin bool
 						}
 					}
 				}
-				t.sendCallbackAnswerTgBot(callbackQuery.ID, t.I18nBot("tgbot.answers.errorOperation"))
+				t.answerCallbackTgBot(callbackQuery.ID, t.I18nBot("tgbot.answers.errorOperation"), true)
 				t.searchClient(chatId, email, callbackQuery.Message.GetMessageID())
 			case "reset_exp_in":
 				if len(dataArray) >= 3 {
@@ -640,7 +1459,7 @@ func (t *Tgbot) answerCallback(callbackQuery *telego.CallbackQuery, isAdmin bool
 								return
 							}
 							if inputNumber >= 999999 {
-								t.sendCallbackAnswerTgBot(callbackQuery.ID, t.I18nBot("tgbot.answers.errorOperation"))
+								t.answerCallbackTgBot(callbackQuery.ID, t.I18nBot("tgbot.answers.errorOperation"), true)
 								return
 							}
 						}
@@ -676,7 +1495,7 @@ func (t *Tgbot) answerCallback(callbackQuery *telego.CallbackQuery, isAdmin bool
 						return
 					}
 				}
-				t.sendCallbackAnswerTgBot(callbackQuery.ID, t.I18nBot("tgbot.answers.errorOperation"))
+				t.answerCallbackTgBot(callbackQuery.ID, t.I18nBot("tgbot.answers.errorOperation"), true)
 				t.searchClient(chatId, email, callbackQuery.Message.GetMessageID())
 			case "add_client_reset_exp_c":
 				client_ExpiryTime = 0
@@ -722,7 +1541,7 @@ func (t *Tgbot) answerCallback(callbackQuery *telego.CallbackQuery, isAdmin bool
 								return
 							}
 							if inputNumber >= 999999 {
-								t.sendCallbackAnswerTgBot(callbackQuery.ID, t.I18nBot("tgbot.answers.errorOperation"))
+								t.answerCallbackTgBot(callbackQuery.ID, t.I18nBot("tgbot.answers.errorOperation"), true)
 								return
 							}
 						}
@@ -802,7 +1621,7 @@ func (t *Tgbot) answerCallback(callbackQuery *telego.CallbackQuery, isAdmin bool
 						}
 					}
 				}
-				t.sendCallbackAnswerTgBot(callbackQuery.ID, t.I18nBot("tgbot.answers.errorOperation"))
+				t.answerCallbackTgBot(callbackQuery.ID, t.I18nBot("tgbot.answers.errorOperation"), true)
 				t.searchClient(chatId, email, callbackQuery.Message.GetMessageID())
 			case "ip_limit_in":
 				if len(dataArray) >= 3 {
@@ -828,7 +1647,7 @@ func (t *Tgbot) answerCallback(callbackQuery *telego.CallbackQuery, isAdmin bool
 								return
 							}
 							if inputNumber >= 999999 {
-								t.sendCallbackAnswerTgBot(callbackQuery.ID, t.I18nBot("tgbot.answers.errorOperation"))
+								t.answerCallbackTgBot(callbackQuery.ID, t.I18nBot("tgbot.answers.errorOperation"), true)
 								return
 							}
 						}
@@ -864,7 +1683,7 @@ func (t *Tgbot) answerCallback(callbackQuery *telego.CallbackQuery, isAdmin bool
 						return
 					}
 				}
-				t.sendCallbackAnswerTgBot(callbackQuery.ID, t.I18nBot("tgbot.answers.errorOperation"))
+				t.answerCallbackTgBot(callbackQuery.ID, t.I18nBot("tgbot.answers.errorOperation"), true)
 				t.searchClient(chatId, email, callbackQuery.Message.GetMessageID())
 			case "add_client_ip_limit_c":
 				if len(dataArray) == 2 {
@@ -901,7 +1720,7 @@ func (t *Tgbot) answerCallback(callbackQuery *telego.CallbackQuery, isAdmin bool
 								return
 							}
 							if inputNumber >= 999999 {
-								t.sendCallbackAnswerTgBot(callbackQuery.ID, t.I18nBot("tgbot.answers.errorOperation"))
+								t.answerCallbackTgBot(callbackQuery.ID, t.I18nBot("tgbot.answers.errorOperation"), true)
 								return
 							}
 						}
@@ -953,7 +1772,13 @@ func (t *Tgbot) answerCallback(callbackQuery *telego.CallbackQuery, isAdmin bool
 					t.sendCallbackAnswerTgBot(callbackQuery.ID, t.I18nBot("tgbot.answers.clearIpSuccess", "Email=="+email))
 					t.searchClientIps(chatId, email, callbackQuery.Message.GetMessageID())
 				} else {
-					t.sendCallbackAnswerTgBot(callbackQuery.ID, t.I18nBot("tgbot.answers.errorOperation"))
+					t.answerCallbackTgBot(callbackQuery.ID, t.I18nBot("tgbot.answers.errorOperation"), true)
+				}
+			case "ip_logging_on", "ip_logging_off":
+				enable := dataArray[0] == "ip_logging_on"
+				t.sendCallbackAnswerTgBot(callbackQuery.ID, t.I18nBot("tgbot.messages.email", "Email=="+email))
+				if t.setIPLogging(chatId, enable, callbackQuery.From.ID) {
+					t.searchClientIps(chatId, email, callbackQuery.Message.GetMessageID())
 				}
 			case "ip_log":
 				t.sendCallbackAnswerTgBot(callbackQuery.ID, t.I18nBot("tgbot.answers.getIpLog", "Email=="+email))
@@ -974,7 +1799,7 @@ func (t *Tgbot) answerCallback(callbackQuery *telego.CallbackQuery, isAdmin bool
 			case "tgid_remove_c":
 				traffic, err := t.inboundService.GetClientTrafficByEmail(email)
 				if err != nil || traffic == nil {
-					t.sendCallbackAnswerTgBot(callbackQuery.ID, t.I18nBot("tgbot.answers.errorOperation"))
+					t.answerCallbackTgBot(callbackQuery.ID, t.I18nBot("tgbot.answers.errorOperation"), true)
 					return
 				}
 				needRestart, err := t.clientService.SetClientTelegramUserID(&t.inboundService, traffic.Id, EmptyTelegramUserID)
@@ -985,9 +1810,13 @@ func (t *Tgbot) answerCallback(callbackQuery *telego.CallbackQuery, isAdmin bool
 					t.sendCallbackAnswerTgBot(callbackQuery.ID, t.I18nBot("tgbot.answers.removedTGUserSuccess", "Email=="+email))
 					t.clientTelegramUserInfo(chatId, email, callbackQuery.Message.GetMessageID())
 				} else {
-					t.sendCallbackAnswerTgBot(callbackQuery.ID, t.I18nBot("tgbot.answers.errorOperation"))
+					t.answerCallbackTgBot(callbackQuery.ID, t.I18nBot("tgbot.answers.errorOperation"), true)
 				}
 			case "toggle_enable":
+				if enabled, err := t.clientService.CheckIsEnabledByEmail(&t.inboundService, email); err == nil && enabled {
+					t.promptDisableReason(chatId, callbackQuery.Message.GetMessageID(), email)
+					return
+				}
 				inlineKeyboard := tu.InlineKeyboard(
 					tu.InlineKeyboardRow(
 						tu.InlineKeyboardButton(t.I18nBot("tgbot.buttons.cancel")).WithCallbackData(t.encodeQuery("client_cancel "+email)),
@@ -1010,26 +1839,26 @@ func (t *Tgbot) answerCallback(callbackQuery *telego.CallbackQuery, isAdmin bool
 					}
 					t.searchClient(chatId, email, callbackQuery.Message.GetMessageID())
 				} else {
-					t.sendCallbackAnswerTgBot(callbackQuery.ID, t.I18nBot("tgbot.answers.errorOperation"))
+					t.answerCallbackTgBot(callbackQuery.ID, t.I18nBot("tgbot.answers.errorOperation"), true)
 				}
 			case "get_clients":
 				inboundId := dataArray[1]
 				inboundIdInt, err := strconv.Atoi(inboundId)
 				if err != nil {
-					t.sendCallbackAnswerTgBot(callbackQuery.ID, err.Error())
+					t.answerCallbackTgBot(callbackQuery.ID, err.Error(), true)
 					return
 				}
 				inbound, err := t.inboundService.GetInbound(inboundIdInt)
 				if err != nil {
-					t.sendCallbackAnswerTgBot(callbackQuery.ID, err.Error())
+					t.answerCallbackTgBot(callbackQuery.ID, err.Error(), true)
 					return
 				}
 				clients, err := t.getInboundClients(inboundIdInt)
 				if err != nil {
-					t.sendCallbackAnswerTgBot(callbackQuery.ID, err.Error())
+					t.answerCallbackTgBot(callbackQuery.ID, err.Error(), true)
 					return
 				}
-				t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.answers.chooseClient", "Inbound=="+inbound.Remark), clients)
+				t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.answers.chooseClient", "Inbound=="+escapeField(inbound.Remark)), clients)
 			case "add_client_to":
 				client_Email = t.randomLowerAndNum(8)
 				client_LimitIP = 0
@@ -1044,7 +1873,7 @@ func (t *Tgbot) answerCallback(callbackQuery *telego.CallbackQuery, isAdmin bool
 				inboundId := dataArray[1]
 				inboundIdInt, err := strconv.Atoi(inboundId)
 				if err != nil {
-					t.sendCallbackAnswerTgBot(callbackQuery.ID, err.Error())
+					t.answerCallbackTgBot(callbackQuery.ID, err.Error(), true)
 					return
 				}
 				receiver_inbound_ID = inboundIdInt
@@ -1054,7 +1883,7 @@ func (t *Tgbot) answerCallback(callbackQuery *telego.CallbackQuery, isAdmin bool
 				inboundIdStr := dataArray[1]
 				inboundIdInt, err := strconv.Atoi(inboundIdStr)
 				if err != nil {
-					t.sendCallbackAnswerTgBot(callbackQuery.ID, err.Error())
+					t.answerCallbackTgBot(callbackQuery.ID, err.Error(), true)
 					return
 				}
 				found := -1
@@ -1071,7 +1900,7 @@ func (t *Tgbot) answerCallback(callbackQuery *telego.CallbackQuery, isAdmin bool
 				}
 				picker, err := t.getInboundsAttachPicker()
 				if err != nil {
-					t.sendCallbackAnswerTgBot(callbackQuery.ID, err.Error())
+					t.answerCallbackTgBot(callbackQuery.ID, err.Error(), true)
 					return
 				}
 				t.editMessageCallbackTgBot(callbackQuery.Message.GetChat().ID, callbackQuery.Message.GetMessageID(), picker)
@@ -1082,7 +1911,7 @@ func (t *Tgbot) answerCallback(callbackQuery *telego.CallbackQuery, isAdmin bool
 			case "get_inbounds":
 				inbounds, err := t.getInbounds()
 				if err != nil {
-					t.sendCallbackAnswerTgBot(callbackQuery.ID, err.Error())
+					t.answerCallbackTgBot(callbackQuery.ID, err.Error(), true)
 					return
 
 				}
@@ -1091,24 +1920,43 @@ func (t *Tgbot) answerCallback(callbackQuery *telego.CallbackQuery, isAdmin bool
 			case "admin_client_sub_links":
 				inbounds, err := t.getInboundsFor("get_clients_for_sub")
 				if err != nil {
-					t.sendCallbackAnswerTgBot(callbackQuery.ID, err.Error())
+					t.answerCallbackTgBot(callbackQuery.ID, err.Error(), true)
 					return
 				}
 				t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.answers.chooseInbound"), inbounds)
 			case "admin_client_individual_links":
 				inbounds, err := t.getInboundsFor("get_clients_for_individual")
 				if err != nil {
-					t.sendCallbackAnswerTgBot(callbackQuery.ID, err.Error())
+					t.answerCallbackTgBot(callbackQuery.ID, err.Error(), true)
 					return
 				}
 				t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.answers.chooseInbound"), inbounds)
 			case "admin_client_qr_links":
 				inbounds, err := t.getInboundsFor("get_clients_for_qr")
 				if err != nil {
-					t.sendCallbackAnswerTgBot(callbackQuery.ID, err.Error())
+					t.answerCallbackTgBot(callbackQuery.ID, err.Error(), true)
 					return
 				}
 				t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.answers.chooseInbound"), inbounds)
+			case "reload_rules_restart":
+				t.sendCallbackAnswerTgBot(callbackQuery.ID, t.I18nBot("tgbot.buttons.restartAnyway"))
+				t.restartForRules(chatId, callbackQuery.From.ID)
+			case "restore_good_config":
+				t.sendCallbackAnswerTgBot(callbackQuery.ID, t.I18nBot("tgbot.buttons.restoreGoodConfig"))
+				t.restoreGoodConfig(chatId, callbackQuery.From.ID)
+			case "setting_confirm":
+				t.sendCallbackAnswerTgBot(callbackQuery.ID, t.I18nBot("tgbot.buttons.confirmSetting"))
+				t.applySettingChange(chatId, callbackQuery.From.ID)
+			case "setting_cancel":
+				takePendingSettingChange(chatId, time.Now())
+				t.sendCallbackAnswerTgBot(callbackQuery.ID, t.I18nBot("tgbot.buttons.cancel"))
+				t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.messages.settingCanceled"))
+			case "client_move_cancel":
+				t.sendCallbackAnswerTgBot(callbackQuery.ID, t.I18nBot("tgbot.buttons.cancel"))
+				t.cancelClientMove(chatId)
+			case "client_import_cancel":
+				t.sendCallbackAnswerTgBot(callbackQuery.ID, t.I18nBot("tgbot.buttons.cancel"))
+				t.cancelClientImport(chatId)
 			}
 
 		}
@@ -1126,7 +1974,7 @@ func (t *Tgbot) answerCallback(callbackQuery *telego.CallbackQuery, isAdmin bool
 		t.SendMsgToTgbot(chatId, t.getInboundUsages())
 	case "deplete_soon":
 		t.sendCallbackAnswerTgBot(callbackQuery.ID, t.I18nBot("tgbot.buttons.depleteSoon"))
-		t.getExhausted(chatId)
+		t.getExhausted(chatId, nil)
 	case "get_backup":
 		t.sendCallbackAnswerTgBot(callbackQuery.ID, t.I18nBot("tgbot.buttons.dbBackup"))
 		t.sendBackup(chatId)
@@ -1213,6 +2061,9 @@ func (t *Tgbot) answerCallback(callbackQuery *telego.CallbackQuery, isAdmin bool
 	case "onlines_refresh":
 		t.sendCallbackAnswerTgBot(callbackQuery.ID, t.I18nBot("tgbot.answers.successfulOperation"))
 		t.onlineClients(chatId, callbackQuery.Message.GetMessageID())
+	case "connections_refresh":
+		t.sendCallbackAnswerTgBot(callbackQuery.ID, t.I18nBot("tgbot.answers.successfulOperation"))
+		t.sendConnections(chatId, callbackQuery.Message.GetMessageID())
 	case "commands":
 		t.sendCallbackAnswerTgBot(callbackQuery.ID, t.I18nBot("tgbot.buttons.commands"))
 		t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.commands.helpAdminCommands"))
@@ -1229,7 +2080,7 @@ func (t *Tgbot) answerCallback(callbackQuery *telego.CallbackQuery, isAdmin bool
 
 		inbounds, err := t.getInboundsAddClient()
 		if err != nil {
-			t.sendCallbackAnswerTgBot(callbackQuery.ID, err.Error())
+			t.answerCallbackTgBot(callbackQuery.ID, err.Error(), true)
 			return
 		}
 		t.sendCallbackAnswerTgBot(callbackQuery.ID, t.I18nBot("tgbot.buttons.addClient"))
@@ -1242,7 +2093,7 @@ func (t *Tgbot) answerCallback(callbackQuery *telego.CallbackQuery, isAdmin bool
 				tu.InlineKeyboardButton(t.I18nBot("tgbot.buttons.use_default")).WithCallbackData("add_client_default_info"),
 			),
 		)
-		prompt_message := t.I18nBot("tgbot.messages.email_prompt", "ClientEmail=="+client_Email)
+		prompt_message := t.I18nBot("tgbot.messages.email_prompt", "ClientEmail=="+escapeField(client_Email))
 		t.SendMsgToTgbot(chatId, prompt_message, cancel_btn_markup)
 	case "add_client_ch_default_comment":
 		t.deleteMessageTgBot(chatId, callbackQuery.Message.GetMessageID())
@@ -1378,7 +2229,7 @@ func (t *Tgbot) answerCallback(callbackQuery *telego.CallbackQuery, isAdmin bool
 	case "add_client_attach_more":
 		picker, err := t.getInboundsAttachPicker()
 		if err != nil {
-			t.sendCallbackAnswerTgBot(callbackQuery.ID, err.Error())
+			t.answerCallbackTgBot(callbackQuery.ID, err.Error(), true)
 			return
 		}
 		t.SendMsgToTgbot(chatId, "Pick inbound(s) to attach:", picker)
@@ -1387,7 +2238,7 @@ func (t *Tgbot) answerCallback(callbackQuery *telego.CallbackQuery, isAdmin bool
 			receiver_inbound_ID = receiver_inbound_IDs[0]
 		}
 		if receiver_inbound_ID == 0 {
-			t.sendCallbackAnswerTgBot(callbackQuery.ID, t.I18nBot("tgbot.answers.getInboundsFailed"))
+			t.answerCallbackTgBot(callbackQuery.ID, t.I18nBot("tgbot.answers.getInboundsFailed"), true)
 			return
 		}
 		message_text := t.BuildClientDraftMessage()
@@ -1402,8 +2253,8 @@ func (t *Tgbot) answerCallback(callbackQuery *telego.CallbackQuery, isAdmin bool
 		} else {
 			t.deleteMessageTgBot(chatId, callbackQuery.Message.GetMessageID())
 			t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.answers.successfulOperation"), tu.ReplyKeyboardRemove())
-			t.sendClientIndividualLinks(chatId, client_Email)
-			t.sendClientQRLinks(chatId, client_Email)
+			t.sendClientIndividualLinks(chatId, client_Email, linkFlavorCompat)
+			t.sendClientQRLinks(chatId, client_Email, linkFlavorCompat)
 			receiver_inbound_ID = 0
 			receiver_inbound_IDs = nil
 		}
@@ -1416,15 +2267,36 @@ func (t *Tgbot) answerCallback(callbackQuery *telego.CallbackQuery, isAdmin bool
 		} else {
 			t.deleteMessageTgBot(chatId, callbackQuery.Message.GetMessageID())
 			t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.answers.successfulOperation"), tu.ReplyKeyboardRemove())
-			t.sendClientIndividualLinks(chatId, client_Email)
-			t.sendClientQRLinks(chatId, client_Email)
+			t.sendClientIndividualLinks(chatId, client_Email, linkFlavorCompat)
+			t.sendClientQRLinks(chatId, client_Email, linkFlavorCompat)
 			receiver_inbound_ID = 0
 			receiver_inbound_IDs = nil
 		}
+	case "restart_xray":
+		if !isAdmin {
+			t.answerCallbackTgBot(callbackQuery.ID, t.I18nBot("tgbot.answers.permissionDenied"), true)
+			return
+		}
+		t.confirmRestartXray(chatId)
+	case "restart_xray_c":
+		if !isAdmin {
+			t.answerCallbackTgBot(callbackQuery.ID, t.I18nBot("tgbot.answers.permissionDenied"), true)
+			return
+		}
+		t.sendCallbackAnswerTgBot(callbackQuery.ID, t.I18nBot("tgbot.buttons.restartXray"))
+		t.deleteMessageTgBot(chatId, callbackQuery.Message.GetMessageID())
+		t.restartXrayFromMenu(chatId, callbackQuery.From.ID)
+	case "restart_xray_cancel":
+		t.sendCallbackAnswerTgBot(callbackQuery.ID, t.I18nBot("tgbot.answers.cancelDone"))
+		t.deleteMessageTgBot(chatId, callbackQuery.Message.GetMessageID())
 	case "reset_all_traffics_cancel":
+		t.sendCallbackAnswerTgBot(callbackQuery.ID, t.I18nBot("tgbot.answers.cancelDone"))
 		t.deleteMessageTgBot(chatId, callbackQuery.Message.GetMessageID())
-		t.SendMsgToTgbotDeleteAfter(chatId, t.I18nBot("tgbot.messages.cancel"), 1, tu.ReplyKeyboardRemove())
 	case "reset_all_traffics":
+		if !isAdmin {
+			t.answerCallbackTgBot(callbackQuery.ID, t.I18nBot("tgbot.answers.permissionDenied"), true)
+			return
+		}
 		inlineKeyboard := tu.InlineKeyboard(
 			tu.InlineKeyboardRow(
 				tu.InlineKeyboardButton(t.I18nBot("tgbot.buttons.cancelReset")).WithCallbackData(t.encodeQuery("reset_all_traffics_cancel")),
@@ -1433,8 +2305,39 @@ func (t *Tgbot) answerCallback(callbackQuery *telego.CallbackQuery, isAdmin bool
 				tu.InlineKeyboardButton(t.I18nBot("tgbot.buttons.confirmResetTraffic")).WithCallbackData(t.encodeQuery("reset_all_traffics_c")),
 			),
 		)
-		t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.messages.AreYouSure"), inlineKeyboard)
+		t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.messages.ResetAllTrafficsConfirm"), inlineKeyboard)
+	case "reset_all_inbound_traffics":
+		if !isAdmin {
+			t.answerCallbackTgBot(callbackQuery.ID, t.I18nBot("tgbot.answers.permissionDenied"), true)
+			return
+		}
+		inlineKeyboard := tu.InlineKeyboard(
+			tu.InlineKeyboardRow(
+				tu.InlineKeyboardButton(t.I18nBot("tgbot.buttons.cancelReset")).WithCallbackData(t.encodeQuery("reset_all_traffics_cancel")),
+			),
+			tu.InlineKeyboardRow(
+				tu.InlineKeyboardButton(t.I18nBot("tgbot.buttons.confirmResetTraffic")).WithCallbackData(t.encodeQuery("reset_all_inbound_traffics_c")),
+			),
+		)
+		t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.messages.ResetAllInboundTrafficsConfirm"), inlineKeyboard)
+	case "reset_all_inbound_traffics_c":
+		if !isAdmin {
+			t.answerCallbackTgBot(callbackQuery.ID, t.I18nBot("tgbot.answers.permissionDenied"), true)
+			return
+		}
+		t.deleteMessageTgBot(chatId, callbackQuery.Message.GetMessageID())
+		snapshot := t.snapshotInbounds()
+		if err := t.inboundService.ResetAllTraffics(); err != nil {
+			logger.Warning("ResetAllTraffics failed:", err)
+			t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.answers.errorOperation"), tu.ReplyKeyboardRemove())
+			return
+		}
+		t.offerUndo(chatId, t.I18nBot("tgbot.messages.SuccessResetInboundTraffics"), snapshot)
 	case "reset_all_traffics_c":
+		if !isAdmin {
+			t.answerCallbackTgBot(callbackQuery.ID, t.I18nBot("tgbot.answers.permissionDenied"), true)
+			return
+		}
 		t.deleteMessageTgBot(chatId, callbackQuery.Message.GetMessageID())
 		emails, err := t.inboundService.GetAllEmails()
 		if err != nil {
@@ -1442,18 +2345,19 @@ func (t *Tgbot) answerCallback(callbackQuery *telego.CallbackQuery, isAdmin bool
 			return
 		}
 
+		snapshot := t.snapshotClients(emails...)
 		for _, email := range emails {
 			err := t.inboundService.ResetClientTrafficByEmail(email)
 			if err == nil {
-				msg := t.I18nBot("tgbot.messages.SuccessResetTraffic", "ClientEmail=="+email)
+				msg := t.I18nBot("tgbot.messages.SuccessResetTraffic", "ClientEmail=="+escapeField(email))
 				t.SendMsgToTgbot(chatId, msg, tu.ReplyKeyboardRemove())
 			} else {
-				msg := t.I18nBot("tgbot.messages.FailedResetTraffic", "ClientEmail=="+email, "ErrorMessage=="+err.Error())
+				msg := t.I18nBot("tgbot.messages.FailedResetTraffic", "ClientEmail=="+escapeField(email), "ErrorMessage=="+html.EscapeString(err.Error()))
 				t.SendMsgToTgbot(chatId, msg, tu.ReplyKeyboardRemove())
 			}
 		}
 
-		t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.messages.FinishProcess"), tu.ReplyKeyboardRemove())
+		t.offerUndo(chatId, t.I18nBot("tgbot.messages.FinishProcess"), snapshot)
 	case "get_sorted_traffic_usage_report":
 		t.deleteMessageTgBot(chatId, callbackQuery.Message.GetMessageID())
 		emails, err := t.inboundService.GetAllEmails()
@@ -1486,11 +2390,26 @@ func (t *Tgbot) answerCallback(callbackQuery *telego.CallbackQuery, isAdmin bool
 			t.SendMsgToTgbot(chatId, output, tu.ReplyKeyboardRemove())
 		}
 		for _, extra_emails := range extra_emails {
-			msg := fmt.Sprintf("📧 %s\n%s", extra_emails, t.I18nBot("tgbot.noResult"))
+			msg := fmt.Sprintf("📧 %s\n%s", escapeField(extra_emails), t.I18nBot("tgbot.noResult"))
 			t.SendMsgToTgbot(chatId, msg, tu.ReplyKeyboardRemove())
 
 		}
 	default:
+		// long queries are hashed by encodeQuery, for clients too
+		data, err := t.decodeQuery(callbackQuery.Data)
+		if err != nil {
+			t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.noQuery"))
+			return
+		}
+		if after, ok := strings.CutPrefix(data, "client_links_flavor "); ok {
+			t.sendLinksInFlavor(chatId, after, callbackQuery.From.ID, isAdmin)
+			return
+		}
+		if after, ok := strings.CutPrefix(data, "client_sub_qr "); ok {
+			t.sendCallbackAnswerTgBot(callbackQuery.ID, t.I18nBot("qrCode"))
+			t.sendSubscriptionQR(chatId, after, callbackQuery.From.ID, isAdmin)
+			return
+		}
 		if after, ok := strings.CutPrefix(callbackQuery.Data, "client_sub_links "); ok {
 			email := after
 			t.sendClientSubLinks(chatId, email)
@@ -1498,12 +2417,12 @@ func (t *Tgbot) answerCallback(callbackQuery *telego.CallbackQuery, isAdmin bool
 		}
 		if after, ok := strings.CutPrefix(callbackQuery.Data, "client_individual_links "); ok {
 			email := after
-			t.sendClientIndividualLinks(chatId, email)
+			t.sendClientIndividualLinks(chatId, email, linkFlavorCompat)
 			return
 		}
 		if after, ok := strings.CutPrefix(callbackQuery.Data, "client_qr_links "); ok {
 			email := after
-			t.sendClientQRLinks(chatId, email)
+			t.sendClientQRLinks(chatId, email, linkFlavorCompat)
 			return
 		}
 	}
@@ -0,0 +1,44 @@
+package tgbot
+
+import (
+	"errors"
+	"html"
+	"strconv"
+
+	"github.com/zixu5u/3xv/v3/internal/logger"
+	"github.com/zixu5u/3xv/v3/internal/web/service"
+
+	tu "github.com/mymmrac/telego/telegoutil"
+)
+
+// reloadRoutingRules hot-reloads the routing rules, re-reading the geo
+// files. When the running core can't take the reload through its API, the
+// admin is offered a full restart instead, which drops all connections.
+func (t *Tgbot) reloadRoutingRules(chatId int64, requestedBy int64) {
+	rules, err := t.xrayService.ReloadRoutingRules()
+	if errors.Is(err, service.ErrRoutingReloadUnsupported) {
+		inlineKeyboard := tu.InlineKeyboard(
+			tu.InlineKeyboardRow(
+				tu.InlineKeyboardButton(t.I18nBot("tgbot.buttons.restartAnyway")).WithCallbackData(t.encodeQuery("reload_rules_restart")),
+			),
+		)
+		t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.messages.reloadRulesUnsupported"), inlineKeyboard)
+		return
+	}
+	if err != nil {
+		logger.Warningf("Routing reload requested by %d failed: %v", requestedBy, err)
+		t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.messages.reloadRulesFailed", "Error=="+html.EscapeString(err.Error())))
+		return
+	}
+	logger.Infof("Routing rules reloaded by Telegram user %d (%d rules)", requestedBy, rules)
+	t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.messages.reloadRulesSuccess", "Count=="+strconv.Itoa(rules)))
+}
+
+// restartForRules is the confirmed full-restart fallback of /reloadrules.
+func (t *Tgbot) restartForRules(chatId int64, requestedBy int64) {
+	downtime, attempts, err := t.restartXrayReady()
+	if err == nil {
+		logger.Infof("Xray restarted by Telegram user %d to apply routing rules", requestedBy)
+	}
+	t.SendMsgToTgbot(chatId, t.restartOutcome(downtime, attempts, err))
+}
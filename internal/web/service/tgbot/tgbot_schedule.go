@@ -1,10 +1,15 @@
 package tgbot
 
 import (
+	"html"
+	"slices"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/zixu5u/3xv/v3/internal/logger"
+
+	"github.com/robfig/cron/v3"
 )
 
 var (
@@ -20,6 +25,25 @@ var (
 	scheduledTime string
 )
 
+// ReportScheduleOff is the tgRunTime value that disables the scheduled
+// report. An explicitly empty value means the same thing; only a
+// never-configured setting falls back to the @daily default.
+const ReportScheduleOff = "off"
+
+// IsReportScheduleOff reports whether runTime disables the scheduled report.
+func IsReportScheduleOff(runTime string) bool {
+	runTime = strings.TrimSpace(runTime)
+	return runTime == "" || strings.EqualFold(runTime, ReportScheduleOff)
+}
+
+// IsClockSchedule reports whether runTime is a plain "HH:MM" time, which is
+// handled by the bot's own daily scheduler. Anything else is a cron
+// expression owned by the panel's cron (see web.go startTask).
+func IsClockSchedule(runTime string) bool {
+	_, err := time.Parse("15:04", strings.TrimSpace(runTime))
+	return err == nil
+}
+
 // StartScheduler initializes and starts the daily scheduled report.
 func (t *Tgbot) StartScheduler(scheduleTime string) {
 	schedulerMutex.Lock()
@@ -28,8 +52,13 @@ func (t *Tgbot) StartScheduler(scheduleTime string) {
 	// Stop any existing scheduler
 	t.stopSchedulerLocked()
 
-	if scheduleTime == "" {
-		logger.Info("Schedule time is empty, scheduler not started")
+	if IsReportScheduleOff(scheduleTime) {
+		logger.Info("Scheduled report is disabled, scheduler not started")
+		return
+	}
+	if !IsClockSchedule(scheduleTime) {
+		// A cron expression: the panel's cron sends the report, so starting
+		// here too would fall back to 08:00 and send it twice.
 		return
 	}
 
@@ -149,3 +178,51 @@ func (t *Tgbot) parseScheduleTime(scheduleStr string) (int, int) {
 
 	return hour, min
 }
+
+// cronSpecParser matches the panel cron's parser (cron.WithSeconds()).
+var cronSpecParser = cron.NewParser(cron.Second | cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor)
+
+// describeSchedule renders a report schedule and its next run for /cronstatus.
+func (t *Tgbot) describeSchedule(runTime string) string {
+	if IsReportScheduleOff(runTime) {
+		return t.I18nBot("tgbot.messages.cronStatusDisabled")
+	}
+	loc, err := t.settingService.GetTimeLocation()
+	if err != nil {
+		loc = time.Local
+	}
+	now := time.Now().In(loc)
+	var next time.Time
+	if IsClockSchedule(runTime) {
+		next = t.calculateNextRunTime(now, strings.TrimSpace(runTime))
+	} else {
+		schedule, err := cronSpecParser.Parse(runTime)
+		if err != nil {
+			return t.I18nBot("tgbot.messages.cronStatusInvalid", "Schedule=="+html.EscapeString(runTime), "Error=="+html.EscapeString(err.Error()))
+		}
+		next = schedule.Next(now)
+	}
+	return t.I18nBot("tgbot.messages.cronStatusNext", "Schedule=="+html.EscapeString(runTime), "Time=="+next.Format("2006-01-02 15:04:05"))
+}
+
+// sendCronStatus reports the effective report schedule of the primary bot and
+// of every extra bot subscribed to reports.
+func (t *Tgbot) sendCronStatus(chatId int64) {
+	runTime, err := t.settingService.GetTgbotRuntime()
+	if err != nil {
+		t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.answers.errorOperation"))
+		return
+	}
+
+	msg := t.I18nBot("tgbot.messages.cronStatusReport", "Schedule=="+t.describeSchedule(runTime))
+
+	extraBotsMutex.Lock()
+	bots := slices.Clone(extraBots)
+	extraBotsMutex.Unlock()
+	for _, eb := range bots {
+		if eb.wants(NotifyReport) {
+			msg += t.I18nBot("tgbot.messages.cronStatusExtraBot", "Name=="+html.EscapeString(eb.name), "Schedule=="+t.describeSchedule(eb.runTime))
+		}
+	}
+	t.SendMsgToTgbot(chatId, msg)
+}
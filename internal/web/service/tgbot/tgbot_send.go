@@ -20,60 +20,10 @@ func (t *Tgbot) sendResponse(chatId int64, msg string, onlyMessage, isAdmin bool
 	}
 }
 
-// SendAnswer sends a response message with an inline keyboard to the specified chat.
+// SendAnswer sends a response message with the main menu for the role to
+// the specified chat.
 func (t *Tgbot) SendAnswer(chatId int64, msg string, isAdmin bool) {
-	numericKeyboard := tu.InlineKeyboard(
-		tu.InlineKeyboardRow(
-			tu.InlineKeyboardButton(t.I18nBot("tgbot.buttons.SortedTrafficUsageReport")).WithCallbackData(t.encodeQuery("get_sorted_traffic_usage_report")),
-		),
-		tu.InlineKeyboardRow(
-			tu.InlineKeyboardButton(t.I18nBot("tgbot.buttons.serverUsage")).WithCallbackData(t.encodeQuery("get_usage")),
-			tu.InlineKeyboardButton(t.I18nBot("tgbot.buttons.ResetAllTraffics")).WithCallbackData(t.encodeQuery("reset_all_traffics")),
-		),
-		tu.InlineKeyboardRow(
-			tu.InlineKeyboardButton(t.I18nBot("tgbot.buttons.dbBackup")).WithCallbackData(t.encodeQuery("get_backup")),
-			tu.InlineKeyboardButton(t.I18nBot("tgbot.buttons.getBanLogs")).WithCallbackData(t.encodeQuery("get_banlogs")),
-		),
-		tu.InlineKeyboardRow(
-			tu.InlineKeyboardButton(t.I18nBot("tgbot.buttons.getInbounds")).WithCallbackData(t.encodeQuery("inbounds")),
-			tu.InlineKeyboardButton(t.I18nBot("tgbot.buttons.depleteSoon")).WithCallbackData(t.encodeQuery("deplete_soon")),
-		),
-		tu.InlineKeyboardRow(
-			tu.InlineKeyboardButton(t.I18nBot("tgbot.buttons.commands")).WithCallbackData(t.encodeQuery("commands")),
-			tu.InlineKeyboardButton(t.I18nBot("tgbot.buttons.onlines")).WithCallbackData(t.encodeQuery("onlines")),
-		),
-		tu.InlineKeyboardRow(
-			tu.InlineKeyboardButton(t.I18nBot("tgbot.buttons.allClients")).WithCallbackData(t.encodeQuery("get_inbounds")),
-			tu.InlineKeyboardButton(t.I18nBot("tgbot.buttons.addClient")).WithCallbackData(t.encodeQuery("add_client")),
-		),
-		tu.InlineKeyboardRow(
-			tu.InlineKeyboardButton(t.I18nBot("pages.settings.subSettings")).WithCallbackData(t.encodeQuery("admin_client_sub_links")),
-			tu.InlineKeyboardButton(t.I18nBot("subscription.individualLinks")).WithCallbackData(t.encodeQuery("admin_client_individual_links")),
-			tu.InlineKeyboardButton(t.I18nBot("qrCode")).WithCallbackData(t.encodeQuery("admin_client_qr_links")),
-		),
-		// TODOOOOOOOOOOOOOO: Add restart button here.
-	)
-	numericKeyboardClient := tu.InlineKeyboard(
-		tu.InlineKeyboardRow(
-			tu.InlineKeyboardButton(t.I18nBot("tgbot.buttons.clientUsage")).WithCallbackData(t.encodeQuery("client_traffic")),
-			tu.InlineKeyboardButton(t.I18nBot("tgbot.buttons.commands")).WithCallbackData(t.encodeQuery("client_commands")),
-		),
-		tu.InlineKeyboardRow(
-			tu.InlineKeyboardButton(t.I18nBot("pages.settings.subSettings")).WithCallbackData(t.encodeQuery("client_sub_links")),
-			tu.InlineKeyboardButton(t.I18nBot("subscription.individualLinks")).WithCallbackData(t.encodeQuery("client_individual_links")),
-		),
-		tu.InlineKeyboardRow(
-			tu.InlineKeyboardButton(t.I18nBot("qrCode")).WithCallbackData(t.encodeQuery("client_qr_links")),
-		),
-	)
-
-	var ReplyMarkup telego.ReplyMarkup
-	if isAdmin {
-		ReplyMarkup = numericKeyboard
-	} else {
-		ReplyMarkup = numericKeyboardClient
-	}
-	t.SendMsgToTgbot(chatId, msg, ReplyMarkup)
+	t.SendMsgToTgbot(chatId, msg, t.buildMenu(isAdmin))
 }
 
 // SendMsgToTgbot sends a message to the Telegram bot with optional reply markup.
@@ -81,11 +31,35 @@ func (t *Tgbot) SendMsgToTgbot(chatId int64, msg string, replyMarkup ...telego.R
 	if !isRunning {
 		return
 	}
+	t.sendMsgVia(bot, chatId, msg, replyMarkup...)
+}
+
+// sendMsgVia pages and sends a message through the given bot instance, so the
+// primary bot and any notification-only bots share the same retry logic.
+// It returns the last send error, if any page failed.
+func (t *Tgbot) sendMsgVia(b *telego.Bot, chatId int64, msg string, replyMarkup ...telego.ReplyMarkup) error {
+	_, err := t.sendMsgPages(b, chatId, msg, false, replyMarkup...)
+	return err
+}
+
+// sendNotificationVia is sendMsgVia for a notification of category, which
+// arrives without a sound when the category is one of tgSilentCategories.
+func (t *Tgbot) sendNotificationVia(b *telego.Bot, chatId int64, category string, msg string, replyMarkup ...telego.ReplyMarkup) error {
+	_, err := t.sendMsgPages(b, chatId, msg, isSilent(category), replyMarkup...)
+	return err
+}
 
+// sendMsgPages is sendMsgVia that also returns the ID of the first page
+// Telegram accepted, or 0 when none was, for callers that act on the
+// message afterwards. silent sends every page without a sound.
+func (t *Tgbot) sendMsgPages(b *telego.Bot, chatId int64, msg string, silent bool, replyMarkup ...telego.ReplyMarkup) (int, error) {
 	if msg == "" {
 		logger.Info("[tgbot] message is empty!")
-		return
+		return 0, nil
 	}
+	firstId := 0
+	msg = decorate(severityNone, msg)
+	var sendErr error
 
 	var allMessages []string
 	limit := 2000
@@ -111,9 +85,10 @@ func (t *Tgbot) SendMsgToTgbot(chatId int64, msg string, replyMarkup ...telego.R
 	}
 	for n, message := range allMessages {
 		params := telego.SendMessageParams{
-			ChatID:    tu.ID(chatId),
-			Text:      message,
-			ParseMode: "HTML",
+			ChatID:              tu.ID(chatId),
+			Text:                message,
+			ParseMode:           "HTML",
+			DisableNotification: silent,
 		}
 		// only add replyMarkup to last message
 		if len(replyMarkup) > 0 && n == (len(allMessages)-1) {
@@ -124,10 +99,14 @@ func (t *Tgbot) SendMsgToTgbot(chatId int64, msg string, replyMarkup ...telego.R
 		maxRetries := 3
 		for attempt := range maxRetries {
 			ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-			_, err := bot.SendMessage(ctx, &params)
+			sent, err := b.SendMessage(ctx, &params)
 			cancel()
 
 			if err == nil {
+				if firstId == 0 {
+					firstId = sent.MessageID
+				}
+				recordSend(nil)
 				break // Success
 			}
 
@@ -144,7 +123,9 @@ func (t *Tgbot) SendMsgToTgbot(chatId int64, msg string, replyMarkup ...telego.R
 					attempt+1, maxRetries, backoff, err)
 				time.Sleep(backoff)
 			} else {
-				logger.Warning("Error sending telegram message:", err)
+				logSendFailure(chatId, err)
+				recordSend(err)
+				sendErr = err
 				break
 			}
 		}
@@ -154,32 +135,67 @@ func (t *Tgbot) SendMsgToTgbot(chatId int64, msg string, replyMarkup ...telego.R
 			time.Sleep(100 * time.Millisecond)
 		}
 	}
+	return firstId, sendErr
 }
 
-// SendMsgToTgbotAdmins sends a message to all admin Telegram chats.
+// SendMsgToTgbotAdmins sends a message to all admin Telegram chats. A chat
+// that fails doesn't stop delivery to the others; failures are logged once
+// every chat was tried.
 func (t *Tgbot) SendMsgToTgbotAdmins(msg string, replyMarkup ...telego.ReplyMarkup) {
-	if len(replyMarkup) > 0 {
-		for _, adminId := range adminIds {
-			t.SendMsgToTgbot(adminId, msg, replyMarkup[0])
-		}
-	} else {
-		for _, adminId := range adminIds {
-			t.SendMsgToTgbot(adminId, msg)
-		}
+	t.sendToAdmins("", msg, replyMarkup...)
+}
+
+// sendToAdmins is SendMsgToTgbotAdmins for a notification of category,
+// which is silent when tgSilentCategories lists it.
+func (t *Tgbot) sendToAdmins(category string, msg string, replyMarkup ...telego.ReplyMarkup) {
+	if !isRunning {
+		return
+	}
+	ids := adminChatIds()
+	if len(ids) == 0 {
+		logger.Warning("[tgbot] admin message dropped: no admin chats are configured or readable")
+		return
 	}
+	fanOut(ids, func(adminId int64) error {
+		start := time.Now()
+		err := t.sendNotificationVia(bot, adminId, category, msg, replyMarkup...)
+		logBotEvent(botEvent{Event: "notification", ChatID: adminId, Category: category, Latency: time.Since(start), Err: err})
+		return err
+	}).logSummary("Admin message")
 }
 
-// sendCallbackAnswerTgBot answers a callback query with a message.
+// maxCallbackAnswerLen is the most characters Telegram shows in a callback
+// answer; longer ones are rejected.
+const maxCallbackAnswerLen = 200
+
+// sendCallbackAnswerTgBot answers a callback query with a toast.
 func (t *Tgbot) sendCallbackAnswerTgBot(id string, message string) {
+	t.answerCallbackTgBot(id, message, false)
+}
+
+// answerCallbackTgBot answers a callback query with text shown as a toast
+// that fades by itself, or with alert as a popup the user has to dismiss.
+// It is the feedback for a button press that needs no new message; errors
+// and refusals use the popup so they aren't missed.
+func (t *Tgbot) answerCallbackTgBot(id string, text string, alert bool) {
 	params := telego.AnswerCallbackQueryParams{
 		CallbackQueryID: id,
-		Text:            message,
+		Text:            callbackAnswerText(text),
+		ShowAlert:       alert,
 	}
 	if err := bot.AnswerCallbackQuery(context.Background(), &params); err != nil {
 		logger.Warning(err)
 	}
 }
 
+// callbackAnswerText cuts text to what a callback answer can show.
+func callbackAnswerText(text string) string {
+	if runes := []rune(text); len(runes) > maxCallbackAnswerLen {
+		return string(runes[:maxCallbackAnswerLen-1]) + "…"
+	}
+	return text
+}
+
 // editMessageCallbackTgBot edits the reply markup of a message.
 func (t *Tgbot) editMessageCallbackTgBot(chatId int64, messageID int, inlineKeyboard *telego.InlineKeyboardMarkup) {
 	params := telego.EditMessageReplyMarkupParams{
@@ -0,0 +1,68 @@
+package tgbot
+
+import (
+	"html"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/zixu5u/3xv/v3/internal/logger"
+	"github.com/zixu5u/3xv/v3/internal/web/service"
+)
+
+// portEndpoint renders where p listens, e.g. "443/tcp" or
+// "127.0.0.1:8080/tcp+udp". A wildcard listen address is left out.
+func portEndpoint(p service.ListeningPort) string {
+	transport := p.Transport
+	if transport == "tcpudp" {
+		transport = "tcp+udp"
+	}
+	endpoint := strconv.Itoa(p.Port)
+	switch p.Listen {
+	case "", "0.0.0.0", "::", "::0":
+	default:
+		endpoint = net.JoinHostPort(p.Listen, endpoint)
+	}
+	return endpoint + "/" + transport
+}
+
+// sendServerInfo implements /server: the hostname, the public addresses
+// clients connect to and the ports Xray listens on. A configured
+// tgServerAddress replaces the detected addresses, for servers behind NAT.
+func (t *Tgbot) sendServerInfo(chatId int64) {
+	var msg strings.Builder
+	msg.WriteString(t.I18nBot("tgbot.messages.hostname", "Hostname=="+escapeField(hostname)))
+	if address, err := t.settingService.GetTgServerAddress(); err == nil && address != "" {
+		msg.WriteString(t.I18nBot("tgbot.messages.serverAddress", "Address=="+html.EscapeString(address)))
+	} else {
+		ipv4, ipv6 := t.serverService.PublicIPs()
+		unknown := t.I18nBot("tgbot.unknown")
+		if ipv4 == "N/A" {
+			ipv4 = unknown
+		}
+		if ipv6 == "N/A" {
+			ipv6 = unknown
+		}
+		msg.WriteString(t.I18nBot("tgbot.messages.ipv4", "IPv4=="+html.EscapeString(ipv4)))
+		msg.WriteString(t.I18nBot("tgbot.messages.ipv6", "IPv6=="+html.EscapeString(ipv6)))
+	}
+
+	ports, err := t.inboundService.GetListeningPorts()
+	if err != nil {
+		logger.Warning("Failed to get inbound ports for /server:", err)
+		msg.WriteString("\r\n" + t.I18nBot("tgbot.wentWrong"))
+		t.SendMsgToTgbot(chatId, msg.String())
+		return
+	}
+	if len(ports) == 0 {
+		msg.WriteString("\r\n" + t.I18nBot("tgbot.messages.serverNoPorts"))
+		t.SendMsgToTgbot(chatId, msg.String())
+		return
+	}
+	msg.WriteString("\r\n" + t.I18nBot("tgbot.messages.serverPorts", "Count=="+strconv.Itoa(len(ports))))
+	for _, p := range ports {
+		msg.WriteString("\r\n<code>" + html.EscapeString(portEndpoint(p)) + "</code> " +
+			html.EscapeString(string(p.Protocol)) + " · " + escapeField(p.Remark))
+	}
+	t.SendMsgToTgbot(chatId, msg.String())
+}
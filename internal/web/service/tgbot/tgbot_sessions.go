@@ -0,0 +1,182 @@
+package tgbot
+
+import (
+	"errors"
+	"html"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/zixu5u/3xv/v3/internal/logger"
+	"github.com/zixu5u/3xv/v3/internal/web/service"
+
+	tu "github.com/mymmrac/telego/telegoutil"
+)
+
+const (
+	// maxSessionsShown caps the clients one /sessions message lists; the
+	// rest are only counted.
+	maxSessionsShown = 30
+	// maxSessionMinutes bounds the minimum session length /sessions takes.
+	maxSessionMinutes = 7 * 24 * 60
+	// terminateOffFor is how long /terminate keeps a client disabled before
+	// enabling it again, long enough for its connections to fail and for
+	// its apps to notice and reconnect.
+	terminateOffFor = 5 * time.Second
+)
+
+// clientSession is a client online on the local Xray and how long it has
+// been online without a break.
+type clientSession struct {
+	Email string
+	Since time.Time
+	IPs   int
+}
+
+// parseSessionsArgs reads the optional minimum session length of
+// "/sessions [minutes]". Without one every online client is listed.
+func parseSessionsArgs(args []string) (time.Duration, error) {
+	switch len(args) {
+	case 0:
+		return 0, nil
+	case 1:
+		minutes, err := strconv.Atoi(strings.TrimSuffix(strings.ToLower(args[0]), "m"))
+		if err != nil || minutes < 0 || minutes > maxSessionMinutes {
+			return 0, errors.New("invalid number of minutes")
+		}
+		return time.Duration(minutes) * time.Minute, nil
+	default:
+		return 0, errors.New("too many arguments")
+	}
+}
+
+// longSessions returns the sessions in since, Unix milliseconds by email,
+// that have lasted at least minAge at now, longest first.
+func longSessions(since map[string]int64, ipCounts map[string]int, now time.Time, minAge time.Duration) []clientSession {
+	sessions := make([]clientSession, 0, len(since))
+	for email, ms := range since {
+		start := time.UnixMilli(ms)
+		if now.Sub(start) < minAge {
+			continue
+		}
+		sessions = append(sessions, clientSession{Email: email, Since: start, IPs: ipCounts[email]})
+	}
+	slices.SortFunc(sessions, func(a, b clientSession) int {
+		if c := a.Since.Compare(b.Since); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Email, b.Email)
+	})
+	return sessions
+}
+
+// sendSessions implements /sessions: the clients online on the local Xray,
+// longest session first, with how long they have been online and from how
+// many IPs. Xray doesn't say how long a connection has been open, so a
+// session is measured from the first traffic poll that saw the client after
+// it had been offline.
+func (t *Tgbot) sendSessions(chatId int64, minAge time.Duration) {
+	if !service.XrayProcess().IsRunning() {
+		t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.messages.connectionsXrayStopped"))
+		return
+	}
+	now := time.Now()
+	counts, err := t.clientIpCounts(now)
+	if err != nil {
+		logger.Debug("Failed to count client IPs for /sessions:", err)
+	}
+	sessions := longSessions(service.XrayProcess().GetLocalOnlineSince(), counts, now, minAge)
+
+	var msg strings.Builder
+	msg.WriteString(t.I18nBot("tgbot.messages.sessionsHeader",
+		"Count=="+strconv.Itoa(len(sessions)),
+		"Minutes=="+strconv.Itoa(int(minAge/time.Minute))))
+	for _, session := range sessions[:min(len(sessions), maxSessionsShown)] {
+		msg.WriteString("\r\n" + t.I18nBot("tgbot.messages.sessionsClient",
+			"Email=="+escapeField(session.Email),
+			"Duration=="+now.Sub(session.Since).Round(time.Second).String(),
+			"Count=="+strconv.Itoa(session.IPs)))
+	}
+	if more := len(sessions) - maxSessionsShown; more > 0 {
+		msg.WriteString("\r\n" + t.I18nBot("tgbot.messages.connectionsMore", "Count=="+strconv.Itoa(more)))
+	}
+	if len(sessions) > 0 {
+		msg.WriteString("\r\n\r\n" + t.I18nBot("tgbot.messages.sessionsTerminateHint"))
+	}
+	t.SendMsgToTgbot(chatId, msg.String())
+}
+
+// confirmTerminate asks before terminating the connections of a client.
+func (t *Tgbot) confirmTerminate(chatId int64, email string) {
+	if _, err := t.clientService.GetRecordByEmail(nil, email); err != nil {
+		t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.noResult"))
+		return
+	}
+	inlineKeyboard := tu.InlineKeyboard(
+		tu.InlineKeyboardRow(
+			tu.InlineKeyboardButton(t.I18nBot("tgbot.buttons.confirmTerminate")).WithCallbackData(t.encodeQuery("terminate_c "+email)),
+			tu.InlineKeyboardButton(t.I18nBot("tgbot.buttons.cancel")).WithCallbackData(t.encodeQuery("terminate_cancel "+email)),
+		),
+	)
+	t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.messages.terminateConfirm",
+		"Email=="+escapeField(email),
+		"Seconds=="+strconv.Itoa(int(terminateOffFor/time.Second))), inlineKeyboard)
+}
+
+// terminateConnections forces a client's apps to reconnect. Xray can't drop
+// the connections of a single user, so the client is disabled for
+// terminateOffFor and enabled again; when applying that needs a restart,
+// Xray is restarted, which drops every connection. The reply says which of
+// the two happened. A disabled client has no connections to terminate and
+// is left alone.
+func (t *Tgbot) terminateConnections(chatId int64, email string, requestedBy int64) {
+	enabled, err := t.clientService.CheckIsEnabledByEmail(&t.inboundService, email)
+	if err == nil && !enabled {
+		t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.messages.terminateDisabled", "Email=="+escapeField(email)))
+		return
+	}
+	needRestart := false
+	if err == nil {
+		var offRestart bool
+		_, offRestart, err = t.clientService.SetClientEnableByEmail(&t.inboundService, email, false)
+		needRestart = offRestart
+	}
+	if err == nil {
+		time.Sleep(terminateOffFor)
+		var onRestart bool
+		_, onRestart, err = t.clientService.SetClientEnableByEmail(&t.inboundService, email, true)
+		needRestart = needRestart || onRestart
+		if err != nil {
+			// The client was disabled but could not be enabled again.
+			logger.Warning("Failed to re-enable client after terminating its connections:", err)
+			logBotEvent(botEvent{Event: "terminate", ChatID: requestedBy, Command: "terminate", Err: err})
+			t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.messages.terminateLeftDisabled",
+				"Email=="+escapeField(email), "Error=="+html.EscapeString(err.Error())))
+			return
+		}
+	}
+	if err == nil && needRestart {
+		if t.xrayService.IsXrayRunning() {
+			err = t.xrayService.RestartXray(true)
+		} else {
+			t.xrayService.SetToNeedRestart()
+			needRestart = false
+		}
+	}
+	logBotEvent(botEvent{Event: "terminate", ChatID: requestedBy, Command: "terminate", Err: err})
+	if err != nil {
+		logger.Warning("Failed to terminate client connections:", err)
+		t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.messages.terminateFailed",
+			"Email=="+escapeField(email), "Error=="+html.EscapeString(err.Error())))
+		return
+	}
+	logger.Infof("Connections of %s terminated by Telegram user %d", email, requestedBy)
+	if needRestart {
+		t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.messages.terminateRestarted", "Email=="+escapeField(email)))
+		return
+	}
+	t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.messages.terminateToggled",
+		"Email=="+escapeField(email),
+		"Seconds=="+strconv.Itoa(int(terminateOffFor/time.Second))))
+}
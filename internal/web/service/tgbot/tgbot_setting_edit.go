@@ -0,0 +1,321 @@
+package tgbot
+
+import (
+	"errors"
+	"html"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/zixu5u/3xv/v3/internal/logger"
+	"github.com/zixu5u/3xv/v3/internal/web/service"
+
+	tu "github.com/mymmrac/telego/telegoutil"
+)
+
+const (
+	// settingChangeTTL is how long a /setsetting change waits for its
+	// confirmation.
+	settingChangeTTL = 5 * time.Minute
+	// settingValueRunes caps a value shown by /getsetting, so a large one
+	// such as the Xray template doesn't flood the chat.
+	settingValueRunes = 500
+)
+
+// settableSetting is a setting /setsetting may change.
+type settableSetting struct {
+	// normalize validates a value and returns it as it is stored.
+	normalize func(value string) (string, error)
+	// needsRestart reports whether changing old to new only applies once
+	// the panel restarts, for settings read when the bot starts or when
+	// jobs are scheduled. nil means it applies right away.
+	needsRestart func(old, new string) bool
+	// apply puts a stored value into effect for settings that are applied
+	// by the bot rather than read on use.
+	apply func(t *Tgbot, value string) error
+}
+
+func alwaysRestart(old, new string) bool { return true }
+
+// intRange accepts a whole number from lo to hi.
+func intRange(lo, hi int) func(string) (string, error) {
+	return func(value string) (string, error) {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < lo || n > hi {
+			return "", errors.New("expected a whole number from " + strconv.Itoa(lo) + " to " + strconv.Itoa(hi))
+		}
+		return strconv.Itoa(n), nil
+	}
+}
+
+// boolValue accepts true/false and their usual spellings.
+func boolValue(value string) (string, error) {
+	switch strings.ToLower(value) {
+	case "true", "on", "yes", "1":
+		return "true", nil
+	case "false", "off", "no", "0":
+		return "false", nil
+	}
+	return "", errors.New("expected on or off")
+}
+
+// oneOf accepts one of values.
+func oneOf(values ...string) func(string) (string, error) {
+	return func(value string) (string, error) {
+		value = strings.ToLower(value)
+		if !slices.Contains(values, value) {
+			return "", errors.New("expected one of " + strings.Join(values, ", "))
+		}
+		return value, nil
+	}
+}
+
+// clockOrOff accepts a HH:MM time, or "off" for an empty value.
+func clockOrOff(value string) (string, error) {
+	if strings.EqualFold(value, "off") {
+		return "", nil
+	}
+	clock, err := time.Parse("15:04", value)
+	if err != nil {
+		return "", errors.New("expected HH:MM or off")
+	}
+	return clock.Format("15:04"), nil
+}
+
+// reportSchedule accepts what tgRunTime does: "off", a HH:MM time or a cron
+// expression the panel's cron can run.
+func reportSchedule(value string) (string, error) {
+	switch {
+	case IsReportScheduleOff(value):
+		return ReportScheduleOff, nil
+	case IsClockSchedule(value):
+		return clockOrOff(value)
+	}
+	if _, err := cronSpecParser.Parse(value); err != nil {
+		return "", errors.New("expected off, HH:MM or a cron expression with seconds: " + err.Error())
+	}
+	return value, nil
+}
+
+// notifyCategoryList accepts a comma-separated list of notification
+// categories.
+func notifyCategoryList(value string) (string, error) {
+	categories := parseChannelCategories(value)
+	for _, category := range categories {
+		if !slices.Contains(notifyCategories, category) {
+			return "", errors.New("expected categories from " + strings.Join(notifyCategories, ", "))
+		}
+	}
+	return strings.Join(categories, ","), nil
+}
+
+// settableSettings is the whitelist of /setsetting: bot and report tuning
+// that can't lock anyone out. Credentials, chat lists and network settings
+// are left to the web UI.
+var settableSettings = map[string]settableSetting{
+	"tgRunTime":                {normalize: reportSchedule, apply: (*Tgbot).ReloadReportSchedule},
+	"tgCpu":                    {normalize: intRange(0, 100), needsRestart: func(old, new string) bool { return old == "0" && new != "0" }},
+	"tgCpuWindow":              {normalize: intRange(10, 3600)},
+	"tgBotBackup":              {normalize: boolValue},
+	"tgBotLoginNotify":         {normalize: boolValue},
+	"tgBotStartupNotify":       {normalize: boolValue},
+	"tgSeverityEmoji":          {normalize: boolValue, needsRestart: alwaysRestart},
+	"tgPinCritical":            {normalize: boolValue},
+	"tgTrafficUnits":           {normalize: oneOf("binary", "iec", "si"), needsRestart: alwaysRestart},
+	"tgTrafficDecimals":        {normalize: intRange(0, 4), needsRestart: alwaysRestart},
+	"tgNumberFormat":           {normalize: oneOf("plain", "en", "eu", "fr", "ch"), needsRestart: alwaysRestart},
+	"tgQuietStart":             {normalize: clockOrOff},
+	"tgQuietEnd":               {normalize: clockOrOff},
+	"tgReportFileThreshold":    {normalize: intRange(0, math.MaxInt32)},
+	"tgReportDisabledInbounds": {normalize: boolValue},
+	"tgStatusClientCounts":     {normalize: boolValue},
+	"tgBotChannelCategories":   {normalize: notifyCategoryList},
+	"tgOnlineHistoryDays":      {normalize: intRange(1, 365)},
+	"tgTrafficHistoryDays":     {normalize: intRange(1, 365)},
+	"tgClientUsageInterval":    {normalize: intRange(0, 60), needsRestart: alwaysRestart},
+	"tgClientUsageDays":        {normalize: intRange(1, 90)},
+	"tgClientLimitInterval":    {normalize: intRange(0, 3600), needsRestart: alwaysRestart},
+	"tgButtonTTL":              {normalize: intRange(0, 10080), needsRestart: alwaysRestart},
+	"tgXrayStartTimeout":       {normalize: intRange(2, 300)},
+	"tgXrayStartRetries":       {normalize: intRange(0, 5)},
+	"tgReportSparklines":       {normalize: intRange(0, 10)},
+	"tgCertExpiryDays":         {normalize: intRange(0, 365), needsRestart: alwaysRestart},
+	"tgReportSections":         {normalize: reportSectionList},
+	"tgReportNoInboundsNote":   {normalize: boolValue},
+	"tgTrafficResetNotify":     {normalize: boolValue},
+	"tgGeoMaxAge":              {normalize: intRange(0, 3650)},
+	"tgCommandAliases":         {normalize: commandAliasList, needsRestart: alwaysRestart},
+	"tgSilentCategories":       {normalize: silentCategoryList, needsRestart: alwaysRestart},
+	"tgReportSummaryThreshold": {normalize: intRange(0, math.MaxInt32)},
+	"tgReportSummaryTop":       {normalize: intRange(1, 50)},
+}
+
+// settableSettingKeys lists the keys of settableSettings, sorted.
+func settableSettingKeys() []string {
+	keys := make([]string, 0, len(settableSettings))
+	for key := range settableSettings {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+// pendingSettingChange is a /setsetting change waiting for confirmation.
+type pendingSettingChange struct {
+	key   string
+	old   string
+	value string
+	at    time.Time
+}
+
+// pendingSettingChanges holds the change each chat was last asked to
+// confirm. A newer /setsetting replaces it.
+var pendingSettingChanges = struct {
+	sync.Mutex
+	byChat map[int64]pendingSettingChange
+}{byChat: make(map[int64]pendingSettingChange)}
+
+// takePendingSettingChange removes and returns the change chatId is asked
+// to confirm. ok is false when there is none or it expired.
+func takePendingSettingChange(chatId int64, now time.Time) (pendingSettingChange, bool) {
+	pendingSettingChanges.Lock()
+	defer pendingSettingChanges.Unlock()
+	change, ok := pendingSettingChanges.byChat[chatId]
+	delete(pendingSettingChanges.byChat, chatId)
+	if !ok || now.Sub(change.at) > settingChangeTTL {
+		return pendingSettingChange{}, false
+	}
+	return change, true
+}
+
+// displaySettingValue renders a setting value for a message, masking
+// secrets and shortening long values.
+func (t *Tgbot) displaySettingValue(key, value string) string {
+	switch {
+	case service.IsSecretSetting(key):
+		if value == "" {
+			return t.I18nBot("tgbot.messages.settingEmpty")
+		}
+		return service.MaskedSettingValue
+	case value == "":
+		return t.I18nBot("tgbot.messages.settingEmpty")
+	case key == "tgBotProxy":
+		value = redactProxy(value)
+	}
+	return "<code>" + html.EscapeString(truncateRunes(value, settingValueRunes)) + "</code>"
+}
+
+// sendSetting implements "/getsetting <key>": the current value of a panel
+// setting, with secrets masked, and its last change made from the bot.
+func (t *Tgbot) sendSetting(chatId int64, key string) {
+	value, err := t.settingService.GetSettingValue(key)
+	if err != nil {
+		t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.messages.settingUnknown", "Key=="+html.EscapeString(key)))
+		return
+	}
+	msg := t.I18nBot("tgbot.messages.settingValue",
+		"Key=="+html.EscapeString(key),
+		"Value=="+t.displaySettingValue(key, value))
+	if changes, err := t.settingService.GetSettingChanges(key, 1); err == nil && len(changes) > 0 {
+		change := changes[0]
+		msg += t.I18nBot("tgbot.messages.settingLastChange",
+			"Time=="+time.UnixMilli(change.ChangedAt).In(t.timeLocation()).Format("2006-01-02 15:04"),
+			"By=="+html.EscapeString(change.ChangedBy),
+			"Old=="+t.displaySettingValue(key, change.OldValue))
+	}
+	if _, ok := settableSettings[key]; ok {
+		msg += t.I18nBot("tgbot.messages.settingSettable", "Key=="+html.EscapeString(key))
+	}
+	t.SendMsgToTgbot(chatId, msg)
+}
+
+// confirmSettingChange implements "/setsetting <key> <value>": it validates
+// the value and asks for confirmation before anything is stored.
+func (t *Tgbot) confirmSettingChange(chatId int64, key string, value string) {
+	setting, ok := settableSettings[key]
+	if !ok {
+		t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.messages.settingNotSettable",
+			"Key=="+html.EscapeString(key),
+			"Keys=="+strings.Join(settableSettingKeys(), ", ")))
+		return
+	}
+	value, err := setting.normalize(strings.TrimSpace(value))
+	if err != nil {
+		t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.messages.settingInvalid",
+			"Key=="+html.EscapeString(key),
+			"Error=="+html.EscapeString(err.Error())))
+		return
+	}
+	old, err := t.settingService.GetSettingValue(key)
+	if err != nil {
+		logger.Warning("Failed to read setting", key+":", err)
+		t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.wentWrong"))
+		return
+	}
+	if old == value {
+		t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.messages.settingUnchanged",
+			"Key=="+html.EscapeString(key),
+			"Value=="+t.displaySettingValue(key, value)))
+		return
+	}
+
+	pendingSettingChanges.Lock()
+	pendingSettingChanges.byChat[chatId] = pendingSettingChange{key: key, old: old, value: value, at: time.Now()}
+	pendingSettingChanges.Unlock()
+	keyboard := tu.InlineKeyboard(tu.InlineKeyboardRow(
+		tu.InlineKeyboardButton(t.I18nBot("tgbot.buttons.confirmSetting")).WithCallbackData(t.encodeQuery("setting_confirm")),
+		tu.InlineKeyboardButton(t.I18nBot("tgbot.buttons.cancel")).WithCallbackData(t.encodeQuery("setting_cancel")),
+	))
+	t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.messages.settingConfirm",
+		"Key=="+html.EscapeString(key),
+		"Old=="+t.displaySettingValue(key, old),
+		"New=="+t.displaySettingValue(key, value)), keyboard)
+}
+
+// applySettingChange stores the change chatId confirmed. It is refused when
+// the setting changed in the meantime, so a stale confirmation can't undo
+// someone else's edit.
+func (t *Tgbot) applySettingChange(chatId int64, changedBy int64) {
+	change, ok := takePendingSettingChange(chatId, time.Now())
+	if !ok {
+		t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.messages.settingExpired"))
+		return
+	}
+	current, err := t.settingService.GetSettingValue(change.key)
+	if err != nil || current != change.old {
+		t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.messages.settingChangedMeanwhile", "Key=="+html.EscapeString(change.key)))
+		return
+	}
+	actor := telegramActor(changedBy)
+	_, err = t.settingService.ChangeSetting(change.key, change.value, actor)
+	logBotEvent(botEvent{Event: "setting_change", ChatID: changedBy, Command: "setsetting", Err: err})
+	if err != nil {
+		logger.Warning("Failed to change setting", change.key+":", err)
+		t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.messages.settingFailed",
+			"Key=="+html.EscapeString(change.key),
+			"Error=="+html.EscapeString(err.Error())))
+		return
+	}
+	logger.Infof("Setting %s changed from %q to %q by %s", change.key, change.old, change.value, actor)
+	if apply := settableSettings[change.key].apply; apply != nil {
+		if err := apply(t, change.value); err != nil {
+			logger.Warning("Failed to apply setting", change.key+":", err)
+			t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.messages.settingFailed",
+				"Key=="+html.EscapeString(change.key),
+				"Error=="+html.EscapeString(err.Error())))
+			return
+		}
+	}
+
+	msg := t.I18nBot("tgbot.messages.settingChanged",
+		"Key=="+html.EscapeString(change.key),
+		"Old=="+t.displaySettingValue(change.key, change.old),
+		"New=="+t.displaySettingValue(change.key, change.value))
+	if restart := settableSettings[change.key].needsRestart; restart != nil && restart(change.old, change.value) {
+		msg += t.I18nBot("tgbot.messages.settingNeedsRestart")
+	}
+	t.SendMsgToTgbot(chatId, msg)
+}
@@ -0,0 +1,105 @@
+package tgbot
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/zixu5u/3xv/v3/internal/logger"
+)
+
+// defaultReportRunTime is the default of tgRunTime, used when it can't be
+// read.
+const defaultReportRunTime = "@daily"
+
+// settingAlertInterval is how often a setting that keeps failing to read is
+// reported to admins again. Every failure is still logged.
+const settingAlertInterval = time.Hour
+
+var (
+	settingAlertsMutex sync.Mutex
+	settingAlerts      = map[string]time.Time{}
+)
+
+// shouldAlertSetting reports whether a read failure of name is due to be
+// sent to admins, and if so records it as sent at now.
+func shouldAlertSetting(name string, now time.Time) bool {
+	settingAlertsMutex.Lock()
+	defer settingAlertsMutex.Unlock()
+	if last, ok := settingAlerts[name]; ok && now.Sub(last) < settingAlertInterval {
+		return false
+	}
+	settingAlerts[name] = now
+	return true
+}
+
+// settingFallback handles a failed read of a bot setting: it logs the
+// failure, alerts admins through the chats already known to the bot and
+// returns fallback for the caller to carry on with.
+func (t *Tgbot) settingFallback(name string, err error, fallback string) string {
+	logger.Errorf("[tgbot] setting %s unreadable, using %q: %v", name, fallback, err)
+	if t.IsRunning() && shouldAlertSetting(name, time.Now()) {
+		shown := fallback
+		if shown == "" {
+			shown = t.I18nBot("tgbot.messages.settingEmpty")
+		}
+		go t.deliverNotification(NotifySettings, t.I18nBot("tgbot.messages.settingUnreadable",
+			"Name=="+name,
+			"Fallback=="+escapeField(shown),
+			"Error=="+escapeField(err.Error())))
+	}
+	return fallback
+}
+
+// settingCheck reads one setting the bot depends on.
+type settingCheck struct {
+	name string
+	read func() error
+}
+
+// requiredSettings lists the settings the bot reads to run.
+func (t *Tgbot) requiredSettings() []settingCheck {
+	s := &t.settingService
+	return []settingCheck{
+		{"tgBotToken", func() error { _, err := s.GetTgBotToken(); return err }},
+		{"tgBotChatId", func() error { _, err := s.GetTgBotChatId(); return err }},
+		{"tgRunTime", func() error { _, err := s.GetTgbotRuntime(); return err }},
+		{"tgBotBackup", func() error { _, err := s.GetTgBotBackup(); return err }},
+		{"tgBotLoginNotify", func() error { _, err := s.GetTgBotLoginNotify(); return err }},
+		{"tgCpu", func() error { _, err := s.GetTgCpu(); return err }},
+		{"tgQuietStart", func() error { _, err := s.GetTgQuietStart(); return err }},
+		{"tgQuietEnd", func() error { _, err := s.GetTgQuietEnd(); return err }},
+		{"timeLocation", func() error { _, err := s.GetTimeLocation(); return err }},
+		{"trafficDiff", func() error { _, err := s.GetTrafficDiff(); return err }},
+		{"expireDiff", func() error { _, err := s.GetExpireDiff(); return err }},
+	}
+}
+
+// unreadableSettings runs every check and returns the failures as
+// "name: error" lines, in check order.
+func unreadableSettings(checks []settingCheck) []string {
+	var failed []string
+	for _, check := range checks {
+		if err := check.read(); err != nil {
+			failed = append(failed, check.name+": "+err.Error())
+		}
+	}
+	return failed
+}
+
+// checkSettings is the startup self-check: it verifies every setting the bot
+// needs can be read and reports the ones that can't to admins.
+func (t *Tgbot) checkSettings() {
+	failed := unreadableSettings(t.requiredSettings())
+	if len(failed) == 0 {
+		return
+	}
+	for _, line := range failed {
+		logger.Errorf("[tgbot] startup check: setting %s", line)
+	}
+	for i, line := range failed {
+		failed[i] = "• " + escapeField(line)
+	}
+	t.deliverNotification(NotifySettings, t.I18nBot("tgbot.messages.settingsCheckFailed",
+		"Settings=="+strings.Join(failed, "\r\n")))
+}
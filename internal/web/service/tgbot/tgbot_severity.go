@@ -0,0 +1,111 @@
+package tgbot
+
+import (
+	"strings"
+	"sync/atomic"
+	"unicode"
+)
+
+// severity classifies a bot message so it can be told apart at a glance in
+// a busy chat.
+type severity int
+
+const (
+	severityNone severity = iota // no prefix of its own; taken from the message's leading marker, if any
+	severityInfo
+	severitySuccess
+	severityWarning
+	severityCritical
+)
+
+// severityEmoji is the prefix of each severity.
+var severityEmoji = map[severity]string{
+	severityInfo:     "ℹ️",
+	severitySuccess:  "✅",
+	severityWarning:  "⚠️",
+	severityCritical: "🔴",
+}
+
+// severityMarkers are the leading emoji the bot's messages already use to
+// flag an outcome, longest first so "⚠️" wins over "⚠". Thematic emoji such
+// as 🔕 or 📊 say what a message is about, not how bad it is, and are kept.
+var severityMarkers = []struct {
+	marker   string
+	severity severity
+}{
+	{"ℹ️", severityInfo},
+	{"ℹ", severityInfo},
+	{"✅", severitySuccess},
+	{"⚠️", severityWarning},
+	{"⚠", severityWarning},
+	{"❗️", severityWarning},
+	{"❗", severityWarning},
+	{"🔴", severityCritical},
+	{"🚨", severityCritical},
+}
+
+// severityEmojiOff is set on Start when the tgSeverityEmoji setting is
+// turned off.
+var severityEmojiOff atomic.Bool
+
+// categorySeverity is the severity notifications of category are sent with.
+// Login notifications cover both successful and failed attempts, so they
+// keep the marker their message starts with.
+func categorySeverity(category string) severity {
+	switch category {
+	case NotifyReport, NotifyReminder, NotifyTrafficReset:
+		return severityInfo
+	case NotifyCPU, NotifyClients, NotifyCertExpiring:
+		return severityWarning
+	case NotifyXray, NotifySettings:
+		return severityCritical
+	default:
+		return severityNone
+	}
+}
+
+// leadingSeverity returns the severity flagged by the marker msg starts
+// with and msg without that marker.
+func leadingSeverity(msg string) (severity, string) {
+	for _, m := range severityMarkers {
+		if rest, ok := strings.CutPrefix(msg, m.marker); ok {
+			return m.severity, strings.TrimLeft(rest, " ")
+		}
+	}
+	return severityNone, msg
+}
+
+// isEmojiRune reports whether r is part of an emoji: a pictograph or one of
+// the joiners, selectors and modifiers emoji are composed with.
+func isEmojiRune(r rune) bool {
+	return unicode.Is(unicode.So, r) || (r >= 0x1F3FB && r <= 0x1F3FF) ||
+		r == '\u200d' || r == '\ufe0f' || r == '\u20e3'
+}
+
+// stripLeadingEmoji drops the emoji and spaces msg starts with.
+func stripLeadingEmoji(msg string) string {
+	return strings.TrimLeftFunc(msg, func(r rune) bool { return isEmojiRune(r) || r == ' ' })
+}
+
+// withSeverity prefixes msg with the emoji of sev. A leading marker already
+// in msg is replaced rather than doubled, and with severityNone it decides
+// the severity. When emoji are turned off, msg loses its leading emoji
+// instead. Applying it twice gives the same result.
+func withSeverity(sev severity, msg string, enabled bool) string {
+	if !enabled {
+		return stripLeadingEmoji(msg)
+	}
+	marked, rest := leadingSeverity(msg)
+	if sev == severityNone {
+		sev = marked
+	}
+	if sev == severityNone {
+		return msg
+	}
+	return severityEmoji[sev] + " " + rest
+}
+
+// decorate applies withSeverity with the current setting.
+func decorate(sev severity, msg string) string {
+	return withSeverity(sev, msg, !severityEmojiOff.Load())
+}
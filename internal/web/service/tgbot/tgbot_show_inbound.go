@@ -0,0 +1,151 @@
+package tgbot
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"html"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/zixu5u/3xv/v3/internal/logger"
+	"github.com/zixu5u/3xv/v3/internal/xray"
+
+	tu "github.com/mymmrac/telego/telegoutil"
+)
+
+// errInboundNotInConfig is an inbound the generated config leaves out,
+// e.g. because it is disabled or runs on a remote node.
+var errInboundNotInConfig = errors.New("inbound not in the generated config")
+
+// parseShowInboundArgs reads "/showinbound <tag> [full]". full asks for the
+// unredacted document, which needs a confirmation.
+func parseShowInboundArgs(args []string) (tag string, full bool, ok bool) {
+	switch {
+	case len(args) == 1:
+		return args[0], false, true
+	case len(args) == 2 && strings.EqualFold(args[1], "full"):
+		return args[0], true, true
+	}
+	return "", false, false
+}
+
+// inboundConfigBlock returns the block of the inbound tag in the config a
+// restart would apply, decoded, and the number of its clients.
+func inboundConfigBlock(cfg *xray.Config, tag string) (map[string]any, int, error) {
+	for _, inbound := range cfg.InboundConfigs {
+		if inbound.Tag != tag {
+			continue
+		}
+		data, err := json.Marshal(inbound)
+		if err != nil {
+			return nil, 0, err
+		}
+		var block map[string]any
+		if err := json.Unmarshal(data, &block); err != nil {
+			return nil, 0, err
+		}
+		clients := 0
+		if settings, ok := block["settings"].(map[string]any); ok {
+			if list, ok := settings["clients"].([]any); ok {
+				clients = len(list)
+			}
+		}
+		return block, clients, nil
+	}
+	return nil, 0, errInboundNotInConfig
+}
+
+// sendShowInbound implements /showinbound: the block of one inbound in the
+// Xray config, built by the same generator a restart uses. The secrets are
+// redacted; the block is shown inline when it fits and sent as a document
+// otherwise. With full, the unredacted block is sent as a document, which
+// showInboundFull does once the admin confirmed.
+func (t *Tgbot) sendShowInbound(chatId int64, tag string, full bool, requestedBy int64) {
+	if full {
+		inlineKeyboard := tu.InlineKeyboard(
+			tu.InlineKeyboardRow(
+				tu.InlineKeyboardButton(t.I18nBot("tgbot.buttons.confirmShowInbound")).WithCallbackData(t.encodeQuery("show_inbound_full "+tag)),
+				tu.InlineKeyboardButton(t.I18nBot("tgbot.buttons.cancel")).WithCallbackData(t.encodeQuery("show_inbound_cancel "+tag)),
+			),
+		)
+		t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.messages.showInboundFullConfirm", "Tag=="+escapeField(tag)), inlineKeyboard)
+		return
+	}
+	block, summary, ok := t.loadInboundBlock(chatId, tag)
+	if !ok {
+		return
+	}
+	redactConfigSecrets(block)
+	data, err := json.MarshalIndent(block, "", "  ")
+	if err != nil {
+		t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.messages.previewConfigFailed", "Error=="+html.EscapeString(err.Error())))
+		return
+	}
+	logBotEvent(botEvent{Event: "show_inbound", ChatID: requestedBy, Command: "showinbound", Outcome: "redacted"})
+	if len(data) <= configSnippetBytes {
+		t.SendMsgToTgbot(chatId, summary+"\r\n<pre><code class=\"language-json\">"+html.EscapeString(string(data))+"</code></pre>")
+		return
+	}
+	t.sendInboundDocument(chatId, tag, data, summary+"\r\n"+t.I18nBot("tgbot.messages.showInboundRedacted"))
+}
+
+// showInboundFull sends the unredacted block of the inbound tag as a
+// document, after the admin confirmed.
+func (t *Tgbot) showInboundFull(chatId int64, tag string, requestedBy int64) {
+	block, summary, ok := t.loadInboundBlock(chatId, tag)
+	if !ok {
+		return
+	}
+	data, err := json.MarshalIndent(block, "", "  ")
+	if err != nil {
+		t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.messages.previewConfigFailed", "Error=="+html.EscapeString(err.Error())))
+		return
+	}
+	logger.Infof("Unredacted config of inbound %s sent to Telegram user %d", tag, requestedBy)
+	logBotEvent(botEvent{Event: "show_inbound", ChatID: requestedBy, Command: "showinbound", Outcome: "full"})
+	t.sendInboundDocument(chatId, tag, data, summary+"\r\n"+t.I18nBot("tgbot.messages.previewConfigFileCaption"))
+}
+
+// loadInboundBlock generates the config and returns the block of the
+// inbound tag with its summary line. It reports the problem to chatId and
+// returns false when there is none.
+func (t *Tgbot) loadInboundBlock(chatId int64, tag string) (map[string]any, string, bool) {
+	xrayConfig, err := t.xrayService.GetXrayConfig()
+	if err != nil {
+		t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.messages.previewConfigFailed", "Error=="+html.EscapeString(err.Error())))
+		return nil, "", false
+	}
+	block, clients, err := inboundConfigBlock(xrayConfig, tag)
+	if errors.Is(err, errInboundNotInConfig) {
+		t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.messages.showInboundNotFound", "Tag=="+escapeField(tag)))
+		return nil, "", false
+	}
+	if err != nil {
+		t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.messages.previewConfigFailed", "Error=="+html.EscapeString(err.Error())))
+		return nil, "", false
+	}
+	protocol, _ := block["protocol"].(string)
+	port, _ := block["port"].(float64)
+	summary := t.I18nBot("tgbot.messages.showInboundSummary",
+		"Tag=="+escapeField(tag),
+		"Protocol=="+escapeField(protocol),
+		"Port=="+strconv.Itoa(int(port)),
+		"Clients=="+strconv.Itoa(clients))
+	return block, summary, true
+}
+
+// sendInboundDocument uploads the config block of an inbound as a JSON file.
+func (t *Tgbot) sendInboundDocument(chatId int64, tag string, data []byte, caption string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	document := tu.Document(
+		tu.ID(chatId),
+		tu.FileFromBytes(data, "inbound-"+tag+".json"),
+	).WithCaption(caption).WithParseMode("HTML")
+	if _, err := bot.SendDocument(ctx, document); err != nil {
+		logger.Warning("Error in uploading the inbound config:", err)
+		t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.messages.previewConfigFailed", "Error=="+html.EscapeString(err.Error())))
+	}
+}
@@ -0,0 +1,70 @@
+package tgbot
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"sync/atomic"
+
+	"github.com/zixu5u/3xv/v3/internal/logger"
+)
+
+// defaultSilentCategories are the routine, info-level notifications, which
+// arrive without a sound unless tgSilentCategories says otherwise.
+const defaultSilentCategories = NotifyReport + "," + NotifyTrafficReset
+
+// silentCategories are the notification categories sent without a sound.
+// They are loaded by Start from tgSilentCategories.
+var silentCategories atomic.Pointer[[]string]
+
+// canBeSilent reports whether notifications of category may be sent
+// without a sound. Critical ones always make one.
+func canBeSilent(category string) bool {
+	return !criticalNotifications[category] && categorySeverity(category) != severityCritical
+}
+
+// parseSilentCategories reads a comma-separated list of notification
+// categories to send silently. An empty list keeps every one audible.
+func parseSilentCategories(value string) ([]string, error) {
+	categories := parseChannelCategories(value)
+	for _, category := range categories {
+		if !slices.Contains(notifyCategories, category) {
+			return nil, errors.New("expected categories from " + strings.Join(notifyCategories, ", "))
+		}
+		if !canBeSilent(category) {
+			return nil, errors.New(category + " notifications are critical and can't be silent")
+		}
+	}
+	return categories, nil
+}
+
+// silentCategoryList normalizes a tgSilentCategories value for /setsetting.
+func silentCategoryList(value string) (string, error) {
+	categories, err := parseSilentCategories(value)
+	if err != nil {
+		return "", err
+	}
+	return strings.Join(categories, ","), nil
+}
+
+// loadSilentCategories reads tgSilentCategories. An unreadable or invalid
+// value falls back to defaultSilentCategories.
+func (t *Tgbot) loadSilentCategories() {
+	value, err := t.settingService.GetTgSilentCategories()
+	if err != nil {
+		value = t.settingFallback("tgSilentCategories", err, defaultSilentCategories)
+	}
+	categories, err := parseSilentCategories(value)
+	if err != nil {
+		logger.Warning("Invalid Telegram bot silent categories, using the default:", err)
+		categories, _ = parseSilentCategories(defaultSilentCategories)
+	}
+	silentCategories.Store(&categories)
+}
+
+// isSilent reports whether notifications of category are sent without a
+// sound. Messages that aren't notifications have no category and never are.
+func isSilent(category string) bool {
+	categories := silentCategories.Load()
+	return category != "" && categories != nil && slices.Contains(*categories, category)
+}
@@ -0,0 +1,47 @@
+package tgbot
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/zixu5u/3xv/v3/internal/config"
+	"github.com/zixu5u/3xv/v3/internal/logger"
+)
+
+// startupNotice is armed once per process by the panel and consumed by the
+// first bot that gets its long polling up, so the notice is never attempted
+// before the bot can actually send and isn't repeated on bot restarts.
+var startupNotice struct {
+	pending   atomic.Bool
+	cleanExit atomic.Bool
+}
+
+// QueueStartupNotice arms the startup notification. cleanExit reports
+// whether the previous run shut down cleanly.
+func QueueStartupNotice(cleanExit bool) {
+	startupNotice.cleanExit.Store(cleanExit)
+	startupNotice.pending.Store(true)
+}
+
+// sendStartupNotice sends the queued startup notification to admins when
+// tgBotStartupNotify is enabled.
+func (t *Tgbot) sendStartupNotice() {
+	if !startupNotice.pending.CompareAndSwap(true, false) {
+		return
+	}
+	enabled, err := t.settingService.GetTgBotStartupNotify()
+	if err != nil || !enabled {
+		return
+	}
+	msg := t.I18nBot("tgbot.messages.startupNotice",
+		"Version=="+config.GetVersion(),
+		"XrayVersion=="+t.xrayService.GetXrayVersion(),
+		"Time=="+time.Now().Format("15:04"))
+	if startupNotice.cleanExit.Load() {
+		msg += t.I18nBot("tgbot.messages.startupClean")
+	} else {
+		msg += t.I18nBot("tgbot.messages.startupUnclean")
+	}
+	logger.Info("Sending Telegram startup notification")
+	t.SendMsgToTgbotAdmins(msg)
+}
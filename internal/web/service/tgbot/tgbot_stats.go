@@ -0,0 +1,142 @@
+package tgbot
+
+import (
+	"cmp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// botStats holds the bot's own counters for /botstats. They count from the
+// last time the receiver started and are updated without locks from the
+// dispatcher and send paths; only the per-category map needs the mutex.
+var botStats struct {
+	startedAt    atomic.Int64 // unix nanoseconds, 0 until the receiver first starts
+	updates      atomic.Int64
+	messagesSent atomic.Int64
+	sendsFailed  atomic.Int64
+
+	mu            sync.Mutex
+	notifications map[string]int64
+}
+
+// botStatsSnapshot is a consistent-enough copy of botStats for reporting.
+type botStatsSnapshot struct {
+	Uptime        time.Duration
+	Updates       int64
+	MessagesSent  int64
+	SendsFailed   int64
+	Notifications []categoryCount
+	BusyWorkers   int
+	Workers       int
+	Queued        int64
+}
+
+// categoryCount is the number of notifications fired for one category.
+type categoryCount struct {
+	Category string
+	Count    int64
+}
+
+// resetBotStats zeroes the counters and marks now as the start time.
+func resetBotStats(now time.Time) {
+	botStats.updates.Store(0)
+	botStats.messagesSent.Store(0)
+	botStats.sendsFailed.Store(0)
+	botStats.mu.Lock()
+	botStats.notifications = make(map[string]int64)
+	botStats.mu.Unlock()
+	botStats.startedAt.Store(now.UnixNano())
+}
+
+// recordNotification counts one notification fired for category.
+func recordNotification(category string) {
+	botStats.mu.Lock()
+	defer botStats.mu.Unlock()
+	if botStats.notifications == nil {
+		botStats.notifications = make(map[string]int64)
+	}
+	botStats.notifications[category]++
+}
+
+// recordSend counts one message page handed to Telegram.
+func recordSend(err error) {
+	if err != nil {
+		botStats.sendsFailed.Add(1)
+		return
+	}
+	botStats.messagesSent.Add(1)
+}
+
+// takeBotStats copies the counters. Notification categories are ordered by
+// count, busiest first. busy and workers describe the handler pool; handlers
+// beyond the busy ones are waiting for a free worker.
+func takeBotStats(now time.Time, busy, workers int) botStatsSnapshot {
+	snap := botStatsSnapshot{
+		Updates:      botStats.updates.Load(),
+		MessagesSent: botStats.messagesSent.Load(),
+		SendsFailed:  botStats.sendsFailed.Load(),
+		BusyWorkers:  busy,
+		Workers:      workers,
+		Queued:       max(activeHandlers.Load()-int64(busy), 0),
+	}
+	if started := botStats.startedAt.Load(); started > 0 {
+		snap.Uptime = now.Sub(time.Unix(0, started))
+	}
+
+	botStats.mu.Lock()
+	for category, count := range botStats.notifications {
+		snap.Notifications = append(snap.Notifications, categoryCount{Category: category, Count: count})
+	}
+	botStats.mu.Unlock()
+	slices.SortFunc(snap.Notifications, func(a, b categoryCount) int {
+		if c := cmp.Compare(b.Count, a.Count); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Category, b.Category)
+	})
+	return snap
+}
+
+// sendBotStats implements /botstats: how long the bot has been up and how
+// much it has done since, as opposed to the panel's own statistics, and the
+// recipients that keep failing and should be removed.
+func (t *Tgbot) sendBotStats(chatId int64) {
+	pool := messageWorkerPool
+	snap := takeBotStats(time.Now(), len(pool), cap(pool))
+
+	var output strings.Builder
+	output.WriteString(t.I18nBot("tgbot.messages.botStatsHeader"))
+	output.WriteString(t.I18nBot("tgbot.messages.botStatsUptime", "Uptime=="+snap.Uptime.Round(time.Second).String()))
+	output.WriteString(t.I18nBot("tgbot.messages.botStatsUpdates", "Count=="+strconv.FormatInt(snap.Updates, 10)))
+	output.WriteString(t.I18nBot("tgbot.messages.botStatsSent",
+		"Sent=="+strconv.FormatInt(snap.MessagesSent, 10),
+		"Failed=="+strconv.FormatInt(snap.SendsFailed, 10)))
+	output.WriteString(t.I18nBot("tgbot.messages.botStatsWorkers",
+		"Busy=="+strconv.Itoa(snap.BusyWorkers),
+		"Total=="+strconv.Itoa(snap.Workers),
+		"Queued=="+strconv.FormatInt(snap.Queued, 10)))
+	if len(snap.Notifications) == 0 {
+		output.WriteString(t.I18nBot("tgbot.messages.botStatsNoNotifications"))
+	} else {
+		output.WriteString(t.I18nBot("tgbot.messages.botStatsNotifications"))
+		for _, n := range snap.Notifications {
+			output.WriteString(t.I18nBot("tgbot.messages.botStatsCategory",
+				"Category=="+escapeField(n.Category),
+				"Count=="+strconv.FormatInt(n.Count, 10)))
+		}
+	}
+	if chronic := chronicChats(); len(chronic) > 0 {
+		output.WriteString(t.I18nBot("tgbot.messages.botStatsChronic", "Streak=="+strconv.Itoa(chronicFailureStreak)))
+		for _, c := range chronic {
+			output.WriteString(t.I18nBot("tgbot.messages.botStatsChronicChat",
+				"ChatId=="+strconv.FormatInt(c.ChatId, 10),
+				"Count=="+strconv.Itoa(c.Streak),
+				"Reason=="+c.Kind))
+		}
+	}
+	t.SendMsgToTgbot(chatId, output.String())
+}
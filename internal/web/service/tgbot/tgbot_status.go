@@ -9,13 +9,13 @@ import (
 
 	"github.com/zixu5u/3xv/v3/internal/config"
 	"github.com/zixu5u/3xv/v3/internal/logger"
-	"github.com/zixu5u/3xv/v3/internal/util/common"
+	"github.com/zixu5u/3xv/v3/internal/web/service"
 
 	"github.com/mymmrac/telego"
 )
 
 // getServerAndInboundsStatus 获取服务器和所有启用节点的状态信息
-// 返回格式化的状态消息，仅显示启用的节点
+// 返回格式化的状态消息；tgReportDisabledInbounds 开启时也列出禁用的节点并以🚫标记
 func (t *Tgbot) getServerAndInboundsStatus() string {
 	var info strings.Builder
 
@@ -51,6 +51,12 @@ func (t *Tgbot) getServerAndInboundsStatus() string {
 	}
 	info.WriteString("✅xray状态:" + xrayStatus + "\r\n")
 
+	// 在线客户及今日峰值
+	if p := service.XrayProcess(); p != nil && p.IsRunning() {
+		info.WriteString(t.I18nBot("tgbot.messages.onlinesCount", "Count=="+strconv.Itoa(len(p.GetOnlineClients()))))
+	}
+	info.WriteString(t.getOnlineTrend())
+
 	// 获取公网IP
 	ipv4, ipv6 := t.getServerIPs()
 	if ipv4 != "" {
@@ -72,6 +78,11 @@ func (t *Tgbot) getServerAndInboundsStatus() string {
 		return info.String()
 	}
 
+	if len(inbounds) == 0 {
+		info.WriteString(t.I18nBot("tgbot.messages.noInbounds"))
+		return info.String()
+	}
+
 	// 统计启用的节点
 	enabledCount := 0
 	for _, inbound := range inbounds {
@@ -80,31 +91,30 @@ func (t *Tgbot) getServerAndInboundsStatus() string {
 		}
 	}
 
-	if enabledCount == 0 {
+	listed := reportInbounds(inbounds, t.reportDisabledInbounds())
+	if len(listed) == 0 {
 		info.WriteString("⚠️ 没有启用的节点\r\n")
 		return info.String()
 	}
 
 	info.WriteString(fmt.Sprintf("📊 共 %d 个节点启用\r\n\r\n", enabledCount))
 
-	// 逐个显示启用的节点
-	for _, inbound := range inbounds {
-		if !inbound.Enable {
-			continue // 跳过禁用的节点
-		}
-
-		info.WriteString("🆔节点名称:" + inbound.Remark + "\r\n")
+	// 逐个显示节点
+	counts := t.inboundClientCounts()
+	for _, inbound := range listed {
+		info.WriteString("🆔节点名称:" + reportRemark(inbound) + "\r\n")
 		info.WriteString("🔗节点类型:" + string(inbound.Protocol) + "\r\n")
 		info.WriteString("🎯节点端口:" + strconv.Itoa(inbound.Port) + "\r\n")
 
 		// 流量信息
-		info.WriteString("⏫上行流量↑:" + common.FormatTraffic(inbound.Up) + "\r\n")
-		info.WriteString("⏬下行流量↓:" + common.FormatTraffic(inbound.Down) + "\r\n")
-		info.WriteString("📊整体流量:" + common.FormatTraffic(inbound.Up+inbound.Down) + "\r\n")
+		info.WriteString("⏫上行流量↑:" + formatTraffic(inbound.Up) + "\r\n")
+		info.WriteString("⏬下行流量↓:" + formatTraffic(inbound.Down) + "\r\n")
+		info.WriteString("📊整体流量:" + formatTraffic(inbound.Up+inbound.Down) + "\r\n")
+		info.WriteString(t.inboundClientsLine(counts, inbound.Id))
 
 		// 总流量限制
 		if inbound.Total > 0 {
-			info.WriteString("❄️流量限制:" + common.FormatTraffic(inbound.Total) + "\r\n")
+			info.WriteString("❄️流量限制:" + formatTraffic(inbound.Total) + "\r\n")
 		} else {
 			info.WriteString("❄️流量限制:♾️无限\r\n")
 		}
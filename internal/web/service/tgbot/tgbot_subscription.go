@@ -0,0 +1,97 @@
+package tgbot
+
+import (
+	"context"
+	"html"
+
+	tu "github.com/mymmrac/telego/telegoutil"
+	"github.com/skip2/go-qrcode"
+)
+
+// subscriptionSetupError explains why subscription URLs can't be handed out,
+// or returns "" when the subscription server is enabled and has a public
+// address to build URLs from.
+func (t *Tgbot) subscriptionSetupError() string {
+	enabled, err := t.settingService.GetSubEnable()
+	if err != nil || !enabled {
+		return t.I18nBot("tgbot.messages.subscriptionDisabled")
+	}
+	subURI, _ := t.settingService.GetSubURI()
+	subDomain, _ := t.settingService.GetSubDomain()
+	webDomain, _ := t.settingService.GetWebDomain()
+	if subURI == "" && subDomain == "" && webDomain == "" {
+		return t.I18nBot("tgbot.messages.subscriptionNoBaseURL")
+	}
+	return ""
+}
+
+// canSeeSubscription reports whether tgUserID may get email's subscription:
+// admins may see any client, everyone else only clients bound to them.
+func (t *Tgbot) canSeeSubscription(tgUserID int64, email string, isAdmin bool) bool {
+	if isAdmin {
+		return true
+	}
+	traffics, err := t.inboundService.GetClientTrafficTgBot(tgUserID)
+	if err != nil {
+		return false
+	}
+	for _, traffic := range traffics {
+		if traffic.Email == email {
+			return true
+		}
+	}
+	return false
+}
+
+// sendSubscription sends a client's aggregated subscription URL, the one
+// an app polls to pick up all of the client's configs.
+func (t *Tgbot) sendSubscription(chatId int64, email string, tgUserID int64, isAdmin bool) {
+	if msg := t.subscriptionSetupError(); msg != "" {
+		t.SendMsgToTgbot(chatId, msg)
+		return
+	}
+	if !t.canSeeSubscription(tgUserID, email, isAdmin) {
+		t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.noResult"))
+		return
+	}
+	subURL, _, err := t.buildSubscriptionURLs(email)
+	if err != nil {
+		t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.answers.errorOperation")+"\r\n"+html.EscapeString(err.Error()))
+		return
+	}
+	inlineKeyboard := tu.InlineKeyboard(
+		tu.InlineKeyboardRow(
+			tu.InlineKeyboardButton(t.I18nBot("qrCode")).WithCallbackData(t.encodeQuery("client_sub_qr " + email)),
+		),
+	)
+	t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.messages.subscriptionUrl",
+		"Email=="+escapeField(email),
+		"URL=="+html.EscapeString(subURL)), inlineKeyboard)
+}
+
+// sendSubscriptionQR sends the subscription URL of email as a QR code.
+func (t *Tgbot) sendSubscriptionQR(chatId int64, email string, tgUserID int64, isAdmin bool) {
+	if msg := t.subscriptionSetupError(); msg != "" {
+		t.SendMsgToTgbot(chatId, msg)
+		return
+	}
+	if !t.canSeeSubscription(tgUserID, email, isAdmin) {
+		t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.noResult"))
+		return
+	}
+	subURL, _, err := t.buildSubscriptionURLs(email)
+	if err != nil {
+		t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.answers.errorOperation")+"\r\n"+html.EscapeString(err.Error()))
+		return
+	}
+	png, err := qrcode.Encode(subURL, qrcode.Medium, 320)
+	if err != nil {
+		t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.answers.errorOperation")+"\r\n"+err.Error())
+		return
+	}
+	document := tu.Document(
+		tu.ID(chatId),
+		tu.FileFromBytes(png, "sub.png"),
+	).WithCaption(email)
+	_, _ = bot.SendDocument(context.Background(), document)
+}
@@ -0,0 +1,114 @@
+package tgbot
+
+import (
+	"errors"
+	"html"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/zixu5u/3xv/v3/internal/logger"
+
+	"github.com/mymmrac/telego/telegoapi"
+)
+
+// notifyCategories lists every notification category, in display order.
+var notifyCategories = []string{NotifyReport, NotifyLogin, NotifyCPU, NotifyXray, NotifySettings, NotifyReminder, NotifyClients, NotifyCertExpiring, NotifyTrafficReset}
+
+// Delivery outcomes reported by /testnotify.
+const (
+	deliveryDelivered = "delivered"
+	deliveryBlocked   = "blocked"
+	deliveryFailed    = "failed"
+)
+
+var deliveryLabels = map[string]string{
+	deliveryDelivered: "tgbot.messages.testNotifyDelivered",
+	deliveryBlocked:   "tgbot.messages.testNotifyBlocked",
+	deliveryFailed:    "tgbot.messages.testNotifyFailed",
+}
+
+// classifyDelivery maps a send error to a delivery outcome. Telegram answers
+// 403 when the bot was blocked, kicked, or never started by the user.
+func classifyDelivery(err error) string {
+	if err == nil {
+		return deliveryDelivered
+	}
+	var apiErr *telegoapi.Error
+	if errors.As(err, &apiErr) && apiErr.ErrorCode == http.StatusForbidden {
+		return deliveryBlocked
+	}
+	return deliveryFailed
+}
+
+// sendTestNotification sends a labelled test message through the regular
+// send path to every recipient that would get a notification of category,
+// or of any category when it is empty, and reports per-chat results back.
+// For the channel, a failure the admins must fix is explained as well.
+func (t *Tgbot) sendTestNotification(chatId int64, category string, requestedBy int64) {
+	category = strings.ToLower(strings.TrimSpace(category))
+	if category != "" && !slices.Contains(notifyCategories, category) {
+		t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.messages.testNotifyUsage",
+			"Categories=="+strings.Join(notifyCategories, ", ")))
+		return
+	}
+
+	msg := t.I18nBot("tgbot.messages.testNotifyMessage",
+		"Hostname=="+hostname,
+		"Time=="+time.Now().Format("2006-01-02 15:04:05"))
+	msg = decorate(categorySeverity(category), msg)
+
+	tgBotMutex.Lock()
+	primary := slices.Clone(adminIds)
+	tgBotMutex.Unlock()
+	extraBotsMutex.Lock()
+	bots := slices.Clone(extraBots)
+	extraBotsMutex.Unlock()
+
+	var report strings.Builder
+	report.WriteString(t.I18nBot("tgbot.messages.testNotifyHeader"))
+	counts := map[string]int{}
+	record := func(botName string, target int64, err error) string {
+		outcome := classifyDelivery(err)
+		counts[outcome]++
+		logBotEvent(botEvent{Event: "test_notification", Bot: botName, ChatID: target, Category: category, Outcome: outcome, Err: err})
+		if err != nil {
+			logger.Warningf("Test notification to %d failed: %v", target, err)
+		}
+		return t.I18nBot(deliveryLabels[outcome])
+	}
+
+	for _, target := range primary {
+		err := t.sendMsgVia(bot, target, msg)
+		report.WriteString("\r\n<code>" + strconv.FormatInt(target, 10) + "</code>: " + record("", target, err))
+	}
+	for _, eb := range bots {
+		if category != "" && !eb.wants(category) {
+			continue
+		}
+		report.WriteString("\r\n\r\n🤖 " + html.EscapeString(eb.name) + " [" + html.EscapeString(strings.Join(eb.categories, ", ")) + "]")
+		for _, target := range eb.chatIds {
+			err := t.sendMsgVia(eb.bot, target, msg)
+			report.WriteString("\r\n<code>" + strconv.FormatInt(target, 10) + "</code>: " + record(eb.name, target, err))
+		}
+	}
+
+	if id, categories, ok := t.channel(); ok && (category == "" || slices.Contains(categories, category)) {
+		report.WriteString("\r\n\r\n📢 " + t.I18nBot("tgbot.messages.testNotifyChannel") + " [" + html.EscapeString(strings.Join(categories, ", ")) + "]")
+		err := t.sendMsgVia(bot, id, msg)
+		report.WriteString("\r\n<code>" + strconv.FormatInt(id, 10) + "</code>: " + record("channel", id, err))
+		if problem := classifyChannelError(err); problem != "" {
+			report.WriteString("\r\n" + t.channelProblemMessage(id, problem))
+		}
+	}
+
+	report.WriteString("\r\n\r\n" + t.I18nBot("tgbot.messages.testNotifySummary",
+		"Delivered=="+strconv.Itoa(counts[deliveryDelivered]),
+		"Blocked=="+strconv.Itoa(counts[deliveryBlocked]),
+		"Failed=="+strconv.Itoa(counts[deliveryFailed])))
+	logger.Infof("Test notification requested by %d: %d delivered, %d blocked, %d failed",
+		requestedBy, counts[deliveryDelivered], counts[deliveryBlocked], counts[deliveryFailed])
+	t.SendMsgToTgbot(chatId, report.String())
+}
@@ -0,0 +1,122 @@
+package tgbot
+
+import (
+	"html"
+	"strconv"
+	"strings"
+
+	"github.com/zixu5u/3xv/v3/internal/database/model"
+	"github.com/zixu5u/3xv/v3/internal/logger"
+	"github.com/zixu5u/3xv/v3/internal/web/service"
+)
+
+// thresholdChanges are the overrides a /thresholds command sets. A field
+// left false is kept as it is; a nil value with its field true goes back
+// to the panel default.
+type thresholdChanges struct {
+	SetTraffic bool
+	Traffic    *int
+	SetExpiry  bool
+	Expiry     *int
+}
+
+// parseThresholdsArgs reads "/thresholds <tag> [traffic <percent|default>]
+// [expiry <days|default>]". ok is false for anything else; the values are
+// range-checked by the service.
+func parseThresholdsArgs(args []string) (tag string, changes thresholdChanges, ok bool) {
+	if len(args) == 0 || len(args)%2 == 0 {
+		return "", changes, false
+	}
+	for i := 1; i < len(args); i += 2 {
+		var value *int
+		if !strings.EqualFold(args[i+1], "default") {
+			n, err := strconv.Atoi(args[i+1])
+			if err != nil {
+				return "", changes, false
+			}
+			value = &n
+		}
+		switch strings.ToLower(args[i]) {
+		case "traffic":
+			if changes.SetTraffic {
+				return "", changes, false
+			}
+			changes.SetTraffic, changes.Traffic = true, value
+		case "expiry":
+			if changes.SetExpiry {
+				return "", changes, false
+			}
+			changes.SetExpiry, changes.Expiry = true, value
+		default:
+			return "", changes, false
+		}
+	}
+	return args[0], changes, true
+}
+
+// alertThresholds returns the panel-wide alert thresholds. An unreadable
+// trafficDiff or expireDiff counts as 0, as it did before overrides.
+func (t *Tgbot) alertThresholds() service.AlertThresholds {
+	trafficDiff, err := t.settingService.GetTrafficDiff()
+	if err != nil {
+		t.settingFallback("trafficDiff", err, "0")
+		trafficDiff = 0
+	}
+	expireDiff, err := t.settingService.GetExpireDiff()
+	if err != nil {
+		t.settingFallback("expireDiff", err, "0")
+		expireDiff = 0
+	}
+	return service.DefaultAlertThresholds(trafficDiff, expireDiff)
+}
+
+// thresholdsLine renders the effective alert thresholds of an inbound,
+// marking each as the inbound's own or the panel default.
+func (t *Tgbot) thresholdsLine(inbound *model.Inbound) string {
+	thresholds := t.alertThresholds().ForInbound(inbound)
+	traffic := t.I18nBot("tgbot.messages.thresholdsTrafficLeft", "Traffic=="+formatTraffic(thresholds.TrafficDiffBytes))
+	if thresholds.TrafficOverride {
+		traffic = t.I18nBot("tgbot.messages.thresholdsTrafficUsed", "Percent=="+strconv.Itoa(thresholds.TrafficPercent))
+	}
+	trafficSource := t.I18nBot("tgbot.messages.thresholdsDefault")
+	if thresholds.TrafficOverride {
+		trafficSource = t.I18nBot("tgbot.messages.thresholdsCustom")
+	}
+	expirySource := t.I18nBot("tgbot.messages.thresholdsDefault")
+	if thresholds.ExpiryOverride {
+		expirySource = t.I18nBot("tgbot.messages.thresholdsCustom")
+	}
+	return t.I18nBot("tgbot.messages.thresholds",
+		"Traffic=="+traffic,
+		"TrafficSource=="+trafficSource,
+		"Days=="+strconv.FormatInt(thresholds.ExpireDiffMs/86400000, 10),
+		"ExpirySource=="+expirySource)
+}
+
+// handleThresholdsCommand implements /thresholds: with only a tag it shows
+// the inbound's effective alert thresholds, otherwise it changes the given
+// overrides first.
+func (t *Tgbot) handleThresholdsCommand(chatId int64, tag string, changes thresholdChanges, requestedBy int64) {
+	inbound, err := t.inboundService.GetInboundByTag(tag)
+	if err != nil {
+		t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.messages.thresholdsNoInbound", "Tag=="+escapeField(tag)))
+		return
+	}
+	if changes.SetTraffic || changes.SetExpiry {
+		traffic, expiry := inbound.AlertTrafficPercent, inbound.AlertExpiryDays
+		if changes.SetTraffic {
+			traffic = changes.Traffic
+		}
+		if changes.SetExpiry {
+			expiry = changes.Expiry
+		}
+		if err := t.inboundService.SetInboundAlertThresholds(inbound.Id, traffic, expiry); err != nil {
+			logger.Warning("Failed to set inbound alert thresholds:", err)
+			t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.messages.thresholdsFailed", "Error=="+html.EscapeString(err.Error())))
+			return
+		}
+		logger.Infof("Alert thresholds of inbound %s set by Telegram user %d", inbound.Tag, requestedBy)
+		inbound.AlertTrafficPercent, inbound.AlertExpiryDays = traffic, expiry
+	}
+	t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.messages.thresholdsHeader", "Tag=="+escapeField(inbound.Tag))+t.thresholdsLine(inbound))
+}
@@ -0,0 +1,45 @@
+package tgbot
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/mymmrac/telego/telegoapi"
+)
+
+// tokenCheckTimeout bounds the live token check done when settings are
+// saved, so an unreachable Telegram doesn't hang the save.
+const tokenCheckTimeout = 10 * time.Second
+
+// CheckToken asks Telegram which bot token belongs to and returns the bot's
+// username. proxyUrl and apiServerUrl are the values being saved; like
+// Start, an empty proxy falls back to the panel's egress bridge, so the
+// token is checked over the route the bot will use.
+func (t *Tgbot) CheckToken(token string, proxyUrl string, apiServerUrl string) (string, error) {
+	if proxyUrl == "" {
+		if egress := t.settingService.PanelEgressProxyURL(); egress != "" && isSupportedBotProxyScheme(egress) {
+			proxyUrl = egress
+		}
+	}
+	b, err := t.NewBot(token, proxyUrl, apiServerUrl)
+	if err != nil {
+		return "", err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), tokenCheckTimeout)
+	defer cancel()
+	me, err := b.GetMe(ctx)
+	if err != nil {
+		return "", err
+	}
+	return me.Username, nil
+}
+
+// IsTokenRejected reports whether an error from CheckToken means Telegram
+// refused the token, rather than that it couldn't be reached.
+func IsTokenRejected(err error) bool {
+	var apiErr *telegoapi.Error
+	return errors.As(err, &apiErr) &&
+		(apiErr.ErrorCode == http.StatusUnauthorized || apiErr.ErrorCode == http.StatusNotFound)
+}
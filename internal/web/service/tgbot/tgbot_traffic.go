@@ -0,0 +1,190 @@
+package tgbot
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/zixu5u/3xv/v3/internal/database/model"
+	"github.com/zixu5u/3xv/v3/internal/logger"
+	"github.com/zixu5u/3xv/v3/internal/util/common"
+	"github.com/zixu5u/3xv/v3/internal/web/service"
+)
+
+// trafficStaleAfter is how old the last Xray stats sync may get before
+// traffic figures are flagged as stale. The traffic job syncs every 5s.
+const trafficStaleAfter = 2 * time.Minute
+
+// withTrafficFreshness appends the time of the last Xray stats sync to a
+// message carrying database traffic figures, and prepends a warning when
+// that sync is older than trafficStaleAfter or hasn't happened yet.
+func (t *Tgbot) withTrafficFreshness(msg string) string {
+	last := t.xrayService.LastTrafficSync()
+	if last.IsZero() {
+		return t.I18nBot("tgbot.messages.trafficNeverSynced") + msg
+	}
+	age := time.Since(last).Round(time.Second)
+	updated := t.I18nBot("tgbot.messages.trafficUpdated",
+		"Time=="+last.Format("2006-01-02 15:04:05"),
+		"Age=="+age.String())
+	if age > trafficStaleAfter {
+		return t.I18nBot("tgbot.messages.trafficStale", "Age=="+age.String()) + msg + updated
+	}
+	return msg + updated
+}
+
+// trafficFormat mirrors the tgTrafficUnits and tgTrafficDecimals settings.
+// It is refreshed on every bot Start; nil means the default format.
+var trafficFormat atomic.Pointer[common.TrafficFormat]
+
+// numberFormat mirrors the tgNumberFormat setting. It is refreshed on every
+// bot Start; nil means plain numbers.
+var numberFormat atomic.Pointer[common.NumberFormat]
+
+// currentNumberFormat returns the number format the bot settings ask for.
+func currentNumberFormat() common.NumberFormat {
+	if n := numberFormat.Load(); n != nil {
+		return *n
+	}
+	return common.NumberFormat{}
+}
+
+// formatTraffic renders a byte count the way the bot settings ask for.
+// Every traffic figure the bot sends should go through it.
+func formatTraffic(trafficBytes int64) string {
+	format := common.DefaultTrafficFormat
+	if f := trafficFormat.Load(); f != nil {
+		format = *f
+	}
+	return format.FormatWith(currentNumberFormat(), trafficBytes)
+}
+
+// formatDecimal renders a figure such as a percentage in the configured
+// number format.
+func formatDecimal(value float64, decimals int) string {
+	return currentNumberFormat().Format(value, decimals)
+}
+
+// inboundTrafficDelta is the traffic moved from Xray's in-memory counters
+// into the database for a single inbound during a reconciliation.
+type inboundTrafficDelta struct {
+	Tag  string
+	Up   int64
+	Down int64
+}
+
+// reconcileHistoryShown is how many runs /reconcile history lists.
+const reconcileHistoryShown = 10
+
+// reconcileTraffic runs the traffic sync of XrayTrafficJob right away,
+// returning the per-inbound deltas that were applied and the number of
+// client counters touched. The sync and the job take the same lock, and
+// Xray resets its counters on read, so nothing is counted twice.
+func (t *Tgbot) reconcileTraffic() ([]inboundTrafficDelta, int, error) {
+	traffics, clientTraffics, err := t.trafficSync.Sync()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	deltas := make([]inboundTrafficDelta, 0, len(traffics))
+	for _, tr := range traffics {
+		if tr == nil || !tr.IsInbound || tr.Up+tr.Down == 0 {
+			continue
+		}
+		deltas = append(deltas, inboundTrafficDelta{Tag: tr.Tag, Up: tr.Up, Down: tr.Down})
+	}
+	sort.Slice(deltas, func(i, j int) bool {
+		return deltas[i].Up+deltas[i].Down > deltas[j].Up+deltas[j].Down
+	})
+
+	clientCount := 0
+	for _, ct := range clientTraffics {
+		if ct != nil && ct.Up+ct.Down > 0 {
+			clientCount++
+		}
+	}
+	return deltas, clientCount, nil
+}
+
+// sendReconcile runs a traffic reconciliation and reports the applied deltas.
+func (t *Tgbot) sendReconcile(chatId int64, requestedBy int64) {
+	deltas, clientCount, err := t.reconcileTraffic()
+	if err != nil {
+		logger.Warningf("Traffic reconciliation requested by %d failed: %v", requestedBy, err)
+		t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.messages.reconcileFailed", "Error=="+err.Error()))
+		return
+	}
+
+	remarks := make(map[string]string)
+	if inbounds, err := t.inboundService.GetAllInbounds(); err == nil {
+		for _, inbound := range inbounds {
+			remarks[inbound.Tag] = inbound.Remark
+		}
+	}
+
+	var output strings.Builder
+	output.WriteString(t.I18nBot("tgbot.messages.reconcileHeader"))
+	if len(deltas) == 0 && clientCount == 0 {
+		output.WriteString(t.I18nBot("tgbot.messages.reconcileNoDelta"))
+	}
+	var totalUp, totalDown int64
+	for _, d := range deltas {
+		name := d.Tag
+		if remark, ok := remarks[d.Tag]; ok && remark != "" {
+			name = remark
+		}
+		totalUp += d.Up
+		totalDown += d.Down
+		output.WriteString(t.I18nBot("tgbot.messages.reconcileInbound",
+			"Remark=="+escapeField(name),
+			"Upload=="+formatTraffic(d.Up),
+			"Download=="+formatTraffic(d.Down)))
+	}
+	output.WriteString(t.I18nBot("tgbot.messages.reconcileClients", "Count=="+strconv.Itoa(clientCount)))
+
+	logger.Infof("Traffic reconciliation requested by %d: %d inbounds, %d clients, ↑%s ↓%s applied",
+		requestedBy, len(deltas), clientCount, formatTraffic(totalUp), formatTraffic(totalDown))
+	run := &model.TrafficReconciliation{
+		RequestedBy:  telegramActor(requestedBy),
+		Inbounds:     len(deltas),
+		Clients:      clientCount,
+		Up:           totalUp,
+		Down:         totalDown,
+		ReconciledAt: time.Now().UnixMilli(),
+	}
+	if err := t.trafficSync.RecordReconciliation(run); err != nil {
+		logger.Warning("Failed to record the traffic reconciliation:", err)
+	}
+	t.SendMsgToTgbot(chatId, output.String())
+}
+
+// sendReconcileHistory implements /reconcile history: the last runs of
+// /reconcile, who ran them and the traffic they moved.
+func (t *Tgbot) sendReconcileHistory(chatId int64) {
+	runs, err := t.trafficSync.RecentReconciliations(reconcileHistoryShown)
+	if err != nil {
+		logger.Warning("Failed to get traffic reconciliations:", err)
+		t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.wentWrong"))
+		return
+	}
+	if len(runs) == 0 {
+		t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.messages.reconcileHistoryNone",
+			"Days=="+strconv.Itoa(int(service.TrafficReconciliationRetention/(24*time.Hour)))))
+		return
+	}
+	var output strings.Builder
+	output.WriteString(t.I18nBot("tgbot.messages.reconcileHistoryHeader", "Count=="+strconv.Itoa(len(runs))))
+	loc := t.timeLocation()
+	for _, run := range runs {
+		output.WriteString(t.I18nBot("tgbot.messages.reconcileHistoryLine",
+			"Time=="+time.UnixMilli(run.ReconciledAt).In(loc).Format("2006-01-02 15:04"),
+			"By=="+escapeField(run.RequestedBy),
+			"Inbounds=="+strconv.Itoa(run.Inbounds),
+			"Clients=="+strconv.Itoa(run.Clients),
+			"Upload=="+formatTraffic(run.Up),
+			"Download=="+formatTraffic(run.Down)))
+	}
+	t.SendMsgToTgbot(chatId, output.String())
+}
@@ -0,0 +1,90 @@
+package tgbot
+
+import (
+	"html"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/zixu5u/3xv/v3/internal/web/service"
+)
+
+const (
+	// reportWaitWindow is how close to a traffic reset the scheduled report
+	// must be due for the reset to wait for it.
+	reportWaitWindow = time.Minute
+	// reportWaitTimeout bounds how long a reset waits for that report, e.g.
+	// when quiet hours hold it back.
+	reportWaitTimeout = 2 * time.Minute
+)
+
+// lastReportBuilt is when SendReport last read the traffic for a report, in
+// unix milliseconds.
+var lastReportBuilt atomic.Int64
+
+// reportDueNear returns when the primary bot's scheduled report runs if
+// that is within reportWaitWindow of at.
+func (t *Tgbot) reportDueNear(at time.Time) (time.Time, bool) {
+	runTime, err := t.settingService.GetTgbotRuntime()
+	if err != nil || IsReportScheduleOff(runTime) {
+		return time.Time{}, false
+	}
+	from := at.Add(-reportWaitWindow)
+	var due time.Time
+	if IsClockSchedule(runTime) {
+		due = t.calculateNextRunTime(from, strings.TrimSpace(runTime))
+	} else {
+		schedule, err := service.ParseInboundSchedule(runTime)
+		if err != nil {
+			return time.Time{}, false
+		}
+		due = schedule.Next(from.In(service.PanelCron().Location()))
+	}
+	return due, !due.After(at.Add(reportWaitWindow))
+}
+
+// WaitForReport implements service.ReportWaiter: when the scheduled report
+// is due within reportWaitWindow of at, it blocks until that report has
+// read the traffic, for at most reportWaitTimeout.
+func (t *Tgbot) WaitForReport(at time.Time) {
+	if !t.IsRunning() {
+		return
+	}
+	due, ok := t.reportDueNear(at)
+	if !ok {
+		return
+	}
+	deadline := time.Now().Add(reportWaitTimeout)
+	for lastReportBuilt.Load() < due.Add(-time.Second).UnixMilli() {
+		if time.Now().After(deadline) {
+			return
+		}
+		time.Sleep(time.Second)
+	}
+}
+
+// NotifyTrafficReset implements service.TrafficResetNotifier: unless
+// tgTrafficResetNotify is off, it tells the admins what an automatic
+// traffic reset cleared.
+func (t *Tgbot) NotifyTrafficReset(summary service.TrafficResetSummary) {
+	enabled, err := t.settingService.GetTgTrafficResetNotify()
+	if err != nil {
+		t.settingFallback("tgTrafficResetNotify", err, "true")
+		enabled = true
+	}
+	if !enabled {
+		return
+	}
+	msg := t.I18nBot("tgbot.messages.trafficResetDone",
+		"Tag=="+escapeField(summary.Tag),
+		"Trigger=="+html.EscapeString(summary.Trigger),
+		"Total=="+formatTraffic(summary.Up+summary.Down),
+		"Upload=="+formatTraffic(summary.Up),
+		"Download=="+formatTraffic(summary.Down),
+		"Clients=="+strconv.Itoa(summary.Clients))
+	if summary.Boosts > 0 {
+		msg += "\r\n" + t.I18nBot("tgbot.messages.trafficResetBoosts", "Count=="+strconv.Itoa(summary.Boosts))
+	}
+	t.SendNotification(NotifyTrafficReset, msg)
+}
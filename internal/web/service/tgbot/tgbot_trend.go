@@ -0,0 +1,217 @@
+package tgbot
+
+import (
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/zixu5u/3xv/v3/internal/logger"
+	"github.com/zixu5u/3xv/v3/internal/web/service"
+)
+
+// trendListSize caps the inbounds listed by /trend; the biggest increases
+// come first.
+const trendListSize = 10
+
+// parseTrendArgs reads the optional period ("day" or "week", default day)
+// and inbound tag of /trend, in any order.
+func parseTrendArgs(args []string) (period string, tag string, err error) {
+	period = "day"
+	for _, arg := range args {
+		switch strings.ToLower(arg) {
+		case "day", "d":
+			period = "day"
+		case "week", "w":
+			period = "week"
+		default:
+			if tag != "" {
+				return "", "", errors.New("more than one tag")
+			}
+			tag = arg
+		}
+	}
+	return period, tag, nil
+}
+
+// trendPeriod returns the length of a /trend period.
+func trendPeriod(period string) time.Duration {
+	if period == "week" {
+		return 7 * 24 * time.Hour
+	}
+	return 24 * time.Hour
+}
+
+// formatTrendChange renders the change from previous to current as an
+// arrow and a percentage.
+func formatTrendChange(current, previous int64) string {
+	switch {
+	case current == previous:
+		return "▪️ 0%"
+	case previous == 0:
+		return "🔺 +∞"
+	}
+	pct := float64(current-previous) / float64(previous) * 100
+	if pct > 0 {
+		return "🔺 +" + formatDecimal(pct, 1) + "%"
+	}
+	return "🔻 " + formatDecimal(pct, 1) + "%"
+}
+
+// sendTrend implements /trend: the traffic of the last day or week compared
+// with the one before, overall and per inbound, or for one inbound.
+func (t *Tgbot) sendTrend(chatId int64, args []string) {
+	period, tag, err := parseTrendArgs(args)
+	if err != nil {
+		t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.messages.trendUsage"))
+		return
+	}
+	periodName := t.I18nBot("tgbot.messages.trendPeriod" + strings.ToUpper(period[:1]) + period[1:])
+
+	trends, ok, err := t.trafficHistory.GetTrends(trendPeriod(period), time.Now())
+	if err != nil {
+		logger.Warning("Failed to get traffic trends:", err)
+		t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.wentWrong"))
+		return
+	}
+	if !ok {
+		t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.messages.trendNoHistory", "Period=="+periodName))
+		return
+	}
+	msg := t.I18nBot("tgbot.messages.trendHeader", "Period=="+periodName)
+
+	if tag != "" {
+		inbound, err := t.inboundService.GetInboundByTag(tag)
+		if err != nil {
+			t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.messages.trendNoInbound", "Tag=="+escapeField(tag)))
+			return
+		}
+		for _, trend := range trends {
+			if trend.InboundId == inbound.Id {
+				t.SendMsgToTgbot(chatId, msg+t.trendLine(inbound.Remark, trend))
+				return
+			}
+		}
+		t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.messages.trendNoInbound", "Tag=="+escapeField(tag)))
+		return
+	}
+
+	names := map[int]string{}
+	if inbounds, err := t.inboundService.GetAllInbounds(); err == nil {
+		for _, inbound := range inbounds {
+			names[inbound.Id] = inbound.Remark
+		}
+	}
+	var total service.InboundTrend
+	for _, trend := range trends {
+		total.Current += trend.Current
+		total.Previous += trend.Previous
+	}
+	msg += t.I18nBot("tgbot.messages.trendTotal",
+		"Current=="+formatTraffic(total.Current),
+		"Previous=="+formatTraffic(total.Previous),
+		"Change=="+formatTrendChange(total.Current, total.Previous))
+
+	sort.SliceStable(trends, func(i, j int) bool {
+		return trends[i].Current-trends[i].Previous > trends[j].Current-trends[j].Previous
+	})
+	for i, trend := range trends {
+		if i == trendListSize {
+			msg += t.I18nBot("tgbot.messages.trendMore", "Count=="+strconv.Itoa(len(trends)-trendListSize))
+			break
+		}
+		name, found := names[trend.InboundId]
+		if !found {
+			name = "#" + strconv.Itoa(trend.InboundId)
+		}
+		msg += t.trendLine(name, trend)
+	}
+	t.SendMsgToTgbot(chatId, msg)
+}
+
+func (t *Tgbot) trendLine(name string, trend service.InboundTrend) string {
+	return t.I18nBot("tgbot.messages.trendLine",
+		"Change=="+formatTrendChange(trend.Current, trend.Previous),
+		"Name=="+escapeField(name),
+		"Current=="+formatTraffic(trend.Current),
+		"Previous=="+formatTraffic(trend.Previous))
+}
+
+// reportSparklineDays is how many days the sparklines of the report cover.
+const reportSparklineDays = 7
+
+// topInboundSeries returns the IDs of the n inbounds in series that moved
+// the most traffic, busiest first. Idle inbounds are left out.
+func topInboundSeries(series map[int][]int64, n int) []int {
+	totals := make(map[int]int64, len(series))
+	var ids []int
+	for id, values := range series {
+		for _, v := range values {
+			totals[id] += v
+		}
+		if totals[id] > 0 {
+			ids = append(ids, id)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		if totals[ids[i]] != totals[ids[j]] {
+			return totals[ids[i]] > totals[ids[j]]
+		}
+		return ids[i] < ids[j]
+	})
+	return ids[:min(n, len(ids))]
+}
+
+// reportSparklines draws the daily traffic of the busiest inbounds for the
+// report. It returns "" when tgReportSparklines is 0 or there isn't a day
+// of traffic history yet.
+func (t *Tgbot) reportSparklines() string {
+	count, err := t.settingService.GetTgReportSparklines()
+	if err != nil {
+		t.settingFallback("tgReportSparklines", err, "3")
+		count = 3
+	}
+	if count <= 0 {
+		return ""
+	}
+	series, ok, err := t.trafficHistory.GetDailyTraffic(reportSparklineDays, time.Now())
+	if err != nil {
+		logger.Warning("Failed to get daily inbound traffic:", err)
+		return ""
+	}
+	if !ok {
+		return ""
+	}
+
+	inbounds, err := t.inboundService.GetAllInbounds()
+	if err != nil {
+		logger.Warning("Unable to load Inbounds", err)
+		return ""
+	}
+	listed := make(map[int][]int64, len(series))
+	remarks := make(map[int]string, len(inbounds))
+	for _, inbound := range reportInbounds(inbounds, t.reportDisabledInbounds()) {
+		if values, found := series[inbound.Id]; found {
+			listed[inbound.Id] = values
+			remarks[inbound.Id] = reportRemark(inbound)
+		}
+	}
+	top := topInboundSeries(listed, count)
+	if len(top) == 0 {
+		return ""
+	}
+
+	msg := t.I18nBot("tgbot.messages.reportSparklineHeader", "Days=="+strconv.Itoa(len(listed[top[0]])))
+	for _, id := range top {
+		var total int64
+		for _, v := range listed[id] {
+			total += v
+		}
+		msg += t.I18nBot("tgbot.messages.reportSparklineLine",
+			"Chart=="+sparkline(listed[id]),
+			"Name=="+remarks[id],
+			"Total=="+formatTraffic(total))
+	}
+	return msg
+}
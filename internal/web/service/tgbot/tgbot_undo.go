@@ -0,0 +1,110 @@
+package tgbot
+
+import (
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/zixu5u/3xv/v3/internal/logger"
+	"github.com/zixu5u/3xv/v3/internal/web/service"
+
+	"github.com/mymmrac/telego"
+	tu "github.com/mymmrac/telego/telegoutil"
+)
+
+// undoWindow is how long the Undo button of a traffic reset can be used
+// before its snapshot is discarded.
+const undoWindow = 60 * time.Second
+
+// pendingUndos holds the snapshots of the resets that can still be undone,
+// by the sequence number their Undo button carries. They live in memory
+// only, so a restart ends every undo window.
+var pendingUndos = struct {
+	sync.Mutex
+	seq     uint64
+	entries map[uint64]*service.TrafficSnapshot
+}{entries: make(map[uint64]*service.TrafficSnapshot)}
+
+// storeUndo keeps snapshot for undoWindow and returns its sequence number.
+func storeUndo(snapshot *service.TrafficSnapshot) uint64 {
+	pendingUndos.Lock()
+	pendingUndos.seq++
+	seq := pendingUndos.seq
+	pendingUndos.entries[seq] = snapshot
+	pendingUndos.Unlock()
+	time.AfterFunc(undoWindow, func() { takeUndo(seq) })
+	return seq
+}
+
+// takeUndo removes and returns the snapshot seq. ok is false when it was
+// already undone or its window passed.
+func takeUndo(seq uint64) (snapshot *service.TrafficSnapshot, ok bool) {
+	pendingUndos.Lock()
+	defer pendingUndos.Unlock()
+	snapshot, ok = pendingUndos.entries[seq]
+	delete(pendingUndos.entries, seq)
+	return snapshot, ok
+}
+
+// undoKeyboard offers to undo the reset whose snapshot is seq.
+func (t *Tgbot) undoKeyboard(seq uint64) *telego.InlineKeyboardMarkup {
+	return tu.InlineKeyboard(tu.InlineKeyboardRow(
+		tu.InlineKeyboardButton(t.I18nBot("tgbot.buttons.undo")).WithCallbackData(t.encodeQuery("undo_reset " + strconv.FormatUint(seq, 10))),
+	))
+}
+
+// offerUndo sends msg with an Undo button for snapshot. Without a snapshot,
+// e.g. when it could not be read, msg is sent as it is.
+func (t *Tgbot) offerUndo(chatId int64, msg string, snapshot *service.TrafficSnapshot) {
+	if snapshot == nil {
+		t.SendMsgToTgbot(chatId, msg)
+		return
+	}
+	msg += "\r\n" + t.I18nBot("tgbot.messages.undoOffer", "Seconds=="+strconv.Itoa(int(undoWindow/time.Second)))
+	t.SendMsgToTgbot(chatId, msg, t.undoKeyboard(storeUndo(snapshot)))
+}
+
+// snapshotClients reads the counters of emails before a reset. A failure is
+// logged and the reset goes ahead without an undo.
+func (t *Tgbot) snapshotClients(emails ...string) *service.TrafficSnapshot {
+	snapshot, err := t.inboundService.SnapshotClientTraffics(emails)
+	if err != nil {
+		logger.Warning("Failed to snapshot client traffic, the reset can't be undone:", err)
+		return nil
+	}
+	return snapshot
+}
+
+// snapshotInbounds reads the counters of every inbound before a reset. A
+// failure is logged and the reset goes ahead without an undo.
+func (t *Tgbot) snapshotInbounds() *service.TrafficSnapshot {
+	snapshot, err := t.inboundService.SnapshotInboundTraffics()
+	if err != nil {
+		logger.Warning("Failed to snapshot inbound traffic, the reset can't be undone:", err)
+		return nil
+	}
+	return snapshot
+}
+
+// undoReset restores the snapshot of the Undo button seq, if its window is
+// still open, and removes the button.
+func (t *Tgbot) undoReset(chatId int64, messageId int, seq uint64, requestedBy int64) {
+	snapshot, ok := takeUndo(seq)
+	if !ok {
+		t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.messages.undoExpired"))
+		return
+	}
+	err := t.inboundService.RestoreTrafficSnapshot(snapshot)
+	logBotEvent(botEvent{Event: "undo_reset", ChatID: requestedBy, Err: err})
+	if err != nil {
+		logger.Warning("Failed to undo traffic reset:", err)
+		t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.messages.undoFailed"))
+		return
+	}
+	logger.Infof("Traffic reset undone by Telegram user %d (%d clients, %d inbounds)",
+		requestedBy, len(snapshot.Clients), len(snapshot.Inbounds))
+	t.editMessageCallbackTgBot(chatId, messageId, tu.InlineKeyboard())
+	t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.messages.undoDone",
+		"Clients=="+strconv.Itoa(len(snapshot.Clients)),
+		"Inbounds=="+strconv.Itoa(len(snapshot.Inbounds))))
+}
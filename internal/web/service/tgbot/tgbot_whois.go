@@ -0,0 +1,59 @@
+package tgbot
+
+import (
+	"html"
+	"strings"
+
+	"github.com/zixu5u/3xv/v3/internal/logger"
+)
+
+// parseWhoisArgs reads "/whois <uuid|password>". ok is false unless there
+// is exactly one argument.
+func parseWhoisArgs(args []string) (credential string, ok bool) {
+	if len(args) != 1 || strings.TrimSpace(args[0]) == "" {
+		return "", false
+	}
+	return args[0], true
+}
+
+// sendWhois implements /whois: it finds the clients, or shadowsocks server
+// keys, a leaked credential belongs to, so they can be rotated. Every
+// lookup is logged with who ran it; the credential itself is not.
+func (t *Tgbot) sendWhois(chatId int64, credential string, requestedBy int64) {
+	matches, err := t.inboundService.FindByCredential(credential)
+	if err != nil {
+		logger.Warning("Credential lookup failed:", err)
+		t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.wentWrong"))
+		return
+	}
+	logger.Infof("Credential lookup by Telegram user %d found %d match(es)", requestedBy, len(matches))
+	if len(matches) == 0 {
+		logBotEvent(botEvent{Event: "whois", ChatID: requestedBy, Command: "whois", Outcome: "none"})
+		t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.messages.whoisNone"))
+		return
+	}
+
+	logBotEvent(botEvent{Event: "whois", ChatID: requestedBy, Command: "whois", Outcome: "match"})
+
+	msg := t.I18nBot("tgbot.messages.whoisHeader")
+	for _, m := range matches {
+		inbound := m.InboundRemark
+		if inbound == "" {
+			inbound = m.InboundTag
+		}
+		if m.Email == "" {
+			msg += t.I18nBot("tgbot.messages.whoisInboundKey",
+				"Inbound=="+escapeField(inbound),
+				"Tag=="+html.EscapeString(m.InboundTag),
+				"Protocol=="+string(m.Protocol))
+			continue
+		}
+		msg += t.I18nBot("tgbot.messages.whoisClient",
+			"Email=="+escapeField(m.Email),
+			"Inbound=="+escapeField(inbound),
+			"Tag=="+html.EscapeString(m.InboundTag),
+			"Protocol=="+string(m.Protocol),
+			"Field=="+m.Field)
+	}
+	t.SendMsgToTgbot(chatId, msg)
+}
@@ -0,0 +1,81 @@
+package tgbot
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/zixu5u/3xv/v3/internal/logger"
+)
+
+// handlerDrainTimeout bounds how long StopBot waits for in-flight command
+// and callback handlers, e.g. a backup upload, before giving up on them.
+const handlerDrainTimeout = 15 * time.Second
+
+var (
+	// handlerMutex guards acceptingWork together with handlerWG.Add so a
+	// handler can't be added while StopBot is already waiting.
+	handlerMutex   sync.Mutex
+	acceptingWork  bool
+	handlerWG      sync.WaitGroup
+	activeHandlers atomic.Int64
+)
+
+// acceptHandlers lets runHandler start new work again after a stop.
+func acceptHandlers() {
+	handlerMutex.Lock()
+	acceptingWork = true
+	handlerMutex.Unlock()
+}
+
+// runHandler runs fn on the worker pool and tracks it until it returns.
+// It reports false, dropping fn, once the bot is stopping.
+func runHandler(fn func()) bool {
+	handlerMutex.Lock()
+	if !acceptingWork {
+		handlerMutex.Unlock()
+		return false
+	}
+	handlerWG.Add(1)
+	activeHandlers.Add(1)
+	// Release into the pool that was acquired even if Start has replaced
+	// messageWorkerPool in the meantime.
+	pool := messageWorkerPool
+	handlerMutex.Unlock()
+
+	go func() {
+		defer handlerWG.Done()
+		defer activeHandlers.Add(-1)
+		pool <- struct{}{}        // Acquire worker
+		defer func() { <-pool }() // Release worker
+		fn()
+	}()
+	return true
+}
+
+// drainHandlers stops accepting new handlers and waits up to timeout for
+// the running ones to finish.
+func drainHandlers(timeout time.Duration) {
+	handlerMutex.Lock()
+	acceptingWork = false
+	handlerMutex.Unlock()
+
+	inFlight := activeHandlers.Load()
+	if inFlight == 0 {
+		return
+	}
+	logger.Infof("Waiting for %d Telegram bot handlers to finish...", inFlight)
+
+	done := make(chan struct{})
+	go func() {
+		handlerWG.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		logger.Infof("Drained %d Telegram bot handlers", inFlight)
+	case <-time.After(timeout):
+		left := activeHandlers.Load()
+		logger.Warningf("Drained %d Telegram bot handlers, %d still running after %s", inFlight-left, left, timeout)
+	}
+}
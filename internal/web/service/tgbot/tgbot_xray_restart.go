@@ -0,0 +1,220 @@
+package tgbot
+
+import (
+	"errors"
+	"html"
+	"strconv"
+	"time"
+
+	"github.com/zixu5u/3xv/v3/internal/logger"
+	"github.com/zixu5u/3xv/v3/internal/web/service"
+
+	tu "github.com/mymmrac/telego/telegoutil"
+)
+
+// restartBusyOnlines is the online client count from which the restart
+// prompt advises waiting for a quieter moment.
+const restartBusyOnlines = 20
+
+// onlineClientCount returns the number of clients connected right now.
+func onlineClientCount() int {
+	if p := service.XrayProcess(); p != nil && p.IsRunning() {
+		return len(p.GetOnlineClients())
+	}
+	return 0
+}
+
+// confirmRestartXray asks before restarting Xray, showing how many clients
+// the restart would disconnect.
+func (t *Tgbot) confirmRestartXray(chatId int64) {
+	count := onlineClientCount()
+	msg := t.I18nBot("tgbot.messages.restartXrayConfirm", "Count=="+strconv.Itoa(count))
+	if count >= restartBusyOnlines {
+		msg += "\r\n" + t.I18nBot("tgbot.messages.restartXrayBusy")
+	}
+	inlineKeyboard := tu.InlineKeyboard(
+		tu.InlineKeyboardRow(
+			tu.InlineKeyboardButton(t.I18nBot("tgbot.buttons.cancel")).WithCallbackData(t.encodeQuery("restart_xray_cancel")),
+		),
+		tu.InlineKeyboardRow(
+			tu.InlineKeyboardButton(t.I18nBot("tgbot.buttons.confirmRestartXray")).WithCallbackData(t.encodeQuery("restart_xray_c")),
+		),
+	)
+	t.SendMsgToTgbot(chatId, msg, inlineKeyboard)
+}
+
+// Health check of a restarted core: it must become ready within the
+// tgXrayStartTimeout setting and then stay ready for xrayHealthSettle, since
+// a core that can't bind a listener exits right after starting. A start
+// that fails is tried again after xrayRetryDelay, up to tgXrayStartRetries
+// more times.
+const (
+	xrayHealthSettle = 2 * time.Second
+	xrayHealthPoll   = 100 * time.Millisecond
+	xrayRetryDelay   = 2 * time.Second
+)
+
+var (
+	// errXrayStartTimeout is a restart that did not return within the start
+	// timeout; it goes on in the background.
+	errXrayStartTimeout = errors.New("xray did not finish starting in time")
+	// errXrayNotReady is a core that started but did not become ready.
+	errXrayNotReady = errors.New("xray did not become ready")
+)
+
+// waitXrayUp polls running until it has reported true for settle in a row
+// or timeout passes. It returns how long the core took to come up and
+// whether it stayed up.
+func waitXrayUp(running func() bool, poll, settle, timeout time.Duration) (time.Duration, bool) {
+	begin := time.Now()
+	upAt := time.Duration(-1)
+	for {
+		elapsed := time.Since(begin)
+		if running() {
+			if upAt < 0 {
+				upAt = elapsed
+			}
+			if elapsed-upAt >= settle {
+				return upAt, true
+			}
+		} else {
+			upAt = -1
+		}
+		if elapsed >= timeout {
+			return max(upAt, 0), false
+		}
+		time.Sleep(poll)
+	}
+}
+
+// callWithTimeout runs fn and returns its error, or errXrayStartTimeout
+// when it has not returned within timeout; fn then finishes on its own.
+func callWithTimeout(fn func() error, timeout time.Duration) error {
+	done := make(chan error, 1)
+	go func() { done <- fn() }()
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case err := <-done:
+		return err
+	case <-timer.C:
+		return errXrayStartTimeout
+	}
+}
+
+// restartWithRetries runs restart, bounded by timeout, then waits for ready
+// and tries again up to retries more times when restart failed or the core
+// did not become ready. A restart still running after timeout is not tried
+// again: it holds the Xray lock, so another one would only queue behind it.
+// It returns how long the core was down, the number of attempts and the
+// error of the last one.
+func restartWithRetries(restart func() error, ready func(timeout time.Duration) (time.Duration, bool),
+	timeout time.Duration, retries int, retryDelay time.Duration) (downtime time.Duration, attempts int, err error) {
+	begin := time.Now()
+	for attempts = 1; ; attempts++ {
+		attemptBegin := time.Now()
+		err = callWithTimeout(restart, timeout)
+		if errors.Is(err, errXrayStartTimeout) {
+			return time.Since(begin), attempts, err
+		}
+		if err == nil {
+			restarted := time.Since(attemptBegin)
+			up, ok := ready(timeout)
+			if ok {
+				return attemptBegin.Sub(begin) + restarted + up, attempts, nil
+			}
+			err = errXrayNotReady
+		}
+		if attempts > retries {
+			return time.Since(begin), attempts, err
+		}
+		logger.Warningf("Xray restart attempt %d failed, trying again: %v", attempts, err)
+		time.Sleep(retryDelay)
+	}
+}
+
+// xrayStartLimits returns the tgXrayStartTimeout and tgXrayStartRetries
+// settings, falling back to their defaults when they can't be read.
+func (t *Tgbot) xrayStartLimits() (time.Duration, int) {
+	seconds, err := t.settingService.GetTgXrayStartTimeout()
+	if err != nil || seconds < 2 {
+		if err != nil {
+			logger.Warning("Failed to get Xray start timeout setting:", err)
+		}
+		seconds = 10
+	}
+	retries, err := t.settingService.GetTgXrayStartRetries()
+	if err != nil {
+		logger.Warning("Failed to get Xray start retries setting:", err)
+		retries = 2
+	}
+	return time.Duration(seconds) * time.Second, min(max(retries, 0), 5)
+}
+
+// waitXrayReady waits until the core is ready, up to the start timeout.
+func (t *Tgbot) waitXrayReady() (time.Duration, bool) {
+	timeout, _ := t.xrayStartLimits()
+	return waitXrayUp(t.xrayService.IsXrayReady, xrayHealthPoll, xrayHealthSettle, timeout)
+}
+
+// restartXrayReady force-restarts Xray and waits until its API answers, with
+// the configured start timeout and retries.
+func (t *Tgbot) restartXrayReady() (downtime time.Duration, attempts int, err error) {
+	timeout, retries := t.xrayStartLimits()
+	return restartWithRetries(
+		func() error { return t.xrayService.RestartXray(true) },
+		func(timeout time.Duration) (time.Duration, bool) {
+			return waitXrayUp(t.xrayService.IsXrayReady, xrayHealthPoll, xrayHealthSettle, timeout)
+		},
+		timeout, retries, xrayRetryDelay)
+}
+
+// restartOutcome renders the result of restartXrayReady: the downtime, a
+// core that is still starting, one that did not stay up or a failed start,
+// and how many attempts it took when there was more than one.
+func (t *Tgbot) restartOutcome(downtime time.Duration, attempts int, err error) string {
+	var msg string
+	switch {
+	case err == nil:
+		msg = t.I18nBot("tgbot.messages.restartXrayHealthy", "Downtime=="+downtime.Round(time.Millisecond).String())
+	case errors.Is(err, errXrayStartTimeout):
+		timeout, _ := t.xrayStartLimits()
+		msg = t.I18nBot("tgbot.messages.restartXrayTimeout", "Seconds=="+strconv.Itoa(int(timeout/time.Second)))
+	case errors.Is(err, errXrayNotReady):
+		reason := t.I18nBot("tgbot.commands.xrayNotRunning")
+		if xrayErr := t.xrayService.GetXrayErr(); xrayErr != nil {
+			reason = html.EscapeString(xrayErr.Error())
+		}
+		msg = t.I18nBot("tgbot.messages.restartXrayUnhealthy", "Error=="+reason)
+	default:
+		msg = t.I18nBot("tgbot.commands.restartFailed", "Error=="+html.EscapeString(err.Error()))
+	}
+	if attempts > 1 {
+		msg += "\r\n" + t.I18nBot("tgbot.messages.restartXrayAttempts", "Count=="+strconv.Itoa(attempts))
+	}
+	return msg
+}
+
+// restartXrayFromMenu restarts Xray after the admin confirmed the restart
+// prompt of the menu button, /restart or /restartxray. Only the core is
+// restarted, the panel and the bot keep running. The config is tested
+// first, so a broken config never takes down a working core, and the reply
+// reports the downtime and whether the core came up.
+func (t *Tgbot) restartXrayFromMenu(chatId int64, requestedBy int64) {
+	if !t.xrayService.IsXrayRunning() {
+		t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.commands.xrayNotRunning"))
+		return
+	}
+	if err := t.xrayService.CheckXrayConfig(); err != nil {
+		logger.Warningf("Xray restart requested by %d refused, config test failed: %v", requestedBy, err)
+		t.SendMsgToTgbot(chatId, t.I18nBot("tgbot.messages.restartXrayConfigInvalid", "Error=="+html.EscapeString(err.Error())))
+		return
+	}
+	downtime, attempts, err := t.restartXrayReady()
+	if err != nil {
+		logger.Warningf("Xray restart from the Telegram menu by %d did not come up after %d attempt(s): %v", requestedBy, attempts, err)
+	} else {
+		logger.Infof("Xray restarted from the Telegram menu by %d, down for %s", requestedBy, downtime)
+	}
+	t.SendMsgToTgbot(chatId, t.restartOutcome(downtime, attempts, err))
+}
@@ -0,0 +1,168 @@
+package service
+
+import (
+	"time"
+
+	"github.com/zixu5u/3xv/v3/internal/database"
+	"github.com/zixu5u/3xv/v3/internal/database/model"
+)
+
+// trafficSnapshotTolerance is how long before a period boundary the nearest
+// snapshot may have been taken to stand in for it.
+const trafficSnapshotTolerance = time.Hour
+
+// TrafficHistoryService samples the inbound traffic counters over time so
+// the Telegram bot can compare the traffic of one period with the previous
+// one.
+type TrafficHistoryService struct{}
+
+// InboundTrend is the traffic an inbound moved in the current and the
+// previous period.
+type InboundTrend struct {
+	InboundId int
+	Current   int64
+	Previous  int64
+}
+
+// Record stores the counters of every inbound. Old snapshots are removed by
+// Prune.
+func (s *TrafficHistoryService) Record(at time.Time) error {
+	db := database.GetDB()
+	var inbounds []model.Inbound
+	if err := db.Model(&model.Inbound{}).Select("id, up, down").Find(&inbounds).Error; err != nil {
+		return err
+	}
+	if len(inbounds) == 0 {
+		return nil
+	}
+	snapshots := make([]model.InboundTrafficSnapshot, len(inbounds))
+	for i, inbound := range inbounds {
+		snapshots[i] = model.InboundTrafficSnapshot{
+			InboundId: inbound.Id,
+			SampledAt: at.Unix(),
+			Up:        inbound.Up,
+			Down:      inbound.Down,
+		}
+	}
+	return db.CreateInBatches(snapshots, 200).Error
+}
+
+// Prune deletes the snapshots taken before before and returns how many
+// there were.
+func (s *TrafficHistoryService) Prune(before time.Time) (int64, error) {
+	result := database.GetDB().Where("sampled_at < ?", before.Unix()).Delete(&model.InboundTrafficSnapshot{})
+	return result.RowsAffected, result.Error
+}
+
+// GetTrends returns, per inbound, the traffic of the period ending at now
+// and of the period before it. ok is false while there are not yet
+// snapshots covering both periods. Inbounds created since the start of the
+// previous period are left out.
+func (s *TrafficHistoryService) GetTrends(period time.Duration, now time.Time) (trends []InboundTrend, ok bool, err error) {
+	start, ok, err := s.countersAt(now.Add(-2 * period))
+	if err != nil || !ok {
+		return nil, false, err
+	}
+	middle, ok, err := s.countersAt(now.Add(-period))
+	if err != nil || !ok {
+		return nil, false, err
+	}
+
+	var inbounds []model.Inbound
+	if err := database.GetDB().Model(&model.Inbound{}).Select("id, up, down").Order("id").Find(&inbounds).Error; err != nil {
+		return nil, false, err
+	}
+	for _, inbound := range inbounds {
+		from, inStart := start[inbound.Id]
+		mid, inMiddle := middle[inbound.Id]
+		if !inStart || !inMiddle {
+			continue
+		}
+		trends = append(trends, InboundTrend{
+			InboundId: inbound.Id,
+			Current:   counterDelta(mid, inbound.Up+inbound.Down),
+			Previous:  counterDelta(from, mid),
+		})
+	}
+	return trends, true, nil
+}
+
+// GetDailyTraffic returns, per inbound ID, the traffic of each of the days
+// days ending at now, oldest first. Days before the oldest snapshot are
+// left out, so the series are shorter while history builds up; ok is false
+// until a snapshot from a day ago exists. A day without a snapshot at its
+// end counts its traffic towards the next one.
+func (s *TrafficHistoryService) GetDailyTraffic(days int, now time.Time) (series map[int][]int64, ok bool, err error) {
+	var boundaries []map[int]int64
+	for day := days; day > 0; day-- {
+		counters, found, err := s.countersAt(now.Add(-time.Duration(day) * 24 * time.Hour))
+		if err != nil {
+			return nil, false, err
+		}
+		if !found {
+			if len(boundaries) == 0 {
+				continue
+			}
+			counters = boundaries[len(boundaries)-1]
+		}
+		boundaries = append(boundaries, counters)
+	}
+	if len(boundaries) == 0 {
+		return nil, false, nil
+	}
+
+	var inbounds []model.Inbound
+	if err := database.GetDB().Model(&model.Inbound{}).Select("id, up, down").Find(&inbounds).Error; err != nil {
+		return nil, false, err
+	}
+	current := make(map[int]int64, len(inbounds))
+	for _, inbound := range inbounds {
+		current[inbound.Id] = inbound.Up + inbound.Down
+	}
+	boundaries = append(boundaries, current)
+
+	series = make(map[int][]int64, len(inbounds))
+	for id := range current {
+		values := make([]int64, len(boundaries)-1)
+		for i := range values {
+			if to, exists := boundaries[i+1][id]; exists {
+				values[i] = counterDelta(boundaries[i][id], to)
+			}
+		}
+		series[id] = values
+	}
+	return series, true, nil
+}
+
+// countersAt returns the up+down counters of the latest snapshot taken at
+// or shortly before at, keyed by inbound ID.
+func (s *TrafficHistoryService) countersAt(at time.Time) (map[int]int64, bool, error) {
+	db := database.GetDB()
+	var latest model.InboundTrafficSnapshot
+	err := db.Where("sampled_at <= ? AND sampled_at > ?", at.Unix(), at.Add(-trafficSnapshotTolerance).Unix()).
+		Order("sampled_at desc").
+		Limit(1).
+		Find(&latest).Error
+	if err != nil || latest.Id == 0 {
+		return nil, false, err
+	}
+
+	var snapshots []model.InboundTrafficSnapshot
+	if err := db.Where("sampled_at = ?", latest.SampledAt).Find(&snapshots).Error; err != nil {
+		return nil, false, err
+	}
+	counters := make(map[int]int64, len(snapshots))
+	for _, snapshot := range snapshots {
+		counters[snapshot.InboundId] = snapshot.Up + snapshot.Down
+	}
+	return counters, true, nil
+}
+
+// counterDelta is the traffic moved between two counter readings. A
+// counter lower than before was reset in between and counts from zero.
+func counterDelta(from, to int64) int64 {
+	if to < from {
+		return to
+	}
+	return to - from
+}
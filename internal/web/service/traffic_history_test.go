@@ -0,0 +1,147 @@
+package service
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/zixu5u/3xv/v3/internal/database/model"
+)
+
+func TestTrafficHistoryTrends(t *testing.T) {
+	db := initTrafficTestDB(t)
+	svc := &TrafficHistoryService{}
+	now := time.Date(2024, 5, 3, 12, 0, 0, 0, time.UTC)
+
+	steady := &model.Inbound{Tag: "steady", Port: 1001, Protocol: model.VLESS, Enable: true}
+	jumped := &model.Inbound{Tag: "jumped", Port: 1002, Protocol: model.VLESS, Enable: true}
+	for _, inbound := range []*model.Inbound{steady, jumped} {
+		if err := db.Create(inbound).Error; err != nil {
+			t.Fatalf("create inbound: %v", err)
+		}
+	}
+	setCounters := func(inbound *model.Inbound, up, down int64) {
+		t.Helper()
+		if err := db.Model(inbound).Updates(map[string]any{"up": up, "down": down}).Error; err != nil {
+			t.Fatalf("update counters: %v", err)
+		}
+	}
+
+	if _, ok, err := svc.GetTrends(24*time.Hour, now); err != nil || ok {
+		t.Fatalf("trends without history: ok=%v err=%v", ok, err)
+	}
+
+	// two days ago
+	setCounters(steady, 100, 100)
+	setCounters(jumped, 0, 50)
+	if err := svc.Record(now.Add(-48*time.Hour - 10*time.Minute)); err != nil {
+		t.Fatalf("Record: %v", err)
+	}
+	// a day ago; jumped's counters were reset in between
+	setCounters(steady, 150, 150)
+	setCounters(jumped, 0, 20)
+	if err := svc.Record(now.Add(-24 * time.Hour)); err != nil {
+		t.Fatalf("Record: %v", err)
+	}
+	// now
+	setCounters(steady, 200, 200)
+	setCounters(jumped, 0, 520)
+
+	trends, ok, err := svc.GetTrends(24*time.Hour, now)
+	if err != nil || !ok {
+		t.Fatalf("GetTrends: ok=%v err=%v", ok, err)
+	}
+	if len(trends) != 2 {
+		t.Fatalf("want 2 trends, got %+v", trends)
+	}
+	if trends[0].Current != 100 || trends[0].Previous != 100 {
+		t.Errorf("steady trend = %+v", trends[0])
+	}
+	if trends[1].Current != 500 || trends[1].Previous != 20 {
+		t.Errorf("jumped trend = %+v", trends[1])
+	}
+
+	// a snapshot more than the tolerance before a boundary doesn't count
+	if _, ok, _ := svc.GetTrends(36*time.Hour, now); ok {
+		t.Fatal("stale snapshot used for a period boundary")
+	}
+}
+
+func TestTrafficHistoryPrune(t *testing.T) {
+	db := initTrafficTestDB(t)
+	svc := &TrafficHistoryService{}
+	now := time.Now()
+
+	for _, tag := range []string{"a", "b"} {
+		if err := db.Create(&model.Inbound{Tag: tag, Port: 1000 + int(tag[0]), Protocol: model.VLESS}).Error; err != nil {
+			t.Fatalf("create inbound: %v", err)
+		}
+	}
+	for _, at := range []time.Time{now.AddDate(0, 0, -20), now} {
+		if err := svc.Record(at); err != nil {
+			t.Fatalf("Record: %v", err)
+		}
+	}
+
+	deleted, err := svc.Prune(now.AddDate(0, 0, -15))
+	if err != nil {
+		t.Fatalf("Prune: %v", err)
+	}
+	if deleted != 2 {
+		t.Errorf("deleted = %d, want 2", deleted)
+	}
+	var count int64
+	if err := db.Model(&model.InboundTrafficSnapshot{}).Count(&count).Error; err != nil {
+		t.Fatalf("count: %v", err)
+	}
+	if count != 2 {
+		t.Errorf("expected the recent snapshots to be kept, %d rows left", count)
+	}
+}
+
+func TestTrafficHistoryDailyTraffic(t *testing.T) {
+	db := initTrafficTestDB(t)
+	svc := &TrafficHistoryService{}
+	now := time.Date(2024, 5, 3, 12, 0, 0, 0, time.UTC)
+
+	if _, ok, err := svc.GetDailyTraffic(7, now); err != nil || ok {
+		t.Fatalf("daily traffic without history: ok=%v err=%v", ok, err)
+	}
+
+	old := &model.Inbound{Tag: "old", Port: 1001, Protocol: model.VLESS, Enable: true, Up: 100}
+	if err := db.Create(old).Error; err != nil {
+		t.Fatalf("create inbound: %v", err)
+	}
+	if err := svc.Record(now.Add(-72 * time.Hour)); err != nil {
+		t.Fatalf("Record: %v", err)
+	}
+	// created after the first snapshot
+	added := &model.Inbound{Tag: "added", Port: 1002, Protocol: model.VLESS, Enable: true, Down: 50}
+	if err := db.Create(added).Error; err != nil {
+		t.Fatalf("create inbound: %v", err)
+	}
+	if err := db.Model(old).Update("up", 300).Error; err != nil {
+		t.Fatalf("update counters: %v", err)
+	}
+	if err := svc.Record(now.Add(-48 * time.Hour)); err != nil {
+		t.Fatalf("Record: %v", err)
+	}
+	// no snapshot a day ago; added's counters were reset since
+	if err := db.Model(old).Update("up", 700).Error; err != nil {
+		t.Fatalf("update counters: %v", err)
+	}
+	if err := db.Model(added).Update("down", 30).Error; err != nil {
+		t.Fatalf("update counters: %v", err)
+	}
+
+	series, ok, err := svc.GetDailyTraffic(7, now)
+	if err != nil || !ok {
+		t.Fatalf("GetDailyTraffic: ok=%v err=%v", ok, err)
+	}
+	want := map[int][]int64{old.Id: {200, 0, 400}, added.Id: {50, 0, 30}}
+	for id, values := range want {
+		if !slices.Equal(series[id], values) {
+			t.Errorf("series of inbound %d = %v, want %v", id, series[id], values)
+		}
+	}
+}
@@ -0,0 +1,40 @@
+package service
+
+import (
+	"errors"
+
+	"github.com/zixu5u/3xv/v3/internal/database"
+	"github.com/zixu5u/3xv/v3/internal/database/model"
+	"github.com/zixu5u/3xv/v3/internal/xray"
+)
+
+// TrafficResetSummary describes one automatic traffic reset of an inbound:
+// the traffic it had used and how many client counters and boosts were
+// reset with it. Trigger is the trafficReset period or the cron expression
+// of the schedule that ran it.
+type TrafficResetSummary struct {
+	Tag     string
+	Trigger string
+	Up      int64
+	Down    int64
+	Clients int
+	Boosts  int
+}
+
+// ResetInboundPeriod ends a billing period of an inbound: its traffic and
+// that of its clients go back to zero and temporary boosts expire. Every
+// step is attempted even when an earlier one fails; the errors are joined.
+// The returned bool reports whether Xray needs a restart.
+func (s *ClientService) ResetInboundPeriod(inboundSvc *InboundService, inbound *model.Inbound) (TrafficResetSummary, bool, error) {
+	summary := TrafficResetSummary{Tag: inbound.Tag, Up: inbound.Up, Down: inbound.Down}
+	var clients int64
+	if err := database.GetDB().Model(xray.ClientTraffic{}).Where("inbound_id = ?", inbound.Id).Count(&clients).Error; err == nil {
+		summary.Clients = int(clients)
+	}
+
+	inboundErr := inboundSvc.ResetInboundTraffic(inbound.Id)
+	clientErr := s.ResetAllClientTraffics(inboundSvc, inbound.Id)
+	expired, needRestart, boostErr := s.ExpireInboundBoosts(inboundSvc, inbound.Id)
+	summary.Boosts = expired
+	return summary, needRestart, errors.Join(inboundErr, clientErr, boostErr)
+}
@@ -0,0 +1,127 @@
+package service
+
+import (
+	"github.com/zixu5u/3xv/v3/internal/database"
+	"github.com/zixu5u/3xv/v3/internal/database/model"
+	"github.com/zixu5u/3xv/v3/internal/xray"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ClientTrafficCounters are the counters of a client a traffic reset clears.
+type ClientTrafficCounters struct {
+	Email  string
+	Up     int64
+	Down   int64
+	Enable bool
+}
+
+// InboundTrafficCounters are the counters of an inbound a traffic reset
+// clears.
+type InboundTrafficCounters struct {
+	Id                   int
+	Up                   int64
+	Down                 int64
+	LastTrafficResetTime int64
+}
+
+// TrafficSnapshot holds traffic counters as they were before a reset, so
+// the reset can be undone. It only covers this panel's database: a reset
+// already propagated to a remote node stays in effect there.
+type TrafficSnapshot struct {
+	Clients  []ClientTrafficCounters
+	Global   []model.ClientGlobalTraffic
+	Inbounds []InboundTrafficCounters
+}
+
+// SnapshotClientTraffics returns the counters of the clients with emails,
+// including the usage masters pushed for them, for undoing a client traffic
+// reset.
+func (s *InboundService) SnapshotClientTraffics(emails []string) (*TrafficSnapshot, error) {
+	db := database.GetDB()
+	snapshot := &TrafficSnapshot{}
+	for _, batch := range chunkStrings(emails, sqlInChunk) {
+		var rows []xray.ClientTraffic
+		if err := db.Model(xray.ClientTraffic{}).Where("email IN ?", batch).Find(&rows).Error; err != nil {
+			return nil, err
+		}
+		for _, row := range rows {
+			snapshot.Clients = append(snapshot.Clients, ClientTrafficCounters{
+				Email: row.Email, Up: row.Up, Down: row.Down, Enable: row.Enable,
+			})
+		}
+		var global []model.ClientGlobalTraffic
+		if err := db.Where("email IN ?", batch).Find(&global).Error; err != nil {
+			return nil, err
+		}
+		snapshot.Global = append(snapshot.Global, global...)
+	}
+	return snapshot, nil
+}
+
+// SnapshotInboundTraffics returns the counters of every inbound, for undoing
+// ResetAllTraffics.
+func (s *InboundService) SnapshotInboundTraffics() (*TrafficSnapshot, error) {
+	var inbounds []model.Inbound
+	err := database.GetDB().Model(model.Inbound{}).
+		Select("id", "up", "down", "last_traffic_reset_time").
+		Where("user_id > ?", 0).
+		Find(&inbounds).Error
+	if err != nil {
+		return nil, err
+	}
+	snapshot := &TrafficSnapshot{Inbounds: make([]InboundTrafficCounters, 0, len(inbounds))}
+	for _, inbound := range inbounds {
+		snapshot.Inbounds = append(snapshot.Inbounds, InboundTrafficCounters{
+			Id: inbound.Id, Up: inbound.Up, Down: inbound.Down, LastTrafficResetTime: inbound.LastTrafficResetTime,
+		})
+	}
+	return snapshot, nil
+}
+
+// RestoreTrafficSnapshot undoes a traffic reset. The snapshot counters are
+// added to the current ones rather than written over them, so traffic used
+// since the reset is kept; clients get back the enable state the reset
+// changed, and master-pushed usage is written back as it was.
+func (s *InboundService) RestoreTrafficSnapshot(snapshot *TrafficSnapshot) error {
+	return submitTrafficWrite(func() error {
+		return database.GetDB().Transaction(func(tx *gorm.DB) error {
+			for _, c := range snapshot.Clients {
+				err := tx.Model(xray.ClientTraffic{}).
+					Where("email = ?", c.Email).
+					Updates(map[string]any{
+						"up":     gorm.Expr("up + ?", c.Up),
+						"down":   gorm.Expr("down + ?", c.Down),
+						"enable": c.Enable,
+					}).Error
+				if err != nil {
+					return err
+				}
+			}
+			for _, g := range snapshot.Global {
+				g.Id = 0
+				err := tx.Clauses(clause.OnConflict{
+					Columns:   []clause.Column{{Name: "master_guid"}, {Name: "email"}},
+					DoUpdates: clause.AssignmentColumns([]string{"up", "down"}),
+				}).Create(&g).Error
+				if err != nil {
+					return err
+				}
+			}
+			for _, ib := range snapshot.Inbounds {
+				err := tx.Model(model.Inbound{}).
+					Where("id = ?", ib.Id).
+					Updates(map[string]any{
+						"up":                      gorm.Expr("up + ?", ib.Up),
+						"down":                    gorm.Expr("down + ?", ib.Down),
+						"last_traffic_reset_time": ib.LastTrafficResetTime,
+					}).Error
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	})
+}
@@ -0,0 +1,48 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/zixu5u/3xv/v3/internal/database/model"
+	"github.com/zixu5u/3xv/v3/internal/xray"
+)
+
+func TestRestoreTrafficSnapshotUndoesClientReset(t *testing.T) {
+	db := initTrafficTestDB(t)
+	svc := &InboundService{}
+	seedClientRow(t, "alice", 1, 100, 200, 300)
+	if err := db.Model(xray.ClientTraffic{}).Where("email = ?", "alice").Update("enable", false).Error; err != nil {
+		t.Fatalf("disable alice: %v", err)
+	}
+	if err := svc.AcceptGlobalTraffic("master-a", []*xray.ClientTraffic{{Email: "alice", Up: 900, Down: 800}}); err != nil {
+		t.Fatalf("AcceptGlobalTraffic: %v", err)
+	}
+
+	snapshot, err := svc.SnapshotClientTraffics([]string{"alice"})
+	if err != nil {
+		t.Fatalf("SnapshotClientTraffics: %v", err)
+	}
+	if err := svc.ResetClientTrafficByEmail("alice"); err != nil {
+		t.Fatalf("ResetClientTrafficByEmail: %v", err)
+	}
+	// Traffic used between the reset and the undo.
+	if err := db.Model(xray.ClientTraffic{}).Where("email = ?", "alice").Update("up", 5).Error; err != nil {
+		t.Fatalf("add traffic: %v", err)
+	}
+
+	if err := svc.RestoreTrafficSnapshot(snapshot); err != nil {
+		t.Fatalf("RestoreTrafficSnapshot: %v", err)
+	}
+	alice := readTraffic(t, db, "alice")
+	assertUpDown(t, alice, 105, 200, "after undo")
+	if alice.Enable {
+		t.Error("undo must restore the enable state the reset changed")
+	}
+	var global []model.ClientGlobalTraffic
+	if err := db.Where("email = ?", "alice").Find(&global).Error; err != nil {
+		t.Fatalf("read globals: %v", err)
+	}
+	if len(global) != 1 || global[0].Up != 900 || global[0].Down != 800 {
+		t.Errorf("master usage not restored: %+v", global)
+	}
+}
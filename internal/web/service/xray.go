@@ -3,11 +3,14 @@ package service
 import (
 	"encoding/json"
 	"errors"
+	"net"
 	"path"
 	"path/filepath"
 	"runtime"
+	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/zixu5u/3xv/v3/internal/config"
 	"github.com/zixu5u/3xv/v3/internal/database/model"
@@ -21,8 +24,9 @@ import (
 var (
 	p                 *xray.Process
 	lock              sync.Mutex
-	isNeedXrayRestart atomic.Bool // Indicates that restart was requested for Xray
-	isManuallyStopped atomic.Bool // Indicates that Xray was stopped manually from the panel
+	isNeedXrayRestart atomic.Bool  // Indicates that restart was requested for Xray
+	isManuallyStopped atomic.Bool  // Indicates that Xray was stopped manually from the panel
+	lastTrafficSync   atomic.Int64 // Unix-milli time Xray stats were last written to the database
 	result            string
 )
 
@@ -296,6 +300,14 @@ func (s *XrayService) GetXrayConfig() (*xray.Config, error) {
 		injectPanelEgress(xrayConfig, egressTag)
 	}
 
+	// Drop blocked source IPs on every inbound. Injected last so the rule sits
+	// at the top of the routing table.
+	if blocked, err := s.settingService.GetBlockedIps(); err != nil {
+		logger.Warning("read blockedIps setting failed:", err)
+	} else {
+		injectBlockedIps(xrayConfig, parseBlockedIps(blocked))
+	}
+
 	return xrayConfig, nil
 }
 
@@ -642,6 +654,27 @@ func (s *XrayService) GetXrayTraffic() ([]*xray.Traffic, []*xray.ClientTraffic,
 	return traffic, clientTraffic, nil
 }
 
+// MarkTrafficSynced records that Xray stats were just written to the database.
+func (s *XrayService) MarkTrafficSynced() {
+	lastTrafficSync.Store(time.Now().UnixMilli())
+}
+
+// LastTrafficSync returns when Xray stats were last written to the database,
+// or the zero time if that hasn't happened since the panel started.
+func (s *XrayService) LastTrafficSync() time.Time {
+	ms := lastTrafficSync.Load()
+	if ms == 0 {
+		return time.Time{}
+	}
+	return time.UnixMilli(ms)
+}
+
+// OnlineIPTracking reports whether the running core tracks client IPs
+// itself through its online-stats API, so IP tracking needs no access log.
+func (s *XrayService) OnlineIPTracking() bool {
+	return s.IsXrayRunning() && p.OnlineAPISupport() == xray.OnlineAPISupported
+}
+
 // GetOnlineUsers returns connection-based online users (email + source IPs)
 // from the running core's online-stats API. ok=false means the API is not
 // available — xray isn't running or the core predates the online-stats RPCs —
@@ -749,6 +782,16 @@ func (s *XrayService) TestRoute(req xray.RouteTestRequest) (*xray.RouteTestResul
 	return s.xrayAPI.TestRoute(req)
 }
 
+// CheckXrayConfig builds the current desired config and validates it with
+// the Xray binary without touching the running core.
+func (s *XrayService) CheckXrayConfig() error {
+	xrayConfig, err := s.GetXrayConfig()
+	if err != nil {
+		return err
+	}
+	return xray.CheckConfig(xrayConfig)
+}
+
 // RestartXray reconciles the running Xray process with the current desired
 // config. When isForce is false it first tries to apply the changes through
 // the Xray gRPC API without restarting the process (inbounds, outbounds and
@@ -806,7 +849,12 @@ func (s *XrayService) tryHotApply(newCfg *xray.Config) bool {
 		p.SetConfig(newCfg)
 		return true
 	}
+	return s.applyHotDiff(newCfg, diff)
+}
 
+// applyHotDiff pushes diff to the running core through its API and adopts
+// newCfg on success. Callers must hold the package-level lock.
+func (s *XrayService) applyHotDiff(newCfg *xray.Config, diff *xray.HotDiff) bool {
 	apiPort := p.GetAPIPort()
 	if apiPort <= 0 {
 		return false
@@ -857,6 +905,45 @@ func (s *XrayService) tryHotApply(newCfg *xray.Config) bool {
 	return true
 }
 
+// ErrRoutingReloadUnsupported reports that the running core can't take a
+// routing reload through its API, so only a full restart applies the rules.
+var ErrRoutingReloadUnsupported = errors.New("routing hot reload is not supported by the running config")
+
+// ReloadRoutingRules re-applies the routing section of the generated config
+// to the running core without restarting it, so connections stay up. The
+// rules are rebuilt in full, which makes geoip:/geosite: conditions re-read
+// the dat files from disk. Pending inbound/outbound changes are applied
+// alongside so the rules never point at a missing handler. It returns the
+// number of active routing rules.
+func (s *XrayService) ReloadRoutingRules() (int, error) {
+	lock.Lock()
+	defer lock.Unlock()
+
+	if !s.IsXrayRunning() {
+		return 0, errors.New("xray is not running")
+	}
+	newCfg, err := s.GetXrayConfig()
+	if err != nil {
+		return 0, err
+	}
+	diff, ok := xray.ComputeHotDiff(p.GetConfig(), newCfg)
+	if !ok {
+		return 0, ErrRoutingReloadUnsupported
+	}
+	if len(newCfg.RouterConfig) > 0 {
+		diff.RoutingConfig = newCfg.RouterConfig
+	}
+	if !s.applyHotDiff(newCfg, diff) {
+		return 0, ErrRoutingReloadUnsupported
+	}
+
+	var routing struct {
+		Rules []json.RawMessage `json:"rules"`
+	}
+	_ = json.Unmarshal(newCfg.RouterConfig, &routing)
+	return len(routing.Rules), nil
+}
+
 // addInboundReconciling adds an inbound, and on a tag conflict (the handler
 // was already created through the runtime API while the stored snapshot was
 // stale) replaces the existing handler instead.
@@ -924,6 +1011,22 @@ func (s *XrayService) GetXrayAPIPort() int {
 	return p.GetAPIPort()
 }
 
+// IsXrayReady reports whether the core is running and its API port accepts
+// connections. The core only opens its listeners once the config is loaded,
+// so this tells a core that is serving from one that is still starting.
+func (s *XrayService) IsXrayReady() bool {
+	port := s.GetXrayAPIPort()
+	if port <= 0 {
+		return false
+	}
+	conn, err := net.DialTimeout("tcp", net.JoinHostPort("127.0.0.1", strconv.Itoa(port)), time.Second)
+	if err != nil {
+		return false
+	}
+	conn.Close()
+	return true
+}
+
 // IsNeedRestartAndSetFalse checks if restart is needed and resets the flag to false.
 func (s *XrayService) IsNeedRestartAndSetFalse() bool {
 	return isNeedXrayRestart.CompareAndSwap(true, false)
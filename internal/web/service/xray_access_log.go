@@ -0,0 +1,72 @@
+package service
+
+import (
+	"encoding/json"
+)
+
+// DefaultAccessLogPath is the access log the panel offers in the Xray
+// settings, next to the Xray binary.
+const DefaultAccessLogPath = "./access.log"
+
+// SetAccessLog turns the Xray access log on or off in the template config.
+// Client IP tracking and IP limits read it on cores without the online-stats
+// API. Xray has a single access log, so it applies to every inbound. It
+// reports whether the template changed; Xray must be restarted to pick it up.
+func (s *XraySettingService) SetAccessLog(enable bool) (bool, error) {
+	template, err := s.GetXrayConfigTemplate()
+	if err != nil {
+		return false, err
+	}
+	template = UnwrapXrayTemplateConfig(template)
+	path := "none"
+	if enable {
+		path = DefaultAccessLogPath
+	}
+	updated, changed, err := setAccessLogPath(template, path)
+	if err != nil || !changed {
+		return false, err
+	}
+	if err := s.SettingService.saveSetting("xrayTemplateConfig", updated); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// accessLogOn reports whether path, the log.access value of an Xray config,
+// writes an access log.
+func accessLogOn(path string) bool {
+	return path != "" && path != "none"
+}
+
+// setAccessLogPath sets log.access of the config template raw. A log that
+// is already on is kept on its own path rather than moved. Other fields are
+// left as they are.
+func setAccessLogPath(raw string, path string) (string, bool, error) {
+	cfg, log, err := templateLog(raw)
+	if err != nil {
+		return raw, false, err
+	}
+	var current string
+	if a, ok := log["access"]; ok {
+		_ = json.Unmarshal(a, &current)
+	}
+	if accessLogOn(current) == accessLogOn(path) {
+		return raw, false, nil
+	}
+
+	access, err := json.Marshal(path)
+	if err != nil {
+		return raw, false, err
+	}
+	log["access"] = access
+	logJSON, err := json.Marshal(log)
+	if err != nil {
+		return raw, false, err
+	}
+	cfg["log"] = logJSON
+	out, err := json.MarshalIndent(cfg, "", "  ")
+	if err != nil {
+		return raw, false, err
+	}
+	return string(out), true, nil
+}
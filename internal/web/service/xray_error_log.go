@@ -0,0 +1,207 @@
+package service
+
+import (
+	"errors"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/zixu5u/3xv/v3/internal/logger"
+	"github.com/zixu5u/3xv/v3/internal/xray"
+)
+
+// xrayErrorLogTail bounds how much of an Xray error log file is read: only
+// its last bytes, so a log that was never rotated costs no more to scan.
+const xrayErrorLogTail = 1 << 20
+
+// panelLogLines is how many lines the panel keeps in memory; scanning them
+// all costs little.
+const panelLogLines = 10240
+
+// maxXrayErrorSignature caps the length of an error signature.
+const maxXrayErrorSignature = 200
+
+// ErrXrayErrorLogOff is returned when the Xray config sets log.error to
+// "none", so there is nothing to scan.
+var ErrXrayErrorLogOff = errors.New("xray error log is off")
+
+// XrayErrorPattern is one kind of error in the Xray log: the message with
+// addresses, IDs and numbers replaced by placeholders, how many lines had
+// it and the time of the newest one, as logged.
+type XrayErrorPattern struct {
+	Signature string
+	Count     int
+	LastSeen  string
+}
+
+var (
+	// xrayFileLine matches a line Xray writes to its error log file.
+	xrayFileLine = regexp.MustCompile(`^(\d{4}/\d{2}/\d{2} \d{2}:\d{2}:\d{2})(?:\.\d+)? \[(\w+)\] (.*)$`)
+	// xrayPanelLine matches a line the panel's log buffer holds for Xray's
+	// stdout, as returned by logger.GetLogs.
+	xrayPanelLine = regexp.MustCompile(`^(\d{4}/\d{2}/\d{2} \d{2}:\d{2}:\d{2}) (\w+) - XRAY: (.*)$`)
+
+	// xrayErrorNoise is applied in order to turn a message into its
+	// signature; the more specific patterns come first.
+	xrayErrorNoise = []struct {
+		re   *regexp.Regexp
+		repl string
+	}{
+		{regexp.MustCompile(`^\[\d+\]\s*`), ""},
+		{regexp.MustCompile(`"[^"]*"`), `"<str>"`},
+		{regexp.MustCompile(`[\w.+-]+@[\w-]+(?:\.[\w-]+)*`), "<email>"},
+		{regexp.MustCompile(`(?i)\b[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}\b`), "<id>"},
+		{regexp.MustCompile(`\[[0-9a-fA-F:.%\w]*:[0-9a-fA-F:.%\w]*\](?::\d+)?`), "<addr>"},
+		{regexp.MustCompile(`\b\d{1,3}(?:\.\d{1,3}){3}(?::\d+)?\b`), "<addr>"},
+		{regexp.MustCompile(`(?i)\b[0-9a-f]{0,4}(?::[0-9a-f]{0,4}){2,7}\b`), "<addr>"},
+		{regexp.MustCompile(`(?i)\b(?:[a-z0-9-]+\.)+[a-z][a-z0-9-]*:\d+\b`), "<addr>"},
+		{regexp.MustCompile(`(?i)\b(?:[a-z0-9-]+\.)+[a-z]{2,}\b`), "<host>"},
+		{regexp.MustCompile(`\b\d+(?:\.\d+)?(?:ns|us|ms|s|m|h)?\b`), "N"},
+		{regexp.MustCompile(`(?i)\b[0-9a-f]{8,}\b`), "<id>"},
+		{regexp.MustCompile(`\s+`), " "},
+	}
+)
+
+// XrayErrorSignature normalizes an Xray log message so that lines that
+// differ only in client addresses, IDs, durations or other numbers group
+// together.
+func XrayErrorSignature(message string) string {
+	sig := strings.TrimSpace(message)
+	for _, n := range xrayErrorNoise {
+		sig = n.re.ReplaceAllString(sig, n.repl)
+	}
+	sig = strings.TrimSpace(sig)
+	if r := []rune(sig); len(r) > maxXrayErrorSignature {
+		sig = string(r[:maxXrayErrorSignature]) + "…"
+	}
+	return sig
+}
+
+// parseXrayLogLine splits a line of the Xray error log file or of the
+// panel's log buffer into its time, level and message. ok is false for
+// lines of neither kind, such as the rest of a multi-line message.
+func parseXrayLogLine(line string) (at string, level string, message string, ok bool) {
+	m := xrayFileLine.FindStringSubmatch(line)
+	if m == nil {
+		m = xrayPanelLine.FindStringSubmatch(line)
+	}
+	if m == nil {
+		return "", "", "", false
+	}
+	return m[1], m[2], m[3], true
+}
+
+// isXrayErrorLine reports whether a message at level is an error or a
+// dropped connection rather than routine output.
+func isXrayErrorLine(level string, message string) bool {
+	switch strings.ToLower(level) {
+	case "warning", "error":
+		return true
+	}
+	lower := strings.ToLower(message)
+	return strings.Contains(lower, "error") || strings.Contains(lower, "failed") ||
+		strings.Contains(lower, "connection ends")
+}
+
+// GroupXrayErrors groups the error lines among lines, oldest first, by
+// signature and returns the n patterns seen most recently, newest first,
+// along with the number of error lines found.
+func GroupXrayErrors(lines []string, n int) ([]XrayErrorPattern, int) {
+	type group struct {
+		XrayErrorPattern
+		last int
+	}
+	groups := make(map[string]*group)
+	total := 0
+	for i, line := range lines {
+		at, level, message, ok := parseXrayLogLine(strings.TrimSpace(line))
+		if !ok || !isXrayErrorLine(level, message) {
+			continue
+		}
+		sig := XrayErrorSignature(message)
+		if sig == "" {
+			continue
+		}
+		total++
+		g, ok := groups[sig]
+		if !ok {
+			g = &group{XrayErrorPattern: XrayErrorPattern{Signature: sig}}
+			groups[sig] = g
+		}
+		g.Count++
+		g.LastSeen = at
+		g.last = i
+	}
+
+	sorted := make([]*group, 0, len(groups))
+	for _, g := range groups {
+		sorted = append(sorted, g)
+	}
+	slices.SortFunc(sorted, func(a, b *group) int { return b.last - a.last })
+	patterns := make([]XrayErrorPattern, 0, min(n, len(sorted)))
+	for _, g := range sorted[:min(n, len(sorted))] {
+		patterns = append(patterns, g.XrayErrorPattern)
+	}
+	return patterns, total
+}
+
+// readLogTail returns the lines in the last limit bytes of the file at
+// path, oldest first. A line cut by the limit is dropped.
+func readLogTail(path string, limit int64) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	offset := max(info.Size()-limit, 0)
+	buf := make([]byte, info.Size()-offset)
+	read, err := file.ReadAt(buf, offset)
+	if err != nil && read == 0 && len(buf) > 0 {
+		return nil, err
+	}
+	text := string(buf[:read])
+	if offset > 0 {
+		if i := strings.IndexByte(text, '\n'); i >= 0 {
+			text = text[i+1:]
+		} else {
+			text = ""
+		}
+	}
+	return strings.Split(text, "\n"), nil
+}
+
+// RecentXrayErrors returns the n most recent distinct error patterns in the
+// Xray error log and the number of error lines they cover. When log.error
+// names a file only its tail is read; otherwise the core logs to stdout and
+// the lines the panel keeps in memory are scanned. path is the file read, or
+// empty for the panel's log.
+func (s *ServerService) RecentXrayErrors(n int) (patterns []XrayErrorPattern, total int, path string, err error) {
+	path, err = xray.GetErrorLogPath()
+	if err != nil {
+		return nil, 0, "", err
+	}
+	var lines []string
+	switch path {
+	case "none":
+		return nil, 0, path, ErrXrayErrorLogOff
+	case "":
+		buffered := logger.GetLogs(panelLogLines, "DEBUG")
+		lines = make([]string, 0, len(buffered))
+		for _, line := range slices.Backward(buffered) {
+			if strings.Contains(line, " - XRAY: ") {
+				lines = append(lines, line)
+			}
+		}
+	default:
+		if lines, err = readLogTail(path, xrayErrorLogTail); err != nil {
+			return nil, 0, path, err
+		}
+	}
+	patterns, total = GroupXrayErrors(lines, n)
+	return patterns, total, path, nil
+}
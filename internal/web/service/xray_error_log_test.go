@@ -0,0 +1,92 @@
+package service
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestXrayErrorSignature(t *testing.T) {
+	cases := []struct{ a, b string }{
+		{
+			"[1234567] app/proxyman/inbound: connection ends > proxy/vless/encoding: failed to read request version > read tcp 10.0.0.1:443->203.0.113.9:51234: i/o timeout",
+			"[89] app/proxyman/inbound: connection ends > proxy/vless/encoding: failed to read request version > read tcp 10.0.0.1:443->198.51.100.7:40000: i/o timeout",
+		},
+		{
+			"transport/internet/tcp: failed to lookup upstream.example.com: dial udp [2001:db8::1]:53: i/o timeout",
+			"transport/internet/tcp: failed to lookup other.example.org: dial udp [2001:db8::2]:53: i/o timeout",
+		},
+		{
+			`proxy/vless/inbound: invalid request user id "alice@example.com" after 1500ms`,
+			`proxy/vless/inbound: invalid request user id "bob@example.com" after 20ms`,
+		},
+	}
+	for _, c := range cases {
+		if a, b := XrayErrorSignature(c.a), XrayErrorSignature(c.b); a != b {
+			t.Errorf("signatures differ:\n%q\n%q", a, b)
+		}
+	}
+	sig := XrayErrorSignature(cases[0].a)
+	for _, leaked := range []string{"203.0.113.9", "51234", "1234567"} {
+		if strings.Contains(sig, leaked) {
+			t.Errorf("signature %q keeps %q", sig, leaked)
+		}
+	}
+	if XrayErrorSignature("tls: handshake failure") == XrayErrorSignature("dns: resolution failed") {
+		t.Error("different errors share a signature")
+	}
+}
+
+func TestGroupXrayErrors(t *testing.T) {
+	lines := []string{
+		"2026/10/16 10:00:00.000001 [Warning] [1] app/dns: failed to resolve a.example.com",
+		"2026/10/16 10:00:01.000001 [Info] [2] proxy/freedom: connection opened to tcp:b.example.com:443",
+		"2026/10/16 10:00:02.000001 [Info] [3] app/proxyman/inbound: connection ends > tls handshake error 203.0.113.1:1000",
+		"  goroutine 1 [running]:",
+		"2026/10/16 10:00:03 ERROR - XRAY: app/dns: failed to resolve c.example.net",
+		"2026/10/16 10:00:04 DEBUG - XRAY: app/proxyman/inbound: connection ends > tls handshake error 203.0.113.2:2000",
+		"2026/10/16 10:00:05.000001 [Error] [4] app/observatory: probe failed",
+	}
+	patterns, total := GroupXrayErrors(lines, 10)
+	if total != 5 {
+		t.Fatalf("total = %d, want 5", total)
+	}
+	if len(patterns) != 3 {
+		t.Fatalf("patterns = %+v, want 3", patterns)
+	}
+	if patterns[0].Signature != "app/observatory: probe failed" || patterns[0].Count != 1 {
+		t.Errorf("newest = %+v", patterns[0])
+	}
+	if !strings.Contains(patterns[1].Signature, "tls handshake error") || patterns[1].Count != 2 || patterns[1].LastSeen != "2026/10/16 10:00:04" {
+		t.Errorf("second = %+v", patterns[1])
+	}
+	if patterns[2].Count != 2 {
+		t.Errorf("third = %+v", patterns[2])
+	}
+
+	if patterns, _ := GroupXrayErrors(lines, 1); len(patterns) != 1 || patterns[0].Signature != "app/observatory: probe failed" {
+		t.Errorf("n=1: %+v", patterns)
+	}
+}
+
+func TestReadLogTail(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "error.log")
+	if err := os.WriteFile(path, []byte("first line\nsecond line\nthird\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	lines, err := readLogTail(path, 15)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(lines, "|"); got != "third|" {
+		t.Errorf("tail = %q, want the cut line dropped", got)
+	}
+	lines, err = readLogTail(path, 1<<20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(lines) != 4 || lines[0] != "first line" {
+		t.Errorf("whole file = %q", lines)
+	}
+}
@@ -0,0 +1,110 @@
+package service
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/zixu5u/3xv/v3/internal/config"
+	"github.com/zixu5u/3xv/v3/internal/logger"
+	"github.com/zixu5u/3xv/v3/internal/util/common"
+	"github.com/zixu5u/3xv/v3/internal/xray"
+)
+
+// goodConfigUptime is how long Xray must have run on a config before it
+// counts as known-good. A config the core rejects makes it exit well
+// within that.
+const goodConfigUptime = 5 * time.Second
+
+// goodConfig remembers the config last saved as known-good, so an unchanged
+// config isn't written again on every check.
+var goodConfig struct {
+	sync.Mutex
+	saved *xray.Config
+}
+
+// GoodConfigPath is where the last-known-good Xray config is kept, next to
+// the config.json Xray is started with.
+func GoodConfigPath() string {
+	return filepath.Join(config.GetBinFolderPath(), "config.good.json")
+}
+
+// HasGoodConfig reports whether a last-known-good config was saved.
+func HasGoodConfig() bool {
+	_, err := os.Stat(GoodConfigPath())
+	return err == nil
+}
+
+// SnapshotGoodConfig saves the config of the running Xray as the
+// last-known-good one once the core has been up on it for goodConfigUptime.
+// Configs applied through the core API count as soon as they are accepted.
+// It is called by the running check and does nothing while the config is
+// unchanged.
+func (s *XrayService) SnapshotGoodConfig() error {
+	proc := p
+	if proc == nil || !proc.IsRunning() || time.Duration(proc.GetUptime())*time.Second < goodConfigUptime {
+		return nil
+	}
+	cfg := proc.GetConfig()
+	if cfg == nil {
+		return nil
+	}
+	goodConfig.Lock()
+	defer goodConfig.Unlock()
+	if goodConfig.saved == cfg || (goodConfig.saved != nil && goodConfig.saved.Equals(cfg)) {
+		goodConfig.saved = cfg
+		return nil
+	}
+	// remembered even when saving fails, so a failing disk is reported
+	// once per config rather than on every check
+	goodConfig.saved = cfg
+	data, err := json.MarshalIndent(cfg, "", "  ")
+	if err != nil {
+		return err
+	}
+	// written to a temporary file first, so a crash never leaves a
+	// half-written snapshot behind
+	path := GoodConfigPath()
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	logger.Debug("Saved the last-known-good Xray config")
+	return nil
+}
+
+// RestoreGoodConfig restarts Xray on the last-known-good config instead of
+// the one built from the panel settings. It is a stopgap: the settings are
+// left as they are, so the next restart builds the broken config again
+// unless they were fixed in the meantime.
+func (s *XrayService) RestoreGoodConfig() error {
+	data, err := os.ReadFile(GoodConfigPath())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return common.NewError("no last-known-good Xray config was saved yet")
+		}
+		return err
+	}
+	cfg := &xray.Config{}
+	if err := json.Unmarshal(data, cfg); err != nil {
+		return common.NewErrorf("the saved Xray config is unreadable: %v", err)
+	}
+
+	lock.Lock()
+	defer lock.Unlock()
+	isManuallyStopped.Store(false)
+	if s.IsXrayRunning() {
+		p.Stop()
+	}
+	p = xray.NewProcess(cfg)
+	result = ""
+	s.xrayAPI.StatsLastValues = nil
+	logger.Warning("Starting Xray on the last-known-good config, the panel settings still produce a different one")
+	return p.Start()
+}
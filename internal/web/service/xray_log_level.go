@@ -0,0 +1,105 @@
+package service
+
+import (
+	"encoding/json"
+	"slices"
+	"strings"
+
+	"github.com/zixu5u/3xv/v3/internal/util/common"
+)
+
+// XrayLogLevels are the values log.loglevel of an Xray config takes, most
+// verbose first.
+var XrayLogLevels = []string{"debug", "info", "warning", "error", "none"}
+
+// DefaultXrayLogLevel is the level Xray logs at when log.loglevel is unset.
+const DefaultXrayLogLevel = "warning"
+
+// GetLogLevel returns log.loglevel of the template config.
+func (s *XraySettingService) GetLogLevel() (string, error) {
+	template, err := s.GetXrayConfigTemplate()
+	if err != nil {
+		return "", err
+	}
+	return logLevelOf(UnwrapXrayTemplateConfig(template))
+}
+
+// SetLogLevel sets log.loglevel of the template config. It reports whether
+// the template changed; Xray must be restarted to pick it up.
+func (s *XraySettingService) SetLogLevel(level string) (bool, error) {
+	template, err := s.GetXrayConfigTemplate()
+	if err != nil {
+		return false, err
+	}
+	updated, changed, err := setLogLevel(UnwrapXrayTemplateConfig(template), level)
+	if err != nil || !changed {
+		return false, err
+	}
+	if err := s.SettingService.saveSetting("xrayTemplateConfig", updated); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// templateLog decodes the config template raw and its log block, which is
+// empty when there is none.
+func templateLog(raw string) (cfg map[string]json.RawMessage, log map[string]json.RawMessage, err error) {
+	if err := json.Unmarshal([]byte(raw), &cfg); err != nil {
+		return nil, nil, common.NewError("xray template config invalid:", err)
+	}
+	if l, ok := cfg["log"]; ok && len(l) > 0 && string(l) != "null" {
+		if err := json.Unmarshal(l, &log); err != nil {
+			return nil, nil, common.NewError("xray template config invalid:", err)
+		}
+	}
+	if log == nil {
+		log = make(map[string]json.RawMessage)
+	}
+	return cfg, log, nil
+}
+
+// logLevelOf returns log.loglevel of the config template raw, or
+// DefaultXrayLogLevel when it is unset.
+func logLevelOf(raw string) (string, error) {
+	_, log, err := templateLog(raw)
+	if err != nil {
+		return "", err
+	}
+	var level string
+	if l, ok := log["loglevel"]; ok {
+		_ = json.Unmarshal(l, &level)
+	}
+	if level == "" {
+		return DefaultXrayLogLevel, nil
+	}
+	return strings.ToLower(level), nil
+}
+
+// setLogLevel sets log.loglevel of the config template raw to one of
+// XrayLogLevels. Other fields are left as they are.
+func setLogLevel(raw string, level string) (string, bool, error) {
+	if !slices.Contains(XrayLogLevels, level) {
+		return raw, false, common.NewError("unknown xray log level:", level)
+	}
+	cfg, log, err := templateLog(raw)
+	if err != nil {
+		return raw, false, err
+	}
+	if current, _ := logLevelOf(raw); current == level {
+		return raw, false, nil
+	}
+	log["loglevel"], err = json.Marshal(level)
+	if err != nil {
+		return raw, false, err
+	}
+	logJSON, err := json.Marshal(log)
+	if err != nil {
+		return raw, false, err
+	}
+	cfg["log"] = logJSON
+	out, err := json.MarshalIndent(cfg, "", "  ")
+	if err != nil {
+		return raw, false, err
+	}
+	return string(out), true, nil
+}
@@ -203,3 +203,77 @@ func TestEnsureStatsRouting_AcceptsInboundTagAsString(t *testing.T) {
 		t.Fatalf("api rule with string-form inboundTag should hoist to front, got %q\nfull: %s", got, out)
 	}
 }
+
+func TestSetAccessLogPath(t *testing.T) {
+	accessOf := func(t *testing.T, raw string) string {
+		t.Helper()
+		var cfg struct {
+			Log map[string]any `json:"log"`
+		}
+		if err := json.Unmarshal([]byte(raw), &cfg); err != nil {
+			t.Fatalf("output is not valid JSON: %v", err)
+		}
+		access, _ := cfg.Log["access"].(string)
+		return access
+	}
+
+	off := `{"log":{"access":"none","loglevel":"warning"},"inbounds":[]}`
+	out, changed, err := setAccessLogPath(off, DefaultAccessLogPath)
+	if err != nil || !changed || accessOf(t, out) != DefaultAccessLogPath {
+		t.Fatalf("turning on: changed=%v err=%v out=%s", changed, err, out)
+	}
+	if !strings.Contains(out, `"loglevel": "warning"`) || !strings.Contains(out, `"inbounds"`) {
+		t.Fatalf("other fields were lost: %s", out)
+	}
+
+	// a log already on keeps its own path
+	custom := `{"log":{"access":"/var/log/xray/access.log"}}`
+	if out, changed, err := setAccessLogPath(custom, DefaultAccessLogPath); err != nil || changed || out != custom {
+		t.Fatalf("already on: changed=%v err=%v out=%s", changed, err, out)
+	}
+	out, changed, err = setAccessLogPath(custom, "none")
+	if err != nil || !changed || accessOf(t, out) != "none" {
+		t.Fatalf("turning off: changed=%v err=%v out=%s", changed, err, out)
+	}
+
+	// no log block at all reads as off
+	out, changed, err = setAccessLogPath(`{"inbounds":[]}`, DefaultAccessLogPath)
+	if err != nil || !changed || accessOf(t, out) != DefaultAccessLogPath {
+		t.Fatalf("no log block: changed=%v err=%v out=%s", changed, err, out)
+	}
+
+	if _, _, err := setAccessLogPath("not json", DefaultAccessLogPath); err == nil {
+		t.Fatal("invalid JSON must fail")
+	}
+}
+
+func TestSetLogLevel(t *testing.T) {
+	raw := `{"log":{"access":"none","loglevel":"warning"},"inbounds":[]}`
+	if level, err := logLevelOf(raw); err != nil || level != "warning" {
+		t.Fatalf("logLevelOf = %q, %v", level, err)
+	}
+	out, changed, err := setLogLevel(raw, "debug")
+	if err != nil || !changed {
+		t.Fatalf("setting debug: changed=%v err=%v", changed, err)
+	}
+	if level, _ := logLevelOf(out); level != "debug" {
+		t.Fatalf("level after setting debug = %q\nfull: %s", level, out)
+	}
+	if !strings.Contains(out, `"access": "none"`) || !strings.Contains(out, `"inbounds"`) {
+		t.Fatalf("other fields were lost: %s", out)
+	}
+	if out, changed, err := setLogLevel(raw, "warning"); err != nil || changed || out != raw {
+		t.Fatalf("same level: changed=%v err=%v", changed, err)
+	}
+
+	// no log block reads as Xray's default
+	if level, err := logLevelOf(`{"inbounds":[]}`); err != nil || level != DefaultXrayLogLevel {
+		t.Fatalf("no log block: %q, %v", level, err)
+	}
+	if _, _, err := setLogLevel(raw, "verbose"); err == nil {
+		t.Fatal("an unknown level must fail")
+	}
+	if _, _, err := setLogLevel("not json", "info"); err == nil {
+		t.Fatal("invalid JSON must fail")
+	}
+}
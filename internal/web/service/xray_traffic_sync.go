@@ -0,0 +1,237 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/zixu5u/3xv/v3/internal/database"
+	"github.com/zixu5u/3xv/v3/internal/database/model"
+	"github.com/zixu5u/3xv/v3/internal/logger"
+	"github.com/zixu5u/3xv/v3/internal/web/service/outbound"
+	"github.com/zixu5u/3xv/v3/internal/web/websocket"
+	"github.com/zixu5u/3xv/v3/internal/xray"
+
+	"github.com/valyala/fasthttp"
+)
+
+// TrafficReconciliationRetention is how long /reconcile runs are kept.
+const TrafficReconciliationRetention = 90 * 24 * time.Hour
+
+// trafficSyncMutex serializes traffic syncs. Xray resets its counters on
+// read, so the periodic job and an on-demand /reconcile each apply the
+// deltas they read; the lock keeps their database writes and the
+// follow-ups from interleaving.
+var trafficSyncMutex sync.Mutex
+
+// XrayTrafficSyncService moves Xray's traffic counters into the database.
+// The traffic job runs it every few seconds and the bot's /reconcile on
+// demand.
+type XrayTrafficSyncService struct {
+	settingService  SettingService
+	xrayService     XrayService
+	inboundService  InboundService
+	outboundService outbound.OutboundService
+}
+
+// Sync reads and resets Xray's traffic counters, adds them to the inbounds,
+// clients and outbounds, and applies what follows: restarting Xray for
+// disabled clients when RestartXrayOnClientDisable is on, informing the
+// external traffic API, refreshing the online clients and pushing the
+// update to the panel. It returns the counters it applied. A failure to
+// add the inbound traffic is returned after the rest ran.
+func (s *XrayTrafficSyncService) Sync() ([]*xray.Traffic, []*xray.ClientTraffic, error) {
+	trafficSyncMutex.Lock()
+	defer trafficSyncMutex.Unlock()
+
+	if !s.xrayService.IsXrayRunning() {
+		return nil, nil, errors.New("xray is not running")
+	}
+	traffics, clientTraffics, err := s.xrayService.GetXrayTraffic()
+	if err != nil {
+		return nil, nil, err
+	}
+	needRestart0, clientsDisabled, addErr := s.inboundService.AddTraffic(traffics, clientTraffics)
+	if addErr != nil {
+		logger.Warning("add inbound traffic failed:", addErr)
+	} else {
+		s.xrayService.MarkTrafficSynced()
+	}
+	err, needRestart1 := s.outboundService.AddTraffic(traffics, clientTraffics)
+	if err != nil {
+		logger.Warning("add outbound traffic failed:", err)
+	}
+	if clientsDisabled {
+		restartOnDisable, settingErr := s.settingService.GetRestartXrayOnClientDisable()
+		if settingErr != nil {
+			logger.Warning("get RestartXrayOnClientDisable failed:", settingErr)
+		}
+		if restartOnDisable {
+			if err := s.xrayService.RestartXray(true); err != nil {
+				logger.Warning("restart xray after disabling clients failed:", err)
+				s.xrayService.SetToNeedRestart()
+			}
+		}
+		websocket.BroadcastInvalidate(websocket.MessageTypeInbounds)
+	}
+	if ExternalTrafficInformEnable, err := s.settingService.GetExternalTrafficInformEnable(); ExternalTrafficInformEnable {
+		s.informTrafficToExternalAPI(traffics, clientTraffics)
+	} else if err != nil {
+		logger.Warning("get ExternalTrafficInformEnable failed:", err)
+	}
+	if needRestart0 || needRestart1 {
+		s.xrayService.SetToNeedRestart()
+	}
+
+	s.refreshOnlineClients(traffics, clientTraffics)
+	s.broadcastTraffic(traffics, clientTraffics)
+	return traffics, clientTraffics, addErr
+}
+
+// refreshOnlineClients updates the local online clients from the counters
+// of one sync.
+func (s *XrayTrafficSyncService) refreshOnlineClients(traffics []*xray.Traffic, clientTraffics []*xray.ClientTraffic) {
+	// Derive the local online set from this poll's per-email deltas rather
+	// than the shared last_online column, which remote-node syncs also bump
+	// and would otherwise make a client active only on a remote node appear
+	// online on local inbounds.
+	activeEmails := make([]string, 0, len(clientTraffics))
+	deltaActive := make(map[string]bool, len(clientTraffics))
+	for _, ct := range clientTraffics {
+		if ct != nil && ct.Up+ct.Down > 0 {
+			activeEmails = append(activeEmails, ct.Email)
+			deltaActive[ct.Email] = true
+		}
+	}
+	// When the core supports the online-stats API, union in connection-based
+	// onlines. Neither signal alone covers everything: an idle-but-connected
+	// client moves no bytes between polls (the delta heuristic's blind spot),
+	// while a short-lived connection can close before this poll yet still show
+	// in the delta. Older cores fall back to deltas alone.
+	if onlineUsers, apiMode, ouErr := s.xrayService.GetOnlineUsers(); ouErr != nil {
+		logger.Debug("get online users from xray api failed:", ouErr)
+	} else if apiMode {
+		idleOnline := make([]string, 0, len(onlineUsers))
+		for _, u := range onlineUsers {
+			if !deltaActive[u.Email] {
+				activeEmails = append(activeEmails, u.Email)
+				idleOnline = append(idleOnline, u.Email)
+			}
+		}
+		// The traffic path only bumps last_online on a non-zero delta; keep the
+		// column fresh for clients kept online purely by a live connection.
+		if err := s.inboundService.BumpClientsLastOnline(idleOnline); err != nil {
+			logger.Warning("bump last online for connected clients failed:", err)
+		}
+	}
+	// Pair the email signal with the inbound tags that moved bytes this poll.
+	// Xray's user>>>email counter aggregates across every inbound a client is
+	// attached to, so an online email alone can't say which inbound it used —
+	// gating the per-inbound view on these tags keeps a multi-inbound client
+	// off inbounds that saw no traffic. See issue #4859.
+	activeInboundTags := make([]string, 0, len(traffics))
+	for _, tr := range traffics {
+		if tr != nil && tr.IsInbound && tr.Up+tr.Down > 0 {
+			activeInboundTags = append(activeInboundTags, tr.Tag)
+		}
+	}
+	s.inboundService.RefreshLocalOnlineClients(activeEmails, activeInboundTags)
+}
+
+// broadcastTraffic pushes real-time updates over WebSocket using compact
+// delta payloads — no REST fallback, scales to 10k–20k+ clients per
+// inbound.
+func (s *XrayTrafficSyncService) broadcastTraffic(traffics []*xray.Traffic, clientTraffics []*xray.ClientTraffic) {
+	if !websocket.HasClients() {
+		return
+	}
+
+	lastOnlineMap, err := s.inboundService.GetClientsLastOnline()
+	if err != nil {
+		logger.Warning("get clients last online failed:", err)
+	}
+	if lastOnlineMap == nil {
+		lastOnlineMap = make(map[string]int64)
+	}
+	onlineClients := s.inboundService.GetOnlineClients()
+	if onlineClients == nil {
+		onlineClients = []string{}
+	}
+	websocket.BroadcastTraffic(map[string]any{
+		"traffics":       traffics,
+		"clientTraffics": clientTraffics,
+		"onlineClients":  onlineClients,
+		"onlineByGuid":   s.inboundService.GetOnlineClientsByGuid(),
+		"activeInbounds": s.inboundService.GetActiveInboundsByGuid(),
+		"lastOnlineMap":  lastOnlineMap,
+	})
+
+	clientStatsPayload := map[string]any{}
+	if stats, err := s.inboundService.GetAllClientTraffics(); err != nil {
+		logger.Warning("get all client traffics for websocket failed:", err)
+	} else if len(stats) > 0 {
+		clientStatsPayload["clients"] = stats
+	}
+	if inboundSummary, err := s.inboundService.GetInboundsTrafficSummary(); err != nil {
+		logger.Warning("get inbounds traffic summary for websocket failed:", err)
+	} else if len(inboundSummary) > 0 {
+		clientStatsPayload["inbounds"] = inboundSummary
+	}
+	if len(clientStatsPayload) > 0 {
+		websocket.BroadcastClientStats(clientStatsPayload)
+	}
+
+	if updatedOutbounds, err := s.outboundService.GetOutboundsTraffic(); err == nil && updatedOutbounds != nil {
+		websocket.BroadcastOutbounds(updatedOutbounds)
+	} else if err != nil {
+		logger.Warning("get all outbounds for websocket failed:", err)
+	}
+}
+
+func (s *XrayTrafficSyncService) informTrafficToExternalAPI(inboundTraffics []*xray.Traffic, clientTraffics []*xray.ClientTraffic) {
+	informURL, err := s.settingService.GetExternalTrafficInformURI()
+	if err != nil {
+		logger.Warning("get ExternalTrafficInformURI failed:", err)
+		return
+	}
+	informURL, err = SanitizePublicHTTPURL(informURL, false)
+	if err != nil {
+		logger.Warning("ExternalTrafficInformURI blocked:", err)
+		return
+	}
+	requestBody, err := json.Marshal(map[string]any{"clientTraffics": clientTraffics, "inboundTraffics": inboundTraffics})
+	if err != nil {
+		logger.Warning("parse client/inbound traffic failed:", err)
+		return
+	}
+	request := fasthttp.AcquireRequest()
+	defer fasthttp.ReleaseRequest(request)
+	request.Header.SetMethod("POST")
+	request.Header.SetContentType("application/json; charset=UTF-8")
+	request.SetBody([]byte(requestBody))
+	request.SetRequestURI(informURL)
+	response := fasthttp.AcquireResponse()
+	defer fasthttp.ReleaseResponse(response)
+	if err := fasthttp.Do(request, response); err != nil {
+		logger.Warning("POST ExternalTrafficInformURI failed:", err)
+	}
+}
+
+// RecordReconciliation stores a /reconcile run and drops the runs older
+// than TrafficReconciliationRetention.
+func (s *XrayTrafficSyncService) RecordReconciliation(run *model.TrafficReconciliation) error {
+	db := database.GetDB()
+	before := time.UnixMilli(run.ReconciledAt).Add(-TrafficReconciliationRetention)
+	if err := db.Where("reconciled_at < ?", before.UnixMilli()).Delete(&model.TrafficReconciliation{}).Error; err != nil {
+		return err
+	}
+	return db.Create(run).Error
+}
+
+// RecentReconciliations returns up to limit /reconcile runs, newest first.
+func (s *XrayTrafficSyncService) RecentReconciliations(limit int) ([]model.TrafficReconciliation, error) {
+	var runs []model.TrafficReconciliation
+	err := database.GetDB().Order("reconciled_at DESC, id DESC").Limit(limit).Find(&runs).Error
+	return runs, err
+}
@@ -0,0 +1,44 @@
+package service
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/zixu5u/3xv/v3/internal/database"
+	"github.com/zixu5u/3xv/v3/internal/database/model"
+)
+
+func TestTrafficReconciliations(t *testing.T) {
+	dbDir := t.TempDir()
+	t.Setenv("XUI_DB_FOLDER", dbDir)
+	if err := database.InitDB(filepath.Join(dbDir, "x-ui.db")); err != nil {
+		t.Fatalf("InitDB: %v", err)
+	}
+	t.Cleanup(func() { _ = database.CloseDB() })
+
+	svc := XrayTrafficSyncService{}
+	old := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	now := old.Add(TrafficReconciliationRetention + time.Hour)
+	runs := []*model.TrafficReconciliation{
+		{RequestedBy: "telegram:1", Inbounds: 1, ReconciledAt: old.UnixMilli()},
+		{RequestedBy: "telegram:2", Inbounds: 2, Up: 10, ReconciledAt: now.Add(-time.Minute).UnixMilli()},
+		{RequestedBy: "telegram:3", Clients: 5, Down: 20, ReconciledAt: now.UnixMilli()},
+	}
+	for _, run := range runs {
+		if err := svc.RecordReconciliation(run); err != nil {
+			t.Fatalf("RecordReconciliation: %v", err)
+		}
+	}
+
+	recent, err := svc.RecentReconciliations(10)
+	if err != nil {
+		t.Fatalf("RecentReconciliations: %v", err)
+	}
+	if len(recent) != 2 || recent[0].RequestedBy != "telegram:3" || recent[1].Up != 10 {
+		t.Fatalf("runs past the retention should be dropped, newest first: %+v", recent)
+	}
+	if recent, _ := svc.RecentReconciliations(1); len(recent) != 1 {
+		t.Fatalf("RecentReconciliations(1) = %+v", recent)
+	}
+}
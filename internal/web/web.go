@@ -260,6 +260,14 @@ func (s *Server) startTask(restartXray bool) {
 		if err != nil {
 			logger.Warning("start xray failed:", err)
 		}
+		// A config Xray rejects leaves it down; the admins are told why
+		// once the bot is up.
+		go func() {
+			if err := s.xrayService.CheckXrayConfig(); err != nil {
+				logger.Error("Xray config test failed on startup:", err)
+				tgbot.QueueXrayConfigAlert(err)
+			}
+		}()
 	}
 	// Check whether xray is running every second
 	s.cron.AddJob("@every 1s", job.NewCheckXrayRunningJob())
@@ -296,6 +304,8 @@ func (s *Server) startTask(restartXray bool) {
 
 	// check client ips from log file every day
 	s.cron.AddJob("@daily", job.NewClearLogsJob())
+	// Prune online and traffic history past their retention
+	s.cron.AddJob("@daily", job.NewHistoryRetentionJob())
 	s.cron.AddJob("@hourly", job.NewWarpIpJob())
 
 	// Inbound traffic reset jobs
@@ -308,6 +318,15 @@ func (s *Server) startTask(restartXray bool) {
 	// Run once a month, midnight, first of month
 	s.cron.AddJob("@monthly", job.NewPeriodicTrafficResetJob("monthly"))
 
+	// Scheduled inbound enable/disable, kept in the database
+	if err := (&service.InboundScheduleService{}).RegisterAll(); err != nil {
+		logger.Warning("Failed to register inbound schedules:", err)
+	}
+	// One-off admin reminders, kept in the database until sent
+	if err := (&service.ReminderService{}).RegisterAll(); err != nil {
+		logger.Warning("Failed to register reminders:", err)
+	}
+
 	// LDAP sync scheduling
 	if ldapEnabled, _ := s.settingService.GetLdapEnable(); ldapEnabled {
 		runtime, err := s.settingService.GetLdapSyncCron()
@@ -320,39 +339,67 @@ func (s *Server) startTask(restartXray bool) {
 	}
 
 	// Make a traffic condition every day, 8:30
-	var entry cron.EntryID
 	isTgbotenabled, err := s.settingService.GetTgbotEnabled()
 	if (err == nil) && (isTgbotenabled) {
+		// A never-configured tgRunTime already reads as the @daily default;
+		// an empty or "off" value is an explicit opt-out of the report.
 		runtime, err := s.settingService.GetTgbotRuntime()
 		if err != nil {
 			logger.Warningf("Add NewStatsNotifyJob: failed to load runtime: %v; using default @daily", err)
 			runtime = "@daily"
-		} else if strings.TrimSpace(runtime) == "" {
-			logger.Warning("Add NewStatsNotifyJob runtime is empty, using default @daily")
-			runtime = "@daily"
 		}
-		logger.Infof("Tg notify enabled,run at %s", runtime)
-		_, err = s.cron.AddJob(runtime, job.NewStatsNotifyJob())
-		if err != nil {
-			logger.Warningf("Add NewStatsNotifyJob: failed to schedule runtime %q: %v", runtime, err)
-			return
+		if tgbot.IsReportScheduleOff(runtime) {
+			logger.Info("Tg notify enabled, scheduled report disabled")
+		} else if tgbot.IsClockSchedule(runtime) {
+			logger.Infof("Tg notify enabled, bot scheduler runs report at %s", runtime)
+		} else {
+			logger.Infof("Tg notify enabled,run at %s", runtime)
+			err = service.PanelCron().SetSpec(tgbot.ReportCronName, runtime, job.NewStatsNotifyJob())
+			if err != nil {
+				logger.Warningf("Add NewStatsNotifyJob: failed to schedule runtime %q: %v", runtime, err)
+				return
+			}
 		}
 
 		// check for Telegram bot callback query hash storage reset
 		s.cron.AddJob("@every 2m", job.NewCheckHashStorageJob())
 
+		// Sample online clients for the report's daily peak and average
+		s.cron.AddJob("@every 1m", job.NewOnlineHistoryJob())
+
+		// Sample inbound traffic counters for /trend
+		s.cron.AddJob("@every 15m", job.NewTrafficHistoryJob())
+
+		// Sample per-client traffic for /usage with a period
+		if interval, err := s.settingService.GetTgClientUsageInterval(); err == nil && interval > 0 {
+			s.cron.AddJob("@every "+strconv.Itoa(interval)+"m", job.NewClientUsageJob())
+		}
+
+		// Check a batch of clients for traffic limit and expiry alerts
+		if interval, err := s.settingService.GetTgClientLimitInterval(); err == nil && interval > 0 {
+			s.cron.AddJob("@every "+strconv.Itoa(interval)+"s", job.NewClientLimitJob())
+		}
+
+		// Warn about inbound TLS certificates close to expiry
+		if days, err := s.settingService.GetTgCertExpiryDays(); err == nil && days > 0 {
+			s.cron.AddJob("@daily", job.NewCertExpiryJob())
+		}
+
 		// Check CPU load and alarm to TgBot if threshold passes
 		cpuThreshold, err := s.settingService.GetTgCpu()
 		if (err == nil) && (cpuThreshold > 0) {
 			s.cron.AddJob("@every 10s", job.NewCheckCpuJob())
 		}
-	} else {
-		s.cron.Remove(entry)
 	}
 }
 
 // Start initializes and starts the web server with configured settings, routes, and background jobs.
 func (s *Server) Start() (err error) {
+	cleanExit, markErr := s.settingService.MarkPanelRunning()
+	if markErr != nil {
+		logger.Warning("mark panel running failed:", markErr)
+	}
+	tgbot.QueueStartupNotice(cleanExit)
 	return s.start(true, true)
 }
 
@@ -376,6 +423,7 @@ func (s *Server) start(restartXray bool, startTgBot bool) (err error) {
 
 	s.cron = cron.New(cron.WithLocation(loc), cron.WithSeconds())
 	s.cron.Start()
+	service.PanelCron().Attach(s.cron)
 
 	// Wire the inbound-runtime manager once so InboundService can route
 	// add/update/delete to either the local xray or a remote node panel.
@@ -457,6 +505,9 @@ func (s *Server) start(restartXray bool, startTgBot bool) (err error) {
 
 // Stop gracefully shuts down the web server, stops Xray, cron jobs, and Telegram bot.
 func (s *Server) Stop() error {
+	if err := s.settingService.MarkPanelStopped(); err != nil {
+		logger.Warning("mark panel stopped failed:", err)
+	}
 	return s.stop(true, true)
 }
 
@@ -96,6 +96,30 @@ func TestRefreshLocalOnlineGraceWindow(t *testing.T) {
 	}
 }
 
+// TestLocalOnlineSinceTracksSessions checks a session starts at a client's
+// first activity, survives idle ticks within the grace window, and restarts
+// once the client aged out and comes back.
+func TestLocalOnlineSinceTracksSessions(t *testing.T) {
+	p := newOnlineTestProcess()
+	const grace = 20000
+
+	p.RefreshLocalOnline([]string{"user1"}, nil, 1000, grace)
+	p.RefreshLocalOnline([]string{"user1"}, nil, 11000, grace)
+	if got := p.GetLocalOnlineSince()["user1"]; got != 1000 {
+		t.Fatalf("session should start at the first activity, got %d", got)
+	}
+
+	p.RefreshLocalOnline(nil, nil, 32000, grace)
+	if _, ok := p.GetLocalOnlineSince()["user1"]; ok {
+		t.Fatalf("user1 aged out, its session should be gone")
+	}
+
+	p.RefreshLocalOnline([]string{"user1"}, nil, 40000, grace)
+	if got := p.GetLocalOnlineSince()["user1"]; got != 40000 {
+		t.Errorf("a client coming back should start a new session, got %d", got)
+	}
+}
+
 // TestGetLocalActiveInboundsTracksGraceWindow pins #4859: a multi-inbound
 // client only counts online on inbounds that actually carried traffic, and the
 // active-inbound signal honours the same grace window as the online signal.
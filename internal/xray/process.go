@@ -2,6 +2,7 @@ package xray
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -100,6 +101,25 @@ func GetAccessLogPath() (string, error) {
 	return "", err
 }
 
+// GetErrorLogPath reads the Xray config and returns the error log file path.
+// It is empty when the error log goes to the core's stdout, which the panel
+// forwards into its own log.
+func GetErrorLogPath() (string, error) {
+	config, err := os.ReadFile(GetConfigPath())
+	if err != nil {
+		return "", err
+	}
+	var jsonConfig struct {
+		Log struct {
+			Error string `json:"error"`
+		} `json:"log"`
+	}
+	if err := json.Unmarshal(config, &jsonConfig); err != nil {
+		return "", err
+	}
+	return jsonConfig.Log.Error, nil
+}
+
 // stopProcess calls Stop on the given Process instance.
 func stopProcess(p *Process) {
 	p.Stop()
@@ -153,6 +173,12 @@ type process struct {
 	// column is bumped by remote-node syncs too and would otherwise leak
 	// remote-only clients into the local set.
 	localLastOnline map[string]int64
+	// localOnlineSince records, per email in localLastOnline, when its
+	// current online session began: the first tick it had traffic after
+	// being offline for longer than the grace window. Xray doesn't report
+	// how long a connection has been open, so this is what the session
+	// length shown to admins is measured from. Pruned with localLastOnline.
+	localOnlineSince map[string]int64
 	// localInboundLastActive mirrors localLastOnline for inbound tags: the
 	// last tick this panel's xray reported traffic through each tag.
 	// Rebuilt into localActiveInbounds under the same grace window so the
@@ -334,6 +360,18 @@ func (p *Process) GetLocalOnlineClients() []string {
 	return out
 }
 
+// GetLocalOnlineSince returns, for each email online on THIS panel's own
+// xray, when its current online session began, in Unix milliseconds.
+func (p *Process) GetLocalOnlineSince() map[string]int64 {
+	p.onlineMu.RLock()
+	defer p.onlineMu.RUnlock()
+	out := make(map[string]int64, len(p.localOnlineSince))
+	for email, since := range p.localOnlineSince {
+		out[email] = since
+	}
+	return out
+}
+
 // GetMergedNodeTrees returns the union of every direct node's reported subtree,
 // keyed by the panelGuid of the node that physically hosts each client set.
 // Because each child already reports its descendants under their own GUIDs,
@@ -399,7 +437,13 @@ func (p *Process) RefreshLocalOnline(activeEmails, activeInboundTags []string, n
 	if p.localLastOnline == nil {
 		p.localLastOnline = make(map[string]int64, len(activeEmails))
 	}
+	if p.localOnlineSince == nil {
+		p.localOnlineSince = make(map[string]int64, len(activeEmails))
+	}
 	for _, email := range activeEmails {
+		if ts, ok := p.localLastOnline[email]; !ok || now-ts >= graceMs {
+			p.localOnlineSince[email] = now
+		}
 		p.localLastOnline[email] = now
 	}
 	online := make([]string, 0, len(p.localLastOnline))
@@ -408,6 +452,7 @@ func (p *Process) RefreshLocalOnline(activeEmails, activeInboundTags []string, n
 			online = append(online, email)
 		} else {
 			delete(p.localLastOnline, email)
+			delete(p.localOnlineSince, email)
 		}
 	}
 	p.onlineClients = online
@@ -482,6 +527,48 @@ func (p *process) refreshVersion() {
 	}
 }
 
+// configCheckTimeout bounds a CheckConfig run of the Xray binary.
+const configCheckTimeout = 30 * time.Second
+
+// configCheckOutputLimit caps how much of the binary's output a failed
+// CheckConfig reports; the cause is at the end.
+const configCheckOutputLimit = 512
+
+// CheckConfig validates xrayConfig by running the Xray binary with -test on
+// a temporary copy, so the running core and config.json are left alone.
+// The returned error carries the tail of the binary's output.
+func CheckConfig(xrayConfig *Config) error {
+	data, err := json.MarshalIndent(xrayConfig, "", "  ")
+	if err != nil {
+		return common.NewErrorf("Failed to generate XRAY configuration files: %v", err)
+	}
+	tmpFile, err := os.CreateTemp(config.GetBinFolderPath(), "xray_check_*.json")
+	if err != nil {
+		return err
+	}
+	path := tmpFile.Name()
+	defer os.Remove(path)
+	_, err = tmpFile.Write(data)
+	if closeErr := tmpFile.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return common.NewErrorf("Failed to write configuration file: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), configCheckTimeout)
+	defer cancel()
+	out, err := exec.CommandContext(ctx, GetBinaryPath(), "-test", "-c", path).CombinedOutput()
+	if err != nil {
+		out = bytes.TrimSpace(out)
+		if len(out) > configCheckOutputLimit {
+			out = out[len(out)-configCheckOutputLimit:]
+		}
+		return common.NewErrorf("xray config test failed: %v: %s", err, out)
+	}
+	return nil
+}
+
 // Start launches the Xray process with the current configuration.
 func (p *process) Start() (err error) {
 	if p.IsRunning() {